@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Azure/azure-workload-identity/pkg/cmd"
 )
 
 func main() {
-	if err := cmd.NewRootCmd().Execute(); err != nil {
+	// cancel in-flight operations on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.NewRootCmd().ExecuteContext(ctx); err != nil {
+		stop()
 		os.Exit(1)
 	}
 }
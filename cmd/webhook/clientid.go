@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+)
+
+// azureClientIDChecker checks the client IDs with Microsoft Graph.
+type azureClientIDChecker struct {
+	azureClient cloud.Interface
+}
+
+// ClientIDExists returns true if the client ID is the app ID of an application or, since managed
+// identities and the applications of other tenants have no application object in the tenant, of a service principal.
+func (c *azureClientIDChecker) ClientIDExists(ctx context.Context, clientID string) (bool, error) {
+	_, err := c.azureClient.GetApplicationByAppID(ctx, clientID)
+	if err == nil {
+		return true, nil
+	}
+	if !cloud.IsNotFound(err) {
+		return false, err
+	}
+
+	if _, err = c.azureClient.GetServicePrincipalByAppID(ctx, clientID); err != nil {
+		if cloud.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
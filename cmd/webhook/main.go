@@ -19,6 +19,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
 	"github.com/Azure/azure-workload-identity/pkg/metrics"
 	"github.com/Azure/azure-workload-identity/pkg/util"
 	"github.com/Azure/azure-workload-identity/pkg/version"
@@ -41,6 +42,9 @@ const (
 
 var (
 	audience            string
+	defaultClientID     string
+	tokenFilePath       string
+	clientIDValidation  string
 	webhookCertDir      string
 	tlsMinVersion       string
 	healthAddr          string
@@ -70,6 +74,9 @@ func mainErr() error {
 	defer mlog.Setup()()
 
 	flag.StringVar(&audience, "audience", "", "Audience for service account token")
+	flag.StringVar(&defaultClientID, "default-client-id", "", "Client ID used for the pods whose service account and pod are not annotated with azure.workload.identity/client-id")
+	flag.StringVar(&tokenFilePath, "token-file-path", wh.DefaultTokenFilePath, "Path of the projected service account token file in the containers of the pods whose service account is not annotated with azure.workload.identity/token-file-path")
+	flag.StringVar(&clientIDValidation, "client-id-validation", "", "How pods whose client ID doesn't exist in Azure AD are handled: unset (empty string) to not validate client IDs, warn or deny. The webhook authenticates to Microsoft Graph with the Azure default credential")
 	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/certs", "Webhook certificates dir to use. Defaults to /certs")
 	flag.BoolVar(&disableCertRotation, "disable-cert-rotation", false, "disable automatic generation and rotation of webhook TLS certificates/keys")
 	flag.StringVar(&tlsMinVersion, "tls-min-version", "1.3", "Minimum TLS version")
@@ -89,6 +96,20 @@ func mainErr() error {
 		return fmt.Errorf("invalid --log-level set: %w", err)
 	}
 
+	clientIDValidationMode, err := wh.ParseClientIDValidationMode(clientIDValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --client-id-validation set: %w", err)
+	}
+	var clientIDChecker wh.ClientIDChecker
+	if clientIDValidationMode != wh.ClientIDValidationDisabled {
+		entryLog.Info("setting up client ID validation", "mode", clientIDValidationMode)
+		azureClient, err := cloud.NewAzureClientFromDefaultCredential(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("entrypoint: unable to set up client ID validation: %w", err)
+		}
+		clientIDChecker = &azureClientIDChecker{azureClient: azureClient}
+	}
+
 	// nolint:staticcheck
 	// controller-runtime forces use to use the deprecated logr.Logger returned by mlog.Logr here
 	log.SetLogger(mlog.Logr())
@@ -141,7 +162,7 @@ func mainErr() error {
 	}
 
 	setupProbeEndpoints(mgr, setupFinished)
-	go setupWebhook(mgr, setupFinished)
+	go setupWebhook(mgr, setupFinished, clientIDChecker, clientIDValidationMode)
 
 	entryLog.Info("starting manager")
 	if err := mgr.Start(ctx); err != nil {
@@ -151,7 +172,7 @@ func mainErr() error {
 	return nil
 }
 
-func setupWebhook(mgr manager.Manager, setupFinished chan struct{}) {
+func setupWebhook(mgr manager.Manager, setupFinished chan struct{}, clientIDChecker wh.ClientIDChecker, clientIDValidationMode wh.ClientIDValidationMode) {
 	// Block until the setup (certificate generation) finishes.
 	<-setupFinished
 
@@ -160,11 +181,16 @@ func setupWebhook(mgr manager.Manager, setupFinished chan struct{}) {
 
 	// setup webhooks
 	entryLog.Info("registering webhook to the webhook server")
-	podMutator, err := wh.NewPodMutator(mgr.GetClient(), mgr.GetAPIReader(), audience)
+	podMutator, err := wh.NewPodMutator(mgr.GetClient(), mgr.GetAPIReader(), audience, defaultClientID, tokenFilePath, clientIDChecker, clientIDValidationMode)
 	if err != nil {
 		panic(fmt.Errorf("unable to set up pod mutator: %w", err))
 	}
 	hookServer.Register("/mutate-v1-pod", &webhook.Admission{Handler: podMutator})
+	configHandler, err := wh.NewConfigHandler(podMutator)
+	if err != nil {
+		panic(fmt.Errorf("unable to set up config endpoint: %w", err))
+	}
+	hookServer.Register("/config", configHandler)
 }
 
 func setupProbeEndpoints(mgr ctrl.Manager, setupFinished chan struct{}) {
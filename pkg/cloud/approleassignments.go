@@ -0,0 +1,96 @@
+package cloud
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/pkg/errors"
+)
+
+// AddAppRoleAssignment assigns the app role of the resource service principal to the service principal, e.g. to
+// grant it an application permission of Microsoft Graph such as User.Read.All, which is admin consent for the
+// permission. It returns the ID of the app role assignment, which is the existing one if the app role has already
+// been assigned to the service principal.
+func (c *AzureClient) AddAppRoleAssignment(ctx context.Context, spObjectID, resourceSPObjectID, appRoleID string) (string, error) {
+	principalID, err := uuid.Parse(spObjectID)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid service principal object ID %q", spObjectID)
+	}
+	resourceID, err := uuid.Parse(resourceSPObjectID)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid resource service principal object ID %q", resourceSPObjectID)
+	}
+	roleID, err := uuid.Parse(appRoleID)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid app role ID %q", appRoleID)
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("adding app role assignment", "servicePrincipalObjectID", spObjectID, "resourceServicePrincipalObjectID", resourceSPObjectID, "appRoleID", appRoleID) {
+		return DryRunObjectID, nil
+	}
+	c.logDebug("Adding app role assignment",
+		"servicePrincipalObjectID", spObjectID,
+		"resourceServicePrincipalObjectID", resourceSPObjectID,
+		"appRoleID", appRoleID,
+	)
+
+	body := models.NewAppRoleAssignment()
+	body.SetPrincipalId(&principalID)
+	body.SetResourceId(&resourceID)
+	body.SetAppRoleId(&roleID)
+
+	assignment, err := c.graphServiceClient.ServicePrincipalsById(spObjectID).AppRoleAssignments().Post(ctx, body, nil)
+	if isAppRoleAssignmentAlreadyExists(err) {
+		c.logDebug("App role has previously been assigned", "servicePrincipalObjectID", spObjectID, "appRoleID", appRoleID)
+		return c.getAppRoleAssignmentID(ctx, spObjectID, resourceID, roleID)
+	}
+	if err != nil {
+		return "", errors.Wrapf(withRequiredPermission(withODataErrorDetails(err), appRoleAssignmentRequiredPermission), "failed to assign app role %s of service principal %s to service principal %s", appRoleID, resourceSPObjectID, spObjectID)
+	}
+	graphErr, err := GetGraphError(assignment.GetAdditionalData())
+	if err != nil {
+		return "", err
+	}
+	if graphErr != nil {
+		return "", *graphErr
+	}
+	return to.String(assignment.GetId()), nil
+}
+
+// getAppRoleAssignmentID returns the ID of the assignment of the app role of the resource service principal to
+// the service principal.
+func (c *AzureClient) getAppRoleAssignmentID(ctx context.Context, spObjectID string, resourceID, appRoleID uuid.UUID) (string, error) {
+	resp, err := c.graphServiceClient.ServicePrincipalsById(spObjectID).AppRoleAssignments().Get(ctx, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list the app role assignments of service principal %s", spObjectID)
+	}
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return "", err
+		}
+		if graphErr != nil {
+			return "", *graphErr
+		}
+		for _, assignment := range resp.GetValue() {
+			if assignment.GetResourceId() != nil && *assignment.GetResourceId() == resourceID &&
+				assignment.GetAppRoleId() != nil && *assignment.GetAppRoleId() == appRoleID {
+				return to.String(assignment.GetId()), nil
+			}
+		}
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return "", errors.Errorf("app role %s of service principal %s is assigned to service principal %s, but the assignment is not listed", appRoleID, resourceID, spObjectID)
+		}
+		if resp, err = serviceprincipals.NewItemAppRoleAssignmentsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return "", errors.Wrapf(err, "failed to list the app role assignments of service principal %s", spObjectID)
+		}
+	}
+}
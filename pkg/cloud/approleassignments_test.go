@@ -0,0 +1,83 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const (
+	testSPObjectID         = "00000000-0000-0000-0000-000000000001"
+	testResourceSPObjectID = "00000000-0000-0000-0000-000000000002"
+	testAppRoleID          = "df021288-bdef-4463-88db-98f22de89214"
+)
+
+func TestAddAppRoleAssignment(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/"+testSPObjectID+"/appRoleAssignments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": "assignment-id", "principalId": %q, "resourceId": %q, "appRoleId": %q}`, testSPObjectID, testResourceSPObjectID, testAppRoleID)
+	})
+	c := newTestAzureClient(t, mux)
+
+	id, err := c.AddAppRoleAssignment(context.Background(), testSPObjectID, testResourceSPObjectID, testAppRoleID)
+	if err != nil {
+		t.Fatalf("AddAppRoleAssignment() error = %v", err)
+	}
+	if id != "assignment-id" {
+		t.Errorf("AddAppRoleAssignment() = %s, want assignment-id", id)
+	}
+	for property, want := range map[string]string{"principalId": testSPObjectID, "resourceId": testResourceSPObjectID, "appRoleId": testAppRoleID} {
+		if got := body[property]; got != want {
+			t.Errorf("expected %s to be %s in the request body, got %v", property, want, got)
+		}
+	}
+}
+
+func TestAddAppRoleAssignmentAlreadyAssigned(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/"+testSPObjectID+"/appRoleAssignments", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"code": "Request_BadRequest", "message": "Permission being assigned already exists on the object"}}`)
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"value": [
+				{"id": "other-assignment-id", "principalId": %[1]q, "resourceId": %[2]q, "appRoleId": "00000000-0000-0000-0000-000000000003"},
+				{"id": "existing-assignment-id", "principalId": %[1]q, "resourceId": %[2]q, "appRoleId": %[3]q}
+			]}`, testSPObjectID, testResourceSPObjectID, testAppRoleID)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	id, err := c.AddAppRoleAssignment(context.Background(), testSPObjectID, testResourceSPObjectID, testAppRoleID)
+	if err != nil {
+		t.Fatalf("AddAppRoleAssignment() error = %v", err)
+	}
+	if id != "existing-assignment-id" {
+		t.Errorf("AddAppRoleAssignment() = %s, want existing-assignment-id", id)
+	}
+}
+
+func TestAddAppRoleAssignmentInvalidID(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	if _, err := c.AddAppRoleAssignment(context.Background(), testSPObjectID, testResourceSPObjectID, "User.Read.All"); err == nil {
+		t.Error("AddAppRoleAssignment() expected error for an app role ID that is not a GUID")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -19,12 +20,17 @@ import (
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/cli"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
 	"github.com/microsoft/kiota-abstractions-go/authentication"
 	kiotaauth "github.com/microsoft/kiota-authentication-azure-go"
 	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
 	"github.com/pkg/errors"
 	"monis.app/mlog"
+
+	"github.com/Azure/azure-workload-identity/pkg/consts"
 )
 
 // ref: https://docs.microsoft.com/en-us/graph/migrate-azure-ad-graph-request-differences#basic-requests
@@ -35,40 +41,250 @@ var msGraphEndpoint = map[azure.Environment]string{
 	azure.GermanCloud:       "https://graph.microsoft.de/",
 }
 
+// ref: https://learn.microsoft.com/en-us/graph/api/resources/federatedidentitycredential
+var federatedCredentialAudience = map[azure.Environment]string{
+	azure.PublicCloud:       consts.DefaultAudience,
+	azure.USGovernmentCloud: "api://AzureADTokenExchangeUSGov",
+	azure.ChinaCloud:        "api://AzureADTokenExchangeChina",
+}
+
 type Interface interface {
 	CreateServicePrincipal(ctx context.Context, appID string, tags []string) (models.ServicePrincipalable, error)
+	CreateServicePrincipalWithOptions(ctx context.Context, appID string, opts ServicePrincipalOptions) (models.ServicePrincipalable, error)
 	CreateApplication(ctx context.Context, displayName string) (models.Applicationable, error)
+	GetOrCreateApplication(ctx context.Context, displayName string) (models.Applicationable, error)
+	GetApplicationWithRetry(ctx context.Context, displayName string, backoff RetryBackoff) (models.Applicationable, error)
+	GetServicePrincipalWithRetry(ctx context.Context, displayName string, backoff RetryBackoff) (models.ServicePrincipalable, error)
+	CreateApplicationWithAppID(ctx context.Context, displayName, appID string) (models.Applicationable, error)
+	CreateApplicationWithTags(ctx context.Context, displayName string, tags []string) (models.Applicationable, error)
+	CreateApplicationAndGetAppID(ctx context.Context, displayName string) (appID, objectID string, err error)
+	CreateWorkloadIdentity(ctx context.Context, spec WorkloadIdentitySpec) (WorkloadIdentityResult, error)
+	ExportApplication(ctx context.Context, objectID string) ([]byte, error)
+	ImportApplication(ctx context.Context, data []byte) (models.Applicationable, error)
+	CloneApplication(ctx context.Context, sourceObjectID, newDisplayName string) (models.Applicationable, error)
 	DeleteServicePrincipal(ctx context.Context, objectID string) error
 	DeleteApplication(ctx context.Context, objectID string) error
+	DeleteApplicationAndWait(ctx context.Context, objectID string, timeout time.Duration) error
+	DeleteApplicationByDisplayName(ctx context.Context, displayName string) error
+	TagApplications(ctx context.Context, filter, tag string) (int, error)
+	ApplicationsDelta(ctx context.Context, deltaLink string) ([]models.Applicationable, string, error)
+	NewApplicationPager(ctx context.Context, filter string) *ApplicationPager
+	DeleteApplicationsByTag(ctx context.Context, tag string, maxDelete int) (int, error)
+	ListDeletedApplications(ctx context.Context) ([]models.Applicationable, error)
+	ListDeletedApplicationsByDisplayName(ctx context.Context, displayName string) ([]models.Applicationable, error)
+	RestoreDeletedApplication(ctx context.Context, objectID string) (models.Applicationable, error)
+	PermanentlyDeleteApplication(ctx context.Context, objectID string) error
 	GetServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error)
+	GetEnabledServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error)
+	GetServicePrincipalByAppID(ctx context.Context, appID string) (models.ServicePrincipalable, error)
+	WaitForServicePrincipal(ctx context.Context, appID string, timeout time.Duration) (models.ServicePrincipalable, error)
+	GetApplicationForServicePrincipal(ctx context.Context, spObjectID string) (models.Applicationable, error)
+	GetServicePrincipalForApplication(ctx context.Context, appObjectID string) (models.ServicePrincipalable, error)
+	GetServicePrincipalType(ctx context.Context, objectID string) (string, error)
+	GetServicePrincipalSignInActivity(ctx context.Context, objectID string) (*time.Time, error)
+	GetServicePrincipalTags(ctx context.Context, objectID string) (map[string]string, error)
+	AddServicePrincipalTags(ctx context.Context, objectID string, tags map[string]string) error
+	SetServicePrincipalCustomSecurityAttributes(ctx context.Context, objectID string, attrs map[string]map[string]interface{}) error
+	ListServicePrincipalsByTag(ctx context.Context, tag string) ([]models.ServicePrincipalable, error)
+	ListApplicationsByTag(ctx context.Context, tag string) ([]models.Applicationable, error)
+	FindOrphanedServicePrincipals(ctx context.Context, tag string) ([]models.ServicePrincipalable, error)
 	GetApplication(ctx context.Context, displayName string) (models.Applicationable, error)
+	GetApplicationByAppID(ctx context.Context, appID string) (models.Applicationable, error)
+	GetApplicationByIdentifierURI(ctx context.Context, uri string) (models.Applicationable, error)
+	GetApplicationCreatedTime(ctx context.Context, objectID string) (time.Time, error)
+	ListApplicationsCreatedBefore(ctx context.Context, cutoff time.Time, tag string) ([]models.Applicationable, error)
+	GetApplicationSignInAudience(ctx context.Context, objectID string) (string, error)
+	UpdateApplicationDisplayName(ctx context.Context, objectID, displayName string) error
+	SetApplicationSignInAudience(ctx context.Context, objectID, audience string) error
+	SetApplicationTokenClaims(ctx context.Context, objectID string, groupMembershipClaims string, optional models.OptionalClaimsable) error
+	SetApplicationRequiredResourceAccess(ctx context.Context, objectID string, access []models.RequiredResourceAccessable) error
+	SetApplicationImplicitGrantSettings(ctx context.Context, objectID string, idToken, accessToken bool) error
+	SetApplicationLogo(ctx context.Context, objectID string, logo []byte, contentType string) error
+	SetPreAuthorizedApplications(ctx context.Context, objectID string, preAuth []models.PreAuthorizedApplicationable) error
+	GetApplicationOAuth2Scopes(ctx context.Context, objectID string) ([]models.PermissionScopeable, error)
+	AddApplicationOAuth2Scope(ctx context.Context, objectID string, scope models.PermissionScopeable) error
+	ListApplicationAppRoles(ctx context.Context, objectID string) ([]models.AppRoleable, error)
+	AddApplicationAppRole(ctx context.Context, objectID string, role models.AppRoleable) error
+	AddApplicationCertificate(ctx context.Context, objectID string, cert []byte, displayName string, notAfter time.Time) (string, error)
+	AddApplicationPassword(ctx context.Context, objectID, displayName string, notAfter time.Time) (string, string, error)
+	AddApplicationOwner(ctx context.Context, objectID, ownerObjectID string) error
+	AddServicePrincipalOwner(ctx context.Context, objectID, ownerObjectID string) error
+	TransferApplicationOwnership(ctx context.Context, objectID, newOwnerObjectID string, removeExisting bool) error
 
 	// Role assignment methods
 	CreateRoleAssignment(ctx context.Context, scope, roleName, principalID string) (authorization.RoleAssignment, error)
+	EnsureRoleAssignment(ctx context.Context, scope, roleDefinitionID, principalID string) (string, error)
 	DeleteRoleAssignment(ctx context.Context, roleAssignmentID string) (authorization.RoleAssignment, error)
 
+	// Permission grant methods
+	GrantAdminConsent(ctx context.Context, spObjectID string, resourceSPObjectID string, scopes []string) error
+	AddAppRoleAssignment(ctx context.Context, spObjectID, resourceSPObjectID, appRoleID string) (string, error)
+	ListServicePrincipalOAuth2PermissionGrants(ctx context.Context, objectID string) ([]models.OAuth2PermissionGrantable, error)
+	DeleteServicePrincipalOAuth2PermissionGrant(ctx context.Context, grantID string) error
+
 	// Role definition methods
 	GetRoleDefinitionIDByName(ctx context.Context, scope, roleName string) (authorization.RoleDefinition, error)
 
 	// Federation methods
-	AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) error
+	AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error)
 	GetFederatedCredential(ctx context.Context, objectID, issuer, subject string) (models.FederatedIdentityCredentialable, error)
+	FederatedCredentialExists(ctx context.Context, objectID, issuer, subject string) (bool, error)
+	CheckFederatedCredentialLimit(ctx context.Context, objectID string) error
+	GetFederatedCredentialWithAudiences(ctx context.Context, objectID, issuer, subject string, audiences []string) (models.FederatedIdentityCredentialable, error)
+	GetFederatedCredentialsBySubject(ctx context.Context, objectID, subject string) ([]models.FederatedIdentityCredentialable, error)
+	GetFederatedCredentialByName(ctx context.Context, objectID, name string) (models.FederatedIdentityCredentialable, error)
+	GetFederatedCredentialByClaimsMatchingExpression(ctx context.Context, objectID, issuer, expression string) (models.FederatedIdentityCredentialable, error)
+	GetFederatedCredentialRaw(ctx context.Context, objectID, ficID string) ([]byte, error)
+	WaitForFederatedCredential(ctx context.Context, objectID, name string, timeout time.Duration) error
+	ListFederatedCredentials(ctx context.Context, objectID string) ([]models.FederatedIdentityCredentialable, error)
+	UpdateFederatedCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) error
 	DeleteFederatedCredential(ctx context.Context, objectID, federatedCredentialID string) error
+	DeleteFederatedCredentialBySubject(ctx context.Context, objectID, issuer, subject string) error
+	DeleteFederatedCredentials(ctx context.Context, objectID string, predicate func(models.FederatedIdentityCredentialable) bool) (int, error)
+	DeleteFederatedCredentialsBatch(ctx context.Context, objectID string, ficIDs []string) ([]error, error)
+	AddFederatedCredentials(ctx context.Context, reqs []FederatedCredentialRequest) ([]FederatedCredentialResult, error)
+	ReconcileFederatedCredentials(ctx context.Context, objectID string, desired []ExpectedFIC, dryRun bool) (ReconcileResult, error)
+	ReconcileAllFederatedCredentials(ctx context.Context, desired map[string][]ExpectedFIC, workers int) (map[string]ReconcileResult, []error)
+	EnsureIdentities(ctx context.Context, specs []IdentitySpec, workers int) ([]IdentityResult, []error)
+	ListTrustedIssuers(ctx context.Context, objectID string) ([]string, error)
+	ListFederatedCredentialsByAudience(ctx context.Context, objectID, audience string) ([]models.FederatedIdentityCredentialable, error)
+	RewriteFederatedCredentialAudiences(ctx context.Context, objectID, oldAudience, newAudience string) (int, error)
+	RewriteAllFederatedCredentialAudiences(ctx context.Context, oldAudience, newAudience string) (int, error)
+	FindStaleFederatedCredentials(ctx context.Context, objectID string, activeSubjects map[string]bool) ([]models.FederatedIdentityCredentialable, error)
+	FindApplicationsBySubject(ctx context.Context, subject string) ([]models.Applicationable, error)
+	FindApplicationsBySubjectWithTag(ctx context.Context, subject, tag string) ([]models.Applicationable, error)
+	DetectSubjectCollisions(ctx context.Context, subjects []string) (map[string][]string, error)
+	DetectSubjectCollisionsWithManagedIdentities(ctx context.Context, subjects, identityResourceIDs []string) (map[string][]string, error)
+	DetectMissingFederatedCredentials(ctx context.Context, references []SARef) ([]SARef, error)
+	ValidateFederatedCredentialIssuer(ctx context.Context, objectID, name string) error
+
+	// Permission methods
+	CheckRequiredPermissions(ctx context.Context, required []string) ([]string, error)
+	Preflight(ctx context.Context) error
+
+	// Managed identity methods
+	GetUserAssignedIdentity(ctx context.Context, resourceID string) (UserAssignedIdentity, error)
+	AddManagedIdentityFederatedCredential(ctx context.Context, resourceID string, fic FederatedCredential) error
+	GetManagedIdentityFederatedCredential(ctx context.Context, resourceID, issuer, subject string) (FederatedCredential, error)
+	ListManagedIdentityFederatedCredentials(ctx context.Context, resourceID string) ([]FederatedCredential, error)
+	DeleteManagedIdentityFederatedCredential(ctx context.Context, resourceID, name string) error
+
+	// Throttling methods
+	ThrottleStats() ThrottleStats
+}
+
+// GraphServiceClient is the part of the Graph service client that AzureClient uses to list, get, create, update
+// and delete the applications, service principals and federated identity credentials, so that these requests can
+// be faked in tests without a Graph server. The next pages of their lists are sent with the request adapter.
+// It doesn't cover the other Graph requests, e.g. of the owners, app role assignments, OAuth2 permission grants,
+// password credentials, logos, deleted items and application deltas, which are sent with the Graph service client
+// directly, so the methods sending them are still tested against a Graph test server.
+type GraphServiceClient interface {
+	// Application methods
+	ListApplications(ctx context.Context, options *applications.ApplicationsRequestBuilderGetRequestConfiguration) (models.ApplicationCollectionResponseable, error)
+	GetApplication(ctx context.Context, objectID string, options *applications.ApplicationItemRequestBuilderGetRequestConfiguration) (models.Applicationable, error)
+	CreateApplication(ctx context.Context, app models.Applicationable) (models.Applicationable, error)
+	UpdateApplication(ctx context.Context, objectID string, app models.Applicationable) (models.Applicationable, error)
+	DeleteApplication(ctx context.Context, objectID string) error
+
+	// Service principal methods
+	ListServicePrincipals(ctx context.Context, options *serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration) (models.ServicePrincipalCollectionResponseable, error)
+	GetServicePrincipal(ctx context.Context, objectID string, options *serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration) (models.ServicePrincipalable, error)
+	CreateServicePrincipal(ctx context.Context, sp models.ServicePrincipalable) (models.ServicePrincipalable, error)
+	UpdateServicePrincipal(ctx context.Context, objectID string, sp models.ServicePrincipalable) (models.ServicePrincipalable, error)
+	DeleteServicePrincipal(ctx context.Context, objectID string) error
+
+	// Federated identity credential methods
+	ListFederatedIdentityCredentials(ctx context.Context, objectID string, options *applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration) (models.FederatedIdentityCredentialCollectionResponseable, error)
+	CreateFederatedIdentityCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error)
+	UpdateFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error)
+	DeleteFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string) error
+
+	GetAdapter() abstractions.RequestAdapter
 }
 
+// AzureClient is the client of the Graph, ARM and managed identity operations of a tenant. Its methods are safe to
+// call concurrently from multiple goroutines, which share the Graph request adapter, HTTP clients, caches and
+// circuit breaker of the client. The Set methods configure the client and must be called before it is shared.
 type AzureClient struct {
 	environment    azure.Environment
 	subscriptionID string
-
+	// tenantID is the AAD tenant the client authenticates against. Service principals
+	// looked up by display name must be owned by it unless foreign service principals are allowed.
+	tenantID string
+	// allowForeignServicePrincipals disables the owning organization check of the service principals.
+	allowForeignServicePrincipals bool
+	// partialResults makes the list methods return the items listed before a page fails with the error.
+	partialResults bool
+	// pageSize is the number of items per page requested by the list methods. Zero means the default of Graph.
+	pageSize int32
+
+	// graphClient sends the list, get, create, update and delete requests of the applications, service principals
+	// and federated identity credentials, and graphServiceClient the other Graph requests, see GraphServiceClient.
+	graphClient        GraphServiceClient
 	graphServiceClient *msgraphsdk.GraphServiceClient
+	// graphTokenProvider provides the access tokens of the Graph requests. It is nil if the
+	// authentication provider of the Graph requests doesn't expose its access token provider.
+	graphTokenProvider authentication.AccessTokenProvider
+	// graphCircuitBreaker fails Graph requests fast after consecutive Graph failures.
+	// It is disabled unless a failure threshold is set.
+	graphCircuitBreaker *circuitBreaker
+	// graphWriteLimiter limits the rate of the Graph requests that create, update or delete objects.
+	// It is disabled unless a write rate limit is set.
+	graphWriteLimiter *writeRateLimiter
 
 	roleAssignmentsClient authorization.RoleAssignmentsClient
 	roleDefinitionsClient authorization.RoleDefinitionsClient
+
+	managedIdentitiesClient autorest.Client
+
+	// httpClient sends the unauthenticated requests of the client, e.g. to OIDC issuers.
+	httpClient *http.Client
+
+	// defaultTimeout is the timeout applied to each operation if the context
+	// passed by the caller doesn't have an earlier deadline. Zero means no timeout.
+	defaultTimeout time.Duration
+
+	// applicationCache caches the applications resolved by the Get methods.
+	// It is nil, and nothing is cached, unless a cache TTL is set.
+	applicationCache *objectCache[models.Applicationable]
+	// servicePrincipalCache caches the service principals resolved by the Get methods.
+	// It is nil, and nothing is cached, unless a cache TTL is set.
+	servicePrincipalCache *objectCache[models.ServicePrincipalable]
+
+	// defaultFederatedAudiences are the audiences of the federated credentials added without audiences.
+	defaultFederatedAudiences []string
+	// allowedIssuers are the normalized issuers of the federated credentials that AddFederatedCredential accepts.
+	// Any issuer is accepted if it is empty.
+	allowedIssuers []string
+
+	// federatedCredentialPollInterval is the interval at which WaitForFederatedCredential polls.
+	// Zero means defaultFederatedCredentialPollInterval.
+	federatedCredentialPollInterval time.Duration
+
+	// servicePrincipalPollInterval is the interval at which WaitForServicePrincipal polls.
+	// Zero means defaultServicePrincipalPollInterval.
+	servicePrincipalPollInterval time.Duration
+	// applicationDeletionPollInterval is the interval at which DeleteApplicationAndWait polls.
+	// Zero means defaultApplicationDeletionPollInterval.
+	applicationDeletionPollInterval time.Duration
+	// federatedCredentialPropagationRetryDelay is the delay before AddFederatedCredential first retries while the
+	// application is not propagated. Zero means defaultFederatedCredentialPropagationRetryDelay.
+	federatedCredentialPropagationRetryDelay time.Duration
+
+	// throttles records the throttled responses of the requests of the client.
+	throttles *throttleRecorder
+	// metrics records the Graph operations of the client. Nothing is recorded if it is nil.
+	metrics MetricsRecorder
+	// dryRun makes the operations that create, update or delete objects log their action instead of sending it.
+	dryRun bool
+	// logger logs the operations of the client. The global logger is used if it is nil.
+	logger mlog.Logger
 }
 
 // NewAzureClientWithCLI creates an AzureClient configured from Azure CLI 2.0 for local development scenarios.
 func NewAzureClientWithCLI(env azure.Environment, subscriptionID, tenantID string, client *http.Client) (*AzureClient, error) {
-	_, _, err := getOAuthConfig(env, tenantID)
+	_, tenantID, err := getOAuthConfig(env, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +308,7 @@ func NewAzureClientWithCLI(env azure.Environment, subscriptionID, tenantID strin
 		return nil, errors.Wrap(err, "failed to create authentication provider")
 	}
 
-	return getClient(env, subscriptionID, autorest.NewBearerAuthorizer(&adalToken), auth, client)
+	return getClient(env, subscriptionID, tenantID, autorest.NewBearerAuthorizer(&adalToken), auth, client)
 }
 
 // NewAzureClientWithClientSecret returns an AzureClient via client_id and client_secret
@@ -121,7 +337,7 @@ func NewAzureClientWithClientSecret(env azure.Environment, subscriptionID, clien
 		return nil, errors.Wrap(err, "failed to create authentication provider")
 	}
 
-	return getClient(env, subscriptionID, autorest.NewBearerAuthorizer(armSpt), auth, client)
+	return getClient(env, subscriptionID, tenantID, autorest.NewBearerAuthorizer(armSpt), auth, client)
 }
 
 // NewAzureClientWithClientCertificateFile returns an AzureClient via client_id and jwt certificate assertion
@@ -196,32 +412,186 @@ func newAzureClientWithCertificate(env azure.Environment, oauthConfig *adal.OAut
 		return nil, errors.Wrap(err, "failed to create authentication provider")
 	}
 
-	return getClient(env, subscriptionID, autorest.NewBearerAuthorizer(armSpt), auth, client)
+	return getClient(env, subscriptionID, tenantID, autorest.NewBearerAuthorizer(armSpt), auth, client)
 }
 
-func getClient(env azure.Environment, subscriptionID string, armAuthorizer autorest.Authorizer, auth authentication.AuthenticationProvider, client *http.Client) (*AzureClient, error) {
-	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(auth, nil, nil, client)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create request adapter")
+// getClient returns an AzureClient with the default config for the cloud, subscription and tenant.
+func getClient(env azure.Environment, subscriptionID, tenantID string, armAuthorizer autorest.Authorizer, auth authentication.AuthenticationProvider, client *http.Client) (*AzureClient, error) {
+	cfg := config{
+		Environment:    env,
+		SubscriptionID: subscriptionID,
+		TenantID:       tenantID,
+		HTTPClient:     client,
+	}
+	return newAzureClient(cfg.withDefaults(), armAuthorizer, auth)
+}
+
+// SetDefaultTimeout sets the timeout applied to each operation of the AzureClient.
+// A zero timeout disables the default timeout.
+func (c *AzureClient) SetDefaultTimeout(timeout time.Duration) {
+	c.defaultTimeout = timeout
+}
+
+// SetCircuitBreaker enables a circuit breaker on the Graph requests. After threshold consecutive
+// Graph failures, Graph requests fail fast with ErrCircuitOpen for the cooldown, after which a single
+// trial request is sent and the circuit breaker closes if it succeeds or opens again if it fails.
+// Transport errors, throttling and server errors count as failures.
+// A zero threshold disables the circuit breaker, which is the default.
+// It has no effect on an AzureClient that was not created with one of the NewAzureClient functions.
+func (c *AzureClient) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	if c.graphCircuitBreaker == nil {
+		c.logDebug("Graph circuit breaker is not available")
+		return
+	}
+	c.graphCircuitBreaker.configure(threshold, cooldown)
+}
+
+// SetWriteRateLimit limits the Graph requests that create, update or delete objects to limit requests per second,
+// with bursts of up to burst requests, so that bulk onboardings stay under the throttling threshold of Graph
+// instead of being throttled and retried. The reads are not limited, see WithRateLimit for a limit of all the
+// requests. A zero limit disables the write rate limit, which is the default.
+// It has no effect on an AzureClient that was not created with one of the NewAzureClient functions.
+func (c *AzureClient) SetWriteRateLimit(limit float64, burst int) {
+	if c.graphWriteLimiter == nil {
+		c.logDebug("Graph write rate limiter is not available")
+		return
+	}
+	c.graphWriteLimiter.configure(limit, burst)
+}
+
+// SetApplicationCacheTTL enables an in-memory cache of the applications resolved by
+// display name or app ID, which reduces repeated Graph lookups of the same application.
+// A cached application is evicted after the TTL or when it is deleted or updated.
+// A zero TTL disables the cache, which is the default.
+func (c *AzureClient) SetApplicationCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		c.applicationCache = nil
+		return
+	}
+	c.applicationCache = newObjectCache[models.Applicationable](ttl)
+}
+
+// SetServicePrincipalCacheTTL enables an in-memory cache of the service principals resolved by
+// display name or app ID, which reduces repeated Graph lookups of the same service principal.
+// A cached service principal is evicted after the TTL or when it is deleted or updated.
+// A zero TTL disables the cache, which is the default.
+func (c *AzureClient) SetServicePrincipalCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		c.servicePrincipalCache = nil
+		return
+	}
+	c.servicePrincipalCache = newObjectCache[models.ServicePrincipalable](ttl)
+}
+
+// InvalidateCache evicts the application or service principal with the given object ID from the caches,
+// e.g. after it was changed by another client, so that the next lookup reads it from Graph.
+func (c *AzureClient) InvalidateCache(objectID string) {
+	c.applicationCache.evict(objectID)
+	c.servicePrincipalCache.evict(objectID)
+}
+
+// SetFederatedCredentialPollInterval sets the interval at which WaitForFederatedCredential polls
+// for the federated credential. A zero interval resets it to the default of 2 seconds.
+func (c *AzureClient) SetFederatedCredentialPollInterval(interval time.Duration) {
+	c.federatedCredentialPollInterval = interval
+}
+
+// SetDefaultFederatedAudiences sets the audiences of the federated credentials that AddFederatedCredential adds
+// without audiences. The audiences of the federated credential take precedence if it has any. Empty audiences reset
+// the default to the token exchange audience of the cloud, e.g. api://AzureADTokenExchange for the Azure public cloud.
+func (c *AzureClient) SetDefaultFederatedAudiences(audiences []string) {
+	if len(audiences) == 0 {
+		audiences = getDefaultFederatedAudiences(c.environment)
+	}
+	c.defaultFederatedAudiences = append([]string(nil), audiences...)
+}
+
+// SetAllowedIssuers restricts the issuers of the federated credentials that AddFederatedCredential adds to the
+// given ones, as a guardrail against trusting an unexpected issuer. The issuers are compared after normalization,
+// e.g. a trailing slash is not significant. Empty issuers allow any issuer, which is the default.
+func (c *AzureClient) SetAllowedIssuers(issuers []string) {
+	c.allowedIssuers = nil
+	for _, issuer := range issuers {
+		c.allowedIssuers = append(c.allowedIssuers, normalizeIssuer(issuer))
+	}
+}
+
+// isIssuerAllowed returns true if the issuer is one of the allowed issuers, or if any issuer is allowed.
+func (c *AzureClient) isIssuerAllowed(issuer string) bool {
+	if len(c.allowedIssuers) == 0 {
+		return true
+	}
+	issuer = normalizeIssuer(issuer)
+	for _, allowed := range c.allowedIssuers {
+		if allowed == issuer {
+			return true
+		}
 	}
+	return false
+}
 
-	azClient := &AzureClient{
-		environment:    env,
-		subscriptionID: subscriptionID,
+// SetAllowForeignServicePrincipals allows GetServicePrincipal to return service principals of applications
+// owned by another organization, such as multi-tenant third-party applications with the same display name.
+// Foreign service principals are excluded by default.
+func (c *AzureClient) SetAllowForeignServicePrincipals(allow bool) {
+	c.allowForeignServicePrincipals = allow
+}
 
-		graphServiceClient: msgraphsdk.NewGraphServiceClient(adapter),
+// SetPartialResults makes the methods that list across pages return the items of the pages listed before
+// a page fails, along with the error, so that a best-effort caller can use them. They return no items on
+// error by default. The items are incomplete whenever the error is not nil, see the package documentation
+// for the methods that support partial results.
+func (c *AzureClient) SetPartialResults(partial bool) {
+	c.partialResults = partial
+}
 
-		roleAssignmentsClient: authorization.NewRoleAssignmentsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
-		roleDefinitionsClient: authorization.NewRoleDefinitionsClientWithBaseURI(env.ResourceManagerEndpoint, subscriptionID),
+// SetPageSize sets the number of items per page requested by the methods that list across pages, e.g. to lower
+// the memory used by each page in a memory-constrained environment at the cost of more requests. The total number
+// of items listed is not limited. The size must be at most 999, the maximum of Graph. Zero resets it to the default
+// page size of Graph, which is the default.
+func (c *AzureClient) SetPageSize(size int) error {
+	if size < 0 || size > maxPageSize {
+		return errors.Errorf("page size %d must be between 1 and %d, or 0 for the default page size", size, maxPageSize)
 	}
+	c.pageSize = int32(size)
+	return nil
+}
 
-	azClient.roleAssignmentsClient.Authorizer = armAuthorizer
-	azClient.roleDefinitionsClient.Authorizer = armAuthorizer
+// top returns the $top query parameter of the first page of the list requests, or nil for the default page size.
+func (c *AzureClient) top() *int32 {
+	if c.pageSize <= 0 {
+		return nil
+	}
+	top := c.pageSize
+	return &top
+}
 
-	azClient.roleAssignmentsClient.Sender = client
-	azClient.roleDefinitionsClient.Sender = client
+// partialResults returns the items listed before a page failed if the client returns partial results, or nil otherwise.
+func partialResults[T any](c *AzureClient, items []T) []T {
+	if c.partialResults {
+		return items
+	}
+	return nil
+}
 
-	return azClient, nil
+// withDefaultTimeout returns a context with the default timeout applied, unless the
+// default timeout is disabled or the given context already has an earlier deadline.
+// The context also carries the throttle recorder of the client and the operation of
+// the calling method, which is recorded by the metrics recorder when it is canceled.
+func (c *AzureClient) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = withThrottleRecorder(ctx, c.throttles)
+	ctx, done := c.startGraphOperation(ctx)
+	if c.defaultTimeout <= 0 {
+		return ctx, done
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= c.defaultTimeout {
+		return ctx, done
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.defaultTimeout)
+	return ctx, func() {
+		cancel()
+		done()
+	}
 }
 
 // GetTenantID figures out the AAD tenant ID of the subscription by making an
@@ -232,7 +602,7 @@ func GetTenantID(resourceManagerEndpoint string, subscriptionID string) (string,
 	const hdrKey = "WWW-Authenticate"
 	c := subscriptions.NewClientWithBaseURI(resourceManagerEndpoint)
 
-	mlog.Debug("Resolving tenantID", "subscriptionID", subscriptionID)
+	logDebug("Resolving tenantID", "subscriptionID", subscriptionID)
 
 	// we expect this request to fail (err != nil), but we are only interested
 	// in headers, so surface the error if the Response is not present (i.e.
@@ -291,6 +661,39 @@ func parseRsaPrivateKey(path string) (*rsa.PrivateKey, error) {
 	return nil, errors.Errorf("failed to parse private key as Pkcs#1 or Pkcs#8. (%s). (%s)", errPkcs1, errPkcs8)
 }
 
+// GraphScopes returns the scopes of the Graph permissions, e.g. Application.Read.All, qualified with the
+// Graph endpoint of the cloud, to request a token with only the delegated permissions needed by the client.
+func GraphScopes(env azure.Environment, permissions ...string) []string {
+	scopes := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		scopes = append(scopes, msGraphEndpoint[env]+permission)
+	}
+	return scopes
+}
+
 func getGraphScope(env azure.Environment) string {
 	return fmt.Sprintf("%s.default", msGraphEndpoint[env])
 }
+
+// getGraphBaseURL returns the base URL of the v1.0 endpoint of Graph in the cloud, e.g.
+// https://graph.microsoft.us/v1.0 for Azure US Government. The Graph endpoint of an environment that is not
+// a known cloud, e.g. Azure Stack, is taken from the environment, and the Azure public cloud is the fallback.
+func getGraphBaseURL(env azure.Environment) string {
+	endpoint, ok := msGraphEndpoint[env]
+	if !ok {
+		endpoint = env.MicrosoftGraphEndpoint
+	}
+	if endpoint == "" || endpoint == azure.NotAvailable {
+		endpoint = msGraphEndpoint[azure.PublicCloud]
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1.0"
+}
+
+// getDefaultFederatedAudiences returns the token exchange audience of the cloud,
+// which is the audience of the Azure public cloud for the clouds without one.
+func getDefaultFederatedAudiences(env azure.Environment) []string {
+	if audience, ok := federatedCredentialAudience[env]; ok {
+		return []string{audience}
+	}
+	return []string{consts.DefaultAudience}
+}
@@ -0,0 +1,241 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/microsoft/kiota-abstractions-go/authentication"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/pkg/errors"
+)
+
+// newTestAzureClient returns an AzureClient with the graph service client
+// configured to send requests to a test server serving the given handler.
+func newTestAzureClient(t *testing.T, handler http.Handler) *AzureClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// the request adapter derives the request deadline from the client timeout
+	httpClient := server.Client()
+	httpClient.Timeout = 30 * time.Second
+	httpClient.Transport = &graphOperationTransport{next: &correlationIDTransport{next: NewThrottleRecordingTransport(httpClient.Transport)}}
+
+	breaker := newCircuitBreaker()
+	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(&authentication.AnonymousAuthenticationProvider{}, nil, nil, newCircuitBreakerClient(httpClient, breaker))
+	if err != nil {
+		t.Fatalf("failed to create request adapter: %v", err)
+	}
+	adapter.SetBaseUrl(server.URL + "/v1.0")
+
+	managedIdentitiesClient := autorest.NewClientWithUserAgent("")
+	managedIdentitiesClient.Sender = httpClient
+
+	graphServiceClient := msgraphsdk.NewGraphServiceClient(adapter)
+	env := azure.Environment{ResourceManagerEndpoint: server.URL}
+	return &AzureClient{
+		environment:               env,
+		defaultFederatedAudiences: getDefaultFederatedAudiences(env),
+		graphClient:               newGraphServiceClientAdapter(graphServiceClient),
+		graphServiceClient:        graphServiceClient,
+		graphCircuitBreaker:       breaker,
+		managedIdentitiesClient:   managedIdentitiesClient,
+		httpClient:                httpClient,
+		throttles:                 &throttleRecorder{},
+	}
+}
+
+// blockingHandler blocks until the request is canceled or the test ends.
+func blockingHandler(t *testing.T) http.Handler {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	c := newTestAzureClient(t, blockingHandler(t))
+	c.SetDefaultTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := c.GetApplication(context.Background(), "test")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected request to time out after the default timeout, took %s", elapsed)
+	}
+}
+
+func TestDefaultTimeoutEarlierDeadline(t *testing.T) {
+	c := newTestAzureClient(t, blockingHandler(t))
+	c.SetDefaultTimeout(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := c.DeleteApplication(ctx, "object-id"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+}
+
+func TestWithDefaultTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultTimeout time.Duration
+		ctxTimeout     time.Duration
+		wantDeadline   bool
+		wantMax        time.Duration
+	}{
+		{
+			name: "no default timeout",
+		},
+		{
+			name:           "default timeout",
+			defaultTimeout: time.Minute,
+			wantDeadline:   true,
+			wantMax:        time.Minute,
+		},
+		{
+			name:           "context with earlier deadline",
+			defaultTimeout: time.Hour,
+			ctxTimeout:     time.Minute,
+			wantDeadline:   true,
+			wantMax:        time.Minute,
+		},
+		{
+			name:           "context with later deadline",
+			defaultTimeout: time.Minute,
+			ctxTimeout:     time.Hour,
+			wantDeadline:   true,
+			wantMax:        time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AzureClient{defaultTimeout: tt.defaultTimeout}
+
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			ctx, cancel := c.withDefaultTimeout(ctx)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if ok != tt.wantDeadline {
+				t.Fatalf("expected deadline: %v, got: %v", tt.wantDeadline, ok)
+			}
+			if ok && time.Until(deadline) > tt.wantMax {
+				t.Errorf("expected deadline within %s, got %s", tt.wantMax, time.Until(deadline))
+			}
+		})
+	}
+}
+
+func TestGraphScopes(t *testing.T) {
+	got := GraphScopes(azure.ChinaCloud, "Application.Read.All", "DelegatedPermissionGrant.ReadWrite.All")
+	want := []string{
+		"https://microsoftgraph.chinacloudapi.cn/Application.Read.All",
+		"https://microsoftgraph.chinacloudapi.cn/DelegatedPermissionGrant.ReadWrite.All",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GraphScopes() = %v, want %v", got, want)
+	}
+}
+
+func TestGetGraphBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "AzurePublicCloud", want: "https://graph.microsoft.com/v1.0"},
+		{name: "AzureUSGovernmentCloud", want: "https://graph.microsoft.us/v1.0"},
+		{name: "AzureChinaCloud", want: "https://microsoftgraph.chinacloudapi.cn/v1.0"},
+		{name: "AzureGermanCloud", want: "https://graph.microsoft.de/v1.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := azure.EnvironmentFromName(test.name)
+			if err != nil {
+				t.Fatalf("EnvironmentFromName() error = %v", err)
+			}
+			if got := getGraphBaseURL(env); got != test.want {
+				t.Errorf("getGraphBaseURL() = %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	custom := azure.Environment{Name: "AzureStackCloud", MicrosoftGraphEndpoint: "https://graph.local.azurestack.external/"}
+	if got := getGraphBaseURL(custom); got != "https://graph.local.azurestack.external/v1.0" {
+		t.Errorf("getGraphBaseURL() = %s, want the Graph endpoint of the environment", got)
+	}
+}
+
+func TestAzureClientConcurrentUse(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "object-id", "appId": "app-id", "displayName": "app"}]}`)
+	})
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "sp-object-id", "appId": "app-id", "displayName": "app"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetApplicationCacheTTL(time.Minute)
+	c.SetServicePrincipalCacheTTL(time.Minute)
+	c.SetCircuitBreaker(5, time.Minute)
+	c.SetMetricsRecorder(&fakeMetricsRecorder{})
+	adapter := c.graphServiceClient.GetAdapter()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetApplication(ctx, "app"); err != nil {
+				t.Errorf("GetApplication() error = %v", err)
+			}
+			if _, err := c.GetServicePrincipal(ctx, "app"); err != nil {
+				t.Errorf("GetServicePrincipal() error = %v", err)
+			}
+			if _, err := c.GetServicePrincipalByAppID(ctx, "app-id"); err != nil {
+				t.Errorf("GetServicePrincipalByAppID() error = %v", err)
+			}
+			if _, err := c.GetFederatedCredential(ctx, "object-id", "https://issuer.example.com/", "system:serviceaccount:default:sa"); err != nil {
+				t.Errorf("GetFederatedCredential() error = %v", err)
+			}
+			_ = c.ThrottleStats()
+		}()
+	}
+	wg.Wait()
+
+	if c.graphServiceClient.GetAdapter() != adapter {
+		t.Error("expected the Graph request adapter to be reused")
+	}
+}
@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// cachedObject is a directory object that can be cached by display name and app ID,
+// i.e. an application or a service principal.
+type cachedObject interface {
+	GetId() *string
+	GetDisplayName() *string
+	GetAppId() *string
+}
+
+// objectCache is an in-memory cache of directory objects keyed by display name and app ID.
+// All methods are safe to call on a nil cache, which caches nothing.
+type objectCache[T cachedObject] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]objectCacheEntry[T]
+}
+
+type objectCacheEntry[T cachedObject] struct {
+	object    T
+	expiresAt time.Time
+}
+
+func newObjectCache[T cachedObject](ttl time.Duration) *objectCache[T] {
+	return &objectCache[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]objectCacheEntry[T]),
+	}
+}
+
+// displayNameCacheKey returns the cache key of an object with the given display name.
+func displayNameCacheKey(displayName string) string {
+	return "displayName/" + displayName
+}
+
+// appIDCacheKey returns the cache key of an object with the given app ID.
+func appIDCacheKey(appID string) string {
+	return "appID/" + appID
+}
+
+// get returns the cached object for the key if it hasn't expired.
+func (c *objectCache[T]) get(key string) (T, bool) {
+	var zero T
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return zero, false
+	}
+	return entry.object, true
+}
+
+// add caches the object by its app ID. Display names are not unique, so an object found by another
+// property is not cached by its display name, which would hide the other objects with the same display name.
+func (c *objectCache[T]) add(object T) {
+	c.addWithKeys(object, false)
+}
+
+// addByDisplayName caches the object by both its display name and app ID. It must only be called with the
+// object found by a display name lookup, i.e. the only object with the display name.
+func (c *objectCache[T]) addByDisplayName(object T) {
+	c.addWithKeys(object, true)
+}
+
+func (c *objectCache[T]) addWithKeys(object T, byDisplayName bool) {
+	if c == nil || any(object) == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := objectCacheEntry[T]{object: object, expiresAt: c.now().Add(c.ttl)}
+	if displayName := to.String(object.GetDisplayName()); byDisplayName && displayName != "" {
+		c.entries[displayNameCacheKey(displayName)] = entry
+	}
+	if appID := to.String(object.GetAppId()); appID != "" {
+		c.entries[appIDCacheKey(appID)] = entry
+	}
+}
+
+// evict removes the object with the given object ID from the cache.
+func (c *objectCache[T]) evict(objectID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if to.String(entry.object.GetId()) == objectID {
+			delete(c.entries, key)
+		}
+	}
+}
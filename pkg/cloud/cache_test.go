@@ -0,0 +1,167 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// newApplicationsHandler returns a handler serving a single application for any filter
+// and counting the number of application lookups.
+func newApplicationsHandler(lookups *int32) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(lookups, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "object-id", "appId": "app-id", "displayName": "app"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestApplicationCache(t *testing.T) {
+	var lookups int32
+	c := newTestAzureClient(t, newApplicationsHandler(&lookups))
+	c.SetApplicationCacheTTL(time.Minute)
+	now := time.Now()
+	c.applicationCache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := c.GetApplication(ctx, "app"); err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	// the application is cached by both its display name and app ID
+	if _, err := c.GetApplication(ctx, "app"); err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if _, err := c.GetApplicationByAppID(ctx, "app-id"); err != nil {
+		t.Fatalf("GetApplicationByAppID() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 1 {
+		t.Errorf("expected 1 Graph lookup within the TTL, got %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.GetApplicationByAppID(ctx, "app-id"); err != nil {
+		t.Fatalf("GetApplicationByAppID() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 2 {
+		t.Errorf("expected a Graph lookup after the TTL, got %d lookups", got)
+	}
+
+	if err := c.DeleteApplication(ctx, "object-id"); err != nil {
+		t.Fatalf("DeleteApplication() error = %v", err)
+	}
+	if _, err := c.GetApplication(ctx, "app"); err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 3 {
+		t.Errorf("expected a Graph lookup after delete, got %d lookups", got)
+	}
+}
+
+func TestApplicationCacheDisabledByDefault(t *testing.T) {
+	var lookups int32
+	c := newTestAzureClient(t, newApplicationsHandler(&lookups))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetApplication(ctx, "app"); err != nil {
+			t.Fatalf("GetApplication() error = %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&lookups); got != 2 {
+		t.Errorf("expected 2 Graph lookups without a cache, got %d", got)
+	}
+}
+
+func TestApplicationCacheNotKeyedByDisplayNameFromAppIDLookup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Query().Get("$filter"), "appId") {
+			fmt.Fprint(w, `{"value": [{"id": "object-id-1", "appId": "app-id-1", "displayName": "app"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "object-id-1", "appId": "app-id-1", "displayName": "app"}, {"id": "object-id-2", "appId": "app-id-2", "displayName": "app"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetApplicationCacheTTL(time.Minute)
+	ctx := context.Background()
+
+	if _, err := c.GetApplicationByAppID(ctx, "app-id-1"); err != nil {
+		t.Fatalf("GetApplicationByAppID() error = %v", err)
+	}
+	// the application found by app ID is not the only one with its display name
+	_, err := c.GetApplication(ctx, "app")
+	var merr *MultipleMatchesError
+	if !errors.As(err, &merr) {
+		t.Fatalf("expected a MultipleMatchesError, got %v", err)
+	}
+}
+
+func TestServicePrincipalCache(t *testing.T) {
+	var lookups int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "sp-object-id", "appId": "app-id", "displayName": "sp"}]}`)
+	})
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetServicePrincipalCacheTTL(time.Minute)
+	now := time.Now()
+	c.servicePrincipalCache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := c.GetServicePrincipal(ctx, "sp"); err != nil {
+		t.Fatalf("GetServicePrincipal() error = %v", err)
+	}
+	// the service principal is cached by both its display name and app ID
+	if _, err := c.GetServicePrincipal(ctx, "sp"); err != nil {
+		t.Fatalf("GetServicePrincipal() error = %v", err)
+	}
+	if _, err := c.GetServicePrincipalByAppID(ctx, "app-id"); err != nil {
+		t.Fatalf("GetServicePrincipalByAppID() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 1 {
+		t.Errorf("expected 1 Graph lookup within the TTL, got %d", got)
+	}
+
+	c.InvalidateCache("sp-object-id")
+	if _, err := c.GetServicePrincipalByAppID(ctx, "app-id"); err != nil {
+		t.Fatalf("GetServicePrincipalByAppID() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 2 {
+		t.Errorf("expected a Graph lookup after invalidation, got %d lookups", got)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.GetServicePrincipal(ctx, "sp"); err != nil {
+		t.Fatalf("GetServicePrincipal() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 3 {
+		t.Errorf("expected a Graph lookup after the TTL, got %d lookups", got)
+	}
+
+	if err := c.DeleteServicePrincipal(ctx, "sp-object-id"); err != nil {
+		t.Fatalf("DeleteServicePrincipal() error = %v", err)
+	}
+	if _, err := c.GetServicePrincipal(ctx, "sp"); err != nil {
+		t.Fatalf("GetServicePrincipal() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 4 {
+		t.Errorf("expected a Graph lookup after delete, got %d lookups", got)
+	}
+}
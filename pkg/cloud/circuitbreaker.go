@@ -0,0 +1,158 @@
+package cloud
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned when a Graph request is not sent because
+// the circuit breaker opened after too many consecutive Graph failures.
+var ErrCircuitOpen = errors.New("circuit breaker is open after consecutive Graph failures")
+
+type circuitState int
+
+const (
+	// circuitClosed lets all requests through.
+	circuitClosed circuitState = iota
+	// circuitOpen fails all requests fast until the cooldown elapses.
+	circuitOpen
+	// circuitHalfOpen lets a single trial request through to probe whether Graph has recovered.
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops sending requests for a cooldown after a number of consecutive failures.
+// A zero threshold disables the circuit breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// probing is true while the trial request of the half-open state is in flight
+	probing bool
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{now: time.Now}
+}
+
+// configure sets the consecutive failure threshold and the cooldown, and resets the circuit breaker.
+func (b *circuitBreaker) configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.threshold, b.cooldown = threshold, cooldown
+	b.state, b.failures, b.probing = circuitClosed, 0, false
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return nil
+	}
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+	case circuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record records the outcome of a request that was allowed.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return
+	}
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+	}
+}
+
+// release releases the trial request of the half-open state without recording an outcome.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state != state {
+		logDebug("Graph circuit breaker changed state", "from", b.state.String(), "to", state.String(), "failures", b.failures)
+	}
+	b.state = state
+}
+
+// circuitBreakerTransport is an http.RoundTripper that sends requests through the circuit breaker.
+// Transport errors, throttling and server errors count as failures.
+type circuitBreakerTransport struct {
+	breaker *circuitBreaker
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// the request was canceled by the caller, which says nothing about the health of Graph
+		t.breaker.release()
+		return resp, err
+	}
+	t.breaker.record(err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// newCircuitBreakerClient returns a copy of the client that sends requests through the circuit breaker.
+// If the client is nil, the default Graph client is used so that its middleware, e.g. retries on
+// throttling and redirect handling, is kept, since Graph only adds it to clients it creates itself.
+// The throttled responses are recorded below the middleware of the default Graph client.
+func newCircuitBreakerClient(client *http.Client, breaker *circuitBreaker) *http.Client {
+	if client == nil {
+		client = newDefaultGraphClient(defaultMaxRetries)
+	}
+	return wrapTransport(client, func(next http.RoundTripper) http.RoundTripper {
+		return &circuitBreakerTransport{breaker: breaker, next: next}
+	})
+}
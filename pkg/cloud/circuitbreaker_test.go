@@ -0,0 +1,121 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker()
+	b.configure(2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	mustAllow := func(t *testing.T) {
+		t.Helper()
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow() error = %v, want nil", err)
+		}
+	}
+	mustReject := func(t *testing.T) {
+		t.Helper()
+		if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("allow() error = %v, want %v", err, ErrCircuitOpen)
+		}
+	}
+
+	// closed: failures below the threshold and a success resetting the count
+	mustAllow(t)
+	b.record(false)
+	mustAllow(t)
+	b.record(true)
+	mustAllow(t)
+	b.record(false)
+	if b.state != circuitClosed {
+		t.Fatalf("expected closed state, got %s", b.state)
+	}
+
+	// open: the threshold of consecutive failures is reached
+	mustAllow(t)
+	b.record(false)
+	if b.state != circuitOpen {
+		t.Fatalf("expected open state, got %s", b.state)
+	}
+	mustReject(t)
+
+	// half-open: a single trial request after the cooldown, failing opens the circuit again
+	now = now.Add(time.Minute)
+	mustAllow(t)
+	if b.state != circuitHalfOpen {
+		t.Fatalf("expected half-open state, got %s", b.state)
+	}
+	mustReject(t)
+	b.record(false)
+	if b.state != circuitOpen {
+		t.Fatalf("expected open state, got %s", b.state)
+	}
+	mustReject(t)
+
+	// half-open: a successful trial request closes the circuit
+	now = now.Add(time.Minute)
+	mustAllow(t)
+	b.record(true)
+	if b.state != circuitClosed {
+		t.Fatalf("expected closed state, got %s", b.state)
+	}
+	mustAllow(t)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker()
+	for i := 0; i < 10; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow() error = %v, want nil", err)
+		}
+		b.record(false)
+	}
+}
+
+func TestAzureClientCircuitBreaker(t *testing.T) {
+	var requests int32
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	c.SetCircuitBreaker(2, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetApplication(context.Background(), "app"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("GetApplication() error = %v, want Graph error", err)
+		}
+	}
+	if _, err := c.GetApplication(context.Background(), "app"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetApplication() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("expected 2 requests to be sent, got %d", got)
+	}
+}
+
+func TestNewCircuitBreakerClientDefaultClient(t *testing.T) {
+	c := newCircuitBreakerClient(nil, newCircuitBreaker())
+	transport, ok := c.Transport.(*circuitBreakerTransport)
+	if !ok {
+		t.Fatalf("expected the transport to be a circuit breaker transport, got %T", c.Transport)
+	}
+	// the default Graph client sends requests through its middleware pipeline
+	if transport.next == http.DefaultTransport {
+		t.Errorf("expected the circuit breaker to wrap the default Graph client middleware, got http.DefaultTransport")
+	}
+}
+
+func TestSetCircuitBreakerWithoutBreaker(t *testing.T) {
+	c := &AzureClient{}
+	// must not panic
+	c.SetCircuitBreaker(1, time.Minute)
+}
@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// claimsMatchingExpressionKey is the property of the claims matching expression of a flexible federated
+	// identity credential, which is not modeled by the Graph SDK and is sent as additional data
+	claimsMatchingExpressionKey = "claimsMatchingExpression"
+
+	// claimsMatchingExpressionLanguageVersion is the version of the language of the claims matching expressions
+	claimsMatchingExpressionLanguageVersion = 1
+
+	subjectKey = "subject"
+)
+
+// SetClaimsMatchingExpression makes the federated identity credential a flexible federated identity credential,
+// which trusts the tokens whose claims match the expression rather than the tokens of a fixed subject, e.g.
+// claims['sub'] matches 'system:serviceaccount:*:workload-identity-sa' trusts the service account in every
+// namespace. The subject of the federated identity credential is cleared since Graph only accepts one of the two.
+// An empty expression removes the claims matching expression.
+func SetClaimsMatchingExpression(fic models.FederatedIdentityCredentialable, expression string) {
+	data := fic.GetAdditionalData()
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if expression == "" {
+		delete(data, claimsMatchingExpressionKey)
+		delete(data, subjectKey)
+	} else {
+		fic.SetSubject(nil)
+		// the subject is sent as null so that an update replaces the subject with the expression
+		data[subjectKey] = nil
+		data[claimsMatchingExpressionKey] = map[string]interface{}{
+			"value":           expression,
+			"languageVersion": claimsMatchingExpressionLanguageVersion,
+		}
+	}
+	fic.SetAdditionalData(data)
+}
+
+// GetClaimsMatchingExpression returns the claims matching expression of the federated identity credential,
+// or an empty string if it trusts a fixed subject.
+func GetClaimsMatchingExpression(fic models.FederatedIdentityCredentialable) string {
+	switch expression := fic.GetAdditionalData()[claimsMatchingExpressionKey].(type) {
+	case map[string]interface{}:
+		// the expression is set by SetClaimsMatchingExpression as a string, and deserialized by the Graph SDK
+		// as a string pointer
+		switch value := expression["value"].(type) {
+		case string:
+			return value
+		case *string:
+			return to.String(value)
+		default:
+			return ""
+		}
+	case map[string]*jsonserialization.JsonParseNode:
+		// the expression of a federated identity credential returned by Graph
+		if expression["value"] == nil {
+			return ""
+		}
+		value, err := expression["value"].GetStringValue()
+		if err != nil || value == nil {
+			return ""
+		}
+		return *value
+	default:
+		return ""
+	}
+}
+
+// validateFederatedCredentialMatching returns an error unless exactly one of the subject and the
+// claims matching expression of a federated identity credential is set.
+func validateFederatedCredentialMatching(subject, expression string) error {
+	switch {
+	case subject == "" && expression == "":
+		return errors.New("federated credential must have a subject or a claims matching expression")
+	case subject != "" && expression != "":
+		return errors.Errorf("federated credential has both subject %q and claims matching expression %q, but only one is allowed", subject, expression)
+	}
+	return nil
+}
+
+// GetFederatedCredentialByClaimsMatchingExpression gets the flexible federated credential of the application with
+// the given issuer and claims matching expression, which GetFederatedCredential can't find as it has no subject.
+// Graph doesn't support filtering on the claims matching expression, so all the federated credentials of the
+// application are listed. ErrFederatedCredentialNotFound is returned if there is none.
+func (c *AzureClient) GetFederatedCredentialByClaimsMatchingExpression(ctx context.Context, objectID, issuer, expression string) (models.FederatedIdentityCredentialable, error) {
+	c.logDebug("Getting federated credential",
+		"objectID", objectID,
+		"issuer", issuer,
+		"claimsMatchingExpression", expression,
+	)
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fic := range fics {
+		if to.String(fic.GetIssuer()) == issuer && GetClaimsMatchingExpression(fic) == expression {
+			return fic, nil
+		}
+	}
+	return nil, ErrFederatedCredentialNotFound
+}
@@ -0,0 +1,212 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+const testClaimsMatchingExpression = "claims['sub'] matches 'system:serviceaccount:*:workload-identity-sa'"
+
+func TestSetClaimsMatchingExpression(t *testing.T) {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+
+	SetClaimsMatchingExpression(fic, testClaimsMatchingExpression)
+	if got := GetClaimsMatchingExpression(fic); got != testClaimsMatchingExpression {
+		t.Errorf("GetClaimsMatchingExpression() = %q, want %q", got, testClaimsMatchingExpression)
+	}
+	if fic.GetSubject() != nil {
+		t.Errorf("expected the subject to be cleared, got %q", *fic.GetSubject())
+	}
+
+	SetClaimsMatchingExpression(fic, "")
+	if got := GetClaimsMatchingExpression(fic); got != "" {
+		t.Errorf("expected the claims matching expression to be removed, got %q", got)
+	}
+	if len(fic.GetAdditionalData()) != 0 {
+		t.Errorf("expected no additional data, got %v", fic.GetAdditionalData())
+	}
+}
+
+func TestValidateFederatedCredentialMatching(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    string
+		expression string
+		errorMsg   string
+	}{
+		{
+			name:    "subject",
+			subject: "system:serviceaccount:default:sa",
+		},
+		{
+			name:       "claims matching expression",
+			expression: testClaimsMatchingExpression,
+		},
+		{
+			name:     "neither",
+			errorMsg: "federated credential must have a subject or a claims matching expression",
+		},
+		{
+			name:       "both",
+			subject:    "system:serviceaccount:default:sa",
+			expression: testClaimsMatchingExpression,
+			errorMsg:   fmt.Sprintf("federated credential has both subject %q and claims matching expression %q, but only one is allowed", "system:serviceaccount:default:sa", testClaimsMatchingExpression),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateFederatedCredentialMatching(test.subject, test.expression)
+			if test.errorMsg == "" {
+				if err != nil {
+					t.Errorf("validateFederatedCredentialMatching() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("validateFederatedCredentialMatching() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialClaimsMatchingExpression(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"name": "fic"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+	SetClaimsMatchingExpression(fic, testClaimsMatchingExpression)
+
+	if _, err := c.AddFederatedCredential(context.Background(), "object-id", fic); err != nil {
+		t.Fatalf("AddFederatedCredential() error = %v", err)
+	}
+	subject, ok := body["subject"]
+	if !ok || subject != nil {
+		t.Errorf("expected the subject to be null, got %v", subject)
+	}
+	expression, _ := body["claimsMatchingExpression"].(map[string]interface{})
+	if got := expression["value"]; got != testClaimsMatchingExpression {
+		t.Errorf("expected claims matching expression to be %q, got %v", testClaimsMatchingExpression, got)
+	}
+	if got := expression["languageVersion"]; got != float64(claimsMatchingExpressionLanguageVersion) {
+		t.Errorf("expected language version to be %d, got %v", claimsMatchingExpressionLanguageVersion, got)
+	}
+}
+
+func TestAddFederatedCredentialSubjectAndClaimsMatchingExpression(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusCreated)
+	})
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		name     string
+		setup    func(fic models.FederatedIdentityCredentialable)
+		errorMsg string
+	}{
+		{
+			name:     "neither",
+			setup:    func(fic models.FederatedIdentityCredentialable) {},
+			errorMsg: "federated credential must have a subject or a claims matching expression",
+		},
+		{
+			name: "both",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				SetClaimsMatchingExpression(fic, testClaimsMatchingExpression)
+				fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			},
+			errorMsg: fmt.Sprintf("federated credential has both subject %q and claims matching expression %q, but only one is allowed", "system:serviceaccount:default:sa", testClaimsMatchingExpression),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+			test.setup(fic)
+
+			_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("AddFederatedCredential() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("expected no request to be sent, got %d", got)
+	}
+}
+
+func TestGetClaimsMatchingExpressionFromGraph(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value": [{"name": "fic-1", "subject": "system:serviceaccount:default:sa"}, {"name": "fic-2", "subject": null, "claimsMatchingExpression": {"value": %q, "languageVersion": 1}}]}`, testClaimsMatchingExpression)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fics, err := c.ListFederatedCredentials(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("ListFederatedCredentials() error = %v", err)
+	}
+	if len(fics) != 2 {
+		t.Fatalf("expected 2 federated credentials, got %d", len(fics))
+	}
+	if got := GetClaimsMatchingExpression(fics[0]); got != "" {
+		t.Errorf("expected no claims matching expression for fic-1, got %q", got)
+	}
+	if got := GetClaimsMatchingExpression(fics[1]); got != testClaimsMatchingExpression {
+		t.Errorf("GetClaimsMatchingExpression() = %q, want %q", got, testClaimsMatchingExpression)
+	}
+}
+
+func TestGetFederatedCredentialByClaimsMatchingExpression(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value": [
+			{"id": "fic-1-id", "name": "fic-1", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa"},
+			{"id": "fic-2-id", "name": "fic-2", "issuer": "https://other-issuer.example.com/", "subject": null, "claimsMatchingExpression": {"value": %[1]q, "languageVersion": 1}},
+			{"id": "fic-3-id", "name": "fic-3", "issuer": "https://issuer.example.com/", "subject": null, "claimsMatchingExpression": {"value": %[1]q, "languageVersion": 1}}
+		]}`, testClaimsMatchingExpression)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic, err := c.GetFederatedCredentialByClaimsMatchingExpression(context.Background(), "object-id", "https://issuer.example.com/", testClaimsMatchingExpression)
+	if err != nil {
+		t.Fatalf("GetFederatedCredentialByClaimsMatchingExpression() error = %v", err)
+	}
+	if got := to.String(fic.GetId()); got != "fic-3-id" {
+		t.Errorf("GetFederatedCredentialByClaimsMatchingExpression() = %s, want fic-3-id", got)
+	}
+
+	_, err = c.GetFederatedCredentialByClaimsMatchingExpression(context.Background(), "object-id", "https://issuer.example.com/", "claims['sub'] matches 'system:serviceaccount:other:*'")
+	if !errors.Is(err, ErrFederatedCredentialNotFound) {
+		t.Errorf("expected ErrFederatedCredentialNotFound, got %v", err)
+	}
+}
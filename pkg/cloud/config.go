@@ -0,0 +1,574 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	azcloud "github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-01-01-preview/authorization"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/microsoft/kiota-abstractions-go/authentication"
+	khttp "github.com/microsoft/kiota-http-go"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	"monis.app/mlog"
+)
+
+const (
+	// defaultMaxRetries is the maximum number of retries of the throttled or unavailable Graph requests.
+	defaultMaxRetries = 3
+	// maxMaxRetries is the highest maximum number of retries, which is the limit of the retry middleware of the Graph SDK.
+	maxMaxRetries = 10
+)
+
+// config configures an AzureClient created with NewAzureClient, see the options for the fields.
+// The zero value of a field stands for its default, so the zero config is valid.
+type config struct {
+	Environment    azure.Environment
+	SubscriptionID string
+	TenantID       string
+
+	Credential  azcore.TokenCredential
+	GraphScopes []string
+
+	HTTPClient *http.Client
+	Transport  http.RoundTripper
+	UserAgent  string
+
+	Timeout        time.Duration
+	MaxRetries     int
+	RateLimit      float64
+	WriteRateLimit float64
+
+	CircuitBreakerThreshold         int
+	CircuitBreakerCooldown          time.Duration
+	ApplicationCacheTTL             time.Duration
+	ServicePrincipalCacheTTL        time.Duration
+	DefaultFederatedAudiences       []string
+	FederatedCredentialPollInterval time.Duration
+	ServicePrincipalPollInterval    time.Duration
+	AllowForeignServicePrincipals   bool
+	AllowedIssuers                  []string
+	PageSize                        int
+	PartialResults                  bool
+	MetricsRecorder                 MetricsRecorder
+	DryRun                          bool
+	Logger                          mlog.Logger
+}
+
+// Option configures an AzureClient created with NewAzureClient. The options are applied in order, so a later
+// option overrides an earlier one.
+type Option func(*config)
+
+// WithCloudEnvironment sets the Azure cloud. It defaults to the Azure public cloud.
+func WithCloudEnvironment(env azure.Environment) Option {
+	return func(cfg *config) {
+		cfg.Environment = env
+	}
+}
+
+// WithSubscriptionID sets the subscription of the role assignments.
+func WithSubscriptionID(subscriptionID string) Option {
+	return func(cfg *config) {
+		cfg.SubscriptionID = subscriptionID
+	}
+}
+
+// WithTenantID sets the AAD tenant the client authenticates against.
+func WithTenantID(tenantID string) Option {
+	return func(cfg *config) {
+		cfg.TenantID = tenantID
+	}
+}
+
+// WithGraphScopes sets the scopes requested for the Graph requests, see GraphScopes and the package documentation
+// for the permissions of the methods. They default to the .default scope of the Graph endpoint of the cloud, which
+// is the only scope of the tokens issued to applications.
+func WithGraphScopes(scopes ...string) Option {
+	return func(cfg *config) {
+		cfg.GraphScopes = scopes
+	}
+}
+
+// WithHTTPClient sets the HTTP client that sends the requests. It defaults to a client with the Graph middleware,
+// e.g. retries on throttling. A custom client is used as is for the Graph requests, except that the requests carry
+// the retry options of WithMaxRetries, which the retry middleware of the Graph SDK uses instead of its own. Its
+// transport sees every Graph and ARM request, e.g. to record and replay the interactions with a tenant in tests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *config) {
+		cfg.HTTPClient = client
+	}
+}
+
+// WithTransport sets the transport below the Graph middleware of the default HTTP client, e.g. with a proxy or the
+// root CAs of a TLS-intercepting proxy in a locked-down network, which also sends the ARM and token requests.
+// It is ignored if WithHTTPClient is set. It defaults to a clone of http.DefaultTransport, which honors the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(cfg *config) {
+		cfg.Transport = transport
+	}
+}
+
+// WithUserAgent sets the user agent prepended to the user agent of the requests. It defaults to the user agent
+// of the SDKs.
+func WithUserAgent(userAgent string) Option {
+	return func(cfg *config) {
+		cfg.UserAgent = userAgent
+	}
+}
+
+// WithTimeout sets the timeout applied to each operation if the context passed by the caller doesn't have an
+// earlier deadline. It defaults to no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithMaxRetries sets the maximum number of retries of the throttled or unavailable Graph requests, at most 10.
+// The requests creating objects are only retried when throttled or refused, see IsAmbiguousCreateError.
+// It defaults to 3; a negative value is clamped to 0, which disables the retries. NewAzureClient rejects
+// a value above 10.
+func WithMaxRetries(maxRetries int) Option {
+	return func(cfg *config) {
+		cfg.MaxRetries = maxRetries
+	}
+}
+
+// WithRateLimit sets the maximum number of Graph requests per second. It defaults to no rate limit.
+func WithRateLimit(limit float64) Option {
+	return func(cfg *config) {
+		cfg.RateLimit = limit
+	}
+}
+
+// WithWriteRateLimit sets the maximum number of Graph requests per second that create, update or delete objects,
+// see SetWriteRateLimit. It defaults to no rate limit.
+func WithWriteRateLimit(limit float64) Option {
+	return func(cfg *config) {
+		cfg.WriteRateLimit = limit
+	}
+}
+
+// WithCircuitBreaker configures the circuit breaker of the Graph requests, see SetCircuitBreaker. The circuit
+// breaker is disabled by default.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(cfg *config) {
+		cfg.CircuitBreakerThreshold = threshold
+		cfg.CircuitBreakerCooldown = cooldown
+	}
+}
+
+// WithApplicationCacheTTL enables the application cache, see SetApplicationCacheTTL. It is disabled by default.
+func WithApplicationCacheTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.ApplicationCacheTTL = ttl
+	}
+}
+
+// WithServicePrincipalCacheTTL enables the service principal cache, see SetServicePrincipalCacheTTL. It is
+// disabled by default.
+func WithServicePrincipalCacheTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.ServicePrincipalCacheTTL = ttl
+	}
+}
+
+// WithDefaultFederatedAudiences sets the audiences of the federated credentials added without audiences, see
+// SetDefaultFederatedAudiences. They default to the token exchange audience of the cloud.
+func WithDefaultFederatedAudiences(audiences ...string) Option {
+	return func(cfg *config) {
+		cfg.DefaultFederatedAudiences = audiences
+	}
+}
+
+// WithFederatedCredentialPollInterval sets the interval at which WaitForFederatedCredential polls. It defaults
+// to 2 seconds.
+func WithFederatedCredentialPollInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.FederatedCredentialPollInterval = interval
+	}
+}
+
+// WithServicePrincipalPollInterval sets the interval at which WaitForServicePrincipal polls. It defaults to 2 seconds.
+func WithServicePrincipalPollInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.ServicePrincipalPollInterval = interval
+	}
+}
+
+// WithForeignServicePrincipals allows GetServicePrincipal to return service principals of applications owned by
+// another organization. They are excluded by default.
+func WithForeignServicePrincipals(allow bool) Option {
+	return func(cfg *config) {
+		cfg.AllowForeignServicePrincipals = allow
+	}
+}
+
+// WithAllowedIssuers sets the only issuers AddFederatedCredential accepts, see SetAllowedIssuers. Any issuer is
+// accepted by default.
+func WithAllowedIssuers(issuers ...string) Option {
+	return func(cfg *config) {
+		cfg.AllowedIssuers = issuers
+	}
+}
+
+// WithPageSize sets the number of items per page requested by the list methods, see SetPageSize. It defaults to
+// the page size of Graph.
+func WithPageSize(size int) Option {
+	return func(cfg *config) {
+		cfg.PageSize = size
+	}
+}
+
+// WithPartialResults makes the list methods return the items listed before a page fails, along with the error,
+// see SetPartialResults. They return no items on error by default.
+func WithPartialResults(partialResults bool) Option {
+	return func(cfg *config) {
+		cfg.PartialResults = partialResults
+	}
+}
+
+// WithMetricsRecorder sets the recorder of the Graph operations, see SetMetricsRecorder. Nothing is recorded
+// by default.
+func WithMetricsRecorder(recorder MetricsRecorder) Option {
+	return func(cfg *config) {
+		cfg.MetricsRecorder = recorder
+	}
+}
+
+// WithDryRun makes the operations that create, update or delete objects log their action instead of sending it,
+// see SetDryRun. It is disabled by default.
+func WithDryRun(dryRun bool) Option {
+	return func(cfg *config) {
+		cfg.DryRun = dryRun
+	}
+}
+
+// WithLogger sets the logger of the operations of the client, e.g. with the values of a caller added with
+// WithValues. It defaults to the global logger.
+func WithLogger(logger mlog.Logger) Option {
+	return func(cfg *config) {
+		cfg.Logger = logger
+	}
+}
+
+// NewAzureClient returns an AzureClient that authenticates the Graph and ARM requests with the credential,
+// configured with the options. A nil credential defaults to azidentity's DefaultAzureCredential, which tries the
+// environment variables, workload identity, managed identity and the Azure CLI in turn. Without options, the
+// client has the defaults documented by each option. A token is requested once so that a misconfiguration is
+// reported when the client is created.
+func NewAzureClient(ctx context.Context, cred azcore.TokenCredential, opts ...Option) (*AzureClient, error) {
+	cfg := config{Credential: cred}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	if cred == nil {
+		options := &azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: azcore.ClientOptions{
+				Cloud: azcloud.Configuration{ActiveDirectoryAuthorityHost: cfg.Environment.ActiveDirectoryEndpoint},
+			},
+		}
+		if client := cfg.httpClient(); client != nil {
+			options.ClientOptions.Transport = client
+		}
+		var err error
+		if cred, err = azidentity.NewDefaultAzureCredential(options); err != nil {
+			return nil, errors.Wrap(err, "failed to create credential")
+		}
+	}
+	if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: cfg.GraphScopes}); err != nil {
+		return nil, errors.Wrap(err, "failed to get token")
+	}
+
+	return newAzureClientWithTokenCredential(cfg, cred)
+}
+
+// validate returns an error if a field of the config is invalid.
+func (cfg config) validate() error {
+	if cfg.MaxRetries > maxMaxRetries {
+		return errors.Errorf("max retries %d exceeds the maximum of %d", cfg.MaxRetries, maxMaxRetries)
+	}
+	return nil
+}
+
+// withDefaults returns the config with the defaults applied to the zero fields that the client doesn't default itself.
+func (cfg config) withDefaults() config {
+	if cfg.Environment.Name == "" {
+		cfg.Environment = azure.PublicCloud
+	}
+	if len(cfg.GraphScopes) == 0 {
+		cfg.GraphScopes = []string{getGraphScope(cfg.Environment)}
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if len(cfg.DefaultFederatedAudiences) == 0 {
+		cfg.DefaultFederatedAudiences = getDefaultFederatedAudiences(cfg.Environment)
+	}
+	return cfg
+}
+
+// httpClient returns the HTTP client of the requests that are not sent by the Graph SDK, e.g. the ARM and token
+// requests: the HTTPClient, or a client with the Transport, or nil for the default client of each SDK.
+func (cfg config) httpClient() *http.Client {
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+	if cfg.Transport != nil {
+		return &http.Client{Transport: cfg.Transport}
+	}
+	return nil
+}
+
+// newAzureClient returns an AzureClient that authorizes the ARM requests with the authorizer and
+// the Graph requests with the authentication provider. The defaults must be applied to the config.
+func newAzureClient(cfg config, armAuthorizer autorest.Authorizer, auth authentication.AuthenticationProvider) (*AzureClient, error) {
+	graphClient := cfg.HTTPClient
+	if graphClient == nil {
+		graphClient = newDefaultGraphClientWithTransport(cfg.MaxRetries, cfg.Transport)
+	} else {
+		graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+			return &correlationIDTransport{next: next}
+		})
+		// the retry middleware of a custom client, e.g. the one of NewGraphMiddlewares, must not send a create
+		// request again after an ambiguous response either
+		retryOptions := newGraphRetryHandlerOptions(cfg.MaxRetries)
+		graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+			return &retryOptionsTransport{options: retryOptions, next: next}
+		})
+	}
+	breaker := newCircuitBreaker()
+	breaker.configure(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+	graphClient = newCircuitBreakerClient(graphClient, breaker)
+	if cfg.RateLimit > 0 {
+		limiter := rate.NewLimiter(rate.Limit(cfg.RateLimit), 1)
+		graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+			return &rateLimitTransport{limiter: limiter, next: next}
+		})
+	}
+	writeLimiter := &writeRateLimiter{}
+	writeLimiter.configure(cfg.WriteRateLimit, 1)
+	graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+		return &writeRateLimitTransport{limiter: writeLimiter, next: next}
+	})
+	if cfg.UserAgent != "" {
+		graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+			return &userAgentTransport{userAgent: cfg.UserAgent, next: next}
+		})
+	}
+	graphClient = wrapTransport(graphClient, func(next http.RoundTripper) http.RoundTripper {
+		return &graphOperationTransport{next: next}
+	})
+
+	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(auth, nil, nil, graphClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request adapter")
+	}
+	// the Graph service client only defaults the base URL to the Azure public cloud
+	adapter.SetBaseUrl(getGraphBaseURL(cfg.Environment))
+	graphServiceClient := msgraphsdk.NewGraphServiceClient(adapter)
+
+	azClient := &AzureClient{
+		environment:                   cfg.Environment,
+		subscriptionID:                cfg.SubscriptionID,
+		tenantID:                      cfg.TenantID,
+		allowForeignServicePrincipals: cfg.AllowForeignServicePrincipals,
+		partialResults:                cfg.PartialResults,
+
+		graphClient:         newGraphServiceClientAdapter(graphServiceClient),
+		graphServiceClient:  graphServiceClient,
+		graphCircuitBreaker: breaker,
+		graphWriteLimiter:   writeLimiter,
+
+		roleAssignmentsClient: authorization.NewRoleAssignmentsClientWithBaseURI(cfg.Environment.ResourceManagerEndpoint, cfg.SubscriptionID),
+		roleDefinitionsClient: authorization.NewRoleDefinitionsClientWithBaseURI(cfg.Environment.ResourceManagerEndpoint, cfg.SubscriptionID),
+
+		managedIdentitiesClient: autorest.NewClientWithUserAgent(cfg.UserAgent),
+
+		httpClient:                      cfg.httpClient(),
+		defaultTimeout:                  cfg.Timeout,
+		defaultFederatedAudiences:       append([]string(nil), cfg.DefaultFederatedAudiences...),
+		federatedCredentialPollInterval: cfg.FederatedCredentialPollInterval,
+		servicePrincipalPollInterval:    cfg.ServicePrincipalPollInterval,
+		throttles:                       &throttleRecorder{},
+		metrics:                         cfg.MetricsRecorder,
+		dryRun:                          cfg.DryRun,
+		logger:                          cfg.Logger,
+	}
+	if azClient.httpClient == nil {
+		azClient.httpClient = http.DefaultClient
+	}
+	azClient.SetApplicationCacheTTL(cfg.ApplicationCacheTTL)
+	azClient.SetServicePrincipalCacheTTL(cfg.ServicePrincipalCacheTTL)
+	azClient.SetAllowedIssuers(cfg.AllowedIssuers)
+	if err := azClient.SetPageSize(cfg.PageSize); err != nil {
+		return nil, err
+	}
+
+	if p, ok := auth.(interface {
+		GetAuthorizationTokenProvider() authentication.AccessTokenProvider
+	}); ok {
+		azClient.graphTokenProvider = p.GetAuthorizationTokenProvider()
+	}
+
+	azClient.roleAssignmentsClient.Authorizer = armAuthorizer
+	azClient.roleDefinitionsClient.Authorizer = armAuthorizer
+	azClient.managedIdentitiesClient.Authorizer = armAuthorizer
+
+	if cfg.UserAgent != "" {
+		if err := azClient.roleAssignmentsClient.AddToUserAgent(cfg.UserAgent); err != nil {
+			return nil, errors.Wrap(err, "failed to add user agent")
+		}
+		if err := azClient.roleDefinitionsClient.AddToUserAgent(cfg.UserAgent); err != nil {
+			return nil, errors.Wrap(err, "failed to add user agent")
+		}
+	}
+
+	// a nil client is not assigned since the autorest clients only fall back to their default sender if it is unset
+	if client := cfg.httpClient(); client != nil {
+		azClient.roleAssignmentsClient.Sender = client
+		azClient.roleDefinitionsClient.Sender = client
+		azClient.managedIdentitiesClient.Sender = client
+	}
+
+	return azClient, nil
+}
+
+// newDefaultGraphClient returns the default Graph client, whose middleware retries the throttled or
+// unavailable requests at most maxRetries times, see isRetriableGraphRequest for the requests that are
+// retried. The requests whose connection is refused are retried below the middleware, where the throttled
+// responses are recorded and the correlation IDs are set.
+func newDefaultGraphClient(maxRetries int) *http.Client {
+	return newDefaultGraphClientWithTransport(maxRetries, nil)
+}
+
+// newDefaultGraphClientWithTransport returns the default Graph client like newDefaultGraphClient, with the
+// transport below its middleware. A nil transport is the default transport of the Graph SDK.
+func newDefaultGraphClientWithTransport(maxRetries int, base http.RoundTripper) *http.Client {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if base == nil {
+		base = khttp.GetDefaultTransport()
+	}
+	options := msgraphsdk.GetDefaultClientOptions()
+	middlewares := newGraphMiddlewares(&options, maxRetries)
+	client := msgraphcore.GetDefaultClient(&options, middlewares...)
+	transport := &connectionRefusedRetryTransport{
+		next:       base,
+		maxRetries: maxRetries,
+		delay:      defaultConnectionRefusedRetryDelay,
+	}
+	client.Transport = khttp.NewCustomTransportWithParentTransport(&correlationIDTransport{next: NewThrottleRecordingTransport(transport)}, middlewares...)
+	return client
+}
+
+// wrapTransport returns a copy of the client whose transport is wrapped by wrap.
+func wrapTransport(client *http.Client, wrap func(next http.RoundTripper) http.RoundTripper) *http.Client {
+	c := &http.Client{}
+	*c = *client
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c.Transport = wrap(next)
+	return c
+}
+
+// rateLimitTransport is an http.RoundTripper that waits for the rate limiter before sending a request.
+type rateLimitTransport struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, errors.Wrap(err, "failed to wait for the Graph rate limit")
+	}
+	return t.next.RoundTrip(req)
+}
+
+// writeRateLimiter limits the rate of the Graph requests that create, update or delete objects, which Graph
+// throttles at a lower rate than the reads. It is disabled unless a limit is set.
+type writeRateLimiter struct {
+	mu      sync.Mutex
+	limiter *rate.Limiter
+}
+
+// configure sets the maximum number of requests per second and the burst of requests sent at once.
+// A zero limit disables the rate limiter.
+func (l *writeRateLimiter) configure(limit float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit <= 0 {
+		l.limiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.limiter = rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// wait blocks until a request is allowed by the rate limiter or the context is done.
+func (l *writeRateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	limiter := l.limiter
+	l.mu.Unlock()
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// writeRateLimitTransport is an http.RoundTripper that waits for the write rate limiter before sending a request
+// that is not a read, i.e. a POST, PATCH, PUT or DELETE request.
+type writeRateLimitTransport struct {
+	limiter *writeRateLimiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *writeRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if err := t.limiter.wait(req.Context()); err != nil {
+			return nil, errors.Wrap(err, "failed to wait for the Graph write rate limit")
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// userAgentTransport is an http.RoundTripper that prepends the user agent to the User-Agent header of the requests.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if current := req.Header.Get("User-Agent"); current != "" {
+		req.Header.Set("User-Agent", t.userAgent+" "+current)
+	} else {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
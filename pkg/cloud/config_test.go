@@ -0,0 +1,559 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"golang.org/x/time/rate"
+	"monis.app/mlog"
+)
+
+func TestConfigWithDefaults(t *testing.T) {
+	cfg := config{}.withDefaults()
+	if cfg.Environment != azure.PublicCloud {
+		t.Errorf("expected the environment to default to %s, got %s", azure.PublicCloud.Name, cfg.Environment.Name)
+	}
+	if len(cfg.GraphScopes) != 1 || cfg.GraphScopes[0] != "https://graph.microsoft.com/.default" {
+		t.Errorf("expected the Graph scopes to default to the .default scope, got %v", cfg.GraphScopes)
+	}
+	if cfg.MaxRetries != defaultMaxRetries {
+		t.Errorf("expected max retries to default to %d, got %d", defaultMaxRetries, cfg.MaxRetries)
+	}
+	if len(cfg.DefaultFederatedAudiences) != 1 || cfg.DefaultFederatedAudiences[0] != "api://AzureADTokenExchange" {
+		t.Errorf("expected the default federated audiences to default to api://AzureADTokenExchange, got %v", cfg.DefaultFederatedAudiences)
+	}
+
+	cfg = config{
+		Environment: azure.USGovernmentCloud,
+		GraphScopes: []string{"https://graph.microsoft.us/Application.ReadWrite.All"},
+		MaxRetries:  -1,
+	}.withDefaults()
+	if cfg.Environment != azure.USGovernmentCloud {
+		t.Errorf("expected the environment to be %s, got %s", azure.USGovernmentCloud.Name, cfg.Environment.Name)
+	}
+	if len(cfg.GraphScopes) != 1 || cfg.GraphScopes[0] != "https://graph.microsoft.us/Application.ReadWrite.All" {
+		t.Errorf("expected the Graph scopes to be kept, got %v", cfg.GraphScopes)
+	}
+	if cfg.MaxRetries != -1 {
+		t.Errorf("expected max retries to be kept, got %d", cfg.MaxRetries)
+	}
+	if len(cfg.DefaultFederatedAudiences) != 1 || cfg.DefaultFederatedAudiences[0] != "api://AzureADTokenExchangeUSGov" {
+		t.Errorf("expected the default federated audiences to be the audience of the cloud, got %v", cfg.DefaultFederatedAudiences)
+	}
+}
+
+func TestNewAzureClient(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		userAgents []string
+		hosts      []string
+	)
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": "sp-object-id", "servicePrincipalType": "Application"}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	cred := &fakeTokenCredential{}
+	c, err := NewAzureClient(context.Background(), cred,
+		WithCloudEnvironment(azure.ChinaCloud),
+		WithSubscriptionID("subscription-id"),
+		WithTenantID("tenant-id"),
+		WithHTTPClient(client),
+		WithUserAgent("azwi-test"),
+		WithTimeout(time.Minute),
+		WithRateLimit(1000),
+		WithWriteRateLimit(100),
+		WithCircuitBreaker(5, time.Second),
+		WithApplicationCacheTTL(time.Hour),
+		WithFederatedCredentialPollInterval(time.Millisecond),
+		WithServicePrincipalPollInterval(time.Millisecond),
+		WithForeignServicePrincipals(true),
+		WithAllowedIssuers("https://issuer.example.com/"),
+		WithPartialResults(true),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+
+	if c.environment != azure.ChinaCloud {
+		t.Errorf("expected environment %s, got %s", azure.ChinaCloud.Name, c.environment.Name)
+	}
+	if c.subscriptionID != "subscription-id" || c.tenantID != "tenant-id" {
+		t.Errorf("expected subscription ID subscription-id and tenant ID tenant-id, got %s and %s", c.subscriptionID, c.tenantID)
+	}
+	if c.httpClient != client {
+		t.Errorf("expected the HTTP client to be used")
+	}
+	if c.defaultTimeout != time.Minute {
+		t.Errorf("expected default timeout %s, got %s", time.Minute, c.defaultTimeout)
+	}
+	if c.graphCircuitBreaker.threshold != 5 || c.graphCircuitBreaker.cooldown != time.Second {
+		t.Errorf("expected circuit breaker threshold 5 and cooldown 1s, got %d and %s", c.graphCircuitBreaker.threshold, c.graphCircuitBreaker.cooldown)
+	}
+	if c.graphWriteLimiter.limiter == nil || c.graphWriteLimiter.limiter.Limit() != 100 {
+		t.Errorf("expected the write rate limit to be 100")
+	}
+	if c.applicationCache == nil {
+		t.Errorf("expected the application cache to be enabled")
+	}
+	if c.federatedCredentialPollInterval != time.Millisecond {
+		t.Errorf("expected federated credential poll interval 1ms, got %s", c.federatedCredentialPollInterval)
+	}
+	if c.servicePrincipalPollInterval != time.Millisecond {
+		t.Errorf("expected service principal poll interval 1ms, got %s", c.servicePrincipalPollInterval)
+	}
+	if !c.partialResults {
+		t.Errorf("expected partial results to be enabled")
+	}
+	if !c.allowForeignServicePrincipals {
+		t.Errorf("expected foreign service principals to be allowed")
+	}
+	if !reflect.DeepEqual(c.allowedIssuers, []string{"https://issuer.example.com"}) {
+		t.Errorf("expected the allowed issuers to be normalized, got %v", c.allowedIssuers)
+	}
+	// the token is requested once when the client is created
+	if len(cred.scopes) != 1 || strings.Join(cred.scopes[0], " ") != getGraphScope(azure.ChinaCloud) {
+		t.Errorf("expected a token to be requested for the Graph scope of the cloud, got %v", cred.scopes)
+	}
+
+	if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if len(userAgents) != 1 || !strings.HasPrefix(userAgents[0], "azwi-test") {
+		t.Errorf("expected the user agent to be prepended, got %v", userAgents)
+	}
+	if len(hosts) != 1 || hosts[0] != "microsoftgraph.chinacloudapi.cn" {
+		t.Errorf("expected the request to be sent to the Graph endpoint of the cloud, got %v", hosts)
+	}
+}
+
+func TestNewAzureClientGraphScopes(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": "sp-object-id", "servicePrincipalType": "Application"}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	cred := &fakeTokenCredential{}
+	scopes := GraphScopes(azure.USGovernmentCloud, "Application.Read.All")
+	c, err := NewAzureClient(context.Background(), cred,
+		WithCloudEnvironment(azure.USGovernmentCloud),
+		WithGraphScopes(scopes...),
+		WithHTTPClient(client),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+	if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+
+	// the token is requested for the scopes when the client is created and by the adapter for the request
+	want := [][]string{{"https://graph.microsoft.us/Application.Read.All"}, {"https://graph.microsoft.us/Application.Read.All"}}
+	if !reflect.DeepEqual(cred.scopes, want) {
+		t.Errorf("expected the tokens to be requested for scopes %v, got %v", want, cred.scopes)
+	}
+}
+
+func TestNewAzureClientDefaults(t *testing.T) {
+	c, err := NewAzureClient(context.Background(), &fakeTokenCredential{})
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+
+	if c.environment != azure.PublicCloud {
+		t.Errorf("expected environment %s, got %s", azure.PublicCloud.Name, c.environment.Name)
+	}
+	if c.httpClient != http.DefaultClient {
+		t.Errorf("expected the default HTTP client to be used")
+	}
+	if c.defaultTimeout != 0 || c.applicationCache != nil || c.graphCircuitBreaker.threshold != 0 || c.allowForeignServicePrincipals || c.partialResults {
+		t.Errorf("expected the timeout, application cache, circuit breaker and foreign service principals to be disabled")
+	}
+}
+
+func TestNewAzureClientOptions(t *testing.T) {
+	client := &http.Client{}
+	transport := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	recorder := &fakeMetricsRecorder{}
+	logger := mlog.New().WithName("test")
+
+	tests := []struct {
+		name string
+		opt  Option
+		want config
+	}{
+		{
+			name: "cloud environment",
+			opt:  WithCloudEnvironment(azure.ChinaCloud),
+			want: config{Environment: azure.ChinaCloud},
+		},
+		{
+			name: "subscription ID",
+			opt:  WithSubscriptionID("subscription-id"),
+			want: config{SubscriptionID: "subscription-id"},
+		},
+		{
+			name: "tenant ID",
+			opt:  WithTenantID("tenant-id"),
+			want: config{TenantID: "tenant-id"},
+		},
+		{
+			name: "graph scopes",
+			opt:  WithGraphScopes("https://graph.microsoft.com/Application.Read.All"),
+			want: config{GraphScopes: []string{"https://graph.microsoft.com/Application.Read.All"}},
+		},
+		{
+			name: "HTTP client",
+			opt:  WithHTTPClient(client),
+			want: config{HTTPClient: client},
+		},
+		{
+			name: "user agent",
+			opt:  WithUserAgent("azwi-test"),
+			want: config{UserAgent: "azwi-test"},
+		},
+		{
+			name: "timeout",
+			opt:  WithTimeout(time.Minute),
+			want: config{Timeout: time.Minute},
+		},
+		{
+			name: "max retries",
+			opt:  WithMaxRetries(5),
+			want: config{MaxRetries: 5},
+		},
+		{
+			name: "rate limit",
+			opt:  WithRateLimit(10),
+			want: config{RateLimit: 10},
+		},
+		{
+			name: "write rate limit",
+			opt:  WithWriteRateLimit(5),
+			want: config{WriteRateLimit: 5},
+		},
+		{
+			name: "circuit breaker",
+			opt:  WithCircuitBreaker(5, time.Second),
+			want: config{CircuitBreakerThreshold: 5, CircuitBreakerCooldown: time.Second},
+		},
+		{
+			name: "application cache TTL",
+			opt:  WithApplicationCacheTTL(time.Hour),
+			want: config{ApplicationCacheTTL: time.Hour},
+		},
+		{
+			name: "service principal cache TTL",
+			opt:  WithServicePrincipalCacheTTL(time.Hour),
+			want: config{ServicePrincipalCacheTTL: time.Hour},
+		},
+		{
+			name: "default federated audiences",
+			opt:  WithDefaultFederatedAudiences("api://custom"),
+			want: config{DefaultFederatedAudiences: []string{"api://custom"}},
+		},
+		{
+			name: "federated credential poll interval",
+			opt:  WithFederatedCredentialPollInterval(time.Millisecond),
+			want: config{FederatedCredentialPollInterval: time.Millisecond},
+		},
+		{
+			name: "service principal poll interval",
+			opt:  WithServicePrincipalPollInterval(time.Millisecond),
+			want: config{ServicePrincipalPollInterval: time.Millisecond},
+		},
+		{
+			name: "foreign service principals",
+			opt:  WithForeignServicePrincipals(true),
+			want: config{AllowForeignServicePrincipals: true},
+		},
+		{
+			name: "allowed issuers",
+			opt:  WithAllowedIssuers("https://issuer.example.com/"),
+			want: config{AllowedIssuers: []string{"https://issuer.example.com/"}},
+		},
+		{
+			name: "page size",
+			opt:  WithPageSize(100),
+			want: config{PageSize: 100},
+		},
+		{
+			name: "partial results",
+			opt:  WithPartialResults(true),
+			want: config{PartialResults: true},
+		},
+		{
+			name: "metrics recorder",
+			opt:  WithMetricsRecorder(recorder),
+			want: config{MetricsRecorder: recorder},
+		},
+		{
+			name: "dry run",
+			opt:  WithDryRun(true),
+			want: config{DryRun: true},
+		},
+		{
+			name: "logger",
+			opt:  WithLogger(logger),
+			want: config{Logger: logger},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			tt.opt(&cfg)
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("config = %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+
+	// the transport is a func, which reflect.DeepEqual only considers equal to a nil func
+	var cfg config
+	WithTransport(transport)(&cfg)
+	if cfg.Transport == nil {
+		t.Errorf("expected the transport to be set")
+	}
+}
+
+func TestNewAzureClientWithOptions(t *testing.T) {
+	recorder := &fakeMetricsRecorder{}
+	c, err := NewAzureClient(context.Background(), &fakeTokenCredential{},
+		WithCloudEnvironment(azure.USGovernmentCloud),
+		WithTimeout(time.Second),
+		WithMetricsRecorder(recorder),
+		WithDryRun(true),
+		WithTimeout(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+
+	if c.environment != azure.USGovernmentCloud {
+		t.Errorf("expected environment %s, got %s", azure.USGovernmentCloud.Name, c.environment.Name)
+	}
+	// a later option overrides an earlier one
+	if c.defaultTimeout != time.Minute {
+		t.Errorf("expected default timeout %s, got %s", time.Minute, c.defaultTimeout)
+	}
+	if c.metrics != recorder {
+		t.Errorf("expected the metrics recorder to be used")
+	}
+	if !c.dryRun {
+		t.Errorf("expected dry run to be enabled")
+	}
+}
+
+func TestNewAzureClientMaxRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantErr    bool
+	}{
+		{
+			name:       "maximum",
+			maxRetries: maxMaxRetries,
+		},
+		{
+			name:       "above the maximum",
+			maxRetries: maxMaxRetries + 1,
+			wantErr:    true,
+		},
+		{
+			name:       "negative",
+			maxRetries: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAzureClient(context.Background(), &fakeTokenCredential{}, WithMaxRetries(tt.maxRetries))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAzureClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAzureClientTransport(t *testing.T) {
+	var graphRequests, armRequests int
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": "sp-object-id", "servicePrincipalType": "Application"}`)),
+			Request:    r,
+		}
+		if r.URL.Host != "graph.microsoft.com" {
+			armRequests++
+			resp.Body = io.NopCloser(strings.NewReader(`{"value": [{"id": "/providers/Microsoft.Authorization/roleDefinitions/role-id", "properties": {"roleName": "Reader"}}]}`))
+			return resp, nil
+		}
+		// the first Graph request is throttled to check that the Graph middleware is kept above the transport
+		if graphRequests++; graphRequests == 1 {
+			resp.StatusCode = http.StatusServiceUnavailable
+			resp.Header.Set("Retry-After", "0")
+			resp.Body = io.NopCloser(strings.NewReader(""))
+		}
+		return resp, nil
+	})
+
+	c, err := NewAzureClient(context.Background(), &fakeTokenCredential{},
+		WithSubscriptionID("subscription-id"),
+		WithTransport(transport),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+
+	if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if graphRequests != 2 {
+		t.Errorf("expected the Graph request to be retried through the transport, got %d requests", graphRequests)
+	}
+	if _, err := c.GetRoleDefinitionIDByName(context.Background(), "/subscriptions/subscription-id", "Reader"); err != nil {
+		t.Fatalf("GetRoleDefinitionIDByName() error = %v", err)
+	}
+	if armRequests != 1 {
+		t.Errorf("expected the ARM request to be sent through the transport, got %d requests", armRequests)
+	}
+}
+
+func TestNewDefaultGraphClientMaxRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRetries   int
+		wantAttempts int
+	}{
+		{
+			name:         "default",
+			maxRetries:   defaultMaxRetries,
+			wantAttempts: 4,
+		},
+		{
+			name:         "one retry",
+			maxRetries:   1,
+			wantAttempts: 2,
+		},
+		{
+			name:         "retries disabled",
+			maxRetries:   -1,
+			wantAttempts: 1,
+		},
+		{
+			name:         "large negative value clamped",
+			maxRetries:   -100,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer server.Close()
+
+			resp, err := newDefaultGraphClient(test.maxRetries).Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			resp.Body.Close()
+			if attempts != test.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", test.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	client := wrapTransport(&http.Client{}, func(http.RoundTripper) http.RoundTripper {
+		return &rateLimitTransport{
+			limiter: rate.NewLimiter(20, 1),
+			next: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			}),
+		}
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("https://graph.microsoft.com/v1.0/applications")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	// the first request is sent right away and the others wait for 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the requests to be rate limited, took %s", elapsed)
+	}
+}
+
+func TestWriteRateLimitTransport(t *testing.T) {
+	limiter := &writeRateLimiter{}
+	client := wrapTransport(&http.Client{}, func(http.RoundTripper) http.RoundTripper {
+		return &writeRateLimitTransport{
+			limiter: limiter,
+			next: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			}),
+		}
+	})
+	c := &AzureClient{graphWriteLimiter: limiter}
+
+	send := func(method string, n int) time.Duration {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			req, err := http.NewRequest(method, "https://graph.microsoft.com/v1.0/applications", nil)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+		}
+		return time.Since(start)
+	}
+
+	c.SetWriteRateLimit(20, 1)
+	// the first request is sent right away and the others wait for 50ms each
+	if elapsed := send(http.MethodPost, 3); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the writes to be spaced by the rate limit, took %s", elapsed)
+	}
+	if elapsed := send(http.MethodGet, 10); elapsed > 40*time.Millisecond {
+		t.Errorf("expected the reads not to be rate limited, took %s", elapsed)
+	}
+
+	c.SetWriteRateLimit(0, 0)
+	if elapsed := send(http.MethodPatch, 10); elapsed > 40*time.Millisecond {
+		t.Errorf("expected the writes not to be rate limited once disabled, took %s", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultRetryInitialInterval is the default interval before the first retry of a lookup.
+	defaultRetryInitialInterval = 500 * time.Millisecond
+	// defaultRetryMaxInterval is the default maximum interval between the retries of a lookup.
+	defaultRetryMaxInterval = 8 * time.Second
+	// defaultRetryMaxElapsedTime is the default time after which a lookup is not retried anymore.
+	defaultRetryMaxElapsedTime = time.Minute
+)
+
+// RetryBackoff is the exponential backoff of the retries of a lookup while the object is not found. A zero
+// field is set to its default: 500ms for the initial interval, 8s for the maximum interval and 1m for the
+// maximum elapsed time.
+type RetryBackoff struct {
+	// InitialInterval is the interval before the first retry, which doubles after each retry.
+	InitialInterval time.Duration
+	// MaxInterval is the maximum interval between two retries.
+	MaxInterval time.Duration
+	// MaxElapsedTime is the time after which the lookup is not retried anymore.
+	MaxElapsedTime time.Duration
+}
+
+// withDefaults returns the backoff with its zero fields set to their default.
+func (b RetryBackoff) withDefaults() RetryBackoff {
+	if b.InitialInterval <= 0 {
+		b.InitialInterval = defaultRetryInitialInterval
+	}
+	if b.MaxInterval <= 0 {
+		b.MaxInterval = defaultRetryMaxInterval
+	}
+	if b.MaxElapsedTime <= 0 {
+		b.MaxElapsedTime = defaultRetryMaxElapsedTime
+	}
+	return b
+}
+
+// GetApplicationWithRetry is like GetApplication, but retries the lookup with the backoff while the application
+// is not found, until it's found, the maximum elapsed time of the backoff or the deadline of the context. The error
+// of the context is returned when it's canceled or its deadline expires first. Graph is eventually consistent, so
+// an application may not be found right after CreateApplication returns it.
+func (c *AzureClient) GetApplicationWithRetry(ctx context.Context, displayName string, backoff RetryBackoff) (models.Applicationable, error) {
+	app, err := retryWhileNotFound(ctx, backoff, func(ctx context.Context) (models.Applicationable, error) {
+		return c.GetApplication(ctx, displayName)
+	})
+	return app, errors.Wrapf(err, "failed to get application %s", displayName)
+}
+
+// GetServicePrincipalWithRetry is like GetServicePrincipal, but retries the lookup with the backoff while the
+// service principal is not found, see GetApplicationWithRetry.
+func (c *AzureClient) GetServicePrincipalWithRetry(ctx context.Context, displayName string, backoff RetryBackoff) (models.ServicePrincipalable, error) {
+	sp, err := retryWhileNotFound(ctx, backoff, func(ctx context.Context) (models.ServicePrincipalable, error) {
+		return c.GetServicePrincipal(ctx, displayName)
+	})
+	return sp, errors.Wrapf(err, "failed to get service principal %s", displayName)
+}
+
+// retryWhileNotFound calls get with the backoff while it returns a not found error. The last not found error is
+// returned when the maximum elapsed time of the backoff expires, including while a lookup is in flight. The error
+// of the context is returned when it's canceled or its deadline expires before.
+func retryWhileNotFound[T any](parentCtx context.Context, backoff RetryBackoff, get func(ctx context.Context) (T, error)) (T, error) {
+	backoff = backoff.withDefaults()
+	ctx, cancel := context.WithTimeout(parentCtx, backoff.MaxElapsedTime)
+	defer cancel()
+
+	var notFound error
+	interval := backoff.InitialInterval
+	for attempt := 1; ; attempt++ {
+		object, err := get(ctx)
+		if err != nil && parentCtx.Err() != nil {
+			return object, parentCtx.Err()
+		}
+		if err != nil && notFound != nil && ctx.Err() != nil {
+			// the lookup was canceled by the expiry of the backoff, which is still an object not found
+			return object, errors.Wrapf(notFound, "still not found after %d attempts", attempt-1)
+		}
+		if err == nil || !IsNotFound(err) {
+			return object, err
+		}
+		notFound = err
+
+		logDebug("Object not found, retrying lookup", "attempt", attempt, "interval", interval)
+		select {
+		case <-ctx.Done():
+			if parentCtx.Err() != nil {
+				return object, parentCtx.Err()
+			}
+			return object, errors.Wrapf(err, "still not found after %d attempts", attempt)
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > backoff.MaxInterval {
+			interval = backoff.MaxInterval
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+)
+
+func TestGetApplicationWithRetry(t *testing.T) {
+	var lookups int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// the application is not found until it has propagated
+		if atomic.AddInt32(&lookups, 1) < 3 {
+			fmt.Fprint(w, `{"value": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "object-id", "displayName": "app"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.GetApplicationWithRetry(context.Background(), "app", RetryBackoff{InitialInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("GetApplicationWithRetry() error = %v", err)
+	}
+	if got := to.String(app.GetId()); got != "object-id" {
+		t.Errorf("GetApplicationWithRetry() = %s, want object-id", got)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 3 {
+		t.Errorf("expected 3 lookups, got %d", got)
+	}
+}
+
+func TestGetServicePrincipalWithRetryMaxElapsedTime(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	}))
+
+	_, err := c.GetServicePrincipalWithRetry(context.Background(), "sp", RetryBackoff{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, MaxElapsedTime: 50 * time.Millisecond})
+	if err == nil || !IsNotFound(err) {
+		t.Errorf("GetServicePrincipalWithRetry() error = %v, want not found error", err)
+	}
+}
+
+func TestRetryWhileNotFound(t *testing.T) {
+	var attempts int
+	_, err := retryWhileNotFound(context.Background(), RetryBackoff{InitialInterval: time.Millisecond}, func(ctx context.Context) (string, error) {
+		attempts++
+		return "", errors.New("insufficient privileges")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected the error other than not found to be returned without retries, got %v after %d attempts", err, attempts)
+	}
+
+	var intervals []time.Duration
+	last := time.Now()
+	_, err = retryWhileNotFound(context.Background(), RetryBackoff{InitialInterval: 10 * time.Millisecond, MaxInterval: 20 * time.Millisecond, MaxElapsedTime: time.Minute}, func(ctx context.Context) (string, error) {
+		now := time.Now()
+		intervals = append(intervals, now.Sub(last))
+		last = now
+		if len(intervals) == 4 {
+			return "found", nil
+		}
+		return "", errors.New("not found")
+	})
+	if err != nil {
+		t.Fatalf("retryWhileNotFound() error = %v", err)
+	}
+	// the interval doubles up to the maximum interval
+	for i, min := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond} {
+		if intervals[i+1] < min {
+			t.Errorf("expected interval %d to be at least %s, got %s", i+1, min, intervals[i+1])
+		}
+	}
+}
+
+func TestRetryWhileNotFoundContextCanceled(t *testing.T) {
+	tests := []struct {
+		name     string
+		inFlight bool
+	}{
+		{
+			name: "canceled while waiting for the next lookup",
+		},
+		{
+			name:     "canceled while a lookup is in flight",
+			inFlight: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			interval := time.Hour
+			if tt.inFlight {
+				interval = time.Millisecond
+			}
+			var attempts int
+			_, err := retryWhileNotFound(ctx, RetryBackoff{InitialInterval: interval}, func(ctx context.Context) (string, error) {
+				attempts++
+				if attempts == 1 {
+					if !tt.inFlight {
+						time.AfterFunc(10*time.Millisecond, cancel)
+					}
+					return "", errors.New("not found")
+				}
+				cancel()
+				return "", ctx.Err()
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("retryWhileNotFound() error = %v, want %v", err, context.Canceled)
+			}
+			if IsNotFound(err) {
+				t.Errorf("expected the error of the context instead of the not found error, got %v", err)
+			}
+		})
+	}
+}
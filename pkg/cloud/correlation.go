@@ -0,0 +1,46 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+)
+
+// clientRequestIDHeader is the header of the ID that Graph logs for a request, which support requests refer to.
+const clientRequestIDHeader = "client-request-id"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context that carries the correlation ID of a logical operation. The Graph
+// requests sent with the context, or a context derived from it, have the correlation ID as their
+// client-request-id header and are logged with it, so that they can be tied together in Graph and in the logs.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationIDFromContext returns the correlation ID carried by the context, or an empty string if there is none.
+func correlationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// correlationIDTransport is an http.RoundTripper that sets the correlation ID of the request context as
+// the client-request-id header. It replaces the random ID set by the Graph middleware, so it must be below it.
+type correlationIDTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *correlationIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := correlationIDFromContext(req.Context())
+	if id == "" {
+		return t.next.RoundTrip(req)
+	}
+
+	logTrace("Sending Graph request", "correlationID", id, "method", req.Method, "path", req.URL.Path)
+	req = req.Clone(req.Context())
+	req.Header.Set(clientRequestIDHeader, id)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,79 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithCorrelationID(t *testing.T) {
+	var ids []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(clientRequestIDHeader))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "sp-object-id", "appId": "client-id", "servicePrincipalType": "Application"}`)
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(clientRequestIDHeader))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "app-object-id", "appId": "client-id"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	// the correlation ID is carried across the calls made with the context and the contexts derived from it
+	ctx, cancel := context.WithCancel(WithCorrelationID(context.Background(), "operation-id"))
+	defer cancel()
+	if _, err := c.GetServicePrincipalType(ctx, "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if _, err := c.GetApplicationForServicePrincipal(ctx, "sp-object-id"); err != nil {
+		t.Fatalf("GetApplicationForServicePrincipal() error = %v", err)
+	}
+	if want := []string{"operation-id", "operation-id", "operation-id"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected client-request-id headers %v, got %v", want, ids)
+	}
+
+	ids = nil
+	if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if want := []string{""}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected no client-request-id header without a correlation ID, got %v", ids)
+	}
+}
+
+func TestNewDefaultGraphClientCorrelationID(t *testing.T) {
+	var headers [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Values(clientRequestIDHeader))
+	}))
+	defer server.Close()
+
+	client := newDefaultGraphClient(defaultMaxRetries)
+	for _, ctx := range []context.Context{WithCorrelationID(context.Background(), "operation-id"), context.Background()} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatalf("NewRequestWithContext() error = %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// the correlation ID replaces the random ID set by the Graph middleware
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(headers))
+	}
+	if want := []string{"operation-id"}; !reflect.DeepEqual(headers[0], want) {
+		t.Errorf("expected client-request-id header %v, got %v", want, headers[0])
+	}
+	if len(headers[1]) != 1 || headers[1][0] == "" || headers[1][0] == "operation-id" {
+		t.Errorf("expected the random client-request-id header of the Graph middleware, got %v", headers[1])
+	}
+}
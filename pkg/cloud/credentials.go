@@ -0,0 +1,156 @@
+package cloud
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyCredentialTypeAsymmetricX509Cert is the type of a certificate credential.
+	keyCredentialTypeAsymmetricX509Cert = "AsymmetricX509Cert"
+	// keyCredentialUsageVerify is the usage of a certificate credential the application authenticates with.
+	keyCredentialUsageVerify = "Verify"
+)
+
+// AddApplicationCertificate adds a certificate credential to the application, e.g. for a fallback to client
+// certificate authentication where federation is not available. The certificate is either PEM or DER encoded.
+// The credential expires at notAfter, or when the certificate expires if notAfter is zero. It returns the key ID
+// of the credential, which identifies it to remove it later. The existing certificate credentials are kept.
+func (c *AzureClient) AddApplicationCertificate(ctx context.Context, objectID string, cert []byte, displayName string, notAfter time.Time) (string, error) {
+	if block, _ := pem.Decode(cert); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return "", errors.Errorf("unexpected PEM block type %q, must be CERTIFICATE", block.Type)
+		}
+		cert = block.Bytes
+	}
+	if _, err := x509.ParseCertificate(cert); err != nil {
+		return "", errors.Wrap(err, "failed to parse certificate")
+	}
+
+	credentials, err := c.listApplicationKeyCredentials(ctx, objectID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list application key credentials")
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Adding application certificate", "objectID", objectID, "displayName", displayName)
+
+	// the collection replaces the existing credentials, so they are sent along with the added one
+	updated := make([]models.KeyCredentialable, 0, len(credentials)+1)
+	for _, existing := range credentials {
+		updated = append(updated, copyKeyCredential(existing))
+	}
+	keyID := uuid.New()
+	added := models.NewKeyCredential()
+	added.SetKeyId(&keyID)
+	added.SetType(to.StringPtr(keyCredentialTypeAsymmetricX509Cert))
+	added.SetUsage(to.StringPtr(keyCredentialUsageVerify))
+	added.SetKey(cert)
+	if displayName != "" {
+		added.SetDisplayName(to.StringPtr(displayName))
+	}
+	if !notAfter.IsZero() {
+		added.SetEndDateTime(&notAfter)
+	}
+	updated = append(updated, added)
+
+	app := models.NewApplication()
+	app.SetKeyCredentials(updated)
+
+	if err := c.patchApplication(ctx, objectID, app); err != nil {
+		return "", err
+	}
+	return keyID.String(), nil
+}
+
+// AddApplicationPassword adds a password credential, i.e. a client secret, to the application, e.g. for a fallback
+// to client secret authentication where federation is not available. The secret expires at notAfter, or after the
+// default lifetime of Graph if notAfter is zero. It returns the secret, which is generated by Graph and can't be
+// retrieved afterwards, and the key ID of the credential, which identifies it to remove it later. The secret is
+// sensitive and is never logged.
+func (c *AzureClient) AddApplicationPassword(ctx context.Context, objectID, displayName string, notAfter time.Time) (string, string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("adding application password", "objectID", objectID, "displayName", displayName, "notAfter", notAfter) {
+		return "", DryRunObjectID, nil
+	}
+	c.logDebug("Adding application password", "objectID", objectID, "displayName", displayName, "notAfter", notAfter)
+
+	credential := models.NewPasswordCredential()
+	if displayName != "" {
+		credential.SetDisplayName(to.StringPtr(displayName))
+	}
+	if !notAfter.IsZero() {
+		credential.SetEndDateTime(&notAfter)
+	}
+	body := applications.NewItemAddPasswordPostRequestBody()
+	body.SetPasswordCredential(credential)
+
+	resp, err := c.graphServiceClient.ApplicationsById(objectID).AddPassword().Post(ctx, body, nil)
+	if err != nil {
+		return "", "", err
+	}
+	c.applicationCache.evict(objectID)
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return "", "", err
+	}
+	if graphErr != nil {
+		return "", "", *graphErr
+	}
+	if resp.GetKeyId() == nil || to.String(resp.GetSecretText()) == "" {
+		return "", "", errors.Errorf("no password credential returned for application %s", objectID)
+	}
+	return to.String(resp.GetSecretText()), resp.GetKeyId().String(), nil
+}
+
+// listApplicationKeyCredentials lists the key credentials of the application. The keys are only returned
+// when the credentials are selected on the application itself, which they must be to be sent back.
+func (c *AzureClient) listApplicationKeyCredentials(ctx context.Context, objectID string) ([]models.KeyCredentialable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "keyCredentials"},
+		},
+	}
+
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	return app.GetKeyCredentials(), nil
+}
+
+// copyKeyCredential returns a copy of the key credential.
+func copyKeyCredential(credential models.KeyCredentialable) models.KeyCredentialable {
+	copied := models.NewKeyCredential()
+	copied.SetKeyId(credential.GetKeyId())
+	copied.SetType(credential.GetType())
+	copied.SetUsage(credential.GetUsage())
+	copied.SetKey(credential.GetKey())
+	copied.SetCustomKeyIdentifier(credential.GetCustomKeyIdentifier())
+	copied.SetDisplayName(credential.GetDisplayName())
+	copied.SetStartDateTime(credential.GetStartDateTime())
+	copied.SetEndDateTime(credential.GetEndDateTime())
+	return copied
+}
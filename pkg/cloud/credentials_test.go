@@ -0,0 +1,200 @@
+package cloud
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"monis.app/mlog"
+)
+
+// newTestCertificate returns a self-signed DER encoded certificate.
+func newTestCertificate(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return cert
+}
+
+func TestAddApplicationCertificate(t *testing.T) {
+	cert := newTestCertificate(t)
+	existingKey := base64.StdEncoding.EncodeToString([]byte("existing"))
+
+	var body struct {
+		KeyCredentials []map[string]interface{} `json:"keyCredentials"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id": "object-id", "keyCredentials": [
+				{"keyId": "00000000-0000-0000-0000-000000000001", "type": "AsymmetricX509Cert", "usage": "Verify", "key": "%s", "displayName": "existing"}
+			]}`, existingKey)
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyID, err := c.AddApplicationCertificate(context.Background(), "object-id", pemCert, "fallback", notAfter)
+	if err != nil {
+		t.Fatalf("AddApplicationCertificate() error = %v", err)
+	}
+
+	if len(body.KeyCredentials) != 2 {
+		t.Fatalf("expected the existing and the added key credentials, got %v", body.KeyCredentials)
+	}
+	existing, added := body.KeyCredentials[0], body.KeyCredentials[1]
+	if existing["keyId"] != "00000000-0000-0000-0000-000000000001" || existing["key"] != existingKey {
+		t.Errorf("existing key credential = %v, want it preserved", existing)
+	}
+	want := map[string]interface{}{
+		"keyId":       keyID,
+		"type":        "AsymmetricX509Cert",
+		"usage":       "Verify",
+		"key":         base64.StdEncoding.EncodeToString(cert),
+		"displayName": "fallback",
+	}
+	for k, v := range want {
+		if added[k] != v {
+			t.Errorf("added key credential %s = %v, want %v", k, added[k], v)
+		}
+	}
+	if got, err := time.Parse(time.RFC3339, fmt.Sprint(added["endDateTime"])); err != nil || !got.Equal(notAfter) {
+		t.Errorf("added key credential endDateTime = %v, want %v", added["endDateTime"], notAfter)
+	}
+}
+
+func TestAddApplicationCertificateInvalid(t *testing.T) {
+	c := newTestAzureClient(t, http.NewServeMux())
+
+	if _, err := c.AddApplicationCertificate(context.Background(), "object-id", []byte("not a certificate"), "", time.Time{}); err == nil {
+		t.Error("AddApplicationCertificate() expected error for an invalid certificate")
+	}
+}
+
+// captureDebugLogs returns what is logged at the debug level while fn runs.
+func captureDebugLogs(t *testing.T, fn func()) string {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // we do not need log flushing for this test
+
+	origLogLevel := mlog.LevelWarning
+	for _, level := range []mlog.LogLevel{mlog.LevelAll, mlog.LevelTrace, mlog.LevelDebug, mlog.LevelInfo} {
+		if mlog.Enabled(level) {
+			origLogLevel = level
+			break
+		}
+	}
+
+	// the logger writes to the stderr it is configured with
+	stderr := os.Stderr
+	os.Stderr = f
+	err = mlog.ValidateAndSetLogLevelAndFormatGlobally(ctx, mlog.LogSpec{Level: mlog.LevelDebug, Format: mlog.FormatJSON})
+	os.Stderr = stderr
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mlog.ValidateAndSetLogLevelAndFormatGlobally(ctx, mlog.LogSpec{Level: origLogLevel, Format: mlog.FormatJSON}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	fn()
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("failed to read stderr: %v", err)
+	}
+	return string(b)
+}
+
+func TestAddApplicationPassword(t *testing.T) {
+	const secret = "s3cr3t-v4lu3"
+
+	var body struct {
+		PasswordCredential map[string]interface{} `json:"passwordCredential"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/addPassword", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"keyId": "00000000-0000-0000-0000-000000000001", "displayName": "fallback", "secretText": "%s", "hint": "s3c"}`, secret)
+	})
+	c := newTestAzureClient(t, mux)
+
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var (
+		gotSecret, keyID string
+		err              error
+	)
+	logs := captureDebugLogs(t, func() {
+		gotSecret, keyID, err = c.AddApplicationPassword(context.Background(), "object-id", "fallback", notAfter)
+	})
+	if err != nil {
+		t.Fatalf("AddApplicationPassword() error = %v", err)
+	}
+	if gotSecret != secret || keyID != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("AddApplicationPassword() = %q, %q, want %q, 00000000-0000-0000-0000-000000000001", gotSecret, keyID, secret)
+	}
+
+	if got := body.PasswordCredential["displayName"]; got != "fallback" {
+		t.Errorf("displayName = %v, want fallback", got)
+	}
+	if got, err := time.Parse(time.RFC3339, fmt.Sprint(body.PasswordCredential["endDateTime"])); err != nil || !got.Equal(notAfter) {
+		t.Errorf("endDateTime = %v, want %v", body.PasswordCredential["endDateTime"], notAfter)
+	}
+
+	if !strings.Contains(logs, "Adding application password") {
+		t.Errorf("expected the debug logs to be captured, got:\n%s", logs)
+	}
+	if strings.Contains(logs, secret) {
+		t.Errorf("the secret is logged:\n%s", logs)
+	}
+}
@@ -0,0 +1,123 @@
+package cloud
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// customSecurityAttributesKey is the property of the custom security attributes of a service principal,
+	// which is not modeled by the Graph SDK and is sent as additional data
+	customSecurityAttributesKey = "customSecurityAttributes"
+
+	// customSecurityAttributeValueType is the OData type of an attribute set of custom security attributes
+	customSecurityAttributeValueType = "#Microsoft.DirectoryServices.CustomSecurityAttributeValue"
+
+	odataTypeKey = "@odata.type"
+)
+
+// customSecurityAttributeNamePattern is the format of the names of the attribute sets and attributes,
+// which are up to 32 letters and digits.
+// Reference: https://learn.microsoft.com/en-us/azure/active-directory/fundamentals/custom-security-attributes-overview#custom-security-attribute-limits-and-constraints
+var customSecurityAttributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+// SetServicePrincipalCustomSecurityAttributes sets the custom security attributes of the service principal,
+// keyed by attribute set and attribute name. The values are strings, integers, booleans, or slices of strings
+// or integers for multi-valued attributes. A nil value clears the attribute. The attributes that are not set
+// are kept. The attribute sets and attributes must be defined in the tenant.
+func (c *AzureClient) SetServicePrincipalCustomSecurityAttributes(ctx context.Context, objectID string, attrs map[string]map[string]interface{}) error {
+	body, err := newCustomSecurityAttributes(attrs)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("setting service principal custom security attributes", "objectID", objectID, "attributeSets", len(attrs)) {
+		return nil
+	}
+	c.logDebug("Setting service principal custom security attributes", "objectID", objectID, "attributeSets", len(attrs))
+
+	sp := models.NewServicePrincipal()
+	sp.SetAdditionalData(map[string]interface{}{customSecurityAttributesKey: body})
+	c.servicePrincipalCache.evict(objectID)
+	resp, err := c.graphClient.UpdateServicePrincipal(ctx, objectID, sp)
+	if err != nil {
+		return errors.Wrapf(withODataErrorDetails(err), "failed to set custom security attributes of service principal %s", objectID)
+	}
+	// the service principal is not returned when it is updated successfully
+	if resp == nil {
+		return nil
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+	return nil
+}
+
+// newCustomSecurityAttributes validates the custom security attributes and returns them with the OData type
+// of each attribute set and of the values that are not strings or booleans, as required by Graph.
+func newCustomSecurityAttributes(attrs map[string]map[string]interface{}) (map[string]interface{}, error) {
+	if len(attrs) == 0 {
+		return nil, errors.New("no custom security attributes to set")
+	}
+
+	sets := make([]string, 0, len(attrs))
+	for set := range attrs {
+		sets = append(sets, set)
+	}
+	sort.Strings(sets)
+
+	body := make(map[string]interface{}, len(attrs))
+	for _, set := range sets {
+		if !customSecurityAttributeNamePattern.MatchString(set) {
+			return nil, errors.Errorf("invalid attribute set name %q, must be 1 to 32 letters or digits", set)
+		}
+		if len(attrs[set]) == 0 {
+			return nil, errors.Errorf("attribute set %s has no attributes", set)
+		}
+
+		values := map[string]interface{}{odataTypeKey: customSecurityAttributeValueType}
+		for name, value := range attrs[set] {
+			if !customSecurityAttributeNamePattern.MatchString(name) {
+				return nil, errors.Errorf("invalid attribute name %q in attribute set %s, must be 1 to 32 letters or digits", name, set)
+			}
+			odataType, err := getCustomSecurityAttributeType(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value of attribute %s.%s", set, name)
+			}
+			if odataType != "" {
+				values[name+odataTypeKey] = odataType
+			}
+			values[name] = value
+		}
+		body[set] = values
+	}
+	return body, nil
+}
+
+// getCustomSecurityAttributeType returns the OData type annotation of the value of a custom security attribute,
+// or an empty string if the type is inferred by Graph.
+func getCustomSecurityAttributeType(value interface{}) (string, error) {
+	switch value.(type) {
+	case nil, string, bool:
+		return "", nil
+	case int, int32, int64:
+		return "#Int32", nil
+	case []string:
+		return "#Collection(String)", nil
+	case []int, []int32, []int64:
+		return "#Collection(Int32)", nil
+	default:
+		return "", errors.Errorf("unsupported type %T, must be a string, an integer, a boolean, or a slice of strings or integers", value)
+	}
+}
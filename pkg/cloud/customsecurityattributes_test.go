@@ -0,0 +1,123 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetServicePrincipalCustomSecurityAttributes(t *testing.T) {
+	var body []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	err := c.SetServicePrincipalCustomSecurityAttributes(context.Background(), "sp-object-id", map[string]map[string]interface{}{
+		"Engineering": {
+			"Project":   "Baker",
+			"CostCode":  1001,
+			"Certified": true,
+			"Teams":     []string{"Alpine", "Baker"},
+			"Retired":   nil,
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetServicePrincipalCustomSecurityAttributes() error = %v", err)
+	}
+
+	want := `{
+		"@odata.type": "#microsoft.graph.servicePrincipal",
+		"customSecurityAttributes": {
+			"Engineering": {
+				"@odata.type": "#Microsoft.DirectoryServices.CustomSecurityAttributeValue",
+				"Project": "Baker",
+				"CostCode@odata.type": "#Int32",
+				"CostCode": 1001,
+				"Certified": true,
+				"Teams@odata.type": "#Collection(String)",
+				"Teams": ["Alpine", "Baker"],
+				"Retired": null
+			}
+		}
+	}`
+	if !jsonEqual(body, []byte(want)) {
+		t.Errorf("expected request body %s, got %s", want, body)
+	}
+}
+
+func TestSetServicePrincipalCustomSecurityAttributesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "no attributes",
+			wantErr: "no custom security attributes to set",
+		},
+		{
+			name:    "invalid attribute set name",
+			attrs:   map[string]map[string]interface{}{"Cost Center": {"Code": "1001"}},
+			wantErr: `invalid attribute set name "Cost Center", must be 1 to 32 letters or digits`,
+		},
+		{
+			name:    "attribute set name too long",
+			attrs:   map[string]map[string]interface{}{strings.Repeat("a", 33): {"Code": "1001"}},
+			wantErr: "invalid attribute set name",
+		},
+		{
+			name:    "empty attribute set",
+			attrs:   map[string]map[string]interface{}{"Engineering": {}},
+			wantErr: "attribute set Engineering has no attributes",
+		},
+		{
+			name:    "invalid attribute name",
+			attrs:   map[string]map[string]interface{}{"Engineering": {"Project@odata.type": "#String"}},
+			wantErr: `invalid attribute name "Project@odata.type" in attribute set Engineering`,
+		},
+		{
+			name:    "unsupported value type",
+			attrs:   map[string]map[string]interface{}{"Engineering": {"Budget": 1.5}},
+			wantErr: "invalid value of attribute Engineering.Budget: unsupported type float64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}))
+
+			err := c.SetServicePrincipalCustomSecurityAttributes(context.Background(), "sp-object-id", tt.attrs)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SetServicePrincipalCustomSecurityAttributes() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetServicePrincipalCustomSecurityAttributesError(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": "Request_BadRequest", "message": "Invalid property 'Engineering'."}}`))
+	}))
+
+	err := c.SetServicePrincipalCustomSecurityAttributes(context.Background(), "sp-object-id", map[string]map[string]interface{}{
+		"Engineering": {"Project": "Baker"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Invalid property 'Engineering'.") {
+		t.Errorf("expected the Graph error message, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	kiotaauth "github.com/microsoft/kiota-authentication-azure-go"
+	"github.com/pkg/errors"
+
+	"github.com/Azure/azure-workload-identity/pkg/consts"
+)
+
+// NewAzureClientFromDefaultCredential returns an AzureClient that authenticates with azidentity's
+// DefaultAzureCredential, which tries the environment variables, workload identity, managed identity
+// and the Azure CLI in turn. The Azure environment is derived from AZURE_AUTHORITY_HOST and the
+// subscription and tenant IDs are read from AZURE_SUBSCRIPTION_ID and AZURE_TENANT_ID.
+//
+// The scopes are the Graph scopes requested for the Graph requests and default to
+// "https://graph.microsoft.com/.default" (or the endpoint of the cloud), which grants the application
+// permissions consented to the identity. The identity needs Application.ReadWrite.All, or
+// Application.ReadWrite.OwnedBy to only manage the applications it owns, to manage applications and
+// their federated identity credentials, and DelegatedPermissionGrant.ReadWrite.All to grant admin consent.
+// A token is requested once so that a misconfiguration is reported when the client is created.
+func NewAzureClientFromDefaultCredential(ctx context.Context, scopes []string) (*AzureClient, error) {
+	env := azure.PublicCloud
+	if authorityHost := os.Getenv(consts.AzureAuthorityHostEnvVar); authorityHost != "" {
+		var err error
+		if env, err = getEnvironmentByAuthorityHost(authorityHost); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewAzureClient(ctx, nil,
+		WithCloudEnvironment(env),
+		WithSubscriptionID(os.Getenv(azureSubscriptionIDEnvVar)),
+		WithTenantID(os.Getenv(consts.AzureTenantIDEnvVar)),
+		WithGraphScopes(scopes...),
+		WithHTTPClient(http.DefaultClient),
+	)
+}
+
+// NewAzureClientWithManagedIdentity returns an AzureClient that authenticates with a managed identity, e.g. to
+// run the tooling in an Azure VM or AKS node without distributing secrets. The client ID selects a user-assigned
+// managed identity; the system-assigned managed identity is used if it is empty.
+//
+// The Graph token is requested for the .default scope of the Graph endpoint of the cloud, which grants the
+// application permissions assigned to the managed identity, as app role assignments since managed identities
+// can't be granted permissions in the portal. The managed identity needs Application.ReadWrite.All, or
+// Application.ReadWrite.OwnedBy to only manage the applications it owns, to manage applications and their
+// federated identity credentials, and DelegatedPermissionGrant.ReadWrite.All to grant admin consent.
+// A token is requested once so that a misconfiguration is reported when the client is created.
+func NewAzureClientWithManagedIdentity(ctx context.Context, env azure.Environment, subscriptionID, tenantID, clientID string, client *http.Client) (*AzureClient, error) {
+	options := &azidentity.ManagedIdentityCredentialOptions{}
+	if clientID != "" {
+		options.ID = azidentity.ClientID(clientID)
+	}
+	if client != nil {
+		options.ClientOptions.Transport = client
+	}
+	cred, err := azidentity.NewManagedIdentityCredential(options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create managed identity credential")
+	}
+
+	return NewAzureClient(ctx, cred,
+		WithCloudEnvironment(env),
+		WithSubscriptionID(subscriptionID),
+		WithTenantID(tenantID),
+		WithHTTPClient(client),
+	)
+}
+
+// newAzureClientWithTokenCredential returns an AzureClient that authenticates the Graph requests
+// with the Graph scopes of the config and the ARM requests with the resource manager scope.
+// The defaults must be applied to the config.
+func newAzureClientWithTokenCredential(cfg config, cred azcore.TokenCredential) (*AzureClient, error) {
+	auth, err := kiotaauth.NewAzureIdentityAuthenticationProviderWithScopes(cred, cfg.GraphScopes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create authentication provider")
+	}
+
+	armAuthorizer := &tokenCredentialAuthorizer{
+		cred:   cred,
+		scopes: []string{fmt.Sprintf("%s.default", cfg.Environment.ResourceManagerEndpoint)},
+	}
+	return newAzureClient(cfg, armAuthorizer, auth)
+}
+
+// tokenCredentialAuthorizer is an autorest.Authorizer that authorizes requests
+// with bearer tokens of an azcore.TokenCredential.
+type tokenCredentialAuthorizer struct {
+	cred   azcore.TokenCredential
+	scopes []string
+}
+
+// WithAuthorization returns a PrepareDecorator that sets the Authorization header to a bearer token for the scopes.
+func (a *tokenCredentialAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil {
+				return r, err
+			}
+			token, err := a.cred.GetToken(r.Context(), policy.TokenRequestOptions{Scopes: a.scopes})
+			if err != nil {
+				return r, errors.Wrap(err, "failed to get token")
+			}
+			return autorest.Prepare(r, autorest.WithBearerAuthorization(token.Token))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// fakeTokenCredential returns a static token and records the requested scopes.
+type fakeTokenCredential struct {
+	mu     sync.Mutex
+	scopes [][]string
+}
+
+func (f *fakeTokenCredential) GetToken(_ context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scopes = append(f.scopes, options.Scopes)
+	return azcore.AccessToken{Token: "fake-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewAzureClientWithTokenCredentialScopes(t *testing.T) {
+	var authorization string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		authorization = r.Header.Get("Authorization")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": "sp-object-id", "servicePrincipalType": "Application"}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	cred := &fakeTokenCredential{}
+	scopes := []string{"https://graph.microsoft.com/Application.ReadWrite.All"}
+	cfg := config{
+		SubscriptionID: "subscription-id",
+		TenantID:       "tenant-id",
+		GraphScopes:    scopes,
+		HTTPClient:     client,
+	}
+	c, err := newAzureClientWithTokenCredential(cfg.withDefaults(), cred)
+	if err != nil {
+		t.Fatalf("newAzureClientWithTokenCredential() error = %v", err)
+	}
+
+	if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if authorization != "Bearer fake-token" {
+		t.Errorf("expected Authorization header to be Bearer fake-token, got %q", authorization)
+	}
+	if len(cred.scopes) != 1 || strings.Join(cred.scopes[0], " ") != scopes[0] {
+		t.Errorf("expected the Graph token to be requested for scopes %v, got %v", scopes, cred.scopes)
+	}
+}
+
+func TestNewAzureClientWithManagedIdentity(t *testing.T) {
+	// the managed identity endpoint is selected by the environment, which must select the IMDS endpoint
+	for _, name := range []string{"IDENTITY_ENDPOINT", "IMDS_ENDPOINT", "MSI_ENDPOINT"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	tests := []struct {
+		name         string
+		clientID     string
+		wantClientID string
+	}{
+		{
+			name:         "user-assigned managed identity",
+			clientID:     "uami-client-id",
+			wantClientID: "uami-client-id",
+		},
+		{
+			name: "system-assigned managed identity",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				tokenRequests int
+				tokenQuery    url.Values
+				authorization string
+			)
+			client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				body := `{"id": "sp-object-id", "servicePrincipalType": "Application"}`
+				if r.URL.Host == "169.254.169.254" {
+					tokenRequests++
+					tokenQuery = r.URL.Query()
+					body = `{"access_token": "mi-token", "expires_in": "3600", "resource": "https://graph.microsoft.com", "token_type": "Bearer"}`
+				} else {
+					authorization = r.Header.Get("Authorization")
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Request:    r,
+				}, nil
+			})}
+
+			c, err := NewAzureClientWithManagedIdentity(context.Background(), azure.PublicCloud, "subscription-id", "tenant-id", test.clientID, client)
+			if err != nil {
+				t.Fatalf("NewAzureClientWithManagedIdentity() error = %v", err)
+			}
+			if _, err := c.GetServicePrincipalType(context.Background(), "sp-object-id"); err != nil {
+				t.Fatalf("GetServicePrincipalType() error = %v", err)
+			}
+
+			if tokenRequests == 0 {
+				t.Fatal("expected the token to be requested from the managed identity endpoint")
+			}
+			if got := tokenQuery.Get("client_id"); got != test.wantClientID {
+				t.Errorf("expected the token to be requested for client ID %q, got %q", test.wantClientID, got)
+			}
+			if got := tokenQuery.Get("resource"); got != "https://graph.microsoft.com" {
+				t.Errorf("expected the token to be requested for resource https://graph.microsoft.com, got %q", got)
+			}
+			if authorization != "Bearer mi-token" {
+				t.Errorf("expected Authorization header to be Bearer mi-token, got %q", authorization)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cloud
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/directory"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+// ListDeletedApplications lists the deleted applications, which are kept in the
+// recycle bin of the directory for 30 days and can be restored in the meantime.
+func (c *AzureClient) ListDeletedApplications(ctx context.Context) ([]models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing deleted applications")
+
+	return c.listDeletedApplications(ctx, nil)
+}
+
+// ListDeletedApplicationsByDisplayName lists the deleted applications with the given display name, e.g. to find
+// the object ID of an application deleted by mistake and restore it with RestoreDeletedApplication, which is the
+// only way to get its app ID back. Several deleted applications may have the display name.
+func (c *AzureClient) ListDeletedApplicationsByDisplayName(ctx context.Context, displayName string) ([]models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing deleted applications", "displayName", displayName)
+
+	return c.listDeletedApplications(ctx, &directory.DeletedItemsGraphApplicationRequestBuilderGetRequestConfiguration{
+		QueryParameters: &directory.DeletedItemsGraphApplicationRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getDisplayNameFilter(displayName)),
+		},
+	})
+}
+
+// listDeletedApplications lists all the pages of the deleted applications of the request.
+func (c *AzureClient) listDeletedApplications(ctx context.Context, options *directory.DeletedItemsGraphApplicationRequestBuilderGetRequestConfiguration) ([]models.Applicationable, error) {
+	resp, err := c.graphServiceClient.Directory().DeletedItems().GraphApplication().Get(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []models.Applicationable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return partialResults(c, apps), err
+		}
+		if graphErr != nil {
+			return partialResults(c, apps), *graphErr
+		}
+		apps = append(apps, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return apps, nil
+		}
+		if resp, err = directory.NewDeletedItemsGraphApplicationRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return partialResults(c, apps), err
+		}
+	}
+}
+
+// RestoreDeletedApplication restores the deleted application with the given object ID from the recycle bin.
+// The restored application keeps its object ID, app ID and federated identity credentials.
+func (c *AzureClient) RestoreDeletedApplication(ctx context.Context, objectID string) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("restoring deleted application", "objectID", objectID) {
+		// the restored application keeps its object ID
+		app := models.NewApplication()
+		app.SetId(to.StringPtr(objectID))
+		return app, nil
+	}
+	c.logDebug("Restoring deleted application", "objectID", objectID)
+
+	obj, err := c.graphServiceClient.Directory().DeletedItemsById(objectID).Restore().Post(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(obj.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	app, ok := obj.(models.Applicationable)
+	if !ok {
+		return nil, errors.Errorf("restored directory object %s is not an application", objectID)
+	}
+	return app, nil
+}
+
+// PermanentlyDeleteApplication permanently deletes the deleted application with the given object ID from the
+// recycle bin, after which it can no longer be restored. It succeeds if the application has already been purged.
+func (c *AzureClient) PermanentlyDeleteApplication(ctx context.Context, objectID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("permanently deleting application", "objectID", objectID) {
+		return nil
+	}
+	c.logDebug("Permanently deleting application", "objectID", objectID)
+
+	if err := c.graphServiceClient.Directory().DeletedItemsById(objectID).Delete(ctx, nil); err != nil {
+		if isGraphResourceNotFound(err) {
+			c.logDebug("Application has already been permanently deleted", "objectID", objectID)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
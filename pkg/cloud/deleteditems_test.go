@@ -0,0 +1,151 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestListDeletedApplications(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/directory/deletedItems/graph.application", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"value": [{"id": "object-id-1", "displayName": "app-1"}], "@odata.nextLink": "http://%s%s?page=2"}`, r.Host, r.URL.Path)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "object-id-2", "displayName": "app-2"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	apps, err := c.ListDeletedApplications(context.Background())
+	if err != nil {
+		t.Fatalf("ListDeletedApplications() error = %v", err)
+	}
+	if len(apps) != 2 || to.String(apps[0].GetId()) != "object-id-1" || to.String(apps[1].GetId()) != "object-id-2" {
+		t.Errorf("expected the deleted applications of both pages, got %d", len(apps))
+	}
+}
+
+func TestListDeletedApplicationsByDisplayName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/directory/deletedItems/graph.application", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$filter"); got != "displayName eq 'team''s app'" {
+			t.Errorf("expected $filter to be displayName eq 'team''s app', got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "object-id-1", "appId": "app-id-1", "displayName": "team's app"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	apps, err := c.ListDeletedApplicationsByDisplayName(context.Background(), "team's app")
+	if err != nil {
+		t.Fatalf("ListDeletedApplicationsByDisplayName() error = %v", err)
+	}
+	if len(apps) != 1 || to.String(apps[0].GetId()) != "object-id-1" || to.String(apps[0].GetAppId()) != "app-id-1" {
+		t.Errorf("expected the deleted application with the display name, got %d", len(apps))
+	}
+}
+
+func TestRestoreDeletedApplication(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    string
+		wantErr bool
+	}{
+		{
+			name: "application",
+			resp: `{"@odata.type": "#microsoft.graph.application", "id": "object-id", "appId": "app-id", "displayName": "app"}`,
+		},
+		{
+			name:    "not an application",
+			resp:    `{"@odata.type": "#microsoft.graph.group", "id": "object-id", "displayName": "group"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/directory/deletedItems/object-id/restore", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST request, got %s", r.Method)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.resp)
+			})
+			c := newTestAzureClient(t, mux)
+
+			app, err := c.RestoreDeletedApplication(context.Background(), "object-id")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestoreDeletedApplication() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && to.String(app.GetAppId()) != "app-id" {
+				t.Errorf("expected the restored application, got app ID %s", to.String(app.GetAppId()))
+			}
+		})
+	}
+}
+
+func TestRestoreDeletedApplicationNotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/directory/deletedItems/object-id/restore", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": "Request_ResourceNotFound", "message": "Resource 'object-id' does not exist."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if _, err := c.RestoreDeletedApplication(context.Background(), "object-id"); !isGraphResourceNotFound(err) {
+		t.Errorf("expected resource not found error, got %v", err)
+	}
+}
+
+func TestPermanentlyDeleteApplication(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{
+			name:   "deleted",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "already deleted",
+			status: http.StatusNotFound,
+			body:   `{"error": {"code": "Request_ResourceNotFound", "message": "Resource 'object-id' does not exist."}}`,
+		},
+		{
+			name:    "forbidden",
+			status:  http.StatusForbidden,
+			body:    `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/directory/deletedItems/object-id", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Errorf("expected DELETE request, got %s", r.Method)
+				}
+				if tt.body != "" {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			c := newTestAzureClient(t, mux)
+
+			if err := c.PermanentlyDeleteApplication(context.Background(), "object-id"); (err != nil) != tt.wantErr {
+				t.Errorf("PermanentlyDeleteApplication() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
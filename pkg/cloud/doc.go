@@ -0,0 +1,41 @@
+// Package cloud is the client of Microsoft Graph and Azure Resource Manager used to manage the AAD applications,
+// service principals, federated identity credentials, user-assigned managed identities and role assignments.
+//
+// # Graph permissions
+//
+// The Graph requests are authorized with the .default scope of the Graph endpoint of the cloud unless
+// WithGraphScopes is passed. A token issued to an application, e.g. a service principal or a managed identity,
+// always has the application permissions granted to the application, so only the .default scope can be
+// requested. A token issued to a user has the delegated permissions of the requested scopes, which GraphScopes
+// qualifies with the Graph endpoint of the cloud, so a client that only reads applications can request
+// Application.Read.All rather than every permission consented to.
+//
+// The least privileged permissions required by the methods are:
+//
+//   - Application.Read.All: the Get, List and Find methods of the applications, service principals and their
+//     federated identity credentials, ApplicationsDelta, ExportApplication, DetectSubjectCollisions and
+//     ValidateFederatedCredentialIssuer.
+//   - Application.ReadWrite.OwnedBy: the methods that create, update or delete the applications owned by the
+//     principal, their service principals and federated identity credentials, e.g. CreateApplication,
+//     AddFederatedCredential, ReconcileFederatedCredentials, TagApplications, ImportApplication and
+//     TransferApplicationOwnership. Application.ReadWrite.All is required for the applications of other owners,
+//     and for ListDeletedApplications, RestoreDeletedApplication and PermanentlyDeleteApplication.
+//   - DelegatedPermissionGrant.ReadWrite.All: GrantAdminConsent and DeleteServicePrincipalOAuth2PermissionGrant;
+//     DelegatedPermissionGrant.Read.All for ListServicePrincipalOAuth2PermissionGrants.
+//   - CustomSecAttributeAssignment.ReadWrite.All: SetServicePrincipalCustomSecurityAttributes. A signed-in
+//     user also needs the Attribute Assignment Administrator role.
+//   - AuditLog.Read.All: GetServicePrincipalSignInActivity, which also requires Microsoft Entra ID P1 or P2.
+//
+// The role assignment and managed identity methods are authorized by Azure RBAC rather than Graph permissions.
+// CheckRequiredPermissions reports the permissions missing from the Graph token of the client.
+//
+// # Partial results
+//
+// The methods that list across pages return no items if a page fails, unless WithPartialResults is passed, in
+// which case they return the items of the pages listed before the failed page along with the error. This lets
+// best-effort callers, e.g. audits, proceed with what was listed. The items are never complete when the error is
+// not nil. The methods that support partial results are ListServicePrincipalsByTag, ListFederatedCredentials,
+// GetFederatedCredentialsBySubject, ListDeletedApplications, ListServicePrincipalOAuth2PermissionGrants and
+// ListManagedIdentityFederatedCredentials. ApplicationsDelta
+// never returns partial results since the changes can only be resumed from a delta link.
+package cloud
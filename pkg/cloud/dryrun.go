@@ -0,0 +1,32 @@
+package cloud
+
+import (
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// DryRunObjectID is the object ID of the placeholder objects returned by the creates in dry-run mode.
+const DryRunObjectID = "00000000-0000-0000-0000-000000000000"
+
+// SetDryRun enables the dry-run mode of the AzureClient, in which the operations that create, update or delete
+// objects log the intended action at the info level and succeed without sending their requests, e.g. to preview
+// the changes of an onboarding. The creates return a placeholder object with the requested properties and the
+// DryRunObjectID object ID. The reads are still sent, so that the preview reflects the existing objects.
+// The dry-run mode is disabled by default.
+func (c *AzureClient) SetDryRun(dryRun bool) {
+	c.dryRun = dryRun
+}
+
+// skipInDryRun logs the action and returns true if the AzureClient is in dry-run mode, in which case the caller
+// must return without sending the request of the action.
+func (c *AzureClient) skipInDryRun(action string, keysAndValues ...interface{}) bool {
+	if !c.dryRun {
+		return false
+	}
+	c.log().Info("Dry run: "+action, redactKeysAndValues(keysAndValues)...)
+	return true
+}
+
+// dryRunObjectID returns the object ID of a placeholder object.
+func dryRunObjectID() *string {
+	return to.StringPtr(DryRunObjectID)
+}
@@ -0,0 +1,137 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestDryRun(t *testing.T) {
+	var reads int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s %s request in dry-run mode", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		reads++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1.0/servicePrincipals/sp-object-id":
+			_, _ = w.Write([]byte(`{"id": "sp-object-id", "tags": ["owner:team-a"]}`))
+		case "/v1.0/applications/object-id/federatedIdentityCredentials":
+			_, _ = w.Write([]byte(`{"value": [{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa"}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	c := newTestAzureClient(t, handler)
+	c.SetDryRun(true)
+	ctx := context.Background()
+
+	logs := captureDebugLogs(t, func() {
+		app, err := c.CreateApplication(ctx, "app")
+		if err != nil {
+			t.Fatalf("CreateApplication() error = %v", err)
+		}
+		if to.String(app.GetId()) != DryRunObjectID || to.String(app.GetDisplayName()) != "app" {
+			t.Errorf("CreateApplication() = %s %s, want a placeholder application", to.String(app.GetId()), to.String(app.GetDisplayName()))
+		}
+
+		sp, err := c.CreateServicePrincipal(ctx, to.String(app.GetAppId()), nil)
+		if err != nil {
+			t.Fatalf("CreateServicePrincipal() error = %v", err)
+		}
+		if to.String(sp.GetId()) != DryRunObjectID || to.String(sp.GetAppId()) != DryRunObjectID {
+			t.Errorf("CreateServicePrincipal() = %s %s, want a placeholder service principal", to.String(sp.GetId()), to.String(sp.GetAppId()))
+		}
+
+		fic := models.NewFederatedIdentityCredential()
+		fic.SetName(to.StringPtr("fic"))
+		fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+		fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+		fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+		if _, err := c.AddFederatedCredential(ctx, "object-id", fic); err != nil {
+			t.Errorf("AddFederatedCredential() error = %v", err)
+		}
+		if err := c.UpdateFederatedCredential(ctx, "object-id", "fic-id", fic); err != nil {
+			t.Errorf("UpdateFederatedCredential() error = %v", err)
+		}
+		if err := c.UpdateApplicationDisplayName(ctx, "object-id", "renamed"); err != nil {
+			t.Errorf("UpdateApplicationDisplayName() error = %v", err)
+		}
+		if err := c.AddServicePrincipalTags(ctx, "sp-object-id", map[string]string{"env": "prod"}); err != nil {
+			t.Errorf("AddServicePrincipalTags() error = %v", err)
+		}
+		if err := c.DeleteFederatedCredentialBySubject(ctx, "object-id", "https://issuer.example.com/", "system:serviceaccount:default:sa"); err != nil {
+			t.Errorf("DeleteFederatedCredentialBySubject() error = %v", err)
+		}
+		if err := c.DeleteServicePrincipal(ctx, "sp-object-id"); err != nil {
+			t.Errorf("DeleteServicePrincipal() error = %v", err)
+		}
+		if err := c.DeleteApplication(ctx, "object-id"); err != nil {
+			t.Errorf("DeleteApplication() error = %v", err)
+		}
+	})
+
+	// the reads are still sent to preview the changes
+	if reads != 2 {
+		t.Errorf("expected 2 read requests, got %d", reads)
+	}
+	for _, want := range []string{
+		"Dry run: creating application",
+		"Dry run: creating service principal for application",
+		"Dry run: adding federated credential",
+		"Dry run: updating federated credential",
+		"Dry run: updating application",
+		"Dry run: updating service principal tags",
+		"Dry run: deleting federated credential",
+		"Dry run: deleting service principal",
+		"Dry run: deleting application",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expected %q to be logged, got:\n%s", want, logs)
+		}
+	}
+}
+
+func TestDryRunAddFederatedCredentials(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %s %s request in dry-run mode", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c := newTestAzureClient(t, handler)
+	c.SetDryRun(true)
+
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+
+	results, err := c.AddFederatedCredentials(context.Background(), []FederatedCredentialRequest{{ObjectID: "object-id", FederatedCredential: fic}})
+	if err != nil {
+		t.Fatalf("AddFederatedCredentials() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("AddFederatedCredentials() = %+v, want a single successful result", results)
+	}
+	if got := results[0].FederatedCredential; to.String(got.GetId()) != DryRunObjectID || to.String(got.GetName()) != "fic" {
+		t.Errorf("AddFederatedCredentials() federated credential = %s %s, want a placeholder fic", to.String(got.GetId()), to.String(got.GetName()))
+	}
+
+	errs, err := c.DeleteFederatedCredentialsBatch(context.Background(), "object-id", []string{"fic-1", "fic-2"})
+	if err != nil {
+		t.Fatalf("DeleteFederatedCredentialsBatch() error = %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("DeleteFederatedCredentialsBatch() error %d = %v", i, err)
+		}
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"strings"
 
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
 	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
 	"github.com/pkg/errors"
 )
 
@@ -16,18 +19,252 @@ const (
 	GraphErrorCodeResourceNotFound = "Request_ResourceNotFound"
 	// GraphErrorCodeMultipleObjectsWithSameKeyValue is the error code for multiple objects with same key value.
 	GraphErrorCodeMultipleObjectsWithSameKeyValue = "Request_MultipleObjectsWithSameKeyValue"
+	// GraphErrorCodeAuthorizationRequestDenied is the error code for insufficient privileges to complete the operation.
+	GraphErrorCodeAuthorizationRequestDenied = "Authorization_RequestDenied"
+	// GraphErrorCodeAccessDenied is the generic error code for a caller that doesn't have permission to perform the action.
+	GraphErrorCodeAccessDenied = "accessDenied"
+	// graphErrorCodeBadRequest is the error code for a request that Graph rejected, e.g. because it is invalid.
+	graphErrorCodeBadRequest = "Request_BadRequest"
+
+	// insufficientPrivilegesRequiredPermission is the Graph application permission that is the least privileged
+	// one to create and manage the applications, service principals and federated credentials of azwi.
+	insufficientPrivilegesRequiredPermission = "Application.ReadWrite.OwnedBy"
+	// signInActivityRequiredPermission is the Graph application permission required to read the sign-in activity
+	// of the service principals.
+	signInActivityRequiredPermission = "AuditLog.Read.All"
+	// appRoleAssignmentRequiredPermission is the Graph application permission required to assign the app roles of
+	// service principals.
+	appRoleAssignmentRequiredPermission = "AppRoleAssignment.ReadWrite.All"
 )
 
-// GraphError is a custom error type for Graph API errors.
+// ErrInsufficientPrivileges is matched by errors.Is for the errors Graph returns when the caller
+// lacks the permission to complete the operation, see InsufficientPrivilegesError.
+var ErrInsufficientPrivileges = errors.New("insufficient privileges")
+
+// InsufficientPrivilegesError is the error Graph returns when the caller lacks the permission to complete the
+// operation, with a hint of the permission to grant.
+type InsufficientPrivilegesError struct {
+	// Code is the Graph error code, e.g. Authorization_RequestDenied.
+	Code string
+	// Message is the Graph error message.
+	Message string
+	// RequiredPermission is the Graph application permission the caller usually lacks.
+	RequiredPermission string
+
+	err error
+}
+
+// Error returns the error message with the permission to grant.
+func (e *InsufficientPrivilegesError) Error() string {
+	return fmt.Sprintf("%s (code: %s, message: %s), grant the %s Microsoft Graph application permission to the caller",
+		ErrInsufficientPrivileges, e.Code, e.Message, e.RequiredPermission)
+}
+
+// Is returns true if the target is ErrInsufficientPrivileges.
+func (e *InsufficientPrivilegesError) Is(target error) bool {
+	return target == ErrInsufficientPrivileges
+}
+
+// Unwrap returns the error returned by the Graph SDK.
+func (e *InsufficientPrivilegesError) Unwrap() error {
+	return e.err
+}
+
+// ErrMultipleMatches is matched by errors.Is for the errors of the lookups by display name that match more
+// than one object, see MultipleMatchesError.
+var ErrMultipleMatches = errors.New("multiple matches")
+
+// MultipleMatchesError is the error of a lookup by display name that matches more than one object. Display
+// names are not unique, so picking one of the objects could pick the wrong one.
+type MultipleMatchesError struct {
+	// Kind is the kind of the objects, e.g. application.
+	Kind string
+	// DisplayName is the display name that was looked up.
+	DisplayName string
+	// ObjectIDs are the object IDs of the matching objects.
+	ObjectIDs []string
+	// AppIDs are the app IDs of the matching objects, in the order of their object IDs, e.g. to get the
+	// intended one with GetServicePrincipalByAppID.
+	AppIDs []string
+}
+
+// Error returns the error message with the object IDs and app IDs of the matching objects.
+func (e *MultipleMatchesError) Error() string {
+	return fmt.Sprintf("%s: found %d %ss with display name '%s' (object IDs: %s; app IDs: %s)",
+		ErrMultipleMatches, len(e.ObjectIDs), e.Kind, e.DisplayName, strings.Join(e.ObjectIDs, ", "), strings.Join(e.AppIDs, ", "))
+}
+
+// Is returns true if the target is ErrMultipleMatches.
+func (e *MultipleMatchesError) Is(target error) bool {
+	return target == ErrMultipleMatches
+}
+
+// GraphError is a custom error type for Graph API errors. It is the error returned for a Graph error in the body
+// of a response, see GetGraphError, and can be extracted with errors.As to branch on its code, or with AsGraphError
+// to also get the errors returned by the Graph SDK.
 type GraphError struct {
+	// Code is the code of the Graph error, e.g. Request_ResourceNotFound.
+	Code string
+	// Message is the message of the Graph error.
+	Message string
+	// StatusCode is the HTTP status code of the response with the Graph error, or zero if it is unknown.
+	StatusCode int
+
+	// PublicError is the Graph error as deserialized from the response. Its code and message are
+	// used if Code and Message are empty.
 	PublicError *models.PublicError
 }
 
-// IsNotFound returns true if the given error is a NotFound error.
+// code returns the code of the Graph error, falling back to the code of the PublicError.
+func (e GraphError) code() string {
+	if e.Code != "" || e.PublicError == nil {
+		return e.Code
+	}
+	return to.String(e.PublicError.GetCode())
+}
+
+// message returns the message of the Graph error, falling back to the message of the PublicError.
+func (e GraphError) message() string {
+	if e.Message != "" || e.PublicError == nil {
+		return e.Message
+	}
+	return to.String(e.PublicError.GetMessage())
+}
+
+// AsGraphError returns the Graph error in the chain of the error, whether it was returned in the body of the
+// response (GraphError) or by the Graph SDK (ODataError), with its code, message and status code set.
+// It returns false if there is no Graph error.
+func AsGraphError(err error) (*GraphError, bool) {
+	var oerr *odataerrors.ODataError
+	if errors.As(err, &oerr) && oerr.GetError() != nil {
+		return &GraphError{
+			Code:       to.String(oerr.GetError().GetCode()),
+			Message:    to.String(oerr.GetError().GetMessage()),
+			StatusCode: oerr.ResponseStatusCode,
+		}, true
+	}
+	gerr := GraphError{}
+	if errors.As(err, &gerr) {
+		gerr.Code, gerr.Message = gerr.code(), gerr.message()
+		return &gerr, true
+	}
+	return nil, false
+}
+
+// GraphErrorCode returns the code of the Graph error in the chain of the error, whether it was returned in the body
+// of the response (GraphError) or by the Graph SDK (ODataError), e.g. to compare it with GraphErrorCodeResourceNotFound.
+// It returns an empty string if there is no Graph error.
+func GraphErrorCode(err error) string {
+	if gerr, ok := AsGraphError(err); ok {
+		return gerr.Code
+	}
+	return ""
+}
+
+// IsNotFound returns true if the given error is a NotFound error. A Graph error, see AsGraphError, is a NotFound
+// error if its status code is 404 or its code is Request_ResourceNotFound, regardless of its message. The message
+// of other errors is checked, e.g. for the errors of the lookups by display name that don't match any object.
 func IsNotFound(err error) bool {
+	if gerr, ok := AsGraphError(err); ok {
+		return gerr.StatusCode == http.StatusNotFound || gerr.Code == GraphErrorCodeResourceNotFound
+	}
+	var aerr *abstractions.ApiError
+	if errors.As(err, &aerr) {
+		return aerr.ResponseStatusCode == http.StatusNotFound
+	}
 	return strings.Contains(err.Error(), "not found")
 }
 
+// isGraphResourceNotFound returns true if the given error is the error returned by the Graph SDK
+// when the requested resource doesn't exist.
+func isGraphResourceNotFound(err error) bool {
+	var oerr *odataerrors.ODataError
+	if errors.As(err, &oerr) && oerr.GetError() != nil && oerr.GetError().GetCode() != nil {
+		return *oerr.GetError().GetCode() == GraphErrorCodeResourceNotFound
+	}
+	var aerr *abstractions.ApiError
+	return errors.As(err, &aerr) && aerr.ResponseStatusCode == http.StatusNotFound
+}
+
+// isMultipleObjectsWithSameKeyValue returns true if the given error, returned by the Graph SDK or in the
+// response, is the error Graph returns when an object with the same unique key already exists.
+func isMultipleObjectsWithSameKeyValue(err error) bool {
+	var oerr *odataerrors.ODataError
+	if errors.As(err, &oerr) && oerr.GetError() != nil && oerr.GetError().GetCode() != nil {
+		return *oerr.GetError().GetCode() == GraphErrorCodeMultipleObjectsWithSameKeyValue
+	}
+	return IsFederatedCredentialAlreadyExists(err)
+}
+
+// isOwnerAlreadyExists returns true if the given error is the error Graph returns when the directory object
+// added to the owners of an object is already an owner: a Request_BadRequest ODataError whose message is
+// "One or more added object references already exist for the following modified properties: 'owners'.".
+func isOwnerAlreadyExists(err error) bool {
+	var oerr *odataerrors.ODataError
+	if !errors.As(err, &oerr) || oerr.GetError() == nil {
+		return false
+	}
+	return to.String(oerr.GetError().GetCode()) == graphErrorCodeBadRequest &&
+		strings.Contains(to.String(oerr.GetError().GetMessage()), "object references already exist")
+}
+
+// isAppRoleAssignmentAlreadyExists returns true if the given error is the error Graph returns when an app role is
+// assigned to a principal that has already been assigned it: a Request_BadRequest ODataError whose message is
+// "Permission being assigned already exists on the object".
+func isAppRoleAssignmentAlreadyExists(err error) bool {
+	var oerr *odataerrors.ODataError
+	if !errors.As(err, &oerr) || oerr.GetError() == nil {
+		return false
+	}
+	return to.String(oerr.GetError().GetCode()) == graphErrorCodeBadRequest &&
+		strings.Contains(to.String(oerr.GetError().GetMessage()), "Permission being assigned already exists")
+}
+
+// isApplicationNotPropagated returns true if the given error is the error Graph returns when a federated
+// credential is added to an application that was just created and hasn't propagated yet: an ODataError with
+// the Request_ResourceNotFound code. Unlike isGraphResourceNotFound, a 404 without that code doesn't match.
+func isApplicationNotPropagated(err error) bool {
+	var oerr *odataerrors.ODataError
+	if !errors.As(err, &oerr) || oerr.GetError() == nil || oerr.GetError().GetCode() == nil {
+		return false
+	}
+	return *oerr.GetError().GetCode() == GraphErrorCodeResourceNotFound
+}
+
+// withODataErrorDetails adds the code and message of the Graph error to the error returned by the Graph SDK,
+// whose message is otherwise only the generic message of the API errors.
+func withODataErrorDetails(err error) error {
+	var oerr *odataerrors.ODataError
+	if !errors.As(err, &oerr) || oerr.GetError() == nil || oerr.GetError().GetCode() == nil {
+		return err
+	}
+	mainErr := oerr.GetError()
+	if mainErr.GetMessage() == nil {
+		return errors.WithMessagef(err, "code: %s", *mainErr.GetCode())
+	}
+	return errors.WithMessagef(err, "code: %s, message: %s", *mainErr.GetCode(), *mainErr.GetMessage())
+}
+
+// withInsufficientPrivileges returns an InsufficientPrivilegesError if the error returned by the Graph SDK,
+// or the Graph error in its response, has one of the authorization error codes. Other errors are returned as is.
+func withInsufficientPrivileges(err error) error {
+	return withRequiredPermission(err, insufficientPrivilegesRequiredPermission)
+}
+
+// withRequiredPermission is like withInsufficientPrivileges for an operation that requires the given permission.
+func withRequiredPermission(err error, permission string) error {
+	gerr, ok := AsGraphError(err)
+	if !ok || (gerr.Code != GraphErrorCodeAuthorizationRequestDenied && gerr.Code != GraphErrorCodeAccessDenied) {
+		return err
+	}
+	return &InsufficientPrivilegesError{
+		Code:               gerr.Code,
+		Message:            gerr.Message,
+		RequiredPermission: permission,
+		err:                err,
+	}
+}
+
 // IsRoleAssignmentAlreadyDeleted returns true if the given error is a role assignment already deleted error.
 // Ref: https://docs.microsoft.com/en-us/rest/api/authorization/role-assignments/delete#response
 func IsRoleAssignmentAlreadyDeleted(err error) bool {
@@ -44,17 +281,23 @@ func IsAlreadyExists(err error) bool {
 // IsFederatedCredentialNotFound returns true if the given error is a federated credential not found error.
 func IsFederatedCredentialNotFound(err error) bool {
 	gerr := GraphError{}
-	return errors.As(err, &gerr) && *gerr.PublicError.GetCode() == GraphErrorCodeResourceNotFound
+	return errors.As(err, &gerr) && gerr.code() == GraphErrorCodeResourceNotFound
 }
 
 // IsFederatedCredentialAlreadyExists returns true if the given error is a federated credential already exists error.
 // E1202 22:40:05.500821  867104 main.go:57] "failed to add federated identity credential" err="code: Request_MultipleObjectsWithSameKeyValue, message: FederatedIdentityCredential with name aramase-default-cred already exists."
+// AddFederatedCredential returns ErrFederatedCredentialAlreadyExists in that case.
 func IsFederatedCredentialAlreadyExists(err error) bool {
+	if errors.Is(err, ErrFederatedCredentialAlreadyExists) {
+		return true
+	}
 	gerr := GraphError{}
-	return errors.As(err, &gerr) && *gerr.PublicError.GetCode() == GraphErrorCodeMultipleObjectsWithSameKeyValue
+	return errors.As(err, &gerr) && gerr.code() == GraphErrorCodeMultipleObjectsWithSameKeyValue
 }
 
 // GetGraphError returns the public error message from the additional info.
+// The Graph SDK only deserializes the body of the responses that didn't fail, so the status code of the error
+// is http.StatusOK; the errors of the failed responses are returned by the Graph SDK, see AsGraphError.
 // ref: https://docs.microsoft.com/en-us/graph/errors#error-resource-type
 // errors returned by the graph API aren't serialized today and this is a known issue: https://github.com/microsoftgraph/msgraph-sdk-go-core/issues/1
 func GetGraphError(additionalData map[string]interface{}) (*GraphError, error) {
@@ -64,34 +307,70 @@ func GetGraphError(additionalData map[string]interface{}) (*GraphError, error) {
 	e := models.NewPublicError()
 	e.SetAdditionalData(additionalData)
 
-	ad := additionalData["error"].(map[string]*jsonserialization.JsonParseNode)
-	// error code string for the error that occurred
-	code, err := ad["code"].GetStringValue()
-	if err != nil {
-		return nil, err
-	}
-	// developer ready message about the error that occurred. This should not be displayed to the user directly.
-	message, err := ad["message"].GetStringValue()
-	if err != nil {
-		return nil, err
+	switch ad := additionalData["error"].(type) {
+	case map[string]interface{}:
+		// the JSON parse node stores the raw values of the properties that are not deserialized
+		// error code string for the error that occurred
+		e.SetCode(rawStringValue(ad["code"]))
+		// developer ready message about the error that occurred. This should not be displayed to the user directly.
+		e.SetMessage(rawStringValue(ad["message"]))
+		// Optional. Additional error objects that may be more specific than the top level error.
+		if inner, ok := ad["innerError"].(map[string]interface{}); ok {
+			innerError := models.NewPublicInnerError()
+			innerError.SetCode(rawStringValue(inner["code"]))
+			innerError.SetMessage(rawStringValue(inner["message"]))
+			innerError.SetAdditionalData(inner)
+			e.SetInnerError(innerError)
+		}
+	case map[string]*jsonserialization.JsonParseNode:
+		code, err := ad["code"].GetStringValue()
+		if err != nil {
+			return nil, err
+		}
+		message, err := ad["message"].GetStringValue()
+		if err != nil {
+			return nil, err
+		}
+		innerError, err := ad["innerError"].GetObjectValue(models.CreatePublicInnerErrorFromDiscriminatorValue)
+		if err != nil {
+			return nil, err
+		}
+		e.SetCode(code)
+		e.SetMessage(message)
+		if innerError, ok := innerError.(*models.PublicInnerError); ok {
+			e.SetInnerError(innerError)
+		}
+	default:
+		return nil, errors.Errorf("unexpected type %T of the Graph error", ad)
 	}
-	// Optional. Additional error objects that may be more specific than the top level error.
-	innerError, err := ad["innerError"].GetObjectValue(models.CreatePublicInnerErrorFromDiscriminatorValue)
-	if err != nil {
-		return nil, err
+	if e.GetCode() == nil {
+		return nil, errors.New("Graph error without code")
 	}
 
-	e.SetCode(code)
-	e.SetMessage(message)
-	e.SetInnerError(innerError.(*models.PublicInnerError))
+	return &GraphError{
+		Code:        to.String(e.GetCode()),
+		Message:     to.String(e.GetMessage()),
+		StatusCode:  http.StatusOK,
+		PublicError: e,
+	}, nil
+}
 
-	return &GraphError{e}, nil
+// rawStringValue returns the string of a raw value stored by the JSON parse node, or nil if it is not a string.
+func rawStringValue(v interface{}) *string {
+	switch s := v.(type) {
+	case *string:
+		return s
+	case string:
+		return &s
+	default:
+		return nil
+	}
 }
 
 // Error returns the error message.
 func (e GraphError) Error() string {
-	if e.PublicError == nil {
+	if e.Code == "" && e.Message == "" && e.PublicError == nil {
 		return ""
 	}
-	return fmt.Sprintf("code: %s, message: %s", *e.PublicError.GetCode(), *e.PublicError.GetMessage())
+	return fmt.Sprintf("code: %s, message: %s", e.code(), e.message())
 }
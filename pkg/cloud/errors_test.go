@@ -1,15 +1,29 @@
 package cloud
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/to"
+	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
 	"github.com/pkg/errors"
 )
 
 func TestIsNotFound(t *testing.T) {
+	newODataError := func(statusCode int, code, message string) error {
+		mainErr := odataerrors.NewMainError()
+		mainErr.SetCode(to.StringPtr(code))
+		mainErr.SetMessage(to.StringPtr(message))
+		err := odataerrors.NewODataError()
+		err.ResponseStatusCode = statusCode
+		err.SetError(mainErr)
+		return errors.Wrap(err, "failed to get application")
+	}
+
 	tests := []struct {
 		name      string
 		actualErr error
@@ -25,6 +39,31 @@ func TestIsNotFound(t *testing.T) {
 			actualErr: errors.New("something else"),
 			want:      false,
 		},
+		{
+			name:      "Graph error with resource not found code",
+			actualErr: newODataError(http.StatusBadRequest, GraphErrorCodeResourceNotFound, "Resource does not exist."),
+			want:      true,
+		},
+		{
+			name:      "Graph error with 404 status code",
+			actualErr: newODataError(http.StatusNotFound, "itemNotFound", "Resource does not exist."),
+			want:      true,
+		},
+		{
+			name:      "Graph error with not found in the message",
+			actualErr: newODataError(http.StatusForbidden, GraphErrorCodeAuthorizationRequestDenied, "Owner not found for the operation."),
+			want:      false,
+		},
+		{
+			name:      "Graph error in the response with not found in the message",
+			actualErr: GraphError{Code: graphErrorCodeBadRequest, Message: "Property not found.", StatusCode: http.StatusOK},
+			want:      false,
+		},
+		{
+			name:      "Graph error in the response with resource not found code",
+			actualErr: GraphError{Code: GraphErrorCodeResourceNotFound, Message: "Resource does not exist.", StatusCode: http.StatusOK},
+			want:      true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,3 +218,252 @@ func TestIsFederatedCredentialAlreadyExists(t *testing.T) {
 		})
 	}
 }
+
+func TestWithInsufficientPrivileges(t *testing.T) {
+	newODataError := func(code string) error {
+		mainErr := odataerrors.NewMainError()
+		mainErr.SetCode(to.StringPtr(code))
+		mainErr.SetMessage(to.StringPtr("Insufficient privileges to complete the operation."))
+		err := odataerrors.NewODataError()
+		err.SetError(mainErr)
+		return errors.Wrap(err, "failed to create application")
+	}
+	newGraphError := func(code string) error {
+		err := GraphError{PublicError: models.NewPublicError()}
+		err.PublicError.SetCode(to.StringPtr(code))
+		err.PublicError.SetMessage(to.StringPtr("Insufficient privileges to complete the operation."))
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "not graph error",
+			err:  errors.New("insufficient privileges"),
+			want: false,
+		},
+		{
+			name: "odata error request denied",
+			err:  newODataError(GraphErrorCodeAuthorizationRequestDenied),
+			want: true,
+		},
+		{
+			name: "odata error access denied",
+			err:  newODataError(GraphErrorCodeAccessDenied),
+			want: true,
+		},
+		{
+			name: "odata error code doesn't match",
+			err:  newODataError(GraphErrorCodeResourceNotFound),
+			want: false,
+		},
+		{
+			name: "graph error request denied",
+			err:  newGraphError(GraphErrorCodeAuthorizationRequestDenied),
+			want: true,
+		},
+		{
+			name: "graph error code doesn't match",
+			err:  newGraphError(GraphErrorCodeMultipleObjectsWithSameKeyValue),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := withInsufficientPrivileges(tt.err)
+			if got := errors.Is(err, ErrInsufficientPrivileges); got != tt.want {
+				t.Errorf("errors.Is(err, ErrInsufficientPrivileges) = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				if err != tt.err {
+					t.Errorf("expected the error to be returned as is, got %v", err)
+				}
+				return
+			}
+			perr := &InsufficientPrivilegesError{}
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected an InsufficientPrivilegesError, got %T", err)
+			}
+			if perr.RequiredPermission != "Application.ReadWrite.OwnedBy" {
+				t.Errorf("expected the required permission to be Application.ReadWrite.OwnedBy, got %s", perr.RequiredPermission)
+			}
+			if !strings.Contains(err.Error(), "grant the Application.ReadWrite.OwnedBy Microsoft Graph application permission") {
+				t.Errorf("expected the error to name the permission to grant, got %v", err)
+			}
+			// the error returned by the Graph SDK can still be inspected
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected the error to wrap %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestGetGraphError(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantMessage string
+		wantNil     bool
+	}{
+		{
+			name:    "no error",
+			body:    `{"id": "object-id"}`,
+			wantNil: true,
+		},
+		{
+			name:        "error",
+			body:        `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`,
+			wantCode:    GraphErrorCodeAuthorizationRequestDenied,
+			wantMessage: "Insufficient privileges to complete the operation.",
+		},
+		{
+			name:        "error with inner error",
+			body:        `{"error": {"code": "Request_ResourceNotFound", "message": "Resource 'object-id' does not exist.", "innerError": {"date": "2023-01-01T00:00:00", "request-id": "request-id"}}}`,
+			wantCode:    GraphErrorCodeResourceNotFound,
+			wantMessage: "Resource 'object-id' does not exist.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// deserialize the body the way the Graph SDK does to get the additional data of the response
+			node, err := jsonserialization.NewJsonParseNode([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("failed to parse body: %v", err)
+			}
+			app, err := node.GetObjectValue(models.CreateApplicationFromDiscriminatorValue)
+			if err != nil {
+				t.Fatalf("failed to deserialize body: %v", err)
+			}
+
+			graphErr, err := GetGraphError(app.(models.Applicationable).GetAdditionalData())
+			if err != nil {
+				t.Fatalf("GetGraphError() error = %v", err)
+			}
+			if tt.wantNil {
+				if graphErr != nil {
+					t.Errorf("GetGraphError() = %v, want nil", graphErr)
+				}
+				return
+			}
+			if graphErr == nil {
+				t.Fatal("GetGraphError() = nil, want error")
+			}
+
+			var gerr GraphError
+			if !errors.As(errors.Wrap(*graphErr, "failed to get application"), &gerr) {
+				t.Fatal("expected errors.As to find the GraphError")
+			}
+			if gerr.Code != tt.wantCode || gerr.Message != tt.wantMessage {
+				t.Errorf("GraphError = %q, %q, want %q, %q", gerr.Code, gerr.Message, tt.wantCode, tt.wantMessage)
+			}
+			if gerr.StatusCode != http.StatusOK {
+				t.Errorf("GraphError status code = %d, want %d", gerr.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestGraphErrorCode(t *testing.T) {
+	gerr := GraphError{PublicError: models.NewPublicError()}
+	gerr.PublicError.SetCode(to.StringPtr(GraphErrorCodeResourceNotFound))
+	oerr := odataerrors.NewODataError()
+	mainErr := odataerrors.NewMainError()
+	mainErr.SetCode(to.StringPtr(GraphErrorCodeAuthorizationRequestDenied))
+	oerr.SetError(mainErr)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "graph error",
+			err:  errors.Wrap(gerr, "failed"),
+			want: GraphErrorCodeResourceNotFound,
+		},
+		{
+			name: "odata error",
+			err:  errors.Wrap(oerr, "failed"),
+			want: GraphErrorCodeAuthorizationRequestDenied,
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GraphErrorCode(tt.err); got != tt.want {
+				t.Errorf("GraphErrorCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsGraphError(t *testing.T) {
+	publicErr := GraphError{PublicError: models.NewPublicError()}
+	publicErr.PublicError.SetCode(to.StringPtr(GraphErrorCodeResourceNotFound))
+	publicErr.PublicError.SetMessage(to.StringPtr("Resource 'object-id' does not exist."))
+	oerr := odataerrors.NewODataError()
+	oerr.ResponseStatusCode = http.StatusForbidden
+	mainErr := odataerrors.NewMainError()
+	mainErr.SetCode(to.StringPtr(GraphErrorCodeAuthorizationRequestDenied))
+	mainErr.SetMessage(to.StringPtr("Insufficient privileges to complete the operation."))
+	oerr.SetError(mainErr)
+	batchErr := (&batchResponseItem{
+		Status: http.StatusNotFound,
+		Body:   []byte(`{"error": {"code": "Request_ResourceNotFound", "message": "Resource 'object-id' does not exist."}}`),
+	}).err()
+
+	tests := []struct {
+		name   string
+		err    error
+		want   *GraphError
+		wantOK bool
+	}{
+		{
+			name:   "graph error with public error",
+			err:    errors.Wrap(publicErr, "failed"),
+			want:   &GraphError{Code: GraphErrorCodeResourceNotFound, Message: "Resource 'object-id' does not exist."},
+			wantOK: true,
+		},
+		{
+			name:   "odata error",
+			err:    errors.Wrap(oerr, "failed"),
+			want:   &GraphError{Code: GraphErrorCodeAuthorizationRequestDenied, Message: "Insufficient privileges to complete the operation.", StatusCode: http.StatusForbidden},
+			wantOK: true,
+		},
+		{
+			name:   "batch response error",
+			err:    errors.Wrap(batchErr, "failed"),
+			want:   &GraphError{Code: GraphErrorCodeResourceNotFound, Message: "Resource 'object-id' does not exist.", StatusCode: http.StatusNotFound},
+			wantOK: true,
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AsGraphError(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("AsGraphError() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Code != tt.want.Code || got.Message != tt.want.Message || got.StatusCode != tt.want.StatusCode {
+				t.Errorf("AsGraphError() = %q, %q, %d, want %q, %q, %d", got.Code, got.Message, got.StatusCode, tt.want.Code, tt.want.Message, tt.want.StatusCode)
+			}
+		})
+	}
+}
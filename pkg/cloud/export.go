@@ -0,0 +1,257 @@
+package cloud
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// exportedAppIDPlaceholder stands for the app ID of the application in the exported identifier URIs,
+// since the app ID is assigned by Graph and differs once the application is imported.
+const exportedAppIDPlaceholder = "{appId}"
+
+// ApplicationExport is the reusable configuration of an application, without the instance-specific fields
+// such as the app ID, object ID, credentials and owners. The app ID of the application in its identifier URIs,
+// e.g. api://<appId>, is replaced with {appId}.
+type ApplicationExport struct {
+	DisplayName            string                   `json:"displayName"`
+	SignInAudience         string                   `json:"signInAudience,omitempty"`
+	IdentifierURIs         []string                 `json:"identifierUris,omitempty"`
+	RequiredResourceAccess []ExportedResourceAccess `json:"requiredResourceAccess,omitempty"`
+	OptionalClaims         *ExportedOptionalClaims  `json:"optionalClaims,omitempty"`
+	Tags                   []string                 `json:"tags,omitempty"`
+	FederatedCredentials   []ExpectedFIC            `json:"federatedCredentials,omitempty"`
+}
+
+// ExportedResourceAccess is the set of permissions of a resource, e.g. Microsoft Graph, required by an application.
+type ExportedResourceAccess struct {
+	ResourceAppID  string                       `json:"resourceAppId"`
+	ResourceAccess []ExportedResourcePermission `json:"resourceAccess"`
+}
+
+// ExportedResourcePermission is an OAuth2 permission scope, of type Scope, or an app role, of type Role.
+type ExportedResourcePermission struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ExportedOptionalClaims are the optional claims of the tokens issued to an application.
+type ExportedOptionalClaims struct {
+	IDToken     []ExportedOptionalClaim `json:"idToken,omitempty"`
+	AccessToken []ExportedOptionalClaim `json:"accessToken,omitempty"`
+	SAML2Token  []ExportedOptionalClaim `json:"saml2Token,omitempty"`
+}
+
+// ExportedOptionalClaim is an optional claim of a token.
+type ExportedOptionalClaim struct {
+	Name                 string   `json:"name"`
+	Source               string   `json:"source,omitempty"`
+	Essential            bool     `json:"essential,omitempty"`
+	AdditionalProperties []string `json:"additionalProperties,omitempty"`
+}
+
+// ExportApplication serializes the reusable configuration of the application and its federated identity
+// credentials to YAML, e.g. to back it up or to recreate it in another tenant with ImportApplication.
+func (c *AzureClient) ExportApplication(ctx context.Context, objectID string) ([]byte, error) {
+	export, err := c.exportApplication(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list federated credentials")
+	}
+	export.FederatedCredentials = NewExpectedFICs(fics)
+
+	return yaml.Marshal(export)
+}
+
+func (c *AzureClient) exportApplication(ctx context.Context, objectID string) (*ApplicationExport, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Exporting application", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appId", "displayName", "signInAudience", "identifierUris", "requiredResourceAccess", "optionalClaims", "tags"},
+		},
+	}
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+
+	export := &ApplicationExport{
+		DisplayName:    to.String(app.GetDisplayName()),
+		SignInAudience: to.String(app.GetSignInAudience()),
+		Tags:           app.GetTags(),
+	}
+	for _, uri := range app.GetIdentifierUris() {
+		if appID := to.String(app.GetAppId()); appID != "" {
+			uri = strings.ReplaceAll(uri, appID, exportedAppIDPlaceholder)
+		}
+		export.IdentifierURIs = append(export.IdentifierURIs, uri)
+	}
+	for _, rra := range app.GetRequiredResourceAccess() {
+		exported := ExportedResourceAccess{ResourceAppID: to.String(rra.GetResourceAppId())}
+		for _, ra := range rra.GetResourceAccess() {
+			var id string
+			if ra.GetId() != nil {
+				id = ra.GetId().String()
+			}
+			exported.ResourceAccess = append(exported.ResourceAccess, ExportedResourcePermission{ID: id, Type: to.String(ra.GetType())})
+		}
+		export.RequiredResourceAccess = append(export.RequiredResourceAccess, exported)
+	}
+	if optional := app.GetOptionalClaims(); optional != nil {
+		export.OptionalClaims = &ExportedOptionalClaims{
+			IDToken:     exportOptionalClaims(optional.GetIdToken()),
+			AccessToken: exportOptionalClaims(optional.GetAccessToken()),
+			SAML2Token:  exportOptionalClaims(optional.GetSaml2Token()),
+		}
+	}
+	return export, nil
+}
+
+// ImportApplication creates an application with the configuration exported by ExportApplication, e.g. in
+// another tenant, and adds its federated identity credentials. {appId} in the identifier URIs is replaced
+// with the app ID of the new application. If the configuration fails to be applied after the application
+// is created, the application is returned with the error.
+func (c *AzureClient) ImportApplication(ctx context.Context, data []byte) (models.Applicationable, error) {
+	c.logDebug("Importing application")
+
+	var export ApplicationExport
+	if err := yaml.UnmarshalStrict(data, &export); err != nil {
+		return nil, errors.Wrap(err, "failed to parse exported application")
+	}
+	if export.DisplayName == "" {
+		return nil, errors.New("exported application has no display name")
+	}
+	if export.SignInAudience != "" && !isValidSignInAudience(export.SignInAudience) {
+		return nil, errors.Errorf("invalid sign-in audience %q, must be one of: %s", export.SignInAudience, strings.Join(signInAudienceValues, ", "))
+	}
+
+	body := models.NewApplication()
+	body.SetDisplayName(to.StringPtr(export.DisplayName))
+	if export.SignInAudience != "" {
+		body.SetSignInAudience(to.StringPtr(export.SignInAudience))
+	}
+	if len(export.Tags) > 0 {
+		body.SetTags(export.Tags)
+	}
+	if len(export.RequiredResourceAccess) > 0 {
+		access, err := importRequiredResourceAccess(export.RequiredResourceAccess)
+		if err != nil {
+			return nil, err
+		}
+		body.SetRequiredResourceAccess(access)
+	}
+	if export.OptionalClaims != nil {
+		optional := models.NewOptionalClaims()
+		optional.SetIdToken(importOptionalClaims(export.OptionalClaims.IDToken))
+		optional.SetAccessToken(importOptionalClaims(export.OptionalClaims.AccessToken))
+		optional.SetSaml2Token(importOptionalClaims(export.OptionalClaims.SAML2Token))
+		body.SetOptionalClaims(optional)
+	}
+
+	app, err := c.createApplication(ctx, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create application")
+	}
+	objectID := to.String(app.GetId())
+
+	if len(export.IdentifierURIs) > 0 {
+		// the identifier URIs can only be derived from the app ID once the application is created
+		identifierURIs := make([]string, 0, len(export.IdentifierURIs))
+		for _, uri := range export.IdentifierURIs {
+			identifierURIs = append(identifierURIs, strings.ReplaceAll(uri, exportedAppIDPlaceholder, to.String(app.GetAppId())))
+		}
+		if err := c.setIdentifierURIs(ctx, objectID, identifierURIs); err != nil {
+			return app, errors.Wrap(err, "failed to set identifier URIs of imported application")
+		}
+		app.SetIdentifierUris(identifierURIs)
+	}
+
+	for _, fic := range export.FederatedCredentials {
+		if _, err := c.AddFederatedCredential(ctx, objectID, fic.toFederatedIdentityCredential()); err != nil {
+			return app, errors.Wrapf(err, "failed to add federated credential %s to imported application", fic.Name)
+		}
+	}
+	return app, nil
+}
+
+func (c *AzureClient) setIdentifierURIs(ctx context.Context, objectID string, identifierURIs []string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	update := models.NewApplication()
+	update.SetIdentifierUris(identifierURIs)
+	return c.patchApplication(ctx, objectID, update)
+}
+
+func exportOptionalClaims(claims []models.OptionalClaimable) []ExportedOptionalClaim {
+	var exported []ExportedOptionalClaim
+	for _, claim := range claims {
+		exported = append(exported, ExportedOptionalClaim{
+			Name:                 to.String(claim.GetName()),
+			Source:               to.String(claim.GetSource()),
+			Essential:            to.Bool(claim.GetEssential()),
+			AdditionalProperties: claim.GetAdditionalProperties(),
+		})
+	}
+	return exported
+}
+
+func importOptionalClaims(exported []ExportedOptionalClaim) []models.OptionalClaimable {
+	var claims []models.OptionalClaimable
+	for _, e := range exported {
+		claim := models.NewOptionalClaim()
+		claim.SetName(to.StringPtr(e.Name))
+		if e.Source != "" {
+			claim.SetSource(to.StringPtr(e.Source))
+		}
+		claim.SetEssential(to.BoolPtr(e.Essential))
+		if len(e.AdditionalProperties) > 0 {
+			claim.SetAdditionalProperties(e.AdditionalProperties)
+		}
+		claims = append(claims, claim)
+	}
+	return claims
+}
+
+func importRequiredResourceAccess(exported []ExportedResourceAccess) ([]models.RequiredResourceAccessable, error) {
+	var access []models.RequiredResourceAccessable
+	for _, e := range exported {
+		rra := models.NewRequiredResourceAccess()
+		rra.SetResourceAppId(to.StringPtr(e.ResourceAppID))
+		var resourceAccess []models.ResourceAccessable
+		for _, grant := range e.ResourceAccess {
+			id, err := uuid.Parse(grant.ID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid permission ID %q of resource %s", grant.ID, e.ResourceAppID)
+			}
+			ra := models.NewResourceAccess()
+			ra.SetId(&id)
+			ra.SetType(to.StringPtr(grant.Type))
+			resourceAccess = append(resourceAccess, ra)
+		}
+		rra.SetResourceAccess(resourceAccess)
+		access = append(access, rra)
+	}
+	return access, nil
+}
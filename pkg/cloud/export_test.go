@@ -0,0 +1,181 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"sigs.k8s.io/yaml"
+)
+
+const testGraphAppID = "00000003-0000-0000-c000-000000000000"
+
+func TestExportImportApplication(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		created map[string]interface{}
+		patched map[string]interface{}
+		fics    []map[string]interface{}
+	)
+	decode := func(r *http.Request) map[string]interface{} {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		return body
+	}
+
+	mux := http.NewServeMux()
+	// the source application
+	mux.HandleFunc("/v1.0/applications/source-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"id": "source-object-id",
+			"appId": "source-app-id",
+			"displayName": "azwi-app",
+			"signInAudience": "AzureADMultipleOrgs",
+			"identifierUris": ["api://source-app-id"],
+			"requiredResourceAccess": [{"resourceAppId": "%s", "resourceAccess": [{"id": "e1fe6dd8-ba31-4d61-89e7-88639da4683d", "type": "Scope"}]}],
+			"optionalClaims": {"idToken": [{"name": "groups", "essential": false, "additionalProperties": ["sam_account_name"]}], "accessToken": [], "saml2Token": []},
+			"tags": ["team:a"]
+		}`, testGraphAppID)
+	})
+	mux.HandleFunc("/v1.0/applications/source-object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "fic-id", "name": "kubernetes-federated-credential", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa", "audiences": ["api://AzureADTokenExchange"], "description": "custom description"}]}`)
+	})
+	// the imported application
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+		mu.Lock()
+		created = decode(r)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "new-object-id", "appId": "new-app-id", "displayName": "azwi-app"}`)
+	})
+	mux.HandleFunc("/v1.0/applications/new-object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		mu.Lock()
+		patched = decode(r)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1.0/applications/new-object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fics = append(fics, decode(r))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "new-fic-id"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	data, err := c.ExportApplication(context.Background(), "source-object-id")
+	if err != nil {
+		t.Fatalf("ExportApplication() error = %v", err)
+	}
+	var export ApplicationExport
+	if err := yaml.UnmarshalStrict(data, &export); err != nil {
+		t.Fatalf("failed to unmarshal exported application: %v", err)
+	}
+	want := ApplicationExport{
+		DisplayName:    "azwi-app",
+		SignInAudience: "AzureADMultipleOrgs",
+		IdentifierURIs: []string{"api://{appId}"},
+		RequiredResourceAccess: []ExportedResourceAccess{
+			{ResourceAppID: testGraphAppID, ResourceAccess: []ExportedResourcePermission{{ID: "e1fe6dd8-ba31-4d61-89e7-88639da4683d", Type: "Scope"}}},
+		},
+		OptionalClaims: &ExportedOptionalClaims{
+			IDToken: []ExportedOptionalClaim{{Name: "groups", AdditionalProperties: []string{"sam_account_name"}}},
+		},
+		Tags: []string{"team:a"},
+		FederatedCredentials: []ExpectedFIC{
+			{Name: "kubernetes-federated-credential", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa", Audiences: []string{"api://AzureADTokenExchange"}, Description: "custom description"},
+		},
+	}
+	if !reflect.DeepEqual(export, want) {
+		t.Errorf("ExportApplication() = %+v, want %+v", export, want)
+	}
+
+	app, err := c.ImportApplication(context.Background(), data)
+	if err != nil {
+		t.Fatalf("ImportApplication() error = %v", err)
+	}
+	if got := to.String(app.GetId()); got != "new-object-id" {
+		t.Errorf("expected the imported application new-object-id, got %s", got)
+	}
+
+	if created["displayName"] != "azwi-app" || created["signInAudience"] != "AzureADMultipleOrgs" {
+		t.Errorf("expected the display name and sign-in audience to be imported, got %v", created)
+	}
+	if got := created["tags"]; !reflect.DeepEqual(got, []interface{}{"team:a"}) {
+		t.Errorf("expected the tags to be imported, got %v", got)
+	}
+	rra, _ := created["requiredResourceAccess"].([]interface{})
+	if len(rra) != 1 || rra[0].(map[string]interface{})["resourceAppId"] != testGraphAppID {
+		t.Errorf("expected the required resource access to be imported, got %v", created["requiredResourceAccess"])
+	}
+	idToken, _ := created["optionalClaims"].(map[string]interface{})["idToken"].([]interface{})
+	if len(idToken) != 1 || idToken[0].(map[string]interface{})["name"] != "groups" {
+		t.Errorf("expected the optional claims to be imported, got %v", created["optionalClaims"])
+	}
+	// the identifier URI is derived from the app ID of the imported application
+	if got := patched["identifierUris"]; !reflect.DeepEqual(got, []interface{}{"api://new-app-id"}) {
+		t.Errorf("expected identifier URIs [api://new-app-id], got %v", got)
+	}
+	if len(fics) != 1 || fics[0]["subject"] != "system:serviceaccount:default:sa" || fics[0]["description"] != "custom description" {
+		t.Errorf("expected the federated credential to be imported, got %v", fics)
+	}
+}
+
+func TestImportApplicationInvalid(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	tests := []struct {
+		name     string
+		data     string
+		errorMsg string
+	}{
+		{
+			name:     "no display name",
+			data:     "signInAudience: AzureADMyOrg\n",
+			errorMsg: "exported application has no display name",
+		},
+		{
+			name:     "unknown field",
+			data:     "displayName: azwi-app\nappId: app-id\n",
+			errorMsg: "failed to parse exported application",
+		},
+		{
+			name:     "invalid sign-in audience",
+			data:     "displayName: azwi-app\nsignInAudience: Everyone\n",
+			errorMsg: `invalid sign-in audience "Everyone"`,
+		},
+		{
+			name:     "invalid permission ID",
+			data:     "displayName: azwi-app\nrequiredResourceAccess:\n- resourceAppId: resource\n  resourceAccess:\n  - id: not-a-uuid\n    type: Scope\n",
+			errorMsg: `invalid permission ID "not-a-uuid" of resource resource`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := c.ImportApplication(context.Background(), []byte(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.errorMsg) {
+				t.Errorf("ImportApplication() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+package cloud
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"monis.app/mlog"
+)
+
+// ExpectedFIC is the desired state of a federated identity credential of an application.
+// Federated identity credentials are matched by name since it is unique per application.
+// An empty description stands for the default description that AddFederatedCredential applies,
+// so existing federated identity credentials without a description are not considered drifted.
+type ExpectedFIC struct {
+	Name        string   `json:"name"`
+	Issuer      string   `json:"issuer"`
+	Subject     string   `json:"subject,omitempty"`
+	Audiences   []string `json:"audiences"`
+	Description string   `json:"description,omitempty"`
+	// ClaimsMatchingExpression makes the federated identity credential a flexible federated identity credential,
+	// see SetClaimsMatchingExpression. Exactly one of Subject and ClaimsMatchingExpression is set.
+	ClaimsMatchingExpression string `json:"claimsMatchingExpression,omitempty"`
+}
+
+// ReconcileResult contains the names of the federated identity credentials that were
+// created, updated or deleted to converge an application to the desired state.
+type ReconcileResult struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+// ReconcileFederatedCredentials converges the federated identity credentials of the application
+// to the desired state. Federated identity credentials that are not desired are deleted first to
+// free up the per-application quota and the issuer and subject pairs, then the existing ones are
+// updated and the missing ones are created. If dryRun is true, the changes are computed but not applied.
+// Each Graph operation is subject to the default timeout rather than the reconciliation as a whole.
+func (c *AzureClient) ReconcileFederatedCredentials(ctx context.Context, objectID string, desired []ExpectedFIC, dryRun bool) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	desiredByName := make(map[string]ExpectedFIC, len(desired))
+	desiredNames := make([]string, 0, len(desired))
+	for _, fic := range desired {
+		if _, ok := desiredByName[fic.Name]; ok {
+			return result, errors.Errorf("duplicate federated credential name %q", fic.Name)
+		}
+		desiredByName[fic.Name] = fic
+		desiredNames = append(desiredNames, fic.Name)
+	}
+	sort.Strings(desiredNames)
+	for _, name := range desiredNames {
+		if err := validateFederatedCredentialMatching(desiredByName[name].Subject, desiredByName[name].ClaimsMatchingExpression); err != nil {
+			return result, errors.Wrapf(err, "invalid federated credential %s", name)
+		}
+	}
+
+	current, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to list federated credentials")
+	}
+	currentByName := make(map[string]models.FederatedIdentityCredentialable, len(current))
+	currentNames := make([]string, 0, len(current))
+	for _, fic := range current {
+		currentByName[to.String(fic.GetName())] = fic
+		currentNames = append(currentNames, to.String(fic.GetName()))
+	}
+	sort.Strings(currentNames)
+
+	logger := mlog.WithValues(redactKeysAndValues([]interface{}{"objectID", objectID, "dryRun", dryRun})...)
+
+	for _, name := range currentNames {
+		if _, ok := desiredByName[name]; ok {
+			continue
+		}
+		logger.Info("deleting federated credential", "name", name)
+		if !dryRun {
+			if err := c.DeleteFederatedCredential(ctx, objectID, to.String(currentByName[name].GetId())); err != nil {
+				return result, errors.Wrapf(err, "failed to delete federated credential %s", name)
+			}
+		}
+		result.Deleted = append(result.Deleted, name)
+	}
+
+	for _, name := range desiredNames {
+		expected := desiredByName[name]
+		fic, ok := currentByName[name]
+		if !ok {
+			continue
+		}
+		if expected.matches(fic) {
+			logger.Debug("federated credential is up to date", "name", name)
+			continue
+		}
+		logger.Info("updating federated credential", "name", name)
+		if !dryRun {
+			// the name of a federated identity credential is immutable
+			update := expected.toFederatedIdentityCredential()
+			update.SetName(nil)
+			if err := c.UpdateFederatedCredential(ctx, objectID, to.String(fic.GetId()), update); err != nil {
+				return result, errors.Wrapf(err, "failed to update federated credential %s", name)
+			}
+		}
+		result.Updated = append(result.Updated, name)
+	}
+
+	for _, name := range desiredNames {
+		if _, ok := currentByName[name]; ok {
+			continue
+		}
+		logger.Info("creating federated credential", "name", name)
+		if !dryRun {
+			if _, err := c.AddFederatedCredential(ctx, objectID, desiredByName[name].toFederatedIdentityCredential()); err != nil {
+				return result, errors.Wrapf(err, "failed to create federated credential %s", name)
+			}
+		}
+		result.Created = append(result.Created, name)
+	}
+
+	return result, nil
+}
+
+// ReconcileAllFederatedCredentials converges the federated identity credentials of many applications, keyed
+// by object ID, to their desired state with at most workers applications reconciled in parallel. It returns the
+// results of the applications that were reconciled and the errors of the others, sorted by object ID.
+// Once the context is done, the applications that were not started yet are not reconciled.
+func (c *AzureClient) ReconcileAllFederatedCredentials(ctx context.Context, desired map[string][]ExpectedFIC, workers int) (map[string]ReconcileResult, []error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	objectIDs := make([]string, 0, len(desired))
+	for objectID := range desired {
+		objectIDs = append(objectIDs, objectID)
+	}
+	sort.Strings(objectIDs)
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]ReconcileResult, len(desired))
+		errs    = make(map[string]error)
+	)
+	objectIDCh := make(chan string)
+	for i := 0; i < workers && i < len(objectIDs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for objectID := range objectIDCh {
+				var result ReconcileResult
+				err := ctx.Err()
+				if err == nil {
+					result, err = c.ReconcileFederatedCredentials(ctx, objectID, desired[objectID], false)
+				}
+				mu.Lock()
+				if err != nil {
+					errs[objectID] = errors.Wrapf(err, "failed to reconcile federated credentials of application %s", objectID)
+				} else {
+					results[objectID] = result
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, objectID := range objectIDs {
+		objectIDCh <- objectID
+	}
+	close(objectIDCh)
+	wg.Wait()
+
+	var aggregated []error
+	for _, objectID := range objectIDs {
+		if err, ok := errs[objectID]; ok {
+			aggregated = append(aggregated, err)
+		}
+	}
+	return results, aggregated
+}
+
+// findApplicationsWorkers is the maximum number of applications whose federated
+// identity credentials are fetched in parallel when finding applications by subject.
+const findApplicationsWorkers = 8
+
+// FindApplicationsBySubject finds the applications in the tenant with a federated identity credential
+// for the subject, e.g. system:serviceaccount:<namespace>:<name>, regardless of the issuer.
+func (c *AzureClient) FindApplicationsBySubject(ctx context.Context, subject string) ([]models.Applicationable, error) {
+	return c.FindApplicationsBySubjectWithTag(ctx, subject, "")
+}
+
+// FindApplicationsBySubjectWithTag finds the applications with the given tag that have a federated identity
+// credential for the subject. If the tag is empty, all the applications in the tenant are searched.
+// The applications are returned in the order they are listed.
+func (c *AzureClient) FindApplicationsBySubjectWithTag(ctx context.Context, subject, tag string) ([]models.Applicationable, error) {
+	c.logDebug("Finding applications", "subject", subject, "tag", tag)
+
+	apps, err := c.listApplicationsByTag(ctx, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list applications")
+	}
+
+	var (
+		wg      sync.WaitGroup
+		trusted = make([]bool, len(apps))
+		errs    = make([]error, len(apps))
+	)
+	indexCh := make(chan int)
+	for i := 0; i < findApplicationsWorkers && i < len(apps); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				fics, err := c.GetFederatedCredentialsBySubject(ctx, to.String(apps[i].GetId()), subject)
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "failed to get federated credentials of application %s", to.String(apps[i].GetId()))
+					continue
+				}
+				trusted[i] = len(fics) > 0
+			}
+		}()
+	}
+	for i := range apps {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	var found []models.Applicationable
+	for i, app := range apps {
+		if trusted[i] {
+			found = append(found, app)
+		}
+	}
+	return found, nil
+}
+
+// DetectSubjectCollisions reports, for each of the subjects that is already trusted, the object IDs of the
+// applications in the tenant with a federated identity credential for it, e.g. to check before trusting
+// the subjects with a user-assigned managed identity when migrating. Subjects that are not trusted are omitted.
+func (c *AzureClient) DetectSubjectCollisions(ctx context.Context, subjects []string) (map[string][]string, error) {
+	return c.DetectSubjectCollisionsWithManagedIdentities(ctx, subjects, nil)
+}
+
+// DetectSubjectCollisionsWithManagedIdentities is like DetectSubjectCollisions but also reports the resource IDs of
+// the user-assigned managed identities with a federated identity credential for the subjects, among the given ones.
+// The object and resource IDs of each subject are sorted.
+func (c *AzureClient) DetectSubjectCollisionsWithManagedIdentities(ctx context.Context, subjects, identityResourceIDs []string) (map[string][]string, error) {
+	c.logDebug("Detecting subject collisions", "subjects", len(subjects), "identities", len(identityResourceIDs))
+
+	wanted := make(map[string]bool, len(subjects))
+	for _, subject := range subjects {
+		wanted[subject] = true
+	}
+
+	apps, err := c.listApplicationsByTag(ctx, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list applications")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		trusted = make(map[string][]string)
+		errs    = make([]error, len(apps)+len(identityResourceIDs))
+	)
+	add := func(subject, id string) {
+		if !wanted[subject] {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, existing := range trusted[subject] {
+			if existing == id {
+				return
+			}
+		}
+		trusted[subject] = append(trusted[subject], id)
+	}
+
+	indexCh := make(chan int)
+	for i := 0; i < findApplicationsWorkers && i < len(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				if i < len(apps) {
+					objectID := to.String(apps[i].GetId())
+					fics, err := c.ListFederatedCredentials(ctx, objectID)
+					if err != nil {
+						errs[i] = errors.Wrapf(err, "failed to list federated credentials of application %s", objectID)
+						continue
+					}
+					for _, fic := range fics {
+						add(to.String(fic.GetSubject()), objectID)
+					}
+					continue
+				}
+				resourceID := identityResourceIDs[i-len(apps)]
+				fics, err := c.ListManagedIdentityFederatedCredentials(ctx, resourceID)
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "failed to list federated credentials of managed identity %s", resourceID)
+					continue
+				}
+				for _, fic := range fics {
+					add(fic.Subject, resourceID)
+				}
+			}
+		}()
+	}
+	for i := range errs {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	for _, ids := range trusted {
+		sort.Strings(ids)
+	}
+	return trusted, nil
+}
+
+// ListTrustedIssuers returns the sorted set of OIDC issuers trusted by the federated identity credentials
+// of the application. The issuers are normalized so that the same issuer spelled differently is listed once.
+func (c *AzureClient) ListTrustedIssuers(ctx context.Context, objectID string) ([]string, error) {
+	c.logDebug("Listing trusted issuers", "objectID", objectID)
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	issuers := []string{}
+	for _, fic := range fics {
+		issuer := normalizeIssuer(to.String(fic.GetIssuer()))
+		if issuer == "" || seen[issuer] {
+			continue
+		}
+		seen[issuer] = true
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+	return issuers, nil
+}
+
+// ListFederatedCredentialsByAudience returns the federated identity credentials of the application whose
+// audiences contain the audience, e.g. to find the ones that don't use the default api://AzureADTokenExchange.
+// Graph doesn't support filtering on the audiences, so all the federated identity credentials are listed.
+func (c *AzureClient) ListFederatedCredentialsByAudience(ctx context.Context, objectID, audience string) ([]models.FederatedIdentityCredentialable, error) {
+	c.logDebug("Listing federated credentials by audience", "objectID", objectID, "audience", audience)
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []models.FederatedIdentityCredentialable{}
+	for _, fic := range fics {
+		for _, a := range fic.GetAudiences() {
+			if a == audience {
+				matching = append(matching, fic)
+				break
+			}
+		}
+	}
+	return matching, nil
+}
+
+// RewriteFederatedCredentialAudiences replaces the old audience with the new audience in the federated identity
+// credentials of the application that have it, e.g. when migrating to a sovereign cloud, and returns the number of
+// federated identity credentials that were updated. The other federated identity credentials are left untouched.
+func (c *AzureClient) RewriteFederatedCredentialAudiences(ctx context.Context, objectID, oldAudience, newAudience string) (int, error) {
+	if oldAudience == "" || newAudience == "" {
+		return 0, errors.New("old and new audiences are required")
+	}
+
+	c.logDebug("Rewriting federated credential audiences", "objectID", objectID, "oldAudience", oldAudience, "newAudience", newAudience)
+
+	fics, err := c.ListFederatedCredentialsByAudience(ctx, objectID, oldAudience)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+	for _, fic := range fics {
+		audiences := []string{}
+		seen := make(map[string]bool)
+		for _, a := range fic.GetAudiences() {
+			if a == oldAudience {
+				a = newAudience
+			}
+			if !seen[a] {
+				seen[a] = true
+				audiences = append(audiences, a)
+			}
+		}
+
+		// the other properties are sent unchanged so that the federated identity credential is replaced as a whole
+		update := models.NewFederatedIdentityCredential()
+		update.SetIssuer(fic.GetIssuer())
+		if expression := GetClaimsMatchingExpression(fic); expression != "" {
+			SetClaimsMatchingExpression(update, expression)
+		} else {
+			update.SetSubject(fic.GetSubject())
+		}
+		update.SetAudiences(audiences)
+		update.SetDescription(fic.GetDescription())
+		if err := c.UpdateFederatedCredential(ctx, objectID, to.String(fic.GetId()), update); err != nil {
+			return rewritten, errors.Wrapf(err, "failed to update federated credential %s", to.String(fic.GetName()))
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// RewriteAllFederatedCredentialAudiences is like RewriteFederatedCredentialAudiences for all the applications in the
+// tenant. It returns the total number of federated identity credentials that were updated, including the ones of
+// the applications that were rewritten before an error.
+func (c *AzureClient) RewriteAllFederatedCredentialAudiences(ctx context.Context, oldAudience, newAudience string) (int, error) {
+	if oldAudience == "" || newAudience == "" {
+		return 0, errors.New("old and new audiences are required")
+	}
+
+	apps, err := c.listApplicationsByTag(ctx, "")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list applications")
+	}
+
+	var (
+		wg        sync.WaitGroup
+		rewritten = make([]int, len(apps))
+		errs      = make([]error, len(apps))
+	)
+	indexCh := make(chan int)
+	for i := 0; i < findApplicationsWorkers && i < len(apps); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				rewritten[i], errs[i] = c.RewriteFederatedCredentialAudiences(ctx, to.String(apps[i].GetId()), oldAudience, newAudience)
+				if errs[i] != nil {
+					errs[i] = errors.Wrapf(errs[i], "failed to rewrite federated credential audiences of application %s", to.String(apps[i].GetId()))
+				}
+			}
+		}()
+	}
+	for i := range apps {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	total := 0
+	for _, n := range rewritten {
+		total += n
+	}
+	return total, utilerrors.NewAggregate(errs)
+}
+
+// serviceAccountSubjectPrefix is the prefix of the subject of the tokens issued to Kubernetes service accounts.
+const serviceAccountSubjectPrefix = "system:serviceaccount:"
+
+// FindStaleFederatedCredentials returns the federated identity credentials of the application for a Kubernetes
+// service account, i.e. with a system:serviceaccount:<namespace>:<name> subject, that is not in the set of the
+// subjects of the service accounts that exist, e.g. to remove the trusts of deleted service accounts. The federated
+// identity credentials of other subjects, e.g. GitHub Actions workflows, are never considered stale.
+func (c *AzureClient) FindStaleFederatedCredentials(ctx context.Context, objectID string, activeSubjects map[string]bool) ([]models.FederatedIdentityCredentialable, error) {
+	c.logDebug("Finding stale federated credentials", "objectID", objectID, "activeSubjects", len(activeSubjects))
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []models.FederatedIdentityCredentialable{}
+	for _, fic := range fics {
+		subject := to.String(fic.GetSubject())
+		if !strings.HasPrefix(subject, serviceAccountSubjectPrefix) || activeSubjects[subject] {
+			continue
+		}
+		stale = append(stale, fic)
+	}
+	return stale, nil
+}
+
+// SARef references a Kubernetes service account and the client ID it is annotated with.
+type SARef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ClientID  string `json:"clientId"`
+	// Issuer is the issuer of the service account tokens. If empty, a federated identity
+	// credential for the service account matches regardless of its issuer.
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// subject returns the subject of the tokens issued to the service account.
+func (r SARef) subject() string {
+	return serviceAccountSubjectPrefix + r.Namespace + ":" + r.Name
+}
+
+// DetectMissingFederatedCredentials returns the references whose application has no federated identity credential
+// for the service account, in which case the token exchange of the service account fails. The references whose client
+// ID does not belong to an application, e.g. the ones of user-assigned managed identities, are not checked.
+func (c *AzureClient) DetectMissingFederatedCredentials(ctx context.Context, references []SARef) ([]SARef, error) {
+	c.logDebug("Detecting missing federated credentials", "references", len(references))
+
+	// the federated identity credentials are listed once per application as service accounts often share one.
+	// The client IDs that don't belong to an application are mapped to nil.
+	fics := make(map[string][]models.FederatedIdentityCredentialable)
+	missing := []SARef{}
+	for _, ref := range references {
+		appFICs, ok := fics[ref.ClientID]
+		if !ok {
+			app, err := c.GetApplicationByAppID(ctx, ref.ClientID)
+			if err != nil && !IsNotFound(err) {
+				return nil, errors.Wrapf(err, "failed to get application with app ID %s", ref.ClientID)
+			}
+			if err == nil {
+				if appFICs, err = c.ListFederatedCredentials(ctx, to.String(app.GetId())); err != nil {
+					return nil, errors.Wrapf(err, "failed to list federated credentials of application %s", to.String(app.GetId()))
+				}
+				if appFICs == nil {
+					appFICs = []models.FederatedIdentityCredentialable{}
+				}
+			}
+			fics[ref.ClientID] = appFICs
+		}
+		if appFICs == nil {
+			c.logDebug("Skipping client ID that is not an application", "clientID", ref.ClientID)
+			continue
+		}
+
+		found := false
+		for _, fic := range appFICs {
+			if to.String(fic.GetSubject()) == ref.subject() &&
+				(ref.Issuer == "" || normalizeIssuer(to.String(fic.GetIssuer())) == normalizeIssuer(ref.Issuer)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ref)
+		}
+	}
+	return missing, nil
+}
+
+// normalizeIssuer returns the issuer URL with a lowercase scheme and host and without a trailing slash.
+func normalizeIssuer(issuer string) string {
+	issuer = strings.TrimSpace(issuer)
+	u, err := url.Parse(issuer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return strings.TrimRight(issuer, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// matches returns true if the federated identity credential is in the expected state.
+// The order of the audiences is not significant.
+func (e ExpectedFIC) matches(fic models.FederatedIdentityCredentialable) bool {
+	if e.Issuer != to.String(fic.GetIssuer()) ||
+		e.Subject != to.String(fic.GetSubject()) ||
+		e.ClaimsMatchingExpression != GetClaimsMatchingExpression(fic) ||
+		e.description() != federatedCredentialDescription(to.String(fic.GetDescription()), to.String(fic.GetIssuer())) {
+		return false
+	}
+
+	return sameAudiences(e.Audiences, fic.GetAudiences())
+}
+
+// sameAudiences returns true if the audiences are the same regardless of their order.
+func sameAudiences(expected, current []string) bool {
+	if len(expected) != len(current) {
+		return false
+	}
+	expectedAudiences := append([]string(nil), expected...)
+	currentAudiences := append([]string(nil), current...)
+	sort.Strings(expectedAudiences)
+	sort.Strings(currentAudiences)
+	for i := range expectedAudiences {
+		if expectedAudiences[i] != currentAudiences[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e ExpectedFIC) toFederatedIdentityCredential() models.FederatedIdentityCredentialable {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr(e.Name))
+	fic.SetIssuer(to.StringPtr(e.Issuer))
+	if e.ClaimsMatchingExpression != "" {
+		SetClaimsMatchingExpression(fic, e.ClaimsMatchingExpression)
+	} else {
+		fic.SetSubject(to.StringPtr(e.Subject))
+	}
+	fic.SetAudiences(e.Audiences)
+	fic.SetDescription(to.StringPtr(e.description()))
+	return fic
+}
+
+// description returns the description of the federated identity credential, which is
+// the default description if none is expected.
+func (e ExpectedFIC) description() string {
+	return federatedCredentialDescription(e.Description, e.Issuer)
+}
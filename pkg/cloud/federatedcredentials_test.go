@@ -0,0 +1,630 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+)
+
+const testFederatedCredentialsPath = "/v1.0/applications/object-id/federatedIdentityCredentials"
+
+// fakeFederatedCredential is the JSON representation of a federated identity credential.
+type fakeFederatedCredential struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Issuer      string   `json:"issuer,omitempty"`
+	Subject     string   `json:"subject,omitempty"`
+	Audiences   []string `json:"audiences,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// fakeFederatedCredentialsServer is an in-memory implementation of the
+// federated identity credentials API of an application.
+type fakeFederatedCredentialsServer struct {
+	mu       sync.Mutex
+	fics     map[string]fakeFederatedCredential
+	mutating int
+}
+
+func newFakeFederatedCredentialsServer(fics ...fakeFederatedCredential) *fakeFederatedCredentialsServer {
+	s := &fakeFederatedCredentialsServer{fics: make(map[string]fakeFederatedCredential)}
+	for _, fic := range fics {
+		s.fics[fic.ID] = fic
+	}
+	return s
+}
+
+func (s *fakeFederatedCredentialsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, testFederatedCredentialsPath), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		value := make([]fakeFederatedCredential, 0, len(s.fics))
+		for _, fic := range s.fics {
+			value = append(value, fic)
+		}
+		// list the federated credentials in a stable order like Graph does
+		sort.Slice(value, func(i, j int) bool { return value[i].ID < value[j].ID })
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+	case r.Method == http.MethodPost && id == "":
+		var fic fakeFederatedCredential
+		_ = json.NewDecoder(r.Body).Decode(&fic)
+		fic.ID = fic.Name + "-id"
+		s.fics[fic.ID] = fic
+		s.mutating++
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(fic)
+	case r.Method == http.MethodPatch:
+		var fic fakeFederatedCredential
+		_ = json.NewDecoder(r.Body).Decode(&fic)
+		fic.ID, fic.Name = id, s.fics[id].Name
+		s.fics[id] = fic
+		s.mutating++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(s.fics, id)
+		s.mutating++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// state returns the federated identity credentials without their IDs sorted by name.
+func (s *fakeFederatedCredentialsServer) state() []fakeFederatedCredential {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := make([]fakeFederatedCredential, 0, len(s.fics))
+	for _, fic := range s.fics {
+		fic.ID = ""
+		state = append(state, fic)
+	}
+	sort.Slice(state, func(i, j int) bool { return state[i].Name < state[j].Name })
+	return state
+}
+
+func TestReconcileFederatedCredentials(t *testing.T) {
+	audiences := []string{"api://AzureADTokenExchange"}
+	defaultDescription := defaultFederatedCredentialDescription("https://issuer.example.com/")
+	existing := []fakeFederatedCredential{
+		{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+		{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+	}
+
+	tests := []struct {
+		name       string
+		desired    []ExpectedFIC
+		dryRun     bool
+		wantResult ReconcileResult
+		wantState  []fakeFederatedCredential
+	}{
+		{
+			name: "no changes",
+			desired: []ExpectedFIC{
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+			},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+		},
+		{
+			name: "no changes with the default description",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences, Description: defaultDescription},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+		},
+		{
+			name: "create",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+				{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-3", Audiences: audiences, Description: "new"},
+			},
+			wantResult: ReconcileResult{Created: []string{"fic-3"}},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+				{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-3", Audiences: audiences, Description: "new"},
+			},
+		},
+		{
+			name: "update",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://new-issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+			wantResult: ReconcileResult{Updated: []string{"fic-2"}},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://new-issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences, Description: defaultFederatedCredentialDescription("https://new-issuer.example.com/")},
+			},
+		},
+		{
+			name: "delete",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+			},
+			wantResult: ReconcileResult{Deleted: []string{"fic-2"}},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+			},
+		},
+		{
+			name: "create, update and delete",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange", "custom"}},
+				{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+			wantResult: ReconcileResult{Created: []string{"fic-3"}, Updated: []string{"fic-1"}, Deleted: []string{"fic-2"}},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange", "custom"}, Description: defaultDescription},
+				{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences, Description: defaultDescription},
+			},
+		},
+		{
+			name: "dry run",
+			desired: []ExpectedFIC{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange", "custom"}},
+				{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+			dryRun:     true,
+			wantResult: ReconcileResult{Created: []string{"fic-3"}, Updated: []string{"fic-1"}, Deleted: []string{"fic-2"}},
+			wantState: []fakeFederatedCredential{
+				{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+				{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeFederatedCredentialsServer(existing...)
+			mux := http.NewServeMux()
+			mux.Handle(testFederatedCredentialsPath, server)
+			mux.Handle(testFederatedCredentialsPath+"/", server)
+			c := newTestAzureClient(t, mux)
+
+			result, err := c.ReconcileFederatedCredentials(context.Background(), "object-id", tt.desired, tt.dryRun)
+			if err != nil {
+				t.Fatalf("ReconcileFederatedCredentials() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.wantResult) {
+				t.Errorf("ReconcileFederatedCredentials() = %+v, want %+v", result, tt.wantResult)
+			}
+			if got := server.state(); !reflect.DeepEqual(got, tt.wantState) {
+				t.Errorf("federated credentials = %+v, want %+v", got, tt.wantState)
+			}
+			if tt.dryRun && server.mutating != 0 {
+				t.Errorf("expected no mutating requests in dry run, got %d", server.mutating)
+			}
+		})
+	}
+}
+
+func TestReconcileFederatedCredentialsDuplicateName(t *testing.T) {
+	c := newTestAzureClient(t, newFakeFederatedCredentialsServer())
+
+	desired := []ExpectedFIC{{Name: "fic-1"}, {Name: "fic-1"}}
+	if _, err := c.ReconcileFederatedCredentials(context.Background(), "object-id", desired, false); err == nil {
+		t.Errorf("ReconcileFederatedCredentials() error = nil, want error")
+	}
+}
+
+func TestReconcileFederatedCredentialsSubjectAndClaimsMatchingExpression(t *testing.T) {
+	c := newTestAzureClient(t, newFakeFederatedCredentialsServer())
+
+	desired := []ExpectedFIC{{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", ClaimsMatchingExpression: testClaimsMatchingExpression}}
+	_, err := c.ReconcileFederatedCredentials(context.Background(), "object-id", desired, false)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid federated credential fic-1: ") {
+		t.Errorf("ReconcileFederatedCredentials() error = %v, want invalid federated credential error", err)
+	}
+}
+
+// appFederatedCredentialsHandler serves the federated identity credentials of the application
+// with the given object ID from the fake server of the application with the object ID object-id.
+func appFederatedCredentialsHandler(objectID string, s *fakeFederatedCredentialsServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.Replace(r.URL.Path, "/applications/"+objectID+"/", "/applications/object-id/", 1)
+		s.ServeHTTP(w, r)
+	}
+}
+
+func TestReconcileAllFederatedCredentials(t *testing.T) {
+	app1 := newFakeFederatedCredentialsServer()
+	app2 := newFakeFederatedCredentialsServer(fakeFederatedCredential{ID: "stale-id", Name: "stale", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:stale", Audiences: []string{"api://AzureADTokenExchange"}})
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1.0/applications/app-1/federatedIdentityCredentials", appFederatedCredentialsHandler("app-1", app1))
+	mux.Handle("/v1.0/applications/app-2/federatedIdentityCredentials", appFederatedCredentialsHandler("app-2", app2))
+	mux.Handle("/v1.0/applications/app-2/federatedIdentityCredentials/", appFederatedCredentialsHandler("app-2", app2))
+	mux.HandleFunc("/v1.0/applications/app-3/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`))
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic := ExpectedFIC{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange"}}
+	desired := map[string][]ExpectedFIC{
+		"app-1": {fic},
+		"app-2": {},
+		"app-3": {fic},
+	}
+
+	results, errs := c.ReconcileAllFederatedCredentials(context.Background(), desired, 2)
+	want := map[string]ReconcileResult{
+		"app-1": {Created: []string{"fic-1"}},
+		"app-2": {Deleted: []string{"stale"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("ReconcileAllFederatedCredentials() results = %+v, want %+v", results, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "failed to reconcile federated credentials of application app-3") {
+		t.Errorf("ReconcileAllFederatedCredentials() errors = %v, want an error for app-3", errs)
+	}
+	if got := app1.state(); len(got) != 1 || got[0].Name != "fic-1" {
+		t.Errorf("expected fic-1 to be created in app-1, got %+v", got)
+	}
+	if got := app2.state(); len(got) != 0 {
+		t.Errorf("expected the stale federated credential to be deleted from app-2, got %+v", got)
+	}
+}
+
+func TestReconcileAllFederatedCredentialsCanceled(t *testing.T) {
+	s := newFakeFederatedCredentialsServer()
+	c := newTestAzureClient(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	desired := map[string][]ExpectedFIC{"app-1": {}, "app-2": {}, "app-3": {}}
+	results, errs := c.ReconcileAllFederatedCredentials(ctx, desired, 0)
+	if len(results) != 0 {
+		t.Errorf("expected no results after the context is canceled, got %+v", results)
+	}
+	if len(errs) != len(desired) {
+		t.Fatalf("expected an error per application, got %v", errs)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled error, got %v", err)
+		}
+	}
+}
+
+func TestListTrustedIssuers(t *testing.T) {
+	server := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer-b.example.com/", Subject: "system:serviceaccount:default:sa-1"},
+		fakeFederatedCredential{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer-a.example.com/", Subject: "system:serviceaccount:default:sa-1"},
+		// the same issuer as fic-1 spelled differently
+		fakeFederatedCredential{ID: "fic-3-id", Name: "fic-3", Issuer: "HTTPS://Issuer-B.example.com", Subject: "system:serviceaccount:default:sa-2"},
+	)
+	mux := http.NewServeMux()
+	mux.Handle(testFederatedCredentialsPath, server)
+	c := newTestAzureClient(t, mux)
+
+	issuers, err := c.ListTrustedIssuers(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("ListTrustedIssuers() error = %v", err)
+	}
+	if want := []string{"https://issuer-a.example.com", "https://issuer-b.example.com"}; !reflect.DeepEqual(issuers, want) {
+		t.Errorf("ListTrustedIssuers() = %v, want %v", issuers, want)
+	}
+}
+
+func TestListFederatedCredentialsByAudience(t *testing.T) {
+	server := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange"}},
+		fakeFederatedCredential{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://custom"}},
+		fakeFederatedCredential{ID: "fic-3-id", Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-3", Audiences: []string{"api://AzureADTokenExchange", "api://custom"}},
+	)
+	mux := http.NewServeMux()
+	mux.Handle(testFederatedCredentialsPath, server)
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		audience string
+		want     []string
+	}{
+		{audience: "api://custom", want: []string{"fic-2", "fic-3"}},
+		{audience: "api://AzureADTokenExchange", want: []string{"fic-1", "fic-3"}},
+		// audiences are compared exactly
+		{audience: "API://CUSTOM", want: []string{}},
+	}
+	for _, test := range tests {
+		fics, err := c.ListFederatedCredentialsByAudience(context.Background(), "object-id", test.audience)
+		if err != nil {
+			t.Fatalf("ListFederatedCredentialsByAudience() error = %v", err)
+		}
+		names := []string{}
+		for _, fic := range fics {
+			names = append(names, to.String(fic.GetName()))
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("ListFederatedCredentialsByAudience(%q) = %v, want %v", test.audience, names, test.want)
+		}
+	}
+}
+
+func TestDetectSubjectCollisions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value": [{"id": "app-1"}, {"id": "app-2"}, {"id": "app-3"}]}`))
+	})
+	ficsHandler := func(fics ...fakeFederatedCredential) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": fics})
+		}
+	}
+	mux.Handle("/v1.0/applications/app-1/federatedIdentityCredentials", ficsHandler(
+		fakeFederatedCredential{Name: "fic-1", Subject: "system:serviceaccount:default:sa-1"},
+		fakeFederatedCredential{Name: "fic-2", Subject: "system:serviceaccount:default:sa-2"},
+	))
+	// app-2 trusts sa-1 twice, e.g. for two issuers, which is reported once
+	mux.Handle("/v1.0/applications/app-2/federatedIdentityCredentials", ficsHandler(
+		fakeFederatedCredential{Name: "fic-1", Subject: "system:serviceaccount:default:sa-1"},
+		fakeFederatedCredential{Name: "fic-3", Subject: "system:serviceaccount:default:sa-1"},
+	))
+	mux.Handle("/v1.0/applications/app-3/federatedIdentityCredentials", ficsHandler(
+		fakeFederatedCredential{Name: "fic-4", Subject: "system:serviceaccount:default:sa-4"},
+	))
+	mux.Handle("/subscriptions/", &fakeManagedIdentityFederatedCredentialsServer{
+		fics: []federatedCredentialResource{
+			{Name: "fic-1", Properties: federatedCredentialProperties{Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://AzureADTokenExchange"}}},
+		},
+	})
+	c := newTestAzureClient(t, mux)
+
+	subjects := []string{"system:serviceaccount:default:sa-1", "system:serviceaccount:default:sa-2", "system:serviceaccount:default:sa-3"}
+	collisions, err := c.DetectSubjectCollisions(context.Background(), subjects)
+	if err != nil {
+		t.Fatalf("DetectSubjectCollisions() error = %v", err)
+	}
+	want := map[string][]string{
+		"system:serviceaccount:default:sa-1": {"app-1", "app-2"},
+		"system:serviceaccount:default:sa-2": {"app-1"},
+	}
+	if !reflect.DeepEqual(collisions, want) {
+		t.Errorf("DetectSubjectCollisions() = %v, want %v", collisions, want)
+	}
+
+	collisions, err = c.DetectSubjectCollisionsWithManagedIdentities(context.Background(), subjects, []string{testUserAssignedIdentityID})
+	if err != nil {
+		t.Fatalf("DetectSubjectCollisionsWithManagedIdentities() error = %v", err)
+	}
+	want["system:serviceaccount:default:sa-2"] = []string{testUserAssignedIdentityID, "app-1"}
+	if !reflect.DeepEqual(collisions, want) {
+		t.Errorf("DetectSubjectCollisionsWithManagedIdentities() = %v, want %v", collisions, want)
+	}
+}
+
+func TestFindStaleFederatedCredentials(t *testing.T) {
+	server := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange"}},
+		fakeFederatedCredential{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://AzureADTokenExchange"}},
+		fakeFederatedCredential{ID: "fic-3-id", Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:kube-system:sa-3", Audiences: []string{"api://AzureADTokenExchange"}},
+		fakeFederatedCredential{ID: "fic-4-id", Name: "fic-4", Issuer: "https://token.actions.githubusercontent.com", Subject: "repo:octo-org/octo-repo:ref:refs/heads/main", Audiences: []string{"api://AzureADTokenExchange"}},
+	)
+	mux := http.NewServeMux()
+	mux.Handle(testFederatedCredentialsPath, server)
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		name           string
+		activeSubjects map[string]bool
+		want           []string
+	}{
+		{
+			name:           "some service accounts deleted",
+			activeSubjects: map[string]bool{"system:serviceaccount:default:sa-1": true},
+			want:           []string{"fic-2", "fic-3"},
+		},
+		{
+			name: "all service accounts exist",
+			activeSubjects: map[string]bool{
+				"system:serviceaccount:default:sa-1":     true,
+				"system:serviceaccount:default:sa-2":     true,
+				"system:serviceaccount:kube-system:sa-3": true,
+			},
+			want: []string{},
+		},
+		{
+			// the federated credentials of other subjects are never stale
+			name: "no service accounts",
+			want: []string{"fic-1", "fic-2", "fic-3"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fics, err := c.FindStaleFederatedCredentials(context.Background(), "object-id", test.activeSubjects)
+			if err != nil {
+				t.Fatalf("FindStaleFederatedCredentials() error = %v", err)
+			}
+			names := []string{}
+			for _, fic := range fics {
+				names = append(names, to.String(fic.GetName()))
+			}
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("FindStaleFederatedCredentials() = %v, want %v", names, test.want)
+			}
+		})
+	}
+}
+
+func TestFindApplicationsBySubject(t *testing.T) {
+	const subject = "system:serviceaccount:default:sa-1"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if filter := r.URL.Query().Get("$filter"); filter != "" {
+			t.Errorf("expected all applications to be listed, got filter %q", filter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value": [{"id": "app-1", "displayName": "app-1"}, {"id": "app-2", "displayName": "app-2"}]}`))
+	})
+	ficsHandler := func(fics string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if got, want := r.URL.Query().Get("$filter"), getSubjectFilter(subject); got != want {
+				t.Errorf("expected filter %q, got %q", want, got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"value": ` + fics + `}`))
+		}
+	}
+	mux.Handle("/v1.0/applications/app-1/federatedIdentityCredentials", ficsHandler(`[]`))
+	mux.Handle("/v1.0/applications/app-2/federatedIdentityCredentials", ficsHandler(`[{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "`+subject+`"}]`))
+	c := newTestAzureClient(t, mux)
+
+	apps, err := c.FindApplicationsBySubject(context.Background(), subject)
+	if err != nil {
+		t.Fatalf("FindApplicationsBySubject() error = %v", err)
+	}
+	if len(apps) != 1 || *apps[0].GetId() != "app-2" {
+		t.Errorf("expected only app-2 to trust the subject, got %d applications", len(apps))
+	}
+}
+
+func TestDetectMissingFederatedCredentials(t *testing.T) {
+	mux := http.NewServeMux()
+	var appLookups int32
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&appLookups, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$filter") {
+		case getAppIDFilter("client-id-1"):
+			fmt.Fprint(w, `{"value": [{"id": "app-1", "appId": "client-id-1"}]}`)
+		case getAppIDFilter("client-id-2"):
+			fmt.Fprint(w, `{"value": [{"id": "app-2", "appId": "client-id-2"}]}`)
+		default:
+			fmt.Fprint(w, `{"value": []}`)
+		}
+	})
+	mux.HandleFunc("/v1.0/applications/app-1/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": []fakeFederatedCredential{
+			{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1"},
+		}})
+	})
+	// app-2 has no federated identity credentials
+	mux.HandleFunc("/v1.0/applications/app-2/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	references := []SARef{
+		{Namespace: "default", Name: "sa-1", ClientID: "client-id-1"},
+		{Namespace: "default", Name: "sa-1", ClientID: "client-id-1", Issuer: "https://issuer.example.com"},
+		{Namespace: "default", Name: "sa-1", ClientID: "client-id-1", Issuer: "https://other-issuer.example.com/"},
+		{Namespace: "default", Name: "sa-2", ClientID: "client-id-1"},
+		{Namespace: "default", Name: "sa-3", ClientID: "client-id-2"},
+		// the client ID of a managed identity is not checked
+		{Namespace: "default", Name: "sa-4", ClientID: "managed-identity-client-id"},
+	}
+	missing, err := c.DetectMissingFederatedCredentials(context.Background(), references)
+	if err != nil {
+		t.Fatalf("DetectMissingFederatedCredentials() error = %v", err)
+	}
+	want := []SARef{references[2], references[3], references[4]}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("DetectMissingFederatedCredentials() = %+v, want %+v", missing, want)
+	}
+	if got := atomic.LoadInt32(&appLookups); got != 3 {
+		t.Errorf("expected 3 application lookups, got %d", got)
+	}
+}
+
+func TestRewriteFederatedCredentialAudiences(t *testing.T) {
+	server := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange"}, Description: "sa-1"},
+		fakeFederatedCredential{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://custom"}},
+		fakeFederatedCredential{ID: "fic-3-id", Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-3", Audiences: []string{"api://AzureADTokenExchange"}},
+	)
+	mux := http.NewServeMux()
+	mux.Handle(testFederatedCredentialsPath, server)
+	mux.Handle(testFederatedCredentialsPath+"/", server)
+	c := newTestAzureClient(t, mux)
+
+	rewritten, err := c.RewriteFederatedCredentialAudiences(context.Background(), "object-id", "api://AzureADTokenExchange", "api://AzureADTokenExchangeUSGov")
+	if err != nil {
+		t.Fatalf("RewriteFederatedCredentialAudiences() error = %v", err)
+	}
+	if rewritten != 2 {
+		t.Errorf("RewriteFederatedCredentialAudiences() = %d, want 2", rewritten)
+	}
+	if server.mutating != 2 {
+		t.Errorf("expected 2 mutating requests, got %d", server.mutating)
+	}
+	want := []fakeFederatedCredential{
+		{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchangeUSGov"}, Description: "sa-1"},
+		{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://custom"}},
+		{Name: "fic-3", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-3", Audiences: []string{"api://AzureADTokenExchangeUSGov"}},
+	}
+	if got := server.state(); !reflect.DeepEqual(got, want) {
+		t.Errorf("federated credentials = %+v, want %+v", got, want)
+	}
+
+	// the rewrite is idempotent
+	if rewritten, err = c.RewriteFederatedCredentialAudiences(context.Background(), "object-id", "api://AzureADTokenExchange", "api://AzureADTokenExchangeUSGov"); err != nil || rewritten != 0 {
+		t.Errorf("RewriteFederatedCredentialAudiences() = %d, %v, want 0, nil", rewritten, err)
+	}
+}
+
+func TestRewriteAllFederatedCredentialAudiences(t *testing.T) {
+	app1 := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://AzureADTokenExchange"}},
+	)
+	app2 := newFakeFederatedCredentialsServer(
+		fakeFederatedCredential{ID: "fic-1-id", Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: []string{"api://custom"}},
+		fakeFederatedCredential{ID: "fic-2-id", Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: []string{"api://AzureADTokenExchange"}},
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "app-1"}, {"id": "app-2"}]}`)
+	})
+	for objectID, server := range map[string]*fakeFederatedCredentialsServer{"app-1": app1, "app-2": app2} {
+		mux.Handle("/v1.0/applications/"+objectID+"/federatedIdentityCredentials", appFederatedCredentialsHandler(objectID, server))
+		mux.Handle("/v1.0/applications/"+objectID+"/federatedIdentityCredentials/", appFederatedCredentialsHandler(objectID, server))
+	}
+	c := newTestAzureClient(t, mux)
+
+	rewritten, err := c.RewriteAllFederatedCredentialAudiences(context.Background(), "api://AzureADTokenExchange", "api://AzureADTokenExchangeChina")
+	if err != nil {
+		t.Fatalf("RewriteAllFederatedCredentialAudiences() error = %v", err)
+	}
+	if rewritten != 2 {
+		t.Errorf("RewriteAllFederatedCredentialAudiences() = %d, want 2", rewritten)
+	}
+	if app1.mutating != 1 || app2.mutating != 1 {
+		t.Errorf("expected 1 mutating request per application, got %d and %d", app1.mutating, app2.mutating)
+	}
+	if got := app2.state()[0].Audiences; !reflect.DeepEqual(got, []string{"api://custom"}) {
+		t.Errorf("expected the audiences of app-2/fic-1 to be left untouched, got %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package cloud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// FICDiff is the plan to converge federated identity credentials to their desired state, in the order
+// ReconcileFederatedCredentials applies it: the deletes, then the updates and the creates.
+type FICDiff struct {
+	Create []ExpectedFIC
+	Update []FICUpdate
+	Delete []ExpectedFIC
+}
+
+// FICUpdate is a federated identity credential whose current state differs from its desired state.
+type FICUpdate struct {
+	Current ExpectedFIC
+	Desired ExpectedFIC
+}
+
+// NewExpectedFICs returns the current state of the federated identity credentials as ExpectedFICs,
+// e.g. to diff them with their desired state with DiffFederatedCredentials.
+func NewExpectedFICs(fics []models.FederatedIdentityCredentialable) []ExpectedFIC {
+	expected := make([]ExpectedFIC, 0, len(fics))
+	for _, fic := range fics {
+		expected = append(expected, ExpectedFIC{
+			Name:                     to.String(fic.GetName()),
+			Issuer:                   to.String(fic.GetIssuer()),
+			Subject:                  to.String(fic.GetSubject()),
+			ClaimsMatchingExpression: GetClaimsMatchingExpression(fic),
+			Audiences:                fic.GetAudiences(),
+			Description:              to.String(fic.GetDescription()),
+		})
+	}
+	return expected
+}
+
+// DiffFederatedCredentials returns the federated identity credentials to create, update and delete to converge
+// the current federated identity credentials to the desired ones, matched by name and sorted by name. They are
+// compared the way ReconcileFederatedCredentials compares them, e.g. the order of the audiences is not significant.
+func DiffFederatedCredentials(current, desired []ExpectedFIC) FICDiff {
+	var diff FICDiff
+
+	currentByName := make(map[string]ExpectedFIC, len(current))
+	for _, fic := range current {
+		currentByName[fic.Name] = fic
+	}
+	desiredByName := make(map[string]ExpectedFIC, len(desired))
+	for _, fic := range desired {
+		desiredByName[fic.Name] = fic
+	}
+
+	for _, fic := range desired {
+		existing, ok := currentByName[fic.Name]
+		if !ok {
+			diff.Create = append(diff.Create, fic)
+			continue
+		}
+		if !fic.matches(existing.toFederatedIdentityCredential()) {
+			diff.Update = append(diff.Update, FICUpdate{Current: existing, Desired: fic})
+		}
+	}
+	for _, fic := range current {
+		if _, ok := desiredByName[fic.Name]; !ok {
+			diff.Delete = append(diff.Delete, fic)
+		}
+	}
+
+	sort.Slice(diff.Create, func(i, j int) bool { return diff.Create[i].Name < diff.Create[j].Name })
+	sort.Slice(diff.Update, func(i, j int) bool { return diff.Update[i].Desired.Name < diff.Update[j].Desired.Name })
+	sort.Slice(diff.Delete, func(i, j int) bool { return diff.Delete[i].Name < diff.Delete[j].Name })
+	return diff
+}
+
+// IsEmpty returns true if the federated identity credentials are already in their desired state.
+func (d FICDiff) IsEmpty() bool {
+	return len(d.Create) == 0 && len(d.Update) == 0 && len(d.Delete) == 0
+}
+
+// String renders the diff as a plan with a line per federated identity credential, prefixed with - when it
+// is deleted, ~ when it is updated and + when it is created, followed by the changed properties.
+func (d FICDiff) String() string {
+	if d.IsEmpty() {
+		return "No changes, the federated identity credentials are up to date.\n"
+	}
+
+	var b strings.Builder
+	for _, fic := range d.Delete {
+		fmt.Fprintf(&b, "- %s\n", fic.Name)
+	}
+	for _, update := range d.Update {
+		fmt.Fprintf(&b, "~ %s\n", update.Desired.Name)
+		current, desired := update.Current.properties(), update.Desired.properties()
+		for i := range desired {
+			if current[i].value != desired[i].value {
+				fmt.Fprintf(&b, "    %s: %q -> %q\n", desired[i].name, current[i].value, desired[i].value)
+			}
+		}
+	}
+	for _, fic := range d.Create {
+		fmt.Fprintf(&b, "+ %s\n", fic.Name)
+		for _, property := range fic.properties() {
+			if property.value != "" {
+				fmt.Fprintf(&b, "    %s: %q\n", property.name, property.value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ficProperty is a property of a federated identity credential rendered in a plan.
+type ficProperty struct {
+	name  string
+	value string
+}
+
+// properties returns the properties of the federated identity credential that are rendered in a plan, in a
+// fixed order. The audiences are sorted and the description is the one the federated identity credential gets.
+func (e ExpectedFIC) properties() []ficProperty {
+	audiences := append([]string(nil), e.Audiences...)
+	sort.Strings(audiences)
+	return []ficProperty{
+		{name: "issuer", value: e.Issuer},
+		{name: "subject", value: e.Subject},
+		{name: "claimsMatchingExpression", value: e.ClaimsMatchingExpression},
+		{name: "audiences", value: strings.Join(audiences, ", ")},
+		{name: "description", value: e.description()},
+	}
+}
@@ -0,0 +1,134 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestDiffFederatedCredentials(t *testing.T) {
+	unchanged := ExpectedFIC{
+		Name:      "unchanged",
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:unchanged",
+		Audiences: []string{"api://AzureADTokenExchange", "api://other"},
+	}
+	reordered := unchanged
+	reordered.Audiences = []string{"api://other", "api://AzureADTokenExchange"}
+	stale := ExpectedFIC{
+		Name:      "stale",
+		Issuer:    "https://old-issuer.example.com/",
+		Subject:   "system:serviceaccount:default:stale",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+	refreshed := stale
+	refreshed.Issuer = "https://issuer.example.com/"
+	orphaned := ExpectedFIC{
+		Name:      "orphaned",
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:orphaned",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+	missing := ExpectedFIC{
+		Name:                     "missing",
+		Issuer:                   "https://issuer.example.com/",
+		ClaimsMatchingExpression: testClaimsMatchingExpression,
+		Audiences:                []string{"api://AzureADTokenExchange"},
+	}
+
+	diff := DiffFederatedCredentials([]ExpectedFIC{unchanged, stale, orphaned}, []ExpectedFIC{missing, refreshed, reordered})
+
+	want := FICDiff{
+		Create: []ExpectedFIC{missing},
+		Update: []FICUpdate{{Current: stale, Desired: refreshed}},
+		Delete: []ExpectedFIC{orphaned},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffFederatedCredentials() = %+v, want %+v", diff, want)
+	}
+}
+
+func TestDiffFederatedCredentialsDescription(t *testing.T) {
+	current := ExpectedFIC{
+		Name:        "fic",
+		Issuer:      "https://issuer.example.com/",
+		Subject:     "system:serviceaccount:default:sa",
+		Audiences:   []string{"api://AzureADTokenExchange"},
+		Description: federatedCredentialDescription("", "https://issuer.example.com/"),
+	}
+	// an empty description stands for the default one
+	desired := current
+	desired.Description = ""
+	if diff := DiffFederatedCredentials([]ExpectedFIC{current}, []ExpectedFIC{desired}); !diff.IsEmpty() {
+		t.Errorf("expected no changes, got %+v", diff)
+	}
+
+	desired.Description = "custom"
+	diff := DiffFederatedCredentials([]ExpectedFIC{current}, []ExpectedFIC{desired})
+	if len(diff.Update) != 1 {
+		t.Errorf("expected an update, got %+v", diff)
+	}
+}
+
+func TestFICDiffString(t *testing.T) {
+	tests := []struct {
+		name string
+		diff FICDiff
+		want string
+	}{
+		{
+			name: "no changes",
+			want: "No changes, the federated identity credentials are up to date.\n",
+		},
+		{
+			name: "changes",
+			diff: FICDiff{
+				Delete: []ExpectedFIC{{Name: "orphaned"}},
+				Update: []FICUpdate{{
+					Current: ExpectedFIC{Name: "stale", Issuer: "https://old-issuer.example.com/", Subject: "system:serviceaccount:default:stale", Audiences: []string{"api://AzureADTokenExchange"}, Description: "description"},
+					Desired: ExpectedFIC{Name: "stale", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:stale", Audiences: []string{"api://AzureADTokenExchange"}, Description: "description"},
+				}},
+				Create: []ExpectedFIC{{Name: "missing", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:missing", Audiences: []string{"api://other", "api://AzureADTokenExchange"}, Description: "description"}},
+			},
+			want: `- orphaned
+~ stale
+    issuer: "https://old-issuer.example.com/" -> "https://issuer.example.com/"
++ missing
+    issuer: "https://issuer.example.com/"
+    subject: "system:serviceaccount:default:missing"
+    audiences: "api://AzureADTokenExchange, api://other"
+    description: "description"
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.diff.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewExpectedFICs(t *testing.T) {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+	fic.SetDescription(to.StringPtr("description"))
+	SetClaimsMatchingExpression(fic, testClaimsMatchingExpression)
+
+	want := []ExpectedFIC{{
+		Name:                     "fic",
+		Issuer:                   "https://issuer.example.com/",
+		ClaimsMatchingExpression: testClaimsMatchingExpression,
+		Audiences:                []string{"api://AzureADTokenExchange"},
+		Description:              "description",
+	}}
+	if got := NewExpectedFICs([]models.FederatedIdentityCredentialable{fic}); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewExpectedFICs() = %+v, want %+v", got, want)
+	}
+}
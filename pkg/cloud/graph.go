@@ -2,32 +2,150 @@ package cloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/uuid"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
 	"github.com/microsoftgraph/msgraph-sdk-go/applications"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
 	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
 	"github.com/pkg/errors"
-	"monis.app/mlog"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// defaultFederatedCredentialPollInterval is the default interval at which WaitForFederatedCredential polls.
+	defaultFederatedCredentialPollInterval = 2 * time.Second
+	// defaultServicePrincipalPollInterval is the default interval at which WaitForServicePrincipal polls.
+	defaultServicePrincipalPollInterval = 2 * time.Second
+	// defaultApplicationDeletionPollInterval is the default interval at which DeleteApplicationAndWait polls.
+	defaultApplicationDeletionPollInterval = 2 * time.Second
+
+	// findOrphanedServicePrincipalsWorkers is the maximum number of applications of service principals that are
+	// looked up in parallel when finding orphaned service principals.
+	findOrphanedServicePrincipalsWorkers = 8
+
+	// minFederatedCredentialNameLength is the minimum length of the name of a federated identity credential accepted by Graph.
+	minFederatedCredentialNameLength = 3
+	// maxFederatedCredentialNameLength is the maximum length of the name of a federated identity credential accepted by Graph.
+	maxFederatedCredentialNameLength = 120
+	// maxFederatedCredentialValueLength is the maximum length of the issuer, subject and audiences of a federated
+	// identity credential accepted by Graph.
+	maxFederatedCredentialValueLength = 600
+	// federatedCredentialAudiencesCount is the number of audiences of a federated identity credential accepted by Graph.
+	// ref: https://learn.microsoft.com/en-us/graph/api/resources/federatedidentitycredential
+	federatedCredentialAudiencesCount = 1
+	// maxFederatedCredentialsPerApplication is the maximum number of federated identity credentials of an application.
+	// ref: https://learn.microsoft.com/en-us/graph/api/resources/federatedidentitycredential
+	maxFederatedCredentialsPerApplication = 20
+	// federatedCredentialNameSymbols are the characters other than alphanumeric characters allowed in the name of
+	// a federated identity credential, which must be URL friendly. They include the characters of base64url encoding.
+	federatedCredentialNameSymbols = "-_.~="
+
+	// federatedCredentialPropagationRetryCount is the number of times adding a federated credential is retried
+	// while the application is not propagated, see isApplicationNotPropagated.
+	federatedCredentialPropagationRetryCount = 4
+	// defaultFederatedCredentialPropagationRetryDelay is the delay before the first retry, doubled for each retry.
+	defaultFederatedCredentialPropagationRetryDelay = time.Second
+
+	// maxPageSize is the maximum number of items per page of the list requests accepted by Graph.
+	maxPageSize = 999
+
+	// maxBatchRequests is the maximum number of requests in a JSON batch request accepted by Graph.
+	maxBatchRequests = 20
+
+	// maxApplicationLogoSize is the maximum size of an application logo accepted by Graph.
+	maxApplicationLogoSize = 100 * 1024
+
+	applicationLogoContentTypePNG  = "image/png"
+	applicationLogoContentTypeJPEG = "image/jpeg"
 )
 
 var (
 	// ErrFederatedCredentialNotFound is returned when the federated credential is not found.
 	ErrFederatedCredentialNotFound = errors.New("federated credential not found")
+	// ErrFederatedCredentialAlreadyExists is returned when a federated credential with the same name, or the same
+	// issuer and subject, already exists.
+	ErrFederatedCredentialAlreadyExists = errors.New("federated credential already exists")
+	// ErrApplicationNotFound is returned when the application is not found.
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrIssuerNotAllowed is returned when the issuer of a federated credential is not one of the allowed issuers.
+	ErrIssuerNotAllowed = errors.New("issuer not allowed")
+	// ErrFederatedCredentialLimitExceeded is returned when an application already has the maximum number of
+	// federated credentials.
+	ErrFederatedCredentialLimitExceeded = errors.New("federated credential limit exceeded")
+
+	// groupMembershipClaimsValues are the valid values of the groupMembershipClaims property of an application.
+	// ref: https://learn.microsoft.com/en-us/azure/active-directory/develop/reference-app-manifest#groupmembershipclaims-attribute
+	groupMembershipClaimsValues = []string{"None", "SecurityGroup", "DirectoryRole", "ApplicationGroup", "All"}
+
+	// signInAudienceValues are the valid values of the signInAudience property of an application.
+	// ref: https://learn.microsoft.com/en-us/azure/active-directory/develop/supported-accounts-validation
+	signInAudienceValues = []string{"AzureADMyOrg", "AzureADMultipleOrgs", "AzureADandPersonalMicrosoftAccount", "PersonalMicrosoftAccount"}
+
+	// appRoleMemberTypeValues are the valid values of the allowedMemberTypes property of an app role.
+	// ref: https://learn.microsoft.com/en-us/graph/api/resources/approle
+	appRoleMemberTypeValues = []string{"User", "Application"}
 )
 
+// ServicePrincipalOptions are the properties of a service principal to create with CreateServicePrincipalWithOptions.
+// The properties that are not set keep the defaults of Graph.
+type ServicePrincipalOptions struct {
+	// Tags are the tags of the service principal.
+	Tags []string
+	// AccountEnabled enables or disables the sign-in of the service principal, e.g. to create it disabled until
+	// its role assignments are in place. Graph enables it by default.
+	AccountEnabled *bool
+	// AppRoleAssignmentRequired requires the users and applications to be assigned an app role of the service
+	// principal to get a token for it. Graph doesn't require it by default.
+	AppRoleAssignmentRequired *bool
+	// PreferredSingleSignOnMode is the single sign-on mode of the service principal, e.g. saml or oidc.
+	PreferredSingleSignOnMode string
+}
+
 // CreateServicePrincipal creates a service principal for the given application.
 // No secret or certificate is generated.
 func (c *AzureClient) CreateServicePrincipal(ctx context.Context, appID string, tags []string) (models.ServicePrincipalable, error) {
+	return c.CreateServicePrincipalWithOptions(ctx, appID, ServicePrincipalOptions{Tags: tags})
+}
+
+// CreateServicePrincipalWithOptions creates a service principal for the given application with the properties of
+// the options. No secret or certificate is generated.
+func (c *AzureClient) CreateServicePrincipalWithOptions(ctx context.Context, appID string, opts ServicePrincipalOptions) (models.ServicePrincipalable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
 	body := models.NewServicePrincipal()
 	body.SetAppId(to.StringPtr(appID))
-	body.SetTags(tags)
+	body.SetTags(opts.Tags)
+	if opts.AccountEnabled != nil {
+		body.SetAccountEnabled(opts.AccountEnabled)
+	}
+	if opts.AppRoleAssignmentRequired != nil {
+		body.SetAppRoleAssignmentRequired(opts.AppRoleAssignmentRequired)
+	}
+	if opts.PreferredSingleSignOnMode != "" {
+		body.SetPreferredSingleSignOnMode(to.StringPtr(opts.PreferredSingleSignOnMode))
+	}
 
-	mlog.Debug("Creating service principal for application", "id", appID)
-	sp, err := c.graphServiceClient.ServicePrincipals().Post(ctx, body, nil)
+	if c.skipInDryRun("creating service principal for application", "id", appID) {
+		body.SetId(dryRunObjectID())
+		return body, nil
+	}
+	c.logDebug("Creating service principal for application", "id", appID)
+	sp, err := c.graphClient.CreateServicePrincipal(ctx, body)
 	if err != nil {
-		return nil, err
+		return nil, withAmbiguousCreateHint(withInsufficientPrivileges(err), "service principal")
 	}
 	graphErr, err := GetGraphError(sp.GetAdditionalData())
 	if err != nil {
@@ -44,11 +162,103 @@ func (c *AzureClient) CreateApplication(ctx context.Context, displayName string)
 	body := models.NewApplication()
 	body.SetDisplayName(to.StringPtr(displayName))
 
-	mlog.Debug("Creating application", "displayName", displayName)
-	app, err := c.graphServiceClient.Applications().Post(ctx, body, nil)
+	return c.createApplication(ctx, body)
+}
+
+// CreateApplicationWithTags creates an application with the given tags, so that the applications created by a
+// tool can be filtered by tag like its service principals, e.g. to clean them up with DeleteApplicationsByTag.
+func (c *AzureClient) CreateApplicationWithTags(ctx context.Context, displayName string, tags []string) (models.Applicationable, error) {
+	body := models.NewApplication()
+	body.SetDisplayName(to.StringPtr(displayName))
+	body.SetTags(tags)
+
+	return c.createApplication(ctx, body)
+}
+
+// CreateApplicationAndGetAppID creates an application and returns its app ID (client ID), e.g. to create its
+// service principal, and its object ID. An error is returned if Graph returns the application without either.
+func (c *AzureClient) CreateApplicationAndGetAppID(ctx context.Context, displayName string) (appID, objectID string, err error) {
+	app, err := c.CreateApplication(ctx, displayName)
 	if err != nil {
+		return "", "", err
+	}
+	appID, objectID = to.String(app.GetAppId()), to.String(app.GetId())
+	if appID == "" || objectID == "" {
+		return "", "", errors.Errorf("application %s was created without an app ID or object ID (app ID: %q, object ID: %q)", displayName, appID, objectID)
+	}
+	return appID, objectID, nil
+}
+
+// GetOrCreateApplication gets the application with the display name, or creates it if it doesn't exist. When
+// another caller creates the application concurrently and Graph rejects the create as a duplicate, or when the
+// create fails in a way that doesn't tell whether the application was created, the application is got again
+// and returned instead of the error.
+func (c *AzureClient) GetOrCreateApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
+	app, err := c.GetApplication(ctx, displayName)
+	if err == nil {
+		return app, nil
+	}
+	if !IsNotFound(err) {
+		return nil, err
+	}
+
+	app, err = c.CreateApplication(ctx, displayName)
+	if err == nil {
+		return app, nil
+	}
+	if !isMultipleObjectsWithSameKeyValue(err) && !IsAmbiguousCreateError(err) {
 		return nil, err
 	}
+
+	c.logDebug("Getting application created concurrently", "displayName", displayName)
+	app, getErr := c.GetApplication(ctx, displayName)
+	if getErr != nil {
+		return nil, errors.Wrapf(err, "failed to get application after create failed: %v", getErr)
+	}
+	return app, nil
+}
+
+// CreateApplicationWithAppID creates an application with the given app ID, e.g. to recreate an application
+// with a known app ID in another tenant. Graph only accepts an app ID chosen by the caller in limited cases,
+// in which case its error is returned with its code and message.
+func (c *AzureClient) CreateApplicationWithAppID(ctx context.Context, displayName, appID string) (models.Applicationable, error) {
+	if _, err := uuid.Parse(appID); err != nil {
+		return nil, errors.Wrapf(err, "invalid app ID %q", appID)
+	}
+
+	body := models.NewApplication()
+	body.SetDisplayName(to.StringPtr(displayName))
+	body.SetAppId(to.StringPtr(appID))
+
+	app, err := c.createApplication(ctx, body)
+	if err != nil {
+		if IsAmbiguousCreateError(err) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(withODataErrorDetails(err), "failed to create application with app ID %s, the tenant may not allow choosing the app ID", appID)
+	}
+	return app, nil
+}
+
+// createApplication creates an application with the properties that are set in body.
+func (c *AzureClient) createApplication(ctx context.Context, body models.Applicationable) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("creating application", "displayName", to.String(body.GetDisplayName())) {
+		body.SetId(dryRunObjectID())
+		if body.GetAppId() == nil {
+			body.SetAppId(dryRunObjectID())
+		}
+		return body, nil
+	}
+
+	c.logDebug("Creating application", "displayName", to.String(body.GetDisplayName()))
+
+	app, err := c.graphClient.CreateApplication(ctx, body)
+	if err != nil {
+		return nil, withAmbiguousCreateHint(withInsufficientPrivileges(err), "application")
+	}
 	graphErr, err := GetGraphError(app.GetAdditionalData())
 	if err != nil {
 		return nil, err
@@ -59,17 +269,206 @@ func (c *AzureClient) CreateApplication(ctx context.Context, displayName string)
 	return app, nil
 }
 
-// GetServicePrincipal gets a service principal by its display name.
+// CloneApplication creates an application with the given display name and the configuration of the source
+// application: the sign-in audience, group membership claims, optional claims, required resource access, app
+// roles and the api, web, spa and public client settings. Instance-specific fields, e.g. the app ID, object ID,
+// credentials and owners, are not copied. Since identifier URIs are unique in the tenant, only an identifier URI
+// of the form api://<appId> is copied, with the app ID of the new application.
+func (c *AzureClient) CloneApplication(ctx context.Context, sourceObjectID, newDisplayName string) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Cloning application", "sourceObjectID", sourceObjectID, "displayName", newDisplayName)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appId", "identifierUris", "signInAudience", "groupMembershipClaims", "optionalClaims",
+				"requiredResourceAccess", "appRoles", "api", "web", "spa", "publicClient"},
+		},
+	}
+	source, err := c.graphClient.GetApplication(ctx, sourceObjectID, appGetOptions)
+	if err != nil {
+		return nil, errors.Wrap(withInsufficientPrivileges(err), "failed to get source application")
+	}
+	graphErr, err := GetGraphError(source.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, withInsufficientPrivileges(*graphErr)
+	}
+
+	body := models.NewApplication()
+	body.SetDisplayName(to.StringPtr(newDisplayName))
+	body.SetSignInAudience(source.GetSignInAudience())
+	body.SetGroupMembershipClaims(source.GetGroupMembershipClaims())
+	body.SetOptionalClaims(source.GetOptionalClaims())
+	body.SetRequiredResourceAccess(source.GetRequiredResourceAccess())
+	body.SetAppRoles(source.GetAppRoles())
+	body.SetApi(source.GetApi())
+	body.SetWeb(source.GetWeb())
+	body.SetSpa(source.GetSpa())
+	body.SetPublicClient(source.GetPublicClient())
+
+	if c.skipInDryRun("creating application", "displayName", newDisplayName, "sourceObjectID", sourceObjectID) {
+		body.SetId(dryRunObjectID())
+		body.SetAppId(dryRunObjectID())
+		return body, nil
+	}
+
+	app, err := c.graphClient.CreateApplication(ctx, body)
+	if err != nil {
+		return nil, errors.Wrap(withAmbiguousCreateHint(withInsufficientPrivileges(err), "application"), "failed to create application")
+	}
+	if graphErr, err = GetGraphError(app.GetAdditionalData()); err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, withInsufficientPrivileges(*graphErr)
+	}
+
+	// the identifier URI can only be derived from the app ID once the application is created
+	sourceAppIDURI := "api://" + to.String(source.GetAppId())
+	for _, uri := range source.GetIdentifierUris() {
+		if !strings.EqualFold(uri, sourceAppIDURI) {
+			continue
+		}
+		identifierURIs := []string{"api://" + to.String(app.GetAppId())}
+		update := models.NewApplication()
+		update.SetIdentifierUris(identifierURIs)
+		if err := c.patchApplication(ctx, to.String(app.GetId()), update); err != nil {
+			return app, errors.Wrap(err, "failed to set identifier URIs of cloned application")
+		}
+		app.SetIdentifierUris(identifierURIs)
+		break
+	}
+	return app, nil
+}
+
+// GetServicePrincipal gets a service principal by its display name. All the pages of service principals with
+// the display name are read, and a MultipleMatchesError is returned if more than one is owned by the tenant. Its
+// app IDs can be used to get the intended one with GetServicePrincipalByAppID.
 func (c *AzureClient) GetServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error) {
-	mlog.Debug("Getting service principal", "displayName", displayName)
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	return c.getServicePrincipal(ctx, displayName, false)
+}
+
+// GetEnabledServicePrincipal is like GetServicePrincipal, but excludes the disabled service principals, i.e. those
+// whose accountEnabled property is false, which can't sign in, e.g. so that a provisioning flow doesn't pick one.
+func (c *AzureClient) GetEnabledServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
 
+	return c.getServicePrincipal(ctx, displayName, true)
+}
+
+// getServicePrincipal gets a service principal by its display name like GetServicePrincipal, excluding the
+// disabled service principals if enabledOnly is true.
+func (c *AzureClient) getServicePrincipal(ctx context.Context, displayName string, enabledOnly bool) (models.ServicePrincipalable, error) {
+	if sp, ok := c.servicePrincipalCache.get(displayNameCacheKey(displayName)); ok && (!enabledOnly || !isServicePrincipalDisabled(sp)) {
+		c.logDebug("Using cached service principal", "displayName", displayName)
+		return sp, nil
+	}
+
+	c.logDebug("Getting service principal", "displayName", displayName, "enabledOnly", enabledOnly)
+
+	filter := getDisplayNameFilter(displayName)
+	if enabledOnly {
+		filter += " and accountEnabled eq true"
+	}
 	spGetOptions := &serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
 		QueryParameters: &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
-			Filter: to.StringPtr(getDisplayNameFilter(displayName)),
+			Filter: to.StringPtr(filter),
+		},
+	}
+
+	resp, err := c.graphClient.ListServicePrincipals(ctx, spGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var sps []models.ServicePrincipalable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return nil, err
+		}
+		if graphErr != nil {
+			return nil, *graphErr
+		}
+		for _, sp := range resp.GetValue() {
+			// the disabled service principals are also excluded from the response in case the filter is ignored
+			if !enabledOnly || !isServicePrincipalDisabled(sp) {
+				sps = append(sps, sp)
+			}
+		}
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			break
+		}
+		if resp, err = serviceprincipals.NewServicePrincipalsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(sps) == 0 {
+		if enabledOnly {
+			return nil, errors.Errorf("enabled service principal %s not found", displayName)
+		}
+		return nil, errors.Errorf("service principal %s not found", displayName)
+	}
+	// display names are not unique, so a third-party application consented to in the tenant
+	// can have a service principal with the same display name as our application
+	var owned []models.ServicePrincipalable
+	for _, sp := range sps {
+		if c.isOwnedByTenant(sp) {
+			owned = append(owned, sp)
+		}
+	}
+	switch len(owned) {
+	case 0:
+		return nil, errors.Errorf("service principal %s not found in tenant %s, found %d service principal(s) of applications owned by other organizations", displayName, c.tenantID, len(sps))
+	case 1:
+		// the enabled service principal is not the only one with the display name if a disabled one was excluded
+		if enabledOnly {
+			c.servicePrincipalCache.add(owned[0])
+		} else {
+			c.servicePrincipalCache.addByDisplayName(owned[0])
+		}
+		return owned[0], nil
+	default:
+		return nil, newMultipleMatchesError("service principal", displayName, owned)
+	}
+}
+
+// isServicePrincipalDisabled returns true if the sign-in of the service principal is disabled.
+func isServicePrincipalDisabled(sp models.ServicePrincipalable) bool {
+	return sp.GetAccountEnabled() != nil && !*sp.GetAccountEnabled()
+}
+
+// GetServicePrincipalByAppID gets a service principal by its app ID (client ID).
+// Unlike GetApplicationByAppID, it also finds the service principals of managed identities,
+// which have no application object. App IDs are globally unique, so the owning organization is not checked.
+func (c *AzureClient) GetServicePrincipalByAppID(ctx context.Context, appID string) (models.ServicePrincipalable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if sp, ok := c.servicePrincipalCache.get(appIDCacheKey(appID)); ok {
+		c.logDebug("Using cached service principal", "appID", appID)
+		return sp, nil
+	}
+
+	c.logDebug("Getting service principal", "appID", appID)
+
+	spGetOptions := &serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getAppIDFilter(appID)),
 		},
 	}
 
-	resp, err := c.graphServiceClient.ServicePrincipals().Get(ctx, spGetOptions)
+	resp, err := c.graphClient.ListServicePrincipals(ctx, spGetOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -81,117 +480,2034 @@ func (c *AzureClient) GetServicePrincipal(ctx context.Context, displayName strin
 		return nil, *graphErr
 	}
 	if len(resp.GetValue()) == 0 {
-		return nil, errors.Errorf("service principal %s not found", displayName)
+		return nil, errors.Errorf("service principal with app ID '%s' not found", appID)
 	}
+	c.servicePrincipalCache.add(resp.GetValue()[0])
 	return resp.GetValue()[0], nil
 }
 
-// GetApplication gets an application by its display name.
-func (c *AzureClient) GetApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
-	mlog.Debug("Getting application", "displayName", displayName)
+// WaitForServicePrincipal waits until the service principal of the app ID can be read back and returns it.
+// Azure AD takes a while to propagate a new application or service principal, so looking up the service principal
+// right after creating it may fail. The service principal is polled every 2 seconds unless
+// WithServicePrincipalPollInterval is passed.
+func (c *AzureClient) WaitForServicePrincipal(ctx context.Context, appID string, timeout time.Duration) (models.ServicePrincipalable, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
-		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
-			Filter: to.StringPtr(getDisplayNameFilter(displayName)),
+	interval := c.servicePrincipalPollInterval
+	if interval <= 0 {
+		interval = defaultServicePrincipalPollInterval
+	}
+
+	c.logDebug("Waiting for service principal", "appID", appID, "timeout", timeout)
+
+	for {
+		sp, err := c.GetServicePrincipalByAppID(ctx, appID)
+		if err == nil {
+			return sp, nil
+		}
+		if ctx.Err() == nil && !IsNotFound(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "failed to wait for service principal with app ID %s", appID)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// GetServicePrincipalType gets the type of a service principal by its object ID,
+// e.g. "Application" or "ManagedIdentity".
+func (c *AzureClient) GetServicePrincipalType(ctx context.Context, objectID string) (string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting service principal type", "objectID", objectID)
+
+	spGetOptions := &serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "servicePrincipalType"},
 		},
 	}
 
-	resp, err := c.graphServiceClient.Applications().Get(ctx, appGetOptions)
+	sp, err := c.graphClient.GetServicePrincipal(ctx, objectID, spGetOptions)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	graphErr, err := GetGraphError(sp.GetAdditionalData())
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if graphErr != nil {
-		return nil, *graphErr
+		return "", *graphErr
 	}
-	if len(resp.GetValue()) == 0 {
-		return nil, errors.Errorf("application with display name '%s' not found", displayName)
+	return to.String(sp.GetServicePrincipalType()), nil
+}
+
+// GetServicePrincipalSignInActivity gets the time of the last sign-in of the service principal, e.g. to report
+// the workload identities that are not used anymore. It returns nil if the service principal never signed in.
+// The sign-in activity is read from the servicePrincipalSignInActivities report, which is only available on the
+// beta endpoint and requires the AuditLog.Read.All permission; an InsufficientPrivilegesError is returned without it.
+// ref: https://learn.microsoft.com/en-us/graph/api/reportroot-list-serviceprincipalsigninactivities
+func (c *AzureClient) GetServicePrincipalSignInActivity(ctx context.Context, objectID string) (*time.Time, error) {
+	appID, err := c.getServicePrincipalAppID(ctx, objectID)
+	if err != nil {
+		return nil, err
 	}
-	return resp.GetValue()[0], nil
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting service principal sign-in activity", "objectID", objectID, "appID", appID)
+
+	u, err := url.Parse(getBetaBaseURL(c.graphClient.GetAdapter().GetBaseUrl()) + "/reports/servicePrincipalSignInActivities")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse sign-in activity URL")
+	}
+	u.RawQuery = url.Values{"$filter": []string{getAppIDFilter(appID)}}.Encode()
+	requestInfo := abstractions.NewRequestInformation()
+	requestInfo.Method = abstractions.GET
+	requestInfo.SetUri(*u)
+	requestInfo.Headers.Add("Accept", "application/json")
+
+	errorMapping := abstractions.ErrorMappings{
+		"4XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+		"5XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+	}
+	res, err := c.graphClient.GetAdapter().SendPrimitive(ctx, requestInfo, "[]byte", errorMapping)
+	if err != nil {
+		return nil, withRequiredPermission(err, signInActivityRequiredPermission)
+	}
+	raw, _ := res.([]byte)
+	var resp struct {
+		Value []struct {
+			LastSignInActivity *struct {
+				LastSignInDateTime *time.Time `json:"lastSignInDateTime"`
+			} `json:"lastSignInActivity"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal sign-in activity")
+	}
+	// the report has no entry for a service principal that never signed in
+	if len(resp.Value) == 0 || resp.Value[0].LastSignInActivity == nil {
+		return nil, nil
+	}
+	return resp.Value[0].LastSignInActivity.LastSignInDateTime, nil
 }
 
-// DeleteServicePrincipal deletes a service principal.
-func (c *AzureClient) DeleteServicePrincipal(ctx context.Context, objectID string) error {
-	mlog.Debug("Deleting service principal", "objectID", objectID)
-	return c.graphServiceClient.ServicePrincipalsById(objectID).Delete(ctx, nil)
+// getBetaBaseURL returns the base URL of the beta endpoint of Graph from the base URL of the v1.0 endpoint.
+func getBetaBaseURL(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1.0") + "/beta"
 }
 
-// DeleteApplication deletes an application.
-func (c *AzureClient) DeleteApplication(ctx context.Context, objectID string) error {
-	mlog.Debug("Deleting application", "objectID", objectID)
-	return c.graphServiceClient.ApplicationsById(objectID).Delete(ctx, nil)
+// GetApplicationForServicePrincipal gets the application of a service principal by the object ID of the service
+// principal. The service principals of managed identities and of the applications of other tenants have no
+// application in the tenant, for which a not found error is returned.
+func (c *AzureClient) GetApplicationForServicePrincipal(ctx context.Context, spObjectID string) (models.Applicationable, error) {
+	appID, err := c.getServicePrincipalAppID(ctx, spObjectID)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetApplicationByAppID(ctx, appID)
 }
 
-// AddFederatedCredential adds a federated credential to the cloud provider.
-func (c *AzureClient) AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) error {
-	mlog.Debug("Adding federated credential", "objectID", objectID)
+// GetServicePrincipalForApplication gets the service principal of an application by the object ID of the
+// application. A not found error is returned if no service principal was created for the application.
+func (c *AzureClient) GetServicePrincipalForApplication(ctx context.Context, appObjectID string) (models.ServicePrincipalable, error) {
+	appID, err := c.getApplicationAppID(ctx, appObjectID)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetServicePrincipalByAppID(ctx, appID)
+}
+
+// getServicePrincipalAppID gets the app ID of a service principal by its object ID.
+func (c *AzureClient) getServicePrincipalAppID(ctx context.Context, objectID string) (string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting service principal app ID", "objectID", objectID)
+
+	spGetOptions := &serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appId"},
+		},
+	}
 
-	fic, err := c.graphServiceClient.ApplicationsById(objectID).FederatedIdentityCredentials().Post(ctx, fic, nil)
+	sp, err := c.graphClient.GetServicePrincipal(ctx, objectID, spGetOptions)
 	if err != nil {
-		return err
+		if isGraphResourceNotFound(err) {
+			return "", errors.Errorf("service principal with object ID '%s' not found", objectID)
+		}
+		return "", err
 	}
-	graphErr, err := GetGraphError(fic.GetAdditionalData())
+	graphErr, err := GetGraphError(sp.GetAdditionalData())
 	if err != nil {
-		return err
+		return "", err
 	}
 	if graphErr != nil {
-		return *graphErr
+		return "", *graphErr
 	}
-	return nil
+	return to.String(sp.GetAppId()), nil
 }
 
-// GetFederatedCredential gets a federated credential from the cloud provider.
-func (c *AzureClient) GetFederatedCredential(ctx context.Context, objectID, issuer, subject string) (models.FederatedIdentityCredentialable, error) {
-	mlog.Debug("Getting federated credential",
-		"objectID", objectID,
-		"issuer", issuer,
-		"subject", subject,
-	)
+// getApplicationAppID gets the app ID of an application by its object ID.
+func (c *AzureClient) getApplicationAppID(ctx context.Context, objectID string) (string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
 
-	ficGetOptions := &applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration{
-		QueryParameters: &applications.ItemFederatedIdentityCredentialsRequestBuilderGetQueryParameters{
-			// Filtering on more than one resource is currently not supported.
-			Filter: to.StringPtr(getSubjectFilter(subject)),
+	c.logDebug("Getting application app ID", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appId"},
 		},
 	}
 
-	resp, err := c.graphServiceClient.ApplicationsById(objectID).FederatedIdentityCredentials().Get(ctx, ficGetOptions)
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
 	if err != nil {
-		return nil, err
+		if isGraphResourceNotFound(err) {
+			return "", errors.Errorf("application with object ID '%s' not found", objectID)
+		}
+		return "", err
 	}
-	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	graphErr, err := GetGraphError(app.GetAdditionalData())
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if graphErr != nil {
-		return nil, *graphErr
+		return "", *graphErr
 	}
-	for _, fic := range resp.GetValue() {
-		if *fic.GetIssuer() == issuer {
-			return fic, nil
+	return to.String(app.GetAppId()), nil
+}
+
+// isOwnedByTenant returns true if the application of the service principal is owned by the tenant of the client.
+// Service principals without an owning organization and clients without a tenant ID are not checked.
+func (c *AzureClient) isOwnedByTenant(sp models.ServicePrincipalable) bool {
+	if c.allowForeignServicePrincipals || c.tenantID == "" || sp.GetAppOwnerOrganizationId() == nil {
+		return true
+	}
+	return strings.EqualFold(sp.GetAppOwnerOrganizationId().String(), c.tenantID)
+}
+
+// ListServicePrincipalsByTag lists the service principals that have the given tag.
+// Filtering on tags is an advanced query, which requires the ConsistencyLevel header and $count.
+func (c *AzureClient) ListServicePrincipalsByTag(ctx context.Context, tag string) ([]models.ServicePrincipalable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing service principals", "tag", tag)
+
+	headers := newAdvancedQueryHeaders()
+	spGetOptions := &serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+		Headers: headers,
+		QueryParameters: &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getTagFilter(tag)),
+			Count:  to.BoolPtr(true),
+			Top:    c.top(),
+		},
+	}
+
+	resp, err := c.graphClient.ListServicePrincipals(ctx, spGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var sps []models.ServicePrincipalable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return partialResults(c, sps), err
+		}
+		if graphErr != nil {
+			return partialResults(c, sps), *graphErr
+		}
+		sps = append(sps, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return sps, nil
+		}
+		// follow the next link to get the next page of service principals, the next
+		// link contains the query parameters but the header has to be sent again
+		nextOptions := &serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{Headers: headers}
+		if resp, err = serviceprincipals.NewServicePrincipalsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nextOptions); err != nil {
+			return partialResults(c, sps), err
 		}
 	}
-	return nil, ErrFederatedCredentialNotFound
 }
 
-// DeleteFederatedCredential deletes a federated credential from the cloud provider.
-func (c *AzureClient) DeleteFederatedCredential(ctx context.Context, objectID, federatedCredentialID string) error {
-	mlog.Debug("Deleting federated credential",
-		"objectID", objectID,
-		"federatedCredentialID", federatedCredentialID,
+// FindOrphanedServicePrincipals finds the service principals with the given tag whose application doesn't exist
+// anymore, e.g. to clean up the service principals left behind when their application was deleted.
+// The service principals are returned in the order they are listed.
+func (c *AzureClient) FindOrphanedServicePrincipals(ctx context.Context, tag string) ([]models.ServicePrincipalable, error) {
+	c.logDebug("Finding orphaned service principals", "tag", tag)
+
+	sps, err := c.ListServicePrincipalsByTag(ctx, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service principals")
+	}
+
+	var (
+		wg       sync.WaitGroup
+		orphaned = make([]bool, len(sps))
+		errs     = make([]error, len(sps))
 	)
-	return c.graphServiceClient.ApplicationsById(objectID).FederatedIdentityCredentialsById(federatedCredentialID).Delete(ctx, nil)
+	indexCh := make(chan int)
+	for i := 0; i < findOrphanedServicePrincipalsWorkers && i < len(sps); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				appID := to.String(sps[i].GetAppId())
+				if _, err := c.GetApplicationByAppID(ctx, appID); err != nil {
+					if IsNotFound(err) {
+						orphaned[i] = true
+						continue
+					}
+					errs[i] = errors.Wrapf(err, "failed to get application %s", appID)
+				}
+			}
+		}()
+	}
+	for i := range sps {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	var found []models.ServicePrincipalable
+	for i, sp := range sps {
+		if orphaned[i] {
+			found = append(found, sp)
+		}
+	}
+	return found, nil
 }
 
-// getDisplayNameFilter returns a filter string for the given display name.
-func getDisplayNameFilter(displayName string) string {
-	return fmt.Sprintf("displayName eq '%s'", displayName)
+// GetApplication gets an application by its display name. All the pages of applications with the display name
+// are read, and a MultipleMatchesError is returned if there is more than one.
+func (c *AzureClient) GetApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if app, ok := c.applicationCache.get(displayNameCacheKey(displayName)); ok {
+		c.logDebug("Using cached application", "displayName", displayName)
+		return app, nil
+	}
+
+	c.logDebug("Getting application", "displayName", displayName)
+
+	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getDisplayNameFilter(displayName)),
+		},
+	}
+
+	resp, err := c.graphClient.ListApplications(ctx, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []models.Applicationable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return nil, err
+		}
+		if graphErr != nil {
+			return nil, *graphErr
+		}
+		apps = append(apps, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			break
+		}
+		if resp, err = applications.NewApplicationsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return nil, err
+		}
+	}
+	switch len(apps) {
+	case 0:
+		return nil, errors.Errorf("application with display name '%s' not found", displayName)
+	case 1:
+		c.applicationCache.addByDisplayName(apps[0])
+		return apps[0], nil
+	default:
+		return nil, newMultipleMatchesError("application", displayName, apps)
+	}
+}
+
+// newMultipleMatchesError returns the error of the lookup of the given kind of objects by display name
+// that matched all the objects.
+func newMultipleMatchesError[T interface {
+	GetId() *string
+	GetAppId() *string
+}](kind, displayName string, objects []T) error {
+	objectIDs := make([]string, 0, len(objects))
+	appIDs := make([]string, 0, len(objects))
+	for _, object := range objects {
+		objectIDs = append(objectIDs, to.String(object.GetId()))
+		appIDs = append(appIDs, to.String(object.GetAppId()))
+	}
+	return &MultipleMatchesError{Kind: kind, DisplayName: displayName, ObjectIDs: objectIDs, AppIDs: appIDs}
+}
+
+// GetApplicationByAppID gets an application by its app ID (client ID).
+func (c *AzureClient) GetApplicationByAppID(ctx context.Context, appID string) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if app, ok := c.applicationCache.get(appIDCacheKey(appID)); ok {
+		c.logDebug("Using cached application", "appID", appID)
+		return app, nil
+	}
+
+	c.logDebug("Getting application", "appID", appID)
+
+	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getAppIDFilter(appID)),
+		},
+	}
+
+	resp, err := c.graphClient.ListApplications(ctx, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	if len(resp.GetValue()) == 0 {
+		return nil, errors.Errorf("application with app ID '%s' not found", appID)
+	}
+	c.applicationCache.add(resp.GetValue()[0])
+	return resp.GetValue()[0], nil
+}
+
+// GetApplicationByIdentifierURI gets an application by one of its identifier URIs, e.g. api://<appId>.
+// It returns ErrApplicationNotFound if no application has the identifier URI.
+func (c *AzureClient) GetApplicationByIdentifierURI(ctx context.Context, uri string) (models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting application", "identifierURI", uri)
+
+	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getIdentifierURIFilter(uri)),
+		},
+	}
+
+	resp, err := c.graphClient.ListApplications(ctx, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	if len(resp.GetValue()) == 0 {
+		return nil, ErrApplicationNotFound
+	}
+	c.applicationCache.add(resp.GetValue()[0])
+	return resp.GetValue()[0], nil
+}
+
+// GetApplicationCreatedTime gets the time the application was created, e.g. to find stale applications.
+// Service principals and federated identity credentials have no creation time in Graph v1.0.
+func (c *AzureClient) GetApplicationCreatedTime(ctx context.Context, objectID string) (time.Time, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting application created time", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "createdDateTime"},
+		},
+	}
+
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return time.Time{}, err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if graphErr != nil {
+		return time.Time{}, *graphErr
+	}
+	if app.GetCreatedDateTime() == nil {
+		return time.Time{}, errors.Errorf("application %s has no created time", objectID)
+	}
+	return *app.GetCreatedDateTime(), nil
+}
+
+// ListApplicationsCreatedBefore lists the applications with the given tag that were created strictly before the
+// cutoff, e.g. to clean up the stale applications of tests. If the tag is empty, all the applications in the tenant
+// are listed. The creation time is filtered client-side since filtering on it in Graph requires an advanced query.
+// The applications without a creation time are not returned.
+func (c *AzureClient) ListApplicationsCreatedBefore(ctx context.Context, cutoff time.Time, tag string) ([]models.Applicationable, error) {
+	c.logDebug("Listing applications created before cutoff", "cutoff", cutoff, "tag", tag)
+
+	apps, err := c.listApplicationsByTag(ctx, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list applications")
+	}
+
+	created := []models.Applicationable{}
+	for _, app := range apps {
+		if t := app.GetCreatedDateTime(); t != nil && t.Before(cutoff) {
+			created = append(created, app)
+		}
+	}
+	return created, nil
+}
+
+// DeleteServicePrincipal deletes a service principal.
+func (c *AzureClient) DeleteServicePrincipal(ctx context.Context, objectID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("deleting service principal", "objectID", objectID) {
+		return nil
+	}
+	c.logDebug("Deleting service principal", "objectID", objectID)
+	c.servicePrincipalCache.evict(objectID)
+	return c.graphClient.DeleteServicePrincipal(ctx, objectID)
+}
+
+// DeleteApplication deletes an application.
+func (c *AzureClient) DeleteApplication(ctx context.Context, objectID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("deleting application", "objectID", objectID) {
+		return nil
+	}
+	c.logDebug("Deleting application", "objectID", objectID)
+	c.applicationCache.evict(objectID)
+	return c.graphClient.DeleteApplication(ctx, objectID)
+}
+
+// DeleteApplicationAndWait deletes the application and waits until it can no longer be read back, e.g. before
+// creating an application with the same display name or identifier URIs, as a deleted application lingers for a
+// while. An application that was already deleted is not an error. An error is returned if the application still
+// exists after the timeout.
+func (c *AzureClient) DeleteApplicationAndWait(ctx context.Context, objectID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := c.DeleteApplication(ctx, objectID); err != nil && !isGraphResourceNotFound(err) {
+		return errors.Wrapf(err, "failed to delete application %s", objectID)
+	}
+	// the application is not deleted in dry-run mode, so there is nothing to wait for
+	if c.dryRun {
+		return nil
+	}
+
+	interval := c.applicationDeletionPollInterval
+	if interval <= 0 {
+		interval = defaultApplicationDeletionPollInterval
+	}
+
+	c.logDebug("Waiting for application to be deleted", "objectID", objectID, "timeout", timeout)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id"},
+		},
+	}
+	for {
+		_, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+		if isGraphResourceNotFound(err) {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return errors.Wrapf(err, "failed to get application %s", objectID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "application %s still exists after %s", objectID, timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// DeleteApplicationByDisplayName deletes the application with the given display name. An application that
+// doesn't exist, e.g. because it was already deleted, is not an error, so that a cleanup can be run again.
+// No application is deleted if more than one has the display name.
+func (c *AzureClient) DeleteApplicationByDisplayName(ctx context.Context, displayName string) error {
+	app, err := c.GetApplication(ctx, displayName)
+	if err != nil {
+		if errors.Is(err, ErrMultipleMatches) || !IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get application %s", displayName)
+		}
+		c.logDebug("Application has already been deleted", "displayName", displayName)
+		return nil
+	}
+
+	if err := c.DeleteApplication(ctx, to.String(app.GetId())); err != nil {
+		if isGraphResourceNotFound(err) {
+			c.logDebug("Application has already been deleted", "displayName", displayName)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete application %s", displayName)
+	}
+	return nil
+}
+
+// SetApplicationTokenClaims sets the groupMembershipClaims and optionalClaims properties of the application
+// to customize the claims of the tokens issued to it. An empty groupMembershipClaims or nil optional claims
+// leaves the corresponding property unchanged.
+func (c *AzureClient) SetApplicationTokenClaims(ctx context.Context, objectID string, groupMembershipClaims string, optional models.OptionalClaimsable) error {
+	if groupMembershipClaims != "" && !isValidGroupMembershipClaims(groupMembershipClaims) {
+		return errors.Errorf("invalid group membership claims %q, must be one of: %s", groupMembershipClaims, strings.Join(groupMembershipClaimsValues, ", "))
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Setting application token claims", "objectID", objectID, "groupMembershipClaims", groupMembershipClaims)
+
+	app := models.NewApplication()
+	if groupMembershipClaims != "" {
+		app.SetGroupMembershipClaims(to.StringPtr(groupMembershipClaims))
+	}
+	if optional != nil {
+		app.SetOptionalClaims(optional)
+	}
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// GetApplicationSignInAudience gets the signInAudience property of the application, i.e. the
+// Microsoft accounts that are supported by the application, e.g. AzureADMyOrg for a single-tenant application.
+func (c *AzureClient) GetApplicationSignInAudience(ctx context.Context, objectID string) (string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting application sign-in audience", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "signInAudience"},
+		},
+	}
+
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return "", err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return "", err
+	}
+	if graphErr != nil {
+		return "", *graphErr
+	}
+	return to.String(app.GetSignInAudience()), nil
+}
+
+// SetApplicationSignInAudience sets the signInAudience property of the application, e.g. to AzureADMultipleOrgs
+// to make a single-tenant application multi-tenant. Graph may reject the change, e.g. if the application has
+// no verified publisher or its configuration is not supported by the audience, so the code and message of the
+// Graph error are included in the returned error.
+func (c *AzureClient) SetApplicationSignInAudience(ctx context.Context, objectID, audience string) error {
+	if !isValidSignInAudience(audience) {
+		return errors.Errorf("invalid sign-in audience %q, must be one of: %s", audience, strings.Join(signInAudienceValues, ", "))
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Setting application sign-in audience", "objectID", objectID, "signInAudience", audience)
+
+	app := models.NewApplication()
+	app.SetSignInAudience(to.StringPtr(audience))
+
+	if err := c.patchApplication(ctx, objectID, app); err != nil {
+		return errors.Wrapf(withODataErrorDetails(err), "failed to set sign-in audience of application %s to %s", objectID, audience)
+	}
+	return nil
+}
+
+// UpdateApplicationDisplayName renames the application. The other properties of the application are left unchanged.
+func (c *AzureClient) UpdateApplicationDisplayName(ctx context.Context, objectID, displayName string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Updating application display name", "objectID", objectID, "displayName", displayName)
+
+	app := models.NewApplication()
+	app.SetDisplayName(to.StringPtr(displayName))
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// SetApplicationRequiredResourceAccess sets the requiredResourceAccess collection of the application,
+// which is the programmatic equivalent of adding API permissions to the application in the portal.
+// The collection replaces the existing API permissions of the application.
+func (c *AzureClient) SetApplicationRequiredResourceAccess(ctx context.Context, objectID string, access []models.RequiredResourceAccessable) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Setting application required resource access", "objectID", objectID, "resources", len(access))
+
+	app := models.NewApplication()
+	// an empty collection, rather than a nil one, removes all the API permissions
+	if access == nil {
+		access = []models.RequiredResourceAccessable{}
+	}
+	app.SetRequiredResourceAccess(access)
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// SetApplicationImplicitGrantSettings sets whether the authorization endpoint of the application issues ID tokens
+// and access tokens with the implicit grant flow, which single-page applications that don't use the authorization
+// code flow require. The other web settings of the application are left unchanged.
+func (c *AzureClient) SetApplicationImplicitGrantSettings(ctx context.Context, objectID string, idToken, accessToken bool) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Setting application implicit grant settings", "objectID", objectID, "idToken", idToken, "accessToken", accessToken)
+
+	settings := models.NewImplicitGrantSettings()
+	settings.SetEnableIdTokenIssuance(to.BoolPtr(idToken))
+	settings.SetEnableAccessTokenIssuance(to.BoolPtr(accessToken))
+	web := models.NewWebApplication()
+	web.SetImplicitGrantSettings(settings)
+	app := models.NewApplication()
+	app.SetWeb(web)
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// SetApplicationLogo sets the main logo of the application, which is shown on the tile of the
+// enterprise application in the portal. The logo must be a PNG or JPEG image of at most 100 KiB.
+func (c *AzureClient) SetApplicationLogo(ctx context.Context, objectID string, logo []byte, contentType string) error {
+	if err := validateApplicationLogo(logo, contentType); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("setting application logo", "objectID", objectID, "contentType", contentType, "size", len(logo)) {
+		return nil
+	}
+	c.logDebug("Setting application logo", "objectID", objectID, "contentType", contentType, "size", len(logo))
+
+	requestInfo, err := c.graphServiceClient.ApplicationsById(objectID).Logo().ToPutRequestInformation(ctx, logo, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	// the stream content is sent as application/octet-stream by default, which Graph rejects for a logo
+	requestInfo.Headers.Remove("Content-Type")
+	requestInfo.Headers.Add("Content-Type", contentType)
+
+	errorMapping := abstractions.ErrorMappings{
+		"4XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+		"5XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+	}
+	return c.graphClient.GetAdapter().SendNoContent(ctx, requestInfo, errorMapping)
+}
+
+// validateApplicationLogo returns an error if the logo is not a PNG or JPEG image of the given
+// content type within the size limit of Graph.
+func validateApplicationLogo(logo []byte, contentType string) error {
+	if len(logo) == 0 {
+		return errors.New("application logo is empty")
+	}
+	if len(logo) > maxApplicationLogoSize {
+		return errors.Errorf("application logo is %d bytes, which exceeds the limit of %d bytes", len(logo), maxApplicationLogoSize)
+	}
+	if contentType != applicationLogoContentTypePNG && contentType != applicationLogoContentTypeJPEG {
+		return errors.Errorf("invalid application logo content type %q, must be one of: %s, %s", contentType, applicationLogoContentTypePNG, applicationLogoContentTypeJPEG)
+	}
+	if detected := http.DetectContentType(logo); detected != contentType {
+		return errors.Errorf("application logo content type %q does not match the detected content type %q", contentType, detected)
+	}
+	return nil
+}
+
+// SetPreAuthorizedApplications sets the client applications that are pre-authorized to access the
+// delegated permissions exposed by the API of the application, so users are not asked for consent.
+// The collection replaces the existing pre-authorized applications; the other API properties are unchanged.
+func (c *AzureClient) SetPreAuthorizedApplications(ctx context.Context, objectID string, preAuth []models.PreAuthorizedApplicationable) error {
+	for _, app := range preAuth {
+		if _, err := uuid.Parse(to.String(app.GetAppId())); err != nil {
+			return errors.Errorf("invalid pre-authorized application ID %q, must be a GUID", to.String(app.GetAppId()))
+		}
+		for _, id := range app.GetDelegatedPermissionIds() {
+			if _, err := uuid.Parse(id); err != nil {
+				return errors.Errorf("invalid delegated permission ID %q of pre-authorized application %s, must be a GUID", id, to.String(app.GetAppId()))
+			}
+		}
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Setting pre-authorized applications", "objectID", objectID, "applications", len(preAuth))
+
+	// an empty collection, rather than a nil one, removes all the pre-authorized applications
+	if preAuth == nil {
+		preAuth = []models.PreAuthorizedApplicationable{}
+	}
+	api := models.NewApiApplication()
+	api.SetPreAuthorizedApplications(preAuth)
+	app := models.NewApplication()
+	app.SetApi(api)
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// GetApplicationOAuth2Scopes gets the delegated permissions exposed by the API of the application.
+func (c *AzureClient) GetApplicationOAuth2Scopes(ctx context.Context, objectID string) ([]models.PermissionScopeable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting application OAuth2 permission scopes", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "api"},
+		},
+	}
+
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	if app.GetApi() == nil {
+		return nil, nil
+	}
+	return app.GetApi().GetOauth2PermissionScopes(), nil
+}
+
+// AddApplicationOAuth2Scope adds a delegated permission to the API of the application. The scope is
+// given a generated ID if it has none, and it is an error to add a scope whose value is already exposed.
+func (c *AzureClient) AddApplicationOAuth2Scope(ctx context.Context, objectID string, scope models.PermissionScopeable) error {
+	if to.String(scope.GetValue()) == "" {
+		return errors.New("the value of the OAuth2 permission scope is required")
+	}
+
+	scopes, err := c.GetApplicationOAuth2Scopes(ctx, objectID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get application OAuth2 permission scopes")
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Adding application OAuth2 permission scope", "objectID", objectID, "value", to.String(scope.GetValue()))
+
+	// the collection replaces the existing scopes, so they are sent along with the added one
+	updated := make([]models.PermissionScopeable, 0, len(scopes)+1)
+	for _, existing := range scopes {
+		if strings.EqualFold(to.String(existing.GetValue()), to.String(scope.GetValue())) {
+			return errors.Errorf("OAuth2 permission scope %s already exists", to.String(scope.GetValue()))
+		}
+		updated = append(updated, existing)
+	}
+	// generate the ID on a copy so that the caller's scope is left untouched
+	added := copyPermissionScope(scope)
+	if added.GetId() == nil {
+		id := uuid.New()
+		added.SetId(&id)
+	}
+	updated = append(updated, added)
+
+	api := models.NewApiApplication()
+	api.SetOauth2PermissionScopes(updated)
+	app := models.NewApplication()
+	app.SetApi(api)
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// copyPermissionScope returns a copy of the OAuth2 permission scope.
+func copyPermissionScope(scope models.PermissionScopeable) models.PermissionScopeable {
+	copied := models.NewPermissionScope()
+	copied.SetId(scope.GetId())
+	copied.SetValue(scope.GetValue())
+	copied.SetType(scope.GetType())
+	copied.SetIsEnabled(scope.GetIsEnabled())
+	copied.SetOrigin(scope.GetOrigin())
+	copied.SetAdminConsentDisplayName(scope.GetAdminConsentDisplayName())
+	copied.SetAdminConsentDescription(scope.GetAdminConsentDescription())
+	copied.SetUserConsentDisplayName(scope.GetUserConsentDisplayName())
+	copied.SetUserConsentDescription(scope.GetUserConsentDescription())
+	return copied
+}
+
+// ListApplicationAppRoles gets the app roles defined by the application.
+func (c *AzureClient) ListApplicationAppRoles(ctx context.Context, objectID string) ([]models.AppRoleable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing application app roles", "objectID", objectID)
+
+	appGetOptions := &applications.ApplicationItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appRoles"},
+		},
+	}
+
+	app, err := c.graphClient.GetApplication(ctx, objectID, appGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(app.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	return app.GetAppRoles(), nil
+}
+
+// AddApplicationAppRole adds an app role to the application. The role is given a generated ID if it has
+// none, and it is an error to add a role whose value is already defined. The allowed member types of the
+// role must be User, Application or both.
+func (c *AzureClient) AddApplicationAppRole(ctx context.Context, objectID string, role models.AppRoleable) error {
+	if to.String(role.GetValue()) == "" {
+		return errors.New("the value of the app role is required")
+	}
+	if len(role.GetAllowedMemberTypes()) == 0 {
+		return errors.Errorf("the allowed member types of the app role are required, must be any of: %s", strings.Join(appRoleMemberTypeValues, ", "))
+	}
+	for _, memberType := range role.GetAllowedMemberTypes() {
+		if !isValidAppRoleMemberType(memberType) {
+			return errors.Errorf("invalid app role member type %q, must be one of: %s", memberType, strings.Join(appRoleMemberTypeValues, ", "))
+		}
+	}
+
+	roles, err := c.ListApplicationAppRoles(ctx, objectID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list application app roles")
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Adding application app role", "objectID", objectID, "value", to.String(role.GetValue()))
+
+	// the collection replaces the existing roles, so they are sent along with the added one
+	updated := make([]models.AppRoleable, 0, len(roles)+1)
+	for _, existing := range roles {
+		if strings.EqualFold(to.String(existing.GetValue()), to.String(role.GetValue())) {
+			return errors.Errorf("app role %s already exists", to.String(role.GetValue()))
+		}
+		updated = append(updated, existing)
+	}
+	// generate the ID on a copy so that the caller's role is left untouched
+	added := copyAppRole(role)
+	if added.GetId() == nil {
+		id := uuid.New()
+		added.SetId(&id)
+	}
+	updated = append(updated, added)
+
+	app := models.NewApplication()
+	app.SetAppRoles(updated)
+
+	return c.patchApplication(ctx, objectID, app)
+}
+
+// copyAppRole returns a copy of the app role.
+func copyAppRole(role models.AppRoleable) models.AppRoleable {
+	copied := models.NewAppRole()
+	copied.SetId(role.GetId())
+	copied.SetValue(role.GetValue())
+	copied.SetAllowedMemberTypes(role.GetAllowedMemberTypes())
+	copied.SetDisplayName(role.GetDisplayName())
+	copied.SetDescription(role.GetDescription())
+	copied.SetIsEnabled(role.GetIsEnabled())
+	copied.SetOrigin(role.GetOrigin())
+	return copied
+}
+
+// patchApplication updates the properties of the application that are set in app
+// and evicts the application from the cache.
+func (c *AzureClient) patchApplication(ctx context.Context, objectID string, app models.Applicationable) error {
+	if c.skipInDryRun("updating application", "objectID", objectID) {
+		return nil
+	}
+	c.applicationCache.evict(objectID)
+	resp, err := c.graphClient.UpdateApplication(ctx, objectID, app)
+	if err != nil {
+		return err
+	}
+	// the application is not returned when it is updated successfully
+	if resp == nil {
+		return nil
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+	return nil
+}
+
+// isValidGroupMembershipClaims returns true if the value is a valid groupMembershipClaims value.
+func isValidGroupMembershipClaims(value string) bool {
+	for _, v := range groupMembershipClaimsValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidSignInAudience returns true if the value is a valid signInAudience value.
+func isValidSignInAudience(value string) bool {
+	for _, v := range signInAudienceValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidAppRoleMemberType returns true if the value is a valid allowedMemberTypes value of an app role.
+func isValidAppRoleMemberType(value string) bool {
+	for _, v := range appRoleMemberTypeValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteApplicationsByTag deletes the applications that have the given tag and returns the number of
+// deleted applications. To prevent accidental mass deletion, no application is deleted if more than
+// maxDelete applications have the tag. The errors of the applications that failed to be deleted are aggregated.
+func (c *AzureClient) DeleteApplicationsByTag(ctx context.Context, tag string, maxDelete int) (int, error) {
+	apps, err := c.listApplicationsByTag(ctx, tag)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list applications with tag %s", tag)
+	}
+	if len(apps) > maxDelete {
+		return 0, errors.Errorf("refusing to delete %d applications with tag %s, which exceeds the maximum of %d", len(apps), tag, maxDelete)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, app := range apps {
+		if err := c.DeleteApplication(ctx, to.String(app.GetId())); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to delete application %s", to.String(app.GetDisplayName())))
+			continue
+		}
+		deleted++
+	}
+	return deleted, utilerrors.NewAggregate(errs)
+}
+
+// ListApplicationsByTag lists all the applications that have the given tag, e.g. the applications created with
+// CreateApplicationWithTags, so that the orphaned ones can be garbage collected. All the pages are listed.
+// Filtering on tags is an advanced query, which requires the ConsistencyLevel header and $count.
+func (c *AzureClient) ListApplicationsByTag(ctx context.Context, tag string) ([]models.Applicationable, error) {
+	// an empty tag would list all the applications of the tenant
+	if tag == "" {
+		return nil, errors.New("tag is required")
+	}
+	return c.listApplicationsByTag(ctx, tag)
+}
+
+// listApplicationsByTag lists the applications that have the given tag, or all the applications if the tag is empty.
+func (c *AzureClient) listApplicationsByTag(ctx context.Context, tag string) ([]models.Applicationable, error) {
+	if tag == "" {
+		return c.listApplications(ctx, "")
+	}
+	return c.listApplications(ctx, getTagFilter(tag))
+}
+
+// listApplications lists the applications matching the OData filter, or all the applications if the filter is empty.
+// The filter is sent as an advanced query so that it can use the operators that require one, e.g. endsWith.
+func (c *AzureClient) listApplications(ctx context.Context, filter string) ([]models.Applicationable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing applications", "filter", filter)
+
+	var headers *abstractions.RequestHeaders
+	if filter != "" {
+		headers = newAdvancedQueryHeaders()
+	}
+
+	resp, err := c.graphClient.ListApplications(ctx, newListApplicationsOptions(filter, headers, c.top()))
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []models.Applicationable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return nil, err
+		}
+		if graphErr != nil {
+			return nil, *graphErr
+		}
+		apps = append(apps, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return apps, nil
+		}
+		nextOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{Headers: headers}
+		if resp, err = applications.NewApplicationsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nextOptions); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newListApplicationsOptions returns the options of the request listing the applications matching the filter,
+// which is sent as an advanced query with the headers, with top applications per page if top is not nil.
+func newListApplicationsOptions(filter string, headers *abstractions.RequestHeaders, top *int32) *applications.ApplicationsRequestBuilderGetRequestConfiguration {
+	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
+			Top: top,
+		},
+	}
+	if filter != "" {
+		appGetOptions.Headers = headers
+		appGetOptions.QueryParameters.Filter = to.StringPtr(filter)
+		appGetOptions.QueryParameters.Count = to.BoolPtr(true)
+	}
+	return appGetOptions
+}
+
+// ApplicationsDelta returns the applications that changed since the delta link was returned and the delta link
+// of the next round. If the delta link is empty, all the applications are returned with the first delta link.
+// The applications that were deleted are returned with only their ID and the @removed annotation in their
+// additional data. All the pages of a round are followed, so the returned delta link is never empty.
+// ref: https://learn.microsoft.com/en-us/graph/delta-query-overview
+func (c *AzureClient) ApplicationsDelta(ctx context.Context, deltaLink string) ([]models.Applicationable, string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting applications delta", "initial", deltaLink == "")
+
+	var (
+		resp applications.DeltaResponseable
+		err  error
+	)
+	if deltaLink == "" {
+		resp, err = c.graphServiceClient.Applications().Delta().Get(ctx, nil)
+	} else {
+		resp, err = applications.NewDeltaRequestBuilder(deltaLink, c.graphClient.GetAdapter()).Get(ctx, nil)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var apps []models.Applicationable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return nil, "", err
+		}
+		if graphErr != nil {
+			return nil, "", *graphErr
+		}
+		apps = append(apps, resp.GetValue()...)
+
+		// the last page of a round has the delta link of the next round instead of a next link
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			if link := to.String(resp.GetOdataDeltaLink()); link != "" {
+				return apps, link, nil
+			}
+			return nil, "", errors.New("applications delta response has neither a next link nor a delta link")
+		}
+		if resp, err = applications.NewDeltaRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// AddFederatedCredential adds a federated credential to the cloud provider.
+// If the federated credential has no description, it defaults to one that names
+// the issuer and the managing tool so that the trust can be attributed to a cluster.
+// If it has no audiences, they default to the default federated audiences of the client.
+// The federated credential is checked with ValidateFederatedCredential before the request is sent, as are the
+// defaulted audiences, of which Graph accepts exactly one, and the issuer when allowed issuers are set with
+// SetAllowedIssuers.
+// Right after the application is created, Graph may fail the request with the Request_ResourceNotFound
+// error code until the application has propagated; the request is retried with backoff on that code only.
+// ErrFederatedCredentialAlreadyExists is returned if the application already has the federated credential.
+// It returns the federated credential created by Graph, which has the generated ID, e.g. to delete it later.
+// Graph rejects the federated credentials of an application beyond the maximum of 20; callers that add several
+// can call CheckFederatedCredentialLimit first to fail early with a clear error.
+func (c *AzureClient) AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	body, err := c.newFederatedCredentialBody(fic)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.skipInDryRun("adding federated credential", "objectID", objectID, "name", to.String(body.GetName()), "issuer", to.String(body.GetIssuer()), "subject", to.String(body.GetSubject())) {
+		body.SetId(dryRunObjectID())
+		return body, nil
+	}
+
+	c.logDebug("Adding federated credential", "objectID", objectID)
+
+	delay := c.federatedCredentialPropagationRetryDelay
+	if delay <= 0 {
+		delay = defaultFederatedCredentialPropagationRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		fic, err = c.graphClient.CreateFederatedIdentityCredential(ctx, objectID, body)
+		if err == nil || attempt >= federatedCredentialPropagationRetryCount || !isApplicationNotPropagated(err) {
+			break
+		}
+		c.logDebug("Application not propagated yet, retrying to add federated credential", "objectID", objectID, "attempt", attempt+1, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	if err == nil {
+		var graphErr *GraphError
+		if graphErr, err = GetGraphError(fic.GetAdditionalData()); err != nil {
+			return nil, err
+		}
+		if graphErr == nil {
+			return fic, nil
+		}
+		err = *graphErr
+	}
+	if isMultipleObjectsWithSameKeyValue(err) {
+		return nil, errors.Wrapf(ErrFederatedCredentialAlreadyExists, "federated credential %s of application %s: %v", to.String(body.GetName()), objectID, withODataErrorDetails(err))
+	}
+	return nil, withAmbiguousCreateHint(withInsufficientPrivileges(err), "federated credential")
+}
+
+// newFederatedCredentialBody validates the federated credential to add and returns the body of the request adding
+// it, a copy with the default description and audiences so that the caller's federated credential is left untouched.
+func (c *AzureClient) newFederatedCredentialBody(fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	if err := ValidateFederatedCredential(fic); err != nil {
+		return nil, err
+	}
+	expression := GetClaimsMatchingExpression(fic)
+	if !c.isIssuerAllowed(to.String(fic.GetIssuer())) {
+		return nil, errors.Wrapf(ErrIssuerNotAllowed, "issuer %q of federated credential %s", to.String(fic.GetIssuer()), to.String(fic.GetName()))
+	}
+
+	body := models.NewFederatedIdentityCredential()
+	body.SetName(fic.GetName())
+	body.SetIssuer(fic.GetIssuer())
+	body.SetSubject(fic.GetSubject())
+	if expression != "" {
+		SetClaimsMatchingExpression(body, expression)
+	}
+	audiences := fic.GetAudiences()
+	if len(audiences) == 0 {
+		audiences = c.defaultFederatedAudiences
+	}
+	if err := validateFederatedCredentialAudiences(audiences); err != nil {
+		return nil, err
+	}
+	body.SetAudiences(audiences)
+	body.SetDescription(to.StringPtr(federatedCredentialDescription(to.String(fic.GetDescription()), to.String(fic.GetIssuer()))))
+	return body, nil
+}
+
+// GetFederatedCredential gets a federated credential from the cloud provider.
+// It is a convenience wrapper of GetFederatedCredentialsBySubject that returns the
+// federated credential with the given issuer.
+func (c *AzureClient) GetFederatedCredential(ctx context.Context, objectID, issuer, subject string) (models.FederatedIdentityCredentialable, error) {
+	c.logDebug("Getting federated credential",
+		"objectID", objectID,
+		"issuer", issuer,
+		"subject", subject,
+	)
+
+	return c.GetFederatedCredentialWithAudiences(ctx, objectID, issuer, subject, nil)
+}
+
+// GetFederatedCredentialWithAudiences is like GetFederatedCredential, but only returns the federated credential
+// if it also has the given audiences, in any order, so that a federated credential that doesn't trust the audience
+// of the tokens is not mistaken for a match. The audiences are not matched if they are empty.
+func (c *AzureClient) GetFederatedCredentialWithAudiences(ctx context.Context, objectID, issuer, subject string, audiences []string) (models.FederatedIdentityCredentialable, error) {
+	fics, err := c.GetFederatedCredentialsBySubject(ctx, objectID, subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, fic := range fics {
+		if to.String(fic.GetIssuer()) == issuer && (len(audiences) == 0 || sameAudiences(audiences, fic.GetAudiences())) {
+			return fic, nil
+		}
+	}
+	return nil, ErrFederatedCredentialNotFound
+}
+
+// FederatedCredentialExists returns true if the application has a federated credential with the given issuer
+// and subject. Unlike GetFederatedCredential, a federated credential that is not found is not an error, so the
+// error is only returned if the federated credentials could not be listed.
+func (c *AzureClient) FederatedCredentialExists(ctx context.Context, objectID, issuer, subject string) (bool, error) {
+	_, err := c.GetFederatedCredential(ctx, objectID, issuer, subject)
+	if errors.Is(err, ErrFederatedCredentialNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetFederatedCredentialsBySubject gets all the federated credentials of the application with the given subject.
+// An application can have several federated credentials with the same subject and different issuers,
+// so callers can disambiguate by issuer themselves.
+func (c *AzureClient) GetFederatedCredentialsBySubject(ctx context.Context, objectID, subject string) ([]models.FederatedIdentityCredentialable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting federated credentials",
+		"objectID", objectID,
+		"subject", subject,
+	)
+
+	ficGetOptions := &applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ItemFederatedIdentityCredentialsRequestBuilderGetQueryParameters{
+			// Filtering on more than one property of federated identity credentials is currently not supported,
+			// so the issuer is matched by the callers on all the pages of the credentials with the subject.
+			Filter: to.StringPtr(getSubjectFilter(subject)),
+			Top:    c.top(),
+		},
+	}
+
+	resp, err := c.graphClient.ListFederatedIdentityCredentials(ctx, objectID, ficGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var fics []models.FederatedIdentityCredentialable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return partialResults(c, fics), err
+		}
+		if graphErr != nil {
+			return partialResults(c, fics), *graphErr
+		}
+		fics = append(fics, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return fics, nil
+		}
+		if resp, err = applications.NewItemFederatedIdentityCredentialsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return partialResults(c, fics), err
+		}
+	}
+}
+
+// GetFederatedCredentialByName gets the federated credential of the application with the given name.
+// The name of a federated credential is unique per application.
+func (c *AzureClient) GetFederatedCredentialByName(ctx context.Context, objectID, name string) (models.FederatedIdentityCredentialable, error) {
+	c.logDebug("Getting federated credential",
+		"objectID", objectID,
+		"name", name,
+	)
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, fic := range fics {
+		if to.String(fic.GetName()) == name {
+			return fic, nil
+		}
+	}
+	return nil, ErrFederatedCredentialNotFound
+}
+
+// GetFederatedCredentialRaw gets the JSON representation of the federated credential of the application as
+// returned by Graph, without deserializing it, e.g. to troubleshoot a mismatch with the expected properties.
+// The request is sent with the same request adapter, and hence authentication and transport, as the others.
+func (c *AzureClient) GetFederatedCredentialRaw(ctx context.Context, objectID, ficID string) ([]byte, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting raw federated credential", "objectID", objectID, "ficID", ficID)
+
+	requestInfo, err := c.graphServiceClient.ApplicationsById(objectID).FederatedIdentityCredentialsById(ficID).ToGetRequestInformation(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	errorMapping := abstractions.ErrorMappings{
+		"4XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+		"5XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+	}
+	res, err := c.graphClient.GetAdapter().SendPrimitive(ctx, requestInfo, "[]byte", errorMapping)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := res.([]byte)
+	if !ok {
+		return nil, errors.Errorf("federated credential %s of application %s has no content", ficID, objectID)
+	}
+	return raw, nil
+}
+
+// WaitForFederatedCredential waits until the named federated credential of the application can be read back.
+// Azure AD takes a while to propagate a new federated credential, and a token exchange made before that fails.
+// The federated credential is polled at the interval set with SetFederatedCredentialPollInterval.
+func (c *AzureClient) WaitForFederatedCredential(ctx context.Context, objectID, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	interval := c.federatedCredentialPollInterval
+	if interval <= 0 {
+		interval = defaultFederatedCredentialPollInterval
+	}
+
+	c.logDebug("Waiting for federated credential",
+		"objectID", objectID,
+		"name", name,
+		"timeout", timeout,
+	)
+
+	for {
+		_, err := c.GetFederatedCredentialByName(ctx, objectID, name)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() == nil && !errors.Is(err, ErrFederatedCredentialNotFound) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "failed to wait for federated credential %s", name)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// UpdateFederatedCredential updates a federated credential of the application in the cloud provider.
+func (c *AzureClient) UpdateFederatedCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("updating federated credential", "objectID", objectID, "federatedCredentialID", federatedCredentialID) {
+		return nil
+	}
+
+	c.logDebug("Updating federated credential",
+		"objectID", objectID,
+		"federatedCredentialID", federatedCredentialID,
+	)
+
+	fic, err := c.graphClient.UpdateFederatedIdentityCredential(ctx, objectID, federatedCredentialID, fic)
+	if err != nil {
+		return err
+	}
+	// PATCH returns 204 No Content on success
+	if fic == nil {
+		return nil
+	}
+	graphErr, err := GetGraphError(fic.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+	return nil
+}
+
+// CheckFederatedCredentialLimit returns ErrFederatedCredentialLimitExceeded if the application already has the
+// maximum number of federated credentials, so that no federated credential can be added to it, e.g. before adding
+// federated credentials in a batch.
+func (c *AzureClient) CheckFederatedCredentialLimit(ctx context.Context, objectID string) error {
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list federated credentials of application %s", objectID)
+	}
+	if len(fics) >= maxFederatedCredentialsPerApplication {
+		return errors.Wrapf(ErrFederatedCredentialLimitExceeded, "application %s has %d federated credentials, which is the limit of %d",
+			objectID, len(fics), maxFederatedCredentialsPerApplication)
+	}
+	return nil
+}
+
+// ListFederatedCredentials lists all the federated credentials of the application.
+func (c *AzureClient) ListFederatedCredentials(ctx context.Context, objectID string) ([]models.FederatedIdentityCredentialable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing federated credentials", "objectID", objectID)
+
+	ficGetOptions := &applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ItemFederatedIdentityCredentialsRequestBuilderGetQueryParameters{
+			Top: c.top(),
+		},
+	}
+	resp, err := c.graphClient.ListFederatedIdentityCredentials(ctx, objectID, ficGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var fics []models.FederatedIdentityCredentialable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return partialResults(c, fics), err
+		}
+		if graphErr != nil {
+			return partialResults(c, fics), *graphErr
+		}
+		fics = append(fics, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return fics, nil
+		}
+		// follow the next link to get the next page of federated credentials
+		if resp, err = applications.NewItemFederatedIdentityCredentialsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return partialResults(c, fics), err
+		}
+	}
+}
+
+// DeleteFederatedCredential deletes a federated credential from the cloud provider.
+func (c *AzureClient) DeleteFederatedCredential(ctx context.Context, objectID, federatedCredentialID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("deleting federated credential", "objectID", objectID, "federatedCredentialID", federatedCredentialID) {
+		return nil
+	}
+
+	c.logDebug("Deleting federated credential",
+		"objectID", objectID,
+		"federatedCredentialID", federatedCredentialID,
+	)
+	return c.graphClient.DeleteFederatedIdentityCredential(ctx, objectID, federatedCredentialID)
+}
+
+// DeleteFederatedCredentialBySubject deletes the federated credential of the application with the given issuer and
+// subject, as found by GetFederatedCredential. A federated credential that doesn't exist, e.g. because it was already
+// deleted, is not an error, so that a teardown can be run again.
+func (c *AzureClient) DeleteFederatedCredentialBySubject(ctx context.Context, objectID, issuer, subject string) error {
+	fic, err := c.GetFederatedCredential(ctx, objectID, issuer, subject)
+	if err != nil {
+		if errors.Is(err, ErrFederatedCredentialNotFound) {
+			c.logDebug("Federated credential has already been deleted", "objectID", objectID, "issuer", issuer, "subject", subject)
+			return nil
+		}
+		return errors.Wrap(err, "failed to get federated credential")
+	}
+
+	if err := c.DeleteFederatedCredential(ctx, objectID, to.String(fic.GetId())); err != nil {
+		if isGraphResourceNotFound(err) {
+			c.logDebug("Federated credential has already been deleted", "objectID", objectID, "issuer", issuer, "subject", subject)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete federated credential %s", to.String(fic.GetName()))
+	}
+	return nil
+}
+
+// DeleteFederatedCredentials deletes the federated credentials of the application that match the predicate, e.g. the
+// federated credentials whose subject or issuer belongs to a decommissioned cluster, and returns the number of deleted
+// federated credentials. A federated credential that was already deleted is not an error. The errors of the federated
+// credentials that failed to be deleted are aggregated.
+func (c *AzureClient) DeleteFederatedCredentials(ctx context.Context, objectID string, predicate func(models.FederatedIdentityCredentialable) bool) (int, error) {
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list federated credentials of application %s", objectID)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, fic := range fics {
+		if !predicate(fic) {
+			continue
+		}
+		if err := c.DeleteFederatedCredential(ctx, objectID, to.String(fic.GetId())); err != nil {
+			if isGraphResourceNotFound(err) {
+				c.logDebug("Federated credential has already been deleted", "objectID", objectID, "name", to.String(fic.GetName()))
+				continue
+			}
+			errs = append(errs, errors.Wrapf(err, "failed to delete federated credential %s", to.String(fic.GetName())))
+			continue
+		}
+		deleted++
+	}
+	return deleted, utilerrors.NewAggregate(errs)
+}
+
+// DeleteFederatedCredentialsBatch deletes the federated credentials of the application with JSON batch requests of
+// at most maxBatchRequests deletes each, which is much faster than deleting them one by one. It returns the error of
+// each delete, in the order of the IDs, nil when the federated credential was deleted. The error is only non-nil if a
+// batch request failed as a whole, in which case the federated credentials of the following batches are not deleted.
+// ref: https://learn.microsoft.com/en-us/graph/json-batching
+func (c *AzureClient) DeleteFederatedCredentialsBatch(ctx context.Context, objectID string, ficIDs []string) ([]error, error) {
+	errs := make([]error, len(ficIDs))
+	for start := 0; start < len(ficIDs); start += maxBatchRequests {
+		end := start + maxBatchRequests
+		if end > len(ficIDs) {
+			end = len(ficIDs)
+		}
+		if err := c.deleteFederatedCredentialsBatch(ctx, objectID, ficIDs[start:end], errs[start:end]); err != nil {
+			return errs, errors.Wrapf(err, "failed to delete federated credentials %d to %d", start+1, end)
+		}
+	}
+	return errs, nil
+}
+
+// FederatedCredentialRequest is a federated credential to add to an application with AddFederatedCredentials.
+type FederatedCredentialRequest struct {
+	// ObjectID is the object ID of the application.
+	ObjectID string
+	// FederatedCredential is the federated credential to add, as passed to AddFederatedCredential.
+	FederatedCredential models.FederatedIdentityCredentialable
+}
+
+// FederatedCredentialResult is the result of a FederatedCredentialRequest.
+type FederatedCredentialResult struct {
+	// Index is the index of the request.
+	Index int
+	// FederatedCredential is the federated credential created by Graph, nil if it failed to be added.
+	FederatedCredential models.FederatedIdentityCredentialable
+	// Err is the error of the request, nil if the federated credential was added.
+	Err error
+}
+
+// AddFederatedCredentials adds the federated credentials with JSON batch requests of at most maxBatchRequests
+// creates each, which is much faster than adding them one by one, e.g. to onboard a cluster in many applications.
+// The federated credentials are validated and defaulted like by AddFederatedCredential, but the requests are not
+// retried while an application is not propagated. It returns the result of each request, in the order of the
+// requests. The error is only non-nil if a batch request failed as a whole, in which case the federated credentials
+// of the following batches are not added and their results are not returned.
+// ref: https://learn.microsoft.com/en-us/graph/json-batching
+func (c *AzureClient) AddFederatedCredentials(ctx context.Context, reqs []FederatedCredentialRequest) ([]FederatedCredentialResult, error) {
+	results := make([]FederatedCredentialResult, 0, len(reqs))
+	for start := 0; start < len(reqs); start += maxBatchRequests {
+		end := start + maxBatchRequests
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		batch, err := c.addFederatedCredentialsBatch(ctx, reqs[start:end])
+		if err != nil {
+			return results, errors.Wrapf(err, "failed to add federated credentials %d to %d", start+1, end)
+		}
+		for i := range batch {
+			batch[i].Index += start
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// addFederatedCredentialsBatch adds the federated credentials with a single JSON batch request. The federated
+// credentials that are not valid are not sent.
+func (c *AzureClient) addFederatedCredentialsBatch(ctx context.Context, reqs []FederatedCredentialRequest) ([]FederatedCredentialResult, error) {
+	c.logDebug("Adding federated credentials in batch", "count", len(reqs))
+
+	results := make([]FederatedCredentialResult, len(reqs))
+	requests := make([]batchRequestItem, 0, len(reqs))
+	// sent are the indexes of the requests in the batch request, by their index in the batch
+	sent := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		results[i].Index = i
+		name := to.String(req.FederatedCredential.GetName())
+		body, err := c.newFederatedCredentialBody(req.FederatedCredential)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		content, err := serializeFederatedCredential(body)
+		if err != nil {
+			results[i].Err = errors.Wrapf(err, "failed to serialize federated credential %s", name)
+			continue
+		}
+		requests = append(requests, batchRequestItem{
+			ID:      strconv.Itoa(len(requests)),
+			Method:  http.MethodPost,
+			URL:     fmt.Sprintf("/applications/%s/federatedIdentityCredentials", req.ObjectID),
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    content,
+		})
+		sent = append(sent, i)
+	}
+	if len(requests) == 0 {
+		return results, nil
+	}
+	if c.skipInDryRun("adding federated credentials in batch", "count", len(requests)) {
+		for j, i := range sent {
+			fic, err := deserializeFederatedCredential(requests[j].Body)
+			if err != nil {
+				results[i].Err = errors.Wrapf(err, "failed to deserialize federated credential %s", to.String(reqs[i].FederatedCredential.GetName()))
+				continue
+			}
+			fic.SetId(dryRunObjectID())
+			results[i].FederatedCredential = fic
+		}
+		return results, nil
+	}
+
+	responses, err := c.sendBatch(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+	for j, item := range responses {
+		i := sent[j]
+		name := to.String(reqs[i].FederatedCredential.GetName())
+		switch {
+		case item == nil:
+			results[i].Err = errors.Errorf("no response to the add of federated credential %s in the batch response", name)
+		case item.Status >= http.StatusOK && item.Status < http.StatusMultipleChoices:
+			fic, err := deserializeFederatedCredential(item.Body)
+			if err != nil {
+				results[i].Err = errors.Wrapf(err, "failed to deserialize federated credential %s", name)
+				continue
+			}
+			results[i].FederatedCredential = fic
+		default:
+			err := item.err()
+			if isMultipleObjectsWithSameKeyValue(err) {
+				err = errors.Wrapf(ErrFederatedCredentialAlreadyExists, "federated credential %s of application %s: %v", name, reqs[i].ObjectID, err)
+			}
+			results[i].Err = errors.Wrapf(withInsufficientPrivileges(err), "failed to add federated credential %s", name)
+		}
+	}
+	return results, nil
+}
+
+// serializeFederatedCredential returns the JSON representation of the federated credential sent to Graph.
+func serializeFederatedCredential(fic models.FederatedIdentityCredentialable) ([]byte, error) {
+	writer := jsonserialization.NewJsonSerializationWriter()
+	if err := writer.WriteObjectValue("", fic); err != nil {
+		return nil, err
+	}
+	return writer.GetSerializedContent()
+}
+
+// deserializeFederatedCredential returns the federated credential of its JSON representation returned by Graph.
+func deserializeFederatedCredential(content []byte) (models.FederatedIdentityCredentialable, error) {
+	node, err := jsonserialization.NewJsonParseNode(content)
+	if err != nil {
+		return nil, err
+	}
+	fic, err := node.GetObjectValue(models.CreateFederatedIdentityCredentialFromDiscriminatorValue)
+	if err != nil {
+		return nil, err
+	}
+	return fic.(models.FederatedIdentityCredentialable), nil
+}
+
+// batchRequestItem is a request of a JSON batch request.
+type batchRequestItem struct {
+	ID      string            `json:"id"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// batchResponseItem is the response of a request of a JSON batch request.
+type batchResponseItem struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// err returns the error of a failed request. The error is a GraphError when the response has one, so that
+// e.g. IsFederatedCredentialNotFound can be used on it.
+func (item *batchResponseItem) err() error {
+	var body struct {
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(item.Body, &body); err != nil || body.Error == nil {
+		return errors.Errorf("request failed with status %d", item.Status)
+	}
+	gerr := GraphError{
+		Code:        body.Error.Code,
+		Message:     body.Error.Message,
+		StatusCode:  item.Status,
+		PublicError: models.NewPublicError(),
+	}
+	gerr.PublicError.SetCode(to.StringPtr(body.Error.Code))
+	gerr.PublicError.SetMessage(to.StringPtr(body.Error.Message))
+	return gerr
+}
+
+// sendBatch sends the requests, whose IDs must be their index, in a single JSON batch request and returns the
+// response of each request, in the order of the requests. The response of a request is nil if it is missing.
+func (c *AzureClient) sendBatch(ctx context.Context, requests []batchRequestItem) ([]*batchResponseItem, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	content, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal batch request")
+	}
+
+	requestInfo := abstractions.NewRequestInformation()
+	requestInfo.Method = abstractions.POST
+	requestInfo.UrlTemplate = "{+baseurl}/$batch"
+	requestInfo.Content = content
+	requestInfo.Headers.Add("Content-Type", "application/json")
+
+	errorMapping := abstractions.ErrorMappings{
+		"4XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+		"5XX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+	}
+	res, err := c.graphClient.GetAdapter().SendPrimitive(ctx, requestInfo, "[]byte", errorMapping)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := res.([]byte)
+	var resp struct {
+		Responses []batchResponseItem `json:"responses"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal batch response")
+	}
+
+	responses := make([]*batchResponseItem, len(requests))
+	for j := range resp.Responses {
+		item := &resp.Responses[j]
+		i, err := strconv.Atoi(item.ID)
+		if err != nil || i < 0 || i >= len(requests) {
+			return nil, errors.Errorf("unexpected batch response ID %q", item.ID)
+		}
+		responses[i] = item
+	}
+	return responses, nil
+}
+
+// deleteFederatedCredentialsBatch deletes the federated credentials of the application with a single JSON batch
+// request and sets the error of each delete in errs, which has the length of ficIDs.
+func (c *AzureClient) deleteFederatedCredentialsBatch(ctx context.Context, objectID string, ficIDs []string, errs []error) error {
+	if c.skipInDryRun("deleting federated credentials in batch", "objectID", objectID, "federatedCredentialIDs", ficIDs) {
+		return nil
+	}
+	c.logDebug("Deleting federated credentials in batch", "objectID", objectID, "count", len(ficIDs))
+
+	// the IDs of the requests are their index in the batch
+	requests := make([]batchRequestItem, 0, len(ficIDs))
+	for i, ficID := range ficIDs {
+		requests = append(requests, batchRequestItem{
+			ID:     strconv.Itoa(i),
+			Method: http.MethodDelete,
+			URL:    fmt.Sprintf("/applications/%s/federatedIdentityCredentials/%s", objectID, ficID),
+		})
+	}
+
+	responses, err := c.sendBatch(ctx, requests)
+	if err != nil {
+		return err
+	}
+	for i, item := range responses {
+		switch {
+		case item == nil:
+			errs[i] = errors.Errorf("no response to the delete of federated credential %s in the batch response", ficIDs[i])
+		case item.Status >= http.StatusOK && item.Status < http.StatusMultipleChoices:
+		default:
+			errs[i] = errors.Wrapf(item.err(), "failed to delete federated credential %s", ficIDs[i])
+		}
+	}
+	return nil
+}
+
+// newAdvancedQueryHeaders returns the headers required by the advanced queries of directory objects,
+// such as filtering on tags. The $count query parameter must be set as well.
+// ref: https://learn.microsoft.com/en-us/graph/aad-advanced-queries
+func newAdvancedQueryHeaders() *abstractions.RequestHeaders {
+	headers := abstractions.NewRequestHeaders()
+	headers.Add("ConsistencyLevel", "eventual")
+	return headers
+}
+
+// defaultFederatedCredentialDescription returns the default description of a federated credential with the given issuer.
+func defaultFederatedCredentialDescription(issuer string) string {
+	return fmt.Sprintf("Federated credential for issuer %s, managed by azwi", issuer)
+}
+
+// federatedCredentialDescription returns the description, or the default description if it is empty.
+func federatedCredentialDescription(description, issuer string) string {
+	if description == "" {
+		return defaultFederatedCredentialDescription(issuer)
+	}
+	return description
+}
+
+// ValidateFederatedCredential returns an error describing the first constraint of Graph violated by the federated
+// identity credential, whose own error is generic, e.g. to validate the federated credentials of a configuration
+// before any request is sent. It checks the name, that the issuer is an https URL, that exactly one of the subject
+// and the claims matching expression is set, that the subject of a Kubernetes service account has the
+// system:serviceaccount:<namespace>:<name> format, and the audiences if any are set, as audiences default to the
+// default federated audiences of the client.
+func ValidateFederatedCredential(fic models.FederatedIdentityCredentialable) error {
+	if err := validateFederatedCredentialName(to.String(fic.GetName())); err != nil {
+		return err
+	}
+	if err := validateFederatedCredentialIssuer(to.String(fic.GetIssuer())); err != nil {
+		return err
+	}
+	subject := to.String(fic.GetSubject())
+	if err := validateFederatedCredentialMatching(subject, GetClaimsMatchingExpression(fic)); err != nil {
+		return err
+	}
+	if err := validateFederatedCredentialSubject(subject); err != nil {
+		return err
+	}
+	if audiences := fic.GetAudiences(); len(audiences) > 0 {
+		return validateFederatedCredentialAudiences(audiences)
+	}
+	return nil
+}
+
+// validateFederatedCredentialIssuer returns an error if the issuer of a federated identity credential is not an
+// https URL, which Graph requires to discover the keys of the issuer.
+func validateFederatedCredentialIssuer(issuer string) error {
+	if issuer == "" {
+		return errors.New("federated credential issuer is required")
+	}
+	if len(issuer) > maxFederatedCredentialValueLength {
+		return errors.Errorf("federated credential issuer is %d characters long, which exceeds the limit of %d characters", len(issuer), maxFederatedCredentialValueLength)
+	}
+	u, err := url.Parse(issuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("federated credential issuer %q is not a valid https URL", issuer)
+	}
+	return nil
+}
+
+// validateFederatedCredentialSubject returns an error if the subject of a federated identity credential exceeds the
+// length limit, or if the subject of a Kubernetes service account doesn't name both its namespace and name.
+// The subjects of other issuers, e.g. GitHub Actions workflows, have no known format and are not checked further.
+func validateFederatedCredentialSubject(subject string) error {
+	if len(subject) > maxFederatedCredentialValueLength {
+		return errors.Errorf("federated credential subject is %d characters long, which exceeds the limit of %d characters", len(subject), maxFederatedCredentialValueLength)
+	}
+	if !strings.HasPrefix(subject, serviceAccountSubjectPrefix) {
+		return nil
+	}
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(subject, serviceAccountSubjectPrefix), ":")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, ":") {
+		return errors.Errorf("federated credential subject %q is not in the %s<namespace>:<name> format of a Kubernetes service account", subject, serviceAccountSubjectPrefix)
+	}
+	return nil
+}
+
+// validateFederatedCredentialName returns an error describing the constraint violated by the
+// name of a federated identity credential, so that it is not rejected by Graph.
+func validateFederatedCredentialName(name string) error {
+	if name == "" {
+		return errors.New("federated credential name is required")
+	}
+	if len(name) < minFederatedCredentialNameLength {
+		return errors.Errorf("federated credential name %q is %d characters long, which is below the minimum of %d characters", name, len(name), minFederatedCredentialNameLength)
+	}
+	if len(name) > maxFederatedCredentialNameLength {
+		return errors.Errorf("federated credential name %q is %d characters long, which exceeds the limit of %d characters", name, len(name), maxFederatedCredentialNameLength)
+	}
+	var invalid []string
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune(federatedCredentialNameSymbols, r) {
+			continue
+		}
+		if !containsFold(invalid, string(r)) {
+			invalid = append(invalid, string(r))
+		}
+	}
+	if len(invalid) > 0 {
+		return errors.Errorf("federated credential name %q contains invalid characters %q, only alphanumeric characters and %q are allowed", name, strings.Join(invalid, ""), federatedCredentialNameSymbols)
+	}
+	return nil
+}
+
+// validateFederatedCredentialAudiences returns an error if the number of audiences of a federated identity
+// credential is not the one accepted by Graph, whose own error doesn't name the constraint.
+func validateFederatedCredentialAudiences(audiences []string) error {
+	if len(audiences) != federatedCredentialAudiencesCount {
+		return errors.Errorf("federated credential has %d audiences %q, but exactly %d is required", len(audiences), audiences, federatedCredentialAudiencesCount)
+	}
+	for _, audience := range audiences {
+		if audience == "" {
+			return errors.New("federated credential audience is empty")
+		}
+		if len(audience) > maxFederatedCredentialValueLength {
+			return errors.Errorf("federated credential audience is %d characters long, which exceeds the limit of %d characters", len(audience), maxFederatedCredentialValueLength)
+		}
+	}
+	return nil
+}
+
+// getDisplayNameFilter returns a filter string for the given display name.
+func getDisplayNameFilter(displayName string) string {
+	return fmt.Sprintf("displayName eq '%s'", escapeODataString(displayName))
+}
+
+// getAppIDFilter returns a filter string for the given app ID.
+func getAppIDFilter(appID string) string {
+	return fmt.Sprintf("appId eq '%s'", escapeODataString(appID))
+}
+
+// getIdentifierURIFilter returns a filter string for the given identifier URI.
+func getIdentifierURIFilter(uri string) string {
+	return fmt.Sprintf("identifierUris/any(x:x eq '%s')", escapeODataString(uri))
+}
+
+// getTagFilter returns a filter string for the given tag.
+func getTagFilter(tag string) string {
+	return fmt.Sprintf("tags/any(t:t eq '%s')", escapeODataString(tag))
+}
+
+// escapeODataString escapes the value of an OData string literal, in which a single quote is
+// represented by two single quotes, so that the value can't end the literal and break the filter.
+func escapeODataString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
 }
 
 // getSubjectFilter returns a filter string for the given subject.
 func getSubjectFilter(subject string) string {
-	return fmt.Sprintf("subject eq '%s'", subject)
+	return fmt.Sprintf("subject eq '%s'", escapeODataString(subject))
 }
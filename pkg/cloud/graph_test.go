@@ -1,6 +1,26 @@
 package cloud
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
 
 func TestGetDisplayNameFilter(t *testing.T) {
 	got := getDisplayNameFilter("test")
@@ -11,6 +31,40 @@ func TestGetDisplayNameFilter(t *testing.T) {
 	}
 }
 
+func TestGetAppIDFilter(t *testing.T) {
+	got := getAppIDFilter("test")
+	want := "appId eq 'test'"
+
+	if got != want {
+		t.Errorf("getAppIDFilter() = %v, want %v", got, want)
+	}
+}
+
+func TestGetTagFilter(t *testing.T) {
+	got := getTagFilter("test")
+	want := "tags/any(t:t eq 'test')"
+
+	if got != want {
+		t.Errorf("getTagFilter() = %v, want %v", got, want)
+	}
+
+	got = getTagFilter("owner:O'Brien")
+	want = "tags/any(t:t eq 'owner:O''Brien')"
+
+	if got != want {
+		t.Errorf("getTagFilter() = %v, want %v", got, want)
+	}
+}
+
+func TestGetIdentifierURIFilter(t *testing.T) {
+	got := getIdentifierURIFilter("api://test")
+	want := "identifierUris/any(x:x eq 'api://test')"
+
+	if got != want {
+		t.Errorf("getIdentifierURIFilter() = %v, want %v", got, want)
+	}
+}
+
 func TestGetSubjectFilter(t *testing.T) {
 	got := getSubjectFilter("test")
 	want := "subject eq 'test'"
@@ -19,3 +73,3281 @@ func TestGetSubjectFilter(t *testing.T) {
 		t.Errorf("getSubjectFilter() = %v, want %v", got, want)
 	}
 }
+
+func TestFiltersEscapeQuotes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "display name",
+			got:  getDisplayNameFilter("O'Brien"),
+			want: "displayName eq 'O''Brien'",
+		},
+		{
+			name: "display name ending the literal",
+			got:  getDisplayNameFilter("x' or displayName ne 'x"),
+			want: "displayName eq 'x'' or displayName ne ''x'",
+		},
+		{
+			name: "app ID",
+			got:  getAppIDFilter("'"),
+			want: "appId eq ''''",
+		},
+		{
+			name: "identifier URI",
+			got:  getIdentifierURIFilter("api://o'brien"),
+			want: "identifierUris/any(x:x eq 'api://o''brien')",
+		},
+		{
+			name: "subject",
+			got:  getSubjectFilter("system:serviceaccount:o'brien:sa"),
+			want: "subject eq 'system:serviceaccount:o''brien:sa'",
+		},
+		{
+			name: "role name",
+			got:  getRoleNameFilter("O'Brien's Reader"),
+			want: "roleName eq 'O''Brien''s Reader'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("filter = %v, want %v", test.got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetApplicationEscapesDisplayName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if got := r.URL.Query().Get("$filter"); got != "displayName eq 'O''Brien'" {
+			t.Errorf("expected $filter to be displayName eq 'O''Brien', got %q", got)
+			fmt.Fprint(w, `{"value": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "object-id", "displayName": "O'Brien"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.GetApplication(context.Background(), "O'Brien")
+	if err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if got := *app.GetId(); got != "object-id" {
+		t.Errorf("GetApplication() = %s, want object-id", got)
+	}
+}
+
+func TestGetApplicationByIdentifierURI(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch filter := r.URL.Query().Get("$filter"); filter {
+		case "identifierUris/any(x:x eq 'api://app-id')":
+			fmt.Fprint(w, `{"value": [{"id": "object-id", "appId": "app-id", "identifierUris": ["api://app-id"]}]}`)
+		case "identifierUris/any(x:x eq 'api://missing')":
+			fmt.Fprint(w, `{"value": []}`)
+		default:
+			t.Errorf("unexpected $filter %q", filter)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.GetApplicationByIdentifierURI(context.Background(), "api://app-id")
+	if err != nil {
+		t.Fatalf("GetApplicationByIdentifierURI() error = %v", err)
+	}
+	if to.String(app.GetId()) != "object-id" {
+		t.Errorf("expected application object-id, got %s", to.String(app.GetId()))
+	}
+
+	if _, err := c.GetApplicationByIdentifierURI(context.Background(), "api://missing"); !errors.Is(err, ErrApplicationNotFound) {
+		t.Errorf("expected ErrApplicationNotFound, got %v", err)
+	}
+}
+
+func TestGetOrCreateApplication(t *testing.T) {
+	tests := []struct {
+		name string
+		// createdConcurrently is true if another caller creates the application between the get and the create
+		createdConcurrently bool
+		wantPosts           int32
+		wantGets            int32
+	}{
+		{
+			name:      "not found then created",
+			wantPosts: 1,
+			wantGets:  1,
+		},
+		{
+			name:                "create conflict then get",
+			createdConcurrently: true,
+			wantPosts:           1,
+			wantGets:            2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gets, posts int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method {
+				case http.MethodGet:
+					if atomic.AddInt32(&gets, 1) == 1 {
+						fmt.Fprint(w, `{"value": []}`)
+						return
+					}
+					fmt.Fprint(w, `{"value": [{"id": "other-object-id", "displayName": "app"}]}`)
+				case http.MethodPost:
+					atomic.AddInt32(&posts, 1)
+					if test.createdConcurrently {
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprint(w, `{"error": {"code": "Request_MultipleObjectsWithSameKeyValue", "message": "Another object with the same value for property uniqueName already exists."}}`)
+						return
+					}
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"id": "object-id", "displayName": "app"}`)
+				}
+			})
+			c := newTestAzureClient(t, mux)
+
+			app, err := c.GetOrCreateApplication(context.Background(), "app")
+			if err != nil {
+				t.Fatalf("GetOrCreateApplication() error = %v", err)
+			}
+			wantID := "object-id"
+			if test.createdConcurrently {
+				wantID = "other-object-id"
+			}
+			if got := to.String(app.GetId()); got != wantID {
+				t.Errorf("expected application object ID to be %s, got %s", wantID, got)
+			}
+			if got := atomic.LoadInt32(&posts); got != test.wantPosts {
+				t.Errorf("expected %d creates, got %d", test.wantPosts, got)
+			}
+			if got := atomic.LoadInt32(&gets); got != test.wantGets {
+				t.Errorf("expected %d gets, got %d", test.wantGets, got)
+			}
+		})
+	}
+}
+
+func TestCreateApplicationWithAppID(t *testing.T) {
+	const appID = "00000000-0000-0000-0000-000000000001"
+
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": "object-id", "appId": %q, "displayName": "app"}`, appID)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.CreateApplicationWithAppID(context.Background(), "app", appID)
+	if err != nil {
+		t.Fatalf("CreateApplicationWithAppID() error = %v", err)
+	}
+	if got := body["appId"]; got != appID {
+		t.Errorf("expected appId to be %s in the request body, got %v", appID, got)
+	}
+	if got := body["displayName"]; got != "app" {
+		t.Errorf("expected displayName to be app in the request body, got %v", got)
+	}
+	if got := to.String(app.GetAppId()); got != appID {
+		t.Errorf("expected app ID to be %s, got %s", appID, got)
+	}
+}
+
+func TestCreateApplicationWithTags(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "object-id", "displayName": "app", "tags": ["azwi", "owner:team-a"]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.CreateApplicationWithTags(context.Background(), "app", []string{"azwi", "owner:team-a"})
+	if err != nil {
+		t.Fatalf("CreateApplicationWithTags() error = %v", err)
+	}
+	if got, want := body["tags"], []interface{}{"azwi", "owner:team-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected tags to be %v in the request body, got %v", want, got)
+	}
+	if got := body["displayName"]; got != "app" {
+		t.Errorf("expected displayName to be app in the request body, got %v", got)
+	}
+	if got := app.GetTags(); !reflect.DeepEqual(got, []string{"azwi", "owner:team-a"}) {
+		t.Errorf("expected tags of the created application to be [azwi owner:team-a], got %v", got)
+	}
+}
+
+func TestCreateApplicationAndGetAppID(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     string
+		wantAppID    string
+		wantObjectID string
+		wantErr      bool
+	}{
+		{
+			name:         "created",
+			response:     `{"id": "object-id", "appId": "app-id", "displayName": "app"}`,
+			wantAppID:    "app-id",
+			wantObjectID: "object-id",
+		},
+		{
+			name:     "no app ID",
+			response: `{"id": "object-id", "displayName": "app"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "no object ID",
+			response: `{"appId": "app-id", "displayName": "app"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, test.response)
+			})
+			c := newTestAzureClient(t, mux)
+
+			appID, objectID, err := c.CreateApplicationAndGetAppID(context.Background(), "app")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("CreateApplicationAndGetAppID() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if appID != test.wantAppID || objectID != test.wantObjectID {
+				t.Errorf("CreateApplicationAndGetAppID() = %q, %q, want %q, %q", appID, objectID, test.wantAppID, test.wantObjectID)
+			}
+		})
+	}
+}
+
+func TestCreateServicePrincipalWithOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ServicePrincipalOptions
+		want map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			want: map[string]interface{}{"appId": "app-id"},
+		},
+		{
+			name: "disabled",
+			opts: ServicePrincipalOptions{AccountEnabled: to.BoolPtr(false)},
+			want: map[string]interface{}{"appId": "app-id", "accountEnabled": false},
+		},
+		{
+			name: "app role assignment required",
+			opts: ServicePrincipalOptions{AppRoleAssignmentRequired: to.BoolPtr(true)},
+			want: map[string]interface{}{"appId": "app-id", "appRoleAssignmentRequired": true},
+		},
+		{
+			name: "preferred single sign-on mode",
+			opts: ServicePrincipalOptions{PreferredSingleSignOnMode: "oidc"},
+			want: map[string]interface{}{"appId": "app-id", "preferredSingleSignOnMode": "oidc"},
+		},
+		{
+			name: "all",
+			opts: ServicePrincipalOptions{Tags: []string{"azwi"}, AccountEnabled: to.BoolPtr(true), AppRoleAssignmentRequired: to.BoolPtr(false), PreferredSingleSignOnMode: "saml"},
+			want: map[string]interface{}{"appId": "app-id", "tags": []interface{}{"azwi"}, "accountEnabled": true, "appRoleAssignmentRequired": false, "preferredSingleSignOnMode": "saml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body map[string]interface{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id": "sp-object-id", "appId": "app-id"}`)
+			})
+			c := newTestAzureClient(t, mux)
+
+			if _, err := c.CreateServicePrincipalWithOptions(context.Background(), "app-id", test.opts); err != nil {
+				t.Fatalf("CreateServicePrincipalWithOptions() error = %v", err)
+			}
+			// the properties that are not set are not sent, so that they keep the defaults of Graph
+			for _, property := range []string{"appId", "tags", "accountEnabled", "appRoleAssignmentRequired", "preferredSingleSignOnMode"} {
+				want, wantOK := test.want[property]
+				got, ok := body[property]
+				if ok != wantOK || !reflect.DeepEqual(got, want) {
+					t.Errorf("expected %s to be %v in the request body, got %v", property, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateApplicationWithAppIDError(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"code": "Request_BadRequest", "message": "Property 'appId' is read-only and cannot be set."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	_, err := c.CreateApplicationWithAppID(context.Background(), "app", "00000000-0000-0000-0000-000000000001")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"the tenant may not allow choosing the app ID", "Request_BadRequest", "Property 'appId' is read-only"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+
+	// an invalid app ID is rejected before the request is sent
+	if _, err := c.CreateApplicationWithAppID(context.Background(), "app", "not-a-uuid"); err == nil {
+		t.Error("expected an error for an invalid app ID")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestCreateApplicationInsufficientPrivileges(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	_, err := c.CreateApplication(context.Background(), "app")
+	if !errors.Is(err, ErrInsufficientPrivileges) {
+		t.Fatalf("expected an insufficient privileges error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Application.ReadWrite.OwnedBy") {
+		t.Errorf("expected the error to name the permission to grant, got %v", err)
+	}
+}
+
+func TestCloneApplication(t *testing.T) {
+	var created, patched map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/source-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "source-object-id",
+			"appId": "source-app-id",
+			"displayName": "source",
+			"identifierUris": ["api://source-app-id", "https://contoso.com/api"],
+			"signInAudience": "AzureADMyOrg",
+			"groupMembershipClaims": "SecurityGroup",
+			"requiredResourceAccess": [{"resourceAppId": "00000003-0000-0000-c000-000000000000", "resourceAccess": [{"id": "e1fe6dd8-ba31-4d61-89e7-88639da4683d", "type": "Scope"}]}],
+			"optionalClaims": {"idToken": [{"name": "groups", "essential": false, "additionalProperties": []}]},
+			"passwordCredentials": [{"keyId": "f8a2d5b4-6a3c-4b8e-9d1f-2c7e5a9b3d6f", "displayName": "secret"}],
+			"keyCredentials": [{"keyId": "a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d", "type": "AsymmetricX509Cert"}]
+		}`)
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "new-object-id", "appId": "new-app-id", "displayName": "clone"}`)
+	})
+	mux.HandleFunc("/v1.0/applications/new-object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&patched); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.CloneApplication(context.Background(), "source-object-id", "clone")
+	if err != nil {
+		t.Fatalf("CloneApplication() error = %v", err)
+	}
+	if to.String(app.GetId()) != "new-object-id" {
+		t.Errorf("expected the new application to be returned, got %s", to.String(app.GetId()))
+	}
+
+	for _, field := range []string{"id", "appId", "identifierUris", "passwordCredentials", "keyCredentials"} {
+		if _, ok := created[field]; ok {
+			t.Errorf("expected %s not to be copied, got %v", field, created[field])
+		}
+	}
+	if created["displayName"] != "clone" {
+		t.Errorf("displayName = %v, want clone", created["displayName"])
+	}
+	if created["signInAudience"] != "AzureADMyOrg" || created["groupMembershipClaims"] != "SecurityGroup" {
+		t.Errorf("expected signInAudience and groupMembershipClaims to be copied, got %v and %v", created["signInAudience"], created["groupMembershipClaims"])
+	}
+	if _, ok := created["optionalClaims"]; !ok {
+		t.Errorf("expected optionalClaims to be copied")
+	}
+	wantAccess := []interface{}{
+		map[string]interface{}{
+			"resourceAppId": "00000003-0000-0000-c000-000000000000",
+			"resourceAccess": []interface{}{
+				map[string]interface{}{"id": "e1fe6dd8-ba31-4d61-89e7-88639da4683d", "type": "Scope"},
+			},
+		},
+	}
+	if got := created["requiredResourceAccess"]; !reflect.DeepEqual(got, wantAccess) {
+		t.Errorf("requiredResourceAccess = %v, want %v", got, wantAccess)
+	}
+	if got, want := patched["identifierUris"], []interface{}{"api://new-app-id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("identifierUris = %v, want %v", got, want)
+	}
+}
+
+func TestCloneApplicationInsufficientPrivileges(t *testing.T) {
+	forbidden := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	}
+	tests := []struct {
+		name   string
+		get    http.HandlerFunc
+		create http.HandlerFunc
+	}{
+		{
+			name: "get source application",
+			get:  forbidden,
+		},
+		{
+			name: "create application",
+			get: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"id": "source-object-id", "appId": "source-app-id", "displayName": "source"}`)
+			},
+			create: forbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/source-object-id", test.get)
+			if test.create != nil {
+				mux.HandleFunc("/v1.0/applications", test.create)
+			}
+			c := newTestAzureClient(t, mux)
+
+			_, err := c.CloneApplication(context.Background(), "source-object-id", "clone")
+			if !errors.Is(err, ErrInsufficientPrivileges) {
+				t.Fatalf("expected an insufficient privileges error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "Application.ReadWrite.OwnedBy") {
+				t.Errorf("expected the error to name the permission to grant, got %v", err)
+			}
+		})
+	}
+}
+
+func TestListFederatedCredentials(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"name": "fic-1"}, {"name": "fic-2"}], "@odata.nextLink": "http://%s/v1.0/applications/object-id/federatedIdentityCredentials?$skiptoken=page-2"}`, r.Host)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"name": "fic-3"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fics, err := c.ListFederatedCredentials(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("ListFederatedCredentials() error = %v", err)
+	}
+	var names []string
+	for _, fic := range fics {
+		names = append(names, *fic.GetName())
+	}
+	if want := []string{"fic-1", "fic-2", "fic-3"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListFederatedCredentials() = %v, want %v", names, want)
+	}
+}
+
+func TestListFederatedCredentialsGraphErrorInPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"name": "fic-1"}], "@odata.nextLink": "http://%s/v1.0/applications/object-id/federatedIdentityCredentials?$skiptoken=page-2"}`, r.Host)
+			return
+		}
+		// the error of a page can be returned in the body of a successful response
+		fmt.Fprint(w, `{"value": [], "error": {"code": "Request_BadRequest", "message": "Invalid skip token."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	_, err := c.ListFederatedCredentials(context.Background(), "object-id")
+	gerr := GraphError{}
+	if !errors.As(err, &gerr) {
+		t.Fatalf("ListFederatedCredentials() error = %v, want the Graph error of the second page", err)
+	}
+	if got := to.String(gerr.PublicError.GetCode()); got != "Request_BadRequest" {
+		t.Errorf("expected the error code to be Request_BadRequest, got %s", got)
+	}
+}
+
+func TestListPageSize(t *testing.T) {
+	var tops []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		tops = append(tops, r.URL.Query().Get("$top"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			// the next link carries the query parameters of the first request
+			query := url.Values{"$skiptoken": []string{"page-2"}}
+			if top := r.URL.Query().Get("$top"); top != "" {
+				query.Set("$top", top)
+			}
+			fmt.Fprintf(w, `{"value": [{"name": "fic-1"}], "@odata.nextLink": "http://%s/v1.0/applications/object-id/federatedIdentityCredentials?%s"}`, r.Host, query.Encode())
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"name": "fic-2"}]}`)
+	})
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		tops = append(tops, r.URL.Query().Get("$top"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		tops = append(tops, r.URL.Query().Get("$top"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if _, err := c.ListFederatedCredentials(context.Background(), "object-id"); err != nil {
+		t.Fatalf("ListFederatedCredentials() error = %v", err)
+	}
+	if want := []string{"", ""}; !reflect.DeepEqual(tops, want) {
+		t.Errorf("expected no $top by default, got %v", tops)
+	}
+
+	if err := c.SetPageSize(1); err != nil {
+		t.Fatalf("SetPageSize() error = %v", err)
+	}
+	tops = nil
+	fics, err := c.ListFederatedCredentials(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("ListFederatedCredentials() error = %v", err)
+	}
+	if len(fics) != 2 {
+		t.Errorf("expected the page size not to limit the number of federated credentials, got %d", len(fics))
+	}
+	if _, err := c.ListServicePrincipalsByTag(context.Background(), "azwi"); err != nil {
+		t.Fatalf("ListServicePrincipalsByTag() error = %v", err)
+	}
+	if _, err := c.NewApplicationPager(context.Background(), "").Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if want := []string{"1", "1", "1", "1"}; !reflect.DeepEqual(tops, want) {
+		t.Errorf("expected $top to be %v, got %v", want, tops)
+	}
+}
+
+func TestSetPageSize(t *testing.T) {
+	c := &AzureClient{}
+	for _, size := range []int{0, 1, 999} {
+		if err := c.SetPageSize(size); err != nil {
+			t.Errorf("SetPageSize(%d) error = %v", size, err)
+		}
+	}
+	for _, size := range []int{-1, 1000} {
+		if err := c.SetPageSize(size); err == nil {
+			t.Errorf("SetPageSize(%d) expected an error", size)
+		}
+	}
+}
+
+func TestListFederatedCredentialsPartialResults(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		next := "http://" + r.Host + "/v1.0/applications/object-id/federatedIdentityCredentials?$skiptoken="
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$skiptoken") {
+		case "":
+			fmt.Fprintf(w, `{"value": [{"name": "fic-1"}, {"name": "fic-2"}], "@odata.nextLink": "%spage-2"}`, next)
+		case "page-2":
+			fmt.Fprintf(w, `{"value": [{"name": "fic-3"}], "@odata.nextLink": "%spage-3"}`, next)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		partial   bool
+		wantNames []string
+	}{
+		{
+			name: "no items by default",
+		},
+		{
+			name:      "items listed before the failed page",
+			partial:   true,
+			wantNames: []string{"fic-1", "fic-2", "fic-3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestAzureClient(t, mux)
+			c.SetPartialResults(tt.partial)
+
+			fics, err := c.ListFederatedCredentials(context.Background(), "object-id")
+			if err == nil {
+				t.Fatalf("expected the error of the third page")
+			}
+			var names []string
+			for _, fic := range fics {
+				names = append(names, *fic.GetName())
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("ListFederatedCredentials() = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestGetEnabledServicePrincipal(t *testing.T) {
+	var filters []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		filters = append(filters, r.URL.Query().Get("$filter"))
+		w.Header().Set("Content-Type", "application/json")
+		// the filter is ignored, so the disabled service principal is excluded by the client
+		fmt.Fprint(w, `{"value": [{"id": "disabled-sp-id", "displayName": "sp", "accountEnabled": false}, {"id": "enabled-sp-id", "displayName": "sp", "accountEnabled": true}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	sp, err := c.GetEnabledServicePrincipal(context.Background(), "sp")
+	if err != nil {
+		t.Fatalf("GetEnabledServicePrincipal() error = %v", err)
+	}
+	if got := to.String(sp.GetId()); got != "enabled-sp-id" {
+		t.Errorf("GetEnabledServicePrincipal() = %s, want enabled-sp-id", got)
+	}
+
+	// the default lookup still returns the disabled service principals
+	if _, err := c.GetServicePrincipal(context.Background(), "sp"); !errors.Is(err, ErrMultipleMatches) {
+		t.Errorf("GetServicePrincipal() error = %v, want %v", err, ErrMultipleMatches)
+	}
+
+	want := []string{"displayName eq 'sp' and accountEnabled eq true", "displayName eq 'sp'"}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("expected the filters to be %q, got %q", want, filters)
+	}
+}
+
+func TestGetEnabledServicePrincipalNotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "disabled-sp-id", "displayName": "sp", "accountEnabled": false}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if _, err := c.GetEnabledServicePrincipal(context.Background(), "sp"); err == nil || err.Error() != "enabled service principal sp not found" {
+		t.Errorf("GetEnabledServicePrincipal() error = %v, want enabled service principal sp not found", err)
+	}
+}
+
+func TestGetServicePrincipalByAppID(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$filter") == getAppIDFilter("client-id") {
+			fmt.Fprint(w, `{"value": [{"id": "sp-object-id", "appId": "client-id"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	sp, err := c.GetServicePrincipalByAppID(context.Background(), "client-id")
+	if err != nil {
+		t.Fatalf("GetServicePrincipalByAppID() error = %v", err)
+	}
+	if got := to.String(sp.GetId()); got != "sp-object-id" {
+		t.Errorf("expected service principal object ID to be sp-object-id, got %s", got)
+	}
+
+	_, err = c.GetServicePrincipalByAppID(context.Background(), "unknown")
+	if err == nil || !IsNotFound(err) {
+		t.Errorf("GetServicePrincipalByAppID() error = %v, want not found error", err)
+	}
+}
+
+func TestGetApplicationByAppID(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$filter") == getAppIDFilter("client-id") {
+			fmt.Fprint(w, `{"value": [{"id": "app-object-id", "appId": "client-id"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.GetApplicationByAppID(context.Background(), "client-id")
+	if err != nil {
+		t.Fatalf("GetApplicationByAppID() error = %v", err)
+	}
+	if got := to.String(app.GetId()); got != "app-object-id" {
+		t.Errorf("expected application object ID to be app-object-id, got %s", got)
+	}
+
+	_, err = c.GetApplicationByAppID(context.Background(), "unknown")
+	if err == nil || !IsNotFound(err) {
+		t.Errorf("GetApplicationByAppID() error = %v, want not found error", err)
+	}
+}
+
+// newNavigationTestMux returns a mux serving the application app-object-id and its service principal sp-object-id
+// of app ID client-id, and the service principal mi-sp-object-id of a managed identity, which has no application.
+func newNavigationTestMux() *http.ServeMux {
+	notFound := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": "Request_ResourceNotFound", "message": "Resource does not exist."}}`)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/v1.0/servicePrincipals/") {
+		case "sp-object-id":
+			fmt.Fprint(w, `{"id": "sp-object-id", "appId": "client-id"}`)
+		case "mi-sp-object-id":
+			fmt.Fprint(w, `{"id": "mi-sp-object-id", "appId": "mi-client-id"}`)
+		default:
+			notFound(w)
+		}
+	})
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$filter") {
+		case getAppIDFilter("client-id"):
+			fmt.Fprint(w, `{"value": [{"id": "sp-object-id", "appId": "client-id"}]}`)
+		default:
+			fmt.Fprint(w, `{"value": []}`)
+		}
+	})
+	mux.HandleFunc("/v1.0/applications/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/v1.0/applications/") {
+		case "app-object-id":
+			fmt.Fprint(w, `{"id": "app-object-id", "appId": "client-id"}`)
+		case "app-without-sp-object-id":
+			fmt.Fprint(w, `{"id": "app-without-sp-object-id", "appId": "other-client-id"}`)
+		default:
+			notFound(w)
+		}
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$filter") {
+		case getAppIDFilter("client-id"):
+			fmt.Fprint(w, `{"value": [{"id": "app-object-id", "appId": "client-id"}]}`)
+		default:
+			fmt.Fprint(w, `{"value": []}`)
+		}
+	})
+	return mux
+}
+
+func TestWaitForServicePrincipal(t *testing.T) {
+	var lookups int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// the service principal is not found until it has propagated
+		if atomic.AddInt32(&lookups, 1) < 3 {
+			fmt.Fprint(w, `{"value": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "sp-object-id", "appId": "app-id"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.servicePrincipalPollInterval = time.Millisecond
+
+	sp, err := c.WaitForServicePrincipal(context.Background(), "app-id", time.Minute)
+	if err != nil {
+		t.Fatalf("WaitForServicePrincipal() error = %v", err)
+	}
+	if got := to.String(sp.GetId()); got != "sp-object-id" {
+		t.Errorf("WaitForServicePrincipal() = %s, want sp-object-id", got)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 3 {
+		t.Errorf("expected 3 lookups, got %d", got)
+	}
+}
+
+func TestWaitForServicePrincipalTimeout(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	}))
+	c.servicePrincipalPollInterval = time.Millisecond
+
+	_, err := c.WaitForServicePrincipal(context.Background(), "app-id", 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForServicePrincipal() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDeleteApplicationAndWait(t *testing.T) {
+	var deletes, gets int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			atomic.AddInt32(&deletes, 1)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			// the application is still returned until the deletion has propagated
+			if atomic.AddInt32(&gets, 1) <= 2 {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"id": "object-id"}`)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": "Request_ResourceNotFound", "message": "Resource 'object-id' does not exist."}}`)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+	c.applicationDeletionPollInterval = time.Millisecond
+
+	if err := c.DeleteApplicationAndWait(context.Background(), "object-id", time.Minute); err != nil {
+		t.Fatalf("DeleteApplicationAndWait() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&deletes); got != 1 {
+		t.Errorf("expected 1 delete, got %d", got)
+	}
+	if got := atomic.LoadInt32(&gets); got != 3 {
+		t.Errorf("expected 3 gets, got %d", got)
+	}
+}
+
+func TestDeleteApplicationAndWaitTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "object-id"}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.applicationDeletionPollInterval = time.Millisecond
+
+	err := c.DeleteApplicationAndWait(context.Background(), "object-id", 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeleteApplicationAndWait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGetServicePrincipalSignInActivity(t *testing.T) {
+	lastSignIn := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		activity string
+		want     *time.Time
+	}{
+		{
+			name:     "signed in",
+			activity: `{"value": [{"id": "activity-id", "appId": "app-id", "lastSignInActivity": {"lastSignInDateTime": "2023-03-01T12:00:00Z"}}]}`,
+			want:     &lastSignIn,
+		},
+		{
+			name:     "never signed in",
+			activity: `{"value": []}`,
+		},
+		{
+			name:     "no last sign-in",
+			activity: `{"value": [{"id": "activity-id", "appId": "app-id", "lastSignInActivity": null}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"id": "sp-object-id", "appId": "app-id"}`)
+			})
+			mux.HandleFunc("/beta/reports/servicePrincipalSignInActivities", func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("$filter"); got != "appId eq 'app-id'" {
+					t.Errorf("expected $filter to be appId eq 'app-id', got %q", got)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, test.activity)
+			})
+			c := newTestAzureClient(t, mux)
+
+			got, err := c.GetServicePrincipalSignInActivity(context.Background(), "sp-object-id")
+			if err != nil {
+				t.Fatalf("GetServicePrincipalSignInActivity() error = %v", err)
+			}
+			if (got == nil) != (test.want == nil) || (got != nil && !got.Equal(*test.want)) {
+				t.Errorf("GetServicePrincipalSignInActivity() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetServicePrincipalSignInActivityInsufficientPrivileges(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "sp-object-id", "appId": "app-id"}`)
+	})
+	mux.HandleFunc("/beta/reports/servicePrincipalSignInActivities", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	_, err := c.GetServicePrincipalSignInActivity(context.Background(), "sp-object-id")
+	perr := &InsufficientPrivilegesError{}
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected an InsufficientPrivilegesError, got %v", err)
+	}
+	if perr.RequiredPermission != "AuditLog.Read.All" {
+		t.Errorf("expected the required permission to be AuditLog.Read.All, got %s", perr.RequiredPermission)
+	}
+}
+
+func TestGetBetaBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"https://graph.microsoft.com/v1.0", "https://graph.microsoft.com/v1.0/"} {
+		if got := getBetaBaseURL(baseURL); got != "https://graph.microsoft.com/beta" {
+			t.Errorf("getBetaBaseURL(%q) = %q, want https://graph.microsoft.com/beta", baseURL, got)
+		}
+	}
+}
+
+func TestGetApplicationForServicePrincipal(t *testing.T) {
+	c := newTestAzureClient(t, newNavigationTestMux())
+
+	app, err := c.GetApplicationForServicePrincipal(context.Background(), "sp-object-id")
+	if err != nil {
+		t.Fatalf("GetApplicationForServicePrincipal() error = %v", err)
+	}
+	if got := to.String(app.GetId()); got != "app-object-id" {
+		t.Errorf("expected application object ID to be app-object-id, got %s", got)
+	}
+
+	for _, objectID := range []string{"mi-sp-object-id", "unknown"} {
+		_, err = c.GetApplicationForServicePrincipal(context.Background(), objectID)
+		if err == nil || !IsNotFound(err) {
+			t.Errorf("GetApplicationForServicePrincipal(%s) error = %v, want not found error", objectID, err)
+		}
+	}
+}
+
+func TestGetServicePrincipalForApplication(t *testing.T) {
+	c := newTestAzureClient(t, newNavigationTestMux())
+
+	sp, err := c.GetServicePrincipalForApplication(context.Background(), "app-object-id")
+	if err != nil {
+		t.Fatalf("GetServicePrincipalForApplication() error = %v", err)
+	}
+	if got := to.String(sp.GetId()); got != "sp-object-id" {
+		t.Errorf("expected service principal object ID to be sp-object-id, got %s", got)
+	}
+
+	for _, objectID := range []string{"app-without-sp-object-id", "unknown"} {
+		_, err = c.GetServicePrincipalForApplication(context.Background(), objectID)
+		if err == nil || !IsNotFound(err) {
+			t.Errorf("GetServicePrincipalForApplication(%s) error = %v, want not found error", objectID, err)
+		}
+	}
+}
+
+func TestGetServicePrincipalOwningOrganization(t *testing.T) {
+	const (
+		tenantID        = "11111111-1111-1111-1111-111111111111"
+		foreignTenantID = "22222222-2222-2222-2222-222222222222"
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$filter") {
+		case getDisplayNameFilter("mixed"):
+			fmt.Fprintf(w, `{"value": [{"id": "foreign-sp", "appOwnerOrganizationId": "%s"}, {"id": "own-sp", "appOwnerOrganizationId": "%s"}]}`, foreignTenantID, tenantID)
+		case getDisplayNameFilter("foreign"):
+			fmt.Fprintf(w, `{"value": [{"id": "foreign-sp", "appOwnerOrganizationId": "%s"}]}`, foreignTenantID)
+		default:
+			fmt.Fprint(w, `{"value": []}`)
+		}
+	})
+
+	tests := []struct {
+		name              string
+		tenantID          string
+		allowForeign      bool
+		displayName       string
+		wantID            string
+		wantNotFound      bool
+		wantMultipleMatch bool
+	}{
+		{
+			name:        "own service principal is preferred",
+			tenantID:    tenantID,
+			displayName: "mixed",
+			wantID:      "own-sp",
+		},
+		{
+			name:         "only foreign service principals",
+			tenantID:     tenantID,
+			displayName:  "foreign",
+			wantNotFound: true,
+		},
+		{
+			name:         "foreign service principals allowed",
+			tenantID:     tenantID,
+			allowForeign: true,
+			displayName:  "foreign",
+			wantID:       "foreign-sp",
+		},
+		{
+			// without a tenant ID, the service principals of other organizations can't be told apart
+			name:              "no tenant ID",
+			displayName:       "mixed",
+			wantMultipleMatch: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestAzureClient(t, mux)
+			c.tenantID = test.tenantID
+			c.SetAllowForeignServicePrincipals(test.allowForeign)
+
+			sp, err := c.GetServicePrincipal(context.Background(), test.displayName)
+			if test.wantNotFound {
+				if err == nil || !IsNotFound(err) {
+					t.Fatalf("GetServicePrincipal() error = %v, want not found error", err)
+				}
+				return
+			}
+			if test.wantMultipleMatch {
+				if !errors.Is(err, ErrMultipleMatches) {
+					t.Fatalf("GetServicePrincipal() error = %v, want multiple matches error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetServicePrincipal() error = %v", err)
+			}
+			if got := to.String(sp.GetId()); got != test.wantID {
+				t.Errorf("expected service principal object ID to be %s, got %s", test.wantID, got)
+			}
+		})
+	}
+}
+
+func TestGetByDisplayNamePaginated(t *testing.T) {
+	// Graph can return an empty first page and the matches on later pages
+	pages := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		displayName := strings.TrimSuffix(strings.TrimPrefix(r.URL.Query().Get("$filter"), "displayName eq '"), "'")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [], "@odata.nextLink": "http://%s%s?$filter=%s&$skiptoken=page-2"}`, r.Host, r.URL.Path, url.QueryEscape(getDisplayNameFilter(displayName)))
+			return
+		}
+		switch displayName {
+		case "unique":
+			fmt.Fprint(w, `{"value": [{"id": "object-id-1", "displayName": "unique"}]}`)
+		case "duplicate":
+			fmt.Fprint(w, `{"value": [{"id": "object-id-1", "appId": "app-id-1", "displayName": "duplicate"}, {"id": "object-id-2", "appId": "app-id-2", "displayName": "duplicate"}]}`)
+		default:
+			fmt.Fprint(w, `{"value": []}`)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", pages)
+	mux.HandleFunc("/v1.0/servicePrincipals", pages)
+	c := newTestAzureClient(t, mux)
+
+	app, err := c.GetApplication(context.Background(), "unique")
+	if err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if got := to.String(app.GetId()); got != "object-id-1" {
+		t.Errorf("expected application object ID to be object-id-1, got %s", got)
+	}
+	sp, err := c.GetServicePrincipal(context.Background(), "unique")
+	if err != nil {
+		t.Fatalf("GetServicePrincipal() error = %v", err)
+	}
+	if got := to.String(sp.GetId()); got != "object-id-1" {
+		t.Errorf("expected service principal object ID to be object-id-1, got %s", got)
+	}
+
+	if _, err := c.GetApplication(context.Background(), "missing"); err == nil || !IsNotFound(err) {
+		t.Errorf("GetApplication() error = %v, want not found error", err)
+	}
+
+	_, appErr := c.GetApplication(context.Background(), "duplicate")
+	_, spErr := c.GetServicePrincipal(context.Background(), "duplicate")
+	for _, err := range []error{appErr, spErr} {
+		var merr *MultipleMatchesError
+		if !errors.As(err, &merr) || !errors.Is(err, ErrMultipleMatches) {
+			t.Fatalf("expected a multiple matches error, got %v", err)
+		}
+		if want := []string{"object-id-1", "object-id-2"}; !reflect.DeepEqual(merr.ObjectIDs, want) {
+			t.Errorf("expected the object IDs to be %v, got %v", want, merr.ObjectIDs)
+		}
+		if want := []string{"app-id-1", "app-id-2"}; !reflect.DeepEqual(merr.AppIDs, want) {
+			t.Errorf("expected the app IDs to be %v, got %v", want, merr.AppIDs)
+		}
+		if !strings.Contains(err.Error(), "app IDs: app-id-1, app-id-2") {
+			t.Errorf("expected the error to list the app IDs, got %v", err)
+		}
+		if IsNotFound(err) {
+			t.Errorf("expected the multiple matches error not to be a not found error, got %v", err)
+		}
+	}
+}
+
+func TestGetServicePrincipalType(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$select"); got != "id,servicePrincipalType" {
+			t.Errorf("expected $select to be id,servicePrincipalType, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "sp-object-id", "servicePrincipalType": "ManagedIdentity"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	spType, err := c.GetServicePrincipalType(context.Background(), "sp-object-id")
+	if err != nil {
+		t.Fatalf("GetServicePrincipalType() error = %v", err)
+	}
+	if spType != "ManagedIdentity" {
+		t.Errorf("expected service principal type to be ManagedIdentity, got %s", spType)
+	}
+}
+
+func TestListServicePrincipalsByTag(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("ConsistencyLevel"); got != "eventual" {
+			t.Errorf("expected ConsistencyLevel header to be eventual, got %q", got)
+		}
+		if got := r.URL.Query().Get("$filter"); got != "tags/any(t:t eq 'azwi')" {
+			t.Errorf("expected $filter to be tags/any(t:t eq 'azwi'), got %q", got)
+		}
+		if got := r.URL.Query().Get("$count"); got != "true" {
+			t.Errorf("expected $count to be true, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"displayName": "sp-1"}], "@odata.nextLink": "http://%s/v1.0/servicePrincipals?$filter=tags%%2Fany%%28t%%3At%%20eq%%20%%27azwi%%27%%29&$count=true&$skiptoken=page-2"}`, r.Host)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"displayName": "sp-2"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	sps, err := c.ListServicePrincipalsByTag(context.Background(), "azwi")
+	if err != nil {
+		t.Fatalf("ListServicePrincipalsByTag() error = %v", err)
+	}
+	var names []string
+	for _, sp := range sps {
+		names = append(names, *sp.GetDisplayName())
+	}
+	if want := []string{"sp-1", "sp-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListServicePrincipalsByTag() = %v, want %v", names, want)
+	}
+}
+
+func TestListApplicationsByTag(t *testing.T) {
+	const wantFilter = "tags/any(t:t eq 'team''s')"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("ConsistencyLevel"); got != "eventual" {
+			t.Errorf("expected ConsistencyLevel header to be eventual, got %q", got)
+		}
+		if got := r.URL.Query().Get("$filter"); got != wantFilter {
+			t.Errorf("expected $filter to be %s, got %q", wantFilter, got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"displayName": "app-1"}], "@odata.nextLink": "http://%s/v1.0/applications?$filter=%s&$count=true&$skiptoken=page-2"}`, r.Host, url.QueryEscape(wantFilter))
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"displayName": "app-2"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	apps, err := c.ListApplicationsByTag(context.Background(), "team's")
+	if err != nil {
+		t.Fatalf("ListApplicationsByTag() error = %v", err)
+	}
+	var names []string
+	for _, app := range apps {
+		names = append(names, *app.GetDisplayName())
+	}
+	if want := []string{"app-1", "app-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListApplicationsByTag() = %v, want %v", names, want)
+	}
+
+	if _, err := c.ListApplicationsByTag(context.Background(), ""); err == nil {
+		t.Error("ListApplicationsByTag() expected error for an empty tag")
+	}
+}
+
+func TestFindOrphanedServicePrincipals(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"displayName": "sp-1", "appId": "app-id-1"}, {"displayName": "sp-2", "appId": "app-id-2"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// the application of sp-2 was deleted
+		if r.URL.Query().Get("$filter") == getAppIDFilter("app-id-1") {
+			fmt.Fprint(w, `{"value": [{"id": "object-id-1", "appId": "app-id-1"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	sps, err := c.FindOrphanedServicePrincipals(context.Background(), "azwi")
+	if err != nil {
+		t.Fatalf("FindOrphanedServicePrincipals() error = %v", err)
+	}
+	var names []string
+	for _, sp := range sps {
+		names = append(names, *sp.GetDisplayName())
+	}
+	if want := []string{"sp-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("FindOrphanedServicePrincipals() = %v, want %v", names, want)
+	}
+}
+
+func TestFindOrphanedServicePrincipalsError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"displayName": "sp-1", "appId": "app-id-1"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	// an application that can't be looked up is not reported as orphaned
+	if sps, err := c.FindOrphanedServicePrincipals(context.Background(), "azwi"); err == nil {
+		t.Errorf("expected an error, got %d service principals", len(sps))
+	}
+}
+
+func TestSetApplicationTokenClaims(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	claim := models.NewOptionalClaim()
+	claim.SetName(to.StringPtr("groups"))
+	optional := models.NewOptionalClaims()
+	optional.SetIdToken([]models.OptionalClaimable{claim})
+
+	if err := c.SetApplicationTokenClaims(context.Background(), "object-id", "SecurityGroup", optional); err != nil {
+		t.Fatalf("SetApplicationTokenClaims() error = %v", err)
+	}
+	if got := body["groupMembershipClaims"]; got != "SecurityGroup" {
+		t.Errorf("expected groupMembershipClaims to be SecurityGroup, got %v", got)
+	}
+	idToken, _ := body["optionalClaims"].(map[string]interface{})["idToken"].([]interface{})
+	if len(idToken) != 1 || idToken[0].(map[string]interface{})["name"] != "groups" {
+		t.Errorf("expected optionalClaims.idToken to contain the groups claim, got %v", body["optionalClaims"])
+	}
+}
+
+func TestSetApplicationTokenClaimsInvalidGroupMembershipClaims(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	err := c.SetApplicationTokenClaims(context.Background(), "object-id", "Everything", nil)
+	if err == nil || !strings.Contains(err.Error(), `invalid group membership claims "Everything"`) {
+		t.Errorf("SetApplicationTokenClaims() error = %v, want invalid group membership claims error", err)
+	}
+}
+
+func TestGetApplicationSignInAudience(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$select"); got != "id,signInAudience" {
+			t.Errorf("expected $select to be id,signInAudience, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "object-id", "signInAudience": "AzureADMyOrg"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	audience, err := c.GetApplicationSignInAudience(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("GetApplicationSignInAudience() error = %v", err)
+	}
+	if audience != "AzureADMyOrg" {
+		t.Errorf("GetApplicationSignInAudience() = %s, want AzureADMyOrg", audience)
+	}
+}
+
+func TestSetApplicationSignInAudience(t *testing.T) {
+	for _, audience := range signInAudienceValues {
+		t.Run(audience, func(t *testing.T) {
+			var body map[string]interface{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch {
+					t.Errorf("expected PATCH request, got %s", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+			c := newTestAzureClient(t, mux)
+
+			if err := c.SetApplicationSignInAudience(context.Background(), "object-id", audience); err != nil {
+				t.Fatalf("SetApplicationSignInAudience() error = %v", err)
+			}
+			if got := body["signInAudience"]; got != audience {
+				t.Errorf("expected signInAudience to be %s, got %v", audience, got)
+			}
+		})
+	}
+}
+
+func TestSetApplicationSignInAudienceInvalid(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	for _, audience := range []string{"", "azureadmyorg", "AzureADMultipleOrgs "} {
+		err := c.SetApplicationSignInAudience(context.Background(), "object-id", audience)
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("invalid sign-in audience %q", audience)) {
+			t.Errorf("SetApplicationSignInAudience(%q) error = %v, want invalid sign-in audience error", audience, err)
+		}
+	}
+}
+
+func TestSetApplicationSignInAudienceGraphError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"code": "Request_BadRequest", "message": "The application must have a verified publisher."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	err := c.SetApplicationSignInAudience(context.Background(), "object-id", "AzureADMultipleOrgs")
+	want := "failed to set sign-in audience of application object-id to AzureADMultipleOrgs: code: Request_BadRequest, message: The application must have a verified publisher."
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("SetApplicationSignInAudience() error = %v, want %s", err, want)
+	}
+}
+
+func TestApplicationsDelta(t *testing.T) {
+	var rounds []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/delta()", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		rounds = append(rounds, r.URL.RawQuery)
+		base := "http://" + r.Host + "/v1.0/applications/delta()"
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case query.Get("$skiptoken") == "page-2":
+			fmt.Fprintf(w, `{"value": [{"id": "app-2", "displayName": "app-2"}], "@odata.deltaLink": "%s?$deltatoken=round-2"}`, base)
+		case query.Get("$deltatoken") == "round-2":
+			fmt.Fprintf(w, `{"value": [{"id": "app-1", "displayName": "app-1-renamed"}, {"id": "app-2", "@removed": {"reason": "changed"}}], "@odata.deltaLink": "%s?$deltatoken=round-3"}`, base)
+		case r.URL.RawQuery == "":
+			fmt.Fprintf(w, `{"value": [{"id": "app-1", "displayName": "app-1"}], "@odata.nextLink": "%s?$skiptoken=page-2"}`, base)
+		default:
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	// the initial round returns all the applications across the pages
+	apps, deltaLink, err := c.ApplicationsDelta(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ApplicationsDelta() error = %v", err)
+	}
+	var ids []string
+	for _, app := range apps {
+		ids = append(ids, to.String(app.GetId()))
+	}
+	if want := []string{"app-1", "app-2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ApplicationsDelta() = %v, want %v", ids, want)
+	}
+	if !strings.HasSuffix(deltaLink, "$deltatoken=round-2") {
+		t.Fatalf("expected the delta link of round 2, got %q", deltaLink)
+	}
+
+	// the next round only returns the changed applications
+	apps, deltaLink, err = c.ApplicationsDelta(context.Background(), deltaLink)
+	if err != nil {
+		t.Fatalf("ApplicationsDelta() error = %v", err)
+	}
+	if len(apps) != 2 || to.String(apps[0].GetDisplayName()) != "app-1-renamed" {
+		t.Fatalf("expected the renamed and the deleted applications, got %d applications", len(apps))
+	}
+	if _, ok := apps[1].GetAdditionalData()["@removed"]; !ok {
+		t.Errorf("expected the deleted application to have the @removed annotation")
+	}
+	if !strings.HasSuffix(deltaLink, "$deltatoken=round-3") {
+		t.Errorf("expected the delta link of round 3, got %q", deltaLink)
+	}
+	if len(rounds) != 3 {
+		t.Errorf("expected 3 requests, got %d", len(rounds))
+	}
+}
+
+func TestApplicationsDeltaNoDeltaLink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/delta()", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if _, _, err := c.ApplicationsDelta(context.Background(), ""); err == nil {
+		t.Errorf("expected an error if the response has no delta link")
+	}
+}
+
+func TestDeleteApplicationByDisplayName(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$filter") == "displayName eq 'app'" && len(deleted) == 0 {
+			fmt.Fprint(w, `{"value": [{"id": "object-id", "displayName": "app"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		deleted = append(deleted, "object-id")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+	ctx := context.Background()
+
+	if err := c.DeleteApplicationByDisplayName(ctx, "app"); err != nil {
+		t.Fatalf("DeleteApplicationByDisplayName() error = %v", err)
+	}
+	if want := []string{"object-id"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+
+	// the application has already been deleted
+	if err := c.DeleteApplicationByDisplayName(ctx, "app"); err != nil {
+		t.Fatalf("DeleteApplicationByDisplayName() error = %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("expected no other delete request, got %v", deleted)
+	}
+}
+
+func TestDeleteApplicationsByTag(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("ConsistencyLevel"); got != "eventual" {
+			t.Errorf("expected ConsistencyLevel header to be eventual, got %q", got)
+		}
+		if got := r.URL.Query().Get("$filter"); got != "tags/any(t:t eq 'azwi')" {
+			t.Errorf("expected $filter to be tags/any(t:t eq 'azwi'), got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "object-id-1", "displayName": "app-1"}, {"id": "object-id-2", "displayName": "app-2"}, {"id": "object-id-3", "displayName": "app-3"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		if r.URL.Path == "/v1.0/applications/object-id-2" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+			return
+		}
+		deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v1.0/applications/"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("cap exceeded", func(t *testing.T) {
+		deleted = nil
+		c := newTestAzureClient(t, mux)
+
+		n, err := c.DeleteApplicationsByTag(context.Background(), "azwi", 2)
+		if err == nil || !strings.Contains(err.Error(), "refusing to delete 3 applications") {
+			t.Errorf("DeleteApplicationsByTag() error = %v, want cap exceeded error", err)
+		}
+		if n != 0 || len(deleted) != 0 {
+			t.Errorf("expected no application to be deleted, got %d: %v", n, deleted)
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		deleted = nil
+		c := newTestAzureClient(t, mux)
+
+		n, err := c.DeleteApplicationsByTag(context.Background(), "azwi", 3)
+		if err == nil || !strings.Contains(err.Error(), "failed to delete application app-2") {
+			t.Errorf("DeleteApplicationsByTag() error = %v, want error for app-2", err)
+		}
+		if want := []string{"object-id-1", "object-id-3"}; n != 2 || !reflect.DeepEqual(deleted, want) {
+			t.Errorf("DeleteApplicationsByTag() deleted %d: %v, want %v", n, deleted, want)
+		}
+	})
+}
+
+func TestGetFederatedCredentialsBySubject(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$filter"); got != "subject eq 'system:serviceaccount:default:sa'" {
+			t.Errorf("expected $filter to be subject eq 'system:serviceaccount:default:sa', got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"name": "fic-1", "issuer": "https://issuer-1.example.com/", "subject": "system:serviceaccount:default:sa"},
+			{"name": "fic-2", "issuer": "https://issuer-2.example.com/", "subject": "system:serviceaccount:default:sa"}
+		]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	ctx := context.Background()
+
+	fics, err := c.GetFederatedCredentialsBySubject(ctx, "object-id", "system:serviceaccount:default:sa")
+	if err != nil {
+		t.Fatalf("GetFederatedCredentialsBySubject() error = %v", err)
+	}
+	var issuers []string
+	for _, fic := range fics {
+		issuers = append(issuers, *fic.GetIssuer())
+	}
+	if want := []string{"https://issuer-1.example.com/", "https://issuer-2.example.com/"}; !reflect.DeepEqual(issuers, want) {
+		t.Errorf("GetFederatedCredentialsBySubject() issuers = %v, want %v", issuers, want)
+	}
+
+	fic, err := c.GetFederatedCredential(ctx, "object-id", "https://issuer-2.example.com/", "system:serviceaccount:default:sa")
+	if err != nil {
+		t.Fatalf("GetFederatedCredential() error = %v", err)
+	}
+	if got := *fic.GetName(); got != "fic-2" {
+		t.Errorf("GetFederatedCredential() = %s, want fic-2", got)
+	}
+
+	if _, err := c.GetFederatedCredential(ctx, "object-id", "https://issuer-3.example.com/", "system:serviceaccount:default:sa"); !errors.Is(err, ErrFederatedCredentialNotFound) {
+		t.Errorf("GetFederatedCredential() error = %v, want %v", err, ErrFederatedCredentialNotFound)
+	}
+}
+
+func TestGetFederatedCredentialPaginated(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "page-2" {
+			fmt.Fprint(w, `{"value": [
+				{"name": "fic-3", "issuer": "https://issuer-3.example.com/", "subject": "system:serviceaccount:default:sa"}
+			]}`)
+			return
+		}
+		if got := r.URL.Query().Get("$filter"); got != "subject eq 'system:serviceaccount:default:sa'" {
+			t.Errorf("expected $filter to be subject eq 'system:serviceaccount:default:sa', got %q", got)
+		}
+		fmt.Fprintf(w, `{"value": [
+			{"name": "fic-1", "issuer": "https://issuer-1.example.com/", "subject": "system:serviceaccount:default:sa"},
+			{"name": "fic-2", "issuer": "https://issuer-2.example.com/", "subject": "system:serviceaccount:default:sa"}
+		], "@odata.nextLink": "http://%s/v1.0/applications/object-id/federatedIdentityCredentials?$skiptoken=page-2"}`, r.Host)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic, err := c.GetFederatedCredential(context.Background(), "object-id", "https://issuer-3.example.com/", "system:serviceaccount:default:sa")
+	if err != nil {
+		t.Fatalf("GetFederatedCredential() error = %v", err)
+	}
+	if got := *fic.GetName(); got != "fic-3" {
+		t.Errorf("GetFederatedCredential() = %s, want fic-3", got)
+	}
+}
+
+func TestGetFederatedCredentialWithAudiences(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"name": "fic-1", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa", "audiences": ["api://AzureADTokenExchange"]},
+			{"name": "fic-2", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa", "audiences": ["api://custom", "api://other"]}
+		]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		name      string
+		audiences []string
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "no audiences",
+			want: "fic-1",
+		},
+		{
+			name:      "default audience",
+			audiences: []string{"api://AzureADTokenExchange"},
+			want:      "fic-1",
+		},
+		{
+			name:      "custom audiences in another order",
+			audiences: []string{"api://other", "api://custom"},
+			want:      "fic-2",
+		},
+		{
+			name:      "audiences not matched",
+			audiences: []string{"api://custom"},
+			wantErr:   ErrFederatedCredentialNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fic, err := c.GetFederatedCredentialWithAudiences(context.Background(), "object-id", "https://issuer.example.com/", "system:serviceaccount:default:sa", test.audiences)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("GetFederatedCredentialWithAudiences() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetFederatedCredentialWithAudiences() error = %v", err)
+			}
+			if got := *fic.GetName(); got != test.want {
+				t.Errorf("GetFederatedCredentialWithAudiences() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckFederatedCredentialLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		wantErr bool
+	}{
+		{
+			name:  "below the limit",
+			count: 19,
+		},
+		{
+			name:    "at the limit",
+			count:   20,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+				fics := make([]string, test.count)
+				for i := range fics {
+					fics[i] = fmt.Sprintf(`{"id": "fic-%d", "name": "fic-%d"}`, i, i)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"value": [%s]}`, strings.Join(fics, ","))
+			})
+			c := newTestAzureClient(t, mux)
+
+			err := c.CheckFederatedCredentialLimit(context.Background(), "object-id")
+			if !test.wantErr {
+				if err != nil {
+					t.Errorf("CheckFederatedCredentialLimit() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrFederatedCredentialLimitExceeded) {
+				t.Fatalf("CheckFederatedCredentialLimit() error = %v, want %v", err, ErrFederatedCredentialLimitExceeded)
+			}
+			if !strings.Contains(err.Error(), "has 20 federated credentials, which is the limit of 20") {
+				t.Errorf("CheckFederatedCredentialLimit() error = %v, want the count and the limit", err)
+			}
+		})
+	}
+}
+
+func TestFederatedCredentialExists(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/forbidden/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		name     string
+		objectID string
+		issuer   string
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name:     "exists",
+			objectID: "object-id",
+			issuer:   "https://issuer.example.com/",
+			want:     true,
+		},
+		{
+			name:     "not exists",
+			objectID: "object-id",
+			issuer:   "https://other-issuer.example.com/",
+		},
+		{
+			name:     "error",
+			objectID: "forbidden",
+			issuer:   "https://issuer.example.com/",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exists, err := c.FederatedCredentialExists(context.Background(), test.objectID, test.issuer, "system:serviceaccount:default:sa")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("FederatedCredentialExists() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if exists != test.want {
+				t.Errorf("FederatedCredentialExists() = %v, want %v", exists, test.want)
+			}
+		})
+	}
+}
+
+func TestGetFederatedCredentialByName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"name": "fic-1", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa-1"},
+			{"name": "fic-2", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa-2"}
+		]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	ctx := context.Background()
+
+	fic, err := c.GetFederatedCredentialByName(ctx, "object-id", "fic-2")
+	if err != nil {
+		t.Fatalf("GetFederatedCredentialByName() error = %v", err)
+	}
+	if got := *fic.GetSubject(); got != "system:serviceaccount:default:sa-2" {
+		t.Errorf("GetFederatedCredentialByName() subject = %s, want system:serviceaccount:default:sa-2", got)
+	}
+
+	if _, err := c.GetFederatedCredentialByName(ctx, "object-id", "fic-3"); !errors.Is(err, ErrFederatedCredentialNotFound) {
+		t.Errorf("GetFederatedCredentialByName() error = %v, want %v", err, ErrFederatedCredentialNotFound)
+	}
+}
+
+func TestUpdateApplicationDisplayName(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if err := c.UpdateApplicationDisplayName(context.Background(), "object-id", "new-name"); err != nil {
+		t.Fatalf("UpdateApplicationDisplayName() error = %v", err)
+	}
+
+	// the type of the object is always serialized by the SDK
+	delete(body, "@odata.type")
+	if want := map[string]interface{}{"displayName": "new-name"}; !reflect.DeepEqual(body, want) {
+		t.Errorf("request body = %v, want %v", body, want)
+	}
+}
+
+func TestDeleteFederatedCredentialBySubject(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if len(deleted) > 0 {
+			fmt.Fprint(w, `{"value": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials/fic-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		deleted = append(deleted, "fic-id")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+	ctx := context.Background()
+
+	if err := c.DeleteFederatedCredentialBySubject(ctx, "object-id", "https://issuer.example.com/", "system:serviceaccount:default:sa"); err != nil {
+		t.Fatalf("DeleteFederatedCredentialBySubject() error = %v", err)
+	}
+	if want := []string{"fic-id"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+
+	// the federated credential has already been deleted
+	if err := c.DeleteFederatedCredentialBySubject(ctx, "object-id", "https://issuer.example.com/", "system:serviceaccount:default:sa"); err != nil {
+		t.Fatalf("DeleteFederatedCredentialBySubject() error = %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("expected no other delete request, got %v", deleted)
+	}
+}
+
+func TestDeleteFederatedCredentials(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"id": "fic-1-id", "name": "fic-1", "issuer": "https://cluster-a.example.com/", "subject": "system:serviceaccount:default:sa-1"},
+			{"id": "fic-2-id", "name": "fic-2", "issuer": "https://cluster-b.example.com/", "subject": "system:serviceaccount:default:sa-2"},
+			{"id": "fic-3-id", "name": "fic-3", "issuer": "https://cluster-a.example.com/", "subject": "system:serviceaccount:kube-system:sa-3"}
+		]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		deleted = append(deleted, path.Base(r.URL.Path))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	n, err := c.DeleteFederatedCredentials(context.Background(), "object-id", func(fic models.FederatedIdentityCredentialable) bool {
+		return to.String(fic.GetIssuer()) == "https://cluster-a.example.com/"
+	})
+	if err != nil {
+		t.Fatalf("DeleteFederatedCredentials() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteFederatedCredentials() = %d, want 2", n)
+	}
+	if want := []string{"fic-1-id", "fic-3-id"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+}
+
+func TestDeleteFederatedCredentialsError(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"id": "fic-1-id", "name": "fic-1", "subject": "system:serviceaccount:default:sa-1"},
+			{"id": "fic-2-id", "name": "fic-2", "subject": "system:serviceaccount:default:sa-2"},
+			{"id": "fic-3-id", "name": "fic-3", "subject": "system:serviceaccount:default:sa-3"}
+		]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials/", func(w http.ResponseWriter, r *http.Request) {
+		id := path.Base(r.URL.Path)
+		if id == "fic-1-id" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+			return
+		}
+		deleted = append(deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	// the deletes continue past the failure of the first one
+	n, err := c.DeleteFederatedCredentials(context.Background(), "object-id", func(models.FederatedIdentityCredentialable) bool { return true })
+	if err == nil || !strings.Contains(err.Error(), "failed to delete federated credential fic-1") {
+		t.Errorf("DeleteFederatedCredentials() error = %v, want the error of fic-1", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteFederatedCredentials() = %d, want 2", n)
+	}
+	if want := []string{"fic-2-id", "fic-3-id"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+}
+
+func TestSetApplicationRequiredResourceAccess(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	// User.Read delegated permission of Microsoft Graph
+	scope := models.NewResourceAccess()
+	scopeID := uuid.MustParse("e1fe6dd8-ba31-4d61-89e7-88639da4683d")
+	scope.SetId(&scopeID)
+	scope.SetType(to.StringPtr("Scope"))
+	access := models.NewRequiredResourceAccess()
+	access.SetResourceAppId(to.StringPtr("00000003-0000-0000-c000-000000000000"))
+	access.SetResourceAccess([]models.ResourceAccessable{scope})
+
+	if err := c.SetApplicationRequiredResourceAccess(context.Background(), "object-id", []models.RequiredResourceAccessable{access}); err != nil {
+		t.Fatalf("SetApplicationRequiredResourceAccess() error = %v", err)
+	}
+
+	want := []interface{}{
+		map[string]interface{}{
+			"resourceAppId": "00000003-0000-0000-c000-000000000000",
+			"resourceAccess": []interface{}{
+				map[string]interface{}{"id": "e1fe6dd8-ba31-4d61-89e7-88639da4683d", "type": "Scope"},
+			},
+		},
+	}
+	if got := body["requiredResourceAccess"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("requiredResourceAccess = %v, want %v", got, want)
+	}
+}
+
+func TestSetApplicationImplicitGrantSettings(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	if err := c.SetApplicationImplicitGrantSettings(context.Background(), "object-id", true, false); err != nil {
+		t.Fatalf("SetApplicationImplicitGrantSettings() error = %v", err)
+	}
+
+	// the Graph SDK also serializes the @odata.type of the application, so only the web property is compared
+	want := map[string]interface{}{
+		"implicitGrantSettings": map[string]interface{}{
+			"enableIdTokenIssuance":     true,
+			"enableAccessTokenIssuance": false,
+		},
+	}
+	if !reflect.DeepEqual(body["web"], want) {
+		t.Errorf("web = %v, want %v", body["web"], want)
+	}
+}
+
+func TestSetPreAuthorizedApplications(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH request, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	newPreAuthorizedApplication := func(appID string, permissionIDs ...string) models.PreAuthorizedApplicationable {
+		app := models.NewPreAuthorizedApplication()
+		app.SetAppId(to.StringPtr(appID))
+		app.SetDelegatedPermissionIds(permissionIDs)
+		return app
+	}
+
+	preAuth := []models.PreAuthorizedApplicationable{
+		newPreAuthorizedApplication("04b07795-8ddb-461a-bbee-02f9e1bf7b46", "e1fe6dd8-ba31-4d61-89e7-88639da4683d"),
+	}
+	if err := c.SetPreAuthorizedApplications(context.Background(), "object-id", preAuth); err != nil {
+		t.Fatalf("SetPreAuthorizedApplications() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"preAuthorizedApplications": []interface{}{
+			map[string]interface{}{
+				"appId":                  "04b07795-8ddb-461a-bbee-02f9e1bf7b46",
+				"delegatedPermissionIds": []interface{}{"e1fe6dd8-ba31-4d61-89e7-88639da4683d"},
+			},
+		},
+	}
+	if got := body["api"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("api = %v, want %v", got, want)
+	}
+
+	body = nil
+	for _, invalid := range [][]models.PreAuthorizedApplicationable{
+		{newPreAuthorizedApplication("client-app")},
+		{newPreAuthorizedApplication("04b07795-8ddb-461a-bbee-02f9e1bf7b46", "user_impersonation")},
+	} {
+		if err := c.SetPreAuthorizedApplications(context.Background(), "object-id", invalid); err == nil || !strings.Contains(err.Error(), "must be a GUID") {
+			t.Errorf("expected GUID validation error, got %v", err)
+		}
+	}
+	if body != nil {
+		t.Errorf("expected no request for invalid pre-authorized applications")
+	}
+}
+
+func TestApplicationOAuth2Scopes(t *testing.T) {
+	var body map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if got := r.URL.Query().Get("$select"); got != "id,api" {
+				t.Errorf("expected $select to be id,api, got %q", got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id": "object-id", "api": {"oauth2PermissionScopes": [{"id": "e1fe6dd8-ba31-4d61-89e7-88639da4683d", "value": "user_impersonation", "type": "User", "isEnabled": true, "adminConsentDisplayName": "Access the API"}]}}`)
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	scopes, err := c.GetApplicationOAuth2Scopes(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("GetApplicationOAuth2Scopes() error = %v", err)
+	}
+	if len(scopes) != 1 || to.String(scopes[0].GetValue()) != "user_impersonation" || scopes[0].GetId().String() != "e1fe6dd8-ba31-4d61-89e7-88639da4683d" {
+		t.Fatalf("expected the user_impersonation scope, got %v", scopes)
+	}
+
+	scope := models.NewPermissionScope()
+	scope.SetValue(to.StringPtr("Data.Read"))
+	scope.SetType(to.StringPtr("Admin"))
+	scope.SetIsEnabled(to.BoolPtr(true))
+	if err := c.AddApplicationOAuth2Scope(context.Background(), "object-id", scope); err != nil {
+		t.Fatalf("AddApplicationOAuth2Scope() error = %v", err)
+	}
+	if scope.GetId() != nil {
+		t.Errorf("expected the scope of the caller to be left untouched")
+	}
+
+	got := body["api"].(map[string]interface{})["oauth2PermissionScopes"].([]interface{})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 scopes, got %v", got)
+	}
+	existing := got[0].(map[string]interface{})
+	if existing["value"] != "user_impersonation" || existing["adminConsentDisplayName"] != "Access the API" || existing["id"] != "e1fe6dd8-ba31-4d61-89e7-88639da4683d" {
+		t.Errorf("expected the existing scope to be kept, got %v", existing)
+	}
+	added := got[1].(map[string]interface{})
+	if added["value"] != "Data.Read" || added["type"] != "Admin" {
+		t.Errorf("expected the Data.Read scope to be added, got %v", added)
+	}
+	if _, err := uuid.Parse(fmt.Sprint(added["id"])); err != nil {
+		t.Errorf("expected the added scope to have a generated ID, got %v", added["id"])
+	}
+
+	duplicate := models.NewPermissionScope()
+	duplicate.SetValue(to.StringPtr("user_impersonation"))
+	if err := c.AddApplicationOAuth2Scope(context.Background(), "object-id", duplicate); err == nil {
+		t.Error("expected error for an existing scope but got nil")
+	}
+}
+
+func TestApplicationAppRoles(t *testing.T) {
+	var body map[string]interface{}
+	patched := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if got := r.URL.Query().Get("$select"); got != "id,appRoles" {
+				t.Errorf("expected $select to be id,appRoles, got %q", got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id": "object-id", "appRoles": [{"id": "8748c9f8-3bd9-4d1b-8b0c-5d4b5cb1f9a4", "value": "Data.Read.All", "allowedMemberTypes": ["Application"], "isEnabled": true, "displayName": "Read all data"}]}`)
+		case http.MethodPatch:
+			patched = true
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	roles, err := c.ListApplicationAppRoles(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("ListApplicationAppRoles() error = %v", err)
+	}
+	if len(roles) != 1 || to.String(roles[0].GetValue()) != "Data.Read.All" || roles[0].GetId().String() != "8748c9f8-3bd9-4d1b-8b0c-5d4b5cb1f9a4" {
+		t.Fatalf("expected the Data.Read.All role, got %v", roles)
+	}
+
+	role := models.NewAppRole()
+	role.SetValue(to.StringPtr("Data.Write.All"))
+	role.SetAllowedMemberTypes([]string{"User", "Application"})
+	role.SetDisplayName(to.StringPtr("Write all data"))
+	role.SetIsEnabled(to.BoolPtr(true))
+	if err := c.AddApplicationAppRole(context.Background(), "object-id", role); err != nil {
+		t.Fatalf("AddApplicationAppRole() error = %v", err)
+	}
+	if role.GetId() != nil {
+		t.Errorf("expected the role of the caller to be left untouched")
+	}
+
+	got := body["appRoles"].([]interface{})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 app roles, got %v", got)
+	}
+	existing := got[0].(map[string]interface{})
+	if existing["value"] != "Data.Read.All" || existing["displayName"] != "Read all data" || existing["id"] != "8748c9f8-3bd9-4d1b-8b0c-5d4b5cb1f9a4" {
+		t.Errorf("expected the existing role to be kept, got %v", existing)
+	}
+	added := got[1].(map[string]interface{})
+	if added["value"] != "Data.Write.All" || !reflect.DeepEqual(added["allowedMemberTypes"], []interface{}{"User", "Application"}) {
+		t.Errorf("expected the Data.Write.All role to be added, got %v", added)
+	}
+	if _, err := uuid.Parse(fmt.Sprint(added["id"])); err != nil {
+		t.Errorf("expected the added role to have a generated ID, got %v", added["id"])
+	}
+
+	patched = false
+	tests := []struct {
+		name        string
+		value       string
+		memberTypes []string
+	}{
+		{
+			name:        "existing value",
+			value:       "data.read.all",
+			memberTypes: []string{"Application"},
+		},
+		{
+			name:  "no member types",
+			value: "Data.Delete.All",
+		},
+		{
+			name:        "invalid member type",
+			value:       "Data.Delete.All",
+			memberTypes: []string{"Group"},
+		},
+		{
+			name:        "no value",
+			memberTypes: []string{"Application"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invalid := models.NewAppRole()
+			if test.value != "" {
+				invalid.SetValue(to.StringPtr(test.value))
+			}
+			invalid.SetAllowedMemberTypes(test.memberTypes)
+			if err := c.AddApplicationAppRole(context.Background(), "object-id", invalid); err == nil {
+				t.Error("expected error but got nil")
+			}
+		})
+	}
+	if patched {
+		t.Error("expected no update for invalid app roles")
+	}
+}
+
+func TestGetApplicationCreatedTime(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$select"); got != "id,createdDateTime" {
+			t.Errorf("expected $select to be id,createdDateTime, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "object-id", "createdDateTime": "2023-04-05T06:07:08Z"}`)
+	})
+	mux.HandleFunc("/v1.0/applications/no-created-time", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "no-created-time"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	created, err := c.GetApplicationCreatedTime(context.Background(), "object-id")
+	if err != nil {
+		t.Fatalf("GetApplicationCreatedTime() error = %v", err)
+	}
+	if want := time.Date(2023, time.April, 5, 6, 7, 8, 0, time.UTC); !created.Equal(want) {
+		t.Errorf("expected created time to be %s, got %s", want, created)
+	}
+
+	if _, err := c.GetApplicationCreatedTime(context.Background(), "no-created-time"); err == nil {
+		t.Error("expected error for an application without a created time but got nil")
+	}
+}
+
+func TestListApplicationsCreatedBefore(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$filter"); got != "tags/any(t:t eq 'azwi-e2e')" {
+			t.Errorf("expected $filter to be tags/any(t:t eq 'azwi-e2e'), got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"id": "before", "createdDateTime": "2023-04-05T06:07:07Z"},
+			{"id": "at-cutoff", "createdDateTime": "2023-04-05T06:07:08Z"},
+			{"id": "after", "createdDateTime": "2023-04-05T06:07:09Z"},
+			{"id": "no-created-time"}
+		]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	cutoff := time.Date(2023, time.April, 5, 6, 7, 8, 0, time.UTC)
+	apps, err := c.ListApplicationsCreatedBefore(context.Background(), cutoff, "azwi-e2e")
+	if err != nil {
+		t.Fatalf("ListApplicationsCreatedBefore() error = %v", err)
+	}
+	var ids []string
+	for _, app := range apps {
+		ids = append(ids, to.String(app.GetId()))
+	}
+	if want := []string{"before"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ListApplicationsCreatedBefore() = %v, want %v", ids, want)
+	}
+}
+
+func TestSetApplicationLogo(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	jpeg := append([]byte("\xff\xd8\xff"), make([]byte, 16)...)
+
+	var contentType string
+	var body []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/logo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT request, got %s", r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newTestAzureClient(t, mux)
+
+	tests := []struct {
+		name        string
+		logo        []byte
+		contentType string
+		errorMsg    string
+	}{
+		{
+			name:        "png",
+			logo:        png,
+			contentType: "image/png",
+		},
+		{
+			name:        "jpeg",
+			logo:        jpeg,
+			contentType: "image/jpeg",
+		},
+		{
+			name:        "empty logo",
+			contentType: "image/png",
+			errorMsg:    "application logo is empty",
+		},
+		{
+			name:        "unsupported content type",
+			logo:        []byte("GIF89a"),
+			contentType: "image/gif",
+			errorMsg:    `invalid application logo content type "image/gif", must be one of: image/png, image/jpeg`,
+		},
+		{
+			name:        "content type mismatch",
+			logo:        png,
+			contentType: "image/jpeg",
+			errorMsg:    `application logo content type "image/jpeg" does not match the detected content type "image/png"`,
+		},
+		{
+			name:        "too large",
+			logo:        append(png, make([]byte, maxApplicationLogoSize)...),
+			contentType: "image/png",
+			errorMsg:    fmt.Sprintf("application logo is %d bytes, which exceeds the limit of %d bytes", len(png)+maxApplicationLogoSize, maxApplicationLogoSize),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contentType, body = "", nil
+			err := c.SetApplicationLogo(context.Background(), "object-id", test.logo, test.contentType)
+			if test.errorMsg != "" {
+				if err == nil || err.Error() != test.errorMsg {
+					t.Fatalf("expected error %q, got %v", test.errorMsg, err)
+				}
+				if body != nil {
+					t.Errorf("expected no request for an invalid logo")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetApplicationLogo() error = %v", err)
+			}
+			if contentType != test.contentType {
+				t.Errorf("expected Content-Type to be %s, got %s", test.contentType, contentType)
+			}
+			if !bytes.Equal(body, test.logo) {
+				t.Errorf("expected the logo to be sent as the request body")
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredential(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa", "audiences": ["api://AzureADTokenExchange"]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+
+	created, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+	if err != nil {
+		t.Fatalf("AddFederatedCredential() error = %v", err)
+	}
+	if created.GetId() == nil || *created.GetId() != "fic-id" {
+		t.Errorf("expected the created federated credential to have the ID fic-id, got %v", created.GetId())
+	}
+	if got := to.String(created.GetName()); got != "fic" {
+		t.Errorf("expected the created federated credential to be fic, got %s", got)
+	}
+}
+
+func TestAddFederatedCredentialInvalidName(t *testing.T) {
+	tests := []struct {
+		name     string
+		ficName  string
+		errorMsg string
+	}{
+		{
+			name:     "empty name",
+			errorMsg: "federated credential name is required",
+		},
+		{
+			name:     "under-length name",
+			ficName:  "sa",
+			errorMsg: `federated credential name "sa" is 2 characters long, which is below the minimum of 3 characters`,
+		},
+		{
+			name:     "over-length name",
+			ficName:  strings.Repeat("a", 121),
+			errorMsg: fmt.Sprintf("federated credential name %q is 121 characters long, which exceeds the limit of 120 characters", strings.Repeat("a", 121)),
+		},
+		{
+			name:     "invalid characters",
+			ficName:  "default/sa name/",
+			errorMsg: `federated credential name "default/sa name/" contains invalid characters "/ ", only alphanumeric characters and "-_.~=" are allowed`,
+		},
+	}
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusCreated)
+	})
+	c := newTestAzureClient(t, mux)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fic := models.NewFederatedIdentityCredential()
+			if test.ficName != "" {
+				fic.SetName(to.StringPtr(test.ficName))
+			}
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+
+			_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("expected error %q, got %v", test.errorMsg, err)
+			}
+		})
+	}
+	if requests != 0 {
+		t.Errorf("expected no request for invalid names, got %d", requests)
+	}
+}
+
+func TestValidateFederatedCredentialName(t *testing.T) {
+	// the names generated by the CLI must be valid
+	for _, name := range []string{
+		"foWt5lYFJx_-XwBetmnSltvWY5J_nenUV-2c3Lqes3o=",
+		"kube-system-my-sa",
+		"abc",
+		strings.Repeat("a", 120),
+	} {
+		if err := validateFederatedCredentialName(name); err != nil {
+			t.Errorf("validateFederatedCredentialName(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestValidateFederatedCredential(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(fic models.FederatedIdentityCredentialable)
+		errorMsg string
+	}{
+		{
+			name:  "valid",
+			setup: func(fic models.FederatedIdentityCredentialable) {},
+		},
+		{
+			name: "GitHub Actions subject",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetIssuer(to.StringPtr("https://token.actions.githubusercontent.com"))
+				fic.SetSubject(to.StringPtr("repo:octo-org/octo-repo:environment:prod"))
+			},
+		},
+		{
+			name: "no audiences",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetAudiences(nil)
+			},
+		},
+		{
+			name: "invalid name",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetName(to.StringPtr("default/sa"))
+			},
+			errorMsg: `federated credential name "default/sa" contains invalid characters "/", only alphanumeric characters and "-_.~=" are allowed`,
+		},
+		{
+			name: "no issuer",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetIssuer(nil)
+			},
+			errorMsg: "federated credential issuer is required",
+		},
+		{
+			name: "http issuer",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetIssuer(to.StringPtr("http://issuer.example.com/"))
+			},
+			errorMsg: `federated credential issuer "http://issuer.example.com/" is not a valid https URL`,
+		},
+		{
+			name: "issuer without host",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetIssuer(to.StringPtr("issuer.example.com"))
+			},
+			errorMsg: `federated credential issuer "issuer.example.com" is not a valid https URL`,
+		},
+		{
+			name: "over-length issuer",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetIssuer(to.StringPtr("https://issuer.example.com/" + strings.Repeat("a", 600)))
+			},
+			errorMsg: "federated credential issuer is 627 characters long, which exceeds the limit of 600 characters",
+		},
+		{
+			name: "no subject",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetSubject(nil)
+			},
+			errorMsg: "federated credential must have a subject or a claims matching expression",
+		},
+		{
+			name: "service account subject without name",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetSubject(to.StringPtr("system:serviceaccount:default"))
+			},
+			errorMsg: `federated credential subject "system:serviceaccount:default" is not in the system:serviceaccount:<namespace>:<name> format of a Kubernetes service account`,
+		},
+		{
+			name: "service account subject with empty namespace",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetSubject(to.StringPtr("system:serviceaccount::sa"))
+			},
+			errorMsg: `federated credential subject "system:serviceaccount::sa" is not in the system:serviceaccount:<namespace>:<name> format of a Kubernetes service account`,
+		},
+		{
+			name: "service account subject with extra segment",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa:extra"))
+			},
+			errorMsg: `federated credential subject "system:serviceaccount:default:sa:extra" is not in the system:serviceaccount:<namespace>:<name> format of a Kubernetes service account`,
+		},
+		{
+			name: "over-length subject",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetSubject(to.StringPtr(strings.Repeat("a", 601)))
+			},
+			errorMsg: "federated credential subject is 601 characters long, which exceeds the limit of 600 characters",
+		},
+		{
+			name: "two audiences",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetAudiences([]string{"api://AzureADTokenExchange", "api://custom"})
+			},
+			errorMsg: `federated credential has 2 audiences ["api://AzureADTokenExchange" "api://custom"], but exactly 1 is required`,
+		},
+		{
+			name: "empty audience",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetAudiences([]string{""})
+			},
+			errorMsg: "federated credential audience is empty",
+		},
+		{
+			name: "over-length audience",
+			setup: func(fic models.FederatedIdentityCredentialable) {
+				fic.SetAudiences([]string{strings.Repeat("a", 601)})
+			},
+			errorMsg: "federated credential audience is 601 characters long, which exceeds the limit of 600 characters",
+		},
+	}
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"name": "fic"}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetDefaultFederatedAudiences([]string{"api://AzureADTokenExchange"})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+			test.setup(fic)
+
+			err := ValidateFederatedCredential(fic)
+			if test.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("ValidateFederatedCredential() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("ValidateFederatedCredential() error = %v, want %s", err, test.errorMsg)
+			}
+
+			// AddFederatedCredential fails with the same error before sending any request
+			if _, err := c.AddFederatedCredential(context.Background(), "object-id", fic); err == nil || err.Error() != test.errorMsg {
+				t.Errorf("AddFederatedCredential() error = %v, want %s", err, test.errorMsg)
+			}
+			if got := atomic.LoadInt32(&requests); got != 0 {
+				t.Errorf("expected no request to be sent, got %d", got)
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{
+			name: "default description",
+			want: defaultFederatedCredentialDescription("https://issuer.example.com/"),
+		},
+		{
+			name:        "explicit description",
+			description: "custom description",
+			want:        "custom description",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body map[string]interface{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST request, got %s", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"name": "fic"}`)
+			})
+			c := newTestAzureClient(t, mux)
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+			if test.description != "" {
+				fic.SetDescription(to.StringPtr(test.description))
+			}
+
+			if _, err := c.AddFederatedCredential(context.Background(), "object-id", fic); err != nil {
+				t.Fatalf("AddFederatedCredential() error = %v", err)
+			}
+			if got := body["description"]; got != test.want {
+				t.Errorf("expected description to be %q, got %v", test.want, got)
+			}
+			if got := to.String(fic.GetDescription()); got != test.description {
+				t.Errorf("expected the federated credential description to be left as %q, got %q", test.description, got)
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialDefaultAudiences(t *testing.T) {
+	tests := []struct {
+		name      string
+		audiences []string
+		want      []interface{}
+	}{
+		{
+			name: "default audiences",
+			want: []interface{}{"api://AzureADTokenExchangeChina"},
+		},
+		{
+			name:      "explicit audiences",
+			audiences: []string{"api://custom"},
+			want:      []interface{}{"api://custom"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body map[string]interface{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"name": "fic"}`)
+			})
+			c := newTestAzureClient(t, mux)
+			c.environment = azure.ChinaCloud
+			c.SetDefaultFederatedAudiences(nil)
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences(test.audiences)
+
+			if _, err := c.AddFederatedCredential(context.Background(), "object-id", fic); err != nil {
+				t.Fatalf("AddFederatedCredential() error = %v", err)
+			}
+			if got := body["audiences"]; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected audiences to be %v, got %v", test.want, got)
+			}
+			if len(test.audiences) == 0 && len(fic.GetAudiences()) != 0 {
+				t.Errorf("expected the federated credential audiences to be left empty, got %v", fic.GetAudiences())
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialAudiencesCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		audiences []string
+		errorMsg  string
+	}{
+		{
+			name:     "no audiences",
+			errorMsg: `federated credential has 0 audiences [], but exactly 1 is required`,
+		},
+		{
+			name:      "one audience",
+			audiences: []string{"api://AzureADTokenExchange"},
+		},
+		{
+			name:      "two audiences",
+			audiences: []string{"api://AzureADTokenExchange", "api://custom"},
+			errorMsg:  `federated credential has 2 audiences ["api://AzureADTokenExchange" "api://custom"], but exactly 1 is required`,
+		},
+	}
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"name": "fic"}`)
+	})
+	c := newTestAzureClient(t, mux)
+	// without default federated audiences, the federated credentials added without audiences are rejected
+	c.defaultFederatedAudiences = nil
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences(test.audiences)
+
+			_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+			if test.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("AddFederatedCredential() error = %v", err)
+				}
+				if got := atomic.LoadInt32(&requests); got != 1 {
+					t.Errorf("expected 1 request, got %d", got)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("AddFederatedCredential() error = %v, want %s", err, test.errorMsg)
+			}
+			if got := atomic.LoadInt32(&requests); got != 0 {
+				t.Errorf("expected no request to be sent, got %d", got)
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialApplicationNotPropagated(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		code         string
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{
+			name:         "propagated after retries",
+			failures:     2,
+			code:         GraphErrorCodeResourceNotFound,
+			wantAttempts: 3,
+		},
+		{
+			name:         "never propagated",
+			failures:     federatedCredentialPropagationRetryCount + 1,
+			code:         GraphErrorCodeResourceNotFound,
+			wantAttempts: federatedCredentialPropagationRetryCount + 1,
+			wantErr:      true,
+		},
+		{
+			name:         "other error",
+			failures:     1,
+			code:         "Request_BadRequest",
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if atomic.AddInt32(&attempts, 1) <= test.failures {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprintf(w, `{"error": {"code": %q, "message": "Resource does not exist."}}`, test.code)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"name": "fic"}`)
+			})
+			c := newTestAzureClient(t, mux)
+			c.federatedCredentialPropagationRetryDelay = time.Millisecond
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+
+			_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+			if (err != nil) != test.wantErr {
+				t.Errorf("AddFederatedCredential() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got := atomic.LoadInt32(&attempts); got != test.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", test.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestAddFederatedCredentialAlreadyExists(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"error": {"code": "Request_MultipleObjectsWithSameKeyValue", "message": "FederatedIdentityCredential with name fic already exists."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+
+	_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+	if !errors.Is(err, ErrFederatedCredentialAlreadyExists) {
+		t.Fatalf("AddFederatedCredential() error = %v, want %v", err, ErrFederatedCredentialAlreadyExists)
+	}
+	if !IsFederatedCredentialAlreadyExists(err) {
+		t.Errorf("expected IsFederatedCredentialAlreadyExists to be true for %v", err)
+	}
+	if !strings.Contains(err.Error(), "FederatedIdentityCredential with name fic already exists.") {
+		t.Errorf("expected the error to have the Graph error message, got %v", err)
+	}
+}
+
+func TestAddFederatedCredentialAllowedIssuers(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  string
+		wantErr bool
+	}{
+		{
+			name:   "allowed issuer",
+			issuer: "https://issuer.example.com/",
+		},
+		{
+			name:   "allowed issuer after normalization",
+			issuer: "HTTPS://Issuer.Example.com",
+		},
+		{
+			name:    "issuer not allowed",
+			issuer:  "https://other-issuer.example.com/",
+			wantErr: true,
+		},
+	}
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"name": "fic"}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetAllowedIssuers([]string{"https://issuer.example.com/", "https://other.example.com/"})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			fic := models.NewFederatedIdentityCredential()
+			fic.SetName(to.StringPtr("fic"))
+			fic.SetIssuer(to.StringPtr(test.issuer))
+			fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+			fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+
+			_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("AddFederatedCredential() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrIssuerNotAllowed) {
+				t.Errorf("expected ErrIssuerNotAllowed, got %v", err)
+			}
+			if got := atomic.LoadInt32(&requests); got != 0 {
+				t.Errorf("expected no request to be sent, got %d", got)
+			}
+		})
+	}
+
+	// any issuer is allowed once the allowed issuers are reset
+	c.SetAllowedIssuers(nil)
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://other-issuer.example.com/"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+	if _, err := c.AddFederatedCredential(context.Background(), "object-id", fic); err != nil {
+		t.Errorf("AddFederatedCredential() error = %v", err)
+	}
+}
+
+func TestSetDefaultFederatedAudiences(t *testing.T) {
+	c := &AzureClient{environment: azure.USGovernmentCloud}
+
+	c.SetDefaultFederatedAudiences([]string{"api://custom"})
+	if want := []string{"api://custom"}; !reflect.DeepEqual(c.defaultFederatedAudiences, want) {
+		t.Errorf("expected default federated audiences %v, got %v", want, c.defaultFederatedAudiences)
+	}
+	c.SetDefaultFederatedAudiences(nil)
+	if want := []string{"api://AzureADTokenExchangeUSGov"}; !reflect.DeepEqual(c.defaultFederatedAudiences, want) {
+		t.Errorf("expected default federated audiences %v, got %v", want, c.defaultFederatedAudiences)
+	}
+}
+
+func TestGetFederatedCredentialRaw(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials/fic-id", func(w http.ResponseWriter, r *http.Request) {
+		// the request goes through the transport of the other Graph requests
+		if got := r.Header.Get(clientRequestIDHeader); got != "correlation-id" {
+			t.Errorf("expected %s header to be correlation-id, got %q", clientRequestIDHeader, got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "fic-id", "name": "fic", "issuer": "https://issuer.example.com/", "subject": "system:serviceaccount:default:sa", "audiences": ["api://AzureADTokenExchange"]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": "Request_ResourceNotFound", "message": "Resource does not exist."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	raw, err := c.GetFederatedCredentialRaw(WithCorrelationID(context.Background(), "correlation-id"), "object-id", "fic-id")
+	if err != nil {
+		t.Fatalf("GetFederatedCredentialRaw() error = %v", err)
+	}
+	var fic fakeFederatedCredential
+	if err := json.Unmarshal(raw, &fic); err != nil {
+		t.Fatalf("expected valid JSON, got %q: %v", raw, err)
+	}
+	want := fakeFederatedCredential{
+		ID:        "fic-id",
+		Name:      "fic",
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:sa",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+	if !reflect.DeepEqual(fic, want) {
+		t.Errorf("GetFederatedCredentialRaw() = %+v, want %+v", fic, want)
+	}
+
+	if _, err := c.GetFederatedCredentialRaw(context.Background(), "object-id", "missing"); !isGraphResourceNotFound(err) {
+		t.Errorf("expected a resource not found error, got %v", err)
+	}
+}
+
+func TestDeleteFederatedCredentialsBatch(t *testing.T) {
+	var batchSizes []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/$batch", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Requests []batchRequestItem `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		batchSizes = append(batchSizes, len(body.Requests))
+
+		var responses []string
+		for _, req := range body.Requests {
+			if req.Method != http.MethodDelete {
+				t.Errorf("expected method to be DELETE, got %s", req.Method)
+			}
+			switch req.URL {
+			case "/applications/object-id/federatedIdentityCredentials/fic-3":
+				responses = append(responses, fmt.Sprintf(`{"id": %q, "status": 404, "body": {"error": {"code": "Request_ResourceNotFound", "message": "Resource does not exist."}}}`, req.ID))
+			case "/applications/object-id/federatedIdentityCredentials/fic-30":
+				responses = append(responses, fmt.Sprintf(`{"id": %q, "status": 500}`, req.ID))
+			case "/applications/object-id/federatedIdentityCredentials/fic-41":
+				// no response for this request
+			default:
+				responses = append(responses, fmt.Sprintf(`{"id": %q, "status": 204}`, req.ID))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"responses": [%s]}`, strings.Join(responses, ","))
+	})
+	c := newTestAzureClient(t, mux)
+
+	var ficIDs []string
+	for i := 0; i < 45; i++ {
+		ficIDs = append(ficIDs, fmt.Sprintf("fic-%d", i))
+	}
+	errs, err := c.DeleteFederatedCredentialsBatch(context.Background(), "object-id", ficIDs)
+	if err != nil {
+		t.Fatalf("DeleteFederatedCredentialsBatch() error = %v", err)
+	}
+	if want := []int{20, 20, 5}; !reflect.DeepEqual(batchSizes, want) {
+		t.Errorf("expected batches of %v requests, got %v", want, batchSizes)
+	}
+	if len(errs) != len(ficIDs) {
+		t.Fatalf("expected %d errors, got %d", len(ficIDs), len(errs))
+	}
+	for i, err := range errs {
+		switch i {
+		case 3:
+			if !IsFederatedCredentialNotFound(err) {
+				t.Errorf("expected fic-3 to fail with not found, got %v", err)
+			}
+		case 30, 41:
+			if err == nil {
+				t.Errorf("expected fic-%d to fail", i)
+			}
+		default:
+			if err != nil {
+				t.Errorf("expected fic-%d to be deleted, got %v", i, err)
+			}
+		}
+	}
+}
+
+func TestDeleteFederatedCredentialsBatchError(t *testing.T) {
+	var batches int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/$batch", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&batches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"code": "BadRequest", "message": "Invalid batch payload format."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	ficIDs := make([]string, 25)
+	for i := range ficIDs {
+		ficIDs[i] = fmt.Sprintf("fic-%d", i)
+	}
+	if _, err := c.DeleteFederatedCredentialsBatch(context.Background(), "object-id", ficIDs); err == nil {
+		t.Fatal("expected an error")
+	}
+	// the following batches are not sent
+	if got := atomic.LoadInt32(&batches); got != 1 {
+		t.Errorf("expected 1 batch request, got %d", got)
+	}
+}
+
+func TestAddFederatedCredentials(t *testing.T) {
+	var batchSizes []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/$batch", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Requests []batchRequestItem `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		batchSizes = append(batchSizes, len(body.Requests))
+
+		var responses []string
+		for _, req := range body.Requests {
+			if req.Method != http.MethodPost {
+				t.Errorf("expected method to be POST, got %s", req.Method)
+			}
+			var fic fakeFederatedCredential
+			if err := json.Unmarshal(req.Body, &fic); err != nil {
+				t.Errorf("failed to decode federated credential: %v", err)
+			}
+			if want := fmt.Sprintf("/applications/%s/federatedIdentityCredentials", fic.Subject[len("system:serviceaccount:"):strings.LastIndex(fic.Subject, ":")]); req.URL != want {
+				t.Errorf("expected URL %s, got %s", want, req.URL)
+			}
+			if fic.Description != defaultFederatedCredentialDescription(fic.Issuer) || len(fic.Audiences) != 1 {
+				t.Errorf("expected the federated credential %s to be defaulted, got %+v", fic.Name, fic)
+			}
+			switch fic.Name {
+			case "fic-5":
+				responses = append(responses, fmt.Sprintf(`{"id": %q, "status": 409, "body": {"error": {"code": "Request_MultipleObjectsWithSameKeyValue", "message": "FederatedIdentityCredential with name fic-5 already exists."}}}`, req.ID))
+			default:
+				fic.ID = fic.Name + "-id"
+				created, _ := json.Marshal(fic)
+				responses = append(responses, fmt.Sprintf(`{"id": %q, "status": 201, "body": %s}`, req.ID, created))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"responses": [%s]}`, strings.Join(responses, ","))
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetDefaultFederatedAudiences(nil)
+
+	var reqs []FederatedCredentialRequest
+	for i := 0; i < 22; i++ {
+		// the subject names the application the federated credential is added to
+		objectID := fmt.Sprintf("app-%d", i%2)
+		fic := models.NewFederatedIdentityCredential()
+		fic.SetName(to.StringPtr(fmt.Sprintf("fic-%d", i)))
+		fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+		fic.SetSubject(to.StringPtr(fmt.Sprintf("system:serviceaccount:%s:sa-%d", objectID, i)))
+		reqs = append(reqs, FederatedCredentialRequest{ObjectID: objectID, FederatedCredential: fic})
+	}
+	reqs[1].FederatedCredential.SetName(to.StringPtr("invalid name"))
+
+	results, err := c.AddFederatedCredentials(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("AddFederatedCredentials() error = %v", err)
+	}
+	// the invalid federated credential is not sent
+	if want := []int{19, 2}; !reflect.DeepEqual(batchSizes, want) {
+		t.Errorf("expected batches of %v requests, got %v", want, batchSizes)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("expected %d results, got %d", len(reqs), len(results))
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("expected result %d to have index %d, got %d", i, i, result.Index)
+		}
+		switch i {
+		case 1:
+			if result.Err == nil || result.FederatedCredential != nil {
+				t.Errorf("expected the invalid federated credential to fail, got %+v", result)
+			}
+		case 5:
+			if !errors.Is(result.Err, ErrFederatedCredentialAlreadyExists) {
+				t.Errorf("expected fic-5 to already exist, got %v", result.Err)
+			}
+		default:
+			if result.Err != nil {
+				t.Fatalf("expected fic-%d to be added, got %v", i, result.Err)
+			}
+			if got, want := to.String(result.FederatedCredential.GetId()), fmt.Sprintf("fic-%d-id", i); got != want {
+				t.Errorf("expected the created federated credential to have ID %s, got %s", want, got)
+			}
+		}
+	}
+}
+
+func TestWaitForFederatedCredential(t *testing.T) {
+	var lookups int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/federatedIdentityCredentials", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// the federated credential is not found until it has propagated
+		if atomic.AddInt32(&lookups, 1) < 3 {
+			fmt.Fprint(w, `{"value": [{"id": "other-id", "name": "other"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "other-id", "name": "other"}, {"id": "fic-id", "name": "fic"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+	c.SetFederatedCredentialPollInterval(time.Millisecond)
+
+	if err := c.WaitForFederatedCredential(context.Background(), "object-id", "fic", time.Minute); err != nil {
+		t.Fatalf("WaitForFederatedCredential() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&lookups); got != 3 {
+		t.Errorf("expected 3 lookups, got %d", got)
+	}
+}
+
+func TestWaitForFederatedCredentialTimeout(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	}))
+	c.SetFederatedCredentialPollInterval(time.Millisecond)
+
+	err := c.WaitForFederatedCredential(context.Background(), "object-id", "fic", 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForFederatedCredential() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.WaitForFederatedCredential(ctx, "object-id", "fic", time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForFederatedCredential() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,99 @@
+package cloud
+
+import (
+	"context"
+
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+)
+
+// graphServiceClientAdapter implements GraphServiceClient with the request builders of the Graph service client.
+// It only covers the requests of GraphServiceClient, AzureClient sends the others with the Graph service client.
+type graphServiceClientAdapter struct {
+	client *msgraphsdk.GraphServiceClient
+}
+
+var _ GraphServiceClient = &graphServiceClientAdapter{}
+
+// newGraphServiceClientAdapter returns the GraphServiceClient that sends the requests with the Graph service client.
+func newGraphServiceClientAdapter(client *msgraphsdk.GraphServiceClient) GraphServiceClient {
+	return &graphServiceClientAdapter{client: client}
+}
+
+// ListApplications lists the applications.
+func (a *graphServiceClientAdapter) ListApplications(ctx context.Context, options *applications.ApplicationsRequestBuilderGetRequestConfiguration) (models.ApplicationCollectionResponseable, error) {
+	return a.client.Applications().Get(ctx, options)
+}
+
+// GetApplication gets an application by its object ID.
+func (a *graphServiceClientAdapter) GetApplication(ctx context.Context, objectID string, options *applications.ApplicationItemRequestBuilderGetRequestConfiguration) (models.Applicationable, error) {
+	return a.client.ApplicationsById(objectID).Get(ctx, options)
+}
+
+// CreateApplication creates an application.
+func (a *graphServiceClientAdapter) CreateApplication(ctx context.Context, app models.Applicationable) (models.Applicationable, error) {
+	return a.client.Applications().Post(ctx, app, nil)
+}
+
+// UpdateApplication updates the properties of an application that are set.
+func (a *graphServiceClientAdapter) UpdateApplication(ctx context.Context, objectID string, app models.Applicationable) (models.Applicationable, error) {
+	return a.client.ApplicationsById(objectID).Patch(ctx, app, nil)
+}
+
+// DeleteApplication deletes an application.
+func (a *graphServiceClientAdapter) DeleteApplication(ctx context.Context, objectID string) error {
+	return a.client.ApplicationsById(objectID).Delete(ctx, nil)
+}
+
+// ListServicePrincipals lists the service principals.
+func (a *graphServiceClientAdapter) ListServicePrincipals(ctx context.Context, options *serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration) (models.ServicePrincipalCollectionResponseable, error) {
+	return a.client.ServicePrincipals().Get(ctx, options)
+}
+
+// GetServicePrincipal gets a service principal by its object ID.
+func (a *graphServiceClientAdapter) GetServicePrincipal(ctx context.Context, objectID string, options *serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration) (models.ServicePrincipalable, error) {
+	return a.client.ServicePrincipalsById(objectID).Get(ctx, options)
+}
+
+// CreateServicePrincipal creates a service principal.
+func (a *graphServiceClientAdapter) CreateServicePrincipal(ctx context.Context, sp models.ServicePrincipalable) (models.ServicePrincipalable, error) {
+	return a.client.ServicePrincipals().Post(ctx, sp, nil)
+}
+
+// UpdateServicePrincipal updates the properties of a service principal that are set.
+func (a *graphServiceClientAdapter) UpdateServicePrincipal(ctx context.Context, objectID string, sp models.ServicePrincipalable) (models.ServicePrincipalable, error) {
+	return a.client.ServicePrincipalsById(objectID).Patch(ctx, sp, nil)
+}
+
+// DeleteServicePrincipal deletes a service principal.
+func (a *graphServiceClientAdapter) DeleteServicePrincipal(ctx context.Context, objectID string) error {
+	return a.client.ServicePrincipalsById(objectID).Delete(ctx, nil)
+}
+
+// ListFederatedIdentityCredentials lists the federated identity credentials of an application.
+func (a *graphServiceClientAdapter) ListFederatedIdentityCredentials(ctx context.Context, objectID string, options *applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration) (models.FederatedIdentityCredentialCollectionResponseable, error) {
+	return a.client.ApplicationsById(objectID).FederatedIdentityCredentials().Get(ctx, options)
+}
+
+// CreateFederatedIdentityCredential adds a federated identity credential to an application.
+func (a *graphServiceClientAdapter) CreateFederatedIdentityCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	return a.client.ApplicationsById(objectID).FederatedIdentityCredentials().Post(ctx, fic, nil)
+}
+
+// UpdateFederatedIdentityCredential updates the properties of a federated identity credential that are set.
+func (a *graphServiceClientAdapter) UpdateFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	return a.client.ApplicationsById(objectID).FederatedIdentityCredentialsById(federatedCredentialID).Patch(ctx, fic, nil)
+}
+
+// DeleteFederatedIdentityCredential deletes a federated identity credential of an application.
+func (a *graphServiceClientAdapter) DeleteFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string) error {
+	return a.client.ApplicationsById(objectID).FederatedIdentityCredentialsById(federatedCredentialID).Delete(ctx, nil)
+}
+
+// GetAdapter returns the request adapter of the Graph service client.
+func (a *graphServiceClientAdapter) GetAdapter() abstractions.RequestAdapter {
+	return a.client.GetAdapter()
+}
@@ -0,0 +1,95 @@
+package cloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+// fakeGraphServiceClient is a GraphServiceClient serving the applications and recording the deleted federated
+// identity credentials. The methods that are not overridden panic.
+type fakeGraphServiceClient struct {
+	GraphServiceClient
+
+	apps    []models.Applicationable
+	deleted []string
+}
+
+func (f *fakeGraphServiceClient) ListApplications(_ context.Context, _ *applications.ApplicationsRequestBuilderGetRequestConfiguration) (models.ApplicationCollectionResponseable, error) {
+	resp := models.NewApplicationCollectionResponse()
+	resp.SetValue(f.apps)
+	return resp, nil
+}
+
+func (f *fakeGraphServiceClient) DeleteFederatedIdentityCredential(_ context.Context, objectID, federatedCredentialID string) error {
+	f.deleted = append(f.deleted, objectID+"/"+federatedCredentialID)
+	return nil
+}
+
+func newFakeApplication(objectID, displayName string) models.Applicationable {
+	app := models.NewApplication()
+	app.SetId(to.StringPtr(objectID))
+	app.SetAppId(to.StringPtr("app-id-" + objectID))
+	app.SetDisplayName(to.StringPtr(displayName))
+	return app
+}
+
+func TestGetApplicationWithFakeGraphServiceClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		apps         []models.Applicationable
+		wantObjectID string
+		wantErr      error
+	}{
+		{
+			name:         "found",
+			apps:         []models.Applicationable{newFakeApplication("object-id-1", "app")},
+			wantObjectID: "object-id-1",
+		},
+		{
+			name:    "not found",
+			wantErr: errors.New("application with display name 'app' not found"),
+		},
+		{
+			name:    "multiple matches",
+			apps:    []models.Applicationable{newFakeApplication("object-id-1", "app"), newFakeApplication("object-id-2", "app")},
+			wantErr: ErrMultipleMatches,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AzureClient{graphClient: &fakeGraphServiceClient{apps: tt.apps}, throttles: &throttleRecorder{}}
+
+			app, err := c.GetApplication(context.Background(), "app")
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("GetApplication() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetApplication() error = %v", err)
+			}
+			if got := to.String(app.GetId()); got != tt.wantObjectID {
+				t.Errorf("expected application object ID to be %s, got %s", tt.wantObjectID, got)
+			}
+		})
+	}
+}
+
+func TestDeleteFederatedCredentialWithFakeGraphServiceClient(t *testing.T) {
+	fake := &fakeGraphServiceClient{}
+	c := &AzureClient{graphClient: fake, throttles: &throttleRecorder{}}
+
+	if err := c.DeleteFederatedCredential(context.Background(), "object-id", "fic-id"); err != nil {
+		t.Fatalf("DeleteFederatedCredential() error = %v", err)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != "object-id/fic-id" {
+		t.Errorf("expected the federated credential object-id/fic-id to be deleted, got %v", fake.deleted)
+	}
+}
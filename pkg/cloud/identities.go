@@ -0,0 +1,230 @@
+package cloud
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+)
+
+// IdentitySpec is the desired workload identity of a service account: an application with the display name,
+// its service principal and a federated identity credential that trusts the service account.
+type IdentitySpec struct {
+	// DisplayName is the display name of the application, which identifies the identity.
+	DisplayName string
+	// Tags are the tags of the service principal when it is created.
+	Tags []string
+	// FederatedCredential is the federated identity credential of the application.
+	FederatedCredential ExpectedFIC
+}
+
+// IdentityResult is the workload identity of an IdentitySpec and what had to be created or updated to ensure it.
+type IdentityResult struct {
+	DisplayName              string
+	ApplicationObjectID      string
+	AppID                    string
+	ServicePrincipalObjectID string
+
+	ApplicationCreated         bool
+	ServicePrincipalCreated    bool
+	FederatedCredentialCreated bool
+	FederatedCredentialUpdated bool
+}
+
+// EnsureIdentities ensures the workload identity of each spec with at most workers specs ensured in parallel,
+// e.g. to onboard the service accounts of a namespace: the application and its service principal are created if
+// they don't exist and the federated identity credential is created, or updated if it drifted. It is idempotent,
+// so it can be run again after a failure. The result of each spec is at the index of the spec, with what was
+// ensured before a failure, and the errors are those of the specs that failed, in the order of the specs. Specs
+// with the display name of a previous spec fail rather than race to create the same application.
+func (c *AzureClient) EnsureIdentities(ctx context.Context, specs []IdentitySpec, workers int) ([]IdentityResult, []error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]IdentityResult, len(specs))
+		errs    = make([]error, len(specs))
+		seen    = make(map[string]bool, len(specs))
+		indexes []int
+	)
+	for i, spec := range specs {
+		results[i].DisplayName = spec.DisplayName
+		if seen[spec.DisplayName] {
+			errs[i] = errors.Errorf("duplicate identity %s", spec.DisplayName)
+			continue
+		}
+		seen[spec.DisplayName] = true
+		indexes = append(indexes, i)
+	}
+
+	indexCh := make(chan int)
+	for i := 0; i < workers && i < len(indexes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				err := ctx.Err()
+				if err == nil {
+					err = c.ensureIdentity(ctx, specs[i], &results[i])
+				}
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "failed to ensure identity %s", specs[i].DisplayName)
+				}
+			}
+		}()
+	}
+	for _, i := range indexes {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	var aggregated []error
+	for _, err := range errs {
+		if err != nil {
+			aggregated = append(aggregated, err)
+		}
+	}
+	return results, aggregated
+}
+
+// ensureIdentity gets or creates the application and the service principal of the spec and ensures its
+// federated identity credential, recording what was done in the result as it goes.
+func (c *AzureClient) ensureIdentity(ctx context.Context, spec IdentitySpec, result *IdentityResult) error {
+	if err := validateFederatedCredentialMatching(spec.FederatedCredential.Subject, spec.FederatedCredential.ClaimsMatchingExpression); err != nil {
+		return errors.Wrapf(err, "invalid federated credential %s", spec.FederatedCredential.Name)
+	}
+
+	app, err := c.GetApplication(ctx, spec.DisplayName)
+	if err != nil {
+		if !IsNotFound(err) {
+			return errors.Wrap(err, "failed to get application")
+		}
+		c.logInfo("creating application", "displayName", spec.DisplayName)
+		if app, err = c.CreateApplication(ctx, spec.DisplayName); err != nil {
+			return errors.Wrap(err, "failed to create application")
+		}
+		result.ApplicationCreated = true
+	}
+	result.ApplicationObjectID = to.String(app.GetId())
+	result.AppID = to.String(app.GetAppId())
+
+	sp, err := c.GetServicePrincipalByAppID(ctx, result.AppID)
+	if err != nil {
+		if !IsNotFound(err) {
+			return errors.Wrap(err, "failed to get service principal")
+		}
+		c.logInfo("creating service principal", "displayName", spec.DisplayName, "appID", result.AppID)
+		if sp, err = c.CreateServicePrincipal(ctx, result.AppID, spec.Tags); err != nil {
+			return errors.Wrap(err, "failed to create service principal")
+		}
+		result.ServicePrincipalCreated = true
+	}
+	result.ServicePrincipalObjectID = to.String(sp.GetId())
+
+	expected := spec.FederatedCredential
+	fic, err := c.GetFederatedCredentialByName(ctx, result.ApplicationObjectID, expected.Name)
+	switch {
+	case errors.Is(err, ErrFederatedCredentialNotFound):
+		c.logInfo("creating federated credential", "displayName", spec.DisplayName, "name", expected.Name)
+		if _, err := c.AddFederatedCredential(ctx, result.ApplicationObjectID, expected.toFederatedIdentityCredential()); err != nil {
+			return errors.Wrapf(err, "failed to create federated credential %s", expected.Name)
+		}
+		result.FederatedCredentialCreated = true
+	case err != nil:
+		return errors.Wrapf(err, "failed to get federated credential %s", expected.Name)
+	case !expected.matches(fic):
+		c.logInfo("updating federated credential", "displayName", spec.DisplayName, "name", expected.Name)
+		// the name of a federated identity credential is immutable
+		update := expected.toFederatedIdentityCredential()
+		update.SetName(nil)
+		if err := c.UpdateFederatedCredential(ctx, result.ApplicationObjectID, to.String(fic.GetId()), update); err != nil {
+			return errors.Wrapf(err, "failed to update federated credential %s", expected.Name)
+		}
+		result.FederatedCredentialUpdated = true
+	}
+	return nil
+}
+
+// workloadIdentityRollbackTimeout is the timeout of the deletes of the objects created by CreateWorkloadIdentity
+// before it failed, which are not bound to the context of the create since it may be done.
+const workloadIdentityRollbackTimeout = time.Minute
+
+// WorkloadIdentitySpec is a workload identity to create with CreateWorkloadIdentity.
+type WorkloadIdentitySpec struct {
+	// DisplayName is the display name of the application.
+	DisplayName string
+	// Tags are the tags of the application and of its service principal.
+	Tags []string
+	// FederatedCredential is the federated identity credential of the application.
+	FederatedCredential ExpectedFIC
+}
+
+// WorkloadIdentityResult is the workload identity created by CreateWorkloadIdentity.
+type WorkloadIdentityResult struct {
+	ApplicationObjectID      string
+	AppID                    string
+	ServicePrincipalObjectID string
+	FederatedCredentialID    string
+}
+
+// CreateWorkloadIdentity creates the application of the spec, its service principal and its federated identity
+// credential. Unlike EnsureIdentities, nothing may exist beforehand: if a step fails after the application is
+// created, the service principal and the application are deleted on a best-effort basis before the error is
+// returned, so that a failed onboarding doesn't leak a half-created identity. The result then has the IDs of the
+// objects that failed to be deleted, if any.
+func (c *AzureClient) CreateWorkloadIdentity(ctx context.Context, spec WorkloadIdentitySpec) (WorkloadIdentityResult, error) {
+	var result WorkloadIdentityResult
+	expected := spec.FederatedCredential
+	if err := validateFederatedCredentialMatching(expected.Subject, expected.ClaimsMatchingExpression); err != nil {
+		return result, errors.Wrapf(err, "invalid federated credential %s", expected.Name)
+	}
+
+	app, err := c.CreateApplicationWithTags(ctx, spec.DisplayName, spec.Tags)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to create application")
+	}
+	result.ApplicationObjectID = to.String(app.GetId())
+	result.AppID = to.String(app.GetAppId())
+
+	sp, err := c.CreateServicePrincipal(ctx, result.AppID, spec.Tags)
+	if err != nil {
+		return result, c.rollbackWorkloadIdentity(&result, errors.Wrap(err, "failed to create service principal"))
+	}
+	result.ServicePrincipalObjectID = to.String(sp.GetId())
+
+	fic, err := c.AddFederatedCredential(ctx, result.ApplicationObjectID, expected.toFederatedIdentityCredential())
+	if err != nil {
+		return result, c.rollbackWorkloadIdentity(&result, errors.Wrapf(err, "failed to create federated credential %s", expected.Name))
+	}
+	result.FederatedCredentialID = to.String(fic.GetId())
+	return result, nil
+}
+
+// rollbackWorkloadIdentity deletes the service principal and the application of the result, which were created
+// before the error, and clears their IDs once deleted. It returns the error, wrapped with the failed deletes.
+func (c *AzureClient) rollbackWorkloadIdentity(result *WorkloadIdentityResult, err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), workloadIdentityRollbackTimeout)
+	defer cancel()
+
+	if result.ServicePrincipalObjectID != "" {
+		c.log().Info("deleting service principal after failure", redactKeysAndValues([]interface{}{"objectID", result.ServicePrincipalObjectID})...)
+		if deleteErr := c.DeleteServicePrincipal(ctx, result.ServicePrincipalObjectID); deleteErr != nil {
+			err = errors.Wrapf(err, "failed to delete service principal %s: %v", result.ServicePrincipalObjectID, deleteErr)
+		} else {
+			result.ServicePrincipalObjectID = ""
+		}
+	}
+	// deleting the application deletes its service principal too, if it was created despite an error
+	c.log().Info("deleting application after failure", redactKeysAndValues([]interface{}{"objectID", result.ApplicationObjectID})...)
+	if deleteErr := c.DeleteApplication(ctx, result.ApplicationObjectID); deleteErr != nil {
+		return errors.Wrapf(err, "failed to delete application %s: %v", result.ApplicationObjectID, deleteErr)
+	}
+	result.ApplicationObjectID = ""
+	result.AppID = ""
+	return err
+}
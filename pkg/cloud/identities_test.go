@@ -0,0 +1,269 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTenant is an in-memory implementation of the applications and service principals APIs
+// with a federated identity credentials server per application.
+type fakeTenant struct {
+	mu     sync.Mutex
+	apps   map[string]string // display name -> app ID
+	sps    map[string]bool   // app ID -> has a service principal
+	fics   map[string]*fakeFederatedCredentialsServer
+	failOn string // display name of the application that fails to be created
+}
+
+func newFakeTenant() *fakeTenant {
+	return &fakeTenant{
+		apps: make(map[string]string),
+		sps:  make(map[string]bool),
+		fics: make(map[string]*fakeFederatedCredentialsServer),
+	}
+}
+
+// addApplication adds an application whose object ID and app ID are derived from its display name.
+func (f *fakeTenant) addApplication(displayName string, fics ...fakeFederatedCredential) {
+	f.apps[displayName] = displayName + "-app-id"
+	f.fics[displayName+"-object-id"] = newFakeFederatedCredentialsServer(fics...)
+}
+
+func (f *fakeTenant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	w.Header().Set("Content-Type", "application/json")
+	filter := r.URL.Query().Get("$filter")
+
+	switch {
+	case r.URL.Path == "/v1.0/applications" && r.Method == http.MethodGet:
+		value := []map[string]string{}
+		displayName := strings.TrimSuffix(strings.TrimPrefix(filter, "displayName eq '"), "'")
+		if appID, ok := f.apps[displayName]; ok {
+			value = append(value, map[string]string{"id": displayName + "-object-id", "appId": appID, "displayName": displayName})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+	case r.URL.Path == "/v1.0/applications" && r.Method == http.MethodPost:
+		var app map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&app)
+		displayName := app["displayName"]
+		if displayName == f.failOn {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": "Request_BadRequest", "message": "Invalid value specified for property 'displayName'."}}`))
+			break
+		}
+		f.addApplication(displayName)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": displayName + "-object-id", "appId": f.apps[displayName], "displayName": displayName})
+	case r.URL.Path == "/v1.0/servicePrincipals" && r.Method == http.MethodGet:
+		value := []map[string]string{}
+		appID := strings.TrimSuffix(strings.TrimPrefix(filter, "appId eq '"), "'")
+		if f.sps[appID] {
+			value = append(value, map[string]string{"id": appID + "-sp-id", "appId": appID})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+	case r.URL.Path == "/v1.0/servicePrincipals" && r.Method == http.MethodPost:
+		var sp map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&sp)
+		appID, _ := sp["appId"].(string)
+		f.sps[appID] = true
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": appID + "-sp-id", "appId": appID})
+	case strings.HasPrefix(r.URL.Path, "/v1.0/applications/"):
+		objectID := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v1.0/applications/"), "/", 2)[0]
+		s, ok := f.fics[objectID]
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		appFederatedCredentialsHandler(objectID, s)(w, r)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+	f.mu.Unlock()
+}
+
+func TestEnsureIdentities(t *testing.T) {
+	tenant := newFakeTenant()
+	// existing is fully onboarded, drifted has a stale federated credential and no service principal
+	tenant.addApplication("existing", fakeFederatedCredential{ID: "fic-id", Name: "fic", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:existing", Audiences: []string{"api://AzureADTokenExchange"}, Description: federatedCredentialDescription("", "https://issuer.example.com/")})
+	tenant.sps["existing-app-id"] = true
+	tenant.addApplication("drifted", fakeFederatedCredential{ID: "fic-id", Name: "fic", Issuer: "https://old-issuer.example.com/", Subject: "system:serviceaccount:default:drifted", Audiences: []string{"api://AzureADTokenExchange"}})
+	c := newTestAzureClient(t, tenant)
+
+	spec := func(name string) IdentitySpec {
+		return IdentitySpec{
+			DisplayName: name,
+			Tags:        []string{"azwi"},
+			FederatedCredential: ExpectedFIC{
+				Name:      "fic",
+				Issuer:    "https://issuer.example.com/",
+				Subject:   "system:serviceaccount:default:" + name,
+				Audiences: []string{"api://AzureADTokenExchange"},
+			},
+		}
+	}
+
+	results, errs := c.EnsureIdentities(context.Background(), []IdentitySpec{spec("existing"), spec("new"), spec("drifted")}, 2)
+	if len(errs) != 0 {
+		t.Fatalf("EnsureIdentities() errors = %v", errs)
+	}
+	want := []IdentityResult{
+		{
+			DisplayName:              "existing",
+			ApplicationObjectID:      "existing-object-id",
+			AppID:                    "existing-app-id",
+			ServicePrincipalObjectID: "existing-app-id-sp-id",
+		},
+		{
+			DisplayName:                "new",
+			ApplicationObjectID:        "new-object-id",
+			AppID:                      "new-app-id",
+			ServicePrincipalObjectID:   "new-app-id-sp-id",
+			ApplicationCreated:         true,
+			ServicePrincipalCreated:    true,
+			FederatedCredentialCreated: true,
+		},
+		{
+			DisplayName:                "drifted",
+			ApplicationObjectID:        "drifted-object-id",
+			AppID:                      "drifted-app-id",
+			ServicePrincipalObjectID:   "drifted-app-id-sp-id",
+			ServicePrincipalCreated:    true,
+			FederatedCredentialUpdated: true,
+		},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("EnsureIdentities() results = %+v, want %+v", results, want)
+	}
+	for _, name := range []string{"new", "drifted"} {
+		state := tenant.fics[name+"-object-id"].state()
+		if len(state) != 1 || state[0].Issuer != "https://issuer.example.com/" {
+			t.Errorf("expected the federated credential of %s to trust the issuer, got %+v", name, state)
+		}
+	}
+
+	// ensuring the identities again is a no-op
+	results, errs = c.EnsureIdentities(context.Background(), []IdentitySpec{spec("new")}, 1)
+	if len(errs) != 0 {
+		t.Fatalf("EnsureIdentities() errors = %v", errs)
+	}
+	if results[0] != (IdentityResult{DisplayName: "new", ApplicationObjectID: "new-object-id", AppID: "new-app-id", ServicePrincipalObjectID: "new-app-id-sp-id"}) {
+		t.Errorf("expected the identity to exist, got %+v", results[0])
+	}
+}
+
+func TestEnsureIdentitiesPartialFailure(t *testing.T) {
+	tenant := newFakeTenant()
+	tenant.failOn = "invalid"
+	c := newTestAzureClient(t, tenant)
+
+	spec := func(name string) IdentitySpec {
+		return IdentitySpec{
+			DisplayName:         name,
+			FederatedCredential: ExpectedFIC{Name: "fic", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:" + name},
+		}
+	}
+
+	results, errs := c.EnsureIdentities(context.Background(), []IdentitySpec{spec("invalid"), spec("valid"), spec("valid")}, 0)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "failed to ensure identity invalid: failed to create application") {
+		t.Errorf("expected the application of invalid to fail to be created, got %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "duplicate identity valid") {
+		t.Errorf("expected the second valid identity to be a duplicate, got %v", errs[1])
+	}
+	if results[0] != (IdentityResult{DisplayName: "invalid"}) {
+		t.Errorf("expected nothing to be ensured for invalid, got %+v", results[0])
+	}
+	if !results[1].ApplicationCreated || !results[1].ServicePrincipalCreated || !results[1].FederatedCredentialCreated {
+		t.Errorf("expected the identity of valid to be created, got %+v", results[1])
+	}
+}
+
+// newWorkloadIdentityServer returns a handler creating the objects of a workload identity, whose federated
+// credential fails to be created if failFederatedCredential is true, and records the deleted paths.
+func newWorkloadIdentityServer(t *testing.T, failFederatedCredential bool, deleted *[]string) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/applications":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "object-id", "appId": "app-id", "displayName": "app"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/servicePrincipals":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "sp-object-id", "appId": "app-id"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/applications/object-id/federatedIdentityCredentials":
+			if failFederatedCredential {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": {"code": "Request_BadRequest", "message": "Invalid issuer."}}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "fic-id", "name": "fic"}`))
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			*deleted = append(*deleted, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestCreateWorkloadIdentity(t *testing.T) {
+	spec := WorkloadIdentitySpec{
+		DisplayName: "app",
+		Tags:        []string{"azwi"},
+		FederatedCredential: ExpectedFIC{
+			Name:      "fic",
+			Issuer:    "https://issuer.example.com/",
+			Subject:   "system:serviceaccount:default:sa",
+			Audiences: []string{"api://AzureADTokenExchange"},
+		},
+	}
+
+	t.Run("created", func(t *testing.T) {
+		var deleted []string
+		c := newTestAzureClient(t, newWorkloadIdentityServer(t, false, &deleted))
+
+		result, err := c.CreateWorkloadIdentity(context.Background(), spec)
+		if err != nil {
+			t.Fatalf("CreateWorkloadIdentity() error = %v", err)
+		}
+		want := WorkloadIdentityResult{ApplicationObjectID: "object-id", AppID: "app-id", ServicePrincipalObjectID: "sp-object-id", FederatedCredentialID: "fic-id"}
+		if result != want {
+			t.Errorf("CreateWorkloadIdentity() = %+v, want %+v", result, want)
+		}
+		if len(deleted) != 0 {
+			t.Errorf("expected nothing to be deleted, got %v", deleted)
+		}
+	})
+
+	t.Run("rolled back when the federated credential fails", func(t *testing.T) {
+		var deleted []string
+		c := newTestAzureClient(t, newWorkloadIdentityServer(t, true, &deleted))
+
+		result, err := c.CreateWorkloadIdentity(context.Background(), spec)
+		if err == nil || !strings.Contains(err.Error(), "failed to create federated credential fic") {
+			t.Fatalf("expected the federated credential to fail to be created, got %v", err)
+		}
+		if want := []string{"/v1.0/servicePrincipals/sp-object-id", "/v1.0/applications/object-id"}; !reflect.DeepEqual(deleted, want) {
+			t.Errorf("expected %v to be deleted, got %v", want, deleted)
+		}
+		if result != (WorkloadIdentityResult{}) {
+			t.Errorf("expected no object to be left, got %+v", result)
+		}
+	})
+}
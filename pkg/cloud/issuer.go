@@ -0,0 +1,55 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+)
+
+// discoveryDocumentPath is the path of the OpenID Connect discovery document relative to the issuer URL.
+const discoveryDocumentPath = ".well-known/openid-configuration"
+
+// ValidateFederatedCredentialIssuer validates that the issuer of the federated identity credential of the
+// application with the given name serves an OpenID Connect discovery document whose issuer is exactly the
+// issuer of the federated identity credential. Azure AD only exchanges the tokens of the issuer if they match.
+func (c *AzureClient) ValidateFederatedCredentialIssuer(ctx context.Context, objectID, name string) error {
+	fic, err := c.GetFederatedCredentialByName(ctx, objectID, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get federated credential %s", name)
+	}
+	issuer := to.String(fic.GetIssuer())
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/" + discoveryDocumentPath
+	c.logDebug("Validating federated credential issuer", "objectID", objectID, "name", name, "discoveryURL", discoveryURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch discovery document from %s", discoveryURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to fetch discovery document from %s: unexpected status code %d", discoveryURL, resp.StatusCode)
+	}
+	var doc struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return errors.Wrapf(err, "failed to decode discovery document from %s", discoveryURL)
+	}
+	if doc.Issuer != issuer {
+		return errors.Errorf("issuer %q of the discovery document does not match the issuer %q of federated credential %s", doc.Issuer, issuer, name)
+	}
+	return nil
+}
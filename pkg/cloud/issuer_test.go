@@ -0,0 +1,80 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateFederatedCredentialIssuer(t *testing.T) {
+	tests := []struct {
+		name          string
+		docIssuerPath string
+		wantErr       string
+	}{
+		{
+			name:          "matching issuer",
+			docIssuerPath: "/issuer",
+		},
+		{
+			name:          "trailing slash",
+			docIssuerPath: "/issuer/",
+			wantErr:       "does not match the issuer",
+		},
+		{
+			name:          "different issuer",
+			docIssuerPath: "/other-issuer",
+			wantErr:       "does not match the issuer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var issuerURL string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/issuer/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"issuer": "%s%s", "jwks_uri": "%s/openid/v1/jwks"}`, strings.TrimSuffix(issuerURL, "/issuer"), tt.docIssuerPath, issuerURL)
+			})
+			mux.HandleFunc(testFederatedCredentialsPath, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"value": [{"id": "fic-id", "name": "fic", "issuer": "%s", "subject": "system:serviceaccount:default:sa"}]}`, issuerURL)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			issuerURL = server.URL + "/issuer"
+			c := newTestAzureClient(t, mux)
+
+			err := c.ValidateFederatedCredentialIssuer(context.Background(), "object-id", "fic")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFederatedCredentialIssuer() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFederatedCredentialIssuer() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFederatedCredentialIssuerUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(testFederatedCredentialsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value": [{"id": "fic-id", "name": "fic", "issuer": "%s/issuer", "subject": "system:serviceaccount:default:sa"}]}`, server.URL)
+	})
+	c := newTestAzureClient(t, mux)
+
+	err := c.ValidateFederatedCredentialIssuer(context.Background(), "object-id", "fic")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status code 404") {
+		t.Errorf("ValidateFederatedCredentialIssuer() error = %v, want unexpected status code error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package cloud
+
+import (
+	"regexp"
+	"strings"
+
+	"monis.app/mlog"
+)
+
+const (
+	// redactedLogValue replaces the secret values in the logs.
+	redactedLogValue = "[REDACTED]"
+	// loggedObjectIDLength is the number of leading characters of the directory object IDs that are logged, the first
+	// group of a GUID, which is enough to tell the objects of an operation apart without identifying them.
+	loggedObjectIDLength = 8
+)
+
+// objectIDPattern matches the GUIDs in the URL paths of the requests, e.g. the object ID in /applications/{id}.
+var objectIDPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// logDebug is like the logDebug method of AzureClient for the code that is not run by a client, which logs
+// with the global logger.
+func logDebug(msg string, keysAndValues ...interface{}) {
+	mlog.Debug(msg, redactKeysAndValues(keysAndValues)...)
+}
+
+// logTrace is like logDebug at the trace level.
+func logTrace(msg string, keysAndValues ...interface{}) {
+	mlog.Trace(msg, redactKeysAndValues(keysAndValues)...)
+}
+
+// log returns the logger of the client, which defaults to the global logger.
+func (c *AzureClient) log() mlog.Logger {
+	if c == nil || c.logger == nil {
+		return mlog.New()
+	}
+	return c.logger
+}
+
+// logDebug logs the message and the key-value pairs at the debug level, with the sensitive values redacted by
+// redactKeysAndValues, so that enabling debug logs in production doesn't leak secrets or directory object IDs.
+func (c *AzureClient) logDebug(msg string, keysAndValues ...interface{}) {
+	c.log().Debug(msg, redactKeysAndValues(keysAndValues)...)
+}
+
+// logInfo is like logDebug at the info level, for the progress of the operations that is always shown.
+func (c *AzureClient) logInfo(msg string, keysAndValues ...interface{}) {
+	c.log().Info(msg, redactKeysAndValues(keysAndValues)...)
+}
+
+// redactKeysAndValues returns a copy of the key-value pairs of a log entry with the values of the secret keys, e.g.
+// secret or password, replaced by redactedLogValue, and the values of the directory object ID keys, e.g. objectID or
+// servicePrincipalObjectID, truncated to their first loggedObjectIDLength characters, like the object IDs in the
+// URL paths of the path key.
+func redactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	copy(redacted, keysAndValues)
+	for i := 0; i+1 < len(redacted); i += 2 {
+		key, ok := redacted[i].(string)
+		if !ok {
+			continue
+		}
+		switch {
+		case isSecretLogKey(key):
+			redacted[i+1] = redactedLogValue
+		case isObjectIDLogKey(key):
+			redacted[i+1] = truncateObjectIDs(redacted[i+1])
+		case key == "path":
+			if path, ok := redacted[i+1].(string); ok {
+				redacted[i+1] = objectIDPattern.ReplaceAllStringFunc(path, truncateObjectID)
+			}
+		}
+	}
+	return redacted
+}
+
+// isSecretLogKey returns true if the values of the log key are secret material, which is never logged.
+func isSecretLogKey(key string) bool {
+	key = strings.ToLower(key)
+	switch key {
+	case "key", "token", "accesstoken", "certificate", "privatekey":
+		return true
+	}
+	return strings.Contains(key, "secret") || strings.Contains(key, "password")
+}
+
+// isObjectIDLogKey returns true if the values of the log key are directory object IDs.
+func isObjectIDLogKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasSuffix(key, "objectid") || strings.HasSuffix(key, "objectids") || key == "principalid"
+}
+
+// truncateObjectIDs truncates the object ID, or each of the object IDs, to its first loggedObjectIDLength characters.
+func truncateObjectIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return truncateObjectID(v)
+	case []string:
+		truncated := make([]string, 0, len(v))
+		for _, id := range v {
+			truncated = append(truncated, truncateObjectID(id))
+		}
+		return truncated
+	default:
+		return value
+	}
+}
+
+func truncateObjectID(id string) string {
+	if len(id) <= loggedObjectIDLength {
+		return id
+	}
+	return id[:loggedObjectIDLength] + "..."
+}
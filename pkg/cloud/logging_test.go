@@ -0,0 +1,87 @@
+package cloud
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"monis.app/mlog"
+)
+
+func TestRedactKeysAndValues(t *testing.T) {
+	keysAndValues := []interface{}{
+		"secret", "s3cr3t",
+		"clientSecret", "s3cr3t",
+		"password", "p4ssw0rd",
+		"objectID", "00000000-0000-0000-0000-000000000001",
+		"servicePrincipalObjectID", "00000000-0000-0000-0000-000000000002",
+		"federatedCredentialIDs", []string{"fic-id"},
+		"principalID", "00000000-0000-0000-0000-000000000003",
+		"sourceObjectIDs", []string{"00000000-0000-0000-0000-000000000004", "short"},
+		"displayName", "app",
+		"path", "/v1.0/applications/00000000-0000-0000-0000-000000000005/federatedIdentityCredentials",
+		"count", 2,
+	}
+	want := []interface{}{
+		"secret", "[REDACTED]",
+		"clientSecret", "[REDACTED]",
+		"password", "[REDACTED]",
+		"objectID", "00000000...",
+		"servicePrincipalObjectID", "00000000...",
+		"federatedCredentialIDs", []string{"fic-id"},
+		"principalID", "00000000...",
+		"sourceObjectIDs", []string{"00000000...", "short"},
+		"displayName", "app",
+		"path", "/v1.0/applications/00000000.../federatedIdentityCredentials",
+		"count", 2,
+	}
+
+	got := redactKeysAndValues(keysAndValues)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactKeysAndValues() = %v, want %v", got, want)
+	}
+	// the key-value pairs of the caller are left untouched
+	if keysAndValues[1] != "s3cr3t" {
+		t.Errorf("expected the key-value pairs not to be modified, got %v", keysAndValues)
+	}
+}
+
+func TestLogDebugRedacts(t *testing.T) {
+	const (
+		secret   = "s3cr3t-v4lu3"
+		objectID = "12345678-9abc-def0-1234-56789abcdef0"
+	)
+
+	logs := captureDebugLogs(t, func() {
+		logDebug("Adding application password", "objectID", objectID, "secretText", secret, "displayName", "fallback")
+	})
+
+	if !strings.Contains(logs, "Adding application password") || !strings.Contains(logs, "fallback") {
+		t.Errorf("expected the debug log to be captured, got:\n%s", logs)
+	}
+	if strings.Contains(logs, secret) {
+		t.Errorf("the secret is logged:\n%s", logs)
+	}
+	if strings.Contains(logs, objectID) {
+		t.Errorf("the full object ID is logged:\n%s", logs)
+	}
+	if !strings.Contains(logs, "12345678...") {
+		t.Errorf("expected the truncated object ID to be logged, got:\n%s", logs)
+	}
+}
+
+func TestAzureClientLogDebugUsesLogger(t *testing.T) {
+	const objectID = "12345678-9abc-def0-1234-56789abcdef0"
+
+	logs := captureDebugLogs(t, func() {
+		c := &AzureClient{logger: mlog.New().WithValues("client", "custom-logger")}
+		c.logDebug("Getting application", "objectID", objectID)
+	})
+
+	if !strings.Contains(logs, "custom-logger") {
+		t.Errorf("expected the debug log to be written by the client logger, got:\n%s", logs)
+	}
+	if strings.Contains(logs, objectID) {
+		t.Errorf("the full object ID is logged:\n%s", logs)
+	}
+}
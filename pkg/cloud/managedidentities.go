@@ -0,0 +1,278 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
+)
+
+const (
+	// managedIdentityAPIVersion is the first stable API version of Microsoft.ManagedIdentity
+	// that supports federated identity credentials on user-assigned managed identities.
+	managedIdentityAPIVersion = "2023-01-31"
+
+	managedIdentityProvider          = "Microsoft.ManagedIdentity"
+	userAssignedIdentityResourceType = "userAssignedIdentities"
+)
+
+// UserAssignedIdentity contains the identifiers of a user-assigned managed identity.
+type UserAssignedIdentity struct {
+	ID          string
+	Name        string
+	ClientID    string
+	TenantID    string
+	PrincipalID string
+}
+
+// FederatedCredential is a federated identity credential of a user-assigned managed identity.
+type FederatedCredential struct {
+	Name      string
+	Issuer    string
+	Subject   string
+	Audiences []string
+}
+
+// userAssignedIdentityResource is the ARM representation of a user-assigned managed identity.
+type userAssignedIdentityResource struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Properties struct {
+		ClientID    string `json:"clientId"`
+		TenantID    string `json:"tenantId"`
+		PrincipalID string `json:"principalId"`
+	} `json:"properties"`
+}
+
+// federatedCredentialResource is the ARM representation of a federated identity credential.
+type federatedCredentialResource struct {
+	Name       string                        `json:"name,omitempty"`
+	Properties federatedCredentialProperties `json:"properties"`
+}
+
+// federatedCredentialListResult is a page of federated identity credentials returned by ARM.
+type federatedCredentialListResult struct {
+	Value    []federatedCredentialResource `json:"value"`
+	NextLink string                        `json:"nextLink"`
+}
+
+type federatedCredentialProperties struct {
+	Issuer    string   `json:"issuer"`
+	Subject   string   `json:"subject"`
+	Audiences []string `json:"audiences"`
+}
+
+// GetUserAssignedIdentity gets a user-assigned managed identity by its resource ID.
+func (c *AzureClient) GetUserAssignedIdentity(ctx context.Context, resourceID string) (UserAssignedIdentity, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	var result UserAssignedIdentity
+	if err := validateUserAssignedIdentityID(resourceID); err != nil {
+		return result, err
+	}
+
+	c.logDebug("Getting user-assigned managed identity", "resourceID", resourceID)
+	var identity userAssignedIdentityResource
+	resp, err := c.sendManagedIdentityRequest(ctx, resourceID, autorest.AsGet(), nil)
+	if err != nil {
+		return result, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = autorest.Respond(resp, autorest.ByClosing())
+		return result, errors.Errorf("user-assigned managed identity '%s' not found", resourceID)
+	}
+	if err := autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&identity),
+		autorest.ByClosing()); err != nil {
+		return result, errors.Wrapf(err, "failed to get user-assigned managed identity %s", resourceID)
+	}
+
+	return UserAssignedIdentity{
+		ID:          identity.ID,
+		Name:        identity.Name,
+		ClientID:    identity.Properties.ClientID,
+		TenantID:    identity.Properties.TenantID,
+		PrincipalID: identity.Properties.PrincipalID,
+	}, nil
+}
+
+// AddManagedIdentityFederatedCredential adds a federated identity credential to the user-assigned managed identity.
+// The credential is created or updated in place if a credential with the same name already exists.
+func (c *AzureClient) AddManagedIdentityFederatedCredential(ctx context.Context, resourceID string, fic FederatedCredential) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if err := validateUserAssignedIdentityID(resourceID); err != nil {
+		return err
+	}
+
+	if c.skipInDryRun("adding federated credential to user-assigned managed identity", "resourceID", resourceID, "name", fic.Name, "issuer", fic.Issuer, "subject", fic.Subject) {
+		return nil
+	}
+	c.logDebug("Adding federated credential to user-assigned managed identity", "resourceID", resourceID, "name", fic.Name)
+	body := federatedCredentialResource{
+		Properties: federatedCredentialProperties{
+			Issuer:    fic.Issuer,
+			Subject:   fic.Subject,
+			Audiences: fic.Audiences,
+		},
+	}
+	resp, err := c.sendManagedIdentityRequest(ctx, federatedCredentialPath(resourceID, fic.Name), autorest.AsPut(), body)
+	if err != nil {
+		return err
+	}
+	if err := autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusCreated),
+		autorest.ByClosing()); err != nil {
+		return errors.Wrapf(err, "failed to add federated credential %s to user-assigned managed identity %s", fic.Name, resourceID)
+	}
+	return nil
+}
+
+// GetManagedIdentityFederatedCredential gets the federated identity credential of the user-assigned
+// managed identity that matches the given issuer and subject.
+func (c *AzureClient) GetManagedIdentityFederatedCredential(ctx context.Context, resourceID, issuer, subject string) (FederatedCredential, error) {
+	fics, err := c.ListManagedIdentityFederatedCredentials(ctx, resourceID)
+	if err != nil {
+		return FederatedCredential{}, err
+	}
+	for _, fic := range fics {
+		if fic.Issuer == issuer && fic.Subject == subject {
+			return fic, nil
+		}
+	}
+	return FederatedCredential{}, ErrFederatedCredentialNotFound
+}
+
+// ListManagedIdentityFederatedCredentials lists all federated identity credentials of the user-assigned managed identity.
+func (c *AzureClient) ListManagedIdentityFederatedCredentials(ctx context.Context, resourceID string) ([]FederatedCredential, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if err := validateUserAssignedIdentityID(resourceID); err != nil {
+		return nil, err
+	}
+
+	c.logDebug("Listing federated credentials of user-assigned managed identity", "resourceID", resourceID)
+	resp, err := c.sendManagedIdentityRequest(ctx, strings.TrimRight(resourceID, "/")+"/federatedIdentityCredentials", autorest.AsGet(), nil)
+	var fics []FederatedCredential
+	for {
+		if err != nil {
+			return partialResults(c, fics), err
+		}
+		var page federatedCredentialListResult
+		if err := autorest.Respond(resp,
+			azure.WithErrorUnlessStatusCode(http.StatusOK),
+			autorest.ByUnmarshallingJSON(&page),
+			autorest.ByClosing()); err != nil {
+			return partialResults(c, fics), errors.Wrapf(err, "failed to list federated credentials of user-assigned managed identity %s", resourceID)
+		}
+		for _, fic := range page.Value {
+			fics = append(fics, FederatedCredential{
+				Name:      fic.Name,
+				Issuer:    fic.Properties.Issuer,
+				Subject:   fic.Properties.Subject,
+				Audiences: fic.Properties.Audiences,
+			})
+		}
+		if page.NextLink == "" {
+			return fics, nil
+		}
+		// the request is sent with the ARM bearer token, so only follow links to the ARM endpoint
+		if err := c.validateResourceManagerURL(page.NextLink); err != nil {
+			return partialResults(c, fics), err
+		}
+		resp, err = c.prepareAndSend(ctx, autorest.AsGet(), autorest.WithBaseURL(page.NextLink))
+	}
+}
+
+// DeleteManagedIdentityFederatedCredential deletes the named federated identity credential of the user-assigned managed identity.
+func (c *AzureClient) DeleteManagedIdentityFederatedCredential(ctx context.Context, resourceID, name string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if err := validateUserAssignedIdentityID(resourceID); err != nil {
+		return err
+	}
+
+	if c.skipInDryRun("deleting federated credential of user-assigned managed identity", "resourceID", resourceID, "name", name) {
+		return nil
+	}
+	c.logDebug("Deleting federated credential of user-assigned managed identity", "resourceID", resourceID, "name", name)
+	resp, err := c.sendManagedIdentityRequest(ctx, federatedCredentialPath(resourceID, name), autorest.AsDelete(), nil)
+	if err != nil {
+		return err
+	}
+	if err := autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusNoContent),
+		autorest.ByClosing()); err != nil {
+		return errors.Wrapf(err, "failed to delete federated credential %s of user-assigned managed identity %s", name, resourceID)
+	}
+	return nil
+}
+
+// sendManagedIdentityRequest sends a request for the given resource path to the Microsoft.ManagedIdentity resource provider.
+func (c *AzureClient) sendManagedIdentityRequest(ctx context.Context, path string, method autorest.PrepareDecorator, body interface{}) (*http.Response, error) {
+	decorators := []autorest.PrepareDecorator{
+		method,
+		autorest.WithBaseURL(c.environment.ResourceManagerEndpoint),
+		autorest.WithPath(path),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": managedIdentityAPIVersion}),
+	}
+	if body != nil {
+		decorators = append(decorators, autorest.AsContentType("application/json; charset=utf-8"), autorest.WithJSON(body))
+	}
+	return c.prepareAndSend(ctx, decorators...)
+}
+
+// prepareAndSend prepares a request with the given decorators and sends it with the managed identities client.
+func (c *AzureClient) prepareAndSend(ctx context.Context, decorators ...autorest.PrepareDecorator) (*http.Response, error) {
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx), decorators...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to prepare request")
+	}
+	resp, err := c.managedIdentitiesClient.Send(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+	return resp, nil
+}
+
+// validateResourceManagerURL returns an error if the URL is not on the configured ARM endpoint.
+func (c *AzureClient) validateResourceManagerURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse URL %s", rawURL)
+	}
+	endpoint, err := url.Parse(c.environment.ResourceManagerEndpoint)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse resource manager endpoint %s", c.environment.ResourceManagerEndpoint)
+	}
+	if !strings.EqualFold(u.Scheme, endpoint.Scheme) || !strings.EqualFold(u.Host, endpoint.Host) {
+		return errors.Errorf("refusing to follow URL %s, which is not on the resource manager endpoint %s", rawURL, c.environment.ResourceManagerEndpoint)
+	}
+	return nil
+}
+
+// federatedCredentialPath returns the resource path of the named federated identity credential of the user-assigned managed identity.
+func federatedCredentialPath(resourceID, name string) string {
+	return strings.TrimRight(resourceID, "/") + "/federatedIdentityCredentials/" + autorest.Encode("path", name)
+}
+
+// validateUserAssignedIdentityID returns an error if the resource ID is not the ID of a user-assigned managed identity.
+func validateUserAssignedIdentityID(resourceID string) error {
+	r, err := azure.ParseResourceID(resourceID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse resource ID %s", resourceID)
+	}
+	if !strings.EqualFold(r.Provider, managedIdentityProvider) || !strings.EqualFold(r.ResourceType, userAssignedIdentityResourceType) {
+		return errors.Errorf("resource ID %s is not a user-assigned managed identity", resourceID)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+const testUserAssignedIdentityID = "/subscriptions/subscription-id/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity"
+
+func TestGetUserAssignedIdentity(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != testUserAssignedIdentityID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("api-version"); got != managedIdentityAPIVersion {
+			t.Errorf("expected api-version %s, got %s", managedIdentityAPIVersion, got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "` + testUserAssignedIdentityID + `",
+			"name": "identity",
+			"properties": {"clientId": "client-id", "tenantId": "tenant-id", "principalId": "principal-id"}
+		}`))
+	}))
+
+	identity, err := c.GetUserAssignedIdentity(context.Background(), testUserAssignedIdentityID)
+	if err != nil {
+		t.Fatalf("GetUserAssignedIdentity() error = %v", err)
+	}
+	want := UserAssignedIdentity{
+		ID:          testUserAssignedIdentityID,
+		Name:        "identity",
+		ClientID:    "client-id",
+		TenantID:    "tenant-id",
+		PrincipalID: "principal-id",
+	}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("GetUserAssignedIdentity() = %+v, want %+v", identity, want)
+	}
+
+	_, err = c.GetUserAssignedIdentity(context.Background(), strings.Replace(testUserAssignedIdentityID, "identity", "missing", 1))
+	if err == nil || !IsNotFound(err) {
+		t.Errorf("GetUserAssignedIdentity() error = %v, want not found error", err)
+	}
+}
+
+func TestGetUserAssignedIdentityInvalidResourceID(t *testing.T) {
+	c := newTestAzureClient(t, http.NotFoundHandler())
+
+	for _, resourceID := range []string{
+		"identity",
+		"/subscriptions/subscription-id/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm",
+	} {
+		if _, err := c.GetUserAssignedIdentity(context.Background(), resourceID); err == nil {
+			t.Errorf("GetUserAssignedIdentity(%q) error = nil, want error", resourceID)
+		}
+	}
+}
+
+func TestAddManagedIdentityFederatedCredential(t *testing.T) {
+	var got federatedCredentialResource
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != testUserAssignedIdentityID+"/federatedIdentityCredentials/fic" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	fic := FederatedCredential{
+		Name:      "fic",
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:sa",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+	if err := c.AddManagedIdentityFederatedCredential(context.Background(), testUserAssignedIdentityID, fic); err != nil {
+		t.Fatalf("AddManagedIdentityFederatedCredential() error = %v", err)
+	}
+	want := federatedCredentialResource{
+		Properties: federatedCredentialProperties{
+			Issuer:    fic.Issuer,
+			Subject:   fic.Subject,
+			Audiences: fic.Audiences,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request body = %+v, want %+v", got, want)
+	}
+}
+
+func TestAddManagedIdentityFederatedCredentialError(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": "BadRequest", "message": "invalid issuer"}}`))
+	}))
+
+	err := c.AddManagedIdentityFederatedCredential(context.Background(), testUserAssignedIdentityID, FederatedCredential{Name: "fic"})
+	if err == nil || !strings.Contains(err.Error(), "invalid issuer") {
+		t.Errorf("AddManagedIdentityFederatedCredential() error = %v, want error containing invalid issuer", err)
+	}
+}
+
+// fakeManagedIdentityFederatedCredentialsServer is an in-memory implementation of the
+// federated identity credentials API of a user-assigned managed identity that returns
+// one federated identity credential per page.
+type fakeManagedIdentityFederatedCredentialsServer struct {
+	mu   sync.Mutex
+	fics []federatedCredentialResource
+}
+
+func (s *fakeManagedIdentityFederatedCredentialsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	prefix := testUserAssignedIdentityID + "/federatedIdentityCredentials"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		page := federatedCredentialListResult{}
+		i, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if i < len(s.fics) {
+			page.Value = s.fics[i : i+1]
+		}
+		if i+1 < len(s.fics) {
+			page.NextLink = fmt.Sprintf("http://%s%s?api-version=%s&page=%d", r.Host, prefix, managedIdentityAPIVersion, i+1)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodDelete && name != "":
+		for i, fic := range s.fics {
+			if fic.Name == name {
+				s.fics = append(s.fics[:i], s.fics[i+1:]...)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestManagedIdentityFederatedCredentials(t *testing.T) {
+	audiences := []string{"api://AzureADTokenExchange"}
+	server := &fakeManagedIdentityFederatedCredentialsServer{
+		fics: []federatedCredentialResource{
+			{Name: "fic-1", Properties: federatedCredentialProperties{Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences}},
+			{Name: "fic-2", Properties: federatedCredentialProperties{Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences}},
+		},
+	}
+	c := newTestAzureClient(t, server)
+	ctx := context.Background()
+
+	fics, err := c.ListManagedIdentityFederatedCredentials(ctx, testUserAssignedIdentityID)
+	if err != nil {
+		t.Fatalf("ListManagedIdentityFederatedCredentials() error = %v", err)
+	}
+	want := []FederatedCredential{
+		{Name: "fic-1", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-1", Audiences: audiences},
+		{Name: "fic-2", Issuer: "https://issuer.example.com/", Subject: "system:serviceaccount:default:sa-2", Audiences: audiences},
+	}
+	if !reflect.DeepEqual(fics, want) {
+		t.Errorf("ListManagedIdentityFederatedCredentials() = %+v, want %+v", fics, want)
+	}
+
+	fic, err := c.GetManagedIdentityFederatedCredential(ctx, testUserAssignedIdentityID, "https://issuer.example.com/", "system:serviceaccount:default:sa-2")
+	if err != nil {
+		t.Fatalf("GetManagedIdentityFederatedCredential() error = %v", err)
+	}
+	if !reflect.DeepEqual(fic, want[1]) {
+		t.Errorf("GetManagedIdentityFederatedCredential() = %+v, want %+v", fic, want[1])
+	}
+
+	if err := c.DeleteManagedIdentityFederatedCredential(ctx, testUserAssignedIdentityID, "fic-2"); err != nil {
+		t.Fatalf("DeleteManagedIdentityFederatedCredential() error = %v", err)
+	}
+	// deleting a federated identity credential that doesn't exist is a no-op
+	if err := c.DeleteManagedIdentityFederatedCredential(ctx, testUserAssignedIdentityID, "fic-2"); err != nil {
+		t.Fatalf("DeleteManagedIdentityFederatedCredential() error = %v", err)
+	}
+
+	_, err = c.GetManagedIdentityFederatedCredential(ctx, testUserAssignedIdentityID, "https://issuer.example.com/", "system:serviceaccount:default:sa-2")
+	if !errors.Is(err, ErrFederatedCredentialNotFound) {
+		t.Errorf("GetManagedIdentityFederatedCredential() error = %v, want %v", err, ErrFederatedCredentialNotFound)
+	}
+}
+
+func TestListManagedIdentityFederatedCredentialsNextLinkOnOtherHost(t *testing.T) {
+	var requests int
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value": [], "nextLink": "https://attacker.example.com/page-2"}`))
+	}))
+
+	_, err := c.ListManagedIdentityFederatedCredentials(context.Background(), testUserAssignedIdentityID)
+	if err == nil || !strings.Contains(err.Error(), "not on the resource manager endpoint") {
+		t.Errorf("ListManagedIdentityFederatedCredentials() error = %v, want error about the resource manager endpoint", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
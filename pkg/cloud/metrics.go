@@ -0,0 +1,115 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// MetricsRecorder records the Graph operations of an AzureClient, e.g. to export their count, latency and error
+// rate. It must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RecordGraphCall is called when an operation that sent Graph requests returns. The operation is the name of
+	// the AzureClient method, e.g. CreateApplication, and the duration includes the retries of its requests. The
+	// error is the error of the last Graph request of the operation that failed, or nil if none failed.
+	RecordGraphCall(operation string, duration time.Duration, err error)
+}
+
+// SetMetricsRecorder sets the recorder of the Graph operations of the AzureClient. A nil recorder records
+// nothing, which is the default. The operations are only recorded by the AzureClients created with one of
+// the NewAzureClient functions, whose HTTP client attributes the Graph requests to their operation.
+func (c *AzureClient) SetMetricsRecorder(recorder MetricsRecorder) {
+	c.metrics = recorder
+}
+
+// graphOperation is an operation of an AzureClient, which the Graph requests sent with its context are attributed to.
+type graphOperation struct {
+	name  string
+	start time.Time
+
+	mu       sync.Mutex
+	requests int
+	err      error
+}
+
+type graphOperationKey struct{}
+
+// startGraphOperation returns a context that attributes the Graph requests sent with it to the operation of the
+// exported AzureClient method that called withDefaultTimeout, and a function that records the operation when it
+// returns. The requests of the methods it calls are attributed to the same operation.
+func (c *AzureClient) startGraphOperation(ctx context.Context) (context.Context, func()) {
+	if c.metrics == nil {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Value(graphOperationKey{}).(*graphOperation); ok {
+		return ctx, func() {}
+	}
+	// skip runtime.Callers, this function and withDefaultTimeout to get the method that called withDefaultTimeout
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(3, pcs) == 0 {
+		return ctx, func() {}
+	}
+	frame, _ := runtime.CallersFrames(pcs).Next()
+	name := operationName(frame.Function)
+	if name == "" {
+		return ctx, func() {}
+	}
+
+	op := &graphOperation{name: name, start: time.Now()}
+	return context.WithValue(ctx, graphOperationKey{}, op), func() {
+		op.mu.Lock()
+		defer op.mu.Unlock()
+		if op.requests == 0 {
+			return
+		}
+		c.metrics.RecordGraphCall(op.name, time.Since(op.start), op.err)
+	}
+}
+
+// operationName returns the name of the exported AzureClient method of the function, e.g. CreateApplication for
+// github.com/Azure/azure-workload-identity/pkg/cloud.(*AzureClient).CreateApplication.func1, or an empty string if
+// the function is not in an exported method.
+func operationName(function string) string {
+	const receiver = "(*AzureClient)."
+	i := strings.Index(function, receiver)
+	if i < 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(function[i+len(receiver):], ".")
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		return ""
+	}
+	return name
+}
+
+// graphOperationTransport is an http.RoundTripper that attributes the Graph requests to the operation of their
+// context, if any. It should be above the retry middleware so that only the final response of a request is seen.
+type graphOperationTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *graphOperationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	op, ok := req.Context().Value(graphOperationKey{}).(*graphOperation)
+	if !ok {
+		return resp, err
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.requests++
+	switch {
+	case err != nil:
+		op.err = err
+	case resp.StatusCode >= http.StatusBadRequest:
+		op.err = errors.Errorf("Graph request %s %s failed with status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return resp, err
+}
@@ -0,0 +1,101 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedGraphCall is a Graph operation recorded by the fakeMetricsRecorder.
+type recordedGraphCall struct {
+	operation string
+	failed    bool
+}
+
+// fakeMetricsRecorder records the Graph operations.
+type fakeMetricsRecorder struct {
+	mu    sync.Mutex
+	calls []recordedGraphCall
+}
+
+func (r *fakeMetricsRecorder) RecordGraphCall(operation string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, recordedGraphCall{operation: operation, failed: err != nil})
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "object-id", "appId": "app-id", "displayName": "app"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+	recorder := &fakeMetricsRecorder{}
+	c.SetMetricsRecorder(recorder)
+	ctx := context.Background()
+
+	if _, err := c.GetApplication(ctx, "app"); err != nil {
+		t.Fatalf("GetApplication() error = %v", err)
+	}
+	if err := c.DeleteApplication(ctx, "object-id"); err == nil {
+		t.Fatal("DeleteApplication() expected error")
+	}
+	// a method that sends no request itself records the operations of the methods it calls
+	if err := c.DeleteApplicationByDisplayName(ctx, "app"); err == nil {
+		t.Fatal("DeleteApplicationByDisplayName() expected error")
+	}
+
+	want := []recordedGraphCall{
+		{operation: "GetApplication"},
+		{operation: "DeleteApplication", failed: true},
+		{operation: "GetApplication"},
+		{operation: "DeleteApplication", failed: true},
+	}
+	if len(recorder.calls) != len(want) {
+		t.Fatalf("recorded %+v, want %+v", recorder.calls, want)
+	}
+	for i := range want {
+		if recorder.calls[i] != want[i] {
+			t.Errorf("recorded call %d = %+v, want %+v", i, recorder.calls[i], want[i])
+		}
+	}
+}
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{
+			function: "github.com/Azure/azure-workload-identity/pkg/cloud.(*AzureClient).CreateApplication",
+			want:     "CreateApplication",
+		},
+		{
+			function: "github.com/Azure/azure-workload-identity/pkg/cloud.(*AzureClient).EnsureIdentities.func1",
+			want:     "EnsureIdentities",
+		},
+		{
+			function: "github.com/Azure/azure-workload-identity/pkg/cloud.(*AzureClient).patchApplication",
+		},
+		{
+			function: "github.com/Azure/azure-workload-identity/pkg/cloud.GetGraphError",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			if got := operationName(tt.function); got != tt.want {
+				t.Errorf("operationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,10 +7,15 @@ package mock_cloud
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	authorization "github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-01-01-preview/authorization"
+	cloud "github.com/Azure/azure-workload-identity/pkg/cloud"
 	gomock "github.com/golang/mock/gomock"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	applications "github.com/microsoftgraph/msgraph-sdk-go/applications"
 	models "github.com/microsoftgraph/msgraph-sdk-go/models"
+	serviceprincipals "github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
 )
 
 // MockInterface is a mock of Interface interface.
@@ -36,20 +41,227 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
-// AddFederatedCredential mocks base method.
-func (m *MockInterface) AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) error {
+// AddAppRoleAssignment mocks base method.
+func (m *MockInterface) AddAppRoleAssignment(ctx context.Context, spObjectID, resourceSPObjectID, appRoleID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddFederatedCredential", ctx, objectID, fic)
+	ret := m.ctrl.Call(m, "AddAppRoleAssignment", ctx, spObjectID, resourceSPObjectID, appRoleID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddAppRoleAssignment indicates an expected call of AddAppRoleAssignment.
+func (mr *MockInterfaceMockRecorder) AddAppRoleAssignment(ctx, spObjectID, resourceSPObjectID, appRoleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAppRoleAssignment", reflect.TypeOf((*MockInterface)(nil).AddAppRoleAssignment), ctx, spObjectID, resourceSPObjectID, appRoleID)
+}
+
+// AddApplicationAppRole mocks base method.
+func (m *MockInterface) AddApplicationAppRole(ctx context.Context, objectID string, role models.AppRoleable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddApplicationAppRole", ctx, objectID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddApplicationAppRole indicates an expected call of AddApplicationAppRole.
+func (mr *MockInterfaceMockRecorder) AddApplicationAppRole(ctx, objectID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddApplicationAppRole", reflect.TypeOf((*MockInterface)(nil).AddApplicationAppRole), ctx, objectID, role)
+}
+
+// AddApplicationCertificate mocks base method.
+func (m *MockInterface) AddApplicationCertificate(ctx context.Context, objectID string, cert []byte, displayName string, notAfter time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddApplicationCertificate", ctx, objectID, cert, displayName, notAfter)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddApplicationCertificate indicates an expected call of AddApplicationCertificate.
+func (mr *MockInterfaceMockRecorder) AddApplicationCertificate(ctx, objectID, cert, displayName, notAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddApplicationCertificate", reflect.TypeOf((*MockInterface)(nil).AddApplicationCertificate), ctx, objectID, cert, displayName, notAfter)
+}
+
+// AddApplicationOAuth2Scope mocks base method.
+func (m *MockInterface) AddApplicationOAuth2Scope(ctx context.Context, objectID string, scope models.PermissionScopeable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddApplicationOAuth2Scope", ctx, objectID, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddApplicationOAuth2Scope indicates an expected call of AddApplicationOAuth2Scope.
+func (mr *MockInterfaceMockRecorder) AddApplicationOAuth2Scope(ctx, objectID, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddApplicationOAuth2Scope", reflect.TypeOf((*MockInterface)(nil).AddApplicationOAuth2Scope), ctx, objectID, scope)
+}
+
+// AddApplicationOwner mocks base method.
+func (m *MockInterface) AddApplicationOwner(ctx context.Context, objectID, ownerObjectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddApplicationOwner", ctx, objectID, ownerObjectID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// AddApplicationOwner indicates an expected call of AddApplicationOwner.
+func (mr *MockInterfaceMockRecorder) AddApplicationOwner(ctx, objectID, ownerObjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddApplicationOwner", reflect.TypeOf((*MockInterface)(nil).AddApplicationOwner), ctx, objectID, ownerObjectID)
+}
+
+// AddApplicationPassword mocks base method.
+func (m *MockInterface) AddApplicationPassword(ctx context.Context, objectID, displayName string, notAfter time.Time) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddApplicationPassword", ctx, objectID, displayName, notAfter)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddApplicationPassword indicates an expected call of AddApplicationPassword.
+func (mr *MockInterfaceMockRecorder) AddApplicationPassword(ctx, objectID, displayName, notAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddApplicationPassword", reflect.TypeOf((*MockInterface)(nil).AddApplicationPassword), ctx, objectID, displayName, notAfter)
+}
+
+// AddFederatedCredential mocks base method.
+func (m *MockInterface) AddFederatedCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFederatedCredential", ctx, objectID, fic)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // AddFederatedCredential indicates an expected call of AddFederatedCredential.
 func (mr *MockInterfaceMockRecorder) AddFederatedCredential(ctx, objectID, fic interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFederatedCredential", reflect.TypeOf((*MockInterface)(nil).AddFederatedCredential), ctx, objectID, fic)
 }
 
+// AddFederatedCredentials mocks base method.
+func (m *MockInterface) AddFederatedCredentials(ctx context.Context, reqs []cloud.FederatedCredentialRequest) ([]cloud.FederatedCredentialResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFederatedCredentials", ctx, reqs)
+	ret0, _ := ret[0].([]cloud.FederatedCredentialResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddFederatedCredentials indicates an expected call of AddFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) AddFederatedCredentials(ctx, reqs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).AddFederatedCredentials), ctx, reqs)
+}
+
+// AddManagedIdentityFederatedCredential mocks base method.
+func (m *MockInterface) AddManagedIdentityFederatedCredential(ctx context.Context, resourceID string, fic cloud.
+	FederatedCredential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddManagedIdentityFederatedCredential", ctx, resourceID, fic)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddManagedIdentityFederatedCredential indicates an expected call of AddManagedIdentityFederatedCredential.
+func (mr *MockInterfaceMockRecorder) AddManagedIdentityFederatedCredential(ctx, resourceID, fic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddManagedIdentityFederatedCredential", reflect.TypeOf((*MockInterface)(nil).AddManagedIdentityFederatedCredential), ctx, resourceID, fic)
+}
+
+// AddServicePrincipalOwner mocks base method.
+func (m *MockInterface) AddServicePrincipalOwner(ctx context.Context, objectID, ownerObjectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddServicePrincipalOwner", ctx, objectID, ownerObjectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddServicePrincipalOwner indicates an expected call of AddServicePrincipalOwner.
+func (mr *MockInterfaceMockRecorder) AddServicePrincipalOwner(ctx, objectID, ownerObjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddServicePrincipalOwner", reflect.TypeOf((*MockInterface)(nil).AddServicePrincipalOwner), ctx, objectID, ownerObjectID)
+}
+
+// AddServicePrincipalTags mocks base method.
+func (m *MockInterface) AddServicePrincipalTags(ctx context.Context, objectID string, tags map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddServicePrincipalTags", ctx, objectID, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddServicePrincipalTags indicates an expected call of AddServicePrincipalTags.
+func (mr *MockInterfaceMockRecorder) AddServicePrincipalTags(ctx, objectID, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddServicePrincipalTags", reflect.TypeOf((*MockInterface)(nil).AddServicePrincipalTags), ctx, objectID, tags)
+}
+
+// ApplicationsDelta mocks base method.
+func (m *MockInterface) ApplicationsDelta(ctx context.Context, deltaLink string) ([]models.Applicationable, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationsDelta", ctx, deltaLink)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ApplicationsDelta indicates an expected call of ApplicationsDelta.
+func (mr *MockInterfaceMockRecorder) ApplicationsDelta(ctx, deltaLink interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationsDelta", reflect.TypeOf((*MockInterface)(nil).ApplicationsDelta), ctx, deltaLink)
+}
+
+// CheckFederatedCredentialLimit mocks base method.
+func (m *MockInterface) CheckFederatedCredentialLimit(ctx context.Context, objectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckFederatedCredentialLimit", ctx, objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckFederatedCredentialLimit indicates an expected call of CheckFederatedCredentialLimit.
+func (mr *MockInterfaceMockRecorder) CheckFederatedCredentialLimit(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckFederatedCredentialLimit", reflect.TypeOf((*MockInterface)(nil).CheckFederatedCredentialLimit), ctx, objectID)
+}
+
+// CheckRequiredPermissions mocks base method.
+func (m *MockInterface) CheckRequiredPermissions(ctx context.Context, required []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckRequiredPermissions", ctx, required)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckRequiredPermissions indicates an expected call of CheckRequiredPermissions.
+func (mr *MockInterfaceMockRecorder) CheckRequiredPermissions(ctx, required interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRequiredPermissions", reflect.TypeOf((*MockInterface)(nil).CheckRequiredPermissions), ctx, required)
+}
+
+// CloneApplication mocks base method.
+func (m *MockInterface) CloneApplication(ctx context.Context, sourceObjectID, newDisplayName string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloneApplication", ctx, sourceObjectID, newDisplayName)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloneApplication indicates an expected call of CloneApplication.
+func (mr *MockInterfaceMockRecorder) CloneApplication(ctx, sourceObjectID, newDisplayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloneApplication", reflect.TypeOf((*MockInterface)(nil).CloneApplication), ctx, sourceObjectID, newDisplayName)
+}
+
 // CreateApplication mocks base method.
 func (m *MockInterface) CreateApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +277,52 @@ func (mr *MockInterfaceMockRecorder) CreateApplication(ctx, displayName interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplication", reflect.TypeOf((*MockInterface)(nil).CreateApplication), ctx, displayName)
 }
 
+// CreateApplicationAndGetAppID mocks base method.
+func (m *MockInterface) CreateApplicationAndGetAppID(ctx context.Context, displayName string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApplicationAndGetAppID", ctx, displayName)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateApplicationAndGetAppID indicates an expected call of CreateApplicationAndGetAppID.
+func (mr *MockInterfaceMockRecorder) CreateApplicationAndGetAppID(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplicationAndGetAppID", reflect.TypeOf((*MockInterface)(nil).CreateApplicationAndGetAppID), ctx, displayName)
+}
+
+// CreateApplicationWithAppID mocks base method.
+func (m *MockInterface) CreateApplicationWithAppID(ctx context.Context, displayName, appID string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApplicationWithAppID", ctx, displayName, appID)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApplicationWithAppID indicates an expected call of CreateApplicationWithAppID.
+func (mr *MockInterfaceMockRecorder) CreateApplicationWithAppID(ctx, displayName, appID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplicationWithAppID", reflect.TypeOf((*MockInterface)(nil).CreateApplicationWithAppID), ctx, displayName, appID)
+}
+
+// CreateApplicationWithTags mocks base method.
+func (m *MockInterface) CreateApplicationWithTags(ctx context.Context, displayName string, tags []string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApplicationWithTags", ctx, displayName, tags)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApplicationWithTags indicates an expected call of CreateApplicationWithTags.
+func (mr *MockInterfaceMockRecorder) CreateApplicationWithTags(ctx, displayName, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplicationWithTags", reflect.TypeOf((*MockInterface)(nil).CreateApplicationWithTags), ctx, displayName, tags)
+}
+
 // CreateRoleAssignment mocks base method.
 func (m *MockInterface) CreateRoleAssignment(ctx context.Context, scope, roleName, principalID string) (authorization.RoleAssignment, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +353,36 @@ func (mr *MockInterfaceMockRecorder) CreateServicePrincipal(ctx, appID, tags int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServicePrincipal", reflect.TypeOf((*MockInterface)(nil).CreateServicePrincipal), ctx, appID, tags)
 }
 
+// CreateServicePrincipalWithOptions mocks base method.
+func (m *MockInterface) CreateServicePrincipalWithOptions(ctx context.Context, appID string, opts cloud.ServicePrincipalOptions) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServicePrincipalWithOptions", ctx, appID, opts)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateServicePrincipalWithOptions indicates an expected call of CreateServicePrincipalWithOptions.
+func (mr *MockInterfaceMockRecorder) CreateServicePrincipalWithOptions(ctx, appID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServicePrincipalWithOptions", reflect.TypeOf((*MockInterface)(nil).CreateServicePrincipalWithOptions), ctx, appID, opts)
+}
+
+// CreateWorkloadIdentity mocks base method.
+func (m *MockInterface) CreateWorkloadIdentity(ctx context.Context, spec cloud.WorkloadIdentitySpec) (cloud.WorkloadIdentityResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWorkloadIdentity", ctx, spec)
+	ret0, _ := ret[0].(cloud.WorkloadIdentityResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWorkloadIdentity indicates an expected call of CreateWorkloadIdentity.
+func (mr *MockInterfaceMockRecorder) CreateWorkloadIdentity(ctx, spec interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWorkloadIdentity", reflect.TypeOf((*MockInterface)(nil).CreateWorkloadIdentity), ctx, spec)
+}
+
 // DeleteApplication mocks base method.
 func (m *MockInterface) DeleteApplication(ctx context.Context, objectID string) error {
 	m.ctrl.T.Helper()
@@ -109,6 +397,49 @@ func (mr *MockInterfaceMockRecorder) DeleteApplication(ctx, objectID interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplication", reflect.TypeOf((*MockInterface)(nil).DeleteApplication), ctx, objectID)
 }
 
+// DeleteApplicationAndWait mocks base method.
+func (m *MockInterface) DeleteApplicationAndWait(ctx context.Context, objectID string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApplicationAndWait", ctx, objectID, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApplicationAndWait indicates an expected call of DeleteApplicationAndWait.
+func (mr *MockInterfaceMockRecorder) DeleteApplicationAndWait(ctx, objectID, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplicationAndWait", reflect.TypeOf((*MockInterface)(nil).DeleteApplicationAndWait), ctx, objectID, timeout)
+}
+
+// DeleteApplicationByDisplayName mocks base method.
+func (m *MockInterface) DeleteApplicationByDisplayName(ctx context.Context, displayName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApplicationByDisplayName", ctx, displayName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApplicationByDisplayName indicates an expected call of DeleteApplicationByDisplayName.
+func (mr *MockInterfaceMockRecorder) DeleteApplicationByDisplayName(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplicationByDisplayName", reflect.TypeOf((*MockInterface)(nil).DeleteApplicationByDisplayName), ctx, displayName)
+}
+
+// DeleteApplicationsByTag mocks base method.
+func (m *MockInterface) DeleteApplicationsByTag(ctx context.Context, tag string, maxDelete int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApplicationsByTag", ctx, tag, maxDelete)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteApplicationsByTag indicates an expected call of DeleteApplicationsByTag.
+func (mr *MockInterfaceMockRecorder) DeleteApplicationsByTag(ctx, tag, maxDelete interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplicationsByTag", reflect.TypeOf((*MockInterface)(nil).DeleteApplicationsByTag), ctx, tag, maxDelete)
+}
+
 // DeleteFederatedCredential mocks base method.
 func (m *MockInterface) DeleteFederatedCredential(ctx context.Context, objectID, federatedCredentialID string) error {
 	m.ctrl.T.Helper()
@@ -123,6 +454,64 @@ func (mr *MockInterfaceMockRecorder) DeleteFederatedCredential(ctx, objectID, fe
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFederatedCredential", reflect.TypeOf((*MockInterface)(nil).DeleteFederatedCredential), ctx, objectID, federatedCredentialID)
 }
 
+// DeleteFederatedCredentialBySubject mocks base method.
+func (m *MockInterface) DeleteFederatedCredentialBySubject(ctx context.Context, objectID, issuer, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFederatedCredentialBySubject", ctx, objectID, issuer, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFederatedCredentialBySubject indicates an expected call of DeleteFederatedCredentialBySubject.
+func (mr *MockInterfaceMockRecorder) DeleteFederatedCredentialBySubject(ctx, objectID, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFederatedCredentialBySubject", reflect.TypeOf((*MockInterface)(nil).DeleteFederatedCredentialBySubject), ctx, objectID, issuer, subject)
+}
+
+// DeleteFederatedCredentials mocks base method.
+func (m *MockInterface) DeleteFederatedCredentials(ctx context.Context, objectID string, predicate func(models.FederatedIdentityCredentialable) bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFederatedCredentials", ctx, objectID, predicate)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFederatedCredentials indicates an expected call of DeleteFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) DeleteFederatedCredentials(ctx, objectID, predicate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).DeleteFederatedCredentials), ctx, objectID, predicate)
+}
+
+// DeleteFederatedCredentialsBatch mocks base method.
+func (m *MockInterface) DeleteFederatedCredentialsBatch(ctx context.Context, objectID string, ficIDs []string) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFederatedCredentialsBatch", ctx, objectID, ficIDs)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFederatedCredentialsBatch indicates an expected call of DeleteFederatedCredentialsBatch.
+func (mr *MockInterfaceMockRecorder) DeleteFederatedCredentialsBatch(ctx, objectID, ficIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFederatedCredentialsBatch", reflect.TypeOf((*MockInterface)(nil).DeleteFederatedCredentialsBatch), ctx, objectID, ficIDs)
+}
+
+// DeleteManagedIdentityFederatedCredential mocks base method.
+func (m *MockInterface) DeleteManagedIdentityFederatedCredential(ctx context.Context, resourceID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteManagedIdentityFederatedCredential", ctx, resourceID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteManagedIdentityFederatedCredential indicates an expected call of DeleteManagedIdentityFederatedCredential.
+func (mr *MockInterfaceMockRecorder) DeleteManagedIdentityFederatedCredential(ctx, resourceID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteManagedIdentityFederatedCredential", reflect.TypeOf((*MockInterface)(nil).DeleteManagedIdentityFederatedCredential), ctx, resourceID, name)
+}
+
 // DeleteRoleAssignment mocks base method.
 func (m *MockInterface) DeleteRoleAssignment(ctx context.Context, roleAssignmentID string) (authorization.RoleAssignment, error) {
 	m.ctrl.T.Helper()
@@ -152,62 +541,1351 @@ func (mr *MockInterfaceMockRecorder) DeleteServicePrincipal(ctx, objectID interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServicePrincipal", reflect.TypeOf((*MockInterface)(nil).DeleteServicePrincipal), ctx, objectID)
 }
 
-// GetApplication mocks base method.
-func (m *MockInterface) GetApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
+// DeleteServicePrincipalOAuth2PermissionGrant mocks base method.
+func (m *MockInterface) DeleteServicePrincipalOAuth2PermissionGrant(ctx context.Context, grantID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetApplication", ctx, displayName)
-	ret0, _ := ret[0].(models.Applicationable)
+	ret := m.ctrl.Call(m, "DeleteServicePrincipalOAuth2PermissionGrant", ctx, grantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServicePrincipalOAuth2PermissionGrant indicates an expected call of DeleteServicePrincipalOAuth2PermissionGrant.
+func (mr *MockInterfaceMockRecorder) DeleteServicePrincipalOAuth2PermissionGrant(ctx, grantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServicePrincipalOAuth2PermissionGrant", reflect.TypeOf((*MockInterface)(nil).DeleteServicePrincipalOAuth2PermissionGrant), ctx, grantID)
+}
+
+// DetectMissingFederatedCredentials mocks base method.
+func (m *MockInterface) DetectMissingFederatedCredentials(ctx context.Context, references []cloud.SARef) ([]cloud.SARef, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetectMissingFederatedCredentials", ctx, references)
+	ret0, _ := ret[0].([]cloud.SARef)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetApplication indicates an expected call of GetApplication.
-func (mr *MockInterfaceMockRecorder) GetApplication(ctx, displayName interface{}) *gomock.Call {
+// DetectMissingFederatedCredentials indicates an expected call of DetectMissingFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) DetectMissingFederatedCredentials(ctx, references interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplication", reflect.TypeOf((*MockInterface)(nil).GetApplication), ctx, displayName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectMissingFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).DetectMissingFederatedCredentials), ctx, references)
 }
 
-// GetFederatedCredential mocks base method.
-func (m *MockInterface) GetFederatedCredential(ctx context.Context, objectID, issuer, subject string) (models.FederatedIdentityCredentialable, error) {
+// DetectSubjectCollisions mocks base method.
+func (m *MockInterface) DetectSubjectCollisions(ctx context.Context, subjects []string) (map[string][]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFederatedCredential", ctx, objectID, issuer, subject)
-	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret := m.ctrl.Call(m, "DetectSubjectCollisions", ctx, subjects)
+	ret0, _ := ret[0].(map[string][]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetFederatedCredential indicates an expected call of GetFederatedCredential.
-func (mr *MockInterfaceMockRecorder) GetFederatedCredential(ctx, objectID, issuer, subject interface{}) *gomock.Call {
+// DetectSubjectCollisions indicates an expected call of DetectSubjectCollisions.
+func (mr *MockInterfaceMockRecorder) DetectSubjectCollisions(ctx, subjects interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredential", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredential), ctx, objectID, issuer, subject)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectSubjectCollisions", reflect.TypeOf((*MockInterface)(nil).DetectSubjectCollisions), ctx, subjects)
 }
 
-// GetRoleDefinitionIDByName mocks base method.
-func (m *MockInterface) GetRoleDefinitionIDByName(ctx context.Context, scope, roleName string) (authorization.RoleDefinition, error) {
+// DetectSubjectCollisionsWithManagedIdentities mocks base method.
+func (m *MockInterface) DetectSubjectCollisionsWithManagedIdentities(ctx context.Context, subjects, identityResourceIDs []string) (map[string][]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRoleDefinitionIDByName", ctx, scope, roleName)
-	ret0, _ := ret[0].(authorization.RoleDefinition)
+	ret := m.ctrl.Call(m, "DetectSubjectCollisionsWithManagedIdentities", ctx, subjects, identityResourceIDs)
+	ret0, _ := ret[0].(map[string][]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRoleDefinitionIDByName indicates an expected call of GetRoleDefinitionIDByName.
-func (mr *MockInterfaceMockRecorder) GetRoleDefinitionIDByName(ctx, scope, roleName interface{}) *gomock.Call {
+// DetectSubjectCollisionsWithManagedIdentities indicates an expected call of DetectSubjectCollisionsWithManagedIdentities.
+func (mr *MockInterfaceMockRecorder) DetectSubjectCollisionsWithManagedIdentities(ctx, subjects, identityResourceIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoleDefinitionIDByName", reflect.TypeOf((*MockInterface)(nil).GetRoleDefinitionIDByName), ctx, scope, roleName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectSubjectCollisionsWithManagedIdentities", reflect.TypeOf((*MockInterface)(nil).DetectSubjectCollisionsWithManagedIdentities), ctx, subjects, identityResourceIDs)
 }
 
-// GetServicePrincipal mocks base method.
-func (m *MockInterface) GetServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error) {
+// EnsureIdentities mocks base method.
+func (m *MockInterface) EnsureIdentities(ctx context.Context, specs []cloud.IdentitySpec, workers int) ([]cloud.IdentityResult, []error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetServicePrincipal", ctx, displayName)
-	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret := m.ctrl.Call(m, "EnsureIdentities", ctx, specs, workers)
+	ret0, _ := ret[0].([]cloud.IdentityResult)
+	ret1, _ := ret[1].([]error)
+	return ret0, ret1
+}
+
+// EnsureIdentities indicates an expected call of EnsureIdentities.
+func (mr *MockInterfaceMockRecorder) EnsureIdentities(ctx, specs, workers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIdentities", reflect.TypeOf((*MockInterface)(nil).EnsureIdentities), ctx, specs, workers)
+}
+
+// EnsureRoleAssignment mocks base method.
+func (m *MockInterface) EnsureRoleAssignment(ctx context.Context, scope, roleDefinitionID, principalID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureRoleAssignment", ctx, scope, roleDefinitionID, principalID)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetServicePrincipal indicates an expected call of GetServicePrincipal.
-func (mr *MockInterfaceMockRecorder) GetServicePrincipal(ctx, displayName interface{}) *gomock.Call {
+// EnsureRoleAssignment indicates an expected call of EnsureRoleAssignment.
+func (mr *MockInterfaceMockRecorder) EnsureRoleAssignment(ctx, scope, roleDefinitionID, principalID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipal", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipal), ctx, displayName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureRoleAssignment", reflect.TypeOf((*MockInterface)(nil).EnsureRoleAssignment), ctx, scope, roleDefinitionID, principalID)
+}
+
+// ExportApplication mocks base method.
+func (m *MockInterface) ExportApplication(ctx context.Context, objectID string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportApplication", ctx, objectID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportApplication indicates an expected call of ExportApplication.
+func (mr *MockInterfaceMockRecorder) ExportApplication(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportApplication", reflect.TypeOf((*MockInterface)(nil).ExportApplication), ctx, objectID)
+}
+
+// FederatedCredentialExists mocks base method.
+func (m *MockInterface) FederatedCredentialExists(ctx context.Context, objectID, issuer, subject string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FederatedCredentialExists", ctx, objectID, issuer, subject)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FederatedCredentialExists indicates an expected call of FederatedCredentialExists.
+func (mr *MockInterfaceMockRecorder) FederatedCredentialExists(ctx, objectID, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FederatedCredentialExists", reflect.TypeOf((*MockInterface)(nil).FederatedCredentialExists), ctx, objectID, issuer, subject)
+}
+
+// FindApplicationsBySubject mocks base method.
+func (m *MockInterface) FindApplicationsBySubject(ctx context.Context, subject string) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindApplicationsBySubject", ctx, subject)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindApplicationsBySubject indicates an expected call of FindApplicationsBySubject.
+func (mr *MockInterfaceMockRecorder) FindApplicationsBySubject(ctx, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindApplicationsBySubject", reflect.TypeOf((*MockInterface)(nil).FindApplicationsBySubject), ctx, subject)
+}
+
+// FindApplicationsBySubjectWithTag mocks base method.
+func (m *MockInterface) FindApplicationsBySubjectWithTag(ctx context.Context, subject, tag string) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindApplicationsBySubjectWithTag", ctx, subject, tag)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindApplicationsBySubjectWithTag indicates an expected call of FindApplicationsBySubjectWithTag.
+func (mr *MockInterfaceMockRecorder) FindApplicationsBySubjectWithTag(ctx, subject, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindApplicationsBySubjectWithTag", reflect.TypeOf((*MockInterface)(nil).FindApplicationsBySubjectWithTag), ctx, subject, tag)
+}
+
+// FindOrphanedServicePrincipals mocks base method.
+func (m *MockInterface) FindOrphanedServicePrincipals(ctx context.Context, tag string) ([]models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrphanedServicePrincipals", ctx, tag)
+	ret0, _ := ret[0].([]models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrphanedServicePrincipals indicates an expected call of FindOrphanedServicePrincipals.
+func (mr *MockInterfaceMockRecorder) FindOrphanedServicePrincipals(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrphanedServicePrincipals", reflect.TypeOf((*MockInterface)(nil).FindOrphanedServicePrincipals), ctx, tag)
+}
+
+// FindStaleFederatedCredentials mocks base method.
+func (m *MockInterface) FindStaleFederatedCredentials(ctx context.Context, objectID string, activeSubjects map[string]bool) ([]models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindStaleFederatedCredentials", ctx, objectID, activeSubjects)
+	ret0, _ := ret[0].([]models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindStaleFederatedCredentials indicates an expected call of FindStaleFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) FindStaleFederatedCredentials(ctx, objectID, activeSubjects interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindStaleFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).FindStaleFederatedCredentials), ctx, objectID, activeSubjects)
+}
+
+// GetApplication mocks base method.
+func (m *MockInterface) GetApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplication", ctx, displayName)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplication indicates an expected call of GetApplication.
+func (mr *MockInterfaceMockRecorder) GetApplication(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplication", reflect.TypeOf((*MockInterface)(nil).GetApplication), ctx, displayName)
+}
+
+// GetApplicationByAppID mocks base method.
+func (m *MockInterface) GetApplicationByAppID(ctx context.Context, appID string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationByAppID", ctx, appID)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationByAppID indicates an expected call of GetApplicationByAppID.
+func (mr *MockInterfaceMockRecorder) GetApplicationByAppID(ctx, appID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationByAppID", reflect.TypeOf((*MockInterface)(nil).GetApplicationByAppID), ctx, appID)
+}
+
+// GetApplicationByIdentifierURI mocks base method.
+func (m *MockInterface) GetApplicationByIdentifierURI(ctx context.Context, uri string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationByIdentifierURI", ctx, uri)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationByIdentifierURI indicates an expected call of GetApplicationByIdentifierURI.
+func (mr *MockInterfaceMockRecorder) GetApplicationByIdentifierURI(ctx, uri interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationByIdentifierURI", reflect.TypeOf((*MockInterface)(nil).GetApplicationByIdentifierURI), ctx, uri)
+}
+
+// GetApplicationCreatedTime mocks base method.
+func (m *MockInterface) GetApplicationCreatedTime(ctx context.Context, objectID string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationCreatedTime", ctx, objectID)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationCreatedTime indicates an expected call of GetApplicationCreatedTime.
+func (mr *MockInterfaceMockRecorder) GetApplicationCreatedTime(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationCreatedTime", reflect.TypeOf((*MockInterface)(nil).GetApplicationCreatedTime), ctx, objectID)
+}
+
+// GetApplicationForServicePrincipal mocks base method.
+func (m *MockInterface) GetApplicationForServicePrincipal(ctx context.Context, spObjectID string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationForServicePrincipal", ctx, spObjectID)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationForServicePrincipal indicates an expected call of GetApplicationForServicePrincipal.
+func (mr *MockInterfaceMockRecorder) GetApplicationForServicePrincipal(ctx, spObjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationForServicePrincipal", reflect.TypeOf((*MockInterface)(nil).GetApplicationForServicePrincipal), ctx, spObjectID)
+}
+
+// GetApplicationOAuth2Scopes mocks base method.
+func (m *MockInterface) GetApplicationOAuth2Scopes(ctx context.Context, objectID string) ([]models.PermissionScopeable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationOAuth2Scopes", ctx, objectID)
+	ret0, _ := ret[0].([]models.PermissionScopeable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationOAuth2Scopes indicates an expected call of GetApplicationOAuth2Scopes.
+func (mr *MockInterfaceMockRecorder) GetApplicationOAuth2Scopes(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationOAuth2Scopes", reflect.TypeOf((*MockInterface)(nil).GetApplicationOAuth2Scopes), ctx, objectID)
+}
+
+// GetApplicationSignInAudience mocks base method.
+func (m *MockInterface) GetApplicationSignInAudience(ctx context.Context, objectID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationSignInAudience", ctx, objectID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationSignInAudience indicates an expected call of GetApplicationSignInAudience.
+func (mr *MockInterfaceMockRecorder) GetApplicationSignInAudience(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationSignInAudience", reflect.TypeOf((*MockInterface)(nil).GetApplicationSignInAudience), ctx, objectID)
+}
+
+// GetApplicationWithRetry mocks base method.
+func (m *MockInterface) GetApplicationWithRetry(ctx context.Context, displayName string, backoff cloud.RetryBackoff) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplicationWithRetry", ctx, displayName, backoff)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplicationWithRetry indicates an expected call of GetApplicationWithRetry.
+func (mr *MockInterfaceMockRecorder) GetApplicationWithRetry(ctx, displayName, backoff interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplicationWithRetry", reflect.TypeOf((*MockInterface)(nil).GetApplicationWithRetry), ctx, displayName, backoff)
+}
+
+// GetEnabledServicePrincipal mocks base method.
+func (m *MockInterface) GetEnabledServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEnabledServicePrincipal", ctx, displayName)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEnabledServicePrincipal indicates an expected call of GetEnabledServicePrincipal.
+func (mr *MockInterfaceMockRecorder) GetEnabledServicePrincipal(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnabledServicePrincipal", reflect.TypeOf((*MockInterface)(nil).GetEnabledServicePrincipal), ctx, displayName)
+}
+
+// GetFederatedCredential mocks base method.
+func (m *MockInterface) GetFederatedCredential(ctx context.Context, objectID, issuer, subject string) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredential", ctx, objectID, issuer, subject)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredential indicates an expected call of GetFederatedCredential.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredential(ctx, objectID, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredential", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredential), ctx, objectID, issuer, subject)
+}
+
+// GetFederatedCredentialByClaimsMatchingExpression mocks base method.
+func (m *MockInterface) GetFederatedCredentialByClaimsMatchingExpression(ctx context.Context, objectID, issuer, expression string) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredentialByClaimsMatchingExpression", ctx, objectID, issuer, expression)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredentialByClaimsMatchingExpression indicates an expected call of GetFederatedCredentialByClaimsMatchingExpression.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredentialByClaimsMatchingExpression(ctx, objectID, issuer, expression interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredentialByClaimsMatchingExpression", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredentialByClaimsMatchingExpression), ctx, objectID, issuer, expression)
+}
+
+// GetFederatedCredentialByName mocks base method.
+func (m *MockInterface) GetFederatedCredentialByName(ctx context.Context, objectID, name string) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredentialByName", ctx, objectID, name)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredentialByName indicates an expected call of GetFederatedCredentialByName.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredentialByName(ctx, objectID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredentialByName", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredentialByName), ctx, objectID, name)
+}
+
+// GetFederatedCredentialRaw mocks base method.
+func (m *MockInterface) GetFederatedCredentialRaw(ctx context.Context, objectID, ficID string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredentialRaw", ctx, objectID, ficID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredentialRaw indicates an expected call of GetFederatedCredentialRaw.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredentialRaw(ctx, objectID, ficID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredentialRaw", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredentialRaw), ctx, objectID, ficID)
+}
+
+// GetFederatedCredentialWithAudiences mocks base method.
+func (m *MockInterface) GetFederatedCredentialWithAudiences(ctx context.Context, objectID, issuer, subject string, audiences []string) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredentialWithAudiences", ctx, objectID, issuer, subject, audiences)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredentialWithAudiences indicates an expected call of GetFederatedCredentialWithAudiences.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredentialWithAudiences(ctx, objectID, issuer, subject, audiences interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredentialWithAudiences", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredentialWithAudiences), ctx, objectID, issuer, subject, audiences)
+}
+
+// GetFederatedCredentialsBySubject mocks base method.
+func (m *MockInterface) GetFederatedCredentialsBySubject(ctx context.Context, objectID, subject string) ([]models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFederatedCredentialsBySubject", ctx, objectID, subject)
+	ret0, _ := ret[0].([]models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFederatedCredentialsBySubject indicates an expected call of GetFederatedCredentialsBySubject.
+func (mr *MockInterfaceMockRecorder) GetFederatedCredentialsBySubject(ctx, objectID, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFederatedCredentialsBySubject", reflect.TypeOf((*MockInterface)(nil).GetFederatedCredentialsBySubject), ctx, objectID, subject)
+}
+
+// GetManagedIdentityFederatedCredential mocks base method.
+func (m *MockInterface) GetManagedIdentityFederatedCredential(ctx context.Context, resourceID, issuer, subject string) (cloud.
+	FederatedCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetManagedIdentityFederatedCredential", ctx, resourceID, issuer, subject)
+	ret0, _ := ret[0].(cloud.
+		FederatedCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetManagedIdentityFederatedCredential indicates an expected call of GetManagedIdentityFederatedCredential.
+func (mr *MockInterfaceMockRecorder) GetManagedIdentityFederatedCredential(ctx, resourceID, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManagedIdentityFederatedCredential", reflect.TypeOf((*MockInterface)(nil).GetManagedIdentityFederatedCredential), ctx, resourceID, issuer, subject)
+}
+
+// GetOrCreateApplication mocks base method.
+func (m *MockInterface) GetOrCreateApplication(ctx context.Context, displayName string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrCreateApplication", ctx, displayName)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrCreateApplication indicates an expected call of GetOrCreateApplication.
+func (mr *MockInterfaceMockRecorder) GetOrCreateApplication(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateApplication", reflect.TypeOf((*MockInterface)(nil).GetOrCreateApplication), ctx, displayName)
+}
+
+// GetRoleDefinitionIDByName mocks base method.
+func (m *MockInterface) GetRoleDefinitionIDByName(ctx context.Context, scope, roleName string) (authorization.RoleDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoleDefinitionIDByName", ctx, scope, roleName)
+	ret0, _ := ret[0].(authorization.RoleDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoleDefinitionIDByName indicates an expected call of GetRoleDefinitionIDByName.
+func (mr *MockInterfaceMockRecorder) GetRoleDefinitionIDByName(ctx, scope, roleName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoleDefinitionIDByName", reflect.TypeOf((*MockInterface)(nil).GetRoleDefinitionIDByName), ctx, scope, roleName)
+}
+
+// GetServicePrincipal mocks base method.
+func (m *MockInterface) GetServicePrincipal(ctx context.Context, displayName string) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipal", ctx, displayName)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipal indicates an expected call of GetServicePrincipal.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipal(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipal", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipal), ctx, displayName)
+}
+
+// GetServicePrincipalByAppID mocks base method.
+func (m *MockInterface) GetServicePrincipalByAppID(ctx context.Context, appID string) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalByAppID", ctx, appID)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalByAppID indicates an expected call of GetServicePrincipalByAppID.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalByAppID(ctx, appID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalByAppID", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalByAppID), ctx, appID)
+}
+
+// GetServicePrincipalForApplication mocks base method.
+func (m *MockInterface) GetServicePrincipalForApplication(ctx context.Context, appObjectID string) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalForApplication", ctx, appObjectID)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalForApplication indicates an expected call of GetServicePrincipalForApplication.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalForApplication(ctx, appObjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalForApplication", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalForApplication), ctx, appObjectID)
+}
+
+// GetServicePrincipalSignInActivity mocks base method.
+func (m *MockInterface) GetServicePrincipalSignInActivity(ctx context.Context, objectID string) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalSignInActivity", ctx, objectID)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalSignInActivity indicates an expected call of GetServicePrincipalSignInActivity.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalSignInActivity(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalSignInActivity", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalSignInActivity), ctx, objectID)
+}
+
+// GetServicePrincipalTags mocks base method.
+func (m *MockInterface) GetServicePrincipalTags(ctx context.Context, objectID string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalTags", ctx, objectID)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalTags indicates an expected call of GetServicePrincipalTags.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalTags(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalTags", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalTags), ctx, objectID)
+}
+
+// GetServicePrincipalType mocks base method.
+func (m *MockInterface) GetServicePrincipalType(ctx context.Context, objectID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalType", ctx, objectID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalType indicates an expected call of GetServicePrincipalType.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalType(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalType", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalType), ctx, objectID)
+}
+
+// GetServicePrincipalWithRetry mocks base method.
+func (m *MockInterface) GetServicePrincipalWithRetry(ctx context.Context, displayName string, backoff cloud.RetryBackoff) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipalWithRetry", ctx, displayName, backoff)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipalWithRetry indicates an expected call of GetServicePrincipalWithRetry.
+func (mr *MockInterfaceMockRecorder) GetServicePrincipalWithRetry(ctx, displayName, backoff interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipalWithRetry", reflect.TypeOf((*MockInterface)(nil).GetServicePrincipalWithRetry), ctx, displayName, backoff)
+}
+
+// GetUserAssignedIdentity mocks base method.
+func (m *MockInterface) GetUserAssignedIdentity(ctx context.Context, resourceID string) (cloud.
+	UserAssignedIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserAssignedIdentity", ctx, resourceID)
+	ret0, _ := ret[0].(cloud.
+		UserAssignedIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserAssignedIdentity indicates an expected call of GetUserAssignedIdentity.
+func (mr *MockInterfaceMockRecorder) GetUserAssignedIdentity(ctx, resourceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserAssignedIdentity", reflect.TypeOf((*MockInterface)(nil).GetUserAssignedIdentity), ctx, resourceID)
+}
+
+// GrantAdminConsent mocks base method.
+func (m *MockInterface) GrantAdminConsent(ctx context.Context, spObjectID, resourceSPObjectID string, scopes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantAdminConsent", ctx, spObjectID, resourceSPObjectID, scopes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantAdminConsent indicates an expected call of GrantAdminConsent.
+func (mr *MockInterfaceMockRecorder) GrantAdminConsent(ctx, spObjectID, resourceSPObjectID, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantAdminConsent", reflect.TypeOf((*MockInterface)(nil).GrantAdminConsent), ctx, spObjectID, resourceSPObjectID, scopes)
+}
+
+// ImportApplication mocks base method.
+func (m *MockInterface) ImportApplication(ctx context.Context, data []byte) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportApplication", ctx, data)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportApplication indicates an expected call of ImportApplication.
+func (mr *MockInterfaceMockRecorder) ImportApplication(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportApplication", reflect.TypeOf((*MockInterface)(nil).ImportApplication), ctx, data)
+}
+
+// ListApplicationAppRoles mocks base method.
+func (m *MockInterface) ListApplicationAppRoles(ctx context.Context, objectID string) ([]models.AppRoleable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplicationAppRoles", ctx, objectID)
+	ret0, _ := ret[0].([]models.AppRoleable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplicationAppRoles indicates an expected call of ListApplicationAppRoles.
+func (mr *MockInterfaceMockRecorder) ListApplicationAppRoles(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplicationAppRoles", reflect.TypeOf((*MockInterface)(nil).ListApplicationAppRoles), ctx, objectID)
+}
+
+// ListApplicationsByTag mocks base method.
+func (m *MockInterface) ListApplicationsByTag(ctx context.Context, tag string) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplicationsByTag", ctx, tag)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplicationsByTag indicates an expected call of ListApplicationsByTag.
+func (mr *MockInterfaceMockRecorder) ListApplicationsByTag(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplicationsByTag", reflect.TypeOf((*MockInterface)(nil).ListApplicationsByTag), ctx, tag)
+}
+
+// ListApplicationsCreatedBefore mocks base method.
+func (m *MockInterface) ListApplicationsCreatedBefore(ctx context.Context, cutoff time.Time, tag string) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplicationsCreatedBefore", ctx, cutoff, tag)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplicationsCreatedBefore indicates an expected call of ListApplicationsCreatedBefore.
+func (mr *MockInterfaceMockRecorder) ListApplicationsCreatedBefore(ctx, cutoff, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplicationsCreatedBefore", reflect.TypeOf((*MockInterface)(nil).ListApplicationsCreatedBefore), ctx, cutoff, tag)
+}
+
+// ListDeletedApplications mocks base method.
+func (m *MockInterface) ListDeletedApplications(ctx context.Context) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeletedApplications", ctx)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeletedApplications indicates an expected call of ListDeletedApplications.
+func (mr *MockInterfaceMockRecorder) ListDeletedApplications(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeletedApplications", reflect.TypeOf((*MockInterface)(nil).ListDeletedApplications), ctx)
+}
+
+// ListDeletedApplicationsByDisplayName mocks base method.
+func (m *MockInterface) ListDeletedApplicationsByDisplayName(ctx context.Context, displayName string) ([]models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeletedApplicationsByDisplayName", ctx, displayName)
+	ret0, _ := ret[0].([]models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeletedApplicationsByDisplayName indicates an expected call of ListDeletedApplicationsByDisplayName.
+func (mr *MockInterfaceMockRecorder) ListDeletedApplicationsByDisplayName(ctx, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeletedApplicationsByDisplayName", reflect.TypeOf((*MockInterface)(nil).ListDeletedApplicationsByDisplayName), ctx, displayName)
+}
+
+// ListFederatedCredentials mocks base method.
+func (m *MockInterface) ListFederatedCredentials(ctx context.Context, objectID string) ([]models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFederatedCredentials", ctx, objectID)
+	ret0, _ := ret[0].([]models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFederatedCredentials indicates an expected call of ListFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) ListFederatedCredentials(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).ListFederatedCredentials), ctx, objectID)
+}
+
+// ListFederatedCredentialsByAudience mocks base method.
+func (m *MockInterface) ListFederatedCredentialsByAudience(ctx context.Context, objectID, audience string) ([]models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFederatedCredentialsByAudience", ctx, objectID, audience)
+	ret0, _ := ret[0].([]models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFederatedCredentialsByAudience indicates an expected call of ListFederatedCredentialsByAudience.
+func (mr *MockInterfaceMockRecorder) ListFederatedCredentialsByAudience(ctx, objectID, audience interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFederatedCredentialsByAudience", reflect.TypeOf((*MockInterface)(nil).ListFederatedCredentialsByAudience), ctx, objectID, audience)
+}
+
+// ListManagedIdentityFederatedCredentials mocks base method.
+func (m *MockInterface) ListManagedIdentityFederatedCredentials(ctx context.Context, resourceID string) ([]cloud.
+	FederatedCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListManagedIdentityFederatedCredentials", ctx, resourceID)
+	ret0, _ := ret[0].([]cloud.
+		FederatedCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListManagedIdentityFederatedCredentials indicates an expected call of ListManagedIdentityFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) ListManagedIdentityFederatedCredentials(ctx, resourceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListManagedIdentityFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).ListManagedIdentityFederatedCredentials), ctx, resourceID)
+}
+
+// ListServicePrincipalOAuth2PermissionGrants mocks base method.
+func (m *MockInterface) ListServicePrincipalOAuth2PermissionGrants(ctx context.Context, objectID string) ([]models.OAuth2PermissionGrantable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServicePrincipalOAuth2PermissionGrants", ctx, objectID)
+	ret0, _ := ret[0].([]models.OAuth2PermissionGrantable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListServicePrincipalOAuth2PermissionGrants indicates an expected call of ListServicePrincipalOAuth2PermissionGrants.
+func (mr *MockInterfaceMockRecorder) ListServicePrincipalOAuth2PermissionGrants(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServicePrincipalOAuth2PermissionGrants", reflect.TypeOf((*MockInterface)(nil).ListServicePrincipalOAuth2PermissionGrants), ctx, objectID)
+}
+
+// ListServicePrincipalsByTag mocks base method.
+func (m *MockInterface) ListServicePrincipalsByTag(ctx context.Context, tag string) ([]models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServicePrincipalsByTag", ctx, tag)
+	ret0, _ := ret[0].([]models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListServicePrincipalsByTag indicates an expected call of ListServicePrincipalsByTag.
+func (mr *MockInterfaceMockRecorder) ListServicePrincipalsByTag(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServicePrincipalsByTag", reflect.TypeOf((*MockInterface)(nil).ListServicePrincipalsByTag), ctx, tag)
+}
+
+// ListTrustedIssuers mocks base method.
+func (m *MockInterface) ListTrustedIssuers(ctx context.Context, objectID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrustedIssuers", ctx, objectID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrustedIssuers indicates an expected call of ListTrustedIssuers.
+func (mr *MockInterfaceMockRecorder) ListTrustedIssuers(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrustedIssuers", reflect.TypeOf((*MockInterface)(nil).ListTrustedIssuers), ctx, objectID)
+}
+
+// NewApplicationPager mocks base method.
+func (m *MockInterface) NewApplicationPager(ctx context.Context, filter string) *cloud.
+	ApplicationPager {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewApplicationPager", ctx, filter)
+	ret0, _ := ret[0].(*cloud.
+		ApplicationPager)
+	return ret0
+}
+
+// NewApplicationPager indicates an expected call of NewApplicationPager.
+func (mr *MockInterfaceMockRecorder) NewApplicationPager(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewApplicationPager", reflect.TypeOf((*MockInterface)(nil).NewApplicationPager), ctx, filter)
+}
+
+// PermanentlyDeleteApplication mocks base method.
+func (m *MockInterface) PermanentlyDeleteApplication(ctx context.Context, objectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PermanentlyDeleteApplication", ctx, objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PermanentlyDeleteApplication indicates an expected call of PermanentlyDeleteApplication.
+func (mr *MockInterfaceMockRecorder) PermanentlyDeleteApplication(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PermanentlyDeleteApplication", reflect.TypeOf((*MockInterface)(nil).PermanentlyDeleteApplication), ctx, objectID)
+}
+
+// Preflight mocks base method.
+func (m *MockInterface) Preflight(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preflight", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Preflight indicates an expected call of Preflight.
+func (mr *MockInterfaceMockRecorder) Preflight(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preflight", reflect.TypeOf((*MockInterface)(nil).Preflight), ctx)
+}
+
+// ReconcileAllFederatedCredentials mocks base method.
+func (m *MockInterface) ReconcileAllFederatedCredentials(ctx context.Context, desired map[string][]cloud.ExpectedFIC, workers int) (map[string]cloud.ReconcileResult, []error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileAllFederatedCredentials", ctx, desired, workers)
+	ret0, _ := ret[0].(map[string]cloud.ReconcileResult)
+	ret1, _ := ret[1].([]error)
+	return ret0, ret1
+}
+
+// ReconcileAllFederatedCredentials indicates an expected call of ReconcileAllFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) ReconcileAllFederatedCredentials(ctx, desired, workers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAllFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).ReconcileAllFederatedCredentials), ctx, desired, workers)
+}
+
+// ReconcileFederatedCredentials mocks base method.
+func (m *MockInterface) ReconcileFederatedCredentials(ctx context.Context, objectID string, desired []cloud.
+	ExpectedFIC, dryRun bool) (cloud.
+	ReconcileResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileFederatedCredentials", ctx, objectID, desired, dryRun)
+	ret0, _ := ret[0].(cloud.
+		ReconcileResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileFederatedCredentials indicates an expected call of ReconcileFederatedCredentials.
+func (mr *MockInterfaceMockRecorder) ReconcileFederatedCredentials(ctx, objectID, desired, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileFederatedCredentials", reflect.TypeOf((*MockInterface)(nil).ReconcileFederatedCredentials), ctx, objectID, desired, dryRun)
+}
+
+// RestoreDeletedApplication mocks base method.
+func (m *MockInterface) RestoreDeletedApplication(ctx context.Context, objectID string) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreDeletedApplication", ctx, objectID)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreDeletedApplication indicates an expected call of RestoreDeletedApplication.
+func (mr *MockInterfaceMockRecorder) RestoreDeletedApplication(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreDeletedApplication", reflect.TypeOf((*MockInterface)(nil).RestoreDeletedApplication), ctx, objectID)
+}
+
+// RewriteAllFederatedCredentialAudiences mocks base method.
+func (m *MockInterface) RewriteAllFederatedCredentialAudiences(ctx context.Context, oldAudience, newAudience string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RewriteAllFederatedCredentialAudiences", ctx, oldAudience, newAudience)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RewriteAllFederatedCredentialAudiences indicates an expected call of RewriteAllFederatedCredentialAudiences.
+func (mr *MockInterfaceMockRecorder) RewriteAllFederatedCredentialAudiences(ctx, oldAudience, newAudience interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RewriteAllFederatedCredentialAudiences", reflect.TypeOf((*MockInterface)(nil).RewriteAllFederatedCredentialAudiences), ctx, oldAudience, newAudience)
+}
+
+// RewriteFederatedCredentialAudiences mocks base method.
+func (m *MockInterface) RewriteFederatedCredentialAudiences(ctx context.Context, objectID, oldAudience, newAudience string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RewriteFederatedCredentialAudiences", ctx, objectID, oldAudience, newAudience)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RewriteFederatedCredentialAudiences indicates an expected call of RewriteFederatedCredentialAudiences.
+func (mr *MockInterfaceMockRecorder) RewriteFederatedCredentialAudiences(ctx, objectID, oldAudience, newAudience interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RewriteFederatedCredentialAudiences", reflect.TypeOf((*MockInterface)(nil).RewriteFederatedCredentialAudiences), ctx, objectID, oldAudience, newAudience)
+}
+
+// SetApplicationImplicitGrantSettings mocks base method.
+func (m *MockInterface) SetApplicationImplicitGrantSettings(ctx context.Context, objectID string, idToken, accessToken bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetApplicationImplicitGrantSettings", ctx, objectID, idToken, accessToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetApplicationImplicitGrantSettings indicates an expected call of SetApplicationImplicitGrantSettings.
+func (mr *MockInterfaceMockRecorder) SetApplicationImplicitGrantSettings(ctx, objectID, idToken, accessToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetApplicationImplicitGrantSettings", reflect.TypeOf((*MockInterface)(nil).SetApplicationImplicitGrantSettings), ctx, objectID, idToken, accessToken)
+}
+
+// SetApplicationLogo mocks base method.
+func (m *MockInterface) SetApplicationLogo(ctx context.Context, objectID string, logo []byte, contentType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetApplicationLogo", ctx, objectID, logo, contentType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetApplicationLogo indicates an expected call of SetApplicationLogo.
+func (mr *MockInterfaceMockRecorder) SetApplicationLogo(ctx, objectID, logo, contentType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetApplicationLogo", reflect.TypeOf((*MockInterface)(nil).SetApplicationLogo), ctx, objectID, logo, contentType)
+}
+
+// SetApplicationRequiredResourceAccess mocks base method.
+func (m *MockInterface) SetApplicationRequiredResourceAccess(ctx context.Context, objectID string, access []models.RequiredResourceAccessable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetApplicationRequiredResourceAccess", ctx, objectID, access)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetApplicationRequiredResourceAccess indicates an expected call of SetApplicationRequiredResourceAccess.
+func (mr *MockInterfaceMockRecorder) SetApplicationRequiredResourceAccess(ctx, objectID, access interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetApplicationRequiredResourceAccess", reflect.TypeOf((*MockInterface)(nil).SetApplicationRequiredResourceAccess), ctx, objectID, access)
+}
+
+// SetApplicationSignInAudience mocks base method.
+func (m *MockInterface) SetApplicationSignInAudience(ctx context.Context, objectID, audience string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetApplicationSignInAudience", ctx, objectID, audience)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetApplicationSignInAudience indicates an expected call of SetApplicationSignInAudience.
+func (mr *MockInterfaceMockRecorder) SetApplicationSignInAudience(ctx, objectID, audience interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetApplicationSignInAudience", reflect.TypeOf((*MockInterface)(nil).SetApplicationSignInAudience), ctx, objectID, audience)
+}
+
+// SetApplicationTokenClaims mocks base method.
+func (m *MockInterface) SetApplicationTokenClaims(ctx context.Context, objectID, groupMembershipClaims string, optional models.OptionalClaimsable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetApplicationTokenClaims", ctx, objectID, groupMembershipClaims, optional)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetApplicationTokenClaims indicates an expected call of SetApplicationTokenClaims.
+func (mr *MockInterfaceMockRecorder) SetApplicationTokenClaims(ctx, objectID, groupMembershipClaims, optional interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetApplicationTokenClaims", reflect.TypeOf((*MockInterface)(nil).SetApplicationTokenClaims), ctx, objectID, groupMembershipClaims, optional)
+}
+
+// SetPreAuthorizedApplications mocks base method.
+func (m *MockInterface) SetPreAuthorizedApplications(ctx context.Context, objectID string, preAuth []models.PreAuthorizedApplicationable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPreAuthorizedApplications", ctx, objectID, preAuth)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPreAuthorizedApplications indicates an expected call of SetPreAuthorizedApplications.
+func (mr *MockInterfaceMockRecorder) SetPreAuthorizedApplications(ctx, objectID, preAuth interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPreAuthorizedApplications", reflect.TypeOf((*MockInterface)(nil).SetPreAuthorizedApplications), ctx, objectID, preAuth)
+}
+
+// SetServicePrincipalCustomSecurityAttributes mocks base method.
+func (m *MockInterface) SetServicePrincipalCustomSecurityAttributes(ctx context.Context, objectID string, attrs map[string]map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetServicePrincipalCustomSecurityAttributes", ctx, objectID, attrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetServicePrincipalCustomSecurityAttributes indicates an expected call of SetServicePrincipalCustomSecurityAttributes.
+func (mr *MockInterfaceMockRecorder) SetServicePrincipalCustomSecurityAttributes(ctx, objectID, attrs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetServicePrincipalCustomSecurityAttributes", reflect.TypeOf((*MockInterface)(nil).SetServicePrincipalCustomSecurityAttributes), ctx, objectID, attrs)
+}
+
+// TagApplications mocks base method.
+func (m *MockInterface) TagApplications(ctx context.Context, filter, tag string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagApplications", ctx, filter, tag)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TagApplications indicates an expected call of TagApplications.
+func (mr *MockInterfaceMockRecorder) TagApplications(ctx, filter, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagApplications", reflect.TypeOf((*MockInterface)(nil).TagApplications), ctx, filter, tag)
+}
+
+// ThrottleStats mocks base method.
+func (m *MockInterface) ThrottleStats() cloud.
+	ThrottleStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ThrottleStats")
+	ret0, _ := ret[0].(cloud.
+		ThrottleStats)
+	return ret0
+}
+
+// ThrottleStats indicates an expected call of ThrottleStats.
+func (mr *MockInterfaceMockRecorder) ThrottleStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ThrottleStats", reflect.TypeOf((*MockInterface)(nil).ThrottleStats))
+}
+
+// TransferApplicationOwnership mocks base method.
+func (m *MockInterface) TransferApplicationOwnership(ctx context.Context, objectID, newOwnerObjectID string, removeExisting bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferApplicationOwnership", ctx, objectID, newOwnerObjectID, removeExisting)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferApplicationOwnership indicates an expected call of TransferApplicationOwnership.
+func (mr *MockInterfaceMockRecorder) TransferApplicationOwnership(ctx, objectID, newOwnerObjectID, removeExisting interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferApplicationOwnership", reflect.TypeOf((*MockInterface)(nil).TransferApplicationOwnership), ctx, objectID, newOwnerObjectID, removeExisting)
+}
+
+// UpdateApplicationDisplayName mocks base method.
+func (m *MockInterface) UpdateApplicationDisplayName(ctx context.Context, objectID, displayName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateApplicationDisplayName", ctx, objectID, displayName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateApplicationDisplayName indicates an expected call of UpdateApplicationDisplayName.
+func (mr *MockInterfaceMockRecorder) UpdateApplicationDisplayName(ctx, objectID, displayName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateApplicationDisplayName", reflect.TypeOf((*MockInterface)(nil).UpdateApplicationDisplayName), ctx, objectID, displayName)
+}
+
+// UpdateFederatedCredential mocks base method.
+func (m *MockInterface) UpdateFederatedCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFederatedCredential", ctx, objectID, federatedCredentialID, fic)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFederatedCredential indicates an expected call of UpdateFederatedCredential.
+func (mr *MockInterfaceMockRecorder) UpdateFederatedCredential(ctx, objectID, federatedCredentialID, fic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFederatedCredential", reflect.TypeOf((*MockInterface)(nil).UpdateFederatedCredential), ctx, objectID, federatedCredentialID, fic)
+}
+
+// ValidateFederatedCredentialIssuer mocks base method.
+func (m *MockInterface) ValidateFederatedCredentialIssuer(ctx context.Context, objectID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateFederatedCredentialIssuer", ctx, objectID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateFederatedCredentialIssuer indicates an expected call of ValidateFederatedCredentialIssuer.
+func (mr *MockInterfaceMockRecorder) ValidateFederatedCredentialIssuer(ctx, objectID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateFederatedCredentialIssuer", reflect.TypeOf((*MockInterface)(nil).ValidateFederatedCredentialIssuer), ctx, objectID, name)
+}
+
+// WaitForFederatedCredential mocks base method.
+func (m *MockInterface) WaitForFederatedCredential(ctx context.Context, objectID, name string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForFederatedCredential", ctx, objectID, name, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForFederatedCredential indicates an expected call of WaitForFederatedCredential.
+func (mr *MockInterfaceMockRecorder) WaitForFederatedCredential(ctx, objectID, name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFederatedCredential", reflect.TypeOf((*MockInterface)(nil).WaitForFederatedCredential), ctx, objectID, name, timeout)
+}
+
+// WaitForServicePrincipal mocks base method.
+func (m *MockInterface) WaitForServicePrincipal(ctx context.Context, appID string, timeout time.Duration) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForServicePrincipal", ctx, appID, timeout)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitForServicePrincipal indicates an expected call of WaitForServicePrincipal.
+func (mr *MockInterfaceMockRecorder) WaitForServicePrincipal(ctx, appID, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForServicePrincipal", reflect.TypeOf((*MockInterface)(nil).WaitForServicePrincipal), ctx, appID, timeout)
+}
+
+// MockGraphServiceClient is a mock of GraphServiceClient interface.
+type MockGraphServiceClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockGraphServiceClientMockRecorder
+}
+
+// MockGraphServiceClientMockRecorder is the mock recorder for MockGraphServiceClient.
+type MockGraphServiceClientMockRecorder struct {
+	mock *MockGraphServiceClient
+}
+
+// NewMockGraphServiceClient creates a new mock instance.
+func NewMockGraphServiceClient(ctrl *gomock.Controller) *MockGraphServiceClient {
+	mock := &MockGraphServiceClient{ctrl: ctrl}
+	mock.recorder = &MockGraphServiceClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGraphServiceClient) EXPECT() *MockGraphServiceClientMockRecorder {
+	return m.recorder
+}
+
+// CreateApplication mocks base method.
+func (m *MockGraphServiceClient) CreateApplication(ctx context.Context, app models.Applicationable) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApplication", ctx, app)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApplication indicates an expected call of CreateApplication.
+func (mr *MockGraphServiceClientMockRecorder) CreateApplication(ctx, app interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplication", reflect.TypeOf((*MockGraphServiceClient)(nil).CreateApplication), ctx, app)
+}
+
+// CreateFederatedIdentityCredential mocks base method.
+func (m *MockGraphServiceClient) CreateFederatedIdentityCredential(ctx context.Context, objectID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFederatedIdentityCredential", ctx, objectID, fic)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFederatedIdentityCredential indicates an expected call of CreateFederatedIdentityCredential.
+func (mr *MockGraphServiceClientMockRecorder) CreateFederatedIdentityCredential(ctx, objectID, fic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFederatedIdentityCredential", reflect.TypeOf((*MockGraphServiceClient)(nil).CreateFederatedIdentityCredential), ctx, objectID, fic)
+}
+
+// CreateServicePrincipal mocks base method.
+func (m *MockGraphServiceClient) CreateServicePrincipal(ctx context.Context, sp models.ServicePrincipalable) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServicePrincipal", ctx, sp)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateServicePrincipal indicates an expected call of CreateServicePrincipal.
+func (mr *MockGraphServiceClientMockRecorder) CreateServicePrincipal(ctx, sp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServicePrincipal", reflect.TypeOf((*MockGraphServiceClient)(nil).CreateServicePrincipal), ctx, sp)
+}
+
+// DeleteApplication mocks base method.
+func (m *MockGraphServiceClient) DeleteApplication(ctx context.Context, objectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApplication", ctx, objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApplication indicates an expected call of DeleteApplication.
+func (mr *MockGraphServiceClientMockRecorder) DeleteApplication(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplication", reflect.TypeOf((*MockGraphServiceClient)(nil).DeleteApplication), ctx, objectID)
+}
+
+// DeleteFederatedIdentityCredential mocks base method.
+func (m *MockGraphServiceClient) DeleteFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFederatedIdentityCredential", ctx, objectID, federatedCredentialID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFederatedIdentityCredential indicates an expected call of DeleteFederatedIdentityCredential.
+func (mr *MockGraphServiceClientMockRecorder) DeleteFederatedIdentityCredential(ctx, objectID, federatedCredentialID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFederatedIdentityCredential", reflect.TypeOf((*MockGraphServiceClient)(nil).DeleteFederatedIdentityCredential), ctx, objectID, federatedCredentialID)
+}
+
+// DeleteServicePrincipal mocks base method.
+func (m *MockGraphServiceClient) DeleteServicePrincipal(ctx context.Context, objectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServicePrincipal", ctx, objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServicePrincipal indicates an expected call of DeleteServicePrincipal.
+func (mr *MockGraphServiceClientMockRecorder) DeleteServicePrincipal(ctx, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServicePrincipal", reflect.TypeOf((*MockGraphServiceClient)(nil).DeleteServicePrincipal), ctx, objectID)
+}
+
+// GetAdapter mocks base method.
+func (m *MockGraphServiceClient) GetAdapter() abstractions.RequestAdapter {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdapter")
+	ret0, _ := ret[0].(abstractions.RequestAdapter)
+	return ret0
+}
+
+// GetAdapter indicates an expected call of GetAdapter.
+func (mr *MockGraphServiceClientMockRecorder) GetAdapter() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdapter", reflect.TypeOf((*MockGraphServiceClient)(nil).GetAdapter))
+}
+
+// GetApplication mocks base method.
+func (m *MockGraphServiceClient) GetApplication(ctx context.Context, objectID string, options *applications.ApplicationItemRequestBuilderGetRequestConfiguration) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApplication", ctx, objectID, options)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApplication indicates an expected call of GetApplication.
+func (mr *MockGraphServiceClientMockRecorder) GetApplication(ctx, objectID, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplication", reflect.TypeOf((*MockGraphServiceClient)(nil).GetApplication), ctx, objectID, options)
+}
+
+// GetServicePrincipal mocks base method.
+func (m *MockGraphServiceClient) GetServicePrincipal(ctx context.Context, objectID string, options *serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePrincipal", ctx, objectID, options)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePrincipal indicates an expected call of GetServicePrincipal.
+func (mr *MockGraphServiceClientMockRecorder) GetServicePrincipal(ctx, objectID, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePrincipal", reflect.TypeOf((*MockGraphServiceClient)(nil).GetServicePrincipal), ctx, objectID, options)
+}
+
+// ListApplications mocks base method.
+func (m *MockGraphServiceClient) ListApplications(ctx context.Context, options *applications.ApplicationsRequestBuilderGetRequestConfiguration) (models.ApplicationCollectionResponseable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplications", ctx, options)
+	ret0, _ := ret[0].(models.ApplicationCollectionResponseable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplications indicates an expected call of ListApplications.
+func (mr *MockGraphServiceClientMockRecorder) ListApplications(ctx, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplications", reflect.TypeOf((*MockGraphServiceClient)(nil).ListApplications), ctx, options)
+}
+
+// ListFederatedIdentityCredentials mocks base method.
+func (m *MockGraphServiceClient) ListFederatedIdentityCredentials(ctx context.Context, objectID string, options *applications.ItemFederatedIdentityCredentialsRequestBuilderGetRequestConfiguration) (models.FederatedIdentityCredentialCollectionResponseable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFederatedIdentityCredentials", ctx, objectID, options)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialCollectionResponseable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFederatedIdentityCredentials indicates an expected call of ListFederatedIdentityCredentials.
+func (mr *MockGraphServiceClientMockRecorder) ListFederatedIdentityCredentials(ctx, objectID, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFederatedIdentityCredentials", reflect.TypeOf((*MockGraphServiceClient)(nil).ListFederatedIdentityCredentials), ctx, objectID, options)
+}
+
+// ListServicePrincipals mocks base method.
+func (m *MockGraphServiceClient) ListServicePrincipals(ctx context.Context, options *serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration) (models.ServicePrincipalCollectionResponseable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServicePrincipals", ctx, options)
+	ret0, _ := ret[0].(models.ServicePrincipalCollectionResponseable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListServicePrincipals indicates an expected call of ListServicePrincipals.
+func (mr *MockGraphServiceClientMockRecorder) ListServicePrincipals(ctx, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServicePrincipals", reflect.TypeOf((*MockGraphServiceClient)(nil).ListServicePrincipals), ctx, options)
+}
+
+// UpdateApplication mocks base method.
+func (m *MockGraphServiceClient) UpdateApplication(ctx context.Context, objectID string, app models.Applicationable) (models.Applicationable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateApplication", ctx, objectID, app)
+	ret0, _ := ret[0].(models.Applicationable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateApplication indicates an expected call of UpdateApplication.
+func (mr *MockGraphServiceClientMockRecorder) UpdateApplication(ctx, objectID, app interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateApplication", reflect.TypeOf((*MockGraphServiceClient)(nil).UpdateApplication), ctx, objectID, app)
+}
+
+// UpdateFederatedIdentityCredential mocks base method.
+func (m *MockGraphServiceClient) UpdateFederatedIdentityCredential(ctx context.Context, objectID, federatedCredentialID string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFederatedIdentityCredential", ctx, objectID, federatedCredentialID, fic)
+	ret0, _ := ret[0].(models.FederatedIdentityCredentialable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateFederatedIdentityCredential indicates an expected call of UpdateFederatedIdentityCredential.
+func (mr *MockGraphServiceClientMockRecorder) UpdateFederatedIdentityCredential(ctx, objectID, federatedCredentialID, fic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFederatedIdentityCredential", reflect.TypeOf((*MockGraphServiceClient)(nil).UpdateFederatedIdentityCredential), ctx, objectID, federatedCredentialID, fic)
+}
+
+// UpdateServicePrincipal mocks base method.
+func (m *MockGraphServiceClient) UpdateServicePrincipal(ctx context.Context, objectID string, sp models.ServicePrincipalable) (models.ServicePrincipalable, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateServicePrincipal", ctx, objectID, sp)
+	ret0, _ := ret[0].(models.ServicePrincipalable)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateServicePrincipal indicates an expected call of UpdateServicePrincipal.
+func (mr *MockGraphServiceClientMockRecorder) UpdateServicePrincipal(ctx, objectID, sp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateServicePrincipal", reflect.TypeOf((*MockGraphServiceClient)(nil).UpdateServicePrincipal), ctx, objectID, sp)
 }
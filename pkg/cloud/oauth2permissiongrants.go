@@ -0,0 +1,183 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/oauth2permissiongrants"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/pkg/errors"
+)
+
+const (
+	// consentTypeAllPrincipals is the consent type of a grant consented on behalf of all users, i.e. admin consent.
+	consentTypeAllPrincipals = "AllPrincipals"
+)
+
+// GrantAdminConsent grants admin consent for the delegated scopes of the resource service principal to the client
+// service principal by creating an oauth2PermissionGrant on behalf of all users.
+// If the grant already exists, the missing scopes are added to it.
+func (c *AzureClient) GrantAdminConsent(ctx context.Context, spObjectID string, resourceSPObjectID string, scopes []string) error {
+	if len(mergeScopes("", scopes)) == 0 {
+		return errors.New("at least one scope is required to grant admin consent")
+	}
+
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Granting admin consent",
+		"servicePrincipalObjectID", spObjectID,
+		"resourceServicePrincipalObjectID", resourceSPObjectID,
+		"scopes", scopes,
+	)
+
+	grantGetOptions := &oauth2permissiongrants.Oauth2PermissionGrantsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &oauth2permissiongrants.Oauth2PermissionGrantsRequestBuilderGetQueryParameters{
+			Filter: to.StringPtr(getAdminConsentFilter(spObjectID, resourceSPObjectID)),
+		},
+	}
+	resp, err := c.graphServiceClient.Oauth2PermissionGrants().Get(ctx, grantGetOptions)
+	if err != nil {
+		return err
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+
+	if len(resp.GetValue()) == 0 {
+		grant := models.NewOAuth2PermissionGrant()
+		grant.SetClientId(to.StringPtr(spObjectID))
+		grant.SetConsentType(to.StringPtr(consentTypeAllPrincipals))
+		grant.SetResourceId(to.StringPtr(resourceSPObjectID))
+		grant.SetScope(to.StringPtr(strings.Join(mergeScopes("", scopes), " ")))
+
+		if c.skipInDryRun("creating OAuth2 permission grant", "servicePrincipalObjectID", spObjectID, "resourceServicePrincipalObjectID", resourceSPObjectID, "scope", to.String(grant.GetScope())) {
+			return nil
+		}
+		created, err := c.graphServiceClient.Oauth2PermissionGrants().Post(ctx, grant, nil)
+		if err != nil {
+			return err
+		}
+		graphErr, err := GetGraphError(created.GetAdditionalData())
+		if err != nil {
+			return err
+		}
+		if graphErr != nil {
+			return *graphErr
+		}
+		return nil
+	}
+
+	existing := resp.GetValue()[0]
+	merged := strings.Join(mergeScopes(to.String(existing.GetScope()), scopes), " ")
+	if merged == strings.Join(strings.Fields(to.String(existing.GetScope())), " ") {
+		c.logDebug("Admin consent has previously been granted", "grantID", to.String(existing.GetId()))
+		return nil
+	}
+
+	if c.skipInDryRun("updating OAuth2 permission grant", "grantID", to.String(existing.GetId()), "scope", merged) {
+		return nil
+	}
+	update := models.NewOAuth2PermissionGrant()
+	update.SetScope(to.StringPtr(merged))
+	updated, err := c.graphServiceClient.Oauth2PermissionGrantsById(to.String(existing.GetId())).Patch(ctx, update, nil)
+	if err != nil {
+		return err
+	}
+	// the grant is not returned when it is updated successfully
+	if updated == nil {
+		return nil
+	}
+	graphErr, err = GetGraphError(updated.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+	return nil
+}
+
+// ListServicePrincipalOAuth2PermissionGrants lists the delegated permission grants of the service principal,
+// i.e. the delegated permissions it has been consented to, on behalf of all users or of individual users.
+func (c *AzureClient) ListServicePrincipalOAuth2PermissionGrants(ctx context.Context, objectID string) ([]models.OAuth2PermissionGrantable, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Listing OAuth2 permission grants", "servicePrincipalObjectID", objectID)
+
+	resp, err := c.graphServiceClient.ServicePrincipalsById(objectID).Oauth2PermissionGrants().Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []models.OAuth2PermissionGrantable
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return partialResults(c, grants), err
+		}
+		if graphErr != nil {
+			return partialResults(c, grants), *graphErr
+		}
+		grants = append(grants, resp.GetValue()...)
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return grants, nil
+		}
+		if resp, err = serviceprincipals.NewItemOauth2PermissionGrantsRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return partialResults(c, grants), err
+		}
+	}
+}
+
+// DeleteServicePrincipalOAuth2PermissionGrant deletes the delegated permission grant, which revokes the
+// consent to its delegated permissions. It succeeds if the grant has already been deleted.
+func (c *AzureClient) DeleteServicePrincipalOAuth2PermissionGrant(ctx context.Context, grantID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("deleting OAuth2 permission grant", "grantID", grantID) {
+		return nil
+	}
+	c.logDebug("Deleting OAuth2 permission grant", "grantID", grantID)
+
+	if err := c.graphServiceClient.Oauth2PermissionGrantsById(grantID).Delete(ctx, nil); err != nil {
+		if isGraphResourceNotFound(err) {
+			c.logDebug("OAuth2 permission grant has already been deleted", "grantID", grantID)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// mergeScopes returns the space-separated scopes followed by the additional scopes that are not already included.
+func mergeScopes(scope string, additional []string) []string {
+	merged := strings.Fields(scope)
+	seen := make(map[string]bool, len(merged))
+	for _, s := range merged {
+		seen[s] = true
+	}
+	for _, s := range additional {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// getAdminConsentFilter returns a filter string for the admin consent grant of the client to the resource.
+func getAdminConsentFilter(clientID, resourceID string) string {
+	return fmt.Sprintf("clientId eq '%s' and resourceId eq '%s' and consentType eq '%s'", escapeODataString(clientID), escapeODataString(resourceID), consentTypeAllPrincipals)
+}
@@ -0,0 +1,183 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestGrantAdminConsent(t *testing.T) {
+	tests := []struct {
+		name          string
+		existingScope string
+		scopes        []string
+		wantMethod    string
+		wantScope     string
+	}{
+		{
+			name:       "create grant",
+			scopes:     []string{"User.Read", "Mail.Read"},
+			wantMethod: http.MethodPost,
+			wantScope:  "User.Read Mail.Read",
+		},
+		{
+			name:          "update existing grant",
+			existingScope: "User.Read",
+			scopes:        []string{"User.Read", "Mail.Read"},
+			wantMethod:    http.MethodPatch,
+			wantScope:     "User.Read Mail.Read",
+		},
+		{
+			name:          "existing grant includes all scopes",
+			existingScope: "User.Read Mail.Read",
+			scopes:        []string{"Mail.Read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod string
+			var gotBody map[string]interface{}
+			record := func(r *http.Request) {
+				gotMethod = r.Method
+				if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/oauth2PermissionGrants", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method {
+				case http.MethodGet:
+					if got, want := r.URL.Query().Get("$filter"), "clientId eq 'sp-object-id' and resourceId eq 'resource-sp-object-id' and consentType eq 'AllPrincipals'"; got != want {
+						t.Errorf("expected $filter to be %s, got %s", want, got)
+					}
+					if tt.existingScope == "" {
+						fmt.Fprint(w, `{"value": []}`)
+						return
+					}
+					fmt.Fprintf(w, `{"value": [{"id": "grant-id", "clientId": "sp-object-id", "resourceId": "resource-sp-object-id", "consentType": "AllPrincipals", "scope": %q}]}`, tt.existingScope)
+				case http.MethodPost:
+					record(r)
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"id": "grant-id"}`)
+				default:
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				}
+			})
+			mux.HandleFunc("/v1.0/oauth2PermissionGrants/grant-id", func(w http.ResponseWriter, r *http.Request) {
+				record(r)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			c := newTestAzureClient(t, mux)
+
+			if err := c.GrantAdminConsent(context.Background(), "sp-object-id", "resource-sp-object-id", tt.scopes); err != nil {
+				t.Fatalf("GrantAdminConsent() error = %v", err)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Fatalf("expected %q request, got %q", tt.wantMethod, gotMethod)
+			}
+			if tt.wantMethod == "" {
+				return
+			}
+			if got := gotBody["scope"]; got != tt.wantScope {
+				t.Errorf("expected scope %q, got %v", tt.wantScope, got)
+			}
+			if tt.wantMethod == http.MethodPost {
+				want := map[string]interface{}{
+					"clientId":    "sp-object-id",
+					"consentType": "AllPrincipals",
+					"resourceId":  "resource-sp-object-id",
+					"scope":       tt.wantScope,
+				}
+				if !reflect.DeepEqual(gotBody, want) {
+					t.Errorf("request body = %v, want %v", gotBody, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGrantAdminConsentWithoutScopes(t *testing.T) {
+	c := newTestAzureClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	for _, scopes := range [][]string{nil, {""}} {
+		if err := c.GrantAdminConsent(context.Background(), "sp-object-id", "resource-sp-object-id", scopes); err == nil {
+			t.Errorf("GrantAdminConsent() with scopes %q error = nil, want error", scopes)
+		}
+	}
+}
+
+func TestListServicePrincipalOAuth2PermissionGrants(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id/oauth2PermissionGrants", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"id": "grant-1", "clientId": "sp-object-id", "consentType": "AllPrincipals", "resourceId": "graph-sp-object-id", "scope": "User.Read"}], "@odata.nextLink": "http://%s/v1.0/servicePrincipals/sp-object-id/oauth2PermissionGrants?$skiptoken=page-2"}`, r.Host)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "grant-2", "clientId": "sp-object-id", "consentType": "Principal", "principalId": "user-object-id", "resourceId": "api-sp-object-id", "scope": "user_impersonation"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	grants, err := c.ListServicePrincipalOAuth2PermissionGrants(context.Background(), "sp-object-id")
+	if err != nil {
+		t.Fatalf("ListServicePrincipalOAuth2PermissionGrants() error = %v", err)
+	}
+	if len(grants) != 2 {
+		t.Fatalf("expected 2 grants, got %d", len(grants))
+	}
+	if got := to.String(grants[0].GetScope()); got != "User.Read" {
+		t.Errorf("expected the scope of the first grant to be User.Read, got %s", got)
+	}
+	if got := to.String(grants[1].GetPrincipalId()); got != "user-object-id" {
+		t.Errorf("expected the principal of the second grant to be user-object-id, got %s", got)
+	}
+}
+
+func TestDeleteServicePrincipalOAuth2PermissionGrant(t *testing.T) {
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/oauth2PermissionGrants/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		switch id := r.URL.Path[len("/v1.0/oauth2PermissionGrants/"):]; id {
+		case "grant-1":
+			deleted = append(deleted, id)
+			w.WriteHeader(http.StatusNoContent)
+		case "forbidden":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error": {"code": "Request_ResourceNotFound", "message": "Resource '%s' does not exist."}}`, id)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	if err := c.DeleteServicePrincipalOAuth2PermissionGrant(context.Background(), "grant-1"); err != nil {
+		t.Fatalf("DeleteServicePrincipalOAuth2PermissionGrant() error = %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"grant-1"}) {
+		t.Errorf("expected grant-1 to be deleted, got %v", deleted)
+	}
+
+	if err := c.DeleteServicePrincipalOAuth2PermissionGrant(context.Background(), "deleted-grant"); err != nil {
+		t.Errorf("expected no error for a grant that doesn't exist, got %v", err)
+	}
+
+	if err := c.DeleteServicePrincipalOAuth2PermissionGrant(context.Background(), "forbidden"); err == nil {
+		t.Error("expected error for a forbidden request but got nil")
+	}
+}
@@ -0,0 +1,159 @@
+package cloud
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+// AddApplicationOwner adds the user or service principal with the given object ID to the owners of the application,
+// e.g. for the application to have the owner required by a governance policy. It succeeds if it is already an owner.
+func (c *AzureClient) AddApplicationOwner(ctx context.Context, objectID, ownerObjectID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("adding application owner", "objectID", objectID, "ownerObjectID", ownerObjectID) {
+		return nil
+	}
+	c.logDebug("Adding application owner", "objectID", objectID, "ownerObjectID", ownerObjectID)
+
+	err := c.graphServiceClient.ApplicationsById(objectID).Owners().Ref().Post(ctx, c.newDirectoryObjectReference(ownerObjectID), nil)
+	if isOwnerAlreadyExists(err) {
+		c.logDebug("Owner has already been added to application", "objectID", objectID, "ownerObjectID", ownerObjectID)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(withODataErrorDetails(err), "failed to add owner %s to application %s", ownerObjectID, objectID)
+	}
+	c.applicationCache.evict(objectID)
+	return nil
+}
+
+// AddServicePrincipalOwner adds the user or service principal with the given object ID to the owners of the
+// service principal. It succeeds if it is already an owner.
+func (c *AzureClient) AddServicePrincipalOwner(ctx context.Context, objectID, ownerObjectID string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("adding service principal owner", "objectID", objectID, "ownerObjectID", ownerObjectID) {
+		return nil
+	}
+	c.logDebug("Adding service principal owner", "objectID", objectID, "ownerObjectID", ownerObjectID)
+
+	err := c.graphServiceClient.ServicePrincipalsById(objectID).Owners().Ref().Post(ctx, c.newDirectoryObjectReference(ownerObjectID), nil)
+	if isOwnerAlreadyExists(err) {
+		c.logDebug("Owner has already been added to service principal", "objectID", objectID, "ownerObjectID", ownerObjectID)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(withODataErrorDetails(err), "failed to add owner %s to service principal %s", ownerObjectID, objectID)
+	}
+	c.servicePrincipalCache.evict(objectID)
+	return nil
+}
+
+// newDirectoryObjectReference returns the reference to the directory object with the given object ID
+// that is added to a collection of references, e.g. the owners of an object.
+func (c *AzureClient) newDirectoryObjectReference(objectID string) models.ReferenceCreateable {
+	ref := models.NewReferenceCreate()
+	ref.SetOdataId(to.StringPtr(c.graphClient.GetAdapter().GetBaseUrl() + "/directoryObjects/" + objectID))
+	return ref
+}
+
+// TransferApplicationOwnership makes newOwnerObjectID an owner of the application and, if removeExisting
+// is true, removes the prior owners. The new owner is added first and the prior owners are only removed
+// once the new owner is listed as an owner, so the application is never left without an owner: if the new
+// owner can't be confirmed, an error is returned and the prior owners, including the last one, are kept.
+func (c *AzureClient) TransferApplicationOwnership(ctx context.Context, objectID, newOwnerObjectID string, removeExisting bool) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Transferring application ownership", "objectID", objectID, "newOwnerObjectID", newOwnerObjectID, "removeExisting", removeExisting)
+
+	owners, err := c.listApplicationOwners(ctx, objectID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list application owners")
+	}
+	if c.skipInDryRun("transferring application ownership", "objectID", objectID, "newOwnerObjectID", newOwnerObjectID, "owners", owners, "removeExisting", removeExisting) {
+		return nil
+	}
+	if !containsFold(owners, newOwnerObjectID) {
+		if err := c.graphServiceClient.ApplicationsById(objectID).Owners().Ref().Post(ctx, c.newDirectoryObjectReference(newOwnerObjectID), nil); err != nil {
+			return errors.Wrapf(err, "failed to add owner %s", newOwnerObjectID)
+		}
+		c.applicationCache.evict(objectID)
+	}
+	if !removeExisting {
+		return nil
+	}
+
+	// list the owners again to confirm the new owner before removing the prior ones,
+	// which guarantees that the new owner remains as the last owner
+	if owners, err = c.listApplicationOwners(ctx, objectID); err != nil {
+		return errors.Wrap(err, "failed to list application owners")
+	}
+	if !containsFold(owners, newOwnerObjectID) {
+		return errors.Errorf("new owner %s is not an owner of application %s yet, prior owners are not removed", newOwnerObjectID, objectID)
+	}
+
+	for _, owner := range owners {
+		if strings.EqualFold(owner, newOwnerObjectID) {
+			continue
+		}
+		if err := c.graphServiceClient.ApplicationsById(objectID).OwnersById(owner).Ref().Delete(ctx, nil); err != nil {
+			return errors.Wrapf(err, "failed to remove owner %s", owner)
+		}
+	}
+	c.applicationCache.evict(objectID)
+	return nil
+}
+
+// listApplicationOwners lists the object IDs of the owners of the application.
+func (c *AzureClient) listApplicationOwners(ctx context.Context, objectID string) ([]string, error) {
+	ownersGetOptions := &applications.ItemOwnersRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ItemOwnersRequestBuilderGetQueryParameters{
+			Select: []string{"id"},
+		},
+	}
+
+	resp, err := c.graphServiceClient.ApplicationsById(objectID).Owners().Get(ctx, ownersGetOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []string
+	for {
+		graphErr, err := GetGraphError(resp.GetAdditionalData())
+		if err != nil {
+			return nil, err
+		}
+		if graphErr != nil {
+			return nil, *graphErr
+		}
+		for _, owner := range resp.GetValue() {
+			owners = append(owners, to.String(owner.GetId()))
+		}
+
+		nextLink := resp.GetOdataNextLink()
+		if nextLink == nil || *nextLink == "" {
+			return owners, nil
+		}
+		if resp, err = applications.NewItemOwnersRequestBuilder(*nextLink, c.graphClient.GetAdapter()).Get(ctx, nil); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// containsFold returns true if the values contain the value, compared case-insensitively.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOwners serves the owners of an application and records the owner changes.
+type fakeOwners struct {
+	mu     sync.Mutex
+	owners []string
+	// ignoreAdd drops added owners, like an owner that has not propagated yet
+	ignoreAdd bool
+	calls     []string
+}
+
+func (f *fakeOwners) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/owners", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		values := make([]string, 0, len(f.owners))
+		for _, owner := range f.owners {
+			values = append(values, fmt.Sprintf(`{"@odata.type": "#microsoft.graph.user", "id": "%s"}`, owner))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value": [%s]}`, strings.Join(values, ","))
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/owners/$ref", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		id := strings.TrimPrefix(body["@odata.id"], "http://"+r.Host+"/v1.0/directoryObjects/")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, "add "+id)
+		if !f.ignoreAdd {
+			f.owners = append(f.owners, id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1.0/applications/object-id/owners/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || !strings.HasSuffix(r.URL.Path, "/$ref") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1.0/applications/object-id/owners/"), "/$ref")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, "remove "+id)
+		for i, owner := range f.owners {
+			if owner == id {
+				f.owners = append(f.owners[:i], f.owners[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestTransferApplicationOwnership(t *testing.T) {
+	tests := []struct {
+		name           string
+		owners         []string
+		ignoreAdd      bool
+		removeExisting bool
+		wantCalls      []string
+		wantOwners     []string
+		wantErr        bool
+	}{
+		{
+			name:           "add then remove prior owners",
+			owners:         []string{"owner-1", "owner-2"},
+			removeExisting: true,
+			wantCalls:      []string{"add new-owner", "remove owner-1", "remove owner-2"},
+			wantOwners:     []string{"new-owner"},
+		},
+		{
+			name:       "add without removing prior owners",
+			owners:     []string{"owner-1"},
+			wantCalls:  []string{"add new-owner"},
+			wantOwners: []string{"owner-1", "new-owner"},
+		},
+		{
+			name:           "new owner is already an owner",
+			owners:         []string{"new-owner", "owner-1"},
+			removeExisting: true,
+			wantCalls:      []string{"remove owner-1"},
+			wantOwners:     []string{"new-owner"},
+		},
+		{
+			name:           "last owner is kept if the new owner is not confirmed",
+			owners:         []string{"owner-1"},
+			ignoreAdd:      true,
+			removeExisting: true,
+			wantCalls:      []string{"add new-owner"},
+			wantOwners:     []string{"owner-1"},
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &fakeOwners{owners: append([]string(nil), test.owners...), ignoreAdd: test.ignoreAdd}
+			c := newTestAzureClient(t, f.handler(t))
+
+			err := c.TransferApplicationOwnership(context.Background(), "object-id", "new-owner", test.removeExisting)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("TransferApplicationOwnership() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(f.calls, test.wantCalls) {
+				t.Errorf("expected calls %v, got %v", test.wantCalls, f.calls)
+			}
+			if !reflect.DeepEqual(f.owners, test.wantOwners) {
+				t.Errorf("expected owners %v, got %v", test.wantOwners, f.owners)
+			}
+		})
+	}
+}
+
+func TestAddOwner(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		alreadyExists bool
+		add           func(c *AzureClient) error
+	}{
+		{
+			name: "application",
+			path: "/v1.0/applications/object-id/owners/$ref",
+			add: func(c *AzureClient) error {
+				return c.AddApplicationOwner(context.Background(), "object-id", "owner-id")
+			},
+		},
+		{
+			name:          "application already owned",
+			path:          "/v1.0/applications/object-id/owners/$ref",
+			alreadyExists: true,
+			add: func(c *AzureClient) error {
+				return c.AddApplicationOwner(context.Background(), "object-id", "owner-id")
+			},
+		},
+		{
+			name: "service principal",
+			path: "/v1.0/servicePrincipals/sp-object-id/owners/$ref",
+			add: func(c *AzureClient) error {
+				return c.AddServicePrincipalOwner(context.Background(), "sp-object-id", "owner-id")
+			},
+		},
+		{
+			name:          "service principal already owned",
+			path:          "/v1.0/servicePrincipals/sp-object-id/owners/$ref",
+			alreadyExists: true,
+			add: func(c *AzureClient) error {
+				return c.AddServicePrincipalOwner(context.Background(), "sp-object-id", "owner-id")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var reference string
+			mux := http.NewServeMux()
+			mux.HandleFunc(test.path, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST request, got %s", r.Method)
+				}
+				var body map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				reference = strings.Replace(body["@odata.id"], r.Host, "graph", 1)
+				if test.alreadyExists {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"error": {"code": "Request_BadRequest", "message": "One or more added object references already exist for the following modified properties: 'owners'."}}`)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+			c := newTestAzureClient(t, mux)
+
+			if err := test.add(c); err != nil {
+				t.Fatalf("expected the owner to be added, got %v", err)
+			}
+			if want := "http://graph/v1.0/directoryObjects/owner-id"; reference != want {
+				t.Errorf("expected @odata.id to be %s, got %s", want, reference)
+			}
+		})
+	}
+}
+
+func TestAddApplicationOwnerError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications/object-id/owners/$ref", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"code": "Request_BadRequest", "message": "Invalid object identifier 'owner-id'."}}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	err := c.AddApplicationOwner(context.Background(), "object-id", "owner-id")
+	if err == nil || !strings.Contains(err.Error(), "failed to add owner owner-id to application object-id") {
+		t.Errorf("expected the owner to fail to be added, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package cloud
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+// ApplicationPager reads the applications matching a filter one page at a time, so that the caller
+// controls when the next page is requested instead of loading all the applications at once.
+// It is not safe for concurrent use.
+type ApplicationPager struct {
+	ctx     context.Context
+	client  *AzureClient
+	filter  string
+	headers *abstractions.RequestHeaders
+
+	// nextLink is the link of the next page, empty before the first page is read
+	nextLink string
+	done     bool
+}
+
+// NewApplicationPager returns a pager over the applications matching the OData filter, or all the
+// applications if the filter is empty. No request is sent until Next is called.
+func (c *AzureClient) NewApplicationPager(ctx context.Context, filter string) *ApplicationPager {
+	p := &ApplicationPager{
+		ctx:    ctx,
+		client: c,
+		filter: filter,
+	}
+	if filter != "" {
+		p.headers = newAdvancedQueryHeaders()
+	}
+	return p
+}
+
+// HasNext returns true if there is a page left to read. It is true before the first page is read,
+// even if there are no applications, so that the caller reads at least one page.
+func (p *ApplicationPager) HasNext() bool {
+	return !p.done
+}
+
+// Next returns the applications of the next page. If the page fails to be read, the error is returned
+// and the same page is requested again by the next call.
+func (p *ApplicationPager) Next() ([]models.Applicationable, error) {
+	if p.done {
+		return nil, errors.New("no more pages of applications")
+	}
+
+	ctx, cancel := p.client.withDefaultTimeout(p.ctx)
+	defer cancel()
+
+	p.client.logDebug("Reading page of applications", "filter", p.filter, "first", p.nextLink == "")
+
+	var (
+		resp models.ApplicationCollectionResponseable
+		err  error
+	)
+	if p.nextLink == "" {
+		resp, err = p.client.graphClient.ListApplications(ctx, newListApplicationsOptions(p.filter, p.headers, p.client.top()))
+	} else {
+		nextOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{Headers: p.headers}
+		resp, err = applications.NewApplicationsRequestBuilder(p.nextLink, p.client.graphClient.GetAdapter()).Get(ctx, nextOptions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+
+	if p.nextLink = to.String(resp.GetOdataNextLink()); p.nextLink == "" {
+		p.done = true
+	}
+	return resp.GetValue(), nil
+}
@@ -0,0 +1,137 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestApplicationPager(t *testing.T) {
+	const filter = "startswith(displayName,'team-a')"
+
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("ConsistencyLevel"); got != "eventual" {
+			t.Errorf("expected ConsistencyLevel header eventual, got %q", got)
+		}
+		base := "http://" + r.Host + "/v1.0/applications"
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("$skiptoken") {
+		case "":
+			if got := r.URL.Query().Get("$filter"); got != filter {
+				t.Errorf("expected filter %q, got %q", filter, got)
+			}
+			fmt.Fprintf(w, `{"value": [{"id": "app-1"}, {"id": "app-2"}], "@odata.nextLink": "%s?$skiptoken=page-2"}`, base)
+		case "page-2":
+			fmt.Fprintf(w, `{"value": [{"id": "app-3"}], "@odata.nextLink": "%s?$skiptoken=page-3"}`, base)
+		case "page-3":
+			fmt.Fprint(w, `{"value": [{"id": "app-4"}]}`)
+		default:
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	pager := c.NewApplicationPager(context.Background(), filter)
+	if requests != 0 {
+		t.Errorf("expected no request to be sent before Next is called, got %d", requests)
+	}
+
+	var pages [][]string
+	for pager.HasNext() {
+		apps, err := pager.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		var ids []string
+		for _, app := range apps {
+			ids = append(ids, to.String(app.GetId()))
+		}
+		pages = append(pages, ids)
+	}
+
+	if want := [][]string{{"app-1", "app-2"}, {"app-3"}, {"app-4"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("expected pages %v, got %v", want, pages)
+	}
+	if _, err := pager.Next(); err == nil {
+		t.Errorf("expected an error when reading past the last page")
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestApplicationPagerEmpty(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("expected no query without a filter, got %q", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": []}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	pager := c.NewApplicationPager(context.Background(), "")
+	if !pager.HasNext() {
+		t.Fatalf("expected HasNext to be true before the first page is read")
+	}
+	apps, err := pager.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(apps) != 0 {
+		t.Errorf("expected no applications, got %d", len(apps))
+	}
+	if pager.HasNext() {
+		t.Errorf("expected HasNext to be false after the last page is read")
+	}
+}
+
+func TestApplicationPagerRetriesFailedPage(t *testing.T) {
+	failures := 1
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("$skiptoken") == "" {
+			fmt.Fprintf(w, `{"value": [{"id": "app-1"}], "@odata.nextLink": "http://%s/v1.0/applications?$skiptoken=page-2"}`, r.Host)
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": [{"id": "app-2"}]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	pager := c.NewApplicationPager(context.Background(), "")
+	if _, err := pager.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if _, err := pager.Next(); err == nil {
+		t.Fatalf("expected the second page to fail")
+	}
+	if !pager.HasNext() {
+		t.Fatalf("expected HasNext to be true after a failed page")
+	}
+
+	apps, err := pager.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(apps) != 1 || to.String(apps[0].GetId()) != "app-2" {
+		t.Errorf("expected the second page to be read again, got %d applications", len(apps))
+	}
+	if pager.HasNext() {
+		t.Errorf("expected HasNext to be false after the last page is read")
+	}
+}
@@ -0,0 +1,133 @@
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/applications"
+	"github.com/pkg/errors"
+)
+
+// impliedPermissions maps a Graph permission to the broader permissions that also grant it.
+var impliedPermissions = map[string][]string{
+	"Application.Read.All":              {"Application.ReadWrite.All", "Directory.Read.All", "Directory.ReadWrite.All"},
+	"Application.ReadWrite.OwnedBy":     {"Application.ReadWrite.All"},
+	"DelegatedPermissionGrant.Read.All": {"DelegatedPermissionGrant.ReadWrite.All", "Directory.Read.All", "Directory.ReadWrite.All"},
+}
+
+// tokenClaims are the permission claims of a Graph access token.
+type tokenClaims struct {
+	// Roles are the application permissions of an application token.
+	Roles []string `json:"roles"`
+	// Scope is the space-separated list of the delegated permissions of a user token.
+	Scope string `json:"scp"`
+}
+
+// CheckRequiredPermissions returns the required Graph permissions that are not granted to the authenticated
+// principal, as application permissions (roles) or delegated permissions (scopes) of its Graph access token.
+// A permission is also granted by the broader permissions that imply it, e.g. Application.ReadWrite.OwnedBy
+// by Application.ReadWrite.All. Directory roles assigned to the principal are not taken into account.
+func (c *AzureClient) CheckRequiredPermissions(ctx context.Context, required []string) ([]string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.graphTokenProvider == nil {
+		return nil, errors.New("the Graph access token is not available")
+	}
+
+	c.logDebug("Checking required Graph permissions", "required", required)
+	graphURL, err := url.Parse(c.graphClient.GetAdapter().GetBaseUrl())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse Graph URL")
+	}
+	token, err := c.graphTokenProvider.GetAuthorizationToken(ctx, graphURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Graph access token")
+	}
+	claims, err := parseTokenClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.missingPermissions(required), nil
+}
+
+// Preflight checks that the AzureClient can reach Graph with the permissions to manage the applications, e.g. to
+// fail fast at startup rather than in the middle of an operation. It sends a single read-only request, which lists
+// at most one application. An InsufficientPrivilegesError naming the Graph permission to grant is returned if the
+// request is denied.
+func (c *AzureClient) Preflight(ctx context.Context) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Running preflight check")
+
+	appGetOptions := &applications.ApplicationsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &applications.ApplicationsRequestBuilderGetQueryParameters{
+			Select: []string{"id"},
+			Top:    to.Int32Ptr(1),
+		},
+	}
+	resp, err := c.graphClient.ListApplications(ctx, appGetOptions)
+	if err == nil {
+		var graphErr *GraphError
+		if graphErr, err = GetGraphError(resp.GetAdditionalData()); err == nil && graphErr != nil {
+			err = *graphErr
+		}
+	}
+	if err != nil {
+		return errors.Wrap(withInsufficientPrivileges(err), "preflight check failed")
+	}
+	return nil
+}
+
+// parseTokenClaims parses the permission claims of the access token.
+// The signature is not verified since the token is only inspected, not trusted.
+func parseTokenClaims(token string) (tokenClaims, error) {
+	var claims tokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("access token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, errors.Wrap(err, "failed to decode access token payload")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, errors.Wrap(err, "failed to unmarshal access token claims")
+	}
+	return claims, nil
+}
+
+// missingPermissions returns the required permissions that are not granted by the claims, in the order they are required.
+// Permissions are compared case-insensitively.
+func (t tokenClaims) missingPermissions(required []string) []string {
+	granted := make(map[string]bool)
+	for _, role := range t.Roles {
+		granted[strings.ToLower(role)] = true
+	}
+	for _, scope := range strings.Fields(t.Scope) {
+		granted[strings.ToLower(scope)] = true
+	}
+
+	missing := []string{}
+	for _, permission := range required {
+		if granted[strings.ToLower(permission)] {
+			continue
+		}
+		implied := false
+		for _, broader := range impliedPermissions[permission] {
+			if granted[strings.ToLower(broader)] {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,148 @@
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/kiota-abstractions-go/authentication"
+	"github.com/pkg/errors"
+)
+
+// staticTokenProvider is an access token provider that returns a static token.
+type staticTokenProvider struct {
+	token string
+}
+
+func (p staticTokenProvider) GetAuthorizationToken(context.Context, *url.URL, map[string]interface{}) (string, error) {
+	return p.token, nil
+}
+
+func (p staticTokenProvider) GetAllowedHostsValidator() *authentication.AllowedHostsValidator {
+	return nil
+}
+
+func newTestToken(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestCheckRequiredPermissions(t *testing.T) {
+	required := []string{"Application.ReadWrite.OwnedBy", "DelegatedPermissionGrant.ReadWrite.All"}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name:    "no permissions",
+			payload: `{}`,
+			want:    []string{"Application.ReadWrite.OwnedBy", "DelegatedPermissionGrant.ReadWrite.All"},
+		},
+		{
+			name:    "application permissions",
+			payload: `{"roles": ["Application.ReadWrite.OwnedBy", "DelegatedPermissionGrant.ReadWrite.All"]}`,
+			want:    []string{},
+		},
+		{
+			name:    "implied by a broader application permission",
+			payload: `{"roles": ["Application.ReadWrite.All"]}`,
+			want:    []string{"DelegatedPermissionGrant.ReadWrite.All"},
+		},
+		{
+			name:    "delegated permissions compared case-insensitively",
+			payload: `{"scp": "application.readwrite.all User.Read"}`,
+			want:    []string{"DelegatedPermissionGrant.ReadWrite.All"},
+		},
+		{
+			name:    "permission not implied by a narrower one",
+			payload: `{"roles": ["Application.Read.All", "DelegatedPermissionGrant.ReadWrite.All"]}`,
+			want:    []string{"Application.ReadWrite.OwnedBy"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestAzureClient(t, http.NewServeMux())
+			c.graphTokenProvider = staticTokenProvider{token: newTestToken(test.payload)}
+
+			missing, err := c.CheckRequiredPermissions(context.Background(), required)
+			if err != nil {
+				t.Fatalf("CheckRequiredPermissions() error = %v", err)
+			}
+			if !reflect.DeepEqual(missing, test.want) {
+				t.Errorf("expected missing permissions %v, got %v", test.want, missing)
+			}
+		})
+	}
+}
+
+func TestCheckRequiredPermissionsInvalidToken(t *testing.T) {
+	c := newTestAzureClient(t, http.NewServeMux())
+	if _, err := c.CheckRequiredPermissions(context.Background(), nil); err == nil {
+		t.Error("expected error without a Graph access token provider but got nil")
+	}
+
+	c.graphTokenProvider = staticTokenProvider{token: "opaque-token"}
+	if _, err := c.CheckRequiredPermissions(context.Background(), nil); err == nil {
+		t.Error("expected error for a token that is not a JWT but got nil")
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		denied  bool
+		wantErr error
+	}{
+		{
+			name: "allowed",
+		},
+		{
+			name:    "denied",
+			denied:  true,
+			wantErr: ErrInsufficientPrivileges,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Errorf("expected GET request, got %s", r.Method)
+				}
+				if got := r.URL.Query().Get("$top"); got != "1" {
+					t.Errorf("expected $top to be 1, got %q", got)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				if test.denied {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges to complete the operation."}}`)
+					return
+				}
+				fmt.Fprint(w, `{"value": [{"id": "object-id"}]}`)
+			})
+			c := newTestAzureClient(t, mux)
+
+			err := c.Preflight(context.Background())
+			if test.wantErr == nil {
+				if err != nil {
+					t.Errorf("Preflight() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Preflight() error = %v, want %v", err, test.wantErr)
+			}
+			if !strings.Contains(err.Error(), insufficientPrivilegesRequiredPermission) {
+				t.Errorf("Preflight() error = %v, want it to name the %s permission", err, insufficientPrivilegesRequiredPermission)
+			}
+		})
+	}
+}
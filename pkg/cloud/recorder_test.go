@@ -0,0 +1,262 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// record re-records the cassettes against a live tenant, e.g.
+//
+//	AZURE_TENANT_ID=<tenant ID> go test ./pkg/cloud -run TestRecorded -record
+//
+// The credential is resolved with DefaultAzureCredential. Review the recorded cassettes before committing them
+// since the responses contain the IDs of the tenant; the authorization headers are never recorded.
+var record = flag.Bool("record", false, "record the cassettes in testdata/cassettes against a live tenant")
+
+// cassette is a sequence of recorded Graph interactions.
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int             `json:"statusCode"`
+	Header     http.Header     `json:"header,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// recorder is an http.RoundTripper that records the interactions with the next transport or,
+// if next is nil, replays the recorded interactions in order.
+type recorder struct {
+	t    *testing.T
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette cassette
+	replayed int
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next == nil {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	if r.replayed == len(r.cassette.Interactions) {
+		r.t.Errorf("unexpected request %s %s, all the recorded interactions were replayed", req.Method, req.URL)
+		return &http.Response{StatusCode: http.StatusNotImplemented, Body: http.NoBody, Request: req}, nil
+	}
+	recorded := r.cassette.Interactions[r.replayed]
+	r.replayed++
+
+	// the host is not matched so that the cassettes can be replayed against any Graph endpoint
+	u, err := url.Parse(recorded.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if req.Method != recorded.Request.Method || req.URL.RequestURI() != u.RequestURI() {
+		r.t.Errorf("interaction %d: expected request %s %s, got %s %s", r.replayed, recorded.Request.Method, u.RequestURI(), req.Method, req.URL.RequestURI())
+	}
+	if len(recorded.Request.Body) > 0 && !jsonEqual(body, recorded.Request.Body) {
+		r.t.Errorf("interaction %d: expected request body %s, got %s", r.replayed, recorded.Request.Body, body)
+	}
+
+	return &http.Response{
+		StatusCode: recorded.Response.StatusCode,
+		Header:     recorded.Response.Header.Clone(),
+		Body:       io.NopCloser(bytes.NewReader(recorded.Response.Body)),
+		Request:    req,
+	}, nil
+}
+
+func (r *recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := http.Header{}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction{
+		Request:  recordedRequest{Method: req.Method, URL: req.URL.String(), Body: body},
+		Response: recordedResponse{StatusCode: resp.StatusCode, Header: header, Body: respBody},
+	})
+	return resp, nil
+}
+
+// jsonEqual returns true if a and b are the same JSON documents regardless of the formatting and the order of the keys.
+func jsonEqual(a, b []byte) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// newRecordedAzureClient returns an AzureClient that replays the named cassette of testdata/cassettes,
+// or records it against a live tenant if the -record flag is set. The test fails if the cassette is not
+// replayed entirely.
+func newRecordedAzureClient(t *testing.T, name string) *AzureClient {
+	t.Helper()
+
+	path := filepath.Join("testdata", "cassettes", name+".json")
+	r := &recorder{t: t}
+	opts := []Option{
+		// the retries are disabled so that each interaction is recorded as is
+		WithHTTPClient(&http.Client{Transport: r, Timeout: 30 * time.Second}),
+		WithMaxRetries(-1),
+	}
+	var cred azcore.TokenCredential
+
+	if *record {
+		var err error
+		if cred, err = azidentity.NewDefaultAzureCredential(nil); err != nil {
+			t.Fatalf("failed to create credential: %v", err)
+		}
+		r.next = http.DefaultTransport
+		opts = append(opts, WithTenantID(os.Getenv("AZURE_TENANT_ID")))
+		t.Cleanup(func() {
+			if t.Failed() {
+				return
+			}
+			data, err := json.MarshalIndent(r.cassette, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to marshal cassette: %v", err)
+			}
+			if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+				t.Fatalf("failed to write cassette: %v", err)
+			}
+		})
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read cassette: %v", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			t.Fatalf("failed to unmarshal cassette: %v", err)
+		}
+		cred = &fakeTokenCredential{}
+		t.Cleanup(func() {
+			if r.replayed != len(r.cassette.Interactions) {
+				t.Errorf("expected %d interactions to be replayed, got %d", len(r.cassette.Interactions), r.replayed)
+			}
+		})
+	}
+
+	c, err := NewAzureClient(context.Background(), cred, opts...)
+	if err != nil {
+		t.Fatalf("NewAzureClient() error = %v", err)
+	}
+	return c
+}
+
+func TestRecordedApplicationLifecycle(t *testing.T) {
+	c := newRecordedAzureClient(t, "application_lifecycle")
+	ctx := context.Background()
+
+	app, err := c.CreateApplication(ctx, "azwi-recorded-test")
+	if err != nil {
+		t.Fatalf("CreateApplication() error = %v", err)
+	}
+	if to.String(app.GetDisplayName()) != "azwi-recorded-test" || to.String(app.GetAppId()) == "" {
+		t.Errorf("expected the application azwi-recorded-test with an app ID, got %s with app ID %q", to.String(app.GetDisplayName()), to.String(app.GetAppId()))
+	}
+
+	sp, err := c.CreateServicePrincipal(ctx, to.String(app.GetAppId()), []string{"azwi-recorded-test"})
+	if err != nil {
+		t.Fatalf("CreateServicePrincipal() error = %v", err)
+	}
+	if to.String(sp.GetAppId()) != to.String(app.GetAppId()) {
+		t.Errorf("expected the service principal of app ID %s, got %s", to.String(app.GetAppId()), to.String(sp.GetAppId()))
+	}
+
+	if err := c.DeleteApplication(ctx, to.String(app.GetId())); err != nil {
+		t.Fatalf("DeleteApplication() error = %v", err)
+	}
+}
+
+func TestRecordedFederatedCredentials(t *testing.T) {
+	c := newRecordedAzureClient(t, "federated_credentials")
+	ctx := context.Background()
+
+	app, err := c.CreateApplication(ctx, "azwi-recorded-test")
+	if err != nil {
+		t.Fatalf("CreateApplication() error = %v", err)
+	}
+	objectID := to.String(app.GetId())
+	defer func() {
+		if err := c.DeleteApplication(ctx, objectID); err != nil {
+			t.Errorf("DeleteApplication() error = %v", err)
+		}
+	}()
+
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("kubernetes-federated-credential"))
+	fic.SetIssuer(to.StringPtr("https://oidc.example.com"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:workload-identity-sa"))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+	if _, err := c.AddFederatedCredential(ctx, objectID, fic); err != nil {
+		t.Fatalf("AddFederatedCredential() error = %v", err)
+	}
+
+	fics, err := c.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		t.Fatalf("ListFederatedCredentials() error = %v", err)
+	}
+	if len(fics) != 1 || to.String(fics[0].GetSubject()) != "system:serviceaccount:default:workload-identity-sa" {
+		t.Fatalf("expected the federated credential to be listed, got %d federated credentials", len(fics))
+	}
+
+	if err := c.DeleteFederatedCredential(ctx, objectID, to.String(fics[0].GetId())); err != nil {
+		t.Fatalf("DeleteFederatedCredential() error = %v", err)
+	}
+}
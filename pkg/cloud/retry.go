@@ -0,0 +1,176 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	khttp "github.com/microsoft/kiota-http-go"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/pkg/errors"
+)
+
+// defaultConnectionRefusedRetryDelay is the delay before a request whose connection was refused is sent again.
+const defaultConnectionRefusedRetryDelay = time.Second
+
+// isRetriableGraphRequest returns true if the request may be sent again after the given retriable response.
+// GET, PATCH, PUT and DELETE requests are idempotent, so they are retried on any retriable response. A POST
+// request creates an object, so it is only retried when it was throttled, i.e. rejected before it was processed:
+// a 503 or 504 response doesn't tell whether the object was created, and sending the request again could create
+// a duplicate.
+func isRetriableGraphRequest(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodPost {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests
+}
+
+// newGraphRetryHandlerOptions returns the options of the retry middleware of the Graph SDK that retry the throttled
+// or unavailable requests at most maxRetries times, see isRetriableGraphRequest for the requests that are retried.
+func newGraphRetryHandlerOptions(maxRetries int) *khttp.RetryHandlerOptions {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &khttp.RetryHandlerOptions{
+		MaxRetries: maxRetries,
+		ShouldRetry: func(_ time.Duration, _ int, req *http.Request, resp *http.Response) bool {
+			return maxRetries > 0 && isRetriableGraphRequest(req, resp)
+		},
+	}
+}
+
+// newGraphMiddlewares returns the default middleware of the Graph SDK with its retry handler replaced by one with
+// the options of newGraphRetryHandlerOptions.
+func newGraphMiddlewares(options *msgraphcore.GraphClientOptions, maxRetries int) []khttp.Middleware {
+	middlewares := msgraphcore.GetDefaultMiddlewaresWithOptions(options)
+	for i, middleware := range middlewares {
+		if _, ok := middleware.(*khttp.RetryHandler); ok {
+			middlewares[i] = khttp.NewRetryHandlerWithOptions(*newGraphRetryHandlerOptions(maxRetries))
+		}
+	}
+	return middlewares
+}
+
+// NewGraphMiddlewares returns the default middleware of the Graph SDK for the clients that are built outside of
+// NewAzureClient, e.g. with a custom transport below the middleware. Unlike the default retry handler of the SDK,
+// its retry handler doesn't send a create request again after a 503 or 504 response, which could create a duplicate.
+func NewGraphMiddlewares() []khttp.Middleware {
+	options := msgraphsdk.GetDefaultClientOptions()
+	return newGraphMiddlewares(&options, defaultMaxRetries)
+}
+
+// retryOptionsTransport is an http.RoundTripper that sets the retry options of the requests, which the retry
+// middleware of the Graph SDK uses instead of its own, e.g. for the middleware of a custom client.
+type retryOptionsTransport struct {
+	options *khttp.RetryHandlerOptions
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryOptionsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(context.WithValue(req.Context(), t.options.GetKey(), t.options)))
+}
+
+// connectionRefusedRetryTransport is an http.RoundTripper that sends a request again when its connection is
+// refused. Nothing reached the server in that case, so any request, including a POST, can safely be retried.
+type connectionRefusedRetryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	delay      time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *connectionRefusedRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil || !errors.Is(err, syscall.ECONNREFUSED) || attempt >= t.maxRetries || !rewindBody(req) {
+			return resp, err
+		}
+
+		logDebug("Connection refused, retrying Graph request", "method", req.Method, "path", req.URL.Path, "attempt", attempt+1)
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(t.delay):
+		}
+	}
+}
+
+// rewindBody rewinds the body of the request so that it can be sent again. It returns false if the body
+// can't be rewound.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		req.Body = body
+		return true
+	}
+	s, ok := req.Body.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err == nil
+}
+
+// ambiguousCreateError is the error of a create request that failed in a way that doesn't tell whether
+// the object was created.
+type ambiguousCreateError struct {
+	error
+}
+
+// Unwrap returns the error of the request.
+func (e ambiguousCreateError) Unwrap() error {
+	return e.error
+}
+
+// IsAmbiguousCreateError returns true if the given error is the error of a create request that timed out or
+// failed with a gateway error, in which case the object may have been created anyway. Since such requests are
+// not retried, the caller should look the object up before creating it again, e.g. get or create it.
+func IsAmbiguousCreateError(err error) bool {
+	aerr := ambiguousCreateError{}
+	return errors.As(err, &aerr)
+}
+
+// withAmbiguousCreateHint marks the error of the request creating the given kind of object as ambiguous
+// if it doesn't tell whether the object was created. Other errors are returned as is.
+func withAmbiguousCreateHint(err error, kind string) error {
+	if err == nil || !isAmbiguousError(err) {
+		return err
+	}
+	return ambiguousCreateError{errors.WithMessagef(err, "the %s may have been created even though the request failed, get it before creating it again", kind)}
+}
+
+// isAmbiguousError returns true if the request may have been processed even though it failed: it timed out
+// or the gateway failed or timed out before the response of the service was received.
+func isAmbiguousError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	var oerr *odataerrors.ODataError
+	if errors.As(err, &oerr) {
+		return isGatewayError(oerr.ResponseStatusCode)
+	}
+	var aerr *abstractions.ApiError
+	return errors.As(err, &aerr) && isGatewayError(aerr.ResponseStatusCode)
+}
+
+// isGatewayError returns true if the status code is returned when the gateway failed or timed out.
+func isGatewayError(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
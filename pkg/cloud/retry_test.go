@@ -0,0 +1,414 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoft/kiota-abstractions-go/authentication"
+	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+)
+
+// newRetryingTestAzureClient returns an AzureClient that sends the Graph requests through the
+// default Graph client, retries included, to a test server serving the given handler.
+func newRetryingTestAzureClient(t *testing.T, handler http.Handler) *AzureClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(&authentication.AnonymousAuthenticationProvider{}, nil, nil, newDefaultGraphClient(defaultMaxRetries))
+	if err != nil {
+		t.Fatalf("failed to create request adapter: %v", err)
+	}
+	adapter.SetBaseUrl(server.URL + "/v1.0")
+
+	graphServiceClient := msgraphsdk.NewGraphServiceClient(adapter)
+	return &AzureClient{
+		graphClient:        newGraphServiceClientAdapter(graphServiceClient),
+		graphServiceClient: graphServiceClient,
+		throttles:          &throttleRecorder{},
+		// the federated credentials added without audiences default to the audience of the public cloud
+		defaultFederatedAudiences: getDefaultFederatedAudiences(azure.PublicCloud),
+	}
+}
+
+func TestIsRetriableGraphRequest(t *testing.T) {
+	tests := []struct {
+		method     string
+		statusCode int
+		want       bool
+	}{
+		{method: http.MethodGet, statusCode: http.StatusTooManyRequests, want: true},
+		{method: http.MethodGet, statusCode: http.StatusGatewayTimeout, want: true},
+		{method: http.MethodPatch, statusCode: http.StatusServiceUnavailable, want: true},
+		{method: http.MethodDelete, statusCode: http.StatusGatewayTimeout, want: true},
+		{method: http.MethodPost, statusCode: http.StatusTooManyRequests, want: true},
+		{method: http.MethodPost, statusCode: http.StatusServiceUnavailable, want: false},
+		{method: http.MethodPost, statusCode: http.StatusGatewayTimeout, want: false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/v1.0/applications", nil)
+		if got := isRetriableGraphRequest(req, &http.Response{StatusCode: test.statusCode}); got != test.want {
+			t.Errorf("isRetriableGraphRequest(%s, %d) = %v, want %v", test.method, test.statusCode, got, test.want)
+		}
+	}
+}
+
+func TestNewDefaultGraphClientRetriesByMethod(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statusCode   int
+		wantAttempts int
+	}{
+		{
+			name:         "get unavailable",
+			method:       http.MethodGet,
+			statusCode:   http.StatusServiceUnavailable,
+			wantAttempts: 4,
+		},
+		{
+			name:         "patch gateway timeout",
+			method:       http.MethodPatch,
+			statusCode:   http.StatusGatewayTimeout,
+			wantAttempts: 4,
+		},
+		{
+			name:         "post throttled",
+			method:       http.MethodPost,
+			statusCode:   http.StatusTooManyRequests,
+			wantAttempts: 4,
+		},
+		{
+			name:         "post gateway timeout",
+			method:       http.MethodPost,
+			statusCode:   http.StatusGatewayTimeout,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(test.statusCode)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(test.method, server.URL, strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := newDefaultGraphClient(defaultMaxRetries).Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+			if got := atomic.LoadInt32(&attempts); int(got) != test.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", test.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestCustomGraphClientCreateNotRetriedWhenUnavailable(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// the stock retry handler of the Graph SDK retries any request on a 503 response
+	options := msgraphsdk.GetDefaultClientOptions()
+	custom := msgraphcore.GetDefaultClient(&options)
+	c, err := newAzureClient(config{HTTPClient: custom}.withDefaults(), nil, &authentication.AnonymousAuthenticationProvider{})
+	if err != nil {
+		t.Fatalf("newAzureClient() error = %v", err)
+	}
+	c.graphServiceClient.GetAdapter().SetBaseUrl(server.URL + "/v1.0")
+
+	app := models.NewApplication()
+	app.SetDisplayName(to.StringPtr("app"))
+	if _, err := c.graphClient.CreateApplication(context.Background(), app); err == nil {
+		t.Fatalf("expected the unavailable create request to fail")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("expected the create request to be sent once, got %d attempts", got)
+	}
+}
+
+func TestConnectionRefusedRetryTransport(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "refused once",
+			failures:     1,
+			err:          refused,
+			wantAttempts: 2,
+		},
+		{
+			name:         "refused more than max retries",
+			failures:     5,
+			err:          refused,
+			wantAttempts: 4,
+			wantErr:      true,
+		},
+		{
+			name:         "timeout",
+			failures:     1,
+			err:          context.DeadlineExceeded,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			transport := &connectionRefusedRetryTransport{
+				next: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					attempts++
+					body, err := io.ReadAll(r.Body)
+					if err != nil {
+						t.Fatalf("failed to read request body: %v", err)
+					}
+					if string(body) != `{"displayName":"app"}` {
+						t.Errorf("expected the request body to be sent in full, got %q", body)
+					}
+					if attempts <= test.failures {
+						return nil, test.err
+					}
+					return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+				}),
+				maxRetries: defaultMaxRetries,
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "https://graph.microsoft.com/v1.0/applications", strings.NewReader(`{"displayName":"app"}`))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			_, err = transport.RoundTrip(req)
+			if (err != nil) != test.wantErr {
+				t.Errorf("RoundTrip() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if attempts != test.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", test.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestCreateApplicationNotRetriedOnGatewayTimeout(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	c := newRetryingTestAzureClient(t, mux)
+
+	_, err := c.CreateApplication(context.Background(), "app")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !IsAmbiguousCreateError(err) {
+		t.Errorf("expected an ambiguous create error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "get it before creating it again") {
+		t.Errorf("expected the error to suggest getting the application, got %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestCreateApplicationNotRetriedOnTimeout(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		// the application is created, but the response doesn't make it back before the deadline
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	c := newRetryingTestAzureClient(t, mux)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := c.CreateApplication(ctx, "app")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !IsAmbiguousCreateError(err) {
+		t.Errorf("expected an ambiguous create error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestCreateApplicationRetriedWhenThrottled(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "object-id", "displayName": "app"}`))
+	})
+	c := newRetryingTestAzureClient(t, mux)
+
+	if _, err := c.CreateApplication(context.Background(), "app"); err != nil {
+		t.Fatalf("CreateApplication() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestMutatingRequestsRetriedWhenThrottled(t *testing.T) {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr("fic"))
+	fic.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	fic.SetSubject(to.StringPtr("system:serviceaccount:default:sa"))
+
+	tests := []struct {
+		name       string
+		path       string
+		statusCode int
+		body       string
+		call       func(c *AzureClient) error
+	}{
+		{
+			name:       "create service principal",
+			path:       "/v1.0/servicePrincipals",
+			statusCode: http.StatusCreated,
+			body:       `{"id": "sp-object-id", "appId": "app-id"}`,
+			call: func(c *AzureClient) error {
+				_, err := c.CreateServicePrincipal(context.Background(), "app-id", nil)
+				return err
+			},
+		},
+		{
+			name:       "add federated credential",
+			path:       "/v1.0/applications/object-id/federatedIdentityCredentials",
+			statusCode: http.StatusCreated,
+			body:       `{"id": "fic-id", "name": "fic"}`,
+			call: func(c *AzureClient) error {
+				_, err := c.AddFederatedCredential(context.Background(), "object-id", fic)
+				return err
+			},
+		},
+		{
+			name:       "delete federated credential",
+			path:       "/v1.0/applications/object-id/federatedIdentityCredentials/fic-id",
+			statusCode: http.StatusNoContent,
+			call: func(c *AzureClient) error {
+				return c.DeleteFederatedCredential(context.Background(), "object-id", "fic-id")
+			},
+		},
+		{
+			name:       "delete application",
+			path:       "/v1.0/applications/object-id",
+			statusCode: http.StatusNoContent,
+			call: func(c *AzureClient) error {
+				return c.DeleteApplication(context.Background(), "object-id")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			mux := http.NewServeMux()
+			mux.HandleFunc(test.path, func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) <= 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte(test.body))
+			})
+			c := newRetryingTestAzureClient(t, mux)
+
+			if err := test.call(c); err != nil {
+				t.Fatalf("expected the throttled request to succeed once retried, got %v", err)
+			}
+			if got := atomic.LoadInt32(&attempts); got != 3 {
+				t.Errorf("expected 3 attempts, got %d", got)
+			}
+		})
+	}
+}
+
+func TestIsAmbiguousCreateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "deadline exceeded",
+			err:  withAmbiguousCreateHint(errors.Wrap(context.DeadlineExceeded, "request failed"), "application"),
+			want: true,
+		},
+		{
+			name: "wrapped",
+			err:  errors.Wrap(withAmbiguousCreateHint(context.DeadlineExceeded, "application"), "failed to create application"),
+			want: true,
+		},
+		{
+			name: "other error",
+			err:  withAmbiguousCreateHint(errors.New("bad request"), "application"),
+			want: false,
+		},
+		{
+			name: "not marked",
+			err:  context.DeadlineExceeded,
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsAmbiguousCreateError(test.err); got != test.want {
+				t.Errorf("IsAmbiguousCreateError() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -2,22 +2,26 @@ package cloud
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-01-01-preview/authorization"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
-	"monis.app/mlog"
 )
 
-const (
-	roleAssignmentCreateRetryCount = 7
-	roleAssignmentCreateRetryDelay = 5 * time.Second
-)
+const roleAssignmentCreateRetryCount = 7
+
+// roleAssignmentCreateRetryDelay is the delay before a role assignment is created again.
+var roleAssignmentCreateRetryDelay = 5 * time.Second
 
 // CreateRoleAssignment creates a role assignment.
 func (c *AzureClient) CreateRoleAssignment(ctx context.Context, scope, roleName, principalID string) (authorization.RoleAssignment, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
 	var result authorization.RoleAssignment
 
 	roleDefinitionID, err := c.GetRoleDefinitionIDByName(ctx, "", roleName)
@@ -25,7 +29,7 @@ func (c *AzureClient) CreateRoleAssignment(ctx context.Context, scope, roleName,
 		return result, errors.Wrapf(err, "failed to get role definition id for role %s", roleName)
 	}
 
-	mlog.Debug("Creating role assignment",
+	c.logDebug("Creating role assignment",
 		"principalID", principalID,
 		"role", roleName,
 	)
@@ -36,25 +40,117 @@ func (c *AzureClient) CreateRoleAssignment(ctx context.Context, scope, roleName,
 		},
 	}
 
-	// Adding retries to handle the propagation delay of the service principal.
-	// Trying to create role assignment immediately after service principal is created
-	// results in "PrincipalNotFound" error.
+	result, err = c.createRoleAssignment(ctx, scope, parameters)
+	if IsAlreadyExists(err) {
+		c.log().Warning("Role assignment already exists", redactKeysAndValues([]interface{}{"principalID", principalID, "role", roleName})...)
+	}
+	return result, err
+}
+
+// EnsureRoleAssignment assigns the role definition to the principal on the scope and returns the ID of the role
+// assignment. The role definition is given by its ID, e.g. /providers/Microsoft.Authorization/roleDefinitions/{id}.
+// If the role is already assigned to the principal on the scope, the ID of the existing role assignment is returned.
+func (c *AzureClient) EnsureRoleAssignment(ctx context.Context, scope, roleDefinitionID, principalID string) (string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Ensuring role assignment",
+		"scope", scope,
+		"roleDefinitionID", roleDefinitionID,
+		"principalID", principalID,
+	)
+	parameters := authorization.RoleAssignmentCreateParameters{
+		RoleAssignmentProperties: &authorization.RoleAssignmentProperties{
+			RoleDefinitionID: to.StringPtr(roleDefinitionID),
+			PrincipalID:      to.StringPtr(principalID),
+		},
+	}
+
+	result, err := c.createRoleAssignment(ctx, scope, parameters)
+	if err == nil {
+		return to.String(result.ID), nil
+	}
+	if !IsAlreadyExists(err) {
+		return "", err
+	}
+
+	c.logDebug("Role assignment already exists, getting it", "scope", scope, "roleDefinitionID", roleDefinitionID, "principalID", principalID)
+	existing, err := c.getRoleAssignment(ctx, scope, roleDefinitionID, principalID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get existing role assignment")
+	}
+	return to.String(existing.ID), nil
+}
+
+// createRoleAssignment creates a role assignment with a generated name. Creating the role assignment right after
+// the service principal results in a "PrincipalNotFound" error until the service principal has propagated, so
+// the creation is retried, unless the role assignment already exists.
+func (c *AzureClient) createRoleAssignment(ctx context.Context, scope string, parameters authorization.RoleAssignmentCreateParameters) (authorization.RoleAssignment, error) {
+	var (
+		result authorization.RoleAssignment
+		err    error
+	)
+	if c.skipInDryRun("creating role assignment", "scope", scope, "roleDefinitionID", to.String(parameters.RoleDefinitionID), "principalID", to.String(parameters.PrincipalID)) {
+		result.ID = to.StringPtr(scope + "/providers/Microsoft.Authorization/roleAssignments/" + DryRunObjectID)
+		result.Name = to.StringPtr(DryRunObjectID)
+		result.RoleAssignmentPropertiesWithScope = &authorization.RoleAssignmentPropertiesWithScope{
+			Scope:            to.StringPtr(scope),
+			RoleDefinitionID: parameters.RoleDefinitionID,
+			PrincipalID:      parameters.PrincipalID,
+		}
+		return result, nil
+	}
 	for i := 0; i < roleAssignmentCreateRetryCount; i++ {
 		if result, err = c.roleAssignmentsClient.Create(ctx, scope, uuid.New().String(), parameters); err == nil {
 			return result, nil
 		}
 		if IsAlreadyExists(err) {
-			mlog.Warning("Role assignment already exists", "principalID", principalID, "role", roleName)
 			return result, err
 		}
-		time.Sleep(roleAssignmentCreateRetryDelay)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(roleAssignmentCreateRetryDelay):
+		}
 	}
 
 	return result, err
 }
 
+// getRoleAssignment gets the role assignment of the role definition to the principal on the scope.
+func (c *AzureClient) getRoleAssignment(ctx context.Context, scope, roleDefinitionID, principalID string) (authorization.RoleAssignment, error) {
+	// the role assignments of the principal inherited from the parent scopes are listed as well
+	page, err := c.roleAssignmentsClient.ListForScope(ctx, scope, fmt.Sprintf("principalId eq '%s'", escapeODataString(principalID)))
+	if err != nil {
+		return authorization.RoleAssignment{}, err
+	}
+	for page.NotDone() {
+		for _, ra := range page.Values() {
+			if ra.RoleAssignmentPropertiesWithScope == nil {
+				continue
+			}
+			// the role definition ID of the role assignment is scoped to the subscription, e.g.
+			// /subscriptions/{id}/providers/Microsoft.Authorization/roleDefinitions/{id}
+			if strings.EqualFold(to.String(ra.Scope), scope) &&
+				strings.HasSuffix(strings.ToLower(to.String(ra.RoleDefinitionID)), strings.ToLower(roleDefinitionID)) {
+				return ra, nil
+			}
+		}
+		if err := page.NextWithContext(ctx); err != nil {
+			return authorization.RoleAssignment{}, err
+		}
+	}
+	return authorization.RoleAssignment{}, errors.Errorf("role assignment of role definition %s to principal %s on scope %s not found", roleDefinitionID, principalID, scope)
+}
+
 // DeleteRoleAssignment deletes a role assignment.
 func (c *AzureClient) DeleteRoleAssignment(ctx context.Context, roleAssignmentID string) (authorization.RoleAssignment, error) {
-	mlog.Debug("Deleting role assignment", "id", roleAssignmentID)
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	if c.skipInDryRun("deleting role assignment", "id", roleAssignmentID) {
+		return authorization.RoleAssignment{ID: to.StringPtr(roleAssignmentID)}, nil
+	}
+	c.logDebug("Deleting role assignment", "id", roleAssignmentID)
 	return c.roleAssignmentsClient.DeleteByID(ctx, roleAssignmentID)
 }
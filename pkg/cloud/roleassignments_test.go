@@ -0,0 +1,135 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-01-01-preview/authorization"
+)
+
+const (
+	testRoleAssignmentScope = "/subscriptions/subscription-id/resourceGroups/rg"
+	testRoleDefinitionID    = "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7"
+)
+
+// fakeRoleAssignments serves the role assignments of a scope. The first principalNotFound creations fail
+// with a PrincipalNotFound error, like the creations for a service principal that has not propagated yet.
+type fakeRoleAssignments struct {
+	mu                sync.Mutex
+	principalNotFound int
+	existing          bool
+	creates           int
+}
+
+func (f *fakeRoleAssignments) handler(t *testing.T) http.Handler {
+	// the scope is joined to the path as is, so the path is matched without its leading slashes
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := "/" + strings.TrimLeft(r.URL.Path, "/")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && strings.HasPrefix(path, testRoleAssignmentScope+"/providers/Microsoft.Authorization/roleAssignments/"):
+			f.create(t, w, r, path)
+		case r.Method == http.MethodGet && path == testRoleAssignmentScope+"/providers/Microsoft.Authorization/roleAssignments":
+			if got := r.URL.Query().Get("$filter"); got != "principalId eq 'principal-id'" {
+				t.Errorf("expected $filter to be principalId eq 'principal-id', got %q", got)
+			}
+			fmt.Fprintf(w, `{"value": [
+				{"id": "/subscriptions/subscription-id/providers/Microsoft.Authorization/roleAssignments/inherited", "properties": {"scope": "/subscriptions/subscription-id", "roleDefinitionId": "/subscriptions/subscription-id%[1]s", "principalId": "principal-id"}},
+				{"id": "%[2]s/providers/Microsoft.Authorization/roleAssignments/existing", "properties": {"scope": "%[2]s", "roleDefinitionId": "/subscriptions/subscription-id%[1]s", "principalId": "principal-id"}}
+			]}`, testRoleDefinitionID, testRoleAssignmentScope)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (f *fakeRoleAssignments) create(t *testing.T, w http.ResponseWriter, r *http.Request, path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.creates++
+	if f.principalNotFound > 0 {
+		f.principalNotFound--
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"code": "PrincipalNotFound", "message": "Principal does not exist in the directory."}}`)
+		return
+	}
+	if f.existing {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"error": {"code": "RoleAssignmentExists", "message": "The role assignment already exists."}}`)
+		return
+	}
+	var body authorization.RoleAssignmentCreateParameters
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("failed to decode request body: %v", err)
+	}
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"id": "%s", "properties": {"scope": "%s", "roleDefinitionId": "%s", "principalId": "%s"}}`,
+		path, testRoleAssignmentScope, *body.RoleDefinitionID, *body.PrincipalID)
+}
+
+func newTestRoleAssignmentsClient(t *testing.T, f *fakeRoleAssignments) *AzureClient {
+	t.Helper()
+
+	c := newTestAzureClient(t, f.handler(t))
+	c.roleAssignmentsClient = authorization.NewRoleAssignmentsClientWithBaseURI(c.environment.ResourceManagerEndpoint, "subscription-id")
+	c.roleAssignmentsClient.Sender = c.httpClient
+	return c
+}
+
+func TestEnsureRoleAssignment(t *testing.T) {
+	delay := roleAssignmentCreateRetryDelay
+	roleAssignmentCreateRetryDelay = time.Millisecond
+	t.Cleanup(func() { roleAssignmentCreateRetryDelay = delay })
+
+	tests := []struct {
+		name        string
+		fake        *fakeRoleAssignments
+		wantCreates int
+		wantID      string
+	}{
+		{
+			name:        "created",
+			fake:        &fakeRoleAssignments{},
+			wantCreates: 1,
+		},
+		{
+			name:        "principal not propagated yet",
+			fake:        &fakeRoleAssignments{principalNotFound: 2},
+			wantCreates: 3,
+		},
+		{
+			name:        "already exists",
+			fake:        &fakeRoleAssignments{existing: true},
+			wantCreates: 1,
+			wantID:      testRoleAssignmentScope + "/providers/Microsoft.Authorization/roleAssignments/existing",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestRoleAssignmentsClient(t, test.fake)
+
+			id, err := c.EnsureRoleAssignment(context.Background(), testRoleAssignmentScope, testRoleDefinitionID, "principal-id")
+			if err != nil {
+				t.Fatalf("EnsureRoleAssignment() error = %v", err)
+			}
+			if test.wantID != "" && id != test.wantID {
+				t.Errorf("EnsureRoleAssignment() = %s, want %s", id, test.wantID)
+			}
+			if test.wantID == "" && !strings.HasPrefix(id, testRoleAssignmentScope+"/providers/Microsoft.Authorization/roleAssignments/") {
+				t.Errorf("EnsureRoleAssignment() = %s, want a role assignment of scope %s", id, testRoleAssignmentScope)
+			}
+			if test.fake.creates != test.wantCreates {
+				t.Errorf("expected %d create requests, got %d", test.wantCreates, test.fake.creates)
+			}
+		})
+	}
+}
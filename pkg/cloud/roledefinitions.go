@@ -6,12 +6,14 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2018-01-01-preview/authorization"
 	"github.com/pkg/errors"
-	"monis.app/mlog"
 )
 
 // GetRoleDefinitionIDByName returns the role definition ID for the given role name.
 func (c *AzureClient) GetRoleDefinitionIDByName(ctx context.Context, scope, roleName string) (authorization.RoleDefinition, error) {
-	mlog.Debug("Get role definition ID", "name", roleName)
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Get role definition ID", "name", roleName)
 
 	roleDefinitionList, err := c.roleDefinitionsClient.List(ctx, scope, getRoleNameFilter(roleName))
 	if err != nil {
@@ -27,5 +29,5 @@ func (c *AzureClient) GetRoleDefinitionIDByName(ctx context.Context, scope, role
 // getRoleNameFilter returns a filter string for the given role name.
 // Supported filters are either roleName eq '{value}' or type eq 'BuiltInRole|CustomRole'."
 func getRoleNameFilter(roleName string) string {
-	return fmt.Sprintf("roleName eq '%s'", roleName)
+	return fmt.Sprintf("roleName eq '%s'", escapeODataString(roleName))
 }
@@ -0,0 +1,190 @@
+package cloud
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// tagSeparator separates the key and the value of a structured tag, e.g. managed-by:azwi.
+const tagSeparator = ":"
+
+// ParseTags parses the tags of an application or service principal as key-value pairs split on the
+// first colon, with surrounding spaces trimmed. A tag without a colon is a key with an empty value.
+// If a key is repeated, the last value wins.
+func ParseTags(tags []string) map[string]string {
+	parsed := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, _ := strings.Cut(tag, tagSeparator)
+		parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return parsed
+}
+
+// FormatTags formats the key-value pairs as tags sorted by key, which ParseTags parses back.
+// A key with an empty value is formatted as the key alone.
+func FormatTags(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	formatted := make([]string, 0, len(tags))
+	for _, key := range keys {
+		if tags[key] == "" {
+			formatted = append(formatted, key)
+			continue
+		}
+		formatted = append(formatted, key+tagSeparator+tags[key])
+	}
+	return formatted
+}
+
+// tagApplicationsWorkers is the maximum number of applications tagged in parallel.
+const tagApplicationsWorkers = 8
+
+// TagApplications adds the tag to the applications matching the OData filter, e.g. startswith(displayName,'team-a'),
+// and returns the number of applications that were updated. An empty filter matches all the applications.
+// The applications that already have the tag, compared case-insensitively, are not updated. The errors of the applications that failed
+// to be tagged are aggregated and the others are still tagged.
+func (c *AzureClient) TagApplications(ctx context.Context, filter, tag string) (int, error) {
+	if tag == "" {
+		return 0, errors.New("tag must not be empty")
+	}
+
+	c.logDebug("Tagging applications", "filter", filter, "tag", tag)
+	apps, err := c.listApplications(ctx, filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list applications")
+	}
+
+	var (
+		wg      sync.WaitGroup
+		updated int32
+		errs    = make([]error, len(apps))
+	)
+	indexCh := make(chan int)
+	for i := 0; i < tagApplicationsWorkers && i < len(apps); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				if err := c.addApplicationTag(ctx, apps[i], tag); err != nil {
+					errs[i] = errors.Wrapf(err, "failed to tag application %s", to.String(apps[i].GetId()))
+					continue
+				}
+				atomic.AddInt32(&updated, 1)
+			}
+		}()
+	}
+	for i, app := range apps {
+		if containsFold(app.GetTags(), tag) {
+			continue
+		}
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	return int(updated), utilerrors.NewAggregate(errs)
+}
+
+// addApplicationTag adds the tag to the listed tags of the application.
+func (c *AzureClient) addApplicationTag(ctx context.Context, app models.Applicationable, tag string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	body := models.NewApplication()
+	body.SetTags(append(append([]string{}, app.GetTags()...), tag))
+	return c.patchApplication(ctx, to.String(app.GetId()), body)
+}
+
+// GetServicePrincipalTags gets the tags of the service principal as key-value pairs.
+func (c *AzureClient) GetServicePrincipalTags(ctx context.Context, objectID string) (map[string]string, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Getting service principal tags", "objectID", objectID)
+	tags, err := c.getServicePrincipalTags(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTags(tags), nil
+}
+
+// AddServicePrincipalTags adds the key-value pairs to the tags of the service principal, replacing the tags
+// with the same keys. The other tags are kept as they are.
+func (c *AzureClient) AddServicePrincipalTags(ctx context.Context, objectID string, tags map[string]string) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	c.logDebug("Adding service principal tags", "objectID", objectID, "tags", tags)
+	existing, err := c.getServicePrincipalTags(ctx, objectID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get service principal tags")
+	}
+
+	updated := make([]string, 0, len(existing)+len(tags))
+	for _, tag := range existing {
+		key, _, _ := strings.Cut(tag, tagSeparator)
+		if _, ok := tags[strings.TrimSpace(key)]; !ok {
+			updated = append(updated, tag)
+		}
+	}
+	updated = append(updated, FormatTags(tags)...)
+
+	if c.skipInDryRun("updating service principal tags", "objectID", objectID, "tags", updated) {
+		return nil
+	}
+
+	body := models.NewServicePrincipal()
+	body.SetTags(updated)
+	c.servicePrincipalCache.evict(objectID)
+	resp, err := c.graphClient.UpdateServicePrincipal(ctx, objectID, body)
+	if err != nil {
+		return err
+	}
+	// the service principal is not returned when it is updated successfully
+	if resp == nil {
+		return nil
+	}
+	graphErr, err := GetGraphError(resp.GetAdditionalData())
+	if err != nil {
+		return err
+	}
+	if graphErr != nil {
+		return *graphErr
+	}
+	return nil
+}
+
+// getServicePrincipalTags gets the tags of the service principal as they are stored.
+func (c *AzureClient) getServicePrincipalTags(ctx context.Context, objectID string) ([]string, error) {
+	spGetOptions := &serviceprincipals.ServicePrincipalItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "tags"},
+		},
+	}
+
+	sp, err := c.graphClient.GetServicePrincipal(ctx, objectID, spGetOptions)
+	if err != nil {
+		return nil, err
+	}
+	graphErr, err := GetGraphError(sp.GetAdditionalData())
+	if err != nil {
+		return nil, err
+	}
+	if graphErr != nil {
+		return nil, *graphErr
+	}
+	return sp.GetTags(), nil
+}
@@ -0,0 +1,172 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tags := []string{"managed-by:azwi", "cluster: prod", "azwi version: v1.0.0, commit: abc", "WindowsAzureActiveDirectoryIntegratedApp"}
+	want := map[string]string{
+		"managed-by":   "azwi",
+		"cluster":      "prod",
+		"azwi version": "v1.0.0, commit: abc",
+		"WindowsAzureActiveDirectoryIntegratedApp": "",
+	}
+	if got := ParseTags(tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTags() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatTagsRoundTrip(t *testing.T) {
+	tags := map[string]string{
+		"managed-by": "azwi",
+		"cluster":    "prod",
+		"url":        "https://example.com",
+		"key-only":   "",
+	}
+
+	formatted := FormatTags(tags)
+	want := []string{"cluster:prod", "key-only", "managed-by:azwi", "url:https://example.com"}
+	if !reflect.DeepEqual(formatted, want) {
+		t.Errorf("FormatTags() = %v, want %v", formatted, want)
+	}
+	if got := ParseTags(formatted); !reflect.DeepEqual(got, tags) {
+		t.Errorf("ParseTags(FormatTags()) = %v, want %v", got, tags)
+	}
+}
+
+func TestGetServicePrincipalTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "sp-object-id", "tags": ["managed-by:azwi", "HideApp"]}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	tags, err := c.GetServicePrincipalTags(context.Background(), "sp-object-id")
+	if err != nil {
+		t.Fatalf("GetServicePrincipalTags() error = %v", err)
+	}
+	if want := map[string]string{"managed-by": "azwi", "HideApp": ""}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("GetServicePrincipalTags() = %v, want %v", tags, want)
+	}
+}
+
+func TestAddServicePrincipalTags(t *testing.T) {
+	var patched struct {
+		Tags []string `json:"tags"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id": "sp-object-id", "tags": ["azwi version: v1.0.0, commit: abc", "cluster: dev", "HideApp"]}`)
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&patched); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	})
+	c := newTestAzureClient(t, mux)
+
+	if err := c.AddServicePrincipalTags(context.Background(), "sp-object-id", map[string]string{"cluster": "prod", "managed-by": "azwi"}); err != nil {
+		t.Fatalf("AddServicePrincipalTags() error = %v", err)
+	}
+	want := []string{"azwi version: v1.0.0, commit: abc", "HideApp", "cluster:prod", "managed-by:azwi"}
+	if !reflect.DeepEqual(patched.Tags, want) {
+		t.Errorf("patched tags = %v, want %v", patched.Tags, want)
+	}
+}
+
+func TestTagApplications(t *testing.T) {
+	const filter = "startswith(displayName,'team-a')"
+
+	var (
+		mu      sync.Mutex
+		patched = map[string][]string{}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("$filter"); got != filter {
+			t.Errorf("expected filter %q, got %q", filter, got)
+		}
+		if got := r.Header.Get("ConsistencyLevel"); got != "eventual" {
+			t.Errorf("expected ConsistencyLevel header eventual, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [
+			{"id": "app-1", "displayName": "team-a-1", "tags": ["cluster:prod"]},
+			{"id": "app-2", "displayName": "team-a-2"},
+			{"id": "app-3", "displayName": "team-a-3", "tags": ["managed-by:azwi"]}
+		]}`)
+	})
+	for _, id := range []string{"app-1", "app-2", "app-3"} {
+		id := id
+		mux.HandleFunc("/v1.0/applications/"+id, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPatch {
+				t.Errorf("unexpected method %s", r.Method)
+			}
+			var body struct {
+				Tags []string `json:"tags"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			mu.Lock()
+			patched[id] = body.Tags
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	c := newTestAzureClient(t, mux)
+
+	updated, err := c.TagApplications(context.Background(), filter, "managed-by:azwi")
+	if err != nil {
+		t.Fatalf("TagApplications() error = %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("TagApplications() = %d, want 2", updated)
+	}
+	// the application that already has the tag is not updated
+	want := map[string][]string{
+		"app-1": {"cluster:prod", "managed-by:azwi"},
+		"app-2": {"managed-by:azwi"},
+	}
+	if !reflect.DeepEqual(patched, want) {
+		t.Errorf("patched tags = %v, want %v", patched, want)
+	}
+}
+
+func TestTagApplicationsAggregatesErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"value": [{"id": "app-1"}, {"id": "app-2"}]}`)
+	})
+	mux.HandleFunc("/v1.0/applications/app-1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1.0/applications/app-2", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	c := newTestAzureClient(t, mux)
+
+	updated, err := c.TagApplications(context.Background(), "", "managed-by:azwi")
+	if err == nil || !strings.Contains(err.Error(), "app-2") {
+		t.Errorf("expected the error of app-2, got %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("TagApplications() = %d, want 1", updated)
+	}
+}
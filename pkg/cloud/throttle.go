@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottleStats are the statistics of the throttled requests of an AzureClient.
+type ThrottleStats struct {
+	// Throttled is the number of 429 Too Many Requests responses, including those of retried requests.
+	Throttled int
+	// Backoff is the total backoff requested by the Retry-After header of the throttled responses.
+	Backoff time.Duration
+}
+
+// throttleRecorder records the throttled responses of the requests of an AzureClient.
+type throttleRecorder struct {
+	mu    sync.Mutex
+	stats ThrottleStats
+}
+
+func (r *throttleRecorder) record(backoff time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Throttled++
+	r.stats.Backoff += backoff
+}
+
+func (r *throttleRecorder) get() ThrottleStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+type throttleRecorderKey struct{}
+
+// withThrottleRecorder returns a context that carries the recorder of the throttled responses of the requests made with it.
+func withThrottleRecorder(ctx context.Context, recorder *throttleRecorder) context.Context {
+	if recorder == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, throttleRecorderKey{}, recorder)
+}
+
+// ThrottleStats returns the statistics of the throttled requests sent by the AzureClient so far.
+// Throttled responses are only counted if the HTTP client of the AzureClient records them, which
+// the default client does; a custom client must send its requests through NewThrottleRecordingTransport.
+func (c *AzureClient) ThrottleStats() ThrottleStats {
+	if c.throttles == nil {
+		return ThrottleStats{}
+	}
+	return c.throttles.get()
+}
+
+// throttleRecordingTransport is an http.RoundTripper that records the throttled responses
+// in the throttle recorder of the AzureClient that sent the request.
+type throttleRecordingTransport struct {
+	next http.RoundTripper
+}
+
+// NewThrottleRecordingTransport returns an http.RoundTripper that records the throttled responses of the
+// requests sent by an AzureClient, which are reported by its ThrottleStats. To count every throttled attempt,
+// it should be below the retry middleware of the HTTP client, e.g. as the parent transport of the Graph middleware.
+func NewThrottleRecordingTransport(next http.RoundTripper) http.RoundTripper {
+	return &throttleRecordingTransport{next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *throttleRecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	if recorder, ok := req.Context().Value(throttleRecorderKey{}).(*throttleRecorder); ok {
+		recorder.record(retryAfter(resp))
+	}
+	return resp, err
+}
+
+// retryAfter returns the backoff of the Retry-After header of the response in seconds, or zero if there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
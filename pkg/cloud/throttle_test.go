@@ -0,0 +1,76 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThrottleStats(t *testing.T) {
+	throttled := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if throttled < 2 {
+			throttled++
+			w.Header().Set("Retry-After", "0.5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error": {"code": "TooManyRequests", "message": "Too many requests."}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": "sp-object-id", "servicePrincipalType": "Application"}`)
+	})
+	c := newTestAzureClient(t, mux)
+
+	for i := 0; i < 3; i++ {
+		_, _ = c.GetServicePrincipalType(context.Background(), "sp-object-id")
+	}
+	want := ThrottleStats{Throttled: 2, Backoff: time.Second}
+	if got := c.ThrottleStats(); got != want {
+		t.Errorf("ThrottleStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestThrottleRecordingTransportOtherRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/servicePrincipals/sp-object-id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c := newTestAzureClient(t, mux)
+
+	// requests that are not sent by the client are not recorded
+	req, err := http.NewRequest(http.MethodGet, c.graphServiceClient.GetAdapter().GetBaseUrl()+"/servicePrincipals/sp-object-id", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := NewThrottleRecordingTransport(http.DefaultTransport).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	resp.Body.Close()
+	if got := c.ThrottleStats(); got != (ThrottleStats{}) {
+		t.Errorf("ThrottleStats() = %+v, want no throttled requests", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "1.5", want: 1500 * time.Millisecond},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+		{value: "-1", want: 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.value)
+		if got := retryAfter(resp); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
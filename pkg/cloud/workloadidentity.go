@@ -0,0 +1,121 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	azcloud "github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	kiotaauth "github.com/microsoft/kiota-authentication-azure-go"
+	"github.com/pkg/errors"
+
+	"github.com/Azure/azure-workload-identity/pkg/consts"
+)
+
+// azureSubscriptionIDEnvVar is the optional environment variable with the subscription ID
+// used for role assignments. It is not injected by the webhook.
+const azureSubscriptionIDEnvVar = "AZURE_SUBSCRIPTION_ID"
+
+// workloadIdentityConfig is the configuration injected into a pod by the webhook.
+type workloadIdentityConfig struct {
+	env            azure.Environment
+	subscriptionID string
+	clientID       string
+	tenantID       string
+	tokenFile      string
+}
+
+// NewAzureClientFromWorkloadIdentity returns an AzureClient that authenticates with the federated
+// service account token of the pod, using the environment variables injected by the webhook.
+// A token is requested once so that a misconfiguration is reported when the client is created.
+func NewAzureClientFromWorkloadIdentity(ctx context.Context) (*AzureClient, error) {
+	config, err := workloadIdentityConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(config.env.ActiveDirectoryEndpoint, config.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	// the token file is read for every token request since the service account token is rotated
+	armSpt, err := adal.NewServicePrincipalTokenFromFederatedTokenCallback(*oauthConfig, config.clientID, config.readToken, config.env.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create service principal token")
+	}
+
+	client := http.DefaultClient
+	cred, err := azidentity.NewClientAssertionCredential(config.tenantID, config.clientID, func(context.Context) (string, error) {
+		return config.readToken()
+	}, &azidentity.ClientAssertionCredentialOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:     azcloud.Configuration{ActiveDirectoryAuthorityHost: config.env.ActiveDirectoryEndpoint},
+			Transport: client,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create credential")
+	}
+	if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{getGraphScope(config.env)}}); err != nil {
+		return nil, errors.Wrap(err, "failed to get token with workload identity")
+	}
+	auth, err := kiotaauth.NewAzureIdentityAuthenticationProviderWithScopes(cred, []string{getGraphScope(config.env)})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create authentication provider")
+	}
+
+	return getClient(config.env, config.subscriptionID, config.tenantID, autorest.NewBearerAuthorizer(armSpt), auth, client)
+}
+
+// workloadIdentityConfigFromEnv reads the workload identity configuration from the environment.
+// The Azure environment is the cloud whose Active Directory endpoint is the authority host.
+func workloadIdentityConfigFromEnv() (workloadIdentityConfig, error) {
+	config := workloadIdentityConfig{
+		env:            azure.PublicCloud,
+		subscriptionID: os.Getenv(azureSubscriptionIDEnvVar),
+		clientID:       os.Getenv(consts.AzureClientIDEnvVar),
+		tenantID:       os.Getenv(consts.AzureTenantIDEnvVar),
+		tokenFile:      os.Getenv(consts.AzureFederatedTokenFileEnvVar),
+	}
+
+	for _, name := range []string{consts.AzureClientIDEnvVar, consts.AzureTenantIDEnvVar, consts.AzureFederatedTokenFileEnvVar} {
+		if os.Getenv(name) == "" {
+			return config, errors.Errorf("environment variable %s is not set", name)
+		}
+	}
+
+	if authorityHost := os.Getenv(consts.AzureAuthorityHostEnvVar); authorityHost != "" {
+		env, err := getEnvironmentByAuthorityHost(authorityHost)
+		if err != nil {
+			return config, err
+		}
+		config.env = env
+	}
+	return config, nil
+}
+
+// readToken reads the federated service account token from the token file.
+func (c workloadIdentityConfig) readToken() (string, error) {
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read federated token file %s", c.tokenFile)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// getEnvironmentByAuthorityHost returns the Azure environment with the given Active Directory endpoint.
+func getEnvironmentByAuthorityHost(authorityHost string) (azure.Environment, error) {
+	for env := range msGraphEndpoint {
+		if strings.EqualFold(strings.TrimRight(env.ActiveDirectoryEndpoint, "/"), strings.TrimRight(authorityHost, "/")) {
+			return env, nil
+		}
+	}
+	return azure.Environment{}, errors.Errorf("unknown authority host %s", authorityHost)
+}
@@ -0,0 +1,94 @@
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"github.com/Azure/azure-workload-identity/pkg/consts"
+)
+
+func TestWorkloadIdentityConfigFromEnv(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "azure-identity-token")
+	if err := os.WriteFile(tokenFile, []byte("service-account-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     workloadIdentityConfig
+		errorMsg string
+	}{
+		{
+			name: "public cloud by default",
+			env: map[string]string{
+				consts.AzureClientIDEnvVar:           "client-id",
+				consts.AzureTenantIDEnvVar:           "tenant-id",
+				consts.AzureFederatedTokenFileEnvVar: tokenFile,
+			},
+			want: workloadIdentityConfig{env: azure.PublicCloud, clientID: "client-id", tenantID: "tenant-id", tokenFile: tokenFile},
+		},
+		{
+			name: "environment from authority host",
+			env: map[string]string{
+				consts.AzureClientIDEnvVar:           "client-id",
+				consts.AzureTenantIDEnvVar:           "tenant-id",
+				consts.AzureFederatedTokenFileEnvVar: tokenFile,
+				consts.AzureAuthorityHostEnvVar:      "https://login.chinacloudapi.cn",
+				azureSubscriptionIDEnvVar:            "subscription-id",
+			},
+			want: workloadIdentityConfig{env: azure.ChinaCloud, subscriptionID: "subscription-id", clientID: "client-id", tenantID: "tenant-id", tokenFile: tokenFile},
+		},
+		{
+			name: "missing client ID",
+			env: map[string]string{
+				consts.AzureTenantIDEnvVar:           "tenant-id",
+				consts.AzureFederatedTokenFileEnvVar: tokenFile,
+			},
+			errorMsg: "environment variable AZURE_CLIENT_ID is not set",
+		},
+		{
+			name: "unknown authority host",
+			env: map[string]string{
+				consts.AzureClientIDEnvVar:           "client-id",
+				consts.AzureTenantIDEnvVar:           "tenant-id",
+				consts.AzureFederatedTokenFileEnvVar: tokenFile,
+				consts.AzureAuthorityHostEnvVar:      "https://login.example.com/",
+			},
+			errorMsg: "unknown authority host https://login.example.com/",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{consts.AzureClientIDEnvVar, consts.AzureTenantIDEnvVar, consts.AzureFederatedTokenFileEnvVar, consts.AzureAuthorityHostEnvVar, azureSubscriptionIDEnvVar} {
+				t.Setenv(name, test.env[name])
+			}
+
+			config, err := workloadIdentityConfigFromEnv()
+			if test.errorMsg != "" {
+				if err == nil || err.Error() != test.errorMsg {
+					t.Fatalf("expected error %q, got %v", test.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("workloadIdentityConfigFromEnv() error = %v", err)
+			}
+			if config != test.want {
+				t.Errorf("expected config %+v, got %+v", test.want, config)
+			}
+
+			token, err := config.readToken()
+			if err != nil {
+				t.Fatalf("readToken() error = %v", err)
+			}
+			if token != "service-account-token" {
+				t.Errorf("expected token to be service-account-token, got %s", token)
+			}
+		})
+	}
+}
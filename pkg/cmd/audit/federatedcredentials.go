@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+type federatedCredentialsCmd struct {
+	namespace    string
+	issuer       string
+	output       string
+	out          io.Writer
+	authProvider auth.Provider
+	kubeClient   client.Client
+}
+
+func newFederatedCredentialsCmd(authProvider auth.Provider) *cobra.Command {
+	fcCmd := &federatedCredentialsCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "federated-credentials",
+		Short: "Audit the federated identity credentials of the service accounts using workload identity",
+		Long:  "This command lists the service accounts labeled with azure.workload.identity/use=true whose AAD application has no federated identity credential for the service account, in which case the token exchange of the service account fails",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return fcCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fcCmd.out = cmd.OutOrStdout()
+			return fcCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&fcCmd.namespace, "namespace", "", "Namespace to audit. If not provided, all namespaces are audited")
+	f.StringVar(&fcCmd.issuer, "issuer", "", "Issuer of the service account tokens. If not provided, the federated identity credentials of any issuer are considered")
+	f.StringVarP(&fcCmd.output, "output", "o", outputTable, "Output format. One of: table, json")
+
+	return cmd
+}
+
+func (fc *federatedCredentialsCmd) prerun() error {
+	if fc.output != outputTable && fc.output != outputJSON {
+		return errors.Errorf("--output must be one of: %s, %s", outputTable, outputJSON)
+	}
+
+	var err error
+	if fc.kubeClient, err = kuberneteshelper.GetKubeClient(); err != nil {
+		return errors.Wrap(err, "failed to get kubernetes client")
+	}
+
+	return nil
+}
+
+func (fc *federatedCredentialsCmd) run(ctx context.Context) error {
+	mlog.Debug("auditing federated credentials of service accounts", "namespace", fc.namespace)
+
+	serviceAccounts, err := kuberneteshelper.ListServiceAccounts(ctx, fc.kubeClient, fc.namespace, map[string]string{webhook.UseWorkloadIdentityLabel: "true"})
+	if err != nil {
+		return errors.Wrap(err, "failed to list service accounts")
+	}
+	clientIDs, errs := kuberneteshelper.ResolveClientIDs(serviceAccounts)
+	for _, err := range errs {
+		mlog.Warning("skipping service account", "error", err.Error())
+	}
+
+	references := make([]cloud.SARef, 0, len(clientIDs))
+	for key, clientID := range clientIDs {
+		namespace, name, _ := strings.Cut(key, "/")
+		references = append(references, cloud.SARef{Namespace: namespace, Name: name, ClientID: clientID, Issuer: fc.issuer})
+	}
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].Namespace != references[j].Namespace {
+			return references[i].Namespace < references[j].Namespace
+		}
+		return references[i].Name < references[j].Name
+	})
+
+	missing, err := fc.authProvider.GetAzureClient().DetectMissingFederatedCredentials(ctx, references)
+	if err != nil {
+		return errors.Wrap(err, "failed to detect missing federated credentials")
+	}
+
+	return fc.print(missing)
+}
+
+func (fc *federatedCredentialsCmd) print(missing []cloud.SARef) error {
+	if fc.output == outputJSON {
+		b, err := json.MarshalIndent(missing, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal audit results")
+		}
+		_, err = fmt.Fprintln(fc.out, string(b))
+		return err
+	}
+
+	w := tabwriter.NewWriter(fc.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSERVICE ACCOUNT\tCLIENT ID\tSTATUS")
+	for _, ref := range missing {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ref.Namespace, ref.Name, ref.ClientID, statusMissingFederatedCredential)
+	}
+	return w.Flush()
+}
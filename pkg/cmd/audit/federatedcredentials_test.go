@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	testClientID1 = "00000000-0000-0000-0000-000000000001"
+	testClientID2 = "00000000-0000-0000-0000-000000000002"
+)
+
+func TestFederatedCredentialsCmdPreRun(t *testing.T) {
+	fc := &federatedCredentialsCmd{output: "yaml"}
+	if err := fc.prerun(); err == nil || err.Error() != "--output must be one of: table, json" {
+		t.Errorf("prerun() error = %v, want --output must be one of: table, json", err)
+	}
+}
+
+func TestFederatedCredentialsCmdRun(t *testing.T) {
+	useLabel := map[string]string{webhook.UseWorkloadIdentityLabel: "true"}
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		newServiceAccount("ns1", "sa-trusted", useLabel, map[string]string{webhook.ClientIDAnnotation: testClientID1}),
+		newServiceAccount("ns1", "sa-untrusted", useLabel, map[string]string{webhook.ClientIDAnnotation: testClientID2}),
+		// service accounts without a valid client ID are skipped
+		newServiceAccount("ns2", "sa-missing-client-id", useLabel, nil),
+		newServiceAccount("ns2", "sa-malformed-client-id", useLabel, map[string]string{webhook.ClientIDAnnotation: "client-id"}),
+		newServiceAccount("ns2", "sa-not-using-workload-identity", nil, map[string]string{webhook.ClientIDAnnotation: testClientID2}),
+	).Build()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	trusted := cloud.SARef{Namespace: "ns1", Name: "sa-trusted", ClientID: testClientID1, Issuer: "https://issuer.example.com/"}
+	untrusted := cloud.SARef{Namespace: "ns1", Name: "sa-untrusted", ClientID: testClientID2, Issuer: "https://issuer.example.com/"}
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().DetectMissingFederatedCredentials(gomock.Any(), []cloud.SARef{trusted, untrusted}).Return([]cloud.SARef{untrusted}, nil)
+
+	out := &bytes.Buffer{}
+	fc := &federatedCredentialsCmd{
+		issuer:       "https://issuer.example.com/",
+		output:       outputJSON,
+		out:          out,
+		authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient:   kubeClient,
+	}
+	if err := fc.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var got []cloud.SARef
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal output: %v", err)
+	}
+	if want := []cloud.SARef{untrusted}; !reflect.DeepEqual(got, want) {
+		t.Errorf("run() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFederatedCredentialsCmdRunTableOutput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	missing := cloud.SARef{Namespace: "ns1", Name: "sa-untrusted", ClientID: testClientID1}
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().DetectMissingFederatedCredentials(gomock.Any(), []cloud.SARef{missing}).Return([]cloud.SARef{missing}, nil)
+
+	out := &bytes.Buffer{}
+	fc := &federatedCredentialsCmd{
+		output:       outputTable,
+		out:          out,
+		authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient: fake.NewClientBuilder().WithObjects(
+			newServiceAccount("ns1", "sa-untrusted", map[string]string{webhook.UseWorkloadIdentityLabel: "true"}, map[string]string{
+				webhook.ClientIDAnnotation: testClientID1,
+			}),
+		).Build(),
+	}
+	if err := fc.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if got := strings.Fields(lines[1]); !reflect.DeepEqual(got, []string{"ns1", "sa-untrusted", testClientID1, statusMissingFederatedCredential}) {
+		t.Errorf("unexpected table row: %v", got)
+	}
+}
+
+func TestFederatedCredentialsCmdRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().DetectMissingFederatedCredentials(gomock.Any(), gomock.Any()).Return(nil, errors.New("random error"))
+
+	fc := &federatedCredentialsCmd{
+		output:       outputTable,
+		out:          &bytes.Buffer{},
+		authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient:   fake.NewClientBuilder().Build(),
+	}
+	if err := fc.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+}
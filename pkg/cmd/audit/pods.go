@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+
+	statusOK                  = "OK"
+	statusMissingClientID     = "MissingClientID"
+	statusApplicationNotFound = "ApplicationNotFound"
+
+	statusMissingFederatedCredential = "MissingFederatedCredential"
+)
+
+// podResult is the audit result of a pod using workload identity.
+type podResult struct {
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	ServiceAccount string `json:"serviceAccount"`
+	ClientID       string `json:"clientId"`
+	TenantID       string `json:"tenantId"`
+	Status         string `json:"status"`
+}
+
+type podsCmd struct {
+	namespace    string
+	output       string
+	out          io.Writer
+	authProvider auth.Provider
+	kubeClient   client.Client
+}
+
+func newPodsCmd(authProvider auth.Provider) *cobra.Command {
+	podsCmd := &podsCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "pods",
+		Short: "Audit the pods using workload identity",
+		Long:  "This command lists the pods whose service accounts are labeled with azure.workload.identity/use=true, reports the client ID and tenant ID they resolve to, and flags the pods whose AAD application or managed identity no longer exists",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return podsCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			podsCmd.out = cmd.OutOrStdout()
+			return podsCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&podsCmd.namespace, "namespace", "", "Namespace to audit. If not provided, all namespaces are audited")
+	f.StringVarP(&podsCmd.output, "output", "o", outputTable, "Output format. One of: table, json")
+
+	return cmd
+}
+
+func (pc *podsCmd) prerun() error {
+	if pc.output != outputTable && pc.output != outputJSON {
+		return errors.Errorf("--output must be one of: %s, %s", outputTable, outputJSON)
+	}
+
+	var err error
+	if pc.kubeClient, err = kuberneteshelper.GetKubeClient(); err != nil {
+		return errors.Wrap(err, "failed to get kubernetes client")
+	}
+
+	return nil
+}
+
+func (pc *podsCmd) run(ctx context.Context) error {
+	mlog.Debug("auditing pods using workload identity", "namespace", pc.namespace)
+
+	serviceAccounts, err := kuberneteshelper.ListServiceAccounts(ctx, pc.kubeClient, pc.namespace, map[string]string{webhook.UseWorkloadIdentityLabel: "true"})
+	if err != nil {
+		return errors.Wrap(err, "failed to list service accounts")
+	}
+	// key is <namespace>/<name> of the service account
+	annotations := make(map[string]map[string]string)
+	for _, sa := range serviceAccounts {
+		annotations[sa.Namespace+"/"+sa.Name] = sa.Annotations
+	}
+
+	pods, err := kuberneteshelper.ListPods(ctx, pc.kubeClient, pc.namespace, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to list pods")
+	}
+
+	// cache the application lookups as multiple pods usually share the same client ID
+	status := make(map[string]string)
+	results := make([]podResult, 0)
+	for _, pod := range pods {
+		saName := pod.Spec.ServiceAccountName
+		if saName == "" {
+			saName = "default"
+		}
+		saAnnotations, ok := annotations[pod.Namespace+"/"+saName]
+		if !ok {
+			continue
+		}
+
+		result := podResult{
+			Namespace:      pod.Namespace,
+			Name:           pod.Name,
+			ServiceAccount: saName,
+			ClientID:       saAnnotations[webhook.ClientIDAnnotation],
+			TenantID:       saAnnotations[webhook.TenantIDAnnotation],
+		}
+		if result.ClientID == "" {
+			result.Status = statusMissingClientID
+			results = append(results, result)
+			continue
+		}
+		if _, ok := status[result.ClientID]; !ok {
+			if status[result.ClientID], err = pc.applicationStatus(ctx, result.ClientID); err != nil {
+				return err
+			}
+		}
+		result.Status = status[result.ClientID]
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return pc.print(results)
+}
+
+// applicationStatus returns the audit status of the identity with the given client ID.
+// The client ID can belong to an AAD application or to a user-assigned managed identity,
+// which has a service principal but no application object.
+func (pc *podsCmd) applicationStatus(ctx context.Context, clientID string) (string, error) {
+	azureClient := pc.authProvider.GetAzureClient()
+	_, err := azureClient.GetApplicationByAppID(ctx, clientID)
+	if err == nil {
+		return statusOK, nil
+	}
+	if !cloud.IsNotFound(err) {
+		return "", errors.Wrap(err, "failed to get AAD application")
+	}
+	if _, err = azureClient.GetServicePrincipalByAppID(ctx, clientID); err != nil {
+		if cloud.IsNotFound(err) {
+			mlog.Warning("AAD application or service principal not found", "clientID", clientID)
+			return statusApplicationNotFound, nil
+		}
+		return "", errors.Wrap(err, "failed to get service principal")
+	}
+	return statusOK, nil
+}
+
+func (pc *podsCmd) print(results []podResult) error {
+	if pc.output == outputJSON {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal audit results")
+		}
+		_, err = fmt.Fprintln(pc.out, string(b))
+		return err
+	}
+
+	w := tabwriter.NewWriter(pc.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tPOD\tSERVICE ACCOUNT\tCLIENT ID\tTENANT ID\tSTATUS")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Namespace, r.Name, r.ServiceAccount, valueOrNone(r.ClientID), valueOrNone(r.TenantID), r.Status)
+	}
+	return w.Flush()
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+type mockAuthProvider struct {
+	azureClient   *mock_cloud.MockInterface
+	azureTenantID string
+}
+
+func (m *mockAuthProvider) AddFlags(_ *pflag.FlagSet)       {}
+func (m *mockAuthProvider) GetAzureClient() cloud.Interface { return m.azureClient }
+func (m *mockAuthProvider) GetAzureTenantID() string        { return m.azureTenantID }
+func (m *mockAuthProvider) Validate(_ *pflag.FlagSet) error { return nil }
+
+func newServiceAccount(namespace, name string, labels, annotations map[string]string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+}
+
+func newPod(namespace, name, serviceAccountName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccountName,
+		},
+	}
+}
+
+func TestPodsCmdPreRun(t *testing.T) {
+	pc := &podsCmd{output: "yaml"}
+	if err := pc.prerun(); err == nil || err.Error() != "--output must be one of: table, json" {
+		t.Errorf("prerun() error = %v, want --output must be one of: table, json", err)
+	}
+}
+
+func TestPodsCmdRun(t *testing.T) {
+	useLabel := map[string]string{webhook.UseWorkloadIdentityLabel: "true"}
+	objects := []client.Object{
+		newServiceAccount("ns1", "sa-valid", useLabel, map[string]string{
+			webhook.ClientIDAnnotation: "valid-client-id",
+			webhook.TenantIDAnnotation: "tenant-id",
+		}),
+		newServiceAccount("ns1", "sa-deleted-app", useLabel, map[string]string{
+			webhook.ClientIDAnnotation: "deleted-client-id",
+		}),
+		newServiceAccount("ns1", "sa-managed-identity", useLabel, map[string]string{
+			webhook.ClientIDAnnotation: "managed-identity-client-id",
+		}),
+		newServiceAccount("ns2", "default", useLabel, nil),
+		newServiceAccount("ns2", "sa-not-using-workload-identity", nil, map[string]string{
+			webhook.ClientIDAnnotation: "valid-client-id",
+		}),
+		newPod("ns1", "pod-valid-1", "sa-valid"),
+		newPod("ns1", "pod-valid-2", "sa-valid"),
+		newPod("ns1", "pod-deleted-app", "sa-deleted-app"),
+		newPod("ns1", "pod-managed-identity", "sa-managed-identity"),
+		newPod("ns2", "pod-missing-client-id", ""),
+		newPod("ns2", "pod-not-using-workload-identity", "sa-not-using-workload-identity"),
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		expect    func(m *mock_cloud.MockInterfaceMockRecorder)
+		want      []podResult
+	}{
+		{
+			name: "all namespaces",
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				// application lookups are cached per client ID
+				m.GetApplicationByAppID(gomock.Any(), "valid-client-id").Return(models.NewApplication(), nil).Times(1)
+				m.GetApplicationByAppID(gomock.Any(), "deleted-client-id").Return(nil, errors.New("application with app ID 'deleted-client-id' not found")).Times(1)
+				m.GetServicePrincipalByAppID(gomock.Any(), "deleted-client-id").Return(nil, errors.New("service principal with app ID 'deleted-client-id' not found")).Times(1)
+				// managed identities have a service principal but no application
+				m.GetApplicationByAppID(gomock.Any(), "managed-identity-client-id").Return(nil, errors.New("application with app ID 'managed-identity-client-id' not found")).Times(1)
+				m.GetServicePrincipalByAppID(gomock.Any(), "managed-identity-client-id").Return(models.NewServicePrincipal(), nil).Times(1)
+			},
+			want: []podResult{
+				{Namespace: "ns1", Name: "pod-deleted-app", ServiceAccount: "sa-deleted-app", ClientID: "deleted-client-id", Status: statusApplicationNotFound},
+				{Namespace: "ns1", Name: "pod-managed-identity", ServiceAccount: "sa-managed-identity", ClientID: "managed-identity-client-id", Status: statusOK},
+				{Namespace: "ns1", Name: "pod-valid-1", ServiceAccount: "sa-valid", ClientID: "valid-client-id", TenantID: "tenant-id", Status: statusOK},
+				{Namespace: "ns1", Name: "pod-valid-2", ServiceAccount: "sa-valid", ClientID: "valid-client-id", TenantID: "tenant-id", Status: statusOK},
+				{Namespace: "ns2", Name: "pod-missing-client-id", ServiceAccount: "default", Status: statusMissingClientID},
+			},
+		},
+		{
+			name:      "namespace scoped",
+			namespace: "ns2",
+			expect:    func(m *mock_cloud.MockInterfaceMockRecorder) {},
+			want: []podResult{
+				{Namespace: "ns2", Name: "pod-missing-client-id", ServiceAccount: "default", Status: statusMissingClientID},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			test.expect(mockAzureClient.EXPECT())
+
+			out := &bytes.Buffer{}
+			pc := &podsCmd{
+				namespace:    test.namespace,
+				output:       outputJSON,
+				out:          out,
+				authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+				kubeClient:   fake.NewClientBuilder().WithObjects(objects...).Build(),
+			}
+			if err := pc.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+
+			var got []podResult
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("failed to unmarshal output: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("run() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPodsCmdRunTableOutput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplicationByAppID(gomock.Any(), "valid-client-id").Return(models.NewApplication(), nil)
+
+	out := &bytes.Buffer{}
+	pc := &podsCmd{
+		output:       outputTable,
+		out:          out,
+		authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient: fake.NewClientBuilder().WithObjects(
+			newServiceAccount("ns1", "sa-valid", map[string]string{webhook.UseWorkloadIdentityLabel: "true"}, map[string]string{
+				webhook.ClientIDAnnotation: "valid-client-id",
+			}),
+			newPod("ns1", "pod-valid", "sa-valid"),
+		).Build(),
+	}
+	if err := pc.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if got := strings.Fields(lines[1]); !reflect.DeepEqual(got, []string{"ns1", "pod-valid", "sa-valid", "valid-client-id", "<none>", statusOK}) {
+		t.Errorf("unexpected table row: %v", got)
+	}
+}
+
+func TestPodsCmdRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplicationByAppID(gomock.Any(), "valid-client-id").Return(nil, errors.New("random error"))
+
+	pc := &podsCmd{
+		output:       outputTable,
+		out:          &bytes.Buffer{},
+		authProvider: &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient: fake.NewClientBuilder().WithObjects(
+			newServiceAccount("ns1", "sa-valid", map[string]string{webhook.UseWorkloadIdentityLabel: "true"}, map[string]string{
+				webhook.ClientIDAnnotation: "valid-client-id",
+			}),
+			newPod("ns1", "pod-valid", "sa-valid"),
+		).Build(),
+	}
+	if err := pc.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+}
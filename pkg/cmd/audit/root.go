@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+)
+
+// NewAuditCmd returns a new audit command
+func NewAuditCmd() *cobra.Command {
+	authProvider := auth.NewProvider()
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit the workload identity configuration",
+		Long:  "Audit the workload identity configuration",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return authProvider.Validate(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	// auth flags should be available for all subcommands
+	authProvider.AddFlags(auditCmd.PersistentFlags())
+
+	auditCmd.AddCommand(newPodsCmd(authProvider))
+	auditCmd.AddCommand(newFederatedCredentialsCmd(authProvider))
+
+	return auditCmd
+}
@@ -0,0 +1,271 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/verify"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	defaultRequestTimeout = 30 * time.Second
+
+	// servicePrincipalTypeManagedIdentity is the type of the service principal of a managed identity
+	servicePrincipalTypeManagedIdentity = "ManagedIdentity"
+)
+
+type doctorCmd struct {
+	namespace               string
+	serviceAccountName      string
+	serviceAccountIssuerURL string
+	federatedTokenFile      string
+	out                     io.Writer
+	authProvider            auth.Provider
+	kubeClient              client.Client
+	verifyIssuer            func(ctx context.Context, issuerURL string) error
+	exchangeToken           func(ctx context.Context, tenantID, clientID, token string) error
+
+	// failed is the number of failed checks
+	failed int
+}
+
+// NewDoctorCmd returns a new command to diagnose the workload identity configuration of a service account
+func NewDoctorCmd() *cobra.Command {
+	authProvider := auth.NewProvider()
+	httpClient := &http.Client{Timeout: defaultRequestTimeout}
+	doctorCmd := &doctorCmd{
+		authProvider: authProvider,
+		verifyIssuer: func(ctx context.Context, issuerURL string) error {
+			return verify.Issuer(ctx, httpClient, issuerURL)
+		},
+		exchangeToken: func(ctx context.Context, tenantID, clientID, token string) error {
+			authorityHost := os.Getenv(webhook.AzureAuthorityHostEnvVar)
+			if authorityHost == "" {
+				authorityHost = azure.PublicCloud.ActiveDirectoryEndpoint
+			}
+			return verify.TokenExchange(ctx, httpClient, authorityHost, tenantID, clientID, token, "")
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the workload identity configuration of a service account",
+		Long:  "This command checks the service account annotations, the AAD application or user-assigned managed identity, the federated identity credential and the OIDC issuer, and optionally that a service account token can be exchanged for an Azure AD token, and prints a checklist with a remediation hint for each failing check",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return authProvider.Validate(cmd.Flags())
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return doctorCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doctorCmd.out = cmd.OutOrStdout()
+			return doctorCmd.run(cmd.Context())
+		},
+	}
+
+	authProvider.AddFlags(cmd.PersistentFlags())
+
+	f := cmd.Flags()
+	f.StringVar(&doctorCmd.namespace, "namespace", "default", options.ServiceAccountNamespace.Description)
+	f.StringVar(&doctorCmd.serviceAccountName, "service-account", "", options.ServiceAccountName.Description)
+	f.StringVar(&doctorCmd.serviceAccountIssuerURL, options.ServiceAccountIssuerURL.Flag, "", options.ServiceAccountIssuerURL.Description)
+	f.StringVar(&doctorCmd.federatedTokenFile, "federated-token-file", "", "Path of a service account token of the service account to exchange for an Azure AD token, e.g. created with `kubectl create token`")
+
+	return cmd
+}
+
+func (dc *doctorCmd) prerun() error {
+	if dc.serviceAccountName == "" {
+		return options.FlagIsRequiredError("service-account")
+	}
+	if dc.serviceAccountIssuerURL == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountIssuerURL.Flag)
+	}
+
+	var err error
+	if dc.kubeClient, err = kuberneteshelper.GetKubeClient(); err != nil {
+		return errors.Wrap(err, "failed to get Kubernetes client")
+	}
+
+	return nil
+}
+
+func (dc *doctorCmd) run(ctx context.Context) error {
+	mlog.Debug("diagnosing service account", "namespace", dc.namespace, "name", dc.serviceAccountName)
+	dc.failed = 0
+
+	var sa *corev1.ServiceAccount
+	saOK := dc.check(fmt.Sprintf("service account %s/%s exists", dc.namespace, dc.serviceAccountName),
+		fmt.Sprintf("create the service account with `azwi serviceaccount create phase service-account --service-account-namespace %s --service-account-name %s`", dc.namespace, dc.serviceAccountName),
+		func() (err error) {
+			sa, err = kuberneteshelper.GetServiceAccount(ctx, dc.kubeClient, dc.namespace, dc.serviceAccountName)
+			return err
+		})
+
+	clientID := ""
+	if saOK {
+		dc.check(fmt.Sprintf("service account is labeled with %s=true", webhook.UseWorkloadIdentityLabel),
+			fmt.Sprintf("run `kubectl label serviceaccount %s --namespace %s %s=true`", dc.serviceAccountName, dc.namespace, webhook.UseWorkloadIdentityLabel),
+			func() error {
+				if sa.Labels[webhook.UseWorkloadIdentityLabel] != "true" {
+					return errors.Errorf("label %s is %q", webhook.UseWorkloadIdentityLabel, sa.Labels[webhook.UseWorkloadIdentityLabel])
+				}
+				return nil
+			})
+		dc.check(fmt.Sprintf("service account is annotated with %s", webhook.ClientIDAnnotation),
+			fmt.Sprintf("run `kubectl annotate serviceaccount %s --namespace %s %s=<client ID of the AAD application>`", dc.serviceAccountName, dc.namespace, webhook.ClientIDAnnotation),
+			func() error {
+				if clientID = sa.Annotations[webhook.ClientIDAnnotation]; clientID == "" {
+					return errors.Errorf("annotation %s is missing", webhook.ClientIDAnnotation)
+				}
+				return nil
+			})
+	}
+
+	azureClient := dc.authProvider.GetAzureClient()
+	subject := util.GetFederatedCredentialSubject(dc.namespace, dc.serviceAccountName)
+	// the client ID can belong to an AAD application or to a user-assigned managed identity,
+	// both of which are backed by a service principal
+	var sp models.ServicePrincipalable
+	spOK := false
+	if clientID == "" {
+		dc.skip("identity exists")
+	} else {
+		spOK = dc.check(fmt.Sprintf("identity with client ID %s exists", clientID),
+			"create the AAD application and its service principal with `azwi serviceaccount create phase aad-application`, or annotate the service account with the client ID of an existing user-assigned managed identity",
+			func() (err error) {
+				sp, err = azureClient.GetServicePrincipalByAppID(ctx, clientID)
+				return err
+			})
+	}
+
+	switch {
+	case !spOK:
+		dc.skip("federated identity credential exists")
+	case to.String(sp.GetServicePrincipalType()) == servicePrincipalTypeManagedIdentity:
+		resourceID := getManagedIdentityResourceID(sp)
+		dc.check(fmt.Sprintf("federated identity credential exists for subject %s and issuer %s", subject, dc.serviceAccountIssuerURL),
+			fmt.Sprintf("create the federated identity credential with `azwi serviceaccount create phase federated-identity --from-managed-identity %s --service-account-namespace %s --service-account-name %s --service-account-issuer-url %s`",
+				resourceID, dc.namespace, dc.serviceAccountName, dc.serviceAccountIssuerURL),
+			func() error {
+				if resourceID == "" {
+					return errors.Errorf("resource ID of managed identity with client ID %s not found", clientID)
+				}
+				_, err := azureClient.GetManagedIdentityFederatedCredential(ctx, resourceID, dc.serviceAccountIssuerURL, subject)
+				return err
+			})
+	default:
+		var app models.Applicationable
+		appOK := dc.check(fmt.Sprintf("AAD application with client ID %s exists", clientID),
+			"create the AAD application with `azwi serviceaccount create phase aad-application` and annotate the service account with its client ID",
+			func() (err error) {
+				app, err = azureClient.GetApplicationByAppID(ctx, clientID)
+				return err
+			})
+		if !appOK {
+			dc.skip("federated identity credential exists")
+			break
+		}
+		dc.check(fmt.Sprintf("federated identity credential exists for subject %s and issuer %s", subject, dc.serviceAccountIssuerURL),
+			fmt.Sprintf("create the federated identity credential with `azwi serviceaccount create phase federated-identity --aad-application-name %s --service-account-namespace %s --service-account-name %s --service-account-issuer-url %s`",
+				to.String(app.GetDisplayName()), dc.namespace, dc.serviceAccountName, dc.serviceAccountIssuerURL),
+			func() error {
+				_, err := azureClient.GetFederatedCredential(ctx, to.String(app.GetId()), dc.serviceAccountIssuerURL, subject)
+				return err
+			})
+	}
+
+	dc.check(fmt.Sprintf("OIDC issuer %s is reachable and valid", dc.serviceAccountIssuerURL),
+		fmt.Sprintf("run `azwi verify issuer --issuer-url %s` for details", dc.serviceAccountIssuerURL),
+		func() error {
+			return dc.verifyIssuer(ctx, dc.serviceAccountIssuerURL)
+		})
+
+	if dc.federatedTokenFile != "" {
+		dc.checkTokenExchange(ctx, sa, clientID)
+	}
+
+	if dc.failed > 0 {
+		return errors.Errorf("%d check(s) failed", dc.failed)
+	}
+	fmt.Fprintf(dc.out, "\nservice account %s/%s is correctly configured for workload identity\n", dc.namespace, dc.serviceAccountName)
+	return nil
+}
+
+// checkTokenExchange checks the service account token in the federated token file can be exchanged for an
+// Azure AD token of the client ID, which confirms the federated identity credential matches the token.
+func (dc *doctorCmd) checkTokenExchange(ctx context.Context, sa *corev1.ServiceAccount, clientID string) {
+	name := "service account token can be exchanged for an Azure AD token"
+	if clientID == "" {
+		dc.skip(name)
+		return
+	}
+	// the tenant ID annotation overrides the tenant of the webhook configuration
+	tenantID := dc.authProvider.GetAzureTenantID()
+	if sa != nil && sa.Annotations[webhook.TenantIDAnnotation] != "" {
+		tenantID = sa.Annotations[webhook.TenantIDAnnotation]
+	}
+
+	dc.check(name,
+		fmt.Sprintf("make sure the token in %s is a token of service account %s/%s issued with the audience of the federated identity credential, e.g. with `kubectl create token %s --namespace %s --audience %s`",
+			dc.federatedTokenFile, dc.namespace, dc.serviceAccountName, dc.serviceAccountName, dc.namespace, webhook.DefaultAudience),
+		func() error {
+			token, err := os.ReadFile(dc.federatedTokenFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read federated token file %s", dc.federatedTokenFile)
+			}
+			return dc.exchangeToken(ctx, tenantID, clientID, strings.TrimSpace(string(token)))
+		})
+}
+
+// getManagedIdentityResourceID returns the resource ID of the managed identity backing the service principal,
+// which is one of the alternative names of the service principal.
+func getManagedIdentityResourceID(sp models.ServicePrincipalable) string {
+	for _, name := range sp.GetAlternativeNames() {
+		if strings.HasPrefix(strings.ToLower(name), "/subscriptions/") {
+			return name
+		}
+	}
+	return ""
+}
+
+// check runs fn and prints the result of the named check with the remediation hint if it fails.
+func (dc *doctorCmd) check(name, hint string, fn func() error) bool {
+	if err := fn(); err != nil {
+		dc.failed++
+		fmt.Fprintf(dc.out, "[FAIL] %s: %v\n       hint: %s\n", name, err, hint)
+		return false
+	}
+	fmt.Fprintf(dc.out, "[PASS] %s\n", name)
+	return true
+}
+
+// skip prints the named check as skipped because a check it depends on failed.
+func (dc *doctorCmd) skip(name string) {
+	fmt.Fprintf(dc.out, "[SKIP] %s\n", name)
+}
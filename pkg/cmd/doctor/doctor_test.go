@@ -0,0 +1,330 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	testIssuerURL = "https://issuer.example.com/"
+	testClientID  = "client-id"
+	testObjectID  = "object-id"
+	testAppName   = "app"
+	testSubject   = "system:serviceaccount:default:sa"
+	// testManagedIdentityID is the resource ID of a user-assigned managed identity
+	testManagedIdentityID = "/subscriptions/subscription-id/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity"
+)
+
+type mockAuthProvider struct {
+	azureClient   *mock_cloud.MockInterface
+	azureTenantID string
+}
+
+func (m *mockAuthProvider) AddFlags(_ *pflag.FlagSet)       {}
+func (m *mockAuthProvider) GetAzureClient() cloud.Interface { return m.azureClient }
+func (m *mockAuthProvider) GetAzureTenantID() string        { return m.azureTenantID }
+func (m *mockAuthProvider) Validate(_ *pflag.FlagSet) error { return nil }
+
+func newServiceAccount(labels, annotations map[string]string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "sa",
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+}
+
+func newApplication() models.Applicationable {
+	app := models.NewApplication()
+	app.SetId(to.StringPtr(testObjectID))
+	app.SetAppId(to.StringPtr(testClientID))
+	app.SetDisplayName(to.StringPtr(testAppName))
+	return app
+}
+
+func newManagedIdentityServicePrincipal() models.ServicePrincipalable {
+	sp := models.NewServicePrincipal()
+	sp.SetAppId(to.StringPtr(testClientID))
+	sp.SetServicePrincipalType(to.StringPtr(servicePrincipalTypeManagedIdentity))
+	sp.SetAlternativeNames([]string{"isExplicit=True", testManagedIdentityID})
+	return sp
+}
+
+func TestDoctorCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		dc      *doctorCmd
+		wantErr string
+	}{
+		{
+			name:    "missing --service-account",
+			dc:      &doctorCmd{},
+			wantErr: "--service-account is required",
+		},
+		{
+			name:    "missing --service-account-issuer-url",
+			dc:      &doctorCmd{serviceAccountName: "sa"},
+			wantErr: "--service-account-issuer-url is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dc.prerun(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("prerun() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoctorCmdRun(t *testing.T) {
+	validServiceAccount := newServiceAccount(
+		map[string]string{webhook.UseWorkloadIdentityLabel: "true"},
+		map[string]string{webhook.ClientIDAnnotation: testClientID},
+	)
+
+	tests := []struct {
+		name           string
+		objects        []client.Object
+		expect         func(m *mock_cloud.MockInterfaceMockRecorder)
+		issuerErr      error
+		wantFailed     []string
+		wantSkipped    []string
+		wantHealthyMsg bool
+	}{
+		{
+			name:    "all checks pass",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(models.NewServicePrincipal(), nil)
+				m.GetApplicationByAppID(gomock.Any(), testClientID).Return(newApplication(), nil)
+				m.GetFederatedCredential(gomock.Any(), testObjectID, testIssuerURL, testSubject).Return(models.NewFederatedIdentityCredential(), nil)
+			},
+			wantHealthyMsg: true,
+		},
+		{
+			name:        "service account not found",
+			expect:      func(m *mock_cloud.MockInterfaceMockRecorder) {},
+			wantFailed:  []string{"service account default/sa exists"},
+			wantSkipped: []string{"identity exists", "federated identity credential exists"},
+		},
+		{
+			name:        "missing label and client ID annotation",
+			objects:     []client.Object{newServiceAccount(nil, nil)},
+			expect:      func(m *mock_cloud.MockInterfaceMockRecorder) {},
+			wantFailed:  []string{"service account is labeled with azure.workload.identity/use=true", "service account is annotated with azure.workload.identity/client-id"},
+			wantSkipped: []string{"identity exists", "federated identity credential exists"},
+		},
+		{
+			name:    "identity not found",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(nil, errors.Errorf("service principal with app ID '%s' not found", testClientID))
+			},
+			wantFailed:  []string{"identity with client ID client-id exists"},
+			wantSkipped: []string{"federated identity credential exists"},
+		},
+		{
+			name:    "AAD application not found",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(models.NewServicePrincipal(), nil)
+				m.GetApplicationByAppID(gomock.Any(), testClientID).Return(nil, errors.Errorf("application with app ID '%s' not found", testClientID))
+			},
+			wantFailed:  []string{"AAD application with client ID client-id exists"},
+			wantSkipped: []string{"federated identity credential exists"},
+		},
+		{
+			name:    "all checks pass with a managed identity",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(newManagedIdentityServicePrincipal(), nil)
+				m.GetManagedIdentityFederatedCredential(gomock.Any(), testManagedIdentityID, testIssuerURL, testSubject).Return(cloud.FederatedCredential{}, nil)
+			},
+			wantHealthyMsg: true,
+		},
+		{
+			name:    "managed identity federated identity credential not found",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(newManagedIdentityServicePrincipal(), nil)
+				m.GetManagedIdentityFederatedCredential(gomock.Any(), testManagedIdentityID, testIssuerURL, testSubject).Return(cloud.FederatedCredential{}, cloud.ErrFederatedCredentialNotFound)
+			},
+			wantFailed: []string{"federated identity credential exists for subject system:serviceaccount:default:sa and issuer https://issuer.example.com/"},
+		},
+		{
+			name:    "federated identity credential not found",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(models.NewServicePrincipal(), nil)
+				m.GetApplicationByAppID(gomock.Any(), testClientID).Return(newApplication(), nil)
+				m.GetFederatedCredential(gomock.Any(), testObjectID, testIssuerURL, testSubject).Return(nil, cloud.ErrFederatedCredentialNotFound)
+			},
+			wantFailed: []string{"federated identity credential exists for subject system:serviceaccount:default:sa and issuer https://issuer.example.com/"},
+		},
+		{
+			name:    "issuer not reachable",
+			objects: []client.Object{validServiceAccount},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(models.NewServicePrincipal(), nil)
+				m.GetApplicationByAppID(gomock.Any(), testClientID).Return(newApplication(), nil)
+				m.GetFederatedCredential(gomock.Any(), testObjectID, testIssuerURL, testSubject).Return(models.NewFederatedIdentityCredential(), nil)
+			},
+			issuerErr:  errors.New("failed to send request"),
+			wantFailed: []string{"OIDC issuer https://issuer.example.com/ is reachable and valid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			tt.expect(mockAzureClient.EXPECT())
+
+			out := &bytes.Buffer{}
+			dc := &doctorCmd{
+				namespace:               "default",
+				serviceAccountName:      "sa",
+				serviceAccountIssuerURL: testIssuerURL,
+				out:                     out,
+				authProvider:            &mockAuthProvider{azureClient: mockAzureClient},
+				kubeClient:              fake.NewClientBuilder().WithObjects(tt.objects...).Build(),
+				verifyIssuer: func(ctx context.Context, issuerURL string) error {
+					return tt.issuerErr
+				},
+			}
+			err := dc.run(context.Background())
+
+			if tt.wantHealthyMsg {
+				if err != nil {
+					t.Fatalf("run() error = %v, output:\n%s", err, out.String())
+				}
+				if strings.Contains(out.String(), "[FAIL]") || !strings.Contains(out.String(), "is correctly configured for workload identity") {
+					t.Errorf("expected all checks to pass, got:\n%s", out.String())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("run() error = nil, want error")
+			}
+			if got := strings.Count(out.String(), "[FAIL]"); got != len(tt.wantFailed) {
+				t.Errorf("expected %d failed checks, got %d:\n%s", len(tt.wantFailed), got, out.String())
+			}
+			for _, name := range tt.wantFailed {
+				if !strings.Contains(out.String(), "[FAIL] "+name+":") {
+					t.Errorf("expected check %q to fail, got:\n%s", name, out.String())
+				}
+			}
+			for _, name := range tt.wantSkipped {
+				if !strings.Contains(out.String(), "[SKIP] "+name+"\n") {
+					t.Errorf("expected check %q to be skipped, got:\n%s", name, out.String())
+				}
+			}
+			if !strings.Contains(out.String(), "hint: ") {
+				t.Errorf("expected output to contain a remediation hint, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestDoctorCmdRunTokenExchange(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		exchangeErr  error
+		wantTenantID string
+		wantFailed   bool
+	}{
+		{
+			name:         "token exchanged",
+			annotations:  map[string]string{webhook.ClientIDAnnotation: testClientID},
+			wantTenantID: "tenant-id",
+		},
+		{
+			name:         "tenant ID annotation overrides the tenant",
+			annotations:  map[string]string{webhook.ClientIDAnnotation: testClientID, webhook.TenantIDAnnotation: "annotated-tenant-id"},
+			wantTenantID: "annotated-tenant-id",
+		},
+		{
+			name:         "token exchange fails",
+			annotations:  map[string]string{webhook.ClientIDAnnotation: testClientID},
+			exchangeErr:  errors.New("Azure AD returned invalid_client: AADSTS70021: No matching federated identity record found for presented assertion."),
+			wantTenantID: "tenant-id",
+			wantFailed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			mockAzureClient.EXPECT().GetServicePrincipalByAppID(gomock.Any(), testClientID).Return(models.NewServicePrincipal(), nil)
+			mockAzureClient.EXPECT().GetApplicationByAppID(gomock.Any(), testClientID).Return(newApplication(), nil)
+			mockAzureClient.EXPECT().GetFederatedCredential(gomock.Any(), testObjectID, testIssuerURL, testSubject).Return(models.NewFederatedIdentityCredential(), nil)
+
+			tokenFile := filepath.Join(t.TempDir(), "token")
+			if err := os.WriteFile(tokenFile, []byte("service-account-token\n"), 0600); err != nil {
+				t.Fatalf("failed to write token file: %v", err)
+			}
+
+			var gotTenantID, gotClientID, gotToken string
+			out := &bytes.Buffer{}
+			dc := &doctorCmd{
+				namespace:               "default",
+				serviceAccountName:      "sa",
+				serviceAccountIssuerURL: testIssuerURL,
+				federatedTokenFile:      tokenFile,
+				out:                     out,
+				authProvider:            &mockAuthProvider{azureClient: mockAzureClient, azureTenantID: "tenant-id"},
+				kubeClient: fake.NewClientBuilder().WithObjects(newServiceAccount(
+					map[string]string{webhook.UseWorkloadIdentityLabel: "true"}, tt.annotations)).Build(),
+				verifyIssuer: func(ctx context.Context, issuerURL string) error {
+					return nil
+				},
+				exchangeToken: func(ctx context.Context, tenantID, clientID, token string) error {
+					gotTenantID, gotClientID, gotToken = tenantID, clientID, token
+					return tt.exchangeErr
+				},
+			}
+			err := dc.run(context.Background())
+			if (err != nil) != tt.wantFailed {
+				t.Fatalf("run() error = %v, wantFailed %v, output:\n%s", err, tt.wantFailed, out.String())
+			}
+
+			if gotTenantID != tt.wantTenantID || gotClientID != testClientID || gotToken != "service-account-token" {
+				t.Errorf("expected the token to be exchanged in tenant %s for client ID %s, got tenant %s, client ID %s and token %q", tt.wantTenantID, testClientID, gotTenantID, gotClientID, gotToken)
+			}
+			wantResult := "[PASS] service account token can be exchanged for an Azure AD token"
+			if tt.wantFailed {
+				wantResult = "[FAIL] service account token can be exchanged for an Azure AD token: " + tt.exchangeErr.Error()
+			}
+			if !strings.Contains(out.String(), wantResult) {
+				t.Errorf("expected output to contain %q, got:\n%s", wantResult, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/federatedcredential"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+)
+
+// NewExportCmd returns a new export command
+func NewExportCmd() *cobra.Command {
+	authProvider := auth.NewProvider()
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the workload identity configuration as a declarative manifest",
+		Long:  "Export the workload identity configuration as a declarative manifest",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return authProvider.Validate(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	// auth flags should be available for all subcommands
+	authProvider.AddFlags(exportCmd.PersistentFlags())
+
+	exportCmd.AddCommand(federatedcredential.NewExportCmd(authProvider))
+
+	return exportCmd
+}
@@ -0,0 +1,91 @@
+package federatedcredential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+)
+
+const (
+	outputYAML = "yaml"
+	outputJSON = "json"
+)
+
+type exportCmd struct {
+	aadApplicationName string
+	output             string
+	out                io.Writer
+	authProvider       auth.Provider
+}
+
+// NewExportCmd returns a new command to export the federated identity credentials of an AAD application
+func NewExportCmd(authProvider auth.Provider) *cobra.Command {
+	exportCmd := &exportCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "federated-credentials",
+		Short: "Export the federated identity credentials of an AAD application",
+		Long:  "This command lists the federated identity credentials of an AAD application and prints them as a declarative manifest that can be consumed by the reconcile command",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return exportCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportCmd.out = cmd.OutOrStdout()
+			return exportCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&exportCmd.aadApplicationName, options.AADApplicationName.Flag, "", "Name of the AAD application")
+	f.StringVarP(&exportCmd.output, "output", "o", outputYAML, "Output format. One of: yaml, json")
+
+	return cmd
+}
+
+func (ec *exportCmd) prerun() error {
+	if ec.aadApplicationName == "" {
+		return options.FlagIsRequiredError(options.AADApplicationName.Flag)
+	}
+	if ec.output != outputYAML && ec.output != outputJSON {
+		return errors.Errorf("--output must be one of: %s, %s", outputYAML, outputJSON)
+	}
+	return nil
+}
+
+func (ec *exportCmd) run(ctx context.Context) error {
+	mlog.Debug("exporting federated credentials", "aadApplicationName", ec.aadApplicationName)
+
+	azureClient := ec.authProvider.GetAzureClient()
+	app, err := azureClient.GetApplication(ctx, ec.aadApplicationName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get AAD application")
+	}
+	fics, err := azureClient.ListFederatedCredentials(ctx, *app.GetId())
+	if err != nil {
+		return errors.Wrap(err, "failed to list federated credentials")
+	}
+
+	m := newManifest(ec.aadApplicationName, fics)
+	var b []byte
+	if ec.output == outputJSON {
+		b, err = json.MarshalIndent(m, "", "  ")
+	} else {
+		b, err = yaml.Marshal(m)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal manifest")
+	}
+	_, err = fmt.Fprintln(ec.out, string(b))
+	return err
+}
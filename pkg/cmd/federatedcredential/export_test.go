@@ -0,0 +1,158 @@
+package federatedcredential
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+)
+
+type mockAuthProvider struct {
+	azureClient   *mock_cloud.MockInterface
+	azureTenantID string
+}
+
+func (m *mockAuthProvider) AddFlags(_ *pflag.FlagSet)       {}
+func (m *mockAuthProvider) GetAzureClient() cloud.Interface { return m.azureClient }
+func (m *mockAuthProvider) GetAzureTenantID() string        { return m.azureTenantID }
+func (m *mockAuthProvider) Validate(_ *pflag.FlagSet) error { return nil }
+
+func newFederatedIdentityCredential(name, issuer, subject, description string) models.FederatedIdentityCredentialable {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr(name))
+	fic.SetIssuer(to.StringPtr(issuer))
+	fic.SetSubject(to.StringPtr(subject))
+	fic.SetAudiences([]string{"api://AzureADTokenExchange"})
+	if description != "" {
+		fic.SetDescription(to.StringPtr(description))
+	}
+	return fic
+}
+
+func TestExportCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		ec      *exportCmd
+		wantErr string
+	}{
+		{
+			name:    "missing --aad-application-name",
+			ec:      &exportCmd{output: outputYAML},
+			wantErr: "--aad-application-name is required",
+		},
+		{
+			name:    "invalid --output",
+			ec:      &exportCmd{aadApplicationName: "app", output: "table"},
+			wantErr: "--output must be one of: yaml, json",
+		},
+		{
+			name: "valid",
+			ec:   &exportCmd{aadApplicationName: "app", output: outputJSON},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ec.prerun()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("prerun() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("prerun() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportCmdRun(t *testing.T) {
+	fics := []models.FederatedIdentityCredentialable{
+		newFederatedIdentityCredential("fic-2", "https://issuer.example.com/", "system:serviceaccount:default:sa-2", ""),
+		newFederatedIdentityCredential("fic-1", "https://issuer.example.com/", "system:serviceaccount:default:sa-1", "Federated Service Account for default/sa-1"),
+	}
+	want := &manifest{
+		AADApplicationName: "app",
+		FederatedCredentials: []federatedCredential{
+			{
+				Name:        "fic-1",
+				Issuer:      "https://issuer.example.com/",
+				Subject:     "system:serviceaccount:default:sa-1",
+				Audiences:   []string{"api://AzureADTokenExchange"},
+				Description: "Federated Service Account for default/sa-1",
+			},
+			{
+				Name:      "fic-2",
+				Issuer:    "https://issuer.example.com/",
+				Subject:   "system:serviceaccount:default:sa-2",
+				Audiences: []string{"api://AzureADTokenExchange"},
+			},
+		},
+	}
+
+	for _, output := range []string{outputYAML, outputJSON} {
+		t.Run(output, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			app := models.NewApplication()
+			app.SetId(to.StringPtr("object-id"))
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			mockAzureClient.EXPECT().GetApplication(gomock.Any(), "app").Return(app, nil)
+			mockAzureClient.EXPECT().ListFederatedCredentials(gomock.Any(), "object-id").Return(fics, nil)
+
+			out := &bytes.Buffer{}
+			ec := &exportCmd{
+				aadApplicationName: "app",
+				output:             output,
+				out:                out,
+				authProvider:       &mockAuthProvider{azureClient: mockAzureClient},
+			}
+			if err := ec.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+
+			// the exported manifest should parse back into the same desired set
+			got, err := parseManifest(out.Bytes())
+			if err != nil {
+				t.Fatalf("parseManifest() error = %v, output:\n%s", err, out.String())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseManifest() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestExportCmdRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	app := models.NewApplication()
+	app.SetId(to.StringPtr("object-id"))
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplication(gomock.Any(), "app").Return(app, nil)
+	mockAzureClient.EXPECT().ListFederatedCredentials(gomock.Any(), "object-id").Return(nil, errors.New("random error"))
+
+	ec := &exportCmd{
+		aadApplicationName: "app",
+		output:             outputYAML,
+		out:                &bytes.Buffer{},
+		authProvider:       &mockAuthProvider{azureClient: mockAzureClient},
+	}
+	if err := ec.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+}
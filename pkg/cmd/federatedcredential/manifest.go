@@ -0,0 +1,130 @@
+package federatedcredential
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+)
+
+// manifest is the declarative representation of the federated identity credentials of an AAD application.
+type manifest struct {
+	AADApplicationName   string                `json:"aadApplicationName"`
+	FederatedCredentials []federatedCredential `json:"federatedCredentials"`
+}
+
+// federatedCredential is the declarative representation of a federated identity credential.
+type federatedCredential struct {
+	Name        string   `json:"name"`
+	Issuer      string   `json:"issuer"`
+	Subject     string   `json:"subject"`
+	Audiences   []string `json:"audiences"`
+	Description string   `json:"description,omitempty"`
+}
+
+// newManifest returns the manifest of the given federated identity credentials sorted by name.
+func newManifest(aadApplicationName string, fics []models.FederatedIdentityCredentialable) *manifest {
+	m := &manifest{
+		AADApplicationName:   aadApplicationName,
+		FederatedCredentials: make([]federatedCredential, 0, len(fics)),
+	}
+	for _, fic := range fics {
+		m.FederatedCredentials = append(m.FederatedCredentials, federatedCredential{
+			Name:        to.String(fic.GetName()),
+			Issuer:      to.String(fic.GetIssuer()),
+			Subject:     to.String(fic.GetSubject()),
+			Audiences:   fic.GetAudiences(),
+			Description: to.String(fic.GetDescription()),
+		})
+	}
+	sort.Slice(m.FederatedCredentials, func(i, j int) bool {
+		return m.FederatedCredentials[i].Name < m.FederatedCredentials[j].Name
+	})
+	return m
+}
+
+// readManifest reads and validates the manifest from the given file.
+func readManifest(path string) (*manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest %s", path)
+	}
+	return parseManifest(b)
+}
+
+// parseManifest parses and validates the manifest from its YAML or JSON representation.
+func parseManifest(b []byte) (*manifest, error) {
+	m := &manifest{}
+	if err := yaml.UnmarshalStrict(b, m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse manifest")
+	}
+	if err := m.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+	return m, nil
+}
+
+func (m *manifest) validate() error {
+	if m.AADApplicationName == "" {
+		return errors.New("aadApplicationName is required")
+	}
+	names := make(map[string]bool)
+	for i := range m.FederatedCredentials {
+		fc := &m.FederatedCredentials[i]
+		if fc.Name == "" {
+			// the name of the federated identity credential of a service account defaults to the name
+			// the CLI gives it, so that the manifest doesn't have to spell out the derived names
+			namespace, serviceAccount, ok := parseServiceAccountSubject(fc.Subject)
+			if !ok {
+				return errors.Errorf("federatedCredentials[%d].name is required", i)
+			}
+			fc.Name = util.FederatedCredentialName(namespace, serviceAccount)
+		}
+		if names[fc.Name] {
+			return errors.Errorf("federatedCredentials[%d].name %q is duplicated", i, fc.Name)
+		}
+		names[fc.Name] = true
+		if fc.Issuer == "" {
+			return errors.Errorf("federatedCredentials[%d].issuer is required", i)
+		}
+		if fc.Subject == "" {
+			return errors.Errorf("federatedCredentials[%d].subject is required", i)
+		}
+		if len(fc.Audiences) == 0 {
+			return errors.Errorf("federatedCredentials[%d].audiences is required", i)
+		}
+	}
+	return nil
+}
+
+// parseServiceAccountSubject returns the namespace and name of the service account of the subject
+// and true if the subject is the subject of a Kubernetes service account.
+func parseServiceAccountSubject(subject string) (string, string, bool) {
+	parts := strings.Split(subject, ":")
+	if len(parts) != 4 || parts[0] != "system" || parts[1] != "serviceaccount" || parts[2] == "" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[2], parts[3], true
+}
+
+// expectedFederatedCredentials returns the desired state of the federated identity credentials in the manifest.
+func (m *manifest) expectedFederatedCredentials() []cloud.ExpectedFIC {
+	expected := make([]cloud.ExpectedFIC, 0, len(m.FederatedCredentials))
+	for _, fc := range m.FederatedCredentials {
+		expected = append(expected, cloud.ExpectedFIC{
+			Name:        fc.Name,
+			Issuer:      fc.Issuer,
+			Subject:     fc.Subject,
+			Audiences:   fc.Audiences,
+			Description: fc.Description,
+		})
+	}
+	return expected
+}
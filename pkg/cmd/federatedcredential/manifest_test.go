@@ -0,0 +1,109 @@
+package federatedcredential
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantName string
+		wantErr  string
+	}{
+		{
+			name: "valid manifest",
+			manifest: `
+aadApplicationName: app
+federatedCredentials:
+- name: fic-1
+  issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-1
+  audiences:
+  - api://AzureADTokenExchange
+`,
+		},
+		{
+			name:     "empty federated credentials",
+			manifest: `aadApplicationName: app`,
+		},
+		{
+			name:     "unknown field",
+			manifest: `{"aadApplicationName": "app", "unknown": true}`,
+			wantErr:  "failed to parse manifest",
+		},
+		{
+			name:     "missing aadApplicationName",
+			manifest: `federatedCredentials: []`,
+			wantErr:  "aadApplicationName is required",
+		},
+		{
+			name: "missing name of a service account federated credential",
+			manifest: `
+aadApplicationName: app
+federatedCredentials:
+- issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-1
+  audiences: [api://AzureADTokenExchange]
+`,
+			wantName: "default-sa-1",
+		},
+		{
+			name: "missing name",
+			manifest: `
+aadApplicationName: app
+federatedCredentials:
+- issuer: https://token.actions.githubusercontent.com
+  subject: repo:octo-org/octo-repo:environment:prod
+  audiences: [api://AzureADTokenExchange]
+`,
+			wantErr: "federatedCredentials[0].name is required",
+		},
+		{
+			name: "duplicate name",
+			manifest: `
+aadApplicationName: app
+federatedCredentials:
+- name: fic-1
+  issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-1
+  audiences: [api://AzureADTokenExchange]
+- name: fic-1
+  issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-2
+  audiences: [api://AzureADTokenExchange]
+`,
+			wantErr: `federatedCredentials[1].name "fic-1" is duplicated`,
+		},
+		{
+			name: "missing audiences",
+			manifest: `
+aadApplicationName: app
+federatedCredentials:
+- name: fic-1
+  issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-1
+`,
+			wantErr: "federatedCredentials[0].audiences is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseManifest([]byte(tt.manifest))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseManifest() error = %v, want nil", err)
+				}
+				if tt.wantName != "" && m.FederatedCredentials[0].Name != tt.wantName {
+					t.Errorf("expected federated credential name %s, got %s", tt.wantName, m.FederatedCredentials[0].Name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseManifest() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package federatedcredential
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+)
+
+type reconcileCmd struct {
+	aadApplicationName string
+	manifestPath       string
+	dryRun             bool
+	manifest           *manifest
+	out                io.Writer
+	authProvider       auth.Provider
+}
+
+// NewReconcileCmd returns a new command to converge the federated identity credentials of an AAD application to a manifest
+func NewReconcileCmd(authProvider auth.Provider) *cobra.Command {
+	reconcileCmd := &reconcileCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "federated-credentials",
+		Short: "Reconcile the federated identity credentials of an AAD application with a manifest",
+		Long:  "This command converges the federated identity credentials of an AAD application to the declarative manifest generated by the export command. Federated identity credentials that are not in the manifest are deleted",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return reconcileCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reconcileCmd.out = cmd.OutOrStdout()
+			return reconcileCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&reconcileCmd.aadApplicationName, options.AADApplicationName.Flag, "", "Name of the AAD application. If not specified, the aadApplicationName of the manifest will be used")
+	f.StringVarP(&reconcileCmd.manifestPath, "filename", "f", "", "Path to the federated identity credentials manifest")
+	f.BoolVar(&reconcileCmd.dryRun, options.DryRun.Flag, false, options.DryRun.Description)
+
+	return cmd
+}
+
+func (rc *reconcileCmd) prerun() error {
+	if rc.manifestPath == "" {
+		return options.FlagIsRequiredError("filename")
+	}
+
+	var err error
+	if rc.manifest, err = readManifest(rc.manifestPath); err != nil {
+		return err
+	}
+	if rc.aadApplicationName == "" {
+		rc.aadApplicationName = rc.manifest.AADApplicationName
+	}
+	// guard against converging the wrong application to the manifest
+	if rc.aadApplicationName != rc.manifest.AADApplicationName {
+		return errors.Errorf("--%s %q does not match the manifest aadApplicationName %q", options.AADApplicationName.Flag, rc.aadApplicationName, rc.manifest.AADApplicationName)
+	}
+
+	return nil
+}
+
+func (rc *reconcileCmd) run(ctx context.Context) error {
+	mlog.Debug("reconciling federated credentials", "aadApplicationName", rc.aadApplicationName, "dryRun", rc.dryRun)
+
+	azureClient := rc.authProvider.GetAzureClient()
+	app, err := azureClient.GetApplication(ctx, rc.aadApplicationName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get AAD application")
+	}
+
+	if rc.dryRun {
+		current, err := azureClient.ListFederatedCredentials(ctx, *app.GetId())
+		if err != nil {
+			return errors.Wrap(err, "failed to list federated credentials")
+		}
+		diff := cloud.DiffFederatedCredentials(cloud.NewExpectedFICs(current), rc.manifest.expectedFederatedCredentials())
+		if _, err = fmt.Fprintf(rc.out, "%s(dry run) %d created, %d updated, %d deleted\n", diff, len(diff.Create), len(diff.Update), len(diff.Delete)); err != nil {
+			return err
+		}
+	} else {
+		result, err := azureClient.ReconcileFederatedCredentials(ctx, *app.GetId(), rc.manifest.expectedFederatedCredentials(), false)
+		if err != nil {
+			return errors.Wrap(err, "failed to reconcile federated credentials")
+		}
+		if _, err = fmt.Fprintf(rc.out, "%d created, %d updated, %d deleted\n", len(result.Created), len(result.Updated), len(result.Deleted)); err != nil {
+			return err
+		}
+	}
+	// let users know that the run was slowed down by throttling
+	if stats := azureClient.ThrottleStats(); stats.Throttled > 0 {
+		_, err = fmt.Fprintf(rc.out, "throttled %d times, backed off for %s\n", stats.Throttled, stats.Backoff)
+	}
+	return err
+}
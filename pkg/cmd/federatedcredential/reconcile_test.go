@@ -0,0 +1,219 @@
+package federatedcredential
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+)
+
+const testManifest = `
+aadApplicationName: app
+federatedCredentials:
+- name: fic-1
+  issuer: https://issuer.example.com/
+  subject: system:serviceaccount:default:sa-1
+  audiences:
+  - api://AzureADTokenExchange
+`
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestReconcileCmdPreRun(t *testing.T) {
+	manifestPath := writeManifest(t, testManifest)
+
+	tests := []struct {
+		name                   string
+		rc                     *reconcileCmd
+		wantErr                string
+		wantAADApplicationName string
+	}{
+		{
+			name:    "missing --filename",
+			rc:      &reconcileCmd{},
+			wantErr: "--filename is required",
+		},
+		{
+			name:    "manifest not found",
+			rc:      &reconcileCmd{manifestPath: filepath.Join(t.TempDir(), "not-found.yaml")},
+			wantErr: "failed to read manifest",
+		},
+		{
+			name:    "invalid manifest",
+			rc:      &reconcileCmd{manifestPath: writeManifest(t, "federatedCredentials: []")},
+			wantErr: "aadApplicationName is required",
+		},
+		{
+			name:    "application name mismatch",
+			rc:      &reconcileCmd{aadApplicationName: "another-app", manifestPath: manifestPath},
+			wantErr: `--aad-application-name "another-app" does not match the manifest aadApplicationName "app"`,
+		},
+		{
+			name:                   "application name from manifest",
+			rc:                     &reconcileCmd{manifestPath: manifestPath},
+			wantAADApplicationName: "app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rc.prerun()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("prerun() error = %v, want nil", err)
+				}
+				if tt.rc.aadApplicationName != tt.wantAADApplicationName {
+					t.Errorf("aadApplicationName = %s, want %s", tt.rc.aadApplicationName, tt.wantAADApplicationName)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("prerun() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReconcileCmdRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   cloud.ReconcileResult
+		throttle cloud.ThrottleStats
+		wantOut  string
+	}{
+		{
+			name:    "no changes",
+			wantOut: "0 created, 0 updated, 0 deleted\n",
+		},
+		{
+			name:    "changes",
+			result:  cloud.ReconcileResult{Created: []string{"fic-1"}, Deleted: []string{"fic-2", "fic-3"}},
+			wantOut: "1 created, 0 updated, 2 deleted\n",
+		},
+		{
+			name:     "throttled",
+			result:   cloud.ReconcileResult{Created: []string{"fic-1"}},
+			throttle: cloud.ThrottleStats{Throttled: 2, Backoff: 3 * time.Second},
+			wantOut:  "1 created, 0 updated, 0 deleted\nthrottled 2 times, backed off for 3s\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			app := models.NewApplication()
+			app.SetId(to.StringPtr("object-id"))
+
+			m, err := parseManifest([]byte(testManifest))
+			if err != nil {
+				t.Fatalf("parseManifest() error = %v", err)
+			}
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			mockAzureClient.EXPECT().GetApplication(gomock.Any(), "app").Return(app, nil)
+			mockAzureClient.EXPECT().ReconcileFederatedCredentials(gomock.Any(), "object-id", []cloud.ExpectedFIC{
+				{
+					Name:      "fic-1",
+					Issuer:    "https://issuer.example.com/",
+					Subject:   "system:serviceaccount:default:sa-1",
+					Audiences: []string{"api://AzureADTokenExchange"},
+				},
+			}, false).Return(tt.result, nil)
+			mockAzureClient.EXPECT().ThrottleStats().Return(tt.throttle)
+
+			out := &bytes.Buffer{}
+			rc := &reconcileCmd{
+				aadApplicationName: "app",
+				manifest:           m,
+				out:                out,
+				authProvider:       &mockAuthProvider{azureClient: mockAzureClient},
+			}
+			if err := rc.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("run() output = %q, want %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestReconcileCmdRunDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	app := models.NewApplication()
+	app.SetId(to.StringPtr("object-id"))
+
+	m, err := parseManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+
+	current := models.NewFederatedIdentityCredential()
+	current.SetName(to.StringPtr("fic-2"))
+	current.SetIssuer(to.StringPtr("https://issuer.example.com/"))
+	current.SetSubject(to.StringPtr("system:serviceaccount:default:sa-2"))
+	current.SetAudiences([]string{"api://AzureADTokenExchange"})
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplication(gomock.Any(), "app").Return(app, nil)
+	mockAzureClient.EXPECT().ListFederatedCredentials(gomock.Any(), "object-id").Return([]models.FederatedIdentityCredentialable{current}, nil)
+	mockAzureClient.EXPECT().ThrottleStats().Return(cloud.ThrottleStats{})
+
+	out := &bytes.Buffer{}
+	rc := &reconcileCmd{
+		aadApplicationName: "app",
+		dryRun:             true,
+		manifest:           m,
+		out:                out,
+		authProvider:       &mockAuthProvider{azureClient: mockAzureClient},
+	}
+	if err := rc.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	for _, want := range []string{"- fic-2\n", "+ fic-1\n", `subject: "system:serviceaccount:default:sa-1"`, "(dry run) 1 created, 0 updated, 1 deleted\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the output to contain %q, got %q", want, out.String())
+		}
+	}
+}
+
+func TestReconcileCmdRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplication(gomock.Any(), "app").Return(nil, errors.New("random error"))
+
+	rc := &reconcileCmd{
+		aadApplicationName: "app",
+		manifest:           &manifest{AADApplicationName: "app"},
+		out:                &bytes.Buffer{},
+		authProvider:       &mockAuthProvider{azureClient: mockAzureClient},
+	}
+	if err := rc.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+}
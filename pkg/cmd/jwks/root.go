@@ -39,7 +39,7 @@ func NewJWKSCmd() *cobra.Command {
 	}
 
 	f := cmd.Flags()
-	f.StringSliceVar(&jwksCmd.publicKeys, "public-keys", nil, "List of public keys to include in the JWKS")
+	f.StringSliceVar(&jwksCmd.publicKeys, "public-keys", nil, "List of public keys to include in the JWKS. Multiple keys can be provided to support key rotation")
 	f.StringVar(&jwksCmd.outputFile, "output-file", "", "The name of the file to write the JWKS to. If not provided, the default output is stdout")
 
 	_ = cmd.MarkFlagRequired("public-keys")
@@ -100,10 +100,13 @@ type publicKeyGetter interface {
 }
 
 // publicJWKSFromKeys constructs a JSONWebKeySet from a list of keys. The key
-// set will only contain the public keys associated with the input keys.
+// set will only contain the public keys associated with the input keys. Keys
+// that resolve to the same key ID are only included once, which allows the old
+// and new keys to be passed together during key rotation.
 func publicJWKSFromKeys(in []interface{}) (*jose.JSONWebKeySet, error) {
 	// Decode keys into a JWKS.
 	var keys jose.JSONWebKeySet
+	seen := make(map[string]bool)
 	for _, key := range in {
 		var pubkey *jose.JSONWebKey
 		var err error
@@ -122,6 +125,11 @@ func publicJWKSFromKeys(in []interface{}) (*jose.JSONWebKeySet, error) {
 		if !pubkey.Valid() {
 			return nil, errors.New("the public key is not valid")
 		}
+		if seen[pubkey.KeyID] {
+			mlog.Debug("skipping duplicate public key", "kid", pubkey.KeyID)
+			continue
+		}
+		seen[pubkey.KeyID] = true
 		keys.Keys = append(keys.Keys, *pubkey)
 	}
 	return &keys, nil
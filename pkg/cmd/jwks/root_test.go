@@ -144,3 +144,95 @@ dwIDAQAB
 		t.Errorf("expected jwks: %v, got: %v", o2, o1)
 	}
 }
+
+func TestJWKSCmdRunMultipleKeys(t *testing.T) {
+	oldPublicKey := `
+-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA1QJE2YmLbvMLP6FtzcfP
+zGbSDbHEEtA0mH6kwgrOrlKs83zj2vr6Y5k/ZcGdIbsdm5vDj2IxtSkE+pSDtgFM
+2iq0sJ7xuE6RYmlrtBm+H2WHvXrP9RrG1EfO7iWs6Czj4A/Ddxg3kNUiQCtQEJww
+H2pfrUkh8STQhST/T86pq5AIFCuQiQSrkfC80eD9bUFypV3CLB2M9Fa1hbvOWbzS
+F93/I0toUK2+oPgVW6m2EwMyy8Fh/3KRixrAJO8g+D4d537C1fa1vJJRlMRFtLMA
+/bo6k1fAtNsVQuQoML5CmRrvNT7ZpXRLaQy64OSFrVLD3Pb7wct7b4g2xQECixQo
+dwIDAQAB
+-----END PUBLIC KEY-----`
+
+	newPublicKey := `
+-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAxxunL102f1UPPxt4rsnX
+pyR0t8q6aitagQYB0WMsY+POudNoFcphECMDDqpbXV2yH06JF4ID0v02QFCTdVrr
+1NliUUsmQIDXmeilv0iQwk9KL3/ko/WiAKJwzXrvFizHTXRXaDIQORS40FAMCgjK
+kBv4jrl7Nl4mZfnSPM7gGU9o2JVxvGtZXUJFUl7mdGf4YqhfihpbCQkDPBE8wfVi
+IV2EeD0CExMtqUzl/WlihlwR7nYdBPwCP6OpLJAcHkRimUCpQOzBDJx/fE4J6aSK
+Gf7HIFLHHR+DYfrLOlE3ie3HOlNn4npEjyik6BCs4Xpl7mU+4I8DTD/8olkmsyzD
+swIDAQAB
+-----END PUBLIC KEY-----`
+
+	tmpDir, err := os.MkdirTemp("", "jwks")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldPublicKeyFile := filepath.Join(tmpDir, "old.key")
+	newPublicKeyFile := filepath.Join(tmpDir, "new.key")
+	outputFile := filepath.Join(tmpDir, "jwks.json")
+
+	if err = os.WriteFile(oldPublicKeyFile, []byte(oldPublicKey), 0600); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	if err = os.WriteFile(newPublicKeyFile, []byte(newPublicKey), 0600); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		publicKeys   []string
+		expectedKIDs []string
+	}{
+		{
+			name:         "two distinct keys",
+			publicKeys:   []string{oldPublicKeyFile, newPublicKeyFile},
+			expectedKIDs: []string{"2A3FPpix2keOV1SGPQiM0_wVemz4XOIgQyJJnpu5sPE", "zLK5SDQFO2lxmU4GyMy13dywy_dPOFHyQVhRBjuT49w"},
+		},
+		{
+			name:         "duplicate keys are only included once",
+			publicKeys:   []string{oldPublicKeyFile, newPublicKeyFile, oldPublicKeyFile},
+			expectedKIDs: []string{"2A3FPpix2keOV1SGPQiM0_wVemz4XOIgQyJJnpu5sPE", "zLK5SDQFO2lxmU4GyMy13dywy_dPOFHyQVhRBjuT49w"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksCmd := &jwksCmd{
+				publicKeys: tt.publicKeys,
+				outputFile: outputFile,
+			}
+			if err := jwksCmd.run(); err != nil {
+				t.Fatalf("Error running jwksCmd: %v", err)
+			}
+
+			body, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatalf("Error reading jwks.json file: %v", err)
+			}
+
+			var keySet struct {
+				Keys []struct {
+					KeyID string `json:"kid"`
+				} `json:"keys"`
+			}
+			if err := json.Unmarshal(body, &keySet); err != nil {
+				t.Fatalf("Error unmarshalling jwks.json: %v", err)
+			}
+
+			var kids []string
+			for _, key := range keySet.Keys {
+				kids = append(kids, key.KeyID)
+			}
+			if !reflect.DeepEqual(kids, tt.expectedKIDs) {
+				t.Errorf("expected kids: %v, got: %v", tt.expectedKIDs, kids)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/podidentity"
+)
+
+// NewMigrateCmd returns a new migrate command
+func NewMigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate existing identity configuration to workload identity",
+		Long:  "Migrate existing identity configuration to workload identity",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	migrateCmd.AddCommand(podidentity.NewMigrateCmd())
+
+	return migrateCmd
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"io"
+	"regexp"
+)
+
+// ansiEscapeSequence matches the ANSI escape sequences used for colors and text styles
+var ansiEscapeSequence = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// noColorWriter strips ANSI escape sequences from the output before writing it to the underlying writer
+type noColorWriter struct {
+	w io.Writer
+}
+
+func newNoColorWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*noColorWriter); ok {
+		return w
+	}
+	return &noColorWriter{w: w}
+}
+
+// Write writes p without ANSI escape sequences to the underlying writer and returns len(p)
+// on success so that callers don't treat the stripped bytes as a short write.
+func (n *noColorWriter) Write(p []byte) (int, error) {
+	if _, err := n.w.Write(ansiEscapeSequence.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,284 @@
+package podidentity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	aadpodv1 "github.com/Azure/aad-pod-identity/pkg/apis/aadpodidentity/v1"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+// identityMapping maps an aad-pod-identity AzureIdentity used by a service account
+// to the equivalent workload identity configuration.
+type identityMapping struct {
+	azureIdentityBinding string
+	azureIdentity        string
+	clientID             string
+	tenantID             string
+	resourceID           string
+
+	serviceAccountNamespace string
+	serviceAccountName      string
+
+	federatedCredentialName string
+	issuer                  string
+	subject                 string
+}
+
+type migrateCmd struct {
+	namespace               string
+	serviceAccountIssuerURL string
+	apply                   bool
+	out                     io.Writer
+	kubeClient              client.Client
+}
+
+// NewMigrateCmd returns a new command to migrate from aad-pod-identity to workload identity
+func NewMigrateCmd() *cobra.Command {
+	migrateCmd := &migrateCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "pod-identity",
+		Short: "Migrate the aad-pod-identity configuration to workload identity",
+		Long:  "This command reads the existing AzureIdentity and AzureIdentityBinding resources and maps them to the equivalent workload identity service account annotations and federated identity credentials",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return migrateCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrateCmd.out = cmd.OutOrStdout()
+			return migrateCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&migrateCmd.namespace, "namespace", "default", "Namespace of the aad-pod-identity configuration")
+	f.StringVar(&migrateCmd.serviceAccountIssuerURL, options.ServiceAccountIssuerURL.Flag, "", options.ServiceAccountIssuerURL.Description)
+	f.BoolVar(&migrateCmd.apply, "apply", false, "Apply the workload identity annotations to the service accounts")
+
+	return cmd
+}
+
+func (mc *migrateCmd) prerun() error {
+	if mc.serviceAccountIssuerURL == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountIssuerURL.Flag)
+	}
+
+	var err error
+	if mc.kubeClient, err = kuberneteshelper.GetKubeClient(); err != nil {
+		return errors.Wrap(err, "failed to get Kubernetes client")
+	}
+
+	return nil
+}
+
+func (mc *migrateCmd) run(ctx context.Context) error {
+	mlog.Debug("migrating aad-pod-identity configuration", "namespace", mc.namespace)
+
+	azureIdentityBindings, err := kuberneteshelper.ListAzureIdentityBinding(ctx, mc.kubeClient, mc.namespace)
+	if err != nil {
+		return err
+	}
+	azureIdentities, err := kuberneteshelper.ListAzureIdentity(ctx, mc.kubeClient, mc.namespace)
+	if err != nil {
+		return err
+	}
+	azureIdentityMap := make(map[string]aadpodv1.AzureIdentity)
+	for _, azureIdentity := range azureIdentities {
+		if azureIdentity.Spec.Type == aadpodv1.UserAssignedMSI {
+			azureIdentityMap[azureIdentity.Name] = azureIdentity
+		}
+	}
+
+	labelsToAzureIdentityMap := filterAzureIdentities(azureIdentityBindings, azureIdentityMap)
+	podsBySelector := make(map[string][]corev1.Pod)
+	for selector := range labelsToAzureIdentityMap {
+		pods, err := kuberneteshelper.ListPods(ctx, mc.kubeClient, mc.namespace, map[string]string{aadpodv1.CRDLabelKey: selector})
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			podsBySelector[selector] = append(podsBySelector[selector], pod)
+		}
+	}
+
+	mappings := getIdentityMappings(azureIdentityBindings, labelsToAzureIdentityMap, podsBySelector, mc.serviceAccountIssuerURL)
+	if len(mappings) == 0 {
+		mlog.Info("no aad-pod-identity configuration found", "namespace", mc.namespace)
+		return nil
+	}
+
+	if err = mc.printMappings(mappings); err != nil {
+		return err
+	}
+
+	if !mc.apply {
+		mlog.Info("run with --apply to apply the workload identity annotations to the service accounts")
+	} else {
+		for _, m := range mappings {
+			if err = mc.applyServiceAccount(ctx, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintln(mc.out, "\nCreate the federated identity credentials for the managed identities with the following commands:")
+	for _, m := range mappings {
+		fmt.Fprintln(mc.out, getFederatedCredentialCommand(m))
+	}
+
+	return nil
+}
+
+// getIdentityMappings returns the identity mappings for the service accounts used by the pods that are
+// bound to an AzureIdentity. If multiple AzureIdentityBinding resolve to the same service account,
+// the first binding is used since a service account can only be annotated with a single client ID.
+func getIdentityMappings(bindings []aadpodv1.AzureIdentityBinding, labelsToAzureIdentityMap map[string]aadpodv1.AzureIdentity, podsBySelector map[string][]corev1.Pod, issuer string) []identityMapping {
+	bindingNames := make(map[string]string)
+	for _, binding := range bindings {
+		if _, ok := bindingNames[binding.Spec.Selector]; ok {
+			continue
+		}
+		// record the binding that filterAzureIdentities resolved the selector with
+		if azureIdentity, ok := labelsToAzureIdentityMap[binding.Spec.Selector]; ok && azureIdentity.Name == binding.Spec.AzureIdentity {
+			bindingNames[binding.Spec.Selector] = binding.Name
+		}
+	}
+
+	selectors := make([]string, 0, len(labelsToAzureIdentityMap))
+	for selector := range labelsToAzureIdentityMap {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	seen := make(map[string]bool)
+	var mappings []identityMapping
+	for _, selector := range selectors {
+		azureIdentity := labelsToAzureIdentityMap[selector]
+
+		pods := podsBySelector[selector]
+		sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+		for _, pod := range pods {
+			saName := pod.Spec.ServiceAccountName
+			if saName == "" {
+				saName = "default"
+			}
+			key := pod.Namespace + "/" + saName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if saName == "default" {
+				mlog.Warning("pod is using the default service account, consider using a dedicated service account",
+					"namespace", pod.Namespace,
+					"pod", pod.Name,
+				)
+			}
+			mappings = append(mappings, identityMapping{
+				azureIdentityBinding:    bindingNames[selector],
+				azureIdentity:           azureIdentity.Name,
+				clientID:                azureIdentity.Spec.ClientID,
+				tenantID:                azureIdentity.Spec.TenantID,
+				resourceID:              azureIdentity.Spec.ResourceID,
+				serviceAccountNamespace: pod.Namespace,
+				serviceAccountName:      saName,
+				federatedCredentialName: util.FederatedCredentialName(pod.Namespace, saName),
+				issuer:                  issuer,
+				subject:                 util.GetFederatedCredentialSubject(pod.Namespace, saName),
+			})
+		}
+	}
+
+	return mappings
+}
+
+// printMappings prints the identity mappings as a table
+func (mc *migrateCmd) printMappings(mappings []identityMapping) error {
+	w := tabwriter.NewWriter(mc.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "AZURE IDENTITY BINDING\tAZURE IDENTITY\tCLIENT ID\tSERVICE ACCOUNT\tFEDERATED CREDENTIAL SUBJECT")
+	for _, m := range mappings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s/%s\t%s\n", m.azureIdentityBinding, m.azureIdentity, m.clientID, m.serviceAccountNamespace, m.serviceAccountName, m.subject)
+	}
+	return w.Flush()
+}
+
+// applyServiceAccount labels and annotates the service account with the workload identity configuration.
+// The service account is created if it doesn't exist.
+func (mc *migrateCmd) applyServiceAccount(ctx context.Context, m identityMapping) error {
+	annotations := map[string]string{webhook.ClientIDAnnotation: m.clientID}
+	if m.tenantID != "" {
+		annotations[webhook.TenantIDAnnotation] = m.tenantID
+	}
+	logger := mlog.WithValues("namespace", m.serviceAccountNamespace, "name", m.serviceAccountName)
+
+	sa, err := kuberneteshelper.GetServiceAccount(ctx, mc.kubeClient, m.serviceAccountNamespace, m.serviceAccountName)
+	if apierrors.IsNotFound(err) {
+		sa = &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        m.serviceAccountName,
+				Namespace:   m.serviceAccountNamespace,
+				Labels:      map[string]string{webhook.UseWorkloadIdentityLabel: "true"},
+				Annotations: annotations,
+			},
+		}
+		if err = mc.kubeClient.Create(ctx, sa); err != nil {
+			return errors.Wrap(err, "failed to create service account")
+		}
+		logger.Info("created service account")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to get service account")
+	}
+
+	patch := client.MergeFrom(sa.DeepCopy())
+	if sa.Labels == nil {
+		sa.Labels = make(map[string]string)
+	}
+	sa.Labels[webhook.UseWorkloadIdentityLabel] = "true"
+	if sa.Annotations == nil {
+		sa.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		sa.Annotations[k] = v
+	}
+	if err = mc.kubeClient.Patch(ctx, sa, patch); err != nil {
+		return errors.Wrap(err, "failed to patch service account")
+	}
+	logger.Info("updated service account")
+	return nil
+}
+
+// getFederatedCredentialCommand returns the Azure CLI command to create the federated
+// identity credential for the managed identity in the mapping.
+func getFederatedCredentialCommand(m identityMapping) string {
+	resource, err := azure.ParseResourceID(m.resourceID)
+	if err != nil {
+		mlog.Warning("failed to parse managed identity resource ID", "resourceID", m.resourceID, "err", err)
+		return fmt.Sprintf("# %s: invalid managed identity resource ID %q", m.azureIdentity, m.resourceID)
+	}
+	return fmt.Sprintf("az identity federated-credential create --name %s --identity-name %s --resource-group %s --subscription %s --issuer %s --subject %s --audiences %s",
+		m.federatedCredentialName,
+		resource.ResourceName,
+		resource.ResourceGroup,
+		resource.SubscriptionID,
+		m.issuer,
+		m.subject,
+		webhook.DefaultAudience,
+	)
+}
@@ -0,0 +1,294 @@
+package podidentity
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	aadpodv1 "github.com/Azure/aad-pod-identity/pkg/apis/aadpodidentity/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	testIssuerURL  = "https://issuer.example.com/"
+	testResourceID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity-1"
+)
+
+func newAzureIdentityBinding(name, azureIdentity, selector string) aadpodv1.AzureIdentityBinding {
+	return aadpodv1.AzureIdentityBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: aadpodv1.AzureIdentityBindingSpec{
+			AzureIdentity: azureIdentity,
+			Selector:      selector,
+		},
+	}
+}
+
+func newAzureIdentity(name, clientID string) aadpodv1.AzureIdentity {
+	return aadpodv1.AzureIdentity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: aadpodv1.AzureIdentitySpec{
+			Type:       aadpodv1.UserAssignedMSI,
+			ClientID:   clientID,
+			ResourceID: testResourceID,
+		},
+	}
+}
+
+func newBoundPod(name, serviceAccountName, selector string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{aadpodv1.CRDLabelKey: selector},
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccountName,
+		},
+	}
+}
+
+func TestGetIdentityMappings(t *testing.T) {
+	tests := []struct {
+		name                     string
+		bindings                 []aadpodv1.AzureIdentityBinding
+		labelsToAzureIdentityMap map[string]aadpodv1.AzureIdentity
+		podsBySelector           map[string][]corev1.Pod
+		expected                 []identityMapping
+	}{
+		{
+			name:     "no pods using the binding",
+			bindings: []aadpodv1.AzureIdentityBinding{newAzureIdentityBinding("binding-1", "identity-1", "selector-1")},
+			labelsToAzureIdentityMap: map[string]aadpodv1.AzureIdentity{
+				"selector-1": newAzureIdentity("identity-1", "client-id-1"),
+			},
+			expected: nil,
+		},
+		{
+			name:     "multiple pods using the same service account",
+			bindings: []aadpodv1.AzureIdentityBinding{newAzureIdentityBinding("binding-1", "identity-1", "selector-1")},
+			labelsToAzureIdentityMap: map[string]aadpodv1.AzureIdentity{
+				"selector-1": newAzureIdentity("identity-1", "client-id-1"),
+			},
+			podsBySelector: map[string][]corev1.Pod{
+				"selector-1": {
+					newBoundPod("pod-2", "sa-1", "selector-1"),
+					newBoundPod("pod-1", "sa-1", "selector-1"),
+				},
+			},
+			expected: []identityMapping{
+				{
+					azureIdentityBinding:    "binding-1",
+					azureIdentity:           "identity-1",
+					clientID:                "client-id-1",
+					resourceID:              testResourceID,
+					serviceAccountNamespace: "default",
+					serviceAccountName:      "sa-1",
+					federatedCredentialName: "default-sa-1",
+					issuer:                  testIssuerURL,
+					subject:                 "system:serviceaccount:default:sa-1",
+				},
+			},
+		},
+		{
+			name: "pod using the default service account",
+			bindings: []aadpodv1.AzureIdentityBinding{
+				newAzureIdentityBinding("binding-1", "identity-1", "selector-1"),
+			},
+			labelsToAzureIdentityMap: map[string]aadpodv1.AzureIdentity{
+				"selector-1": newAzureIdentity("identity-1", "client-id-1"),
+			},
+			podsBySelector: map[string][]corev1.Pod{
+				"selector-1": {newBoundPod("pod-1", "", "selector-1")},
+			},
+			expected: []identityMapping{
+				{
+					azureIdentityBinding:    "binding-1",
+					azureIdentity:           "identity-1",
+					clientID:                "client-id-1",
+					resourceID:              testResourceID,
+					serviceAccountNamespace: "default",
+					serviceAccountName:      "default",
+					federatedCredentialName: "default-default",
+					issuer:                  testIssuerURL,
+					subject:                 "system:serviceaccount:default:default",
+				},
+			},
+		},
+		{
+			name: "service account used by multiple bindings",
+			bindings: []aadpodv1.AzureIdentityBinding{
+				newAzureIdentityBinding("binding-0", "missing-identity", "selector-1"),
+				newAzureIdentityBinding("binding-1", "identity-1", "selector-1"),
+				newAzureIdentityBinding("binding-2", "identity-2", "selector-2"),
+			},
+			labelsToAzureIdentityMap: map[string]aadpodv1.AzureIdentity{
+				"selector-1": newAzureIdentity("identity-1", "client-id-1"),
+				"selector-2": newAzureIdentity("identity-2", "client-id-2"),
+			},
+			podsBySelector: map[string][]corev1.Pod{
+				"selector-1": {newBoundPod("pod-1", "sa-1", "selector-1")},
+				"selector-2": {
+					newBoundPod("pod-2", "sa-1", "selector-2"),
+					newBoundPod("pod-3", "sa-2", "selector-2"),
+				},
+			},
+			expected: []identityMapping{
+				{
+					azureIdentityBinding:    "binding-1",
+					azureIdentity:           "identity-1",
+					clientID:                "client-id-1",
+					resourceID:              testResourceID,
+					serviceAccountNamespace: "default",
+					serviceAccountName:      "sa-1",
+					federatedCredentialName: "default-sa-1",
+					issuer:                  testIssuerURL,
+					subject:                 "system:serviceaccount:default:sa-1",
+				},
+				{
+					azureIdentityBinding:    "binding-2",
+					azureIdentity:           "identity-2",
+					clientID:                "client-id-2",
+					resourceID:              testResourceID,
+					serviceAccountNamespace: "default",
+					serviceAccountName:      "sa-2",
+					federatedCredentialName: "default-sa-2",
+					issuer:                  testIssuerURL,
+					subject:                 "system:serviceaccount:default:sa-2",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := getIdentityMappings(tt.bindings, tt.labelsToAzureIdentityMap, tt.podsBySelector, testIssuerURL)
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("getIdentityMappings() = %+v, want %+v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetFederatedCredentialCommand(t *testing.T) {
+	m := identityMapping{
+		azureIdentity:           "identity-1",
+		resourceID:              testResourceID,
+		federatedCredentialName: "default-sa-1",
+		issuer:                  testIssuerURL,
+		subject:                 "system:serviceaccount:default:sa-1",
+	}
+	want := "az identity federated-credential create --name default-sa-1 --identity-name identity-1 --resource-group rg --subscription 00000000-0000-0000-0000-000000000000 --issuer https://issuer.example.com/ --subject system:serviceaccount:default:sa-1 --audiences api://AzureADTokenExchange"
+	if got := getFederatedCredentialCommand(m); got != want {
+		t.Errorf("getFederatedCredentialCommand() = %s, want %s", got, want)
+	}
+
+	m.resourceID = "invalid"
+	if got := getFederatedCredentialCommand(m); !strings.HasPrefix(got, "#") {
+		t.Errorf("expected invalid resource ID to be commented out, got %s", got)
+	}
+}
+
+func TestMigrateCmdRun(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	_ = aadpodv1.AddToScheme(s)
+
+	binding := newAzureIdentityBinding("binding-1", "identity-1", "selector-1")
+	identity := newAzureIdentity("identity-1", "client-id-1")
+	pod1 := newBoundPod("pod-1", "sa-existing", "selector-1")
+	pod2 := newBoundPod("pod-2", "sa-new", "selector-1")
+
+	tests := []struct {
+		name  string
+		apply bool
+	}{
+		{
+			name: "dry run",
+		},
+		{
+			name:  "apply",
+			apply: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				&binding,
+				&identity,
+				&pod1,
+				&pod2,
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "sa-existing",
+						Namespace:   "default",
+						Annotations: map[string]string{"foo": "bar"},
+					},
+				},
+			).Build()
+
+			out := &bytes.Buffer{}
+			mc := &migrateCmd{
+				namespace:               "default",
+				serviceAccountIssuerURL: testIssuerURL,
+				apply:                   tt.apply,
+				out:                     out,
+				kubeClient:              kubeClient,
+			}
+			if err := mc.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+
+			for _, want := range []string{"binding-1", "default/sa-existing", "default/sa-new", "--subject system:serviceaccount:default:sa-new"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+
+			existing := &corev1.ServiceAccount{}
+			if err := kubeClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sa-existing"}, existing); err != nil {
+				t.Fatalf("failed to get service account: %v", err)
+			}
+			newSA := &corev1.ServiceAccount{}
+			newSAErr := kubeClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sa-new"}, newSA)
+
+			if !tt.apply {
+				if _, ok := existing.Annotations[webhook.ClientIDAnnotation]; ok {
+					t.Errorf("expected service account to not be annotated in dry run")
+				}
+				if newSAErr == nil {
+					t.Errorf("expected service account to not be created in dry run")
+				}
+				return
+			}
+
+			for _, sa := range []*corev1.ServiceAccount{existing, newSA} {
+				if sa.Annotations[webhook.ClientIDAnnotation] != "client-id-1" {
+					t.Errorf("service account %s client ID annotation = %s, want client-id-1", sa.Name, sa.Annotations[webhook.ClientIDAnnotation])
+				}
+				if sa.Labels[webhook.UseWorkloadIdentityLabel] != "true" {
+					t.Errorf("service account %s is missing the %s label", sa.Name, webhook.UseWorkloadIdentityLabel)
+				}
+			}
+			if existing.Annotations["foo"] != "bar" {
+				t.Errorf("expected existing annotations to be preserved, got %v", existing.Annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package reconcile
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/federatedcredential"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+)
+
+// NewReconcileCmd returns a new reconcile command
+func NewReconcileCmd() *cobra.Command {
+	authProvider := auth.NewProvider()
+	reconcileCmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile the workload identity configuration with a declarative manifest",
+		Long:  "Reconcile the workload identity configuration with a declarative manifest",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return authProvider.Validate(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	// auth flags should be available for all subcommands
+	authProvider.AddFlags(reconcileCmd.PersistentFlags())
+
+	reconcileCmd.AddCommand(federatedcredential.NewReconcileCmd(authProvider))
+
+	return reconcileCmd
+}
@@ -2,25 +2,43 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // import auth plugins. See https://github.com/Azure/azure-workload-identity/issues/362.
 	"monis.app/mlog"
 
+	"github.com/Azure/azure-workload-identity/pkg/cmd/audit"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/doctor"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/export"
 	"github.com/Azure/azure-workload-identity/pkg/cmd/jwks"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/migrate"
 	"github.com/Azure/azure-workload-identity/pkg/cmd/podidentity"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/reconcile"
 	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/verify"
 	"github.com/Azure/azure-workload-identity/pkg/cmd/version"
 )
 
 const (
+	// defaultTimeout is the default timeout of each Azure API operation
+	defaultTimeout = 60 * time.Second
+
+	verbosityFlag = "verbosity"
+	noColorEnvVar = "NO_COLOR"
+
 	rootName             = "azwi"
 	rootShortDescription = "azwi helps to manage workload identity"
 	rootLongDescription  = rootShortDescription + " in Azure."
 )
 
 var (
-	debug bool
+	debug     bool
+	verbosity string
+	noColor   bool
 )
 
 // NewRootCmd returns the root command for Azure Workload Identity.
@@ -31,20 +49,29 @@ func NewRootCmd() *cobra.Command {
 		Short: rootShortDescription,
 		Long:  rootLongDescription,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// default to info instead of warning because existing info logs expect to always be printed
-			logLevel := mlog.LevelInfo
+			logLevel := mlog.LogLevel(verbosity)
 			if debug {
 				logLevel = mlog.LevelAll
 			}
 
-			// inputs are essentially static so this should never error
-			return mlog.ValidateAndSetLogLevelAndFormatGlobally(
+			// respect the NO_COLOR convention, see https://no-color.org
+			// the output of the commands is written to their writers, which strip the colors, and the CLI format
+			// of the logger writes no escape sequences, so the process stderr is never replaced
+			if noColor || os.Getenv(noColorEnvVar) != "" {
+				cmd.SetOut(newNoColorWriter(cmd.OutOrStdout()))
+				cmd.SetErr(newNoColorWriter(cmd.ErrOrStderr()))
+			}
+
+			if err := mlog.ValidateAndSetLogLevelAndFormatGlobally(
 				context.Background(), // context is unused with mlog.FormatCLI
 				mlog.LogSpec{
 					Level:  logLevel,
 					Format: mlog.FormatCLI,
 				},
-			)
+			); err != nil {
+				return errors.Wrapf(err, "invalid --%s %q", verbosityFlag, verbosity)
+			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Usage()
@@ -56,11 +83,22 @@ func NewRootCmd() *cobra.Command {
 
 	p := cmd.PersistentFlags()
 	p.BoolVar(&debug, "debug", false, "Enable debug logging")
+	// default to info instead of warning because existing info logs expect to always be printed
+	p.StringVar(&verbosity, verbosityFlag, string(mlog.LevelInfo), "Log verbosity. In order of increasing verbosity: warning, info, debug, trace and all")
+	p.BoolVar(&noColor, "no-color", false, "Disable colored output. Colored output is also disabled when the NO_COLOR environment variable is set")
+	// the commands with an auth provider pass their flags, which inherit the timeout flag, to the provider
+	p.Duration(auth.TimeoutFlag, defaultTimeout, "Timeout of each Azure API operation. Zero means no timeout")
 
 	cmd.AddCommand(version.NewVersionCmd())
 	cmd.AddCommand(serviceaccount.NewServiceAccountCmd())
 	cmd.AddCommand(jwks.NewJWKSCmd())
 	cmd.AddCommand(podidentity.NewPodIdentityCmd())
+	cmd.AddCommand(verify.NewVerifyCmd())
+	cmd.AddCommand(audit.NewAuditCmd())
+	cmd.AddCommand(migrate.NewMigrateCmd())
+	cmd.AddCommand(export.NewExportCmd())
+	cmd.AddCommand(reconcile.NewReconcileCmd())
+	cmd.AddCommand(doctor.NewDoctorCmd())
 
 	return cmd
 }
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+)
+
+const (
+	coloredText = "\x1b[31mred\x1b[0m text"
+	infoLine    = "info line"
+	debugLine   = "debug line"
+)
+
+// newTestRootCmd returns the root command with a subcommand that writes colored output and logs
+// at the info and debug levels to test the persistent pre-run of the root command.
+func newTestRootCmd(out *bytes.Buffer, args ...string) *cobra.Command {
+	cmd := NewRootCmd()
+	cmd.AddCommand(&cobra.Command{
+		Use: "test",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Print(coloredText)
+			mlog.Info(infoLine)
+			mlog.Debug(debugLine)
+		},
+	})
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs(append([]string{"test"}, args...))
+	return cmd
+}
+
+// executeWithStderr executes the command and returns what was written to os.Stderr, which is where the logs go.
+func executeWithStderr(t *testing.T, cmd *cobra.Command) (string, error) {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer f.Close()
+
+	stderr := os.Stderr
+	os.Stderr = f
+	err = cmd.Execute()
+	os.Stderr = stderr
+
+	b, readErr := os.ReadFile(f.Name())
+	if readErr != nil {
+		t.Fatalf("failed to read stderr: %v", readErr)
+	}
+	return string(b), err
+}
+
+func TestRootCmdVerbosity(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantDebug bool
+		wantErr   bool
+	}{
+		{
+			name: "default verbosity",
+		},
+		{
+			name: "--verbosity warning",
+			args: []string{"--verbosity", "warning"},
+		},
+		{
+			name:      "--verbosity debug",
+			args:      []string{"--verbosity", "debug"},
+			wantDebug: true,
+		},
+		{
+			name:      "--debug",
+			args:      []string{"--debug"},
+			wantDebug: true,
+		},
+		{
+			name:    "invalid --verbosity",
+			args:    []string{"--verbosity", "loud"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, err := executeWithStderr(t, newTestRootCmd(&bytes.Buffer{}, tt.args...))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Execute() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Contains(logs, debugLine); got != tt.wantDebug {
+				t.Errorf("expected debug line to be logged: %t, got logs:\n%s", tt.wantDebug, logs)
+			}
+		})
+	}
+}
+
+func TestRootCmdNoColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		noColor string
+		want    string
+	}{
+		{
+			name: "colored output",
+			want: coloredText,
+		},
+		{
+			name: "--no-color",
+			args: []string{"--no-color"},
+			want: "red text",
+		},
+		{
+			name:    "NO_COLOR environment variable",
+			noColor: "1",
+			want:    "red text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(noColorEnvVar, tt.noColor)
+
+			out := &bytes.Buffer{}
+			logs, err := executeWithStderr(t, newTestRootCmd(out, tt.args...))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+			if !strings.Contains(logs, infoLine) {
+				t.Errorf("expected logs to contain %q, got %q", infoLine, logs)
+			}
+			if tt.want != coloredText && strings.Contains(logs, "\x1b[") {
+				t.Errorf("expected logs without ANSI escape sequences, got %q", logs)
+			}
+		})
+	}
+}
+
+func TestRootCmdNoColorKeepsStderr(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer f.Close()
+
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	var runStderr *os.File
+	cmd := NewRootCmd()
+	cmd.AddCommand(&cobra.Command{
+		Use: "test",
+		Run: func(cmd *cobra.Command, args []string) {
+			runStderr = os.Stderr
+		},
+	})
+	cmd.SetArgs([]string{"test", "--no-color"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// only the writers of the command strip the colors, the process keeps its stderr
+	if runStderr != f {
+		t.Errorf("expected os.Stderr not to be replaced while the command runs")
+	}
+}
@@ -11,8 +11,6 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/google/uuid"
 	nethttplibrary "github.com/microsoft/kiota-http-go"
-	msgrapsdkgo "github.com/microsoftgraph/msgraph-sdk-go"
-	msgraphgocore "github.com/microsoftgraph/msgraph-sdk-go-core"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	ini "gopkg.in/ini.v1"
@@ -35,9 +33,12 @@ type Provider interface {
 	AddFlags(f *pflag.FlagSet)
 	GetAzureClient() cloud.Interface
 	GetAzureTenantID() string
-	Validate() error
+	Validate(flags *pflag.FlagSet) error
 }
 
+// TimeoutFlag is the persistent flag of the root command with the timeout of each Azure API operation.
+const TimeoutFlag = "timeout"
+
 // authArgs is an implementation of the Provider interface
 type authArgs struct {
 	rawAzureEnvironment string
@@ -52,6 +53,8 @@ type authArgs struct {
 	certificatePath string
 	privateKeyPath  string
 	azureClient     cloud.Interface
+	// timeout is the timeout of each operation of the Azure client
+	timeout time.Duration
 
 	client *http.Client
 }
@@ -86,8 +89,8 @@ func defaultTransport() *http.Transport {
 }
 
 func defaultWrap(rt http.RoundTripper) http.RoundTripper {
-	opts := msgrapsdkgo.GetDefaultClientOptions()
-	rt = newMiddlewarePipeline(msgraphgocore.GetDefaultMiddlewaresWithOptions(&opts), rt)
+	// throttled responses are recorded below the retry middleware so that every attempt is counted
+	rt = newMiddlewarePipeline(cloud.NewGraphMiddlewares(), cloud.NewThrottleRecordingTransport(rt))
 	rt = transport.NewUserAgentRoundTripper(rest.DefaultKubernetesUserAgent(), rt)
 	rt = newDelayDebugWrappers(rt)
 	return rt
@@ -156,10 +159,17 @@ func (a *authArgs) GetAzureTenantID() string {
 	return a.tenantID
 }
 
-// Validate validates the authArgs
-func (a *authArgs) Validate() error {
+// Validate validates the authArgs and creates the Azure client. The flags are the flags of the command,
+// which include the --timeout flag inherited from the root command.
+func (a *authArgs) Validate(flags *pflag.FlagSet) error {
 	var err error
 
+	if flags != nil && flags.Lookup(TimeoutFlag) != nil {
+		if a.timeout, err = flags.GetDuration(TimeoutFlag); err != nil {
+			return errors.Wrapf(err, "parsing --%s", TimeoutFlag)
+		}
+	}
+
 	if a.authMethod == "" {
 		return errors.New("--auth-method is a required parameter")
 	}
@@ -201,18 +211,24 @@ func (a *authArgs) Validate() error {
 		return err
 	}
 
+	var azureClient *cloud.AzureClient
 	switch a.authMethod {
 	case cliAuthMethod:
-		a.azureClient, err = cloud.NewAzureClientWithCLI(env, a.subscriptionID.String(), a.tenantID, a.client)
+		azureClient, err = cloud.NewAzureClientWithCLI(env, a.subscriptionID.String(), a.tenantID, a.client)
 	case clientSecretAuthMethod:
-		a.azureClient, err = cloud.NewAzureClientWithClientSecret(env, a.subscriptionID.String(), a.clientID.String(), a.clientSecret, a.tenantID, a.client)
+		azureClient, err = cloud.NewAzureClientWithClientSecret(env, a.subscriptionID.String(), a.clientID.String(), a.clientSecret, a.tenantID, a.client)
 	case clientCertificateAuthMethod:
-		a.azureClient, err = cloud.NewAzureClientWithClientCertificateFile(env, a.subscriptionID.String(), a.clientID.String(), a.tenantID, a.certificatePath, a.privateKeyPath, a.client)
+		azureClient, err = cloud.NewAzureClientWithClientCertificateFile(env, a.subscriptionID.String(), a.clientID.String(), a.tenantID, a.certificatePath, a.privateKeyPath, a.client)
 	default:
 		err = errors.Errorf("--auth-method: ERROR: method unsupported. method=%q", a.authMethod)
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	azureClient.SetDefaultTimeout(a.timeout)
+	a.azureClient = azureClient
+	return nil
 }
 
 // getSubFromAzDir returns the subscription ID from the Azure CLI directory
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
 func TestValidateAuthArgs(t *testing.T) {
@@ -106,7 +112,7 @@ func TestValidateAuthArgs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.authArgs.Validate()
+			err := tt.authArgs.Validate(nil)
 			if tt.wantErr != nil {
 				if err == nil {
 					t.Errorf("validate() = %v, want %v", err, tt.wantErr)
@@ -119,3 +125,65 @@ func TestValidateAuthArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAuthArgsTimeout(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Duration(TimeoutFlag, time.Minute, "")
+	if err := flags.Parse([]string{"--" + TimeoutFlag, "5s"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	// the timeout is read before the auth args are validated
+	a := &authArgs{}
+	if err := a.Validate(flags); err == nil {
+		t.Fatal("expected an error for the missing --auth-method")
+	}
+	if a.timeout != 5*time.Second {
+		t.Errorf("expected timeout %s, got %s", 5*time.Second, a.timeout)
+	}
+}
+
+func TestDefaultClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		wantAttempts int32
+	}{
+		{
+			name:         "get unavailable",
+			method:       http.MethodGet,
+			wantAttempts: 4,
+		},
+		{
+			// the object may have been created, so the request must not be sent again
+			name:         "post unavailable",
+			method:       http.MethodPost,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			resp, err := defaultClient().Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package serviceaccount
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
@@ -29,7 +28,7 @@ func newCreateCmd(authProvider auth.Provider) *cobra.Command {
 	cmd := &cobra.Command{
 		Use: "create",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return createRunner.Run(data)
+			return createRunner.Run(cmd.Context(), data)
 		},
 	}
 
@@ -43,6 +42,7 @@ func newCreateCmd(authProvider auth.Provider) *cobra.Command {
 	f.StringVar(&data.aadApplicationObjectID, options.AADApplicationObjectID.Flag, "", options.AADApplicationObjectID.Description)
 	f.StringVar(&data.servicePrincipalName, options.ServicePrincipalName.Flag, "", options.ServicePrincipalName.Description)
 	f.StringVar(&data.servicePrincipalObjectID, options.ServicePrincipalObjectID.Flag, "", options.ServicePrincipalObjectID.Description)
+	f.StringVar(&data.managedIdentityResourceID, options.ManagedIdentityResourceID.Flag, "", options.ManagedIdentityResourceID.Description)
 	f.StringVar(&data.azureScope, options.AzureScope.Flag, "", options.AzureScope.Description)
 	f.StringVar(&data.azureRole, options.AzureRole.Flag, "", options.AzureRole.Description)
 
@@ -55,6 +55,8 @@ func newCreateCmd(authProvider auth.Provider) *cobra.Command {
 	)
 	createRunner.BindToCommand(cmd, data)
 
+	cmd.AddCommand(newCreateFederatedCredentialCmd(authProvider))
+
 	return cmd
 }
 
@@ -72,6 +74,8 @@ type createData struct {
 	servicePrincipal              models.ServicePrincipalable // cache
 	servicePrincipalObjectID      string
 	servicePrincipalName          string
+	managedIdentity               *cloud.UserAssignedIdentity // cache
+	managedIdentityResourceID     string
 	azureRole                     string
 	azureScope                    string
 	authProvider                  auth.Provider
@@ -118,7 +122,7 @@ func (c *createData) AADApplicationName() string {
 	if name == "" {
 		if c.ServiceAccountNamespace() != "" && c.ServiceAccountName() != "" && c.ServiceAccountIssuerURL() != "" {
 			mlog.Warning("--aad-application-name not specified, constructing name with service account namespace, name, and the hash of the issuer URL")
-			name = fmt.Sprintf("%s-%s-%s", c.ServiceAccountNamespace(), c.serviceAccountName, util.GetIssuerHash(c.ServiceAccountIssuerURL()))
+			name = util.GetAADApplicationName(c.ServiceAccountNamespace(), c.ServiceAccountName(), c.ServiceAccountIssuerURL())
 		}
 	}
 	return name
@@ -130,6 +134,14 @@ func (c *createData) AADApplicationClientID() string {
 	if c.aadApplicationClientID != "" {
 		return c.aadApplicationClientID
 	}
+	if c.ManagedIdentityResourceID() != "" {
+		identity, err := c.ManagedIdentity()
+		if err != nil {
+			mlog.Error("failed to get managed identity client ID. Returning an empty string", err)
+			return ""
+		}
+		return identity.ClientID
+	}
 
 	app, err := c.AADApplication()
 	if err != nil {
@@ -184,6 +196,14 @@ func (c *createData) ServicePrincipalObjectID() string {
 	if c.servicePrincipalObjectID != "" {
 		return c.servicePrincipalObjectID
 	}
+	if c.ManagedIdentityResourceID() != "" {
+		identity, err := c.ManagedIdentity()
+		if err != nil {
+			mlog.Error("failed to get managed identity principal ID. Returning an empty string", err)
+			return ""
+		}
+		return identity.PrincipalID
+	}
 
 	sp, err := c.ServicePrincipal()
 	if err != nil {
@@ -193,6 +213,24 @@ func (c *createData) ServicePrincipalObjectID() string {
 	return *sp.GetId()
 }
 
+// ManagedIdentity returns the user-assigned managed identity.
+// This will return the cached value if it has been fetched.
+func (c *createData) ManagedIdentity() (cloud.UserAssignedIdentity, error) {
+	if c.managedIdentity == nil {
+		identity, err := c.AzureClient().GetUserAssignedIdentity(context.Background(), c.ManagedIdentityResourceID())
+		if err != nil {
+			return cloud.UserAssignedIdentity{}, err
+		}
+		c.managedIdentity = &identity
+	}
+	return *c.managedIdentity, nil
+}
+
+// ManagedIdentityResourceID returns the resource ID of the user-assigned managed identity.
+func (c *createData) ManagedIdentityResourceID() string {
+	return c.managedIdentityResourceID
+}
+
 // AzureRole returns the Azure role.
 func (c *createData) AzureRole() string {
 	return c.azureRole
@@ -204,7 +242,16 @@ func (c *createData) AzureScope() string {
 }
 
 // AzureTenantID returns the Azure tenant ID.
+// This will be the tenant ID of the user-assigned managed identity if it is used.
 func (c *createData) AzureTenantID() string {
+	if c.ManagedIdentityResourceID() != "" {
+		identity, err := c.ManagedIdentity()
+		if err != nil {
+			mlog.Error("failed to get managed identity tenant ID. Returning an empty string", err)
+			return ""
+		}
+		return identity.TenantID
+	}
 	return c.authProvider.GetAzureTenantID()
 }
 
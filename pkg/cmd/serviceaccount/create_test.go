@@ -31,7 +31,7 @@ type mockAuthProvider struct {
 func (m *mockAuthProvider) AddFlags(_ *pflag.FlagSet)       {}
 func (m *mockAuthProvider) GetAzureClient() cloud.Interface { return m.azureClient }
 func (m *mockAuthProvider) GetAzureTenantID() string        { return m.azureTenantID }
-func (m *mockAuthProvider) Validate() error                 { return nil }
+func (m *mockAuthProvider) Validate(_ *pflag.FlagSet) error { return nil }
 
 func TestCreateDataServiceAccountName(t *testing.T) {
 	createData := &createData{
@@ -312,6 +312,38 @@ func TestCreateDataAzureTenantID(t *testing.T) {
 	}
 }
 
+func TestCreateDataManagedIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resourceID := "/subscriptions/subscription-id/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity"
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	// the managed identity is cached after the first lookup
+	mockAzureClient.EXPECT().GetUserAssignedIdentity(gomock.Any(), resourceID).Return(cloud.UserAssignedIdentity{
+		ID:          resourceID,
+		ClientID:    "managed-identity-client-id",
+		TenantID:    "managed-identity-tenant-id",
+		PrincipalID: "managed-identity-principal-id",
+	}, nil).Times(1)
+
+	createData := &createData{
+		managedIdentityResourceID: resourceID,
+		authProvider: &mockAuthProvider{
+			azureClient:   mockAzureClient,
+			azureTenantID: "azure-tenant-id",
+		},
+	}
+	if createData.AADApplicationClientID() != "managed-identity-client-id" {
+		t.Errorf("Expected AADApplicationClientID() to be 'managed-identity-client-id', got %s", createData.AADApplicationClientID())
+	}
+	if createData.AzureTenantID() != "managed-identity-tenant-id" {
+		t.Errorf("Expected AzureTenantID() to be 'managed-identity-tenant-id', got %s", createData.AzureTenantID())
+	}
+	if createData.ServicePrincipalObjectID() != "managed-identity-principal-id" {
+		t.Errorf("Expected ServicePrincipalObjectID() to be 'managed-identity-principal-id', got %s", createData.ServicePrincipalObjectID())
+	}
+}
+
 func testApplication(appID, objectID string) models.Applicationable {
 	app := models.NewApplication()
 	app.SetAppId(to.StringPtr(appID))
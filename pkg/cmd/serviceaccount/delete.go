@@ -2,7 +2,6 @@ package serviceaccount
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	"github.com/spf13/cobra"
@@ -40,7 +39,7 @@ func newDeleteCmd(authProvider auth.Provider) *cobra.Command {
 			if deleteRunner.IsPhaseActive(aadApplicationPhase) {
 				deleteRunner.AppendSkipPhases(federatedIdentityPhase)
 			}
-			return deleteRunner.Run(data)
+			return deleteRunner.Run(cmd.Context(), data)
 		},
 	}
 
@@ -113,7 +112,7 @@ func (d *deleteData) AADApplicationName() string {
 	if name == "" {
 		if d.ServiceAccountNamespace() != "" && d.ServiceAccountName() != "" && d.ServiceAccountIssuerURL() != "" {
 			mlog.Warning("--aad-application-name not specified, constructing name with service account namespace, name, and the hash of the issuer URL")
-			name = fmt.Sprintf("%s-%s-%s", d.ServiceAccountNamespace(), d.serviceAccountName, util.GetIssuerHash(d.ServiceAccountIssuerURL()))
+			name = util.GetAADApplicationName(d.ServiceAccountNamespace(), d.ServiceAccountName(), d.ServiceAccountIssuerURL())
 		}
 	}
 	return name
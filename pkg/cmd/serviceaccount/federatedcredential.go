@@ -0,0 +1,110 @@
+package serviceaccount
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+type createFederatedCredentialCmd struct {
+	namespace              string
+	name                   string
+	issuer                 string
+	aadApplicationName     string
+	aadApplicationObjectID string
+	audiences              []string
+	authProvider           auth.Provider
+}
+
+func newCreateFederatedCredentialCmd(authProvider auth.Provider) *cobra.Command {
+	fcCmd := &createFederatedCredentialCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "federated-credential",
+		Short: "Create the federated identity credential of a service account",
+		Long:  "This command creates a federated identity credential that trusts the tokens of the service account, with the subject built from the namespace and name of the service account and a name derived from them",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return fcCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fcCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&fcCmd.namespace, "namespace", "default", options.ServiceAccountNamespace.Description)
+	f.StringVar(&fcCmd.name, "name", "", options.ServiceAccountName.Description)
+	f.StringVar(&fcCmd.issuer, "issuer", "", options.ServiceAccountIssuerURL.Description)
+	f.StringVar(&fcCmd.aadApplicationName, options.AADApplicationName.Flag, "", options.AADApplicationName.Description)
+	f.StringVar(&fcCmd.aadApplicationObjectID, options.AADApplicationObjectID.Flag, "", options.AADApplicationObjectID.Description)
+	f.StringSliceVar(&fcCmd.audiences, options.FederatedCredentialAudience.Flag, nil, options.FederatedCredentialAudience.Description)
+
+	return cmd
+}
+
+func (fc *createFederatedCredentialCmd) prerun() error {
+	if fc.namespace == "" {
+		return options.FlagIsRequiredError("namespace")
+	}
+	if fc.name == "" {
+		return options.FlagIsRequiredError("name")
+	}
+	if fc.issuer == "" {
+		return options.FlagIsRequiredError("issuer")
+	}
+	return nil
+}
+
+func (fc *createFederatedCredentialCmd) run(ctx context.Context) error {
+	azureClient := fc.authProvider.GetAzureClient()
+
+	objectID := fc.aadApplicationObjectID
+	if objectID == "" {
+		appName := fc.aadApplicationName
+		if appName == "" {
+			appName = util.GetAADApplicationName(fc.namespace, fc.name, fc.issuer)
+		}
+		app, err := azureClient.GetApplication(ctx, appName)
+		if err != nil {
+			return errors.Wrap(err, "failed to get AAD application")
+		}
+		objectID = to.String(app.GetId())
+	}
+
+	subject := util.GetFederatedCredentialSubject(fc.namespace, fc.name)
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr(util.FederatedCredentialName(fc.namespace, fc.name)))
+	fic.SetIssuer(to.StringPtr(fc.issuer))
+	fic.SetSubject(to.StringPtr(subject))
+	audiences := fc.audiences
+	if len(audiences) == 0 {
+		audiences = []string{webhook.DefaultAudience}
+	}
+	fic.SetAudiences(audiences)
+	fic.SetDescription(to.StringPtr(fmt.Sprintf("Federated Service Account for %s/%s", fc.namespace, fc.name)))
+
+	logger := mlog.WithValues("objectID", objectID, "subject", subject)
+	if _, err := azureClient.AddFederatedCredential(ctx, objectID, fic); err != nil {
+		if !cloud.IsFederatedCredentialAlreadyExists(err) {
+			return errors.Wrap(err, "failed to add federated credential")
+		}
+		logger.Info("federated credential has been previously created")
+		return nil
+	}
+	logger.Info("added federated credential")
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package serviceaccount
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+func TestCreateFederatedCredentialCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		fcCmd    *createFederatedCredentialCmd
+		errorMsg string
+	}{
+		{
+			name:     "missing --namespace",
+			fcCmd:    &createFederatedCredentialCmd{name: serviceAccountName, issuer: serviceAccountIssuerURL},
+			errorMsg: "--namespace is required",
+		},
+		{
+			name:     "missing --name",
+			fcCmd:    &createFederatedCredentialCmd{namespace: serviceAccountNamespace, issuer: serviceAccountIssuerURL},
+			errorMsg: "--name is required",
+		},
+		{
+			name:     "missing --issuer",
+			fcCmd:    &createFederatedCredentialCmd{namespace: serviceAccountNamespace, name: serviceAccountName},
+			errorMsg: "--issuer is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.fcCmd.prerun()
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("prerun() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+}
+
+func TestCreateFederatedCredentialCmdRun(t *testing.T) {
+	tests := []struct {
+		name               string
+		aadApplicationName string
+		objectID           string
+		audiences          []string
+		wantAudiences      []string
+		expect             func(m *mock_cloud.MockInterfaceMockRecorder)
+	}{
+		{
+			name:          "object ID",
+			objectID:      objectID,
+			wantAudiences: []string{webhook.DefaultAudience},
+		},
+		{
+			name:          "custom audiences",
+			objectID:      objectID,
+			audiences:     []string{"api://custom"},
+			wantAudiences: []string{"api://custom"},
+		},
+		{
+			name:               "AAD application name",
+			aadApplicationName: appName,
+			wantAudiences:      []string{webhook.DefaultAudience},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetApplication(gomock.Any(), appName).Return(testApplication(appID, objectID), nil)
+			},
+		},
+		{
+			name:          "default AAD application name",
+			wantAudiences: []string{webhook.DefaultAudience},
+			expect: func(m *mock_cloud.MockInterfaceMockRecorder) {
+				m.GetApplication(gomock.Any(), util.GetAADApplicationName(serviceAccountNamespace, serviceAccountName, serviceAccountIssuerURL)).Return(testApplication(appID, objectID), nil)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			if test.expect != nil {
+				test.expect(mockAzureClient.EXPECT())
+			}
+			mockAzureClient.EXPECT().AddFederatedCredential(gomock.Any(), objectID, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, fic models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+				if got, want := to.String(fic.GetSubject()), "system:serviceaccount:service-account-namespace:service-account-name"; got != want {
+					t.Errorf("subject = %q, want %q", got, want)
+				}
+				if got, want := to.String(fic.GetName()), "service-account-namespace-service-account-name"; got != want {
+					t.Errorf("name = %q, want %q", got, want)
+				}
+				if got := to.String(fic.GetIssuer()); got != serviceAccountIssuerURL {
+					t.Errorf("issuer = %q, want %q", got, serviceAccountIssuerURL)
+				}
+				if got := fic.GetAudiences(); !reflect.DeepEqual(got, test.wantAudiences) {
+					t.Errorf("audiences = %v, want %v", got, test.wantAudiences)
+				}
+				return fic, nil
+			})
+
+			fc := &createFederatedCredentialCmd{
+				namespace:              serviceAccountNamespace,
+				name:                   serviceAccountName,
+				issuer:                 serviceAccountIssuerURL,
+				aadApplicationName:     test.aadApplicationName,
+				aadApplicationObjectID: test.objectID,
+				audiences:              test.audiences,
+				authProvider:           &mockAuthProvider{azureClient: mockAzureClient},
+			}
+			if err := fc.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+		})
+	}
+}
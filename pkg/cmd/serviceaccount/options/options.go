@@ -51,6 +51,11 @@ var (
 		Flag:        "service-principal-object-id",
 		Description: "Object ID of the service principal that backs the AAD application. If not specified, it will be fetched using the service principal name",
 	}
+	// ManagedIdentityResourceID flag sets the resource ID of the user-assigned managed identity
+	ManagedIdentityResourceID = option{
+		Flag:        "from-managed-identity",
+		Description: "Resource ID of an existing user-assigned managed identity to use instead of an AAD application. The service account is annotated with the client ID and tenant ID of the managed identity and the federated identity credential is created on the managed identity",
+	}
 	// AzureScope flag sets the Azure scope
 	AzureScope = option{
 		Flag:        "azure-scope",
@@ -66,4 +71,14 @@ var (
 		Flag:        "role-assignment-id",
 		Description: "Azure role assignment ID",
 	}
+	// FederatedCredentialAudience flag sets the audiences of the federated identity credential
+	FederatedCredentialAudience = option{
+		Flag:        "audience",
+		Description: "Audiences of the federated identity credential. Defaults to api://AzureADTokenExchange",
+	}
+	// DryRun flag previews the changes without applying them
+	DryRun = option{
+		Flag:        "dry-run",
+		Description: "Print the changes that would be made without applying them",
+	}
 )
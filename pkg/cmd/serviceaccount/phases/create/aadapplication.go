@@ -29,7 +29,7 @@ func NewAADApplicationPhase() workflow.Phase {
 		Description: "Create Azure Active Directory (AAD) application and its underlying service principal",
 		PreRun:      p.prerun,
 		Run:         p.run,
-		Flags:       []string{options.AADApplicationName.Flag},
+		Flags:       []string{options.AADApplicationName.Flag, options.ManagedIdentityResourceID.Flag},
 	}
 }
 
@@ -39,6 +39,10 @@ func (p *aadApplicationPhase) prerun(data workflow.RunData) error {
 		return errors.Errorf("invalid data type %T", data)
 	}
 
+	// the user-assigned managed identity is used in place of the AAD application
+	if createData.ManagedIdentityResourceID() != "" {
+		return nil
+	}
 	if createData.AADApplicationName() == "" {
 		return options.FlagIsRequiredError(options.AADApplicationName.Flag)
 	}
@@ -49,6 +53,13 @@ func (p *aadApplicationPhase) prerun(data workflow.RunData) error {
 func (p *aadApplicationPhase) run(ctx context.Context, data workflow.RunData) error {
 	createData := data.(CreateData)
 
+	if resourceID := createData.ManagedIdentityResourceID(); resourceID != "" {
+		mlog.WithValues(
+			"resourceID", resourceID,
+		).WithName(aadApplicationPhaseName).Info("using user-assigned managed identity, skipping AAD application creation")
+		return nil
+	}
+
 	// Check if the application with the same name already exists
 	var err error
 	app, err := createData.AADApplication()
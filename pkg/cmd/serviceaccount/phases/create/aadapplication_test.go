@@ -42,6 +42,11 @@ func TestAADApplicationPreRun(t *testing.T) {
 			phase: NewAADApplicationPhase(),
 			data:  &mockCreateData{serviceAccountNamespace: "test", serviceAccountName: "test", serviceAccountIssuerURL: "test"},
 		},
+		{
+			name:  "valid data with --from-managed-identity",
+			phase: NewAADApplicationPhase(),
+			data:  &mockCreateData{managedIdentityResourceID: "managed-identity-resource-id"},
+		},
 	}
 
 	for _, test := range tests {
@@ -80,6 +85,20 @@ func TestAADApplicationRun(t *testing.T) {
 	}
 }
 
+func TestAADApplicationRunWithManagedIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no AAD application or service principal is created for a managed identity
+	data := &mockCreateData{
+		managedIdentityResourceID: "managed-identity-resource-id",
+		azureClient:               mock_cloud.NewMockInterface(ctrl),
+	}
+	if err := NewAADApplicationPhase().Run(context.Background(), data); err != nil {
+		t.Errorf("expected no error but got: %s", err.Error())
+	}
+}
+
 func testApplication(appID, objectID, displayName string) models.Applicationable {
 	app := models.NewApplication()
 	app.SetAppId(to.StringPtr(appID))
@@ -50,6 +50,10 @@ type CreateData interface {
 	// This will be used for creating or removing the role assignment.
 	ServicePrincipalObjectID() string
 
+	// ManagedIdentityResourceID returns the resource ID of the user-assigned managed identity.
+	// If set, the managed identity is used in place of the AAD application.
+	ManagedIdentityResourceID() string
+
 	// AzureRole returns the Azure role.
 	AzureRole() string
 
@@ -24,6 +24,7 @@ type mockCreateData struct {
 	servicePrincipal              models.ServicePrincipalable
 	servicePrincipalObjectID      string
 	servicePrincipalName          string
+	managedIdentityResourceID     string
 	azureRole                     string
 	azureScope                    string
 	azureTenantID                 string
@@ -89,6 +90,10 @@ func (c *mockCreateData) ServicePrincipalObjectID() string {
 	return c.servicePrincipalObjectID
 }
 
+func (c *mockCreateData) ManagedIdentityResourceID() string {
+	return c.managedIdentityResourceID
+}
+
 func (c *mockCreateData) AzureRole() string {
 	return c.azureRole
 }
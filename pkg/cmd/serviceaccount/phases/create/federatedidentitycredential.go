@@ -3,6 +3,7 @@ package phases
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
@@ -18,6 +19,9 @@ import (
 
 const (
 	federatedIdentityPhaseName = "federated-identity"
+
+	// federatedCredentialPropagationTimeout is how long to wait for a new federated credential to propagate in Azure AD
+	federatedCredentialPropagationTimeout = 2 * time.Minute
 )
 
 type federatedIdentityPhase struct {
@@ -38,6 +42,7 @@ func NewFederatedIdentityPhase() workflow.Phase {
 			options.ServiceAccountIssuerURL.Flag,
 			options.AADApplicationName.Flag,
 			options.AADApplicationObjectID.Flag,
+			options.ManagedIdentityResourceID.Flag,
 		},
 	}
 }
@@ -70,6 +75,26 @@ func (p *federatedIdentityPhase) run(ctx context.Context, data workflow.RunData)
 	description := fmt.Sprintf("Federated Service Account for %s/%s", serviceAccountNamespace, serviceAccountName)
 	audiences := []string{webhook.DefaultAudience}
 
+	if resourceID := createData.ManagedIdentityResourceID(); resourceID != "" {
+		// federated identity credentials of managed identities are created or updated in place
+		// and their names are restricted to alphanumeric characters, hyphens and underscores
+		fic := cloud.FederatedCredential{
+			Name:      util.FederatedCredentialName(serviceAccountNamespace, serviceAccountName),
+			Issuer:    createData.ServiceAccountIssuerURL(),
+			Subject:   subject,
+			Audiences: audiences,
+		}
+		if err := createData.AzureClient().AddManagedIdentityFederatedCredential(ctx, resourceID, fic); err != nil {
+			return errors.Wrap(err, "failed to add federated credential to managed identity")
+		}
+
+		mlog.WithValues(
+			"resourceID", resourceID,
+			"subject", subject,
+		).WithName(federatedIdentityPhaseName).Info("added federated credential to managed identity")
+		return nil
+	}
+
 	objectID := createData.AADApplicationObjectID()
 	fic := models.NewFederatedIdentityCredential()
 	fic.SetAudiences(audiences)
@@ -78,7 +103,7 @@ func (p *federatedIdentityPhase) run(ctx context.Context, data workflow.RunData)
 	fic.SetSubject(to.StringPtr(subject))
 	fic.SetName(to.StringPtr(name))
 
-	err := createData.AzureClient().AddFederatedCredential(ctx, objectID, fic)
+	_, err := createData.AzureClient().AddFederatedCredential(ctx, objectID, fic)
 	if err != nil {
 		if cloud.IsFederatedCredentialAlreadyExists(err) {
 			mlog.WithValues(
@@ -88,6 +113,9 @@ func (p *federatedIdentityPhase) run(ctx context.Context, data workflow.RunData)
 		} else {
 			return errors.Wrap(err, "failed to add federated credential")
 		}
+	} else if err = createData.AzureClient().WaitForFederatedCredential(ctx, objectID, name, federatedCredentialPropagationTimeout); err != nil {
+		// a token exchange fails until the federated credential has propagated
+		return errors.Wrap(err, "failed to wait for federated credential to propagate")
 	}
 
 	mlog.WithValues(
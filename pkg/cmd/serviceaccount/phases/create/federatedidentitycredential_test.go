@@ -8,6 +8,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/golang/mock/gomock"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
 
 	"github.com/Azure/azure-workload-identity/pkg/cloud"
 	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
@@ -84,7 +85,8 @@ func TestFederatedIdentityRun(t *testing.T) {
 	fic.SetName(to.StringPtr(util.GetFederatedCredentialName(data.serviceAccountNamespace, data.serviceAccountName, data.serviceAccountIssuerURL)))
 
 	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
-	mockAzureClient.EXPECT().AddFederatedCredential(gomock.Any(), "aad-application-object-id", fic).Return(nil)
+	mockAzureClient.EXPECT().AddFederatedCredential(gomock.Any(), "aad-application-object-id", fic).Return(fic, nil)
+	mockAzureClient.EXPECT().WaitForFederatedCredential(gomock.Any(), "aad-application-object-id", to.String(fic.GetName()), federatedCredentialPropagationTimeout).Return(nil)
 	data.azureClient = mockAzureClient
 
 	err := phase.Run(context.Background(), data)
@@ -96,9 +98,42 @@ func TestFederatedIdentityRun(t *testing.T) {
 	graphError := cloud.GraphError{PublicError: models.NewPublicError()}
 	graphError.PublicError.SetCode(to.StringPtr(cloud.GraphErrorCodeMultipleObjectsWithSameKeyValue))
 	graphError.PublicError.SetMessage(to.StringPtr("FederatedIdentityCredential with name federatedcredential-from-azwi-cli already exists."))
-	mockAzureClient.EXPECT().AddFederatedCredential(gomock.Any(), "aad-application-object-id", gomock.Any()).Return(graphError)
+	mockAzureClient.EXPECT().AddFederatedCredential(gomock.Any(), "aad-application-object-id", gomock.Any()).Return(nil, graphError)
 	err = phase.Run(context.Background(), data)
 	if err != nil {
 		t.Errorf("expected no error but got: %s", err.Error())
 	}
 }
+
+func TestFederatedIdentityRunWithManagedIdentity(t *testing.T) {
+	phase := NewFederatedIdentityPhase()
+	data := &mockCreateData{
+		serviceAccountNamespace:   "service-account-namespace",
+		serviceAccountName:        "service-account-name",
+		serviceAccountIssuerURL:   "service-account-issuer-url",
+		managedIdentityResourceID: "managed-identity-resource-id",
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fic := cloud.FederatedCredential{
+		Name:      "service-account-namespace-service-account-name",
+		Issuer:    data.serviceAccountIssuerURL,
+		Subject:   util.GetFederatedCredentialSubject(data.serviceAccountNamespace, data.serviceAccountName),
+		Audiences: []string{webhook.DefaultAudience},
+	}
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().AddManagedIdentityFederatedCredential(gomock.Any(), "managed-identity-resource-id", fic).Return(nil)
+	data.azureClient = mockAzureClient
+
+	if err := phase.Run(context.Background(), data); err != nil {
+		t.Errorf("expected no error but got: %s", err.Error())
+	}
+
+	mockAzureClient.EXPECT().AddManagedIdentityFederatedCredential(gomock.Any(), "managed-identity-resource-id", fic).Return(errors.New("random error"))
+	if err := phase.Run(context.Background(), data); err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
@@ -3,6 +3,7 @@ package phases
 import (
 	"context"
 
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pkg/errors"
 	"monis.app/mlog"
 
@@ -32,6 +33,7 @@ func NewRoleAssignmentPhase() workflow.Phase {
 			options.AzureRole.Flag,
 			options.ServicePrincipalName.Flag,
 			options.ServicePrincipalObjectID.Flag,
+			options.ManagedIdentityResourceID.Flag,
 		},
 	}
 }
@@ -48,6 +50,10 @@ func (p *roleAssignmentPhase) prerun(data workflow.RunData) error {
 	if createData.AzureRole() == "" {
 		return options.FlagIsRequiredError(options.AzureRole.Flag)
 	}
+	// the role is assigned to the service principal of the user-assigned managed identity
+	if createData.ManagedIdentityResourceID() != "" {
+		return nil
+	}
 	if createData.ServicePrincipalName() == "" && createData.ServicePrincipalObjectID() == "" {
 		return options.OneOfFlagsIsRequiredError(options.ServicePrincipalName.Flag, options.ServicePrincipalObjectID.Flag)
 	}
@@ -59,25 +65,26 @@ func (p *roleAssignmentPhase) run(ctx context.Context, data workflow.RunData) er
 	createData := data.(CreateData)
 
 	// create the role assignment using object id of the service principal
-	ra, err := createData.AzureClient().CreateRoleAssignment(ctx, createData.AzureScope(), createData.AzureRole(), createData.ServicePrincipalObjectID())
+	servicePrincipalObjectID := createData.ServicePrincipalObjectID()
+	ra, err := createData.AzureClient().CreateRoleAssignment(ctx, createData.AzureScope(), createData.AzureRole(), servicePrincipalObjectID)
 	if err != nil {
-		if cloud.IsAlreadyExists(err) {
-			mlog.WithValues(
-				"scope", createData.AzureScope(),
-				"role", createData.AzureRole(),
-				"servicePrincipalObjectID", createData.ServicePrincipalObjectID(),
-				"roleAssignmentID", ra.ID,
-			).WithName(roleAssignmentPhaseName).Debug("role assignment has previously been created")
-		} else {
+		if !cloud.IsAlreadyExists(err) {
 			return errors.Wrap(err, "failed to create role assignment")
 		}
+		// the role assignment is idempotent, an existing role assignment is a success
+		mlog.WithValues(
+			"scope", createData.AzureScope(),
+			"role", createData.AzureRole(),
+			"servicePrincipalObjectID", servicePrincipalObjectID,
+		).WithName(roleAssignmentPhaseName).Info("role assignment has previously been created")
+		return nil
 	}
 
 	mlog.WithValues(
 		"scope", createData.AzureScope(),
 		"role", createData.AzureRole(),
-		"servicePrincipalObjectID", createData.ServicePrincipalObjectID(),
-		"roleAssignmentID", ra.ID,
+		"servicePrincipalObjectID", servicePrincipalObjectID,
+		"roleAssignmentID", to.String(ra.ID),
 	).WithName(roleAssignmentPhaseName).Info("created role assignment")
 
 	return nil
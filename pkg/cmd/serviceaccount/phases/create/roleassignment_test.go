@@ -61,6 +61,10 @@ func TestRoleAssignmentPreRun(t *testing.T) {
 			phase: NewAADApplicationPhase(),
 			data:  &mockCreateData{azureScope: "test", azureRole: "test", aadApplicationName: "test"},
 		},
+		{
+			name: "valid data with --from-managed-identity",
+			data: &mockCreateData{azureScope: "test", azureRole: "test", managedIdentityResourceID: "test"},
+		},
 	}
 
 	for _, test := range tests {
@@ -105,4 +109,10 @@ func TestRoleAssignmentRun(t *testing.T) {
 	if err := phase.Run(context.Background(), data); err != nil {
 		t.Errorf("expected no error but got: %s", err.Error())
 	}
+
+	// Test for scenario where the role assignment fails to be created
+	mockAzureClient.EXPECT().CreateRoleAssignment(context.Background(), data.azureScope, data.azureRole, data.servicePrincipalObjectID).Return(authorization.RoleAssignment{}, autorest.DetailedError{StatusCode: http.StatusForbidden})
+	if err := phase.Run(context.Background(), data); err == nil {
+		t.Errorf("expected error but got nil")
+	}
 }
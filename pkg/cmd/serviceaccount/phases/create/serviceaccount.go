@@ -37,6 +37,7 @@ func NewServiceAccountPhase() workflow.Phase {
 			options.ServiceAccountTokenExpiration.Flag,
 			options.AADApplicationName.Flag,
 			options.AADApplicationClientID.Flag,
+			options.ManagedIdentityResourceID.Flag,
 		},
 	}
 }
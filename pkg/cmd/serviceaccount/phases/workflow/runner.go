@@ -29,7 +29,7 @@ type Runner interface {
 	BindToCommand(cmd *cobra.Command, data RunData)
 
 	// Run runs the phases except the ones specified in skipPhases
-	Run(data RunData) error
+	Run(ctx context.Context, data RunData) error
 }
 
 // runner is the default implementation of the Runner interface
@@ -110,7 +110,7 @@ func (r *runner) BindToCommand(cmd *cobra.Command, data RunData) {
 			RunE: func(c *cobra.Command, args []string) error {
 				// only run this particular phase
 				r.phases = []Phase{p}
-				return r.Run(data)
+				return r.Run(c.Context(), data)
 			},
 		}
 		inheritsFlags(cmd.Flags(), subcommand.Flags(), p.Flags)
@@ -121,7 +121,7 @@ func (r *runner) BindToCommand(cmd *cobra.Command, data RunData) {
 }
 
 // Run runs the phases except the ones specified in skipPhases
-func (r *runner) Run(data RunData) error {
+func (r *runner) Run(ctx context.Context, data RunData) error {
 	skipPhases, err := r.computeSkipPhases()
 	if err != nil {
 		return errors.Wrap(err, "failed to compute skip phases")
@@ -144,7 +144,7 @@ func (r *runner) Run(data RunData) error {
 	}
 
 	for _, phase := range filtered {
-		if err := phase.Run(context.Background(), data); err != nil {
+		if err := phase.Run(ctx, data); err != nil {
 			return errors.Wrapf(err, "failed to run phase %s", phase.Name)
 		}
 	}
@@ -135,7 +135,7 @@ func TestRun(t *testing.T) {
 		skipPhases: []string{"phase-3"},
 	}
 
-	if err := r.Run(nil); err != nil {
+	if err := r.Run(context.Background(), nil); err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 }
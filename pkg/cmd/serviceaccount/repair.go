@@ -0,0 +1,132 @@
+package serviceaccount
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/auth"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+type repairCmd struct {
+	serviceAccountName      string
+	serviceAccountNamespace string
+	serviceAccountIssuerURL string
+	aadApplicationName      string
+	dryRun                  bool
+	authProvider            auth.Provider
+	kubeClient              client.Client
+}
+
+func newRepairCmd(authProvider auth.Provider) *cobra.Command {
+	repairCmd := &repairCmd{
+		authProvider: authProvider,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repair the workload identity annotations of a service account",
+		Long:  "This command resolves the AAD application backing the service account and patches the client ID and tenant ID annotations of the service account to match",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return repairCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return repairCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&repairCmd.serviceAccountName, options.ServiceAccountName.Flag, "", options.ServiceAccountName.Description)
+	f.StringVar(&repairCmd.serviceAccountNamespace, options.ServiceAccountNamespace.Flag, "default", options.ServiceAccountNamespace.Description)
+	f.StringVar(&repairCmd.serviceAccountIssuerURL, options.ServiceAccountIssuerURL.Flag, "", options.ServiceAccountIssuerURL.Description)
+	f.StringVar(&repairCmd.aadApplicationName, options.AADApplicationName.Flag, "", options.AADApplicationName.Description)
+	f.BoolVar(&repairCmd.dryRun, options.DryRun.Flag, false, options.DryRun.Description)
+
+	return cmd
+}
+
+func (rc *repairCmd) prerun() error {
+	if rc.serviceAccountName == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountName.Flag)
+	}
+	if rc.serviceAccountNamespace == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountNamespace.Flag)
+	}
+	if rc.appName() == "" {
+		return options.OneOfFlagsIsRequiredError(options.AADApplicationName.Flag, options.ServiceAccountIssuerURL.Flag)
+	}
+
+	var err error
+	if rc.kubeClient, err = kuberneteshelper.GetKubeClient(); err != nil {
+		return errors.Wrap(err, "failed to get kubernetes client")
+	}
+
+	return nil
+}
+
+func (rc *repairCmd) run(ctx context.Context) error {
+	logger := mlog.WithValues("namespace", rc.serviceAccountNamespace, "name", rc.serviceAccountName)
+
+	app, err := rc.authProvider.GetAzureClient().GetApplication(ctx, rc.appName())
+	if err != nil {
+		return errors.Wrap(err, "failed to get AAD application")
+	}
+	appID := to.String(app.GetAppId())
+	if appID == "" {
+		return errors.Errorf("AAD application %s has no app ID", rc.appName())
+	}
+
+	sa, err := kuberneteshelper.GetServiceAccount(ctx, rc.kubeClient, rc.serviceAccountNamespace, rc.serviceAccountName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get kubernetes service account")
+	}
+
+	want := map[string]string{
+		webhook.ClientIDAnnotation: appID,
+		webhook.TenantIDAnnotation: rc.authProvider.GetAzureTenantID(),
+	}
+	drift := make(map[string]string)
+	for k, v := range want {
+		if sa.Annotations[k] != v {
+			drift[k] = v
+		}
+	}
+	if len(drift) == 0 {
+		logger.Info("service account annotations are already in sync")
+		return nil
+	}
+
+	for k, v := range drift {
+		logger.Info("service account annotation out of sync", "annotation", k, "current", sa.Annotations[k], "desired", v)
+	}
+	if rc.dryRun {
+		logger.Info("dry run, skipping patching the service account")
+		return nil
+	}
+
+	if err = kuberneteshelper.PatchServiceAccountAnnotations(ctx, rc.kubeClient, sa, drift); err != nil {
+		return errors.Wrap(err, "failed to patch kubernetes service account")
+	}
+	logger.Info("repaired service account annotations")
+
+	return nil
+}
+
+// appName returns the name of the AAD application backing the service account.
+func (rc *repairCmd) appName() string {
+	if rc.aadApplicationName != "" {
+		return rc.aadApplicationName
+	}
+	if rc.serviceAccountIssuerURL == "" {
+		return ""
+	}
+	return util.GetAADApplicationName(rc.serviceAccountNamespace, rc.serviceAccountName, rc.serviceAccountIssuerURL)
+}
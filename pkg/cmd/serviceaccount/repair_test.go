@@ -0,0 +1,191 @@
+package serviceaccount
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud/mock_cloud"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+func TestRepairCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		repairCmd *repairCmd
+		errorMsg  string
+	}{
+		{
+			name:      "missing --service-account-name",
+			repairCmd: &repairCmd{serviceAccountNamespace: serviceAccountNamespace},
+			errorMsg:  "--service-account-name is required",
+		},
+		{
+			name:      "missing --service-account-namespace",
+			repairCmd: &repairCmd{serviceAccountName: serviceAccountName},
+			errorMsg:  "--service-account-namespace is required",
+		},
+		{
+			name:      "missing --aad-application-name and --service-account-issuer-url",
+			repairCmd: &repairCmd{serviceAccountName: serviceAccountName, serviceAccountNamespace: serviceAccountNamespace},
+			errorMsg:  "--aad-application-name or --service-account-issuer-url is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.repairCmd.prerun()
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("prerun() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+}
+
+func TestRepairCmdRun(t *testing.T) {
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		dryRun              bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:        "annotations deleted",
+			annotations: nil,
+			expectedAnnotations: map[string]string{
+				webhook.ClientIDAnnotation: appID,
+				webhook.TenantIDAnnotation: "tenant-id",
+			},
+		},
+		{
+			name: "client id drifted",
+			annotations: map[string]string{
+				webhook.ClientIDAnnotation: "stale-client-id",
+				webhook.TenantIDAnnotation: "tenant-id",
+				"foo":                      "bar",
+			},
+			expectedAnnotations: map[string]string{
+				webhook.ClientIDAnnotation: appID,
+				webhook.TenantIDAnnotation: "tenant-id",
+				"foo":                      "bar",
+			},
+		},
+		{
+			name: "dry run",
+			annotations: map[string]string{
+				webhook.ClientIDAnnotation: "stale-client-id",
+			},
+			dryRun: true,
+			expectedAnnotations: map[string]string{
+				webhook.ClientIDAnnotation: "stale-client-id",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			application := models.NewApplication()
+			application.SetAppId(to.StringPtr(appID))
+
+			mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+			mockAzureClient.EXPECT().GetApplication(gomock.Any(), appName).Return(application, nil)
+
+			kubeClient := fake.NewClientBuilder().WithObjects(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        serviceAccountName,
+					Namespace:   serviceAccountNamespace,
+					Annotations: test.annotations,
+				},
+			}).Build()
+
+			rc := &repairCmd{
+				serviceAccountName:      serviceAccountName,
+				serviceAccountNamespace: serviceAccountNamespace,
+				aadApplicationName:      appName,
+				dryRun:                  test.dryRun,
+				authProvider:            &mockAuthProvider{azureClient: mockAzureClient, azureTenantID: "tenant-id"},
+				kubeClient:              kubeClient,
+			}
+			if err := rc.run(context.Background()); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+
+			sa := &corev1.ServiceAccount{}
+			if err := kubeClient.Get(context.Background(), types.NamespacedName{Name: serviceAccountName, Namespace: serviceAccountNamespace}, sa); err != nil {
+				t.Fatalf("failed to get service account: %v", err)
+			}
+			for k, v := range test.expectedAnnotations {
+				if sa.Annotations[k] != v {
+					t.Errorf("annotation %s = %q, want %q", k, sa.Annotations[k], v)
+				}
+			}
+			if len(sa.Annotations) != len(test.expectedAnnotations) {
+				t.Errorf("annotations = %v, want %v", sa.Annotations, test.expectedAnnotations)
+			}
+		})
+	}
+}
+
+func TestRepairCmdRunApplicationNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplication(gomock.Any(), appName).Return(nil, errors.New("application not found"))
+
+	rc := &repairCmd{
+		serviceAccountName:      serviceAccountName,
+		serviceAccountNamespace: serviceAccountNamespace,
+		aadApplicationName:      appName,
+		authProvider:            &mockAuthProvider{azureClient: mockAzureClient},
+		kubeClient:              fake.NewClientBuilder().Build(),
+	}
+	if err := rc.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+}
+
+func TestRepairCmdRunApplicationWithoutAppID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAzureClient := mock_cloud.NewMockInterface(ctrl)
+	mockAzureClient.EXPECT().GetApplication(gomock.Any(), appName).Return(models.NewApplication(), nil)
+
+	kubeClient := fake.NewClientBuilder().WithObjects(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccountName,
+			Namespace: serviceAccountNamespace,
+		},
+	}).Build()
+
+	rc := &repairCmd{
+		serviceAccountName:      serviceAccountName,
+		serviceAccountNamespace: serviceAccountNamespace,
+		aadApplicationName:      appName,
+		authProvider:            &mockAuthProvider{azureClient: mockAzureClient, azureTenantID: "tenant-id"},
+		kubeClient:              kubeClient,
+	}
+	if err := rc.run(context.Background()); err == nil {
+		t.Errorf("run() error = nil, want error")
+	}
+
+	sa := &corev1.ServiceAccount{}
+	if err := kubeClient.Get(context.Background(), types.NamespacedName{Name: serviceAccountName, Namespace: serviceAccountNamespace}, sa); err != nil {
+		t.Fatalf("failed to get service account: %v", err)
+	}
+	if len(sa.Annotations) != 0 {
+		t.Errorf("expected the service account not to be patched, got annotations %v", sa.Annotations)
+	}
+}
@@ -21,7 +21,7 @@ func NewServiceAccountCmd() *cobra.Command {
 					return err
 				}
 			}
-			return authProvider.Validate()
+			return authProvider.Validate(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Usage()
@@ -33,6 +33,8 @@ func NewServiceAccountCmd() *cobra.Command {
 
 	serviceAccountCmd.AddCommand(newCreateCmd(authProvider))
 	serviceAccountCmd.AddCommand(newDeleteCmd(authProvider))
+	serviceAccountCmd.AddCommand(newRepairCmd(authProvider))
+	serviceAccountCmd.AddCommand(newSubjectCmd())
 
 	return serviceAccountCmd
 }
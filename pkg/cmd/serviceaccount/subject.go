@@ -0,0 +1,66 @@
+package serviceaccount
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/options"
+	"github.com/Azure/azure-workload-identity/pkg/cmd/serviceaccount/util"
+)
+
+type subjectCmd struct {
+	serviceAccountName      string
+	serviceAccountNamespace string
+	out                     io.Writer
+}
+
+func newSubjectCmd() *cobra.Command {
+	subjectCmd := &subjectCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "subject",
+		Short: "Print the federated identity credential subject of a service account",
+		Long:  "This command prints the subject to use when creating a federated identity credential for the service account",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag.
+			// Printing the subject doesn't require Azure credentials, so the
+			// serviceaccount command pre-run that validates them is skipped.
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return subjectCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			subjectCmd.out = cmd.OutOrStdout()
+			return subjectCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&subjectCmd.serviceAccountName, options.ServiceAccountName.Flag, "", options.ServiceAccountName.Description)
+	f.StringVar(&subjectCmd.serviceAccountNamespace, options.ServiceAccountNamespace.Flag, "default", options.ServiceAccountNamespace.Description)
+
+	return cmd
+}
+
+func (sc *subjectCmd) prerun() error {
+	if sc.serviceAccountName == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountName.Flag)
+	}
+	if sc.serviceAccountNamespace == "" {
+		return options.FlagIsRequiredError(options.ServiceAccountNamespace.Flag)
+	}
+	return nil
+}
+
+func (sc *subjectCmd) run() error {
+	_, err := fmt.Fprintln(sc.out, util.GetFederatedCredentialSubject(sc.serviceAccountNamespace, sc.serviceAccountName))
+	return err
+}
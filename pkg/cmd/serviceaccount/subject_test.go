@@ -0,0 +1,51 @@
+package serviceaccount
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSubjectCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		subjectCmd *subjectCmd
+		errorMsg   string
+	}{
+		{
+			name:       "missing --service-account-name",
+			subjectCmd: &subjectCmd{serviceAccountNamespace: serviceAccountNamespace},
+			errorMsg:   "--service-account-name is required",
+		},
+		{
+			name:       "missing --service-account-namespace",
+			subjectCmd: &subjectCmd{serviceAccountName: serviceAccountName},
+			errorMsg:   "--service-account-namespace is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.subjectCmd.prerun()
+			if err == nil || err.Error() != test.errorMsg {
+				t.Errorf("prerun() error = %v, want %s", err, test.errorMsg)
+			}
+		})
+	}
+}
+
+func TestSubjectCmdRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	sc := &subjectCmd{
+		serviceAccountName:      serviceAccountName,
+		serviceAccountNamespace: serviceAccountNamespace,
+		out:                     out,
+	}
+	if err := sc.run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	want := "system:serviceaccount:" + serviceAccountNamespace + ":" + serviceAccountName + "\n"
+	if out.String() != want {
+		t.Errorf("run() output = %q, want %q", out.String(), want)
+	}
+}
@@ -3,7 +3,16 @@ package util
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strings"
+)
+
+const (
+	// maxFederatedCredentialNameLength is the maximum length of the name of a federated identity credential
+	maxFederatedCredentialNameLength = 120
+	// federatedCredentialNameHashLength is the length of the hash suffix of a truncated federated identity credential name
+	federatedCredentialNameHashLength = 8
 )
 
 // GetIssuerHash returns a hash of the issuer URL
@@ -13,6 +22,12 @@ func GetIssuerHash(issuerURL string) string {
 	return base64.URLEncoding.EncodeToString(h.Sum(nil))
 }
 
+// GetAADApplicationName returns the default name of the AAD application
+// constructed with the service account namespace, name, and the hash of the issuer URL
+func GetAADApplicationName(namespace, name, issuerURL string) string {
+	return fmt.Sprintf("%s-%s-%s", namespace, name, GetIssuerHash(issuerURL))
+}
+
 // GetFederatedCredentialName returns a hash of
 // the service account namespace, name, and issuer URL
 func GetFederatedCredentialName(namespace, name, issuerURL string) string {
@@ -21,6 +36,26 @@ func GetFederatedCredentialName(namespace, name, issuerURL string) string {
 	return base64.URLEncoding.EncodeToString(h.Sum(nil))
 }
 
+// FederatedCredentialName returns the deterministic name of the federated identity credential for the
+// service account. The name is derived from the namespace and name of the service account with the characters
+// other than alphanumeric characters, hyphens and underscores replaced by hyphens, which is valid for both
+// applications and user-assigned managed identities. Names longer than 120 characters are truncated and
+// suffixed with a hash of the subject so that service accounts with the same prefix don't collide.
+func FederatedCredentialName(namespace, serviceAccount string) string {
+	fcName := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, fmt.Sprintf("%s-%s", namespace, serviceAccount))
+	if len(fcName) <= maxFederatedCredentialNameLength {
+		return fcName
+	}
+	h := sha256.Sum256([]byte(GetFederatedCredentialSubject(namespace, serviceAccount)))
+	suffix := hex.EncodeToString(h[:])[:federatedCredentialNameHashLength]
+	return fcName[:maxFederatedCredentialNameLength-len(suffix)-1] + "-" + suffix
+}
+
 // GetFederatedCredentialSubject returns the subject of the federated credential
 func GetFederatedCredentialSubject(namespace, name string) string {
 	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
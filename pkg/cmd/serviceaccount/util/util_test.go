@@ -1,6 +1,10 @@
 package util
 
-import "testing"
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
 
 func TestGetIssuerHash(t *testing.T) {
 	tests := []struct {
@@ -30,6 +34,14 @@ func TestGetIssuerHash(t *testing.T) {
 	}
 }
 
+func TestGetAADApplicationName(t *testing.T) {
+	want := "oidc-pod-identity-sa-foWt5lYFJx_-XwBetmnSltvWY5J_nenUV-2c3Lqes3o="
+	got := GetAADApplicationName("oidc", "pod-identity-sa", "https://test.blob.core.windows.net/oidc-test/")
+	if got != want {
+		t.Errorf("GetAADApplicationName() = %s, want %s", got, want)
+	}
+}
+
 func TestGetFederatedCredentialName(t *testing.T) {
 	tests := []struct {
 		name                    string
@@ -64,6 +76,60 @@ func TestGetFederatedCredentialName(t *testing.T) {
 	}
 }
 
+func TestFederatedCredentialName(t *testing.T) {
+	tests := []struct {
+		name                    string
+		serviceAccountNamespace string
+		serviceAccountName      string
+		want                    string
+	}{
+		{
+			name:                    "valid",
+			serviceAccountNamespace: "oidc",
+			serviceAccountName:      "pod-identity-sa",
+			want:                    "oidc-pod-identity-sa",
+		},
+		{
+			name:                    "dots are replaced",
+			serviceAccountNamespace: "kube-system",
+			serviceAccountName:      "my.sa",
+			want:                    "kube-system-my-sa",
+		},
+		{
+			name:                    "truncated with a hash suffix",
+			serviceAccountNamespace: "default",
+			serviceAccountName:      strings.Repeat("a", 200),
+			want:                    "default-" + strings.Repeat("a", 103) + "-1e552bd3",
+		},
+		{
+			name:                    "long namespace and name",
+			serviceAccountNamespace: strings.Repeat("n", 63),
+			serviceAccountName:      strings.Repeat("s", 253),
+			want:                    strings.Repeat("n", 63) + "-" + strings.Repeat("s", 47) + "-53461dcd",
+		},
+	}
+
+	// the names accepted by ARM for federated identity credentials of managed identities,
+	// which are also valid names of federated identity credentials of applications
+	allowed := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{2,119}$`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FederatedCredentialName(tt.serviceAccountNamespace, tt.serviceAccountName)
+			if got != tt.want {
+				t.Errorf("FederatedCredentialName() = %s, want %s", got, tt.want)
+			}
+			if !allowed.MatchString(got) {
+				t.Errorf("FederatedCredentialName() = %s, which is not a valid federated credential name", got)
+			}
+		})
+	}
+
+	// service accounts with the same truncated prefix must not collide
+	if FederatedCredentialName("default", strings.Repeat("a", 200)) == FederatedCredentialName("default", strings.Repeat("a", 201)) {
+		t.Error("FederatedCredentialName() returned the same name for different service accounts")
+	}
+}
+
 func TestGetFederatedCredentialSubject(t *testing.T) {
 	want := "system:serviceaccount:oidc:pod-identity-sa"
 	got := GetFederatedCredentialSubject("oidc", "pod-identity-sa")
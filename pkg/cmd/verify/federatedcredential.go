@@ -0,0 +1,120 @@
+package verify
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"monis.app/mlog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Azure/azure-workload-identity/pkg/cloud"
+	"github.com/Azure/azure-workload-identity/pkg/kuberneteshelper"
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+// verifyTokenExpiration is the expiration of the service account token requested to verify the federated
+// credentials, which is the minimum accepted by the TokenRequest API.
+const verifyTokenExpiration = 10 * time.Minute
+
+// tokenClaims are the claims of a service account token that a federated identity credential matches.
+type tokenClaims struct {
+	Issuer    string
+	Subject   string
+	Audiences []string
+}
+
+// ServiceAccountFederatedCredential verifies end to end that the application with the given object ID trusts the
+// tokens of the service account: it requests a token of the service account from the TokenRequest API, checks
+// that it was issued by the issuer of the cluster and returns the federated identity credential of the application
+// that matches its issuer, subject and audience, or an error that explains the mismatch.
+func ServiceAccountFederatedCredential(ctx context.Context, kubeClient client.Client, azureClient cloud.Interface, namespace, name, issuerURL, objectID string) (models.FederatedIdentityCredentialable, error) {
+	mlog.Debug("verifying federated credential", "namespace", namespace, "name", name, "issuerURL", issuerURL, "objectID", objectID)
+
+	token, err := kuberneteshelper.CreateServiceAccountToken(ctx, kubeClient, namespace, name, []string{webhook.DefaultAudience}, verifyTokenExpiration)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request a token of service account %s/%s", namespace, name)
+	}
+	claims, err := parseTokenClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	fics, err := azureClient.ListFederatedCredentials(ctx, objectID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list federated credentials of application %s", objectID)
+	}
+	return matchFederatedCredential(claims, issuerURL, fics)
+}
+
+// parseTokenClaims returns the issuer, subject and audience claims of the token. The signature of the token
+// is not verified since the token is only inspected, not trusted.
+func parseTokenClaims(token string) (tokenClaims, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return tokenClaims{}, errors.Wrap(err, "failed to parse service account token")
+	}
+	var claims jwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return tokenClaims{}, errors.Wrap(err, "failed to decode claims of service account token")
+	}
+	return tokenClaims{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Audiences: claims.Audience,
+	}, nil
+}
+
+// matchFederatedCredential returns the federated identity credential that matches the issuer, subject and one
+// of the audiences of the token, once the issuer of the token is checked to be the issuer of the cluster. The
+// error explains the mismatch, e.g. a federated identity credential with the subject but another issuer.
+func matchFederatedCredential(claims tokenClaims, issuerURL string, fics []models.FederatedIdentityCredentialable) (models.FederatedIdentityCredentialable, error) {
+	if !sameIssuer(claims.Issuer, issuerURL) {
+		return nil, errors.Errorf("token issuer %q doesn't match the cluster issuer %q", claims.Issuer, issuerURL)
+	}
+
+	var otherIssuers, otherAudiences []string
+	for _, fic := range fics {
+		if to.String(fic.GetSubject()) != claims.Subject {
+			continue
+		}
+		if !sameIssuer(to.String(fic.GetIssuer()), claims.Issuer) {
+			otherIssuers = append(otherIssuers, to.String(fic.GetIssuer()))
+			continue
+		}
+		if !hasAudience(fic.GetAudiences(), claims.Audiences) {
+			otherAudiences = append(otherAudiences, fic.GetAudiences()...)
+			continue
+		}
+		return fic, nil
+	}
+
+	switch {
+	case len(otherIssuers) > 0:
+		return nil, errors.Errorf("no federated credential for subject %q has issuer %q, found issuers %q", claims.Subject, claims.Issuer, otherIssuers)
+	case len(otherAudiences) > 0:
+		return nil, errors.Errorf("no federated credential for subject %q has one of the token audiences %q, found audiences %q", claims.Subject, claims.Audiences, otherAudiences)
+	default:
+		return nil, errors.Errorf("no federated credential has subject %q", claims.Subject)
+	}
+}
+
+// sameIssuer returns true if the issuers are the same, regardless of a trailing slash.
+func sameIssuer(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// hasAudience returns true if one of the audiences of the federated identity credential is a token audience.
+func hasAudience(ficAudiences, tokenAudiences []string) bool {
+	for _, a := range ficAudiences {
+		for _, b := range tokenAudiences {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
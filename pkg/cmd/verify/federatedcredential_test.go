@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func newFederatedCredential(name, issuer, subject string, audiences ...string) models.FederatedIdentityCredentialable {
+	fic := models.NewFederatedIdentityCredential()
+	fic.SetName(to.StringPtr(name))
+	fic.SetIssuer(to.StringPtr(issuer))
+	fic.SetSubject(to.StringPtr(subject))
+	fic.SetAudiences(audiences)
+	return fic
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("0123456789abcdef0123456789abcdef")}, nil)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   "https://issuer.example.com/",
+		Subject:  "system:serviceaccount:default:sa",
+		Audience: jwt.Audience{"api://AzureADTokenExchange"},
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	claims, err := parseTokenClaims(token)
+	if err != nil {
+		t.Fatalf("parseTokenClaims() error = %v", err)
+	}
+	want := tokenClaims{
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:sa",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("parseTokenClaims() = %+v, want %+v", claims, want)
+	}
+
+	if _, err := parseTokenClaims("not-a-token"); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
+
+func TestMatchFederatedCredential(t *testing.T) {
+	claims := tokenClaims{
+		Issuer:    "https://issuer.example.com/",
+		Subject:   "system:serviceaccount:default:sa",
+		Audiences: []string{"api://AzureADTokenExchange"},
+	}
+
+	tests := []struct {
+		name      string
+		issuerURL string
+		fics      []models.FederatedIdentityCredentialable
+		wantName  string
+		errorMsg  string
+	}{
+		{
+			name:      "match",
+			issuerURL: "https://issuer.example.com",
+			fics: []models.FederatedIdentityCredentialable{
+				newFederatedCredential("other", "https://issuer.example.com/", "system:serviceaccount:default:other", "api://AzureADTokenExchange"),
+				newFederatedCredential("fic", "https://issuer.example.com", "system:serviceaccount:default:sa", "api://AzureADTokenExchange"),
+			},
+			wantName: "fic",
+		},
+		{
+			name:      "token issuer is not the cluster issuer",
+			issuerURL: "https://other-issuer.example.com/",
+			fics: []models.FederatedIdentityCredentialable{
+				newFederatedCredential("fic", "https://issuer.example.com/", "system:serviceaccount:default:sa", "api://AzureADTokenExchange"),
+			},
+			errorMsg: `token issuer "https://issuer.example.com/" doesn't match the cluster issuer "https://other-issuer.example.com/"`,
+		},
+		{
+			name:      "issuer mismatch",
+			issuerURL: "https://issuer.example.com/",
+			fics: []models.FederatedIdentityCredentialable{
+				newFederatedCredential("fic", "https://old-issuer.example.com/", "system:serviceaccount:default:sa", "api://AzureADTokenExchange"),
+			},
+			errorMsg: `found issuers ["https://old-issuer.example.com/"]`,
+		},
+		{
+			name:      "audience mismatch",
+			issuerURL: "https://issuer.example.com/",
+			fics: []models.FederatedIdentityCredentialable{
+				newFederatedCredential("fic", "https://issuer.example.com/", "system:serviceaccount:default:sa", "api://custom"),
+			},
+			errorMsg: `found audiences ["api://custom"]`,
+		},
+		{
+			name:      "subject mismatch",
+			issuerURL: "https://issuer.example.com/",
+			fics: []models.FederatedIdentityCredentialable{
+				newFederatedCredential("fic", "https://issuer.example.com/", "system:serviceaccount:default:other", "api://AzureADTokenExchange"),
+			},
+			errorMsg: `no federated credential has subject "system:serviceaccount:default:sa"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fic, err := matchFederatedCredential(claims, test.issuerURL, test.fics)
+			if test.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorMsg) {
+					t.Errorf("matchFederatedCredential() error = %v, want %s", err, test.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchFederatedCredential() error = %v", err)
+			}
+			if got := to.String(fic.GetName()); got != test.wantName {
+				t.Errorf("matchFederatedCredential() = %s, want %s", got, test.wantName)
+			}
+		})
+	}
+}
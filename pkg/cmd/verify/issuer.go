@@ -0,0 +1,197 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	jose "gopkg.in/square/go-jose.v2"
+	"monis.app/mlog"
+)
+
+const (
+	// discoveryDocumentPath is the path of the OpenID Connect discovery document relative to the issuer URL
+	discoveryDocumentPath = ".well-known/openid-configuration"
+
+	defaultRequestTimeout = 30 * time.Second
+)
+
+// discoveryDocument is the subset of the OpenID Connect discovery document
+// that is required for Azure AD to validate service account tokens.
+// Reference: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type discoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+type issuerCmd struct {
+	issuerURL  string
+	httpClient *http.Client
+	out        io.Writer
+}
+
+func newIssuerCmd() *cobra.Command {
+	issuerCmd := &issuerCmd{
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "issuer",
+		Short: "Verify the OIDC issuer is reachable and valid",
+		Long:  "This command fetches the OpenID Connect discovery document and the JSON Web Key Set (JWKS) of the OIDC issuer and validates them",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return issuerCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			issuerCmd.out = cmd.OutOrStdout()
+			return issuerCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&issuerCmd.issuerURL, "issuer-url", "", "URL of the OIDC issuer")
+
+	return cmd
+}
+
+// Issuer verifies the OIDC issuer is reachable and serves a valid discovery document and JWKS.
+func Issuer(ctx context.Context, httpClient *http.Client, issuerURL string) error {
+	ic := &issuerCmd{
+		issuerURL:  issuerURL,
+		httpClient: httpClient,
+		out:        io.Discard,
+	}
+	if err := ic.prerun(); err != nil {
+		return err
+	}
+	return ic.run(ctx)
+}
+
+func (ic *issuerCmd) prerun() error {
+	if ic.issuerURL == "" {
+		return errors.New("--issuer-url is required")
+	}
+	u, err := url.Parse(ic.issuerURL)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse issuer URL")
+	}
+	if u.Scheme != "https" {
+		return errors.Errorf("issuer URL %s must use the https scheme", ic.issuerURL)
+	}
+	return nil
+}
+
+func (ic *issuerCmd) run(ctx context.Context) error {
+	discoveryURL := strings.TrimSuffix(ic.issuerURL, "/") + "/" + discoveryDocumentPath
+
+	doc := &discoveryDocument{}
+	if err := ic.check(fmt.Sprintf("fetch discovery document from %s", discoveryURL), func() error {
+		return ic.getJSON(ctx, discoveryURL, doc)
+	}); err != nil {
+		return err
+	}
+	if err := ic.check("validate discovery document", func() error {
+		return validateDiscoveryDocument(doc, ic.issuerURL)
+	}); err != nil {
+		return err
+	}
+
+	keySet := &jose.JSONWebKeySet{}
+	if err := ic.check(fmt.Sprintf("fetch JWKS from %s", doc.JWKSURI), func() error {
+		return ic.getJSON(ctx, doc.JWKSURI, keySet)
+	}); err != nil {
+		return err
+	}
+	if err := ic.check("validate JWKS", func() error {
+		return validateJWKS(keySet)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ic.out, "PASS: OIDC issuer %s is reachable and valid\n", ic.issuerURL)
+	return nil
+}
+
+// check runs fn and prints the result of the named check.
+func (ic *issuerCmd) check(name string, fn func() error) error {
+	if err := fn(); err != nil {
+		fmt.Fprintf(ic.out, "FAIL: %s: %v\n", name, err)
+		return errors.Wrapf(err, "failed to %s", name)
+	}
+	fmt.Fprintf(ic.out, "PASS: %s\n", name)
+	return nil
+}
+
+// getJSON fetches the given URL and decodes the JSON response body into v.
+func (ic *issuerCmd) getJSON(ctx context.Context, u string, v interface{}) error {
+	mlog.Debug("fetching", "url", u)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	resp, err := ic.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "failed to decode response body")
+	}
+	return nil
+}
+
+// validateDiscoveryDocument validates the required fields of the discovery document.
+func validateDiscoveryDocument(doc *discoveryDocument, issuerURL string) error {
+	// the issuer in the discovery document must exactly match the issuer
+	// in the service account token that is exchanged with Azure AD
+	if doc.Issuer != issuerURL {
+		return errors.Errorf("issuer %q does not match the issuer URL %q", doc.Issuer, issuerURL)
+	}
+	if doc.JWKSURI == "" {
+		return errors.New("jwks_uri is required")
+	}
+	if _, err := url.ParseRequestURI(doc.JWKSURI); err != nil {
+		return errors.Wrapf(err, "jwks_uri %q is not a valid URL", doc.JWKSURI)
+	}
+	if len(doc.ResponseTypesSupported) == 0 {
+		return errors.New("response_types_supported is required")
+	}
+	if len(doc.SubjectTypesSupported) == 0 {
+		return errors.New("subject_types_supported is required")
+	}
+	if len(doc.IDTokenSigningAlgValuesSupported) == 0 {
+		return errors.New("id_token_signing_alg_values_supported is required")
+	}
+	return nil
+}
+
+// validateJWKS validates the JWKS contains at least one valid public signing key.
+func validateJWKS(keySet *jose.JSONWebKeySet) error {
+	if len(keySet.Keys) == 0 {
+		return errors.New("JWKS does not contain any keys")
+	}
+	for _, key := range keySet.Keys {
+		if key.KeyID == "" {
+			return errors.New("JWKS contains a key without a kid")
+		}
+		if !key.Valid() || !key.IsPublic() {
+			return errors.Errorf("key %s is not a valid public key", key.KeyID)
+		}
+	}
+	return nil
+}
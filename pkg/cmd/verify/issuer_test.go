@@ -0,0 +1,209 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testJWKS = `
+{
+  "keys": [
+    {
+      "use": "sig",
+      "kty": "RSA",
+      "kid": "2A3FPpix2keOV1SGPQiM0_wVemz4XOIgQyJJnpu5sPE",
+      "alg": "RS256",
+      "n": "1QJE2YmLbvMLP6FtzcfPzGbSDbHEEtA0mH6kwgrOrlKs83zj2vr6Y5k_ZcGdIbsdm5vDj2IxtSkE-pSDtgFM2iq0sJ7xuE6RYmlrtBm-H2WHvXrP9RrG1EfO7iWs6Czj4A_Ddxg3kNUiQCtQEJwwH2pfrUkh8STQhST_T86pq5AIFCuQiQSrkfC80eD9bUFypV3CLB2M9Fa1hbvOWbzSF93_I0toUK2-oPgVW6m2EwMyy8Fh_3KRixrAJO8g-D4d537C1fa1vJJRlMRFtLMA_bo6k1fAtNsVQuQoML5CmRrvNT7ZpXRLaQy64OSFrVLD3Pb7wct7b4g2xQECixQodw",
+      "e": "AQAB"
+    }
+  ]
+}`
+
+func TestIssuerCmdPreRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		issuerURL string
+		wantErr   bool
+	}{
+		{
+			name:    "missing --issuer-url",
+			wantErr: true,
+		},
+		{
+			name:      "non-https issuer URL",
+			issuerURL: "http://issuer.example.com/",
+			wantErr:   true,
+		},
+		{
+			name:      "valid issuer URL",
+			issuerURL: "https://issuer.example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &issuerCmd{issuerURL: tt.issuerURL}
+			if err := ic.prerun(); (err != nil) != tt.wantErr {
+				t.Errorf("prerun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIssuerCmdRun(t *testing.T) {
+	tests := []struct {
+		name string
+		// discoveryDocument is a format string that takes the issuer URL (with trailing slash)
+		discoveryDocument string
+		jwks              string
+		wantFailedCheck   string
+	}{
+		{
+			name: "valid issuer",
+			discoveryDocument: `{
+				"issuer": "%[1]s",
+				"jwks_uri": "%[1]sopenid/v1/jwks",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"]
+			}`,
+			jwks: testJWKS,
+		},
+		{
+			name:              "malformed discovery document",
+			discoveryDocument: `{"issuer": `,
+			jwks:              testJWKS,
+			wantFailedCheck:   "FAIL: fetch discovery document",
+		},
+		{
+			name: "issuer mismatch",
+			discoveryDocument: `{
+				"issuer": "https://another-issuer.example.com/",
+				"jwks_uri": "%[1]sopenid/v1/jwks",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"]
+			}`,
+			jwks:            testJWKS,
+			wantFailedCheck: "FAIL: validate discovery document: issuer",
+		},
+		{
+			name: "missing jwks_uri",
+			discoveryDocument: `{
+				"issuer": "%[1]s",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"]
+			}`,
+			jwks:            testJWKS,
+			wantFailedCheck: "FAIL: validate discovery document: jwks_uri is required",
+		},
+		{
+			name: "missing id_token_signing_alg_values_supported",
+			discoveryDocument: `{
+				"issuer": "%[1]s",
+				"jwks_uri": "%[1]sopenid/v1/jwks",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"]
+			}`,
+			jwks:            testJWKS,
+			wantFailedCheck: "FAIL: validate discovery document: id_token_signing_alg_values_supported is required",
+		},
+		{
+			name: "jwks_uri not found",
+			discoveryDocument: `{
+				"issuer": "%[1]s",
+				"jwks_uri": "%[1]snot-found",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"]
+			}`,
+			jwks:            testJWKS,
+			wantFailedCheck: "FAIL: fetch JWKS",
+		},
+		{
+			name: "empty JWKS",
+			discoveryDocument: `{
+				"issuer": "%[1]s",
+				"jwks_uri": "%[1]sopenid/v1/jwks",
+				"response_types_supported": ["id_token"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"]
+			}`,
+			jwks:            `{"keys": []}`,
+			wantFailedCheck: "FAIL: validate JWKS: JWKS does not contain any keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var issuerURL string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/"+discoveryDocumentPath, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, tt.discoveryDocument, issuerURL)
+			})
+			mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.jwks)
+			})
+			server := httptest.NewTLSServer(mux)
+			defer server.Close()
+			issuerURL = server.URL + "/"
+
+			out := &bytes.Buffer{}
+			ic := &issuerCmd{
+				issuerURL:  issuerURL,
+				httpClient: server.Client(),
+				out:        out,
+			}
+			err := ic.run(context.Background())
+
+			if tt.wantFailedCheck == "" {
+				if err != nil {
+					t.Fatalf("run() error = %v, output:\n%s", err, out.String())
+				}
+				if !strings.Contains(out.String(), "is reachable and valid") {
+					t.Errorf("expected output to report the issuer as valid, got:\n%s", out.String())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("run() error = nil, want error")
+			}
+			if !strings.Contains(out.String(), tt.wantFailedCheck) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.wantFailedCheck, out.String())
+			}
+		})
+	}
+}
+
+func TestIssuer(t *testing.T) {
+	var issuerURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+discoveryDocumentPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{
+			"issuer": "%[1]s",
+			"jwks_uri": "%[1]sopenid/v1/jwks",
+			"response_types_supported": ["id_token"],
+			"subject_types_supported": ["public"],
+			"id_token_signing_alg_values_supported": ["RS256"]
+		}`, issuerURL)
+	})
+	mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testJWKS)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	issuerURL = server.URL + "/"
+
+	if err := Issuer(context.Background(), server.Client(), issuerURL); err != nil {
+		t.Errorf("Issuer() error = %v", err)
+	}
+	if err := Issuer(context.Background(), server.Client(), "http://issuer.example.com/"); err == nil {
+		t.Errorf("Issuer() error = nil, want error for non-https issuer URL")
+	}
+}
@@ -0,0 +1,29 @@
+package verify
+
+import "github.com/spf13/cobra"
+
+// NewVerifyCmd returns a new verify command
+func NewVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the prerequisites of workload identity",
+		Long:  "Verify the prerequisites of workload identity",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// run root command pre-run to register the debug flag
+			if cmd.Root() != nil && cmd.Root().PersistentPreRunE != nil {
+				if err := cmd.Root().PersistentPreRunE(cmd.Root(), args); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	verifyCmd.AddCommand(newIssuerCmd())
+	verifyCmd.AddCommand(newTokenExchangeCmd())
+
+	return verifyCmd
+}
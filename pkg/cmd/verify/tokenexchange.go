@@ -0,0 +1,194 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"monis.app/mlog"
+
+	"github.com/Azure/azure-workload-identity/pkg/webhook"
+)
+
+const (
+	// clientAssertionType is the type of the service account token exchanged for an Azure AD token
+	// Reference: https://learn.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-client-creds-grant-flow#third-case-access-token-request-with-a-federated-credential
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" // #nosec
+
+	// maxTokenResponseSize is the maximum size of the token endpoint response that is read
+	maxTokenResponseSize = 1 << 20
+)
+
+// tokenErrorResponse is the error response of the Azure AD token endpoint.
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type tokenExchangeCmd struct {
+	tokenFile     string
+	clientID      string
+	tenantID      string
+	authorityHost string
+	scope         string
+	httpClient    *http.Client
+	out           io.Writer
+}
+
+func newTokenExchangeCmd() *cobra.Command {
+	tokenExchangeCmd := &tokenExchangeCmd{
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "token-exchange",
+		Short: "Verify the projected service account token can be exchanged for an Azure AD token",
+		Long:  "This command exchanges the projected service account token for an Azure AD token, using the environment variables injected by the webhook by default, and prints the Azure AD error if the exchange fails",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return tokenExchangeCmd.prerun()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokenExchangeCmd.out = cmd.OutOrStdout()
+			return tokenExchangeCmd.run(cmd.Context())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&tokenExchangeCmd.tokenFile, "token-file", os.Getenv(webhook.AzureFederatedTokenFileEnvVar), "Path of the projected service account token")
+	f.StringVar(&tokenExchangeCmd.clientID, "client-id", os.Getenv(webhook.AzureClientIDEnvVar), "Client ID of the AAD application or user-assigned managed identity")
+	f.StringVar(&tokenExchangeCmd.tenantID, "tenant-id", os.Getenv(webhook.AzureTenantIDEnvVar), "ID of the Azure AD tenant")
+	f.StringVar(&tokenExchangeCmd.authorityHost, "authority-host", getEnvOrDefault(webhook.AzureAuthorityHostEnvVar, azure.PublicCloud.ActiveDirectoryEndpoint), "Azure AD authority host")
+	f.StringVar(&tokenExchangeCmd.scope, "scope", "", "Scope of the requested token (default is the Azure Resource Manager scope of the cloud of the authority host)")
+
+	return cmd
+}
+
+// TokenExchange exchanges the service account token for an Azure AD token of the given client ID
+// and returns the Azure AD error if the exchange fails. An empty scope defaults to the Azure Resource
+// Manager scope of the cloud of the authority host.
+func TokenExchange(ctx context.Context, httpClient *http.Client, authorityHost, tenantID, clientID, token, scope string) error {
+	tc := &tokenExchangeCmd{
+		clientID:      clientID,
+		tenantID:      tenantID,
+		authorityHost: authorityHost,
+		scope:         scope,
+		httpClient:    httpClient,
+		out:           io.Discard,
+	}
+	if err := tc.validate(); err != nil {
+		return err
+	}
+	return tc.exchange(ctx, token)
+}
+
+func (tc *tokenExchangeCmd) prerun() error {
+	if tc.tokenFile == "" {
+		return errors.Errorf("--token-file is required if %s is not set", webhook.AzureFederatedTokenFileEnvVar)
+	}
+	return tc.validate()
+}
+
+func (tc *tokenExchangeCmd) validate() error {
+	if tc.clientID == "" {
+		return errors.Errorf("--client-id is required if %s is not set", webhook.AzureClientIDEnvVar)
+	}
+	if tc.tenantID == "" {
+		return errors.Errorf("--tenant-id is required if %s is not set", webhook.AzureTenantIDEnvVar)
+	}
+	u, err := url.Parse(tc.authorityHost)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse authority host")
+	}
+	if u.Scheme != "https" {
+		return errors.Errorf("authority host %s must use the https scheme", tc.authorityHost)
+	}
+	if tc.scope == "" {
+		tc.scope = getDefaultScope(tc.authorityHost)
+	}
+	return nil
+}
+
+func (tc *tokenExchangeCmd) run(ctx context.Context) error {
+	token, err := os.ReadFile(tc.tokenFile)
+	if err != nil {
+		fmt.Fprintf(tc.out, "FAIL: read token file %s: %v\n", tc.tokenFile, err)
+		return errors.Wrapf(err, "failed to read token file %s", tc.tokenFile)
+	}
+
+	if err := tc.exchange(ctx, strings.TrimSpace(string(token))); err != nil {
+		fmt.Fprintf(tc.out, "FAIL: exchange token for client ID %s: %v\n", tc.clientID, err)
+		return err
+	}
+	fmt.Fprintf(tc.out, "PASS: token exchanged for an Azure AD token of client ID %s\n", tc.clientID)
+	return nil
+}
+
+// exchange requests an Azure AD token with the service account token as the client assertion.
+func (tc *tokenExchangeCmd) exchange(ctx context.Context, token string) error {
+	if token == "" {
+		return errors.New("service account token is empty")
+	}
+
+	tokenURL := strings.TrimSuffix(tc.authorityHost, "/") + "/" + url.PathEscape(tc.tenantID) + "/oauth2/v2.0/token"
+	mlog.Debug("exchanging service account token", "url", tokenURL, "clientID", tc.clientID, "scope", tc.scope)
+
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_id":             {tc.clientID},
+		"client_assertion_type": {clientAssertionType},
+		"client_assertion":      {token},
+		"scope":                 {tc.scope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := tc.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return errors.Wrapf(err, "unexpected status code %d", resp.StatusCode)
+	}
+	var tokenErr tokenErrorResponse
+	if err := json.Unmarshal(body, &tokenErr); err != nil || tokenErr.Error == "" {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return errors.Errorf("Azure AD returned %s: %s", tokenErr.Error, tokenErr.ErrorDescription)
+}
+
+// getDefaultScope returns the Azure Resource Manager scope of the cloud with the given Active Directory
+// endpoint, or of the public cloud if the authority host is unknown.
+func getDefaultScope(authorityHost string) string {
+	env := azure.PublicCloud
+	for _, e := range []azure.Environment{azure.PublicCloud, azure.USGovernmentCloud, azure.ChinaCloud} {
+		if strings.EqualFold(strings.TrimSuffix(e.ActiveDirectoryEndpoint, "/"), strings.TrimSuffix(authorityHost, "/")) {
+			env = e
+			break
+		}
+	}
+	return strings.TrimSuffix(env.ResourceManagerEndpoint, "/") + "/.default"
+}
+
+func getEnvOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,186 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeTokenServer returns a fake Azure AD token endpoint of tenant tenant-id that issues a token
+// if the client assertion is valid-token, and responds with the given status code and body otherwise.
+func newFakeTokenServer(t *testing.T, statusCode int, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/tenant-id/oauth2/v2.0/token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		for key, want := range map[string]string{
+			"grant_type":            "client_credentials",
+			"client_id":             "client-id",
+			"client_assertion_type": clientAssertionType,
+			"scope":                 "https://management.azure.com/.default",
+		} {
+			if got := r.PostForm.Get(key); got != want {
+				t.Errorf("expected %s to be %q, got %q", key, want, got)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("client_assertion") == "valid-token" {
+			_, _ = w.Write([]byte(`{"token_type": "Bearer", "expires_in": 3599, "access_token": "access-token"}`))
+			return
+		}
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTokenExchange(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		statusCode int
+		body       string
+		wantErr    string
+	}{
+		{
+			name:  "token exchanged",
+			token: "valid-token",
+		},
+		{
+			name:       "federated identity credential not found",
+			token:      "invalid-token",
+			statusCode: http.StatusBadRequest,
+			body:       `{"error": "invalid_client", "error_description": "AADSTS70021: No matching federated identity record found for presented assertion."}`,
+			wantErr:    "Azure AD returned invalid_client: AADSTS70021: No matching federated identity record found for presented assertion.",
+		},
+		{
+			name:       "unexpected error response",
+			token:      "invalid-token",
+			statusCode: http.StatusInternalServerError,
+			body:       "internal server error",
+			wantErr:    "unexpected status code 500",
+		},
+		{
+			name:    "empty token",
+			wantErr: "service account token is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeTokenServer(t, tt.statusCode, tt.body)
+
+			err := TokenExchange(context.Background(), server.Client(), server.URL+"/", "tenant-id", "client-id", tt.token, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("TokenExchange() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("TokenExchange() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenExchangeValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		tenantID      string
+		clientID      string
+		authorityHost string
+		wantErr       string
+	}{
+		{
+			name:          "missing client ID",
+			tenantID:      "tenant-id",
+			authorityHost: "https://login.microsoftonline.com/",
+			wantErr:       "--client-id is required if AZURE_CLIENT_ID is not set",
+		},
+		{
+			name:          "missing tenant ID",
+			clientID:      "client-id",
+			authorityHost: "https://login.microsoftonline.com/",
+			wantErr:       "--tenant-id is required if AZURE_TENANT_ID is not set",
+		},
+		{
+			name:          "non-https authority host",
+			tenantID:      "tenant-id",
+			clientID:      "client-id",
+			authorityHost: "http://login.microsoftonline.com/",
+			wantErr:       "authority host http://login.microsoftonline.com/ must use the https scheme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TokenExchange(context.Background(), http.DefaultClient, tt.authorityHost, tt.tenantID, tt.clientID, "token", "")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("TokenExchange() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetDefaultScope(t *testing.T) {
+	tests := []struct {
+		authorityHost string
+		want          string
+	}{
+		{authorityHost: "https://login.microsoftonline.com/", want: "https://management.azure.com/.default"},
+		{authorityHost: "https://login.microsoftonline.us", want: "https://management.usgovcloudapi.net/.default"},
+		{authorityHost: "https://login.chinacloudapi.cn/", want: "https://management.chinacloudapi.cn/.default"},
+		{authorityHost: "https://login.example.com/", want: "https://management.azure.com/.default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.authorityHost, func(t *testing.T) {
+			if got := getDefaultScope(tt.authorityHost); got != tt.want {
+				t.Errorf("getDefaultScope() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenExchangeCmdRun(t *testing.T) {
+	server := newFakeTokenServer(t, http.StatusBadRequest, `{"error": "invalid_client", "error_description": "AADSTS70021"}`)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("valid-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	tc := &tokenExchangeCmd{
+		tokenFile:     tokenFile,
+		clientID:      "client-id",
+		tenantID:      "tenant-id",
+		authorityHost: server.URL,
+		httpClient:    server.Client(),
+		out:           out,
+	}
+	if err := tc.prerun(); err != nil {
+		t.Fatalf("prerun() error = %v", err)
+	}
+	if err := tc.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "PASS: token exchanged for an Azure AD token of client ID client-id") {
+		t.Errorf("expected the token exchange to pass, got:\n%s", out.String())
+	}
+}
@@ -10,6 +10,8 @@ type Config struct {
 	Cloud               string `envconfig:"AZURE_ENVIRONMENT"`
 	TenantID            string `envconfig:"AZURE_TENANT_ID"`
 	IsArcEnabledCluster bool   `envconfig:"default=false,IS_ARC_ENABLED_CLUSTER"`
+	// RegionalAuthorityName is the default Azure region of the token endpoint injected in the pods
+	RegionalAuthorityName string `envconfig:"AZURE_REGIONAL_AUTHORITY_NAME"`
 }
 
 // ParseConfig parses the configuration from env variables
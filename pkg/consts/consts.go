@@ -0,0 +1,14 @@
+package consts
+
+// Environment variables injected in the pod and read by the Azure Identity SDKs
+const (
+	AzureClientIDEnvVar           = "AZURE_CLIENT_ID"
+	AzureTenantIDEnvVar           = "AZURE_TENANT_ID"
+	AzureFederatedTokenFileEnvVar = "AZURE_FEDERATED_TOKEN_FILE" // #nosec
+	AzureAuthorityHostEnvVar      = "AZURE_AUTHORITY_HOST"
+
+	// DefaultAudience is the audience added to the service account token audience
+	// This value is to be consistent with other token exchange flows in AAD and has
+	// no impact on the actual token exchange flow.
+	DefaultAudience = "api://AzureADTokenExchange"
+)
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -54,3 +57,68 @@ func GetServiceAccount(ctx context.Context, kubeClient client.Client, namespace,
 	err := kubeClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, sa)
 	return sa, err
 }
+
+// CreateServiceAccountToken requests a token of the ServiceAccount with the given audiences and expiration
+// from the TokenRequest API, like the token projected into the pods of the ServiceAccount.
+func CreateServiceAccountToken(ctx context.Context, kubeClient client.Client, namespace, name string, audiences []string, expiration time.Duration) (string, error) {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	expirationSeconds := int64(expiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	if err := kubeClient.SubResource("token").Create(ctx, sa, tokenRequest); err != nil {
+		return "", err
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+// ListServiceAccounts returns a list of ServiceAccounts in the given namespace that match the given label selector
+func ListServiceAccounts(ctx context.Context, kubeClient client.Client, namespace string, labels map[string]string) ([]corev1.ServiceAccount, error) {
+	list := &corev1.ServiceAccountList{}
+	if err := kubeClient.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// PatchServiceAccountAnnotations merges the given annotations into the ServiceAccount in the cluster
+func PatchServiceAccountAnnotations(ctx context.Context, kubeClient client.Client, sa *corev1.ServiceAccount, annotations map[string]string) error {
+	patch := client.MergeFrom(sa.DeepCopy())
+	if sa.Annotations == nil {
+		sa.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		sa.Annotations[k] = v
+	}
+	return kubeClient.Patch(ctx, sa, patch)
+}
+
+// ResolveClientIDs returns the client IDs the ServiceAccounts are annotated with, keyed by the <namespace>/<name>
+// of the ServiceAccount. The ServiceAccounts whose client ID annotation is missing or is not a valid UUID are left
+// out of the map and an error is returned for each of them.
+func ResolveClientIDs(serviceAccounts []corev1.ServiceAccount) (map[string]string, []error) {
+	clientIDs := make(map[string]string, len(serviceAccounts))
+	var errs []error
+	for _, sa := range serviceAccounts {
+		key := sa.Namespace + "/" + sa.Name
+		clientID := sa.Annotations[webhook.ClientIDAnnotation]
+		if clientID == "" {
+			errs = append(errs, errors.Errorf("service account %s: annotation %s is missing", key, webhook.ClientIDAnnotation))
+			continue
+		}
+		if _, err := uuid.Parse(clientID); err != nil {
+			errs = append(errs, errors.Wrapf(err, "service account %s: annotation %s has invalid client ID %q", key, webhook.ClientIDAnnotation, clientID))
+			continue
+		}
+		clientIDs[key] = clientID
+	}
+	return clientIDs, errs
+}
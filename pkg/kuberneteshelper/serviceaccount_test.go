@@ -2,6 +2,8 @@ package kuberneteshelper
 
 import (
 	"context"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -104,3 +106,33 @@ func TestDeleteServiceAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveClientIDs(t *testing.T) {
+	serviceAccount := func(name, clientID string) corev1.ServiceAccount {
+		sa := corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace}}
+		if clientID != "" {
+			sa.Annotations = map[string]string{webhook.ClientIDAnnotation: clientID}
+		}
+		return sa
+	}
+	serviceAccounts := []corev1.ServiceAccount{
+		serviceAccount("valid", "00000000-0000-0000-0000-000000000001"),
+		serviceAccount("missing", ""),
+		serviceAccount("malformed", "client-id"),
+	}
+
+	clientIDs, errs := ResolveClientIDs(serviceAccounts)
+	want := map[string]string{testNamespace + "/valid": "00000000-0000-0000-0000-000000000001"}
+	if !reflect.DeepEqual(clientIDs, want) {
+		t.Errorf("ResolveClientIDs() = %v, want %v", clientIDs, want)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if want := "service account test-namespace/missing: annotation azure.workload.identity/client-id is missing"; errs[0].Error() != want {
+		t.Errorf("expected error %q, got %q", want, errs[0].Error())
+	}
+	if want := `service account test-namespace/malformed: annotation azure.workload.identity/client-id has invalid client ID "client-id": `; !strings.HasPrefix(errs[1].Error(), want) {
+		t.Errorf("expected error to start with %q, got %q", want, errs[1].Error())
+	}
+}
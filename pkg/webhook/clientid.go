@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ClientIDValidationMode is how the webhook handles a pod whose client ID doesn't exist in Azure AD.
+type ClientIDValidationMode string
+
+const (
+	// ClientIDValidationDisabled doesn't validate the client IDs.
+	ClientIDValidationDisabled ClientIDValidationMode = ""
+	// ClientIDValidationWarn admits the pod with a warning if the client ID doesn't exist.
+	ClientIDValidationWarn ClientIDValidationMode = "warn"
+	// ClientIDValidationDeny denies the pod if the client ID doesn't exist.
+	ClientIDValidationDeny ClientIDValidationMode = "deny"
+)
+
+// clientIDCacheTTL is how long a client ID that exists is trusted before it is checked again.
+const clientIDCacheTTL = 10 * time.Minute
+
+// ClientIDChecker checks whether a client ID belongs to an Azure AD application or managed identity.
+type ClientIDChecker interface {
+	// ClientIDExists returns false without an error if the client ID doesn't exist.
+	ClientIDExists(ctx context.Context, clientID string) (bool, error)
+}
+
+// ParseClientIDValidationMode parses the client ID validation mode.
+func ParseClientIDValidationMode(mode string) (ClientIDValidationMode, error) {
+	switch m := ClientIDValidationMode(mode); m {
+	case ClientIDValidationDisabled, ClientIDValidationWarn, ClientIDValidationDeny:
+		return m, nil
+	default:
+		return "", errors.Errorf("invalid client ID validation mode %q, must be one of %q, %q or %q", mode, ClientIDValidationDisabled, ClientIDValidationWarn, ClientIDValidationDeny)
+	}
+}
+
+// clientIDValidator validates the client IDs injected into the pods at admission time.
+// Only the client IDs that exist are cached so that a newly created application is found
+// as soon as it exists and a pod is never admitted based on a stale negative result.
+type clientIDValidator struct {
+	checker ClientIDChecker
+	mode    ClientIDValidationMode
+
+	mu     sync.Mutex
+	exists map[string]time.Time
+	now    func() time.Time
+}
+
+func newClientIDValidator(checker ClientIDChecker, mode ClientIDValidationMode) *clientIDValidator {
+	return &clientIDValidator{
+		checker: checker,
+		mode:    mode,
+		exists:  make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// validate returns a message describing why the client ID is not valid, or an empty message if it is.
+// An error is returned if the client ID couldn't be checked.
+func (v *clientIDValidator) validate(ctx context.Context, clientID string) (string, error) {
+	if clientID == "" {
+		return "", nil
+	}
+
+	v.mu.Lock()
+	expiry, ok := v.exists[clientID]
+	v.mu.Unlock()
+	if ok && v.now().Before(expiry) {
+		return "", nil
+	}
+
+	exists, err := v.checker.ClientIDExists(ctx, clientID)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check client ID %s", clientID)
+	}
+	if !exists {
+		return fmt.Sprintf("client ID %s doesn't belong to an application or managed identity in Azure AD", clientID), nil
+	}
+
+	v.mu.Lock()
+	v.exists[clientID] = v.now().Add(clientIDCacheTTL)
+	v.mu.Unlock()
+	return "", nil
+}
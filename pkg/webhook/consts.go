@@ -1,5 +1,7 @@
 package webhook
 
+import "github.com/Azure/azure-workload-identity/pkg/consts"
+
 // Annotations and labels defined in service account
 const (
 	// UseWorkloadIdentityLabel represents the service account is to be used for workload identity
@@ -21,6 +23,14 @@ const (
 	ProxySidecarPortAnnotation = "azure.workload.identity/proxy-sidecar-port"
 	// ArcBasedIdentityAnnotation represents the annotation to be used to specify the secret name for arc based identity token
 	ArcBasedIdentityAnnotation = "arc.workload.identity/secret-name"
+	// RegionalAuthorityNameAnnotation represents the Azure region, e.g. westus2, of the regional token endpoint to be used with pod
+	RegionalAuthorityNameAnnotation = "azure.workload.identity/regional-authority-name"
+	// TokenFilePathAnnotation represents the path of the projected service account token file in the containers, which
+	// the AZURE_FEDERATED_TOKEN_FILE env var points to. It overrides the token file path the webhook is configured with.
+	TokenFilePathAnnotation = "azure.workload.identity/token-file-path"
+	// InjectionAnnotation represents the annotation added by the webhook to the mutated pods to record the injection,
+	// i.e. the client ID and the token file path injected and whether the proxy sidecar was injected, as JSON
+	InjectionAnnotation = "azure.workload.identity/injection"
 
 	// MinServiceAccountTokenExpiration is the minimum service account token expiration in seconds
 	MinServiceAccountTokenExpiration = int64(3600)
@@ -51,14 +61,17 @@ const (
 
 // Environment variables injected in the pod
 const (
-	AzureClientIDEnvVar           = "AZURE_CLIENT_ID"
-	AzureTenantIDEnvVar           = "AZURE_TENANT_ID"
-	AzureFederatedTokenFileEnvVar = "AZURE_FEDERATED_TOKEN_FILE" // #nosec
-	AzureAuthorityHostEnvVar      = "AZURE_AUTHORITY_HOST"
+	AzureClientIDEnvVar           = consts.AzureClientIDEnvVar
+	AzureTenantIDEnvVar           = consts.AzureTenantIDEnvVar
+	AzureFederatedTokenFileEnvVar = consts.AzureFederatedTokenFileEnvVar
+	AzureAuthorityHostEnvVar      = consts.AzureAuthorityHostEnvVar
 	TokenFilePathName             = "azure-identity-token"
 	TokenFileMountPath            = "/var/run/secrets/azure/tokens" // #nosec
+	// DefaultTokenFilePath is the path of the projected service account token file unless configured otherwise
+	DefaultTokenFilePath = TokenFileMountPath + "/" + TokenFilePathName // #nosec
 	// DefaultAudience is the audience added to the service account token audience
-	// This value is to be consistent with other token exchange flows in AAD and has
-	// no impact on the actual token exchange flow.
-	DefaultAudience = "api://AzureADTokenExchange"
+	DefaultAudience = consts.DefaultAudience
+
+	// AzureRegionalAuthorityNameEnvVar is only injected if a region is configured for the pod
+	AzureRegionalAuthorityNameEnvVar = "AZURE_REGIONAL_AUTHORITY_NAME"
 )
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// EffectiveConfig is the configuration the webhook mutates the pods with when neither the service account
+// nor the pod override it. It has no secrets, so that it can be served to operators as is.
+type EffectiveConfig struct {
+	Audience                      string                 `json:"audience"`
+	AzureEnvironment              string                 `json:"azureEnvironment"`
+	AzureAuthorityHost            string                 `json:"azureAuthorityHost"`
+	TenantID                      string                 `json:"tenantID"`
+	RegionalAuthorityName         string                 `json:"regionalAuthorityName,omitempty"`
+	DefaultClientID               string                 `json:"defaultClientID,omitempty"`
+	ClientIDValidation            ClientIDValidationMode `json:"clientIDValidation,omitempty"`
+	TokenFilePath                 string                 `json:"tokenFilePath"`
+	ServiceAccountTokenExpiration int64                  `json:"serviceAccountTokenExpiration"`
+	IsArcEnabledCluster           bool                   `json:"isArcEnabledCluster"`
+	ProxyImageRegistry            string                 `json:"proxyImageRegistry,omitempty"`
+	ProxyImageVersion             string                 `json:"proxyImageVersion,omitempty"`
+}
+
+// NewConfigHandler returns a read-only HTTP handler that serves the effective configuration of the pod
+// mutator as JSON, e.g. to debug a mismatch between the expected and the actual injection. The pod mutator
+// must have been returned by NewPodMutator.
+func NewConfigHandler(handler admission.Handler) (http.Handler, error) {
+	m, ok := handler.(*podMutator)
+	if !ok {
+		return nil, errors.Errorf("unexpected pod mutator type %T", handler)
+	}
+	return http.HandlerFunc(m.serveConfig), nil
+}
+
+// effectiveConfig returns the effective configuration of the pod mutator.
+func (m *podMutator) effectiveConfig() EffectiveConfig {
+	c := EffectiveConfig{
+		Audience:                      m.audience,
+		AzureEnvironment:              m.config.Cloud,
+		AzureAuthorityHost:            m.azureAuthorityHost,
+		TenantID:                      m.config.TenantID,
+		RegionalAuthorityName:         m.config.RegionalAuthorityName,
+		DefaultClientID:               m.defaultClientID,
+		TokenFilePath:                 m.tokenFilePath,
+		ServiceAccountTokenExpiration: DefaultServiceAccountTokenExpiration,
+		IsArcEnabledCluster:           m.config.IsArcEnabledCluster,
+		ProxyImageRegistry:            ProxyImageRegistry,
+		ProxyImageVersion:             ProxyImageVersion,
+	}
+	if c.TokenFilePath == "" {
+		c.TokenFilePath = DefaultTokenFilePath
+	}
+	if m.clientIDValidator != nil {
+		c.ClientIDValidation = m.clientIDValidator.mode
+	}
+	return c
+}
+
+// serveConfig serves the effective configuration of the pod mutator as JSON.
+func (m *podMutator) serveConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := json.Marshal(m.effectiveConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -11,12 +11,27 @@ import (
 
 const (
 	requestDurationMetricName = "azwi_mutation_request"
+	mutationResultMetricName  = "azwi_mutation_result"
 
 	namespaceKey = "namespace"
+	resultKey    = "result"
+)
+
+// mutationResult is the outcome of a mutation request.
+type mutationResult string
+
+const (
+	// mutationResultMutated is the result of a pod that was mutated.
+	mutationResultMutated mutationResult = "mutated"
+	// mutationResultSkipped is the result of a pod that was not mutated since it didn't opt in.
+	mutationResultSkipped mutationResult = "skipped"
+	// mutationResultErrored is the result of a pod that failed to be mutated.
+	mutationResultErrored mutationResult = "errored"
 )
 
 var (
-	req instrument.Float64Histogram
+	req      instrument.Float64Histogram
+	mutation instrument.Int64Counter
 	// if service.name is not specified, the default is "unknown_service:<exe name>"
 	// xref: https://opentelemetry.io/docs/reference/specification/resource/semantic_conventions/#service
 	labels = []attribute.KeyValue{attribute.String("service.name", "webhook")}
@@ -29,6 +44,13 @@ func registerMetrics() error {
 	req, err = meter.Float64Histogram(
 		requestDurationMetricName,
 		instrument.WithDescription("Distribution of how long it took for the azure-workload-identity mutation request"))
+	if err != nil {
+		return err
+	}
+
+	mutation, err = meter.Int64Counter(
+		mutationResultMetricName,
+		instrument.WithDescription("Number of azure-workload-identity mutation requests by result: mutated, skipped or errored"))
 
 	return err
 }
@@ -38,3 +60,9 @@ func ReportRequest(ctx context.Context, namespace string, duration time.Duration
 	l := append(labels, attribute.String(namespaceKey, namespace))
 	req.Record(ctx, duration.Seconds(), l...)
 }
+
+// reportMutationResult reports the result of a mutation request for the given namespace.
+func reportMutationResult(ctx context.Context, namespace string, result mutationResult) {
+	l := append(labels, attribute.String(namespaceKey, namespace), attribute.String(resultKey, string(result)))
+	mutation.Add(ctx, 1, l...)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +31,9 @@ var (
 	// ProxyImageVersion is the image version of the proxy init and sidecar.
 	// This is injected via LDFLAGS in the Makefile during the build.
 	ProxyImageVersion string
+
+	// regionalAuthorityNameRegex matches the Azure region names
+	regionalAuthorityNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
 )
 
 // +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=fail,groups="",resources=pods,verbs=create,versions=v1,name=mutation.azure-workload-identity.io,sideEffects=None,admissionReviewVersions=v1;v1beta1,matchPolicy=Equivalent,reinvocationPolicy=IfNeeded
@@ -49,10 +53,22 @@ type podMutator struct {
 	decoder            *admission.Decoder
 	audience           string
 	azureAuthorityHost string
+	// defaultClientID is the client ID used for the pods whose service account and pod are not annotated with one
+	defaultClientID string
+	// tokenFilePath is the path of the token file used for the pods whose service account is not annotated with one,
+	// it is empty for the default path
+	tokenFilePath string
+	// clientIDValidator validates the client IDs against Azure AD, it is nil if the validation is disabled
+	clientIDValidator *clientIDValidator
 }
 
-// NewPodMutator returns a pod mutation handler
-func NewPodMutator(client client.Client, reader client.Reader, audience string) (admission.Handler, error) {
+// NewPodMutator returns a pod mutation handler. The default client ID is used for the pods
+// whose service account and pod are not annotated with a client ID; it can be empty.
+// The token file path is used for the pods whose service account is not annotated with one;
+// it defaults to DefaultTokenFilePath.
+// Unless the client ID validation is disabled, the client ID of every mutated pod is checked
+// with the checker and the pod is admitted with a warning or denied if it doesn't exist.
+func NewPodMutator(client client.Client, reader client.Reader, audience, defaultClientID, tokenFilePath string, clientIDChecker ClientIDChecker, clientIDValidation ClientIDValidationMode) (admission.Handler, error) {
 	c, err := config.ParseConfig()
 	if err != nil {
 		return nil, err
@@ -67,24 +83,52 @@ func NewPodMutator(client client.Client, reader client.Reader, audience string)
 		return nil, errors.Wrap(err, "failed to get AAD endpoint")
 	}
 
+	if c.RegionalAuthorityName != "" && !validRegionalAuthorityName(c.RegionalAuthorityName) {
+		return nil, errors.Errorf("regional authority name %q not valid. Expected an Azure region name, e.g. westus2", c.RegionalAuthorityName)
+	}
+
+	if tokenFilePath != "" && !validTokenFilePath(tokenFilePath) {
+		return nil, errors.Errorf("token file path %q not valid. Expected a clean absolute path of a file outside of the root directory, e.g. %s", tokenFilePath, DefaultTokenFilePath)
+	}
+
 	if err := registerMetrics(); err != nil {
 		return nil, errors.Wrap(err, "failed to register metrics")
 	}
 
+	var validator *clientIDValidator
+	if clientIDValidation != ClientIDValidationDisabled {
+		if clientIDChecker == nil {
+			return nil, errors.New("client ID checker is required to validate client IDs")
+		}
+		validator = newClientIDValidator(clientIDChecker, clientIDValidation)
+	}
+
 	return &podMutator{
 		client:             client,
 		reader:             reader,
 		config:             c,
 		audience:           audience,
 		azureAuthorityHost: azureAuthorityHost,
+		defaultClientID:    defaultClientID,
+		tokenFilePath:      tokenFilePath,
+		clientIDValidator:  validator,
 	}, nil
 }
 
 // PodMutator adds projected service account volume for incoming pods if service account is annotated
 func (m *podMutator) Handle(ctx context.Context, req admission.Request) (response admission.Response) {
 	timeStart := time.Now()
+	skipped := false
 	defer func() {
 		ReportRequest(ctx, req.Namespace, time.Since(timeStart))
+
+		result := mutationResultMutated
+		if !response.Allowed {
+			result = mutationResultErrored
+		} else if skipped {
+			result = mutationResultSkipped
+		}
+		reportMutationResult(ctx, req.Namespace, result)
 	}()
 
 	pod := &corev1.Pod{}
@@ -97,6 +141,12 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) (respons
 	if podName == "" {
 		podName = pod.GetGenerateName() + " (prefix)"
 	}
+	// the webhook only receives the pods with the label, unless the object selector
+	// of the webhook configuration is changed, but pods that didn't opt in are let through unmutated
+	if pod.Labels[UseWorkloadIdentityLabel] != "true" {
+		skipped = true
+		return admission.Allowed("pod is not labeled with " + UseWorkloadIdentityLabel + "=true")
+	}
 	// for daemonset/deployment pods the namespace field is not set in objectMeta
 	// explicitly set the namespace to request namespace
 	pod.Namespace = req.Namespace
@@ -124,7 +174,8 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) (respons
 		}
 	}
 
-	if shouldInjectProxySidecar(pod) {
+	injectProxySidecar := shouldInjectProxySidecar(pod)
+	if injectProxySidecar {
 		proxyPort, err := getProxyPort(pod)
 		if err != nil {
 			logger.Error("failed to get proxy port", err)
@@ -142,36 +193,68 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) (respons
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 	// get the clientID
-	clientID := getClientID(serviceAccount)
+	clientID := getClientID(serviceAccount, pod, m.defaultClientID)
+	var warnings []string
+	if m.clientIDValidator != nil {
+		reason, err := m.clientIDValidator.validate(ctx, clientID)
+		switch {
+		case err != nil:
+			// admission doesn't depend on Azure AD being reachable, the pod is mutated as usual
+			logger.Error("failed to validate client ID", err)
+		case reason != "" && m.clientIDValidator.mode == ClientIDValidationDeny:
+			logger.Info("denying pod with unknown client ID", "client-id", clientID)
+			return admission.Denied(reason)
+		case reason != "":
+			warnings = append(warnings, reason)
+		}
+	}
 	// get the tenantID
-	tenantID := getTenantID(serviceAccount, m.config)
+	tenantID := getTenantID(serviceAccount, pod, m.config)
+	// get the regional authority name
+	regionalAuthorityName, err := getRegionalAuthorityName(serviceAccount, m.config)
+	if err != nil {
+		logger.Error("failed to get regional authority name", err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// get the token file path
+	tokenFilePath, err := getTokenFilePath(serviceAccount, m.tokenFilePath)
+	if err != nil {
+		logger.Error("failed to get token file path", err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
 	// get containers to skip
 	skipContainers := getSkipContainers(pod)
-	pod.Spec.InitContainers = m.mutateContainers(pod.Spec.InitContainers, clientID, tenantID, skipContainers)
-	pod.Spec.Containers = m.mutateContainers(pod.Spec.Containers, clientID, tenantID, skipContainers)
+	pod.Spec.InitContainers = m.mutateContainers(pod.Spec.InitContainers, clientID, tenantID, regionalAuthorityName, tokenFilePath, skipContainers)
+	pod.Spec.Containers = m.mutateContainers(pod.Spec.Containers, clientID, tenantID, regionalAuthorityName, tokenFilePath, skipContainers)
 
 	if m.config.IsArcEnabledCluster {
 		tokenSecretName := getTokenSecretName(serviceAccount)
 
 		// add the projected service account token volume to the pod if not exists
-		if err = addTokenSecretMountVolumne(pod, tokenSecretName); err != nil {
+		if err = addTokenSecretMountVolumne(pod, tokenSecretName, filepath.Base(tokenFilePath)); err != nil {
 			logger.Error("failed to add projected service account volume", err)
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 	} else {
 		// add the projected service account token volume to the pod if not exists
-		if err = addProjectedServiceAccountTokenVolume(pod, serviceAccountTokenExpiration, m.audience); err != nil {
+		if err = addProjectedServiceAccountTokenVolume(pod, serviceAccountTokenExpiration, m.audience, filepath.Base(tokenFilePath)); err != nil {
 			logger.Error("failed to add projected service account volume", err)
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 	}
 
+	// record the injection so that operators can see how the pod was mutated
+	if err = addInjectionAnnotation(pod, clientID, tokenFilePath, injectProxySidecar); err != nil {
+		logger.Error("failed to add injection annotation", err)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
 	marshaledPod, err := json.Marshal(pod)
 	if err != nil {
 		logger.Error("failed to marshal pod object", err)
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
-	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod).WithWarnings(warnings...)
 }
 
 // PodMutator implements admission.DecoderInjector
@@ -185,16 +268,16 @@ func (m *podMutator) InjectDecoder(d *admission.Decoder) error {
 
 // mutateContainers mutates the containers by injecting the projected
 // service account token volume and environment variables
-func (m *podMutator) mutateContainers(containers []corev1.Container, clientID string, tenantID string, skipContainers map[string]struct{}) []corev1.Container {
+func (m *podMutator) mutateContainers(containers []corev1.Container, clientID, tenantID, regionalAuthorityName, tokenFilePath string, skipContainers map[string]struct{}) []corev1.Container {
 	for i := range containers {
 		// container is in the skip list
 		if _, ok := skipContainers[containers[i].Name]; ok {
 			continue
 		}
 		// add environment variables to container if not exists
-		containers[i] = addEnvironmentVariables(containers[i], clientID, tenantID, m.azureAuthorityHost)
+		containers[i] = addEnvironmentVariables(containers[i], clientID, tenantID, m.azureAuthorityHost, regionalAuthorityName, tokenFilePath)
 		// add the volume mount if not exists
-		containers[i] = addProjectedTokenVolumeMount(containers[i])
+		containers[i] = addProjectedTokenVolumeMount(containers[i], filepath.Dir(tokenFilePath))
 	}
 	return containers
 }
@@ -345,9 +428,16 @@ func validServiceAccountTokenExpiry(tokenExpiry int64) bool {
 	return tokenExpiry <= MaxServiceAccountTokenExpiration && tokenExpiry >= MinServiceAccountTokenExpiration
 }
 
-// getClientID returns the clientID to be configured
-func getClientID(sa *corev1.ServiceAccount) string {
-	return sa.Annotations[ClientIDAnnotation]
+// getClientID returns the clientID to be configured. The service account annotation takes
+// precedence over the pod annotation, which takes precedence over the cluster default.
+func getClientID(sa *corev1.ServiceAccount, pod *corev1.Pod, defaultClientID string) string {
+	if clientID := sa.Annotations[ClientIDAnnotation]; clientID != "" {
+		return clientID
+	}
+	if clientID := pod.Annotations[ClientIDAnnotation]; clientID != "" {
+		return clientID
+	}
+	return defaultClientID
 }
 
 func getTokenSecretName(sa *corev1.ServiceAccount) string {
@@ -357,18 +447,68 @@ func getTokenSecretName(sa *corev1.ServiceAccount) string {
 	return fmt.Sprintf("%s%s", DefaultArcBasedIdentitySecretNamePrefix, sa.Name)
 }
 
-// getTenantID returns the tenantID to be configured
-func getTenantID(sa *corev1.ServiceAccount, c *config.Config) string {
+// getTenantID returns the tenantID to be configured. The service account annotation takes
+// precedence over the pod annotation, which takes precedence over the cluster tenantID.
+func getTenantID(sa *corev1.ServiceAccount, pod *corev1.Pod, c *config.Config) string {
 	// use tenantID if provided in the annotation
-	if tenantID, ok := sa.Annotations[TenantIDAnnotation]; ok {
+	if tenantID := sa.Annotations[TenantIDAnnotation]; tenantID != "" {
+		return tenantID
+	}
+	if tenantID := pod.Annotations[TenantIDAnnotation]; tenantID != "" {
 		return tenantID
 	}
 	// use the cluster tenantID as default value
 	return c.TenantID
 }
 
+// getTokenFilePath returns the path of the token file in the containers
+// Order of preference:
+//  1. annotation in the service account
+//  2. token file path the webhook is configured with
+//     default token file path if none is configured
+func getTokenFilePath(sa *corev1.ServiceAccount, tokenFilePath string) (string, error) {
+	if path, ok := sa.Annotations[TokenFilePathAnnotation]; ok {
+		if !validTokenFilePath(path) {
+			return "", errors.Errorf("token file path %q not valid. Expected a clean absolute path of a file outside of the root directory, e.g. %s", path, DefaultTokenFilePath)
+		}
+		return path, nil
+	}
+	if tokenFilePath != "" {
+		return tokenFilePath, nil
+	}
+	return DefaultTokenFilePath, nil
+}
+
+// validTokenFilePath returns true if the path is a clean absolute path of a file in a directory other than
+// the root directory, which the projected token volume is mounted at
+func validTokenFilePath(path string) bool {
+	return filepath.IsAbs(path) && filepath.Clean(path) == path && filepath.Dir(path) != "/"
+}
+
+// getRegionalAuthorityName returns the Azure region of the regional token endpoint to be used with the pod
+// Order of preference:
+//  1. annotation in the service account
+//  2. cluster default, which is empty unless configured
+func getRegionalAuthorityName(sa *corev1.ServiceAccount, c *config.Config) (string, error) {
+	regionalAuthorityName, ok := sa.Annotations[RegionalAuthorityNameAnnotation]
+	if !ok {
+		return c.RegionalAuthorityName, nil
+	}
+	if !validRegionalAuthorityName(regionalAuthorityName) {
+		return "", errors.Errorf("regional authority name %q not valid. Expected an Azure region name, e.g. westus2", regionalAuthorityName)
+	}
+	return regionalAuthorityName, nil
+}
+
+// validRegionalAuthorityName returns true if the name is an Azure region name, which only has
+// letters and digits, e.g. westus2, or TryAutoDetect to let the SDK detect the region
+func validRegionalAuthorityName(name string) bool {
+	return regionalAuthorityNameRegex.MatchString(name)
+}
+
 // addEnvironmentVariables adds the clientID, tenantID and token file path environment variables needed for SDK
-func addEnvironmentVariables(container corev1.Container, clientID, tenantID, azureAuthorityHost string) corev1.Container {
+// and the regional authority name if one is configured
+func addEnvironmentVariables(container corev1.Container, clientID, tenantID, azureAuthorityHost, regionalAuthorityName, tokenFilePath string) corev1.Container {
 	m := make(map[string]string)
 	for _, env := range container.Env {
 		m[env.Name] = env.Value
@@ -383,18 +523,46 @@ func addEnvironmentVariables(container corev1.Container, clientID, tenantID, azu
 	}
 	// add the token file env var
 	if _, ok := m[AzureFederatedTokenFileEnvVar]; !ok {
-		container.Env = append(container.Env, corev1.EnvVar{Name: AzureFederatedTokenFileEnvVar, Value: filepath.Join(TokenFileMountPath, TokenFilePathName)})
+		container.Env = append(container.Env, corev1.EnvVar{Name: AzureFederatedTokenFileEnvVar, Value: tokenFilePath})
 	}
 	// add the azure authority host env var
 	if _, ok := m[AzureAuthorityHostEnvVar]; !ok {
 		container.Env = append(container.Env, corev1.EnvVar{Name: AzureAuthorityHostEnvVar, Value: azureAuthorityHost})
 	}
+	// add the regional authority name env var
+	if _, ok := m[AzureRegionalAuthorityNameEnvVar]; !ok && regionalAuthorityName != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: AzureRegionalAuthorityNameEnvVar, Value: regionalAuthorityName})
+	}
 
 	return container
 }
 
-// addProjectedTokenVolumeMount adds the projected token volume mount for the container
-func addProjectedTokenVolumeMount(container corev1.Container) corev1.Container {
+// injection is the value of the injection annotation of a mutated pod
+type injection struct {
+	ClientID     string `json:"clientID"`
+	TokenFile    string `json:"tokenFile"`
+	ProxySidecar bool   `json:"proxySidecar"`
+}
+
+// addInjectionAnnotation adds the annotation recording the injection to the pod
+func addInjectionAnnotation(pod *corev1.Pod, clientID, tokenFilePath string, proxySidecar bool) error {
+	value, err := json.Marshal(injection{
+		ClientID:     clientID,
+		TokenFile:    tokenFilePath,
+		ProxySidecar: proxySidecar,
+	})
+	if err != nil {
+		return err
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[InjectionAnnotation] = string(value)
+	return nil
+}
+
+// addProjectedTokenVolumeMount adds the projected token volume mount at the mount path for the container
+func addProjectedTokenVolumeMount(container corev1.Container, mountPath string) corev1.Container {
 	for _, volume := range container.VolumeMounts {
 		if volume.Name == TokenFilePathName {
 			return container
@@ -403,14 +571,14 @@ func addProjectedTokenVolumeMount(container corev1.Container) corev1.Container {
 	container.VolumeMounts = append(container.VolumeMounts,
 		corev1.VolumeMount{
 			Name:      TokenFilePathName,
-			MountPath: TokenFileMountPath,
+			MountPath: mountPath,
 			ReadOnly:  true,
 		})
 
 	return container
 }
 
-func addTokenSecretMountVolumne(pod *corev1.Pod, tokenSecretName string) error {
+func addTokenSecretMountVolumne(pod *corev1.Pod, tokenSecretName, tokenFileName string) error {
 	// add the projected service account token volume to the pod if not exists
 	for _, volume := range pod.Spec.Volumes {
 		if volume.Projected == nil {
@@ -426,8 +594,18 @@ func addTokenSecretMountVolumne(pod *corev1.Pod, tokenSecretName string) error {
 		}
 	}
 
+	secret := &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{
+			Name: tokenSecretName,
+		},
+	}
+	// the token is stored in the secret as "azure-identity-token", which is projected to the token file name
+	if tokenFileName != TokenFilePathName {
+		secret.Items = []corev1.KeyToPath{{Key: TokenFilePathName, Path: tokenFileName}}
+	}
+
 	// add the projected service account token volume
-	// the path for this volume will always be set to "azure-identity-token"
+	// the name of this volume will always be set to "azure-identity-token"
 	pod.Spec.Volumes = append(
 		pod.Spec.Volumes,
 		corev1.Volume{
@@ -436,12 +614,7 @@ func addTokenSecretMountVolumne(pod *corev1.Pod, tokenSecretName string) error {
 				Projected: &corev1.ProjectedVolumeSource{
 					Sources: []corev1.VolumeProjection{
 						{
-							Secret: &corev1.SecretProjection{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: tokenSecretName,
-									
-								},
-							},
+							Secret: secret,
 						},
 					},
 				},
@@ -451,24 +624,19 @@ func addTokenSecretMountVolumne(pod *corev1.Pod, tokenSecretName string) error {
 	return nil
 }
 
-func addProjectedServiceAccountTokenVolume(pod *corev1.Pod, serviceAccountTokenExpiration int64, audience string) error {
+func addProjectedServiceAccountTokenVolume(pod *corev1.Pod, serviceAccountTokenExpiration int64, audience, tokenFileName string) error {
 	// add the projected service account token volume to the pod if not exists
+	// the volume is looked up by name since the token file name may be the same as the one of another projected
+	// service account token volume, e.g. "token" in the kube-api-access volume added by the ServiceAccount
+	// admission plugin, and the volume mount added to the containers refers to the volume by name
 	for _, volume := range pod.Spec.Volumes {
-		if volume.Projected == nil {
-			continue
-		}
-		for _, pvs := range volume.Projected.Sources {
-			if pvs.ServiceAccountToken == nil {
-				continue
-			}
-			if pvs.ServiceAccountToken.Path == TokenFilePathName {
-				return nil
-			}
+		if volume.Name == TokenFilePathName {
+			return nil
 		}
 	}
 
 	// add the projected service account token volume
-	// the path for this volume will always be set to "azure-identity-token"
+	// the name of this volume will always be set to "azure-identity-token"
 	pod.Spec.Volumes = append(
 		pod.Spec.Volumes,
 		corev1.Volume{
@@ -478,7 +646,7 @@ func addProjectedServiceAccountTokenVolume(pod *corev1.Pod, serviceAccountTokenE
 					Sources: []corev1.VolumeProjection{
 						{
 							ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
-								Path:              TokenFilePathName,
+								Path:              tokenFileName,
 								ExpirationSeconds: &serviceAccountTokenExpiration,
 								Audience:          audience,
 							},
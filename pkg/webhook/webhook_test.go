@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-workload-identity/pkg/config"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -190,6 +196,8 @@ func TestGetClientID(t *testing.T) {
 	tests := []struct {
 		name             string
 		sa               *corev1.ServiceAccount
+		pod              *corev1.Pod
+		defaultClientID  string
 		expectedClientID string
 	}{
 		{
@@ -200,6 +208,7 @@ func TestGetClientID(t *testing.T) {
 					Namespace: "default",
 				},
 			},
+			pod:              &corev1.Pod{},
 			expectedClientID: "",
 		},
 		{
@@ -211,13 +220,59 @@ func TestGetClientID(t *testing.T) {
 					Annotations: map[string]string{ClientIDAnnotation: "client-id"},
 				},
 			},
+			pod:              &corev1.Pod{},
 			expectedClientID: "client-id",
 		},
+		{
+			name: "service account annotation takes precedence over pod annotation and default",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "sa",
+					Namespace:   "default",
+					Annotations: map[string]string{ClientIDAnnotation: "sa-client-id"},
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ClientIDAnnotation: "pod-client-id"},
+				},
+			},
+			defaultClientID:  "default-client-id",
+			expectedClientID: "sa-client-id",
+		},
+		{
+			name: "pod annotation takes precedence over default",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sa",
+					Namespace: "default",
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ClientIDAnnotation: "pod-client-id"},
+				},
+			},
+			defaultClientID:  "default-client-id",
+			expectedClientID: "pod-client-id",
+		},
+		{
+			name: "default client id",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sa",
+					Namespace: "default",
+				},
+			},
+			pod:              &corev1.Pod{},
+			defaultClientID:  "default-client-id",
+			expectedClientID: "default-client-id",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			clientID := getClientID(test.sa)
+			clientID := getClientID(test.sa, test.pod, test.defaultClientID)
 			if clientID != test.expectedClientID {
 				t.Fatalf("expected: %s, got: %s", test.expectedClientID, clientID)
 			}
@@ -229,6 +284,7 @@ func TestGetTenantID(t *testing.T) {
 	tests := []struct {
 		name             string
 		sa               *corev1.ServiceAccount
+		pod              *corev1.Pod
 		config           *config.Config
 		expectedTenantID string
 	}{
@@ -241,9 +297,66 @@ func TestGetTenantID(t *testing.T) {
 					Annotations: map[string]string{TenantIDAnnotation: "tenant-id"},
 				},
 			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TenantIDAnnotation: "pod-tenant-id"},
+				},
+			},
 			config:           &config.Config{},
 			expectedTenantID: "tenant-id",
 		},
+		{
+			name: "tenant ID annotation defined in pod",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sa",
+					Namespace: "default",
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TenantIDAnnotation: "pod-tenant-id"},
+				},
+			},
+			config: &config.Config{
+				TenantID: "tenant-id",
+			},
+			expectedTenantID: "pod-tenant-id",
+		},
+		{
+			name: "empty tenant ID annotation in service account, use pod annotation",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "sa",
+					Namespace:   "default",
+					Annotations: map[string]string{TenantIDAnnotation: ""},
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TenantIDAnnotation: "pod-tenant-id"},
+				},
+			},
+			config: &config.Config{
+				TenantID: "tenant-id",
+			},
+			expectedTenantID: "pod-tenant-id",
+		},
+		{
+			name: "empty tenant ID annotation in service account, use default",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "sa",
+					Namespace:   "default",
+					Annotations: map[string]string{TenantIDAnnotation: ""},
+				},
+			},
+			pod: &corev1.Pod{},
+			config: &config.Config{
+				TenantID: "tenant-id",
+			},
+			expectedTenantID: "tenant-id",
+		},
 		{
 			name: "tenant ID annotation not defined, use default",
 			sa: &corev1.ServiceAccount{
@@ -252,6 +365,7 @@ func TestGetTenantID(t *testing.T) {
 					Namespace: "default",
 				},
 			},
+			pod: &corev1.Pod{},
 			config: &config.Config{
 				TenantID: "tenant-id",
 			},
@@ -261,7 +375,7 @@ func TestGetTenantID(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			tenantID := getTenantID(test.sa, test.config)
+			tenantID := getTenantID(test.sa, test.pod, test.config)
 			if tenantID != test.expectedTenantID {
 				t.Fatalf("expected: %s, got: %s", test.expectedTenantID, tenantID)
 			}
@@ -269,6 +383,132 @@ func TestGetTenantID(t *testing.T) {
 	}
 }
 
+func TestGetRegionalAuthorityName(t *testing.T) {
+	tests := []struct {
+		name                          string
+		sa                            *corev1.ServiceAccount
+		config                        *config.Config
+		expectedRegionalAuthorityName string
+		expectedErr                   bool
+	}{
+		{
+			name: "regional authority name annotation defined",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{RegionalAuthorityNameAnnotation: "westus2"},
+				},
+			},
+			config:                        &config.Config{RegionalAuthorityName: "eastus"},
+			expectedRegionalAuthorityName: "westus2",
+		},
+		{
+			name:                          "regional authority name annotation not defined, use default",
+			sa:                            &corev1.ServiceAccount{},
+			config:                        &config.Config{RegionalAuthorityName: "eastus"},
+			expectedRegionalAuthorityName: "eastus",
+		},
+		{
+			name:                          "no regional authority name",
+			sa:                            &corev1.ServiceAccount{},
+			config:                        &config.Config{},
+			expectedRegionalAuthorityName: "",
+		},
+		{
+			name: "invalid regional authority name annotation",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{RegionalAuthorityNameAnnotation: "west us 2"},
+				},
+			},
+			config:      &config.Config{},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regionalAuthorityName, err := getRegionalAuthorityName(test.sa, test.config)
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", test.expectedErr, err)
+			}
+			if regionalAuthorityName != test.expectedRegionalAuthorityName {
+				t.Fatalf("expected: %s, got: %s", test.expectedRegionalAuthorityName, regionalAuthorityName)
+			}
+		})
+	}
+}
+
+func TestGetTokenFilePath(t *testing.T) {
+	tests := []struct {
+		name                  string
+		sa                    *corev1.ServiceAccount
+		tokenFilePath         string
+		expectedTokenFilePath string
+		expectedErr           bool
+	}{
+		{
+			name:                  "no token file path",
+			sa:                    &corev1.ServiceAccount{},
+			expectedTokenFilePath: DefaultTokenFilePath,
+		},
+		{
+			name:                  "token file path configured",
+			sa:                    &corev1.ServiceAccount{},
+			tokenFilePath:         "/var/run/secrets/custom/token",
+			expectedTokenFilePath: "/var/run/secrets/custom/token",
+		},
+		{
+			name: "token file path annotation defined",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TokenFilePathAnnotation: "/etc/azure/token"},
+				},
+			},
+			tokenFilePath:         "/var/run/secrets/custom/token",
+			expectedTokenFilePath: "/etc/azure/token",
+		},
+		{
+			name: "relative token file path annotation",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TokenFilePathAnnotation: "azure/token"},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "token file path annotation in the root directory",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TokenFilePathAnnotation: "/token"},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "token file path annotation not clean",
+			sa: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{TokenFilePathAnnotation: "/etc/azure/../token/"},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokenFilePath, err := getTokenFilePath(test.sa, test.tokenFilePath)
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", test.expectedErr, err)
+			}
+			if tokenFilePath != test.expectedTokenFilePath {
+				t.Fatalf("expected: %s, got: %s", test.expectedTokenFilePath, tokenFilePath)
+			}
+		})
+	}
+}
+
 func TestGetSkipContainers(t *testing.T) {
 	tests := []struct {
 		name                   string
@@ -334,6 +574,7 @@ func TestAddProjectedServiceAccountTokenVolume(t *testing.T) {
 	tests := []struct {
 		name           string
 		pod            *corev1.Pod
+		tokenFileName  string
 		expectedVolume []corev1.Volume
 	}{
 		{
@@ -420,7 +661,7 @@ func TestAddProjectedServiceAccountTokenVolume(t *testing.T) {
 				Spec: corev1.PodSpec{
 					Volumes: []corev1.Volume{
 						{
-							Name: TokenFilePathName,
+							Name: "my-projected-volume",
 							VolumeSource: corev1.VolumeSource{
 								Projected: &corev1.ProjectedVolumeSource{
 									Sources: []corev1.VolumeProjection{
@@ -440,7 +681,7 @@ func TestAddProjectedServiceAccountTokenVolume(t *testing.T) {
 			},
 			expectedVolume: []corev1.Volume{
 				{
-					Name: TokenFilePathName,
+					Name: "my-projected-volume",
 					VolumeSource: corev1.VolumeSource{
 						Projected: &corev1.ProjectedVolumeSource{
 							Sources: []corev1.VolumeProjection{
@@ -473,11 +714,77 @@ func TestAddProjectedServiceAccountTokenVolume(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "kube-api-access volume with the same token file name not affected",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{
+							Name: "kube-api-access-abcde",
+							VolumeSource: corev1.VolumeSource{
+								Projected: &corev1.ProjectedVolumeSource{
+									Sources: []corev1.VolumeProjection{
+										{
+											ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+												Path:              "token",
+												ExpirationSeconds: &serviceAccountTokenExpiry,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			tokenFileName: "token",
+			expectedVolume: []corev1.Volume{
+				{
+					Name: "kube-api-access-abcde",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{
+									ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+										Path:              "token",
+										ExpirationSeconds: &serviceAccountTokenExpiry,
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					Name: TokenFilePathName,
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{
+									ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+										Path:              "token",
+										ExpirationSeconds: &serviceAccountTokenExpiry,
+										Audience:          DefaultAudience,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := addProjectedServiceAccountTokenVolume(test.pod, serviceAccountTokenExpiry, DefaultAudience)
+			tokenFileName := test.tokenFileName
+			if tokenFileName == "" {
+				tokenFileName = TokenFilePathName
+			}
+			err := addProjectedServiceAccountTokenVolume(test.pod, serviceAccountTokenExpiry, DefaultAudience, tokenFileName)
 			if err != nil {
 				t.Fatalf("expected err to be nil, got: %v", err)
 			}
@@ -490,9 +797,10 @@ func TestAddProjectedServiceAccountTokenVolume(t *testing.T) {
 
 func TestAddEnvironmentVariables(t *testing.T) {
 	tests := []struct {
-		name              string
-		container         corev1.Container
-		expectedContainer corev1.Container
+		name                  string
+		container             corev1.Container
+		regionalAuthorityName string
+		expectedContainer     corev1.Container
 	}{
 		{
 			name: "environment variables added to container",
@@ -523,6 +831,40 @@ func TestAddEnvironmentVariables(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "regional authority name added to container",
+			container: corev1.Container{
+				Name:  "cont1",
+				Image: "image",
+			},
+			regionalAuthorityName: "westus2",
+			expectedContainer: corev1.Container{
+				Name:  "cont1",
+				Image: "image",
+				Env: []corev1.EnvVar{
+					{
+						Name:  AzureClientIDEnvVar,
+						Value: "clientID",
+					},
+					{
+						Name:  AzureTenantIDEnvVar,
+						Value: "tenantID",
+					},
+					{
+						Name:  AzureFederatedTokenFileEnvVar,
+						Value: filepath.Join(TokenFileMountPath, TokenFilePathName),
+					},
+					{
+						Name:  AzureAuthorityHostEnvVar,
+						Value: "https://login.microsoftonline.com/",
+					},
+					{
+						Name:  AzureRegionalAuthorityNameEnvVar,
+						Value: "westus2",
+					},
+				},
+			},
+		},
 		{
 			name: "existing environment variables not replaced",
 			container: corev1.Container{
@@ -613,7 +955,7 @@ func TestAddEnvironmentVariables(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actualContainer := addEnvironmentVariables(test.container, "clientID", "tenantID", "https://login.microsoftonline.com/")
+			actualContainer := addEnvironmentVariables(test.container, "clientID", "tenantID", "https://login.microsoftonline.com/", test.regionalAuthorityName, DefaultTokenFilePath)
 			if !reflect.DeepEqual(actualContainer, test.expectedContainer) {
 				t.Fatalf("expected: %v, got: %v", test.expectedContainer, actualContainer)
 			}
@@ -700,7 +1042,7 @@ func TestAddProjectServiceAccountTokenVolumeMount(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actualContainer := addProjectedTokenVolumeMount(test.container)
+			actualContainer := addProjectedTokenVolumeMount(test.container, TokenFileMountPath)
 			if !reflect.DeepEqual(actualContainer, test.expectedContainer) {
 				t.Fatalf("expected: %v, got: %v", test.expectedContainer, actualContainer)
 			}
@@ -724,33 +1066,44 @@ func TestHandle(t *testing.T) {
 	}
 
 	decoder, _ := atypes.NewDecoder(runtime.NewScheme())
+	optedIn := map[string]string{UseWorkloadIdentityLabel: "true"}
 
 	tests := []struct {
 		name               string
 		serviceAccountName string
 		podLabels          map[string]string
+		podAnnotations     map[string]string
 		clientObjects      []client.Object
 		readerObjects      []client.Object
+		defaultClientID    string
+		tokenFilePath      string
+		wantClientID       string
+		wantTokenFile      string
+		wantProxySidecar   bool
 	}{
 		{
 			name:               "service account in cache",
 			serviceAccountName: "sa",
+			podLabels:          optedIn,
 			clientObjects:      serviceAccounts,
 			readerObjects:      nil,
 		},
 		{
 			name:               "service account not in cache",
 			serviceAccountName: "sa",
+			podLabels:          optedIn,
 			clientObjects:      nil,
 			readerObjects:      serviceAccounts,
 		},
 		{
 			name:          "default service account in cache",
+			podLabels:     optedIn,
 			clientObjects: serviceAccounts,
 			readerObjects: nil,
 		},
 		{
 			name:          "default service account not in cache",
+			podLabels:     optedIn,
 			clientObjects: nil,
 			readerObjects: serviceAccounts,
 		},
@@ -762,6 +1115,54 @@ func TestHandle(t *testing.T) {
 			clientObjects: serviceAccounts,
 			readerObjects: nil,
 		},
+		{
+			name:      "proxy sidecar injected",
+			podLabels: optedIn,
+			podAnnotations: map[string]string{
+				InjectProxySidecarAnnotation: "true",
+			},
+			clientObjects:    serviceAccounts,
+			readerObjects:    nil,
+			wantProxySidecar: true,
+		},
+		{
+			name:               "service account without client id uses default client id",
+			serviceAccountName: "sa-without-client-id",
+			podLabels:          optedIn,
+			clientObjects: []client.Object{&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sa-without-client-id",
+					Namespace: "ns1",
+				},
+			}},
+			readerObjects:   nil,
+			defaultClientID: "defaultClientID",
+			wantClientID:    "defaultClientID",
+		},
+		{
+			name:          "token file path configured",
+			podLabels:     optedIn,
+			clientObjects: serviceAccounts,
+			tokenFilePath: "/var/run/secrets/custom/token",
+			wantTokenFile: "/var/run/secrets/custom/token",
+		},
+		{
+			name:               "token file path annotation overrides configured path",
+			serviceAccountName: "sa-with-token-file-path",
+			podLabels:          optedIn,
+			clientObjects: []client.Object{&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "sa-with-token-file-path",
+					Namespace: "ns1",
+					Annotations: map[string]string{
+						ClientIDAnnotation:      "clientID",
+						TokenFilePathAnnotation: "/etc/azure/identity/token.jwt",
+					},
+				},
+			}},
+			tokenFilePath: "/var/run/secrets/custom/token",
+			wantTokenFile: "/etc/azure/identity/token.jwt",
+		},
 	}
 
 	for _, test := range tests {
@@ -775,6 +1176,16 @@ func TestHandle(t *testing.T) {
 				reader:  fake.NewClientBuilder().WithObjects(test.readerObjects...).Build(),
 				config:  &config.Config{TenantID: "tenantID"},
 				decoder: decoder,
+
+				defaultClientID: test.defaultClientID,
+				tokenFilePath:   test.tokenFilePath,
+			}
+
+			pod := newPod("pod", "ns1", test.serviceAccountName, test.podLabels)
+			pod.Annotations = test.podAnnotations
+			raw, err := json.Marshal(pod)
+			if err != nil {
+				t.Fatalf("failed to marshal pod: %v", err)
 			}
 
 			req := atypes.Request{
@@ -784,7 +1195,7 @@ func TestHandle(t *testing.T) {
 						Version: "v1",
 						Kind:    "Pod",
 					},
-					Object:    runtime.RawExtension{Raw: newPodRaw("pod", "ns1", test.serviceAccountName, test.podLabels)},
+					Object:    runtime.RawExtension{Raw: raw},
 					Namespace: "ns1",
 					Operation: admissionv1.Create,
 				},
@@ -794,10 +1205,253 @@ func TestHandle(t *testing.T) {
 			if !resp.Allowed {
 				t.Fatalf("expected to be allowed")
 			}
+
+			wantClientID := test.wantClientID
+			if wantClientID == "" {
+				wantClientID = "clientID"
+			}
+			wantTokenFile := test.wantTokenFile
+			if wantTokenFile == "" {
+				wantTokenFile = filepath.Join(TokenFileMountPath, TokenFilePathName)
+			}
+			want := injection{
+				ClientID:     wantClientID,
+				TokenFile:    wantTokenFile,
+				ProxySidecar: test.wantProxySidecar,
+			}
+			if got := getInjectionAnnotation(t, resp); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected injection annotation %+v, got %+v", want, got)
+			}
+
+			// the token file env var, the volume mount and the projected token path agree on the token file
+			patches, err := json.Marshal(resp.Patches)
+			if err != nil {
+				t.Fatalf("failed to marshal patches: %v", err)
+			}
+			for _, want := range []string{
+				fmt.Sprintf(`{"name":%q,"value":%q}`, AzureFederatedTokenFileEnvVar, wantTokenFile),
+				fmt.Sprintf(`"mountPath":%q`, filepath.Dir(wantTokenFile)),
+				fmt.Sprintf(`"path":%q`, filepath.Base(wantTokenFile)),
+			} {
+				if !strings.Contains(string(patches), want) {
+					t.Errorf("expected patches to contain %s, got %s", want, patches)
+				}
+			}
 		})
 	}
 }
 
+func TestHandleReportsMutationResult(t *testing.T) {
+	reader := metric.NewManualReader()
+	global.SetMeterProvider(metric.NewMeterProvider(metric.WithReader(reader)))
+	if err := registerMetrics(); err != nil {
+		t.Fatalf("failed to register metrics: %v", err)
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "sa",
+			Namespace:   "ns1",
+			Annotations: map[string]string{ClientIDAnnotation: "clientID"},
+		},
+	}
+	decoder, _ := atypes.NewDecoder(runtime.NewScheme())
+	m := &podMutator{
+		client:  fake.NewClientBuilder().WithObjects(serviceAccount).Build(),
+		reader:  fake.NewClientBuilder().Build(),
+		config:  &config.Config{TenantID: "tenantID"},
+		decoder: decoder,
+	}
+
+	optedIn := map[string]string{UseWorkloadIdentityLabel: "true"}
+	for _, pod := range []*corev1.Pod{
+		newPod("mutated", "ns1", "sa", optedIn),
+		newPod("skipped", "ns1", "sa", nil),
+		newPod("errored", "ns1", "missing-sa", optedIn),
+	} {
+		raw, err := json.Marshal(pod)
+		if err != nil {
+			t.Fatalf("failed to marshal pod: %v", err)
+		}
+		m.Handle(context.Background(), atypes.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Object:    runtime.RawExtension{Raw: raw},
+				Namespace: "ns1",
+				Operation: admissionv1.Create,
+			},
+		})
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			if md.Name != mutationResultMetricName {
+				continue
+			}
+			sum, ok := md.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("expected %s to be a sum, got %T", mutationResultMetricName, md.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				namespace, _ := dp.Attributes.Value(namespaceKey)
+				result, _ := dp.Attributes.Value(resultKey)
+				got[namespace.AsString()+"/"+result.AsString()] += dp.Value
+			}
+		}
+	}
+	want := map[string]int64{"ns1/mutated": 1, "ns1/skipped": 1, "ns1/errored": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected mutation results %v, got %v", want, got)
+	}
+}
+
+// fakeClientIDChecker is a ClientIDChecker that knows the client IDs in exists.
+type fakeClientIDChecker struct {
+	exists map[string]bool
+	err    error
+	calls  int
+}
+
+func (f *fakeClientIDChecker) ClientIDExists(_ context.Context, clientID string) (bool, error) {
+	f.calls++
+	return f.exists[clientID], f.err
+}
+
+func TestHandleValidatesClientID(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "sa",
+			Namespace:   "ns1",
+			Annotations: map[string]string{ClientIDAnnotation: "clientID"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		exists       bool
+		checkErr     error
+		mode         ClientIDValidationMode
+		wantAllowed  bool
+		wantWarnings bool
+	}{
+		{
+			name:        "client ID exists",
+			exists:      true,
+			mode:        ClientIDValidationDeny,
+			wantAllowed: true,
+		},
+		{
+			name:         "client ID doesn't exist, warn",
+			mode:         ClientIDValidationWarn,
+			wantAllowed:  true,
+			wantWarnings: true,
+		},
+		{
+			name:        "client ID doesn't exist, deny",
+			mode:        ClientIDValidationDeny,
+			wantAllowed: false,
+		},
+		{
+			name:        "client ID can't be checked",
+			checkErr:    fmt.Errorf("graph is unavailable"),
+			mode:        ClientIDValidationDeny,
+			wantAllowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := &fakeClientIDChecker{exists: map[string]bool{"clientID": test.exists}, err: test.checkErr}
+			decoder, _ := atypes.NewDecoder(runtime.NewScheme())
+			m := &podMutator{
+				client:            fake.NewClientBuilder().WithObjects(serviceAccount).Build(),
+				reader:            fake.NewClientBuilder().Build(),
+				config:            &config.Config{TenantID: "tenantID"},
+				decoder:           decoder,
+				clientIDValidator: newClientIDValidator(checker, test.mode),
+			}
+
+			raw, err := json.Marshal(newPod("pod", "ns1", "sa", map[string]string{UseWorkloadIdentityLabel: "true"}))
+			if err != nil {
+				t.Fatalf("failed to marshal pod: %v", err)
+			}
+			req := atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object:    runtime.RawExtension{Raw: raw},
+					Namespace: "ns1",
+					Operation: admissionv1.Create,
+				},
+			}
+
+			resp := m.Handle(context.Background(), req)
+			if resp.Allowed != test.wantAllowed {
+				t.Fatalf("expected allowed to be %t, got %t: %+v", test.wantAllowed, resp.Allowed, resp.Result)
+			}
+			if got := len(resp.Warnings) > 0; got != test.wantWarnings {
+				t.Errorf("expected warnings to be %t, got %v", test.wantWarnings, resp.Warnings)
+			}
+			if test.wantAllowed && len(resp.Patches) == 0 {
+				t.Errorf("expected the pod to be mutated")
+			}
+
+			// only the client IDs that exist are cached
+			m.Handle(context.Background(), req)
+			wantCalls := 2
+			if test.exists {
+				wantCalls = 1
+			}
+			if checker.calls != wantCalls {
+				t.Errorf("expected the client ID to be checked %d times, got %d", wantCalls, checker.calls)
+			}
+		})
+	}
+}
+
+func TestParseClientIDValidationMode(t *testing.T) {
+	for _, mode := range []string{"", "warn", "deny"} {
+		if _, err := ParseClientIDValidationMode(mode); err != nil {
+			t.Errorf("expected mode %q to be valid, got %v", mode, err)
+		}
+	}
+	if _, err := ParseClientIDValidationMode("audit"); err == nil {
+		t.Errorf("expected mode audit to be invalid")
+	}
+}
+
+// getInjectionAnnotation returns the injection annotation added by the patches of the response.
+func getInjectionAnnotation(t *testing.T, resp atypes.Response) injection {
+	t.Helper()
+
+	var value string
+	for _, patch := range resp.Patches {
+		switch patch.Path {
+		case "/metadata/annotations":
+			annotations, ok := patch.Value.(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected annotations to be a map, got %T", patch.Value)
+			}
+			value, _ = annotations[InjectionAnnotation].(string)
+		case "/metadata/annotations/" + strings.ReplaceAll(InjectionAnnotation, "/", "~1"):
+			value, _ = patch.Value.(string)
+		}
+	}
+	if value == "" {
+		t.Fatalf("expected the injection annotation to be added, got patches %+v", resp.Patches)
+	}
+
+	var got injection
+	if err := json.Unmarshal([]byte(value), &got); err != nil {
+		t.Fatalf("failed to unmarshal injection annotation %q: %v", value, err)
+	}
+	return got
+}
+
 func TestGetAzureAuthorityHost(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -967,7 +1621,7 @@ func TestMutateContainers(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			containers := m.mutateContainers(test.containers, azureClientID, azureTenantID, test.skipContainers)
+			containers := m.mutateContainers(test.containers, azureClientID, azureTenantID, "", DefaultTokenFilePath, test.skipContainers)
 			if !reflect.DeepEqual(containers, test.expectedContainers) {
 				t.Errorf("expected: %v, got: %v", test.expectedContainers, test.containers)
 			}
@@ -1415,7 +2069,7 @@ func TestAddTokenSecretMountVolumne(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := addTokenSecretMountVolumne(test.pod, "arc-token-sa")
+			err := addTokenSecretMountVolumne(test.pod, "arc-token-sa", TokenFilePathName)
 			if err != nil {
 				t.Fatalf("expected err to be nil, got: %v", err)
 			}
@@ -1426,6 +2080,18 @@ func TestAddTokenSecretMountVolumne(t *testing.T) {
 	}
 }
 
+func TestAddTokenSecretMountVolumneCustomTokenFileName(t *testing.T) {
+	pod := &corev1.Pod{}
+	if err := addTokenSecretMountVolumne(pod, "arc-token-sa", "token.jwt"); err != nil {
+		t.Fatalf("expected err to be nil, got: %v", err)
+	}
+
+	want := []corev1.KeyToPath{{Key: TokenFilePathName, Path: "token.jwt"}}
+	if len(pod.Spec.Volumes) != 1 || !reflect.DeepEqual(pod.Spec.Volumes[0].Projected.Sources[0].Secret.Items, want) {
+		t.Fatalf("expected the token to be projected to token.jwt, got: %v", pod.Spec.Volumes)
+	}
+}
+
 func TestGetTokenSecretName(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -1464,3 +2130,58 @@ func TestGetTokenSecretName(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigHandler(t *testing.T) {
+	registry, version := ProxyImageRegistry, ProxyImageVersion
+	ProxyImageRegistry, ProxyImageVersion = "my.proxy-image-registry.io/azwi", "v1.0.0"
+	t.Cleanup(func() { ProxyImageRegistry, ProxyImageVersion = registry, version })
+
+	m := &podMutator{
+		config:             &config.Config{Cloud: "AzurePublicCloud", TenantID: "tenantID", RegionalAuthorityName: "westus2"},
+		audience:           DefaultAudience,
+		azureAuthorityHost: "https://login.microsoftonline.com/",
+		defaultClientID:    "defaultClientID",
+		clientIDValidator:  newClientIDValidator(nil, ClientIDValidationWarn),
+	}
+	handler, err := NewConfigHandler(m)
+	if err != nil {
+		t.Fatalf("NewConfigHandler() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected content type application/json, got %q", got)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal config %q: %v", rec.Body.String(), err)
+	}
+	want := map[string]interface{}{
+		"audience":                      DefaultAudience,
+		"azureEnvironment":              "AzurePublicCloud",
+		"azureAuthorityHost":            "https://login.microsoftonline.com/",
+		"tenantID":                      "tenantID",
+		"regionalAuthorityName":         "westus2",
+		"defaultClientID":               "defaultClientID",
+		"clientIDValidation":            "warn",
+		"tokenFilePath":                 DefaultTokenFilePath,
+		"serviceAccountTokenExpiration": float64(DefaultServiceAccountTokenExpiration),
+		"isArcEnabledCluster":           false,
+		"proxyImageRegistry":            "my.proxy-image-registry.io/azwi",
+		"proxyImageVersion":             "v1.0.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected config %v, got %v", want, got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -13,6 +13,8 @@ var replacements = map[string]string{
 
 	"HELMSUBST_CONFIGMAP_AZURE_ENVIRONMENT": `{{ .Values.azureEnvironment | default "AzurePublicCloud" }}`,
 
+	"HELMSUBST_CONFIGMAP_AZURE_REGIONAL_AUTHORITY_NAME": `{{ .Values.azureRegionalAuthorityName | default "" | quote }}`,
+
 	"HELMSUBST_CONFIGMAP_AZURE_TENANT_ID": `{{ required "A valid .Values.azureTenantID entry required!" .Values.azureTenantID }}`,
 
 	"HELMSUBST_CONFIGMAP_IS_ARC_ENABLED_CLUSTER": `{{ .Values.isArcEnabledCluster | default "false" }}`,